@@ -0,0 +1,214 @@
+// Package apierror defines the error envelope returned by the REST API and the
+// catalogue of error codes clients can rely on.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// Code identifies an error condition. Codes are part of the API contract;
+// messages are not.
+type Code string
+
+// Error code catalogue
+const (
+	CodeValidation        Code = "VALIDATION_ERROR"
+	CodeInvalidRequest    Code = "INVALID_REQUEST"
+	CodeUnauthorized      Code = "UNAUTHORIZED"
+	CodeForbidden         Code = "FORBIDDEN"
+	CodeNotFound          Code = "NOT_FOUND"
+	CodeConflict          Code = "CONFLICT"
+	CodePayloadTooLarge   Code = "PAYLOAD_TOO_LARGE"
+	CodeRateLimitExceeded Code = "RATE_LIMIT_EXCEEDED"
+	CodeInternal          Code = "INTERNAL_SERVER_ERROR"
+	CodeExternalAPI       Code = "EXTERNAL_API_ERROR"
+	CodeTimeout           Code = "REQUEST_TIMEOUT"
+	CodeMaintenance       Code = "MAINTENANCE_MODE"
+
+	// Portfolio and asset styles
+	CodeInsufficientShares  Code = "INSUFFICIENT_SHARES"
+	CodeAssetStyleInUse     Code = "ASSET_STYLE_IN_USE"
+	CodeCannotDeleteDefault Code = "CANNOT_DELETE_DEFAULT"
+	CodeDuplicateAssetStyle Code = "DUPLICATE_ASSET_STYLE"
+
+	// Benchmarks, backtests and simulations
+	CodeDuplicateBenchmark      Code = "DUPLICATE_BENCHMARK"
+	CodeDuplicateBenchmarkBlend Code = "DUPLICATE_BENCHMARK_BLEND"
+	CodeLimitExceeded           Code = "LIMIT_EXCEEDED"
+	CodeInsufficientHistory     Code = "INSUFFICIENT_HISTORY"
+	CodeBacktestError           Code = "BACKTEST_ERROR"
+	CodeTooManyJobs             Code = "TOO_MANY_JOBS"
+	CodeJobNotComplete          Code = "JOB_NOT_COMPLETE"
+	CodeSimulationError         Code = "SIMULATION_ERROR"
+
+	// Reports, notifications and sharing
+	CodeReportError       Code = "REPORT_ERROR"
+	CodeNotificationError Code = "NOTIFICATION_ERROR"
+	CodeTooManyShareLinks Code = "TOO_MANY_SHARE_LINKS"
+	CodeDuplicateAccount  Code = "DUPLICATE_ACCOUNT_LINK"
+	CodeInviteNotPending  Code = "INVITE_NOT_PENDING"
+	CodeStatementTooLarge Code = "STATEMENT_TOO_LARGE"
+	CodeUnrecognized      Code = "UNRECOGNIZED_STATEMENT"
+	CodeUnsupportedBroker Code = "UNSUPPORTED_BROKER"
+)
+
+// statusByCode maps each code to its HTTP status
+var statusByCode = map[Code]int{
+	CodeValidation:        http.StatusBadRequest,
+	CodeInvalidRequest:    http.StatusBadRequest,
+	CodeUnauthorized:      http.StatusUnauthorized,
+	CodeForbidden:         http.StatusForbidden,
+	CodeNotFound:          http.StatusNotFound,
+	CodeConflict:          http.StatusConflict,
+	CodePayloadTooLarge:   http.StatusRequestEntityTooLarge,
+	CodeRateLimitExceeded: http.StatusTooManyRequests,
+	CodeInternal:          http.StatusInternalServerError,
+	CodeExternalAPI:       http.StatusServiceUnavailable,
+	CodeTimeout:           http.StatusGatewayTimeout,
+	CodeMaintenance:       http.StatusServiceUnavailable,
+
+	CodeInsufficientShares:  http.StatusBadRequest,
+	CodeAssetStyleInUse:     http.StatusBadRequest,
+	CodeCannotDeleteDefault: http.StatusBadRequest,
+	CodeDuplicateAssetStyle: http.StatusBadRequest,
+
+	CodeDuplicateBenchmark:      http.StatusConflict,
+	CodeDuplicateBenchmarkBlend: http.StatusConflict,
+	CodeLimitExceeded:           http.StatusBadRequest,
+	CodeInsufficientHistory:     http.StatusUnprocessableEntity,
+	CodeBacktestError:           http.StatusInternalServerError,
+	CodeTooManyJobs:             http.StatusTooManyRequests,
+	CodeJobNotComplete:          http.StatusConflict,
+	CodeSimulationError:         http.StatusInternalServerError,
+
+	CodeReportError:       http.StatusInternalServerError,
+	CodeNotificationError: http.StatusInternalServerError,
+	CodeTooManyShareLinks: http.StatusConflict,
+	CodeDuplicateAccount:  http.StatusConflict,
+	CodeInviteNotPending:  http.StatusConflict,
+	CodeStatementTooLarge: http.StatusRequestEntityTooLarge,
+	CodeUnrecognized:      http.StatusUnprocessableEntity,
+	CodeUnsupportedBroker: http.StatusBadRequest,
+}
+
+// Status returns the HTTP status for the code, 500 for codes missing from the
+// catalogue
+func (c Code) Status() int {
+	if status, ok := statusByCode[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an error reported to API clients
+type Error struct {
+	Code    Code
+	Message string      // Shown to clients
+	Details interface{} // Optional extra context shown to clients
+	Err     error       // Underlying cause; shown as details outside production
+}
+
+// New creates an error with a code and client-facing message
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap creates an error with a code and client-facing message caused by err
+func Wrap(err error, code Code, message string) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// WithDetails attaches extra context for clients
+func (e *Error) WithDetails(details interface{}) *Error {
+	e.Details = details
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the HTTP status for the error's code
+func (e *Error) Status() int {
+	return e.Code.Status()
+}
+
+// Body is the error object of a response
+type Body struct {
+	Code       Code        `json:"code"`
+	Message    string      `json:"message"`
+	Details    interface{} `json:"details,omitempty"`
+	RetryAfter int         `json:"retryAfter,omitempty"` // Seconds, for RATE_LIMIT_EXCEEDED and MAINTENANCE_MODE
+}
+
+// Response is the envelope of every error response
+type Response struct {
+	Error Body `json:"error"`
+}
+
+// Response renders the error for clients. In production the causes of server
+// errors are omitted since they may reveal internals such as queries or
+// upstream URLs.
+func (e *Error) Response(production bool) Response {
+	body := Body{Code: e.Code, Message: e.Message, Details: e.Details}
+	if body.Details == nil && e.Err != nil && !(production && e.Status() >= http.StatusInternalServerError) {
+		body.Details = e.Err.Error()
+	}
+	return Response{Error: body}
+}
+
+// registration maps a sentinel error to a code
+type registration struct {
+	target  error
+	code    Code
+	message string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []registration
+)
+
+// Register maps errors matching target (per errors.Is) to code. An empty
+// message shows the sentinel's own text.
+func Register(target error, code Code, message string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, registration{target: target, code: code, message: message})
+}
+
+// From converts any error to an API error. Errors with a specific code pass
+// through; otherwise a registered sentinel in the chain decides the code, so
+// handlers can wrap service errors as internal and still report known
+// failures precisely. Anything else is an internal error.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.Code != CodeInternal {
+		return apiErr
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, r := range registry {
+		if errors.Is(err, r.target) {
+			if r.message == "" {
+				return Wrap(err, r.code, r.target.Error())
+			}
+			return Wrap(err, r.code, r.message)
+		}
+	}
+
+	if apiErr != nil {
+		return apiErr
+	}
+	return Wrap(err, CodeInternal, "Internal server error")
+}
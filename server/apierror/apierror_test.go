@@ -0,0 +1,59 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestFromMapsRegisteredErrors(t *testing.T) {
+	errMissing := errors.New("widget not found")
+	Register(errMissing, CodeNotFound, "Widget not found")
+
+	tests := []struct {
+		name    string
+		err     error
+		code    Code
+		message string
+	}{
+		{"sentinel", errMissing, CodeNotFound, "Widget not found"},
+		{"wrapped sentinel", fmt.Errorf("lookup failed: %w", errMissing), CodeNotFound, "Widget not found"},
+		{"internal wrap of sentinel", Wrap(errMissing, CodeInternal, "Failed to load widget"), CodeNotFound, "Widget not found"},
+		{"specific code", Wrap(errMissing, CodeValidation, "Bad widget"), CodeValidation, "Bad widget"},
+		{"internal wrap", Wrap(errors.New("connection reset"), CodeInternal, "Failed to load widget"), CodeInternal, "Failed to load widget"},
+		{"unknown", errors.New("connection reset"), CodeInternal, "Internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := From(tt.err)
+			if got.Code != tt.code || got.Message != tt.message {
+				t.Errorf("Expected %s %q, got %s %q", tt.code, tt.message, got.Code, got.Message)
+			}
+		})
+	}
+}
+
+func TestResponseHidesServerErrorCausesInProduction(t *testing.T) {
+	internal := Wrap(errors.New("dial tcp 10.0.0.5:27017: connection refused"), CodeInternal, "Failed to fetch holdings")
+	if details := internal.Response(false).Error.Details; details != "dial tcp 10.0.0.5:27017: connection refused" {
+		t.Errorf("Expected cause as details outside production, got %v", details)
+	}
+	if details := internal.Response(true).Error.Details; details != nil {
+		t.Errorf("Expected no details in production, got %v", details)
+	}
+
+	validation := Wrap(errors.New("Key: 'Shares' Error:Field validation"), CodeValidation, "Invalid transaction data")
+	if details := validation.Response(true).Error.Details; details == nil {
+		t.Error("Expected client error details in production")
+	}
+}
+
+func TestCodeStatus(t *testing.T) {
+	if status := CodeNotFound.Status(); status != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", status)
+	}
+	if status := Code("UNKNOWN").Status(); status != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for unknown code, got %d", status)
+	}
+}
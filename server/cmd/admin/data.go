@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/migrations"
+	"stock-portfolio-tracker/repository"
+	"stock-portfolio-tracker/services"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func reindexCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reindex",
+		Short: "Create any missing database indexes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withDatabase(func() error {
+				if err := database.CreateIndexes(); err != nil {
+					return err
+				}
+				fmt.Println("Indexes are up to date")
+				return nil
+			})
+		},
+	}
+}
+
+func runMigrationCommand() *cobra.Command {
+	var status bool
+	cmd := &cobra.Command{
+		Use:   "run-migration",
+		Short: "Apply pending schema migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withDatabase(func() error {
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+				defer cancel()
+
+				if !status {
+					applied, err := migrations.Up(ctx, database.Database)
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Applied %d migrations\n", applied)
+					return nil
+				}
+
+				statuses, err := migrations.List(ctx, database.Database)
+				if err != nil {
+					return err
+				}
+				for _, status := range statuses {
+					state := "pending"
+					if status.Record != nil && status.Record.AppliedAt != nil {
+						state = "applied " + status.Record.AppliedAt.Format(time.RFC3339)
+					} else if status.Record != nil {
+						state = "started " + status.Record.StartedAt.Format(time.RFC3339) + ", not finished"
+					}
+					fmt.Printf("%4d  %-30s %s\n", status.Version, status.Name, state)
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&status, "status", false, "list migrations instead of applying them")
+	return cmd
+}
+
+func flushCacheCommand() *cobra.Command {
+	var url string
+	cmd := &cobra.Command{
+		Use:   "flush-cache",
+		Short: "Empty the running server's market data and exchange rate caches",
+		Long: "The caches live in the server process, so this asks the running server\n" +
+			"to flush them. The server and this command must share ADMIN_TOKEN.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := loadConfig(); err != nil {
+				return err
+			}
+			if cfg.Server.AdminToken == "" {
+				return fmt.Errorf("ADMIN_TOKEN is not configured")
+			}
+			if url == "" {
+				url = "http://localhost:" + cfg.Server.Port
+			}
+
+			req, err := http.NewRequest(http.MethodPost, strings.TrimRight(url, "/")+"/api/admin/cache/flush", nil)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Authorization", "Bearer "+cfg.Server.AdminToken)
+			client := &http.Client{Timeout: 30 * time.Second}
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("server responded %s", resp.Status)
+			}
+
+			var flushed struct {
+				MarketData    int `json:"marketData"`
+				ExchangeRates int `json:"exchangeRates"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&flushed); err != nil {
+				return err
+			}
+			fmt.Printf("Flushed %d market data and %d exchange rate entries\n", flushed.MarketData, flushed.ExchangeRates)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&url, "url", "", "base URL of the server (default http://localhost:$PORT)")
+	return cmd
+}
+
+func recomputeSnapshotsCommand() *cobra.Command {
+	var email string
+	cmd := &cobra.Command{
+		Use:   "recompute-snapshots",
+		Short: "Rebuild portfolio entries from each user's transactions",
+		Long: "Holdings are derived from transactions on every request; the per-symbol\n" +
+			"portfolio entries that carry their metadata are the only stored state.\n" +
+			"This recreates the entries missing for symbols with transactions, for\n" +
+			"one user or every user.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withDatabase(func() error {
+				ctx := context.Background()
+				repos := repository.NewMongo()
+
+				var userIDs []primitive.ObjectID
+				if email != "" {
+					user, err := repos.Users.FindByEmail(ctx, normalizeEmail(email))
+					if err != nil {
+						return fmt.Errorf("user %s: %w", email, err)
+					}
+					userIDs = append(userIDs, user.ID)
+				} else {
+					ids, err := repos.Users.FindIDs(ctx)
+					if err != nil {
+						return err
+					}
+					userIDs = ids
+				}
+
+				stockService := services.NewStockAPIService(services.StockAPIConfig{})
+				currencyService := services.NewCurrencyService(services.CurrencyConfig{})
+				portfolioService := services.NewPortfolioServiceWithRepos(stockService, currencyService, repos)
+				total := 0
+				for _, userID := range userIDs {
+					userCtx, cancel := context.WithTimeout(ctx, time.Minute)
+					created, err := portfolioService.RebuildPortfolioEntries(userCtx, userID)
+					cancel()
+					if err != nil {
+						return fmt.Errorf("user %s: %w", userID.Hex(), err)
+					}
+					if len(created) > 0 {
+						fmt.Printf("%s: created %s\n", userID.Hex(), strings.Join(created, ", "))
+					}
+					total += len(created)
+				}
+				fmt.Printf("Checked %d users, created %d portfolio entries\n", len(userIDs), total)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&email, "email", "", "only rebuild this user's entries")
+	return cmd
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"stock-portfolio-tracker/services"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func flagsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "flags",
+		Short: "Manage feature flags",
+		Long: "Feature flags roll a feature out to listed users or a percentage of users.\n" +
+			"Running servers pick up changes within FEATURE_FLAGS_REFRESH_INTERVAL;\n" +
+			"overrides in FEATURE_FLAGS take precedence over the stored flags.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return errUsage
+		},
+	}
+	cmd.AddCommand(listFlagsCommand(), setFlagCommand(), deleteFlagCommand())
+	return cmd
+}
+
+func listFlagsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the stored feature flags",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withDatabase(func() error {
+				flags, err := services.NewFeatureFlagService(nil).List()
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "FLAG\tENABLED\tPERCENTAGE\tUSERS\tOVERRIDE\tDESCRIPTION")
+				for _, flag := range flags {
+					override := "-"
+					if enabled, ok := cfg.Features.Overrides[flag.Key]; ok {
+						override = fmt.Sprint(enabled)
+					}
+					fmt.Fprintf(w, "%s\t%t\t%d%%\t%d\t%s\t%s\n", flag.Key, flag.Enabled, flag.Percentage, len(flag.Users), override, flag.Description)
+				}
+				return w.Flush()
+			})
+		},
+	}
+}
+
+func setFlagCommand() *cobra.Command {
+	var enabled bool
+	var percentage int
+	var users []string
+	var description string
+	cmd := &cobra.Command{
+		Use:   "set <flag>",
+		Short: "Create a feature flag or change the given settings of one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withDatabase(func() error {
+				service := services.NewFeatureFlagService(nil)
+				existing, err := service.List()
+				if err != nil {
+					return err
+				}
+				flag := models.FeatureFlag{Key: args[0]}
+				for _, stored := range existing {
+					if stored.Key == args[0] {
+						flag = stored
+					}
+				}
+
+				changed := cmd.Flags().Changed
+				if changed("enabled") {
+					flag.Enabled = enabled
+				}
+				if changed("percentage") {
+					flag.Percentage = percentage
+				}
+				if changed("description") {
+					flag.Description = description
+				}
+				if changed("users") {
+					if flag.Users, err = findUserIDs(users); err != nil {
+						return err
+					}
+				}
+
+				saved, err := service.Save(flag)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Flag %s: enabled %t, %d%% of users, %d listed users\n", saved.Key, saved.Enabled, saved.Percentage, len(saved.Users))
+				return nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&enabled, "enabled", true, "master switch; false turns the feature off for everyone")
+	cmd.Flags().IntVar(&percentage, "percentage", 0, "share of users who get the feature, 0 to 100")
+	cmd.Flags().StringSliceVar(&users, "users", nil, "emails of users who always get the feature, replacing the current list")
+	cmd.Flags().StringVar(&description, "description", "", "what the flag gates")
+	return cmd
+}
+
+func deleteFlagCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <flag>",
+		Short: "Delete a feature flag, turning its feature off",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withDatabase(func() error {
+				if err := services.NewFeatureFlagService(nil).Delete(args[0]); err != nil {
+					return err
+				}
+				fmt.Println("Deleted flag", args[0])
+				return nil
+			})
+		},
+	}
+}
+
+// findUserIDs resolves user emails to IDs
+func findUserIDs(emails []string) ([]primitive.ObjectID, error) {
+	repos := repository.NewMongo()
+	ids := make([]primitive.ObjectID, 0, len(emails))
+	for _, email := range emails {
+		if email = strings.TrimSpace(email); email == "" {
+			continue
+		}
+		user, err := repos.Users.FindByEmail(context.Background(), normalizeEmail(email))
+		if err != nil {
+			return nil, fmt.Errorf("user %s: %w", email, err)
+		}
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,142 @@
+// Command admin performs operator tasks against the server's database and
+// services, so they need not be done by editing MongoDB by hand:
+//
+//	go run ./cmd/admin create-user --email ops@example.com --password ...
+//	go run ./cmd/admin reset-password --email user@example.com --password ...
+//	go run ./cmd/admin reindex
+//	go run ./cmd/admin run-migration [--status]
+//	go run ./cmd/admin flush-cache [--url http://localhost:8080]
+//	go run ./cmd/admin maintenance on|off|status [--message ...] [--retry-after 10m]
+//	go run ./cmd/admin flags list|set|delete ...
+//	go run ./cmd/admin recompute-snapshots [--email user@example.com]
+//
+// It reads the same configuration as the server. The exit status is 1 when a
+// command fails and 2 for invalid arguments.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"stock-portfolio-tracker/config"
+	"stock-portfolio-tracker/database"
+
+	"github.com/joho/godotenv"
+	"github.com/spf13/cobra"
+)
+
+// cfg is the server configuration, loaded by loadConfig before a command
+// uses it, so usage and help work without a valid configuration
+var cfg *config.Config
+
+// errUsage is returned by a command with subcommands run without one
+var errUsage = errors.New("missing command")
+
+// commandError is an error returned by a command that ran, as opposed to
+// invalid arguments cobra rejected before running it
+type commandError struct {
+	err error
+}
+
+func (e commandError) Error() string { return e.err.Error() }
+func (e commandError) Unwrap() error { return e.err }
+
+func main() {
+	os.Exit(execute(rootCommand(), os.Args[1:]))
+}
+
+// rootCommand builds the admin command tree
+func rootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "admin",
+		Short:         "Manage users and data of the stock portfolio tracker",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return errUsage
+		},
+	}
+	root.AddCommand(
+		createUserCommand(),
+		resetPasswordCommand(),
+		reindexCommand(),
+		runMigrationCommand(),
+		flushCacheCommand(),
+		maintenanceCommand(),
+		flagsCommand(),
+		recomputeSnapshotsCommand(),
+	)
+	for _, cmd := range root.Commands() {
+		markCommandErrors(cmd)
+	}
+	return root
+}
+
+// markCommandErrors wraps the errors of cmd and its subcommands in
+// commandError
+func markCommandErrors(cmd *cobra.Command) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			if err := run(cmd, args); err != nil {
+				return commandError{err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markCommandErrors(sub)
+	}
+}
+
+// execute runs the command named by args, reports its error and returns the
+// process exit status
+func execute(root *cobra.Command, args []string) int {
+	root.SetArgs(args)
+	cmd, err := root.ExecuteC()
+	var failed commandError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		cmd.SetOut(os.Stderr)
+		cmd.Usage()
+		return 2
+	case errors.As(err, &failed):
+		log.Println("Error:", failed.err)
+		return 1
+	default:
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		cmd.SetOut(os.Stderr)
+		cmd.Usage()
+		return 2
+	}
+}
+
+// loadConfig loads the server configuration into cfg
+func loadConfig() error {
+	if cfg != nil {
+		return nil
+	}
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+	loaded, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	cfg = loaded
+	return nil
+}
+
+// withDatabase runs fn connected to MongoDB
+func withDatabase(fn func() error) error {
+	if err := loadConfig(); err != nil {
+		return err
+	}
+	if err := database.ConnectWithConfig(cfg.Mongo); err != nil {
+		return err
+	}
+	defer database.Disconnect()
+	return fn()
+}
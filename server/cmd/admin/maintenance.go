@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func maintenanceCommand() *cobra.Command {
+	var message string
+	var retryAfter time.Duration
+	cmd := &cobra.Command{
+		Use:   "maintenance on|off|status",
+		Short: "Turn read-only maintenance mode on or off",
+		Long: "In maintenance mode every server instance rejects requests that change\n" +
+			"data with 503 and a Retry-After header, while reads keep working. Running\n" +
+			"instances pick up a change within " + services.MaintenanceRefreshInterval.String() + ".",
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"on", "off", "status"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withDatabase(func() error {
+				service := services.NewMaintenanceService()
+				if args[0] == "status" {
+					if err := service.Refresh(); err != nil {
+						return err
+					}
+					mode := service.Current()
+					printMaintenance(&mode)
+					return nil
+				}
+
+				mode, err := service.Set(args[0] == "on", message, retryAfter)
+				if err != nil {
+					return err
+				}
+				printMaintenance(mode)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&message, "message", "", "message shown to clients whose writes are rejected")
+	cmd.Flags().DurationVar(&retryAfter, "retry-after", services.DefaultMaintenanceRetryAfter, "how long clients are told to wait before retrying")
+	return cmd
+}
+
+// printMaintenance describes the maintenance switch
+func printMaintenance(mode *models.MaintenanceMode) {
+	if !mode.Enabled {
+		fmt.Println("Maintenance mode is off")
+		return
+	}
+	fmt.Printf("Maintenance mode is on since %s, clients retry after %ds\n", mode.Since.Format(time.RFC3339), mode.RetryAfter)
+	if mode.Message != "" {
+		fmt.Println("Message:", mode.Message)
+	}
+}
@@ -0,0 +1,122 @@
+// Command loadtest drives a running server with the dashboard, holdings and
+// performance requests of a seeded user and fails when p95 latency exceeds
+// the configured budgets.
+//
+//	go run ./cmd/loadtest -url http://localhost:8080 -duration 1m -rate 30
+//
+// The user given by -email is registered and seeded with a portfolio on the
+// first run and reused afterwards. The exit status is 1 when a budget is
+// exceeded or the error rate is above -max-error-rate, so the command can
+// gate a CI job.
+//
+// Every request comes from one IP, so the server's global rate limit
+// (RATE_LIMIT_GLOBAL, 500 requests per minute by default) caps the rate
+// that can be tested. The default rate stays under it; a higher -rate needs
+// the server started with a higher limit, e.g. RATE_LIMIT_GLOBAL=3000 for
+// -rate 30, or its requests are rejected with 429s, which are reported
+// separately.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"stock-portfolio-tracker/internal/loadtest"
+	"text/tabwriter"
+	"time"
+)
+
+// defaultBudgets are the p95 latencies the endpoints are expected to meet
+// with warm caches
+const defaultBudgets = "dashboard=300ms,holdings=300ms,performance=800ms"
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "base URL of the server")
+	email := flag.String("email", "loadtest@example.com", "load test user, registered and seeded if missing")
+	password := flag.String("password", "loadtest-password", "password of the load test user")
+	duration := flag.Duration("duration", 30*time.Second, "how long to issue requests for")
+	rate := flag.Int("rate", 5, "requests per second across all endpoints, limited by the server's RATE_LIMIT_GLOBAL")
+	concurrency := flag.Int("concurrency", 10, "most requests in flight at once")
+	timeout := flag.Duration("timeout", 10*time.Second, "per request timeout")
+	warmup := flag.Duration("warmup", 5*time.Second, "unmeasured run before the measured one, to fill caches")
+	budgetSpec := flag.String("budgets", defaultBudgets, "p95 budgets as name=duration pairs")
+	maxErrorRate := flag.Float64("max-error-rate", 0.01, "highest tolerated fraction of failed requests per endpoint")
+	flag.Parse()
+
+	budgets, err := loadtest.ParseBudgets(*budgetSpec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid -budgets:", err)
+		os.Exit(2)
+	}
+
+	client := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency}}
+	ctx := context.Background()
+
+	seedCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	token, err := loadtest.Seed(seedCtx, client, *baseURL, *email, *password, loadtest.DefaultSeed(time.Now()))
+	cancel()
+	if err != nil {
+		log.Fatal("Failed to seed load test user: ", err)
+	}
+
+	opts := loadtest.Options{Duration: *warmup, Rate: *rate, Concurrency: *concurrency, Timeout: *timeout}
+	if *warmup > 0 {
+		if _, err := loadtest.Run(ctx, client, *baseURL, token, loadtest.DefaultTargets, opts); err != nil {
+			log.Fatal("Warmup failed: ", err)
+		}
+	}
+
+	opts.Duration = *duration
+	stats, err := loadtest.Run(ctx, client, *baseURL, token, loadtest.DefaultTargets, opts)
+	if err != nil {
+		log.Fatal("Load test failed: ", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "endpoint\trequests\terrors\t429s\tp50\tp95\tp99\tmax\tbudget\t")
+	for _, s := range stats {
+		budget := "-"
+		if b, ok := budgets[s.Name]; ok {
+			budget = b.String()
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t\n", s.Name, s.Requests, s.Errors, s.RateLimited,
+			round(s.P50), round(s.P95), round(s.P99), round(s.Max), budget)
+	}
+	w.Flush()
+
+	rateLimited := 0
+	for _, s := range stats {
+		rateLimited += s.RateLimited
+	}
+	if rateLimited > 0 {
+		fmt.Printf("HINT: %d requests were rejected by the server's rate limit; start it with RATE_LIMIT_GLOBAL of at least %d (requests per minute) or lower -rate\n",
+			rateLimited, *rate*60)
+	}
+
+	failed := false
+	for _, violation := range loadtest.Check(stats, budgets) {
+		fmt.Println("FAIL:", violation)
+		failed = true
+	}
+	for _, s := range stats {
+		if s.Requests == 0 {
+			fmt.Printf("FAIL: %s made no requests\n", s.Name)
+			failed = true
+		} else if s.ErrorRate() > *maxErrorRate {
+			fmt.Printf("FAIL: %s error rate %.1f%% exceeds %.1f%%\n", s.Name, s.ErrorRate()*100, *maxErrorRate*100)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+	fmt.Println("PASS: all endpoints within budget")
+}
+
+// round formats a latency to a tenth of a millisecond
+func round(d time.Duration) string {
+	return d.Round(100 * time.Microsecond).String()
+}
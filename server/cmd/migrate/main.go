@@ -0,0 +1,72 @@
+// Command migrate applies or lists database schema migrations.
+//
+//	go run ./cmd/migrate up      apply pending migrations
+//	go run ./cmd/migrate status  list migrations and when they were applied
+//
+// It reads the same configuration as the server.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"stock-portfolio-tracker/config"
+	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/migrations"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if len(os.Args) != 2 || (os.Args[1] != "up" && os.Args[1] != "status") {
+		fmt.Fprintln(os.Stderr, "usage: migrate up|status")
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+
+	if err := database.ConnectWithConfig(cfg.Mongo); err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer database.Disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := migrations.Up(ctx, database.Database)
+		if err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+		fmt.Printf("Applied %d migrations\n", applied)
+
+	case "status":
+		statuses, err := migrations.List(ctx, database.Database)
+		if err != nil {
+			log.Fatal("Failed to list migrations:", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			switch {
+			case status.Record != nil && status.Record.AppliedAt != nil:
+				state = "applied " + status.Record.AppliedAt.Format(time.RFC3339)
+			case status.Record != nil:
+				state = "started " + status.Record.StartedAt.Format(time.RFC3339) + ", not finished"
+			}
+			if status.Up == nil {
+				state += " (unknown to this build)"
+			}
+			fmt.Printf("%4d  %-30s %s\n", status.Version, status.Name, state)
+		}
+	}
+}
@@ -0,0 +1,622 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the server settings. Values come from the defaults, then the
+// optional YAML file named by CONFIG_FILE, then environment variables.
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Mongo     MongoConfig     `yaml:"mongo"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Providers ProvidersConfig `yaml:"providers"`
+	Cache     CacheConfig     `yaml:"cache"`
+	RateLimit RateLimitConfig `yaml:"rateLimit"`
+	SMTP      SMTPConfig      `yaml:"smtp"`
+	Chat      ChatConfig      `yaml:"chat"`
+	Brokerage BrokerageConfig `yaml:"brokerage"`
+	Sheets    SheetsConfig    `yaml:"sheets"`
+	Features  FeaturesConfig  `yaml:"features"`
+}
+
+// ServerConfig configures the HTTP listener
+type ServerConfig struct {
+	Environment  string        `yaml:"environment"` // "development" or "production"
+	Port         string        `yaml:"port"`
+	GRPCPort     string        `yaml:"grpcPort"` // The gRPC API is disabled when empty
+	CORSOrigins  []string      `yaml:"corsOrigins"`
+	ReadTimeout  time.Duration `yaml:"readTimeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+
+	// Deadlines for handling a request: analytics and synchronous backtests
+	// have their own, every other route uses RequestTimeout. StreamTimeout
+	// bounds event streams, which outlive the write timeout.
+	RequestTimeout   time.Duration `yaml:"requestTimeout"`
+	AnalyticsTimeout time.Duration `yaml:"analyticsTimeout"`
+	BacktestTimeout  time.Duration `yaml:"backtestTimeout"`
+	StreamTimeout    time.Duration `yaml:"streamTimeout"`
+
+	// Security headers. HSTS is only announced on requests made over HTTPS;
+	// a zero max age or an empty policy omits the header.
+	HSTSMaxAge            time.Duration `yaml:"hstsMaxAge"`
+	ContentSecurityPolicy string        `yaml:"contentSecurityPolicy"`
+	// RedirectHTTPS redirects requests a TLS-terminating proxy received over
+	// plain HTTP, as reported by X-Forwarded-Proto
+	RedirectHTTPS bool `yaml:"redirectHttps"`
+
+	// TrustedProxies lists the addresses or CIDR ranges of the proxies whose
+	// X-Forwarded-For is believed when resolving client addresses for rate
+	// limiting and logging. With none, the connecting address is the client.
+	TrustedProxies []string `yaml:"trustedProxies"`
+	// AllowedIPs, when not empty, restricts access to these client addresses
+	// or CIDR ranges; DeniedIPs blocks the ones listed
+	AllowedIPs []string `yaml:"allowedIps"`
+	DeniedIPs  []string `yaml:"deniedIps"`
+
+	// AdminToken authenticates the admin CLI's maintenance requests, such as
+	// flushing caches. The maintenance routes are disabled when it is empty.
+	AdminToken string `yaml:"adminToken"`
+}
+
+// Production reports whether the server runs in production, where error
+// responses omit internal details
+func (s ServerConfig) Production() bool {
+	return s.Environment == "production"
+}
+
+// MongoConfig configures the MongoDB connection
+type MongoConfig struct {
+	URI            string        `yaml:"uri"`
+	Database       string        `yaml:"database"`
+	MaxPoolSize    uint64        `yaml:"maxPoolSize"`
+	MinPoolSize    uint64        `yaml:"minPoolSize"`
+	MaxConnIdle    time.Duration `yaml:"maxConnIdle"`
+	ConnectTimeout time.Duration `yaml:"connectTimeout"`
+
+	// MigrateOnStartup applies pending schema migrations before serving.
+	// Disable it to run them separately with cmd/migrate.
+	MigrateOnStartup bool `yaml:"migrateOnStartup"`
+}
+
+// AuthConfig configures token signing and the encryption of credentials
+type AuthConfig struct {
+	JWTSecret string `yaml:"jwtSecret"`
+	// CredentialKey is the base64 AES-256 key third-party credentials, such
+	// as brokerage access tokens and Google tokens, are encrypted with at rest
+	CredentialKey string `yaml:"credentialKey"`
+}
+
+// ProvidersConfig configures the external market data and exchange rate APIs
+type ProvidersConfig struct {
+	ExchangeRateAPIKey  string        `yaml:"exchangeRateApiKey"`
+	YahooTimeout        time.Duration `yaml:"yahooTimeout"`
+	EastmoneyTimeout    time.Duration `yaml:"eastmoneyTimeout"`
+	ExchangeRateTimeout time.Duration `yaml:"exchangeRateTimeout"`
+
+	// Requests per second sent to each provider. Requests beyond the rate
+	// queue, taking turns between users, rather than tripping the provider's
+	// throttle.
+	YahooRateLimit        float64 `yaml:"yahooRateLimit"`
+	EastmoneyRateLimit    float64 `yaml:"eastmoneyRateLimit"`
+	ExchangeRateRateLimit float64 `yaml:"exchangeRateRateLimit"`
+}
+
+// CacheConfig configures how long fetched market data is reused
+type CacheConfig struct {
+	QuoteTTL        time.Duration `yaml:"quoteTtl"` // Quotes and price history
+	ExchangeRateTTL time.Duration `yaml:"exchangeRateTtl"`
+	NewsTTL         time.Duration `yaml:"newsTtl"`
+
+	// Entries kept before the least recently used is evicted: quotes (also
+	// dividends and key statistics) and price histories
+	QuoteMaxEntries   int `yaml:"quoteMaxEntries"`
+	HistoryMaxEntries int `yaml:"historyMaxEntries"`
+
+	// Quotes for the holdings of users seen within WarmActiveWindow are
+	// prefetched on startup and every WarmInterval; zero disables warming
+	WarmInterval     time.Duration `yaml:"warmInterval"`
+	WarmActiveWindow time.Duration `yaml:"warmActiveWindow"`
+}
+
+// RateLimitConfig configures per-client request limits
+type RateLimitConfig struct {
+	GlobalPerMinute int `yaml:"globalPerMinute"`
+	AuthPerMinute   int `yaml:"authPerMinute"`
+}
+
+// SMTPConfig configures outgoing mail. Notifications are logged instead of
+// emailed when Host is empty.
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// ChatConfig configures chat notifications. Telegram is only offered when a
+// bot token is set; Slack needs no server settings, users paste an incoming
+// webhook URL.
+type ChatConfig struct {
+	TelegramBotToken string `yaml:"telegramBotToken"`
+}
+
+// BrokerageConfig configures linking brokerage accounts through an
+// aggregator. Each aggregator is only offered when its credentials are set.
+type BrokerageConfig struct {
+	PlaidClientID    string `yaml:"plaidClientId"`
+	PlaidSecret      string `yaml:"plaidSecret"`
+	PlaidEnvironment string `yaml:"plaidEnvironment"` // "sandbox" or "production"
+
+	SnapTradeClientID    string `yaml:"snapTradeClientId"`
+	SnapTradeConsumerKey string `yaml:"snapTradeConsumerKey"`
+
+	// SyncInterval is how often linked accounts are synced
+	SyncInterval time.Duration `yaml:"syncInterval"`
+}
+
+// SheetsConfig configures pushing holdings and daily performance to Google
+// Sheets the users link. It is only offered when the OAuth client is set.
+type SheetsConfig struct {
+	GoogleClientID     string `yaml:"googleClientId"`
+	GoogleClientSecret string `yaml:"googleClientSecret"`
+	// RedirectURL is the web app page Google's consent screen returns to,
+	// which completes the link with the code it receives
+	RedirectURL string `yaml:"redirectUrl"`
+
+	// SyncInterval is how often linked sheets are updated
+	SyncInterval time.Duration `yaml:"syncInterval"`
+}
+
+// Enabled reports whether the Google OAuth client is configured
+func (s SheetsConfig) Enabled() bool {
+	return s.GoogleClientID != ""
+}
+
+// FeaturesConfig configures feature flags. Flags are stored in the database
+// and managed with the admin CLI; overrides force a flag on or off for every
+// user of this deployment, whatever is stored.
+type FeaturesConfig struct {
+	Overrides       map[string]bool `yaml:"overrides"`
+	RefreshInterval time.Duration   `yaml:"refreshInterval"` // How often stored flags are reloaded
+}
+
+// Default returns the settings used when nothing overrides them
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Environment:  "development",
+			Port:         "8080",
+			CORSOrigins:  []string{"http://localhost:3000"},
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 60 * time.Second,
+
+			RequestTimeout:   30 * time.Second,
+			AnalyticsTimeout: 15 * time.Second,
+			BacktestTimeout:  60 * time.Second,
+			StreamTimeout:    5 * time.Minute,
+
+			HSTSMaxAge: 180 * 24 * time.Hour,
+			// The API serves JSON, PDFs and CSV only, none of which load other resources
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+		},
+		Mongo: MongoConfig{
+			Database:       "stock-portfolio",
+			MaxPoolSize:    50,
+			MinPoolSize:    10,
+			MaxConnIdle:    30 * time.Second,
+			ConnectTimeout: 10 * time.Second,
+
+			MigrateOnStartup: true,
+		},
+		Providers: ProvidersConfig{
+			YahooTimeout:        30 * time.Second,
+			EastmoneyTimeout:    10 * time.Second,
+			ExchangeRateTimeout: 30 * time.Second,
+
+			YahooRateLimit:        5,
+			EastmoneyRateLimit:    10,
+			ExchangeRateRateLimit: 2,
+		},
+		Cache: CacheConfig{
+			QuoteTTL:        5 * time.Minute,
+			ExchangeRateTTL: time.Hour,
+			NewsTTL:         15 * time.Minute,
+
+			QuoteMaxEntries:   5000,
+			HistoryMaxEntries: 1000,
+
+			WarmInterval:     time.Minute,
+			WarmActiveWindow: 7 * 24 * time.Hour,
+		},
+		RateLimit: RateLimitConfig{
+			GlobalPerMinute: 500,
+			AuthPerMinute:   30,
+		},
+		SMTP: SMTPConfig{
+			Port: "587",
+		},
+		Brokerage: BrokerageConfig{
+			PlaidEnvironment: "sandbox",
+			SyncInterval:     6 * time.Hour,
+		},
+		Sheets: SheetsConfig{
+			SyncInterval: 24 * time.Hour,
+		},
+		Features: FeaturesConfig{
+			RefreshInterval: 30 * time.Second,
+		},
+	}
+}
+
+// Load reads the configuration and validates it
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadFile overlays the settings present in a YAML file
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overlays the settings present in the environment
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	env := envReader{lookup: lookup}
+
+	env.string("APP_ENV", &c.Server.Environment)
+	env.string("PORT", &c.Server.Port)
+	env.string("GRPC_PORT", &c.Server.GRPCPort)
+	env.list("CORS_ORIGIN", &c.Server.CORSOrigins)
+	env.duration("SERVER_READ_TIMEOUT", &c.Server.ReadTimeout)
+	env.duration("SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	env.duration("REQUEST_TIMEOUT", &c.Server.RequestTimeout)
+	env.duration("ANALYTICS_TIMEOUT", &c.Server.AnalyticsTimeout)
+	env.duration("BACKTEST_TIMEOUT", &c.Server.BacktestTimeout)
+	env.duration("STREAM_TIMEOUT", &c.Server.StreamTimeout)
+	env.duration("HSTS_MAX_AGE", &c.Server.HSTSMaxAge)
+	env.string("CONTENT_SECURITY_POLICY", &c.Server.ContentSecurityPolicy)
+	env.bool("REDIRECT_HTTPS", &c.Server.RedirectHTTPS)
+	env.list("TRUSTED_PROXIES", &c.Server.TrustedProxies)
+	env.list("ALLOWED_IPS", &c.Server.AllowedIPs)
+	env.list("DENIED_IPS", &c.Server.DeniedIPs)
+	env.string("ADMIN_TOKEN", &c.Server.AdminToken)
+
+	env.string("MONGODB_URI", &c.Mongo.URI)
+	env.string("MONGODB_DATABASE", &c.Mongo.Database)
+	env.uint("MONGODB_MAX_POOL_SIZE", &c.Mongo.MaxPoolSize)
+	env.uint("MONGODB_MIN_POOL_SIZE", &c.Mongo.MinPoolSize)
+	env.duration("MONGODB_MAX_CONN_IDLE", &c.Mongo.MaxConnIdle)
+	env.duration("MONGODB_CONNECT_TIMEOUT", &c.Mongo.ConnectTimeout)
+	env.bool("MONGODB_MIGRATE_ON_STARTUP", &c.Mongo.MigrateOnStartup)
+
+	env.string("JWT_SECRET", &c.Auth.JWTSecret)
+	// SHEETS_TOKEN_KEY is the key's former name, from when only Google tokens
+	// were encrypted
+	env.string("SHEETS_TOKEN_KEY", &c.Auth.CredentialKey)
+	env.string("CREDENTIAL_KEY", &c.Auth.CredentialKey)
+
+	env.string("EXCHANGE_RATE_API_KEY", &c.Providers.ExchangeRateAPIKey)
+	env.duration("YAHOO_TIMEOUT", &c.Providers.YahooTimeout)
+	env.duration("EASTMONEY_TIMEOUT", &c.Providers.EastmoneyTimeout)
+	env.duration("EXCHANGE_RATE_TIMEOUT", &c.Providers.ExchangeRateTimeout)
+	env.float("YAHOO_RATE_LIMIT", &c.Providers.YahooRateLimit)
+	env.float("EASTMONEY_RATE_LIMIT", &c.Providers.EastmoneyRateLimit)
+	env.float("EXCHANGE_RATE_RATE_LIMIT", &c.Providers.ExchangeRateRateLimit)
+
+	env.duration("QUOTE_CACHE_TTL", &c.Cache.QuoteTTL)
+	env.duration("EXCHANGE_RATE_CACHE_TTL", &c.Cache.ExchangeRateTTL)
+	env.duration("NEWS_CACHE_TTL", &c.Cache.NewsTTL)
+	env.int("QUOTE_CACHE_MAX_ENTRIES", &c.Cache.QuoteMaxEntries)
+	env.int("HISTORY_CACHE_MAX_ENTRIES", &c.Cache.HistoryMaxEntries)
+	env.duration("CACHE_WARM_INTERVAL", &c.Cache.WarmInterval)
+	env.duration("CACHE_WARM_ACTIVE_WINDOW", &c.Cache.WarmActiveWindow)
+
+	env.int("RATE_LIMIT_GLOBAL", &c.RateLimit.GlobalPerMinute)
+	env.int("RATE_LIMIT_AUTH", &c.RateLimit.AuthPerMinute)
+
+	env.string("SMTP_HOST", &c.SMTP.Host)
+	env.string("SMTP_PORT", &c.SMTP.Port)
+	env.string("SMTP_USERNAME", &c.SMTP.Username)
+	env.string("SMTP_PASSWORD", &c.SMTP.Password)
+	env.string("SMTP_FROM", &c.SMTP.From)
+
+	env.string("TELEGRAM_BOT_TOKEN", &c.Chat.TelegramBotToken)
+
+	env.string("PLAID_CLIENT_ID", &c.Brokerage.PlaidClientID)
+	env.string("PLAID_SECRET", &c.Brokerage.PlaidSecret)
+	env.string("PLAID_ENV", &c.Brokerage.PlaidEnvironment)
+	env.string("SNAPTRADE_CLIENT_ID", &c.Brokerage.SnapTradeClientID)
+	env.string("SNAPTRADE_CONSUMER_KEY", &c.Brokerage.SnapTradeConsumerKey)
+	env.duration("BROKERAGE_SYNC_INTERVAL", &c.Brokerage.SyncInterval)
+
+	env.string("GOOGLE_CLIENT_ID", &c.Sheets.GoogleClientID)
+	env.string("GOOGLE_CLIENT_SECRET", &c.Sheets.GoogleClientSecret)
+	env.string("GOOGLE_SHEETS_REDIRECT_URL", &c.Sheets.RedirectURL)
+	env.duration("SHEETS_SYNC_INTERVAL", &c.Sheets.SyncInterval)
+
+	env.flags("FEATURE_FLAGS", &c.Features.Overrides)
+	env.duration("FEATURE_FLAGS_REFRESH_INTERVAL", &c.Features.RefreshInterval)
+
+	return errors.Join(env.errs...)
+}
+
+// Validate reports every invalid setting at once so a misconfigured
+// deployment fails at startup rather than on first use
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Environment != "development" && c.Server.Environment != "production" {
+		invalid("environment %q must be development or production", c.Server.Environment)
+	}
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port <= 0 || port > 65535 {
+		invalid("server port %q is not a valid port", c.Server.Port)
+	}
+	if c.Server.GRPCPort != "" {
+		if port, err := strconv.Atoi(c.Server.GRPCPort); err != nil || port <= 0 || port > 65535 || c.Server.GRPCPort == c.Server.Port {
+			invalid("gRPC port %q is not a valid port distinct from the HTTP port", c.Server.GRPCPort)
+		}
+	}
+	if c.Server.AdminToken != "" && len(c.Server.AdminToken) < 32 {
+		invalid("admin token must be at least 32 characters")
+	}
+	if c.Server.HSTSMaxAge < 0 {
+		invalid("HSTS max age must not be negative")
+	}
+	if c.Cache.QuoteMaxEntries <= 0 || c.Cache.HistoryMaxEntries <= 0 {
+		invalid("cache entry limits must be positive")
+	}
+	if c.Cache.WarmInterval < 0 {
+		invalid("cache warm interval must not be negative")
+	}
+	for name, values := range map[string][]string{
+		"trusted proxy": c.Server.TrustedProxies,
+		"allowed IP":    c.Server.AllowedIPs,
+		"denied IP":     c.Server.DeniedIPs,
+	} {
+		for _, value := range values {
+			if !validIPOrCIDR(value) {
+				invalid("%s %q is not an IP address or CIDR range", name, value)
+			}
+		}
+	}
+	if len(c.Server.CORSOrigins) == 0 {
+		invalid("at least one CORS origin is required")
+	}
+	if c.Mongo.URI == "" {
+		invalid("MongoDB URI is required (MONGODB_URI)")
+	}
+	if c.Mongo.Database == "" {
+		invalid("MongoDB database name is required")
+	}
+	if c.Mongo.MaxPoolSize == 0 || c.Mongo.MinPoolSize > c.Mongo.MaxPoolSize {
+		invalid("MongoDB pool size must satisfy 0 <= min <= max and max > 0")
+	}
+	if c.Auth.JWTSecret == "" {
+		invalid("JWT secret is required (JWT_SECRET)")
+	}
+	if c.RateLimit.GlobalPerMinute <= 0 || c.RateLimit.AuthPerMinute <= 0 {
+		invalid("rate limits must be positive")
+	}
+	if c.Brokerage.PlaidEnvironment != "sandbox" && c.Brokerage.PlaidEnvironment != "production" {
+		invalid("Plaid environment %q must be sandbox or production", c.Brokerage.PlaidEnvironment)
+	}
+	if (c.Brokerage.PlaidClientID == "") != (c.Brokerage.PlaidSecret == "") {
+		invalid("Plaid needs both a client ID and a secret")
+	}
+	if (c.Brokerage.SnapTradeClientID == "") != (c.Brokerage.SnapTradeConsumerKey == "") {
+		invalid("SnapTrade needs both a client ID and a consumer key")
+	}
+	if (c.Sheets.GoogleClientID == "") != (c.Sheets.GoogleClientSecret == "") {
+		invalid("Google Sheets needs both a client ID and a client secret")
+	}
+	if c.Sheets.Enabled() && c.Sheets.RedirectURL == "" {
+		invalid("Google Sheets needs a redirect URL (GOOGLE_SHEETS_REDIRECT_URL)")
+	}
+	// Linked brokerages and sheets store credentials, which are encrypted
+	storesCredentials := c.Brokerage.PlaidClientID != "" || c.Brokerage.SnapTradeClientID != "" || c.Sheets.Enabled()
+	if storesCredentials || c.Auth.CredentialKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(c.Auth.CredentialKey); err != nil || len(key) != 32 {
+			invalid("credential key must be 32 bytes of base64 (CREDENTIAL_KEY), and is required to link brokerages or Google Sheets")
+		}
+	}
+	if c.Providers.YahooRateLimit <= 0 || c.Providers.EastmoneyRateLimit <= 0 || c.Providers.ExchangeRateRateLimit <= 0 {
+		invalid("provider rate limits must be positive")
+	}
+
+	for name, timeout := range map[string]time.Duration{
+		"request timeout":   c.Server.RequestTimeout,
+		"analytics timeout": c.Server.AnalyticsTimeout,
+		"backtest timeout":  c.Server.BacktestTimeout,
+	} {
+		if timeout > c.Server.WriteTimeout {
+			invalid("%s must not exceed the server write timeout, which would cut off its error response", name)
+		}
+	}
+
+	durations := map[string]time.Duration{
+		"server read timeout":      c.Server.ReadTimeout,
+		"server write timeout":     c.Server.WriteTimeout,
+		"request timeout":          c.Server.RequestTimeout,
+		"analytics timeout":        c.Server.AnalyticsTimeout,
+		"backtest timeout":         c.Server.BacktestTimeout,
+		"stream timeout":           c.Server.StreamTimeout,
+		"MongoDB connect timeout":  c.Mongo.ConnectTimeout,
+		"Yahoo Finance timeout":    c.Providers.YahooTimeout,
+		"Eastmoney timeout":        c.Providers.EastmoneyTimeout,
+		"exchange rate timeout":    c.Providers.ExchangeRateTimeout,
+		"quote cache TTL":          c.Cache.QuoteTTL,
+		"exchange rate cache TTL":  c.Cache.ExchangeRateTTL,
+		"news cache TTL":           c.Cache.NewsTTL,
+		"MongoDB max idle time":    c.Mongo.MaxConnIdle,
+		"cache warm active window": c.Cache.WarmActiveWindow,
+		"feature flag refresh":     c.Features.RefreshInterval,
+		"brokerage sync interval":  c.Brokerage.SyncInterval,
+		"sheets sync interval":     c.Sheets.SyncInterval,
+	}
+	for name, value := range durations {
+		if value <= 0 {
+			invalid("%s must be positive", name)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+}
+
+// validIPOrCIDR reports whether value is an IP address or a CIDR range
+func validIPOrCIDR(value string) bool {
+	if _, _, err := net.ParseCIDR(value); err == nil {
+		return true
+	}
+	return net.ParseIP(value) != nil
+}
+
+// envReader parses environment variables into settings, collecting errors
+type envReader struct {
+	lookup func(string) (string, bool)
+	errs   []error
+}
+
+func (e *envReader) value(key string) (string, bool) {
+	value, ok := e.lookup(key)
+	value = strings.TrimSpace(value)
+	return value, ok && value != ""
+}
+
+func (e *envReader) string(key string, target *string) {
+	if value, ok := e.value(key); ok {
+		*target = value
+	}
+}
+
+// list reads a comma-separated list
+func (e *envReader) list(key string, target *[]string) {
+	value, ok := e.value(key)
+	if !ok {
+		return
+	}
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*target = items
+}
+
+func (e *envReader) int(key string, target *int) {
+	if value, ok := e.value(key); ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be an integer: %q", key, value))
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (e *envReader) uint(key string, target *uint64) {
+	if value, ok := e.value(key); ok {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be a non-negative integer: %q", key, value))
+			return
+		}
+		*target = parsed
+	}
+}
+
+// float reads a decimal number such as "2.5"
+func (e *envReader) float(key string, target *float64) {
+	if value, ok := e.value(key); ok {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be a number: %q", key, value))
+			return
+		}
+		*target = parsed
+	}
+}
+
+// bool reads true/false, 1/0 and similar values accepted by strconv.ParseBool
+func (e *envReader) bool(key string, target *bool) {
+	if value, ok := e.value(key); ok {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be true or false: %q", key, value))
+			return
+		}
+		*target = parsed
+	}
+}
+
+// flags reads comma-separated name=bool pairs such as "new-engine=true",
+// replacing any flags set in the file
+func (e *envReader) flags(key string, target *map[string]bool) {
+	var items []string
+	e.list(key, &items)
+	if items == nil {
+		return
+	}
+	flags := make(map[string]bool, len(items))
+	for _, item := range items {
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+		if !ok || name == "" || err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must list name=true or name=false pairs: %q", key, item))
+			continue
+		}
+		flags[name] = parsed
+	}
+	*target = flags
+}
+
+// duration reads a Go duration such as "30s" or "5m"
+func (e *envReader) duration(key string, target *time.Duration) {
+	if value, ok := e.value(key); ok {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s must be a duration such as 30s: %q", key, value))
+			return
+		}
+		*target = parsed
+	}
+}
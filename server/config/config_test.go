@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lookupFrom(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestLoadLayersFileAndEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `
+server:
+  port: "9000"
+  corsOrigins: [https://a.example.com]
+mongo:
+  uri: mongodb://file-host:27017
+  maxPoolSize: 20
+cache:
+  quoteTtl: 2m
+rateLimit:
+  authPerMinute: 10
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Default()
+	if err := cfg.loadFile(path); err != nil {
+		t.Fatalf("loadFile failed: %v", err)
+	}
+	err := cfg.loadEnv(lookupFrom(map[string]string{
+		"MONGODB_URI":   "mongodb://env-host:27017",
+		"JWT_SECRET":    "secret",
+		"CORS_ORIGIN":   "https://b.example.com, https://c.example.com",
+		"YAHOO_TIMEOUT": "5s",
+		"SMTP_HOST":     "",
+		"FEATURE_FLAGS": "new-engine=true, eastmoney-first=false",
+
+		"YAHOO_RATE_LIMIT":           "2.5",
+		"MONGODB_MIGRATE_ON_STARTUP": "false",
+	}))
+	if err != nil {
+		t.Fatalf("loadEnv failed: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	if cfg.Server.Port != "9000" || cfg.Mongo.MaxPoolSize != 20 || cfg.Cache.QuoteTTL != 2*time.Minute || cfg.RateLimit.AuthPerMinute != 10 {
+		t.Errorf("File values not applied: %+v", cfg)
+	}
+	if cfg.Mongo.URI != "mongodb://env-host:27017" {
+		t.Errorf("Expected the environment to override the file, got %s", cfg.Mongo.URI)
+	}
+	if len(cfg.Server.CORSOrigins) != 2 || cfg.Server.CORSOrigins[1] != "https://c.example.com" {
+		t.Errorf("Unexpected CORS origins %v", cfg.Server.CORSOrigins)
+	}
+	if cfg.Providers.YahooTimeout != 5*time.Second {
+		t.Errorf("Expected Yahoo timeout 5s, got %v", cfg.Providers.YahooTimeout)
+	}
+	if cfg.Providers.YahooRateLimit != 2.5 {
+		t.Errorf("Expected Yahoo rate limit 2.5, got %v", cfg.Providers.YahooRateLimit)
+	}
+	if cfg.Mongo.MigrateOnStartup {
+		t.Errorf("Expected startup migrations to be disabled")
+	}
+	if len(cfg.Features.Overrides) != 2 || !cfg.Features.Overrides["new-engine"] || cfg.Features.Overrides["eastmoney-first"] {
+		t.Errorf("Unexpected feature flag overrides %v", cfg.Features.Overrides)
+	}
+	// Untouched settings keep their defaults
+	if cfg.Mongo.MinPoolSize != 10 || cfg.RateLimit.GlobalPerMinute != 500 || cfg.SMTP.Port != "587" {
+		t.Errorf("Defaults not kept: %+v", cfg)
+	}
+}
+
+func TestLoadReportsInvalidSettings(t *testing.T) {
+	cfg := Default()
+	err := cfg.loadEnv(lookupFrom(map[string]string{
+		"RATE_LIMIT_GLOBAL":          "many",
+		"QUOTE_CACHE_TTL":            "300",
+		"MONGODB_MIGRATE_ON_STARTUP": "sometimes",
+		"FEATURE_FLAGS":              "new-engine",
+		"EASTMONEY_RATE_LIMIT":       "fast",
+	}))
+	for _, want := range []string{"RATE_LIMIT_GLOBAL", "QUOTE_CACHE_TTL", "MONGODB_MIGRATE_ON_STARTUP", "FEATURE_FLAGS", "EASTMONEY_RATE_LIMIT"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected malformed %s to be reported, got %v", want, err)
+		}
+	}
+
+	cfg = Default()
+	cfg.Server.Port = "http"
+	cfg.Mongo.MinPoolSize = 100
+	cfg.Server.BacktestTimeout = 2 * time.Minute
+	cfg.Server.AdminToken = "short"
+	cfg.Providers.ExchangeRateRateLimit = 0
+	cfg.Sheets.GoogleClientID = "client"
+	cfg.Sheets.GoogleClientSecret = "secret"
+	cfg.Auth.CredentialKey = "c2hvcnQ="
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation to fail")
+	}
+	for _, want := range []string{"MONGODB_URI", "JWT_SECRET", "port", "pool size", "backtest timeout", "admin token", "provider rate limits", "GOOGLE_SHEETS_REDIRECT_URL", "CREDENTIAL_KEY"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Package currencycode normalizes currency codes. The renminbi is RMB
+// throughout the tracker, in stored transactions and in every response, while
+// its ISO 4217 code CNY is what quote and rate providers use. Both are accepted
+// as input; Normalize reports either as RMB and ISO converts back for
+// providers.
+package currencycode
+
+import "strings"
+
+const (
+	USD = "USD"
+	// RMB is the tracker's code for the renminbi
+	RMB = "RMB"
+	// CNY is the renminbi's ISO 4217 code
+	CNY = "CNY"
+)
+
+// Normalize upper-cases and trims a currency code and reports CNY as RMB
+func Normalize(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == CNY {
+		return RMB
+	}
+	return code
+}
+
+// ISO returns the ISO 4217 code providers expect, CNY for RMB
+func ISO(code string) string {
+	code = Normalize(code)
+	if code == RMB {
+		return CNY
+	}
+	return code
+}
+
+// Equal reports whether two codes name the same currency
+func Equal(a, b string) bool {
+	return Normalize(a) == Normalize(b)
+}
+
+// IsTransactionCurrency reports whether transactions may be recorded in the
+// currency, which is USD or RMB in either spelling
+func IsTransactionCurrency(code string) bool {
+	switch Normalize(code) {
+	case USD, RMB:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,30 @@
+package currencycode
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	for input, want := range map[string]string{"CNY": "RMB", " cny ": "RMB", "rmb": "RMB", "usd": "USD", "HKD": "HKD", "": ""} {
+		if got := Normalize(input); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestISO(t *testing.T) {
+	for input, want := range map[string]string{"RMB": "CNY", "cny": "CNY", "usd": "USD", "EUR": "EUR"} {
+		if got := ISO(input); got != want {
+			t.Errorf("ISO(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestIsTransactionCurrency(t *testing.T) {
+	for input, want := range map[string]bool{"USD": true, "RMB": true, "CNY": true, "cny": true, "HKD": false, "": false} {
+		if got := IsTransactionCurrency(input); got != want {
+			t.Errorf("IsTransactionCurrency(%q) = %v, want %v", input, got, want)
+		}
+	}
+	if !Equal("CNY", "rmb") || Equal("USD", "RMB") {
+		t.Error("Expected CNY and RMB to be equal and USD to differ")
+	}
+}
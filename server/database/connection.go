@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"stock-portfolio-tracker/config"
+	"stock-portfolio-tracker/telemetry"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -16,17 +18,26 @@ var (
 	Database *mongo.Database
 )
 
-// Connect establishes a connection to MongoDB using the provided URI
+// Connect establishes a connection to MongoDB using the provided URI and the
+// default pool settings
 func Connect(mongoURI string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	cfg := config.Default().Mongo
+	cfg.URI = mongoURI
+	return ConnectWithConfig(cfg)
+}
+
+// ConnectWithConfig establishes a connection to MongoDB with the given settings
+func ConnectWithConfig(cfg config.MongoConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
 	defer cancel()
 
 	// Set client options with connection pooling
 	clientOptions := options.Client().
-		ApplyURI(mongoURI).
-		SetMaxPoolSize(50).
-		SetMinPoolSize(10).
-		SetMaxConnIdleTime(30 * time.Second)
+		ApplyURI(cfg.URI).
+		SetMaxPoolSize(cfg.MaxPoolSize).
+		SetMinPoolSize(cfg.MinPoolSize).
+		SetMaxConnIdleTime(cfg.MaxConnIdle).
+		SetMonitor(telemetry.NewMongoMonitor())
 
 	// Connect to MongoDB
 	client, err := mongo.Connect(ctx, clientOptions)
@@ -43,7 +54,7 @@ func Connect(mongoURI string) error {
 	}
 
 	Client = client
-	Database = client.Database("stock-portfolio")
+	Database = client.Database(cfg.Database)
 	
 	log.Println("Successfully connected to MongoDB")
 	return nil
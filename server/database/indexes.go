@@ -35,6 +35,81 @@ func CreateIndexes() error {
 		return err
 	}
 
+	// Create indexes for AssetSubclasses collection
+	if err := createAssetSubclassIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for BenchmarkBlends collection
+	if err := createBenchmarkBlendIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for CustomBenchmarks collection
+	if err := createCustomBenchmarkIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for UserSettings collection
+	if err := createUserSettingsIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for ShareLinks collection
+	if err := createShareLinkIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for AccountLinks collection
+	if err := createAccountLinkIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for PendingOrders collection
+	if err := createPendingOrderIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for Webhooks collection
+	if err := createWebhookIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for BrokerageLinks collection
+	if err := createBrokerageLinkIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for SheetsLinks collection
+	if err := createSheetsLinkIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for CashInterestRates collection
+	if err := createCashInterestIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for PriceOverrides collection
+	if err := createPriceOverrideIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for Sessions collection
+	if err := createSessionIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for Outbox collection
+	if err := createOutboxIndexes(ctx); err != nil {
+		return err
+	}
+
+	// Create indexes for Symbols collection
+	if err := createSymbolIndexes(ctx); err != nil {
+		return err
+	}
+
 	log.Println("Successfully created all database indexes")
 	return nil
 }
@@ -92,11 +167,18 @@ func createPortfolioIndexes(ctx context.Context) error {
 		},
 	}
 
+	// Partial index on the entries with a stop, for the stop monitoring job
+	stopIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "stop.triggered_at", Value: 1}},
+		Options: options.Index().SetPartialFilterExpression(bson.M{"stop": bson.M{"$exists": true}}),
+	}
+
 	indexes := []mongo.IndexModel{
 		userIDIndex,
 		userSymbolIndex,
 		userAssetStyleIndex,
 		userAssetClassIndex,
+		stopIndex,
 	}
 	_, err := collection.Indexes().CreateMany(ctx, indexes)
 	if err != nil {
@@ -134,11 +216,42 @@ func createTransactionIndexes(ctx context.Context) error {
 		Keys: bson.D{{Key: "date", Value: 1}},
 	}
 
+	// Compound index on user_id + date for the date-ordered holdings aggregation
+	userDateIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "date", Value: 1},
+		},
+	}
+
+	// Compound multikey index on user_id + tags for filtering by tag
+	userTagsIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "tags", Value: 1},
+		},
+	}
+
+	// Unique index on the broker IDs of imported transactions, so a
+	// statement imported twice cannot record a row twice
+	externalIDIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "broker", Value: 1},
+			{Key: "external_id", Value: 1},
+		},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"external_id": bson.M{"$exists": true}}),
+	}
+
 	indexes := []mongo.IndexModel{
 		userIDIndex,
 		portfolioIDIndex,
 		userSymbolIndex,
 		dateIndex,
+		userDateIndex,
+		userTagsIndex,
+		externalIDIndex,
 	}
 
 	_, err := collection.Indexes().CreateMany(ctx, indexes)
@@ -177,3 +290,392 @@ func createAssetStyleIndexes(ctx context.Context) error {
 	log.Println("Created indexes on asset_styles collection")
 	return nil
 }
+
+// createAssetSubclassIndexes creates indexes for the asset_subclasses collection
+func createAssetSubclassIndexes(ctx context.Context) error {
+	collection := Database.Collection("asset_subclasses")
+
+	// Compound unique index on user_id + asset_class + name (unique names per class)
+	userClassNameIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "asset_class", Value: 1},
+			{Key: "name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{userClassNameIndex})
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on asset_subclasses collection")
+	return nil
+}
+
+// createBenchmarkBlendIndexes creates indexes for the benchmark_blends collection
+func createBenchmarkBlendIndexes(ctx context.Context) error {
+	collection := Database.Collection("benchmark_blends")
+
+	// Compound unique index on user_id + name
+	userNameIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, userNameIndex)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created index on benchmark_blends.user_id+name")
+	return nil
+}
+
+// createCustomBenchmarkIndexes creates indexes for the custom_benchmarks collection
+func createCustomBenchmarkIndexes(ctx context.Context) error {
+	collection := Database.Collection("custom_benchmarks")
+
+	// Compound unique index on user_id + symbol
+	userSymbolIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "symbol", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, userSymbolIndex)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created index on custom_benchmarks.user_id+symbol")
+	return nil
+}
+
+// createUserSettingsIndexes creates indexes for the user_settings collection
+func createUserSettingsIndexes(ctx context.Context) error {
+	collection := Database.Collection("user_settings")
+
+	// Unique index on user_id (one settings document per user)
+	userIDIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	// Index on summary_email.frequency for the summary email job
+	frequencyIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "summary_email.frequency", Value: 1}},
+	}
+
+	// Index on drift_alerts.enabled for the drift alert job
+	driftAlertsIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "drift_alerts.enabled", Value: 1}},
+	}
+
+	indexes := []mongo.IndexModel{userIDIndex, frequencyIndex, driftAlertsIndex}
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on user_settings collection")
+	return nil
+}
+
+// createShareLinkIndexes creates indexes for the share_links collection
+func createShareLinkIndexes(ctx context.Context) error {
+	collection := Database.Collection("share_links")
+
+	// Unique index on token_hash for public lookups
+	tokenIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "token_hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	// Index on user_id for listing a user's links
+	userIDIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}},
+	}
+
+	indexes := []mongo.IndexModel{tokenIndex, userIDIndex}
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on share_links collection")
+	return nil
+}
+
+// createAccountLinkIndexes creates indexes for the account_links collection
+func createAccountLinkIndexes(ctx context.Context) error {
+	collection := Database.Collection("account_links")
+
+	// Unique index on owner_id+viewer_id (one link per pair of accounts)
+	pairIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "owner_id", Value: 1},
+			{Key: "viewer_id", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	// Index on viewer_id+status for resolving a household
+	viewerIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "viewer_id", Value: 1},
+			{Key: "status", Value: 1},
+		},
+	}
+
+	indexes := []mongo.IndexModel{pairIndex, viewerIndex}
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on account_links collection")
+	return nil
+}
+
+// createPendingOrderIndexes creates indexes for the pending_orders collection
+func createPendingOrderIndexes(ctx context.Context) error {
+	collection := Database.Collection("pending_orders")
+
+	// Index on user_id+created_at for listing a user's orders
+	userCreatedIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "created_at", Value: -1},
+		},
+	}
+
+	// Index on status for the job that watches open orders
+	statusIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}},
+	}
+
+	indexes := []mongo.IndexModel{userCreatedIndex, statusIndex}
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on pending_orders collection")
+	return nil
+}
+
+// createWebhookIndexes creates indexes for the webhooks collection
+func createWebhookIndexes(ctx context.Context) error {
+	collection := Database.Collection("webhooks")
+
+	// Index on user_id+created_at for listing a user's webhooks
+	userCreatedIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "created_at", Value: 1},
+		},
+	}
+
+	// Index on events for the job that posts daily summaries
+	eventsIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "events", Value: 1}},
+	}
+
+	indexes := []mongo.IndexModel{userCreatedIndex, eventsIndex}
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on webhooks collection")
+	return nil
+}
+
+// createBrokerageLinkIndexes creates indexes for the brokerage_links collection
+func createBrokerageLinkIndexes(ctx context.Context) error {
+	collection := Database.Collection("brokerage_links")
+
+	// Index on user_id+created_at for listing a user's links
+	userCreatedIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "created_at", Value: 1},
+		},
+	}
+
+	// Index on status for the job that syncs active links
+	statusIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}},
+	}
+
+	indexes := []mongo.IndexModel{userCreatedIndex, statusIndex}
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on brokerage_links collection")
+	return nil
+}
+
+// createSheetsLinkIndexes creates indexes for the sheets_links collection
+func createSheetsLinkIndexes(ctx context.Context) error {
+	collection := Database.Collection("sheets_links")
+
+	// Unique index on user_id: one linked sheet per user
+	userIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	// Index on status for the job that syncs active links
+	statusIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}},
+	}
+
+	indexes := []mongo.IndexModel{userIndex, statusIndex}
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on sheets_links collection")
+	return nil
+}
+
+// createCashInterestIndexes creates indexes for the cash_interest_rates collection
+func createCashInterestIndexes(ctx context.Context) error {
+	collection := Database.Collection("cash_interest_rates")
+
+	// Unique index on user_id+symbol: one rate per cash balance
+	userSymbolIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "symbol", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	// Index on apy for the job that accrues interest
+	apyIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "apy", Value: 1}},
+	}
+
+	indexes := []mongo.IndexModel{userSymbolIndex, apyIndex}
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on cash_interest_rates collection")
+	return nil
+}
+
+// createPriceOverrideIndexes creates indexes for the price_overrides collection
+func createPriceOverrideIndexes(ctx context.Context) error {
+	collection := Database.Collection("price_overrides")
+
+	// Unique index on user_id+symbol: one manual price per symbol
+	userSymbolIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "symbol", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, userSymbolIndex)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on price_overrides collection")
+	return nil
+}
+
+// createSessionIndexes creates indexes for the sessions collection
+func createSessionIndexes(ctx context.Context) error {
+	collection := Database.Collection("sessions")
+
+	// Compound index on user_id + last_seen_at for listing a user's sessions
+	userLastSeenIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "last_seen_at", Value: -1},
+		},
+	}
+
+	// Index on last_seen_at for finding recently active users across accounts
+	lastSeenIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "last_seen_at", Value: -1}},
+	}
+
+	// TTL index removing sessions once their token has expired
+	expiryIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	indexes := []mongo.IndexModel{userLastSeenIndex, lastSeenIndex, expiryIndex}
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on sessions collection")
+	return nil
+}
+
+// createOutboxIndexes creates indexes for the outbox collection
+func createOutboxIndexes(ctx context.Context) error {
+	collection := Database.Collection("outbox")
+
+	// Compound index on status + next_attempt_at for the delivery worker
+	dueIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "next_attempt_at", Value: 1},
+		},
+	}
+
+	// TTL index removing delivered messages after 30 days
+	deliveredIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "delivered_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60),
+	}
+
+	indexes := []mongo.IndexModel{dueIndex, deliveredIndex}
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on outbox collection")
+	return nil
+}
+
+// createSymbolIndexes creates indexes for the symbols collection
+func createSymbolIndexes(ctx context.Context) error {
+	collection := Database.Collection("symbols")
+
+	// Sparse index on renamed_to for finding the former tickers of a symbol
+	renamedIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "renamed_to", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, renamedIndex)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created indexes on symbols collection")
+	return nil
+}
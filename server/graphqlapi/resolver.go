@@ -0,0 +1,261 @@
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//go:embed schema.graphql
+var schemaSource string
+
+// Query limits guard the API against expensive nested queries
+const (
+	maxQueryDepth  = 8
+	maxQueryLength = 8 * 1024
+)
+
+var errUnauthenticated = errors.New("user not authenticated")
+
+// Resolver resolves the root query fields
+type Resolver struct {
+	portfolioService  *services.PortfolioService
+	stockService      *services.StockAPIService
+	analyticsService  *services.AnalyticsService
+	assetStyleService *services.AssetStyleService
+}
+
+// NewSchema parses the schema and binds it to the services
+func NewSchema(portfolioService *services.PortfolioService, stockService *services.StockAPIService, analyticsService *services.AnalyticsService, assetStyleService *services.AssetStyleService) (*graphql.Schema, error) {
+	resolver := &Resolver{
+		portfolioService:  portfolioService,
+		stockService:      stockService,
+		analyticsService:  analyticsService,
+		assetStyleService: assetStyleService,
+	}
+	return graphql.ParseSchema(schemaSource, resolver,
+		graphql.MaxDepth(maxQueryDepth),
+		graphql.MaxQueryLength(maxQueryLength),
+	)
+}
+
+// requestState carries the user and the entries loaded once per request, so
+// nested fields resolved for every holding do not repeat the same queries
+type requestState struct {
+	userID primitive.ObjectID
+
+	once       sync.Once
+	portfolios map[string]models.Portfolio  // By symbol
+	styles     map[string]models.AssetStyle // By ID
+	styleList  []models.AssetStyle
+	loadErr    error
+
+	txOnce               sync.Once
+	transactionsBySymbol map[string][]models.Transaction // Newest first
+	txErr                error
+}
+
+type requestStateKey struct{}
+
+// WithUser returns a context that resolves queries for the user
+func WithUser(ctx context.Context, userID primitive.ObjectID) context.Context {
+	return context.WithValue(ctx, requestStateKey{}, &requestState{userID: userID})
+}
+
+func stateFromContext(ctx context.Context) (*requestState, error) {
+	state, ok := ctx.Value(requestStateKey{}).(*requestState)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+	return state, nil
+}
+
+// metadata loads the user's portfolio entries and asset styles on first use
+func (r *Resolver) metadata(ctx context.Context) (*requestState, error) {
+	state, err := stateFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state.once.Do(func() {
+		portfolios, err := r.portfolioService.GetUserPortfolios(ctx, state.userID)
+		if err != nil {
+			state.loadErr = err
+			return
+		}
+		styles, err := r.assetStyleService.GetUserAssetStyles(state.userID)
+		if err != nil {
+			state.loadErr = fmt.Errorf("failed to fetch asset styles: %w", err)
+			return
+		}
+
+		state.portfolios = make(map[string]models.Portfolio, len(portfolios))
+		for _, portfolio := range portfolios {
+			state.portfolios[portfolio.Symbol] = portfolio
+		}
+		state.styleList = styles
+		state.styles = make(map[string]models.AssetStyle, len(styles))
+		for _, style := range styles {
+			state.styles[style.ID.Hex()] = style
+		}
+	})
+	return state, state.loadErr
+}
+
+// transactions loads all of the user's transactions on first use
+func (r *Resolver) transactions(ctx context.Context) (*requestState, error) {
+	state, err := stateFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state.txOnce.Do(func() {
+		transactions, err := r.portfolioService.GetTransactionsSince(state.userID, time.Time{})
+		if err != nil {
+			state.txErr = err
+			return
+		}
+		state.transactionsBySymbol = make(map[string][]models.Transaction)
+		for _, tx := range transactions {
+			state.transactionsBySymbol[tx.Symbol] = append(state.transactionsBySymbol[tx.Symbol], tx)
+		}
+	})
+	return state, state.txErr
+}
+
+// normalizeCurrency validates a requested currency, defaulting to USD
+func normalizeCurrency(currency string) (string, error) {
+	if currency == "" {
+		return "USD", nil
+	}
+	return services.NormalizeDisplayCurrency(currency)
+}
+
+// validatePeriod validates a requested period, defaulting to 1M
+func validatePeriod(period string) (string, error) {
+	switch period {
+	case "":
+		return "1M", nil
+	case "1M", "3M", "6M", "1Y", "ALL":
+		return period, nil
+	}
+	return "", fmt.Errorf("invalid period %q: must be one of 1M, 3M, 6M, 1Y, ALL", period)
+}
+
+// Holdings resolves the user's open positions
+func (r *Resolver) Holdings(ctx context.Context, args struct{ Currency string }) ([]*holdingResolver, error) {
+	state, err := stateFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := normalizeCurrency(args.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	holdings, err := r.portfolioService.GetUserHoldingsContext(ctx, state.userID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*holdingResolver, 0, len(holdings))
+	for _, holding := range holdings {
+		resolvers = append(resolvers, &holdingResolver{root: r, holding: holding})
+	}
+	return resolvers, nil
+}
+
+// Transactions resolves the user's transactions, optionally for one symbol
+func (r *Resolver) Transactions(ctx context.Context, args struct{ Symbol *string }) ([]*transactionResolver, error) {
+	state, err := stateFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []models.Transaction
+	if args.Symbol != nil && strings.TrimSpace(*args.Symbol) != "" {
+		transactions, err = r.portfolioService.GetTransactionsBySymbol(state.userID, strings.ToUpper(strings.TrimSpace(*args.Symbol)))
+	} else {
+		transactions, err = r.portfolioService.GetTransactionsSince(state.userID, time.Time{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return transactionResolvers(transactions), nil
+}
+
+// Quote resolves the latest quote for a symbol
+func (r *Resolver) Quote(ctx context.Context, args struct{ Symbol string }) (*quoteResolver, error) {
+	if _, err := stateFromContext(ctx); err != nil {
+		return nil, err
+	}
+	info, err := r.stockService.GetStockInfoContext(ctx, args.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &quoteResolver{info: info}, nil
+}
+
+// Dashboard resolves the portfolio totals and allocation
+func (r *Resolver) Dashboard(ctx context.Context, args struct{ Currency string }) (*dashboardResolver, error) {
+	state, err := stateFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := normalizeCurrency(args.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := r.analyticsService.GetDashboardMetricsContext(ctx, state.userID, currency)
+	if err != nil {
+		return nil, err
+	}
+	return &dashboardResolver{metrics: metrics}, nil
+}
+
+// Performance resolves the portfolio value series over a period
+func (r *Resolver) Performance(ctx context.Context, args struct {
+	Period   string
+	Currency string
+}) (*performanceResolver, error) {
+	state, err := stateFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	period, err := validatePeriod(args.Period)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := normalizeCurrency(args.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	performance, err := r.analyticsService.GetHistoricalPerformanceWithProgress(ctx, state.userID, period, currency, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &performanceResolver{performance: performance}, nil
+}
+
+// AssetStyles resolves the user's asset styles
+func (r *Resolver) AssetStyles(ctx context.Context) ([]*assetStyleResolver, error) {
+	state, err := r.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*assetStyleResolver, 0, len(state.styleList))
+	for _, style := range state.styleList {
+		resolvers = append(resolvers, &assetStyleResolver{style: style})
+	}
+	return resolvers, nil
+}
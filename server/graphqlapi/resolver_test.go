@@ -0,0 +1,52 @@
+package graphqlapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestQueriesRequireUser(t *testing.T) {
+	schema, err := NewSchema(nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	resp := schema.Exec(context.Background(), `{ quote(symbol: "AAPL") { price } }`, "", nil)
+	if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, errUnauthenticated.Error()) {
+		t.Errorf("Expected unauthenticated error, got %v", resp.Errors)
+	}
+}
+
+func TestQueryLimits(t *testing.T) {
+	schema, err := NewSchema(nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	resp := schema.Exec(context.Background(), `{ holdings { `+strings.Repeat("symbol ", maxQueryLength)+`} }`, "", nil)
+	if len(resp.Errors) == 0 {
+		t.Error("Expected oversized query to be rejected")
+	}
+}
+
+func TestArgumentValidation(t *testing.T) {
+	if currency, err := normalizeCurrency("cny"); err != nil || currency != "RMB" {
+		t.Errorf("Expected CNY to normalize to RMB, got %q, %v", currency, err)
+	}
+	if currency, err := normalizeCurrency(""); err != nil || currency != "USD" {
+		t.Errorf("Expected USD by default, got %q, %v", currency, err)
+	}
+	if currency, err := normalizeCurrency("hkd"); err != nil || currency != "HKD" {
+		t.Errorf("Expected HKD to be accepted, got %q, %v", currency, err)
+	}
+	if _, err := normalizeCurrency("XYZ"); err == nil {
+		t.Error("Expected error for XYZ")
+	}
+	if period, err := validatePeriod(""); err != nil || period != "1M" {
+		t.Errorf("Expected default period, got %q, %v", period, err)
+	}
+	if _, err := validatePeriod("2W"); err == nil {
+		t.Error("Expected error for 2W")
+	}
+}
@@ -0,0 +1,201 @@
+package graphqlapi
+
+import (
+	"context"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// holdingResolver resolves a Holding
+type holdingResolver struct {
+	root    *Resolver
+	holding services.Holding
+}
+
+func (h *holdingResolver) PortfolioID() *graphql.ID {
+	if h.holding.PortfolioID == "" {
+		return nil
+	}
+	id := graphql.ID(h.holding.PortfolioID)
+	return &id
+}
+
+func (h *holdingResolver) Symbol() string           { return h.holding.Symbol }
+func (h *holdingResolver) Name() string             { return h.holding.Name }
+func (h *holdingResolver) Shares() float64          { return h.holding.Shares }
+func (h *holdingResolver) CostBasis() float64       { return h.holding.CostBasis }
+func (h *holdingResolver) CurrentPrice() float64    { return h.holding.CurrentPrice }
+func (h *holdingResolver) CurrentValue() float64    { return h.holding.CurrentValue }
+func (h *holdingResolver) GainLoss() float64        { return h.holding.GainLoss }
+func (h *holdingResolver) GainLossPercent() float64 { return h.holding.GainLossPercent }
+func (h *holdingResolver) Currency() string         { return h.holding.Currency }
+
+// Quote resolves the holding's quote, served from the stock service cache
+// warmed while pricing the holding
+func (h *holdingResolver) Quote(ctx context.Context) (*quoteResolver, error) {
+	info, err := h.root.stockService.GetStockInfoContext(ctx, h.holding.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &quoteResolver{info: info}, nil
+}
+
+// AssetStyle resolves the style assigned to the holding's portfolio entry
+func (h *holdingResolver) AssetStyle(ctx context.Context) (*assetStyleResolver, error) {
+	state, err := h.root.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	portfolio, ok := state.portfolios[h.holding.Symbol]
+	if !ok || portfolio.AssetStyleID == nil {
+		return nil, nil
+	}
+	style, ok := state.styles[portfolio.AssetStyleID.Hex()]
+	if !ok {
+		return nil, nil
+	}
+	return &assetStyleResolver{style: style}, nil
+}
+
+// AssetClass resolves the asset class of the holding's portfolio entry
+func (h *holdingResolver) AssetClass(ctx context.Context) (*string, error) {
+	state, err := h.root.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	portfolio, ok := state.portfolios[h.holding.Symbol]
+	if !ok || portfolio.AssetClass == "" {
+		return nil, nil
+	}
+	return &portfolio.AssetClass, nil
+}
+
+// Transactions resolves the holding's transactions, newest first
+func (h *holdingResolver) Transactions(ctx context.Context) ([]*transactionResolver, error) {
+	state, err := h.root.transactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return transactionResolvers(state.transactionsBySymbol[h.holding.Symbol]), nil
+}
+
+// quoteResolver resolves a Quote
+type quoteResolver struct {
+	info *services.StockInfo
+}
+
+func (q *quoteResolver) Symbol() string   { return q.info.Symbol }
+func (q *quoteResolver) Name() string     { return q.info.Name }
+func (q *quoteResolver) Price() float64   { return q.info.CurrentPrice }
+func (q *quoteResolver) Currency() string { return q.info.Currency }
+
+// assetStyleResolver resolves an AssetStyle
+type assetStyleResolver struct {
+	style models.AssetStyle
+}
+
+func (a *assetStyleResolver) ID() graphql.ID { return graphql.ID(a.style.ID.Hex()) }
+func (a *assetStyleResolver) Name() string   { return a.style.Name }
+
+// transactionResolver resolves a Transaction
+type transactionResolver struct {
+	tx models.Transaction
+}
+
+func transactionResolvers(transactions []models.Transaction) []*transactionResolver {
+	resolvers := make([]*transactionResolver, 0, len(transactions))
+	for _, tx := range transactions {
+		resolvers = append(resolvers, &transactionResolver{tx: tx})
+	}
+	return resolvers
+}
+
+func (t *transactionResolver) ID() graphql.ID          { return graphql.ID(t.tx.ID.Hex()) }
+func (t *transactionResolver) PortfolioID() graphql.ID { return graphql.ID(t.tx.PortfolioID.Hex()) }
+func (t *transactionResolver) Symbol() string          { return t.tx.Symbol }
+func (t *transactionResolver) Action() string          { return t.tx.Action }
+func (t *transactionResolver) Shares() float64         { return t.tx.Shares }
+func (t *transactionResolver) Price() float64          { return t.tx.Price }
+func (t *transactionResolver) Currency() string        { return t.tx.Currency }
+func (t *transactionResolver) Fees() float64           { return t.tx.Fees }
+func (t *transactionResolver) Date() graphql.Time      { return graphql.Time{Time: t.tx.Date} }
+
+// dashboardResolver resolves a Dashboard
+type dashboardResolver struct {
+	metrics *services.DashboardMetrics
+}
+
+func (d *dashboardResolver) Currency() string          { return d.metrics.Currency }
+func (d *dashboardResolver) TotalValue() float64       { return d.metrics.TotalValue }
+func (d *dashboardResolver) TotalGain() float64        { return d.metrics.TotalGain }
+func (d *dashboardResolver) PercentageReturn() float64 { return d.metrics.PercentageReturn }
+func (d *dashboardResolver) DayChange() float64        { return d.metrics.DayChange }
+func (d *dashboardResolver) DayChangePercent() float64 { return d.metrics.DayChangePercent }
+
+func (d *dashboardResolver) Allocation() []*allocationResolver {
+	resolvers := make([]*allocationResolver, 0, len(d.metrics.Allocation))
+	for _, item := range d.metrics.Allocation {
+		resolvers = append(resolvers, &allocationResolver{item: item})
+	}
+	return resolvers
+}
+
+// allocationResolver resolves an AllocationItem
+type allocationResolver struct {
+	item services.AllocationItem
+}
+
+func (a *allocationResolver) Symbol() string      { return a.item.Symbol }
+func (a *allocationResolver) Name() string        { return a.item.Name }
+func (a *allocationResolver) Value() float64      { return a.item.Value }
+func (a *allocationResolver) Percentage() float64 { return a.item.Percentage }
+
+// performanceResolver resolves a Performance
+type performanceResolver struct {
+	performance *services.PerformanceResponse
+}
+
+func (p *performanceResolver) Period() string   { return p.performance.Period }
+func (p *performanceResolver) Currency() string { return p.performance.Currency }
+
+func (p *performanceResolver) Points() []*performancePointResolver {
+	resolvers := make([]*performancePointResolver, 0, len(p.performance.Performance))
+	for _, point := range p.performance.Performance {
+		resolvers = append(resolvers, &performancePointResolver{point: point})
+	}
+	return resolvers
+}
+
+func (p *performanceResolver) TotalReturn() float64 {
+	if p.performance.Metrics == nil {
+		return 0
+	}
+	return p.performance.Metrics.TotalReturn.Percentage
+}
+
+func (p *performanceResolver) PeriodReturn() float64 {
+	if p.performance.Metrics == nil {
+		return 0
+	}
+	return p.performance.Metrics.PeriodReturn.Percentage
+}
+
+func (p *performanceResolver) MaxDrawdown() float64 {
+	if p.performance.Metrics == nil {
+		return 0
+	}
+	return p.performance.Metrics.MaxDrawdown.Percentage
+}
+
+// performancePointResolver resolves a PerformancePoint
+type performancePointResolver struct {
+	point services.PerformanceDataPoint
+}
+
+func (p *performancePointResolver) Date() graphql.Time        { return graphql.Time{Time: p.point.Date} }
+func (p *performancePointResolver) Value() float64            { return p.point.Value }
+func (p *performancePointResolver) PercentageReturn() float64 { return p.point.PercentageReturn }
+func (p *performancePointResolver) DayChange() float64        { return p.point.DayChange }
+func (p *performancePointResolver) DayChangePercent() float64 { return p.point.DayChangePercent }
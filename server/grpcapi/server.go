@@ -0,0 +1,133 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"stock-portfolio-tracker/services"
+	"strings"
+	"time"
+
+	trackerv1 "stock-portfolio-tracker/proto/tracker/v1"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// userIDKey is the context key holding the authenticated user's ID
+type userIDKey struct{}
+
+// NewServer creates a gRPC server exposing the stock, portfolio and analytics
+// services. Every call must carry a bearer token in its metadata. Unlike the
+// REST API, calls are not subject to the IP filter, rate limits or
+// maintenance mode.
+func NewServer(authService *services.AuthService, stockService *services.StockAPIService, portfolioService *services.PortfolioService, analyticsService *services.AnalyticsService) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		loggingInterceptor,
+		recoveryInterceptor,
+		authInterceptor(authService),
+	))
+
+	trackerv1.RegisterStockServiceServer(server, &stockServer{stockService: stockService})
+	trackerv1.RegisterPortfolioServiceServer(server, &portfolioServer{portfolioService: portfolioService})
+	trackerv1.RegisterAnalyticsServiceServer(server, &analyticsServer{analyticsService: analyticsService})
+	return server
+}
+
+// loggingInterceptor logs each call with its duration and status code
+func loggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	fmt.Printf("[gRPC] %s %s %v\n", info.FullMethod, status.Code(err), time.Since(start))
+	return resp, err
+}
+
+// recoveryInterceptor turns a panicking call into an Internal error, as
+// grpc-go would otherwise let the panic take down the whole process
+func recoveryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			fmt.Printf("[gRPC] Panic in %s: %v\n%s", info.FullMethod, recovered, debug.Stack())
+			resp, err = nil, status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// authInterceptor validates the bearer token in the call metadata and stores
+// the user's ID in the context
+func authInterceptor(authService *services.AuthService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "authorization metadata is required")
+		}
+
+		parts := strings.SplitN(values[0], " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			return nil, status.Error(codes.Unauthenticated, "authorization must be in format: Bearer <token>")
+		}
+
+		user, err := authService.ValidateToken(parts[1])
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+		}
+
+		return handler(context.WithValue(ctx, userIDKey{}, user.ID), req)
+	}
+}
+
+// userIDFromContext returns the authenticated user's ID
+func userIDFromContext(ctx context.Context) (primitive.ObjectID, error) {
+	userID, ok := ctx.Value(userIDKey{}).(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, status.Error(codes.Unauthenticated, "user not authenticated")
+	}
+	return userID, nil
+}
+
+// normalizeCurrency validates a requested currency, defaulting to USD
+func normalizeCurrency(currency string) (string, error) {
+	if currency == "" {
+		return "USD", nil
+	}
+	normalized, err := services.NormalizeDisplayCurrency(currency)
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, "currency must be one of "+strings.Join(services.DisplayCurrencies, ", "))
+	}
+	return normalized, nil
+}
+
+// validatePeriod validates a requested period, applying the default when empty
+func validatePeriod(period string, defaultPeriod string) (string, error) {
+	if period == "" {
+		return defaultPeriod, nil
+	}
+	switch period {
+	case "1M", "3M", "6M", "1Y", "ALL":
+		return period, nil
+	}
+	return "", status.Error(codes.InvalidArgument, "period must be one of 1M, 3M, 6M, 1Y, ALL")
+}
+
+// toStatus maps service errors to gRPC status codes
+func toStatus(err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidSymbol), errors.Is(err, services.ErrInvalidPeriod):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, services.ErrStockNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrExternalAPI):
+		return status.Errorf(codes.Unavailable, "%s: %v", message, err)
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Errorf(codes.Internal, "%s: %v", message, err)
+}
@@ -0,0 +1,106 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"stock-portfolio-tracker/services"
+	"testing"
+
+	trackerv1 "stock-portfolio-tracker/proto/tracker/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestCallsRequireBearerToken(t *testing.T) {
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(services.NewAuthService("test-secret"), services.NewStockAPIService(services.StockAPIConfig{}), nil, nil)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	client := trackerv1.NewStockServiceClient(conn)
+
+	tests := []struct {
+		name     string
+		metadata []string
+	}{
+		{"missing", nil},
+		{"not bearer", []string{"authorization", "Basic abc"}},
+		{"invalid token", []string{"authorization", "Bearer not-a-jwt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.metadata != nil {
+				ctx = metadata.AppendToOutgoingContext(ctx, tt.metadata...)
+			}
+			_, err := client.GetQuote(ctx, &trackerv1.GetQuoteRequest{Symbol: "CASH_USD"})
+			if status.Code(err) != codes.Unauthenticated {
+				t.Errorf("Expected Unauthenticated, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRecoveryInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/tracker.v1.AnalyticsService/GetDashboard"}
+	_, err := recoveryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("currency mismatch")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected Internal, got %v", err)
+	}
+
+	resp, err := recoveryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	if resp != "ok" || err != nil {
+		t.Errorf("Expected the handler's result, got %v, %v", resp, err)
+	}
+}
+
+func TestRequestValidationAndErrorCodes(t *testing.T) {
+	if currency, err := normalizeCurrency("cny"); err != nil || currency != "RMB" {
+		t.Errorf("Expected CNY to normalize to RMB, got %q, %v", currency, err)
+	}
+	if currency, err := normalizeCurrency(""); err != nil || currency != "USD" {
+		t.Errorf("Expected USD by default, got %q, %v", currency, err)
+	}
+	if currency, err := normalizeCurrency("eur"); err != nil || currency != "EUR" {
+		t.Errorf("Expected EUR to be accepted, got %q, %v", currency, err)
+	}
+	if _, err := normalizeCurrency("XYZ"); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for XYZ, got %v", err)
+	}
+	if period, err := validatePeriod("", "1Y"); err != nil || period != "1Y" {
+		t.Errorf("Expected default period, got %q, %v", period, err)
+	}
+	if _, err := validatePeriod("2W", "1M"); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for 2W, got %v", err)
+	}
+
+	codesByError := map[error]codes.Code{
+		services.ErrInvalidSymbol:                                  codes.InvalidArgument,
+		fmt.Errorf("wrapped: %w", services.ErrStockNotFound):       codes.NotFound,
+		fmt.Errorf("%w: status code 502", services.ErrExternalAPI): codes.Unavailable,
+		fmt.Errorf("failed to decode"):                             codes.Internal,
+	}
+	for err, want := range codesByError {
+		if got := status.Code(toStatus(err, "failed")); got != want {
+			t.Errorf("%v: expected %v, got %v", err, want, got)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package grpcapi
+
+import (
+	"context"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+	"strings"
+	"time"
+
+	trackerv1 "stock-portfolio-tracker/proto/tracker/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// stockServer implements trackerv1.StockServiceServer
+type stockServer struct {
+	trackerv1.UnimplementedStockServiceServer
+	stockService *services.StockAPIService
+}
+
+// GetQuote returns the latest quote for a symbol
+func (s *stockServer) GetQuote(ctx context.Context, req *trackerv1.GetQuoteRequest) (*trackerv1.Quote, error) {
+	info, err := s.stockService.GetStockInfoContext(ctx, req.GetSymbol())
+	if err != nil {
+		return nil, toStatus(err, "failed to fetch quote")
+	}
+	return &trackerv1.Quote{
+		Symbol:   info.Symbol,
+		Name:     info.Name,
+		Price:    info.CurrentPrice,
+		Currency: info.Currency,
+	}, nil
+}
+
+// GetHistory returns daily closes for a symbol over a period, defaulting to 1Y
+func (s *stockServer) GetHistory(ctx context.Context, req *trackerv1.GetHistoryRequest) (*trackerv1.GetHistoryResponse, error) {
+	period, err := validatePeriod(req.GetPeriod(), "1Y")
+	if err != nil {
+		return nil, err
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(req.GetSymbol()))
+	prices, err := s.stockService.GetHistoricalDataContext(ctx, symbol, period)
+	if err != nil {
+		return nil, toStatus(err, "failed to fetch price history")
+	}
+
+	resp := &trackerv1.GetHistoryResponse{
+		Symbol: symbol,
+		Prices: make([]*trackerv1.PricePoint, 0, len(prices)),
+	}
+	for _, price := range prices {
+		resp.Prices = append(resp.Prices, &trackerv1.PricePoint{
+			Date:  timestamppb.New(price.Date),
+			Price: price.Price,
+		})
+	}
+	return resp, nil
+}
+
+// portfolioServer implements trackerv1.PortfolioServiceServer
+type portfolioServer struct {
+	trackerv1.UnimplementedPortfolioServiceServer
+	portfolioService *services.PortfolioService
+}
+
+// ListHoldings returns the caller's open positions in the requested currency
+func (s *portfolioServer) ListHoldings(ctx context.Context, req *trackerv1.ListHoldingsRequest) (*trackerv1.ListHoldingsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := normalizeCurrency(req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	holdings, err := s.portfolioService.GetUserHoldingsContext(ctx, userID, currency)
+	if err != nil {
+		return nil, toStatus(err, "failed to fetch holdings")
+	}
+
+	resp := &trackerv1.ListHoldingsResponse{
+		Currency: currency,
+		Holdings: make([]*trackerv1.Holding, 0, len(holdings)),
+	}
+	for _, holding := range holdings {
+		resp.Holdings = append(resp.Holdings, &trackerv1.Holding{
+			PortfolioId:     holding.PortfolioID,
+			Symbol:          holding.Symbol,
+			Name:            holding.Name,
+			Shares:          holding.Shares,
+			CostBasis:       holding.CostBasis,
+			CurrentPrice:    holding.CurrentPrice,
+			CurrentValue:    holding.CurrentValue,
+			GainLoss:        holding.GainLoss,
+			GainLossPercent: holding.GainLossPercent,
+			Currency:        holding.Currency,
+		})
+	}
+	return resp, nil
+}
+
+// ListTransactions returns the caller's transactions for a symbol, or all of
+// them newest first when no symbol is given
+func (s *portfolioServer) ListTransactions(ctx context.Context, req *trackerv1.ListTransactionsRequest) (*trackerv1.ListTransactionsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []models.Transaction
+	if symbol := strings.ToUpper(strings.TrimSpace(req.GetSymbol())); symbol != "" {
+		transactions, err = s.portfolioService.GetTransactionsBySymbol(userID, symbol)
+	} else {
+		transactions, err = s.portfolioService.GetTransactionsSince(userID, time.Time{})
+	}
+	if err != nil {
+		return nil, toStatus(err, "failed to fetch transactions")
+	}
+
+	resp := &trackerv1.ListTransactionsResponse{
+		Transactions: make([]*trackerv1.Transaction, 0, len(transactions)),
+	}
+	for _, tx := range transactions {
+		resp.Transactions = append(resp.Transactions, &trackerv1.Transaction{
+			Id:          tx.ID.Hex(),
+			PortfolioId: tx.PortfolioID.Hex(),
+			Symbol:      tx.Symbol,
+			Action:      tx.Action,
+			Shares:      tx.Shares,
+			Price:       tx.Price,
+			Currency:    tx.Currency,
+			Fees:        tx.Fees,
+			Date:        timestamppb.New(tx.Date),
+		})
+	}
+	return resp, nil
+}
+
+// analyticsServer implements trackerv1.AnalyticsServiceServer
+type analyticsServer struct {
+	trackerv1.UnimplementedAnalyticsServiceServer
+	analyticsService *services.AnalyticsService
+}
+
+// GetDashboard returns the caller's portfolio totals and allocation
+func (s *analyticsServer) GetDashboard(ctx context.Context, req *trackerv1.GetDashboardRequest) (*trackerv1.Dashboard, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := normalizeCurrency(req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := s.analyticsService.GetDashboardMetricsContext(ctx, userID, currency)
+	if err != nil {
+		return nil, toStatus(err, "failed to fetch dashboard metrics")
+	}
+
+	resp := &trackerv1.Dashboard{
+		Currency:         metrics.Currency,
+		TotalValue:       metrics.TotalValue,
+		TotalGain:        metrics.TotalGain,
+		PercentageReturn: metrics.PercentageReturn,
+		DayChange:        metrics.DayChange,
+		DayChangePercent: metrics.DayChangePercent,
+		Allocation:       make([]*trackerv1.AllocationItem, 0, len(metrics.Allocation)),
+	}
+	for _, item := range metrics.Allocation {
+		resp.Allocation = append(resp.Allocation, &trackerv1.AllocationItem{
+			Symbol:     item.Symbol,
+			Name:       item.Name,
+			Value:      item.Value,
+			Percentage: item.Percentage,
+		})
+	}
+	return resp, nil
+}
+
+// GetPerformance returns the caller's portfolio value series over a period,
+// defaulting to 1M
+func (s *analyticsServer) GetPerformance(ctx context.Context, req *trackerv1.GetPerformanceRequest) (*trackerv1.Performance, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	period, err := validatePeriod(req.GetPeriod(), "1M")
+	if err != nil {
+		return nil, err
+	}
+	currency, err := normalizeCurrency(req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	performance, err := s.analyticsService.GetHistoricalPerformanceWithProgress(ctx, userID, period, currency, nil)
+	if err != nil {
+		return nil, toStatus(err, "failed to fetch performance")
+	}
+	if performance == nil {
+		return nil, status.Error(codes.Internal, "failed to fetch performance")
+	}
+
+	resp := &trackerv1.Performance{
+		Period:   performance.Period,
+		Currency: performance.Currency,
+		Points:   make([]*trackerv1.PerformancePoint, 0, len(performance.Performance)),
+	}
+	for _, point := range performance.Performance {
+		resp.Points = append(resp.Points, &trackerv1.PerformancePoint{
+			Date:             timestamppb.New(point.Date),
+			Value:            point.Value,
+			PercentageReturn: point.PercentageReturn,
+			DayChange:        point.DayChange,
+			DayChangePercent: point.DayChangePercent,
+		})
+	}
+	if performance.Metrics != nil {
+		resp.TotalReturn = performance.Metrics.TotalReturn.Percentage
+		resp.PeriodReturn = performance.Metrics.PeriodReturn.Percentage
+		resp.MaxDrawdown = performance.Metrics.MaxDrawdown.Percentage
+	}
+	return resp, nil
+}
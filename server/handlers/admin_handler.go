@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler handles maintenance requests from operators
+type AdminHandler struct {
+	stockService    *services.StockAPIService
+	currencyService *services.CurrencyService
+	symbolCatalog   *services.SymbolCatalog
+}
+
+// NewAdminHandler creates a new AdminHandler instance
+func NewAdminHandler(stockService *services.StockAPIService, currencyService *services.CurrencyService, symbolCatalog *services.SymbolCatalog) *AdminHandler {
+	return &AdminHandler{
+		stockService:    stockService,
+		currencyService: currencyService,
+		symbolCatalog:   symbolCatalog,
+	}
+}
+
+// RenameSymbolRequest represents a ticker change
+type RenameSymbolRequest struct {
+	From string `json:"from" binding:"required"`
+	To   string `json:"to" binding:"required"`
+}
+
+// FlushCaches empties the market data and exchange rate caches
+func (h *AdminHandler) FlushCaches(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"marketData":    h.stockService.FlushCaches(),
+		"exchangeRates": h.currencyService.FlushCache(),
+	})
+}
+
+// RenameSymbol registers a ticker change, so transactions in the old symbol
+// are priced and held under the new one
+func (h *AdminHandler) RenameSymbol(c *gin.Context) {
+	var req RenameSymbolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid rename data"))
+		return
+	}
+
+	symbol, err := h.symbolCatalog.Rename(req.From, req.To)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to rename symbol"))
+		return
+	}
+	// Prices cached under the old symbol, and responses built from them, are
+	// stale
+	h.stockService.FlushCaches()
+
+	c.JSON(http.StatusOK, gin.H{
+		"symbol": symbol,
+	})
+}
@@ -1,9 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"stock-portfolio-tracker/apierror"
 	"stock-portfolio-tracker/services"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,12 +20,14 @@ import (
 // AnalyticsHandler handles analytics-related requests
 type AnalyticsHandler struct {
 	analyticsService *services.AnalyticsService
+	benchmarkService *services.BenchmarkComparisonService
 }
 
 // NewAnalyticsHandler creates a new AnalyticsHandler instance
-func NewAnalyticsHandler(analyticsService *services.AnalyticsService) *AnalyticsHandler {
+func NewAnalyticsHandler(analyticsService *services.AnalyticsService, benchmarkService *services.BenchmarkComparisonService) *AnalyticsHandler {
 	return &AnalyticsHandler{
 		analyticsService: analyticsService,
+		benchmarkService: benchmarkService,
 	}
 }
 
@@ -26,37 +36,19 @@ func (h *AnalyticsHandler) GetDashboard(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
 	// Get currency from query parameter (default to USD)
-	currency := c.DefaultQuery("currency", "USD")
-	
-	// Validate currency
-	if currency != "USD" && currency != "RMB" && currency != "CNY" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid currency parameter. Must be USD or RMB",
-			},
-		})
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
 		return
 	}
 
@@ -64,58 +56,59 @@ func (h *AnalyticsHandler) GetDashboard(c *gin.Context) {
 	groupBy := c.DefaultQuery("groupBy", "none")
 
 	// Validate groupBy parameter
-	validGroupBy := map[string]bool{
-		"assetStyle": true,
-		"assetClass": true,
-		"currency":   true,
-		"none":       true,
-	}
-
-	if !validGroupBy[groupBy] {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid groupBy parameter. Must be assetStyle, assetClass, currency, or none",
-			},
-		})
+	if _, err := services.ParseGroupBy(groupBy); err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid groupBy parameter. Must be assetStyle, assetClass, assetSubclass, currency, tag:<name>, or none, or up to three joined by commas such as assetClass,currency"))
+		return
+	}
+
+	// Compare against the requested benchmark or the user's default
+	benchmark, ok := h.resolveBenchmark(c, userID)
+	if !ok {
+		return
+	}
+
+	// Answer polling clients from their cached copy when nothing has changed
+	if h.respondNotModified(c, userID, "dashboard", currency, groupBy, benchmark) {
 		return
 	}
 
 	// If groupBy is specified and not "none", use grouped metrics
 	if groupBy != "none" {
-		groupedMetrics, err := h.analyticsService.GetGroupedDashboardMetrics(userID, currency, groupBy)
+		groupedMetrics, err := h.analyticsService.GetGroupedDashboardMetrics(c.Request.Context(), userID, currency, groupBy)
 		if err != nil {
 			// Log the detailed error for debugging
 			fmt.Printf("Error fetching grouped dashboard metrics for user %s: %v\n", userID.Hex(), err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": gin.H{
-					"code":    "INTERNAL_SERVER_ERROR",
-					"message": "Failed to fetch dashboard metrics",
-					"details": err.Error(),
-				},
-			})
+			c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch dashboard metrics"))
 			return
 		}
 
+		if benchmark != "" {
+			// Copy so a cached result is never modified
+			response := *groupedMetrics
+			response.Benchmark = h.benchmarkDayChange(c.Request.Context(), userID, benchmark, groupedMetrics.DayChangePercent)
+			groupedMetrics = &response
+		}
+
 		c.JSON(http.StatusOK, groupedMetrics)
 		return
 	}
 
 	// Get dashboard metrics (ungrouped)
-	metrics, err := h.analyticsService.GetDashboardMetrics(userID, currency)
+	metrics, err := h.analyticsService.GetDashboardMetricsContext(c.Request.Context(), userID, currency)
 	if err != nil {
 		// Log the detailed error for debugging
 		fmt.Printf("Error fetching dashboard metrics for user %s: %v\n", userID.Hex(), err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to fetch dashboard metrics",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch dashboard metrics"))
 		return
 	}
 
+	if benchmark != "" {
+		// Copy so a cached result is never modified
+		response := *metrics
+		response.Benchmark = h.benchmarkDayChange(c.Request.Context(), userID, benchmark, metrics.DayChangePercent)
+		metrics = &response
+	}
+
 	c.JSON(http.StatusOK, metrics)
 }
 
@@ -124,73 +117,180 @@ func (h *AnalyticsHandler) GetPerformance(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
+		return
+	}
+
+	req, ok := h.parsePerformanceRequest(c, userID)
+	if !ok {
+		return
+	}
+
+	// Answer polling clients from their cached copy when nothing has changed
+	if h.respondNotModified(c, userID, "performance", req.period, req.currency, strconv.Itoa(req.points), c.Query("include"), req.benchmark) {
+		return
+	}
+
+	response, err := h.buildPerformance(c.Request.Context(), userID, req, nil)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Stream large series in chunks instead of buffering one large JSON document
+	if c.Query("stream") == "true" {
+		if err := streamPerformanceResponse(c, response); err != nil {
+			fmt.Printf("Error streaming historical performance for user %s: %v\n", userID.Hex(), err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// performanceHeartbeatInterval is how often a performance stream sends a
+// comment while the computation makes no progress, so proxies don't close it
+// as idle
+var performanceHeartbeatInterval = 15 * time.Second
+
+// GetPerformanceEvents computes historical performance like GetPerformance,
+// for long ALL-period requests, as server-sent events: progress events while
+// price histories are fetched and dates valued, then a result event with the
+// performance response, or an error event. The computation stops when the
+// client disconnects or the request's deadline passes.
+func (h *AnalyticsHandler) GetPerformanceEvents(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	req, ok := h.parsePerformanceRequest(c, userID)
+	if !ok {
 		return
 	}
 
+	type performanceResult struct {
+		response *services.PerformanceResponse
+		err      error
+	}
+	// Progress the client is too slow to take is dropped rather than holding
+	// up the computation
+	progress := make(chan services.PerformanceProgress, 16)
+	done := make(chan performanceResult, 1)
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	go func() {
+		response, err := h.buildPerformance(ctx, userID, req, func(p services.PerformanceProgress) {
+			select {
+			case progress <- p:
+			default:
+			}
+		})
+		done <- performanceResult{response: response, err: err}
+	}()
+
+	// The stream lasts as long as the computation, past the server's write
+	// timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	heartbeat := time.NewTicker(performanceHeartbeatInterval)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case p := <-progress:
+			c.SSEvent("progress", p)
+			return true
+		case <-heartbeat.C:
+			io.WriteString(w, ": heartbeat\n\n")
+			return true
+		case <-ctx.Done():
+			// A client that went away gets nothing more; one still waiting
+			// past the deadline is told it timed out
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				c.SSEvent("error", apierror.From(ctx.Err()).Response(true))
+			}
+			return false
+		case result := <-done:
+			if result.err != nil {
+				fmt.Printf("Error streaming historical performance for user %s: %v\n", userID.Hex(), result.err)
+				// The status is already sent, so the error goes in the stream,
+				// without the causes of server errors
+				c.SSEvent("error", apierror.From(result.err).Response(true))
+				return false
+			}
+			c.SSEvent("result", result.response)
+			return false
+		}
+	})
+}
+
+// performanceRequest is a validated historical performance query
+type performanceRequest struct {
+	period    string
+	currency  string
+	points    int
+	include   map[string]bool
+	benchmark string
+}
+
+// parsePerformanceRequest reads the period, currency, points, include and
+// benchmark query parameters of a performance request
+func (h *AnalyticsHandler) parsePerformanceRequest(c *gin.Context, userID primitive.ObjectID) (performanceRequest, bool) {
+	var req performanceRequest
+
 	// Get period from query parameter (default to 1M)
-	period := c.DefaultQuery("period", "1M")
-	
+	req.period = c.DefaultQuery("period", "1M")
+
 	// Validate period (now including ALL)
 	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
-	if !validPeriods[period] {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid period parameter. Must be 1M, 3M, 6M, 1Y, or ALL",
-			},
-		})
-		return
+	if !validPeriods[req.period] {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid period parameter. Must be 1M, 3M, 6M, 1Y, or ALL"))
+		return req, false
 	}
 
 	// Get currency from query parameter (default to USD)
-	currency := c.DefaultQuery("currency", "USD")
-	
-	// Validate currency
-	if currency != "USD" && currency != "RMB" && currency != "CNY" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid currency parameter. Must be USD or RMB",
-			},
-		})
-		return
+	var ok bool
+	if req.currency, ok = parseDisplayCurrency(c); !ok {
+		return req, false
 	}
 
+	// Get optional downsampling target
+	if req.points, ok = parsePointsQuery(c); !ok {
+		return req, false
+	}
+
+	// Get the optional series to include
+	if req.include, ok = parsePerformanceInclude(c); !ok {
+		return req, false
+	}
+
+	// Compare against the requested benchmark or the user's default
+	req.benchmark, ok = h.resolveBenchmark(c, userID)
+	return req, ok
+}
+
+// buildPerformance computes the performance response for a request: the
+// series and metrics, the requested extra series, the benchmark comparison
+// and downsampling under ctx. progress may be nil. Errors are API errors.
+func (h *AnalyticsHandler) buildPerformance(ctx context.Context, userID primitive.ObjectID, req performanceRequest, progress services.PerformanceProgressFunc) (*services.PerformanceResponse, error) {
 	// Get historical performance with metrics
-	response, err := h.analyticsService.GetHistoricalPerformanceWithMetrics(userID, period, currency)
+	response, err := h.analyticsService.GetHistoricalPerformanceWithProgress(ctx, userID, req.period, req.currency, progress)
 	if err != nil {
 		// Log the detailed error for debugging
 		fmt.Printf("Error fetching historical performance for user %s: %v\n", userID.Hex(), err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to fetch historical performance",
-				"details": err.Error(),
-			},
-		})
-		return
+		return nil, apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch historical performance")
 	}
-	
+
 	// Handle case where no data is available
-	if response.Performance == nil || len(response.Performance) == 0 {
-		fmt.Printf("No performance data available for user %s, period %s\n", userID.Hex(), period)
+	if len(response.Performance) == 0 {
+		fmt.Printf("No performance data available for user %s, period %s\n", userID.Hex(), req.period)
 		// Return empty response with zero metrics
 		response.Performance = []services.PerformanceDataPoint{}
 		if response.Metrics == nil {
@@ -198,5 +298,457 @@ func (h *AnalyticsHandler) GetPerformance(c *gin.Context) {
 		}
 	}
 
+	err = h.analyticsService.AddPerformanceSeries(ctx, userID, response,
+		req.include[services.PerformanceSeriesRolling], req.include[services.PerformanceSeriesDrawdown])
+	if err != nil {
+		fmt.Printf("Error computing performance series for user %s: %v\n", userID.Hex(), err)
+		return nil, apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch historical performance")
+	}
+
+	// A benchmark that can't be fetched leaves the performance uncompared
+	if req.benchmark != "" {
+		performance, info, err := h.benchmarkService.Performance(ctx, userID, req.benchmark, response.Performance)
+		if err != nil {
+			fmt.Printf("Error comparing performance with benchmark %s for user %s: %v\n", req.benchmark, userID.Hex(), err)
+		} else {
+			response.Performance = performance
+			response.Benchmark = info
+		}
+	}
+
+	// Downsample after metrics are calculated so they reflect the full daily series
+	if req.points > 0 {
+		response.Performance, err = services.DownsamplePerformance(response.Performance, req.points)
+		if err == nil {
+			for i := range response.RollingReturns {
+				response.RollingReturns[i].Returns, err = services.DownsampleRollingReturns(response.RollingReturns[i].Returns, req.points)
+			}
+		}
+		if err == nil && response.Drawdowns != nil {
+			response.Drawdowns, err = services.DownsampleDrawdowns(response.Drawdowns, req.points)
+		}
+		if err != nil {
+			return nil, apierror.New(apierror.CodeValidation, "Invalid points parameter. Must be an integer of at least 3")
+		}
+	}
+
+	return response, nil
+}
+
+// respondNotModified sets the ETag header for an analytics response and, when the
+// request's If-None-Match matches it, writes 304 Not Modified and returns true.
+// GetNetWorth returns the net worth timeline for the authenticated user,
+// combining investments and cash with net contributions overlaid
+func (h *AnalyticsHandler) GetNetWorth(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	period := c.DefaultQuery("period", "1M")
+	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
+	if !validPeriods[period] {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid period parameter. Must be 1M, 3M, 6M, 1Y, or ALL"))
+		return
+	}
+
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	points, ok := parsePointsQuery(c)
+	if !ok {
+		return
+	}
+
+	// Answer polling clients from their cached copy when nothing has changed
+	if h.respondNotModified(c, userID, "networth", period, currency, strconv.Itoa(points)) {
+		return
+	}
+
+	response, err := h.analyticsService.GetNetWorthTimeline(c.Request.Context(), userID, period, currency)
+	if err != nil {
+		fmt.Printf("Error fetching net worth timeline for user %s: %v\n", userID.Hex(), err)
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch net worth timeline"))
+		return
+	}
+
+	if points > 0 {
+		response.NetWorth, err = services.DownsampleNetWorth(response.NetWorth, points)
+		if err != nil {
+			c.Error(apierror.New(apierror.CodeValidation, "Invalid points parameter. Must be an integer of at least 3"))
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, response)
 }
+
+// GetMovers returns the authenticated user's best and worst performing
+// holdings today and over a period
+func (h *AnalyticsHandler) GetMovers(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	period := c.DefaultQuery("period", "1M")
+	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
+	if !validPeriods[period] {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid period parameter. Must be 1M, 3M, 6M, 1Y, or ALL"))
+		return
+	}
+
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	count := services.DefaultMoverCount
+	if countStr := c.Query("count"); countStr != "" {
+		parsed, err := strconv.Atoi(countStr)
+		if err != nil || parsed < 1 || parsed > services.MaxMoverCount {
+			c.Error(apierror.New(apierror.CodeValidation, fmt.Sprintf("Invalid count parameter. Must be between 1 and %d", services.MaxMoverCount)))
+			return
+		}
+		count = parsed
+	}
+
+	movers, err := h.analyticsService.GetMovers(c.Request.Context(), userID, period, currency, count)
+	if err != nil {
+		fmt.Printf("Error fetching movers for user %s: %v\n", userID.Hex(), err)
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch movers"))
+		return
+	}
+
+	c.JSON(http.StatusOK, movers)
+}
+
+// GetRisk returns risk metrics for the authenticated user's portfolio
+func (h *AnalyticsHandler) GetRisk(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	period := c.DefaultQuery("period", "1Y")
+	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
+	if !validPeriods[period] {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid period parameter. Must be 1M, 3M, 6M, 1Y, or ALL"))
+		return
+	}
+
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	benchmark := c.DefaultQuery("benchmark", services.DefaultRiskBenchmark)
+	if len(benchmark) > 20 {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid benchmark parameter"))
+		return
+	}
+
+	// Answer polling clients from their cached copy when nothing has changed
+	if h.respondNotModified(c, userID, "risk", period, currency, benchmark) {
+		return
+	}
+
+	metrics, err := h.analyticsService.GetRiskMetrics(c.Request.Context(), userID, period, currency, benchmark)
+	if err != nil {
+		fmt.Printf("Error fetching risk metrics for user %s: %v\n", userID.Hex(), err)
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch risk metrics"))
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+// GetCalendarReturns returns month-, quarter- and year-to-date returns and a
+// per-calendar-year return table
+func (h *AnalyticsHandler) GetCalendarReturns(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	// Answer polling clients from their cached copy when nothing has changed
+	if h.respondNotModified(c, userID, "returns-calendar", currency) {
+		return
+	}
+
+	returns, err := h.analyticsService.GetCalendarReturns(c.Request.Context(), userID, currency)
+	if err != nil {
+		fmt.Printf("Error fetching calendar returns for user %s: %v\n", userID.Hex(), err)
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch calendar returns"))
+		return
+	}
+
+	c.JSON(http.StatusOK, returns)
+}
+
+// GetAttribution returns each holding's contribution to the period return
+func (h *AnalyticsHandler) GetAttribution(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	period := c.DefaultQuery("period", "3M")
+	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
+	if !validPeriods[period] {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid period parameter. Must be 1M, 3M, 6M, 1Y, or ALL"))
+		return
+	}
+
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	// Answer polling clients from their cached copy when nothing has changed
+	if h.respondNotModified(c, userID, "attribution", period, currency) {
+		return
+	}
+
+	attribution, err := h.analyticsService.GetAttribution(c.Request.Context(), userID, period, currency)
+	if err != nil {
+		fmt.Printf("Error fetching attribution for user %s: %v\n", userID.Hex(), err)
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch performance attribution"))
+		return
+	}
+
+	c.JSON(http.StatusOK, attribution)
+}
+
+// GetCurrencyEffect returns the split of each holding's return into asset and FX effects
+func (h *AnalyticsHandler) GetCurrencyEffect(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	period := c.DefaultQuery("period", "1Y")
+	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
+	if !validPeriods[period] {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid period parameter. Must be 1M, 3M, 6M, 1Y, or ALL"))
+		return
+	}
+
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	// Answer polling clients from their cached copy when nothing has changed
+	if h.respondNotModified(c, userID, "currency-effect", period, currency) {
+		return
+	}
+
+	effect, err := h.analyticsService.GetCurrencyEffect(c.Request.Context(), userID, period, currency)
+	if err != nil {
+		fmt.Printf("Error fetching currency effect for user %s: %v\n", userID.Hex(), err)
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch currency effect"))
+		return
+	}
+
+	c.JSON(http.StatusOK, effect)
+}
+
+// GetIncome returns upcoming dividends and projected income for the
+// authenticated user's holdings
+func (h *AnalyticsHandler) GetIncome(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	income, err := h.analyticsService.GetIncome(c.Request.Context(), userID, currency)
+	if err != nil {
+		fmt.Printf("Error fetching income for user %s: %v\n", userID.Hex(), err)
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch dividend income"))
+		return
+	}
+
+	c.JSON(http.StatusOK, income)
+}
+
+// Failing to compute the ETag is not fatal; the response is simply not cacheable.
+func (h *AnalyticsHandler) respondNotModified(c *gin.Context, userID primitive.ObjectID, params ...string) bool {
+	etag, err := h.analyticsService.ResponseETag(userID, params...)
+	if err != nil {
+		fmt.Printf("Warning: failed to compute ETag for user %s: %v\n", userID.Hex(), err)
+		return false
+	}
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
+
+// etagMatches reports whether an If-None-Match header value matches the ETag
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveBenchmark returns the benchmark named by the optional benchmark query
+// parameter, falling back to the user's saved default. benchmark=none turns the
+// comparison off. It writes a validation error response and returns false
+// when the parameter is malformed.
+func (h *AnalyticsHandler) resolveBenchmark(c *gin.Context, userID primitive.ObjectID) (string, bool) {
+	requested := strings.TrimSpace(c.Query("benchmark"))
+	if requested != services.NoBenchmark {
+		if err := services.ValidateBenchmark(requested); err != nil {
+			c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid benchmark parameter"))
+			return "", false
+		}
+	}
+
+	benchmark, err := h.benchmarkService.ResolveBenchmark(userID, requested)
+	if err != nil {
+		fmt.Printf("Warning: failed to load default benchmark for user %s: %v\n", userID.Hex(), err)
+		return "", true
+	}
+	return benchmark, true
+}
+
+// benchmarkDayChange compares a day change with the benchmark's, returning nil
+// when the benchmark's quotes can't be fetched
+func (h *AnalyticsHandler) benchmarkDayChange(ctx context.Context, userID primitive.ObjectID, benchmark string, dayChangePercent float64) *services.BenchmarkDayChange {
+	comparison, err := h.benchmarkService.DayChange(ctx, userID, benchmark, dayChangePercent)
+	if err != nil {
+		fmt.Printf("Error comparing day change with benchmark %s for user %s: %v\n", benchmark, userID.Hex(), err)
+		return nil
+	}
+	return comparison
+}
+
+// parsePointsQuery reads the optional points=N downsampling parameter.
+// It returns 0 when the parameter is absent and writes a validation error
+// response and returns false when it is malformed.
+func parsePointsQuery(c *gin.Context) (int, bool) {
+	pointsStr := c.Query("points")
+	if pointsStr == "" {
+		return 0, true
+	}
+
+	points, err := strconv.Atoi(pointsStr)
+	if err != nil || points < services.MinDownsamplePoints {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid points parameter. Must be an integer of at least 3"))
+		return 0, false
+	}
+
+	return points, true
+}
+
+// parsePerformanceInclude reads the optional comma-separated include query
+// parameter naming extra performance series, rolling and drawdown
+func parsePerformanceInclude(c *gin.Context) (map[string]bool, bool) {
+	include := make(map[string]bool)
+	for _, name := range strings.Split(c.Query("include"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+		case services.PerformanceSeriesRolling, services.PerformanceSeriesDrawdown:
+			include[name] = true
+		default:
+			c.Error(apierror.New(apierror.CodeValidation, "Invalid include parameter. Must be a comma-separated list of rolling and drawdown"))
+			return nil, false
+		}
+	}
+	return include, true
+}
+
+// performanceStreamChunkSize is the number of data points written between flushes
+const performanceStreamChunkSize = 250
+
+// streamPerformanceResponse writes a performance response as chunked JSON,
+// flushing the connection after every chunk of data points. The resulting
+// document has the same shape as the buffered response.
+func streamPerformanceResponse(c *gin.Context, response *services.PerformanceResponse) error {
+	fields := gin.H{
+		"period":   response.Period,
+		"currency": response.Currency,
+		"metrics":  response.Metrics,
+	}
+	if response.ExchangeRates != nil {
+		fields["exchangeRates"] = response.ExchangeRates
+	}
+	if len(response.RollingReturns) > 0 {
+		fields["rollingReturns"] = response.RollingReturns
+	}
+	if len(response.Drawdowns) > 0 {
+		fields["drawdowns"] = response.Drawdowns
+	}
+	header, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	// Reopen the header object so the performance array can be appended to it
+	if _, err := w.Write(header[:len(header)-1]); err != nil {
+		return err
+	}
+	if _, err := w.WriteString(`,"performance":[`); err != nil {
+		return err
+	}
+
+	for i, point := range response.Performance {
+		if i > 0 {
+			if _, err := w.WriteString(","); err != nil {
+				return err
+			}
+		}
+
+		encoded, err := json.Marshal(point)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(encoded); err != nil {
+			return err
+		}
+
+		if (i+1)%performanceStreamChunkSize == 0 {
+			w.Flush()
+		}
+	}
+
+	if _, err := w.WriteString("]}"); err != nil {
+		return err
+	}
+	w.Flush()
+
+	return nil
+}
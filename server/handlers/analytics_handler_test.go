@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"stock-portfolio-tracker/services"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// slowHistoryProvider serves fixture quotes, but its price histories only
+// arrive once the request is cancelled, like a provider that stopped
+// responding
+type slowHistoryProvider struct {
+	*services.FixtureProvider
+}
+
+func (p slowHistoryProvider) GetHistoricalDataContext(ctx context.Context, symbol string, period string) ([]services.HistoricalPrice, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestAnalyticsTimeoutWithSlowProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	fixture := services.NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 110, "USD").
+		SetQuote("^GSPC", "S&P 500", 5000, "USD")
+	provider := slowHistoryProvider{fixture}
+
+	portfolioService := services.NewPortfolioServiceWithRepos(provider, fixture, repository.NewMemory())
+	analyticsService := services.NewAnalyticsService(portfolioService, fixture, provider)
+	backtestService := services.NewBacktestService(portfolioService, analyticsService, fixture, provider)
+	analyticsHandler := NewAnalyticsHandler(analyticsService, services.NewBenchmarkComparisonService(backtestService, nil))
+	backtestHandler := NewBacktestHandler(backtestService, nil)
+
+	userID := primitive.NewObjectID()
+	tx := &models.Transaction{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 100, Currency: "USD", Date: today.AddDate(0, -6, 0)}
+	if err := portfolioService.AddTransaction(userID, tx); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(true), middleware.RequestTimeout(50*time.Millisecond), func(c *gin.Context) {
+		c.Set("userID", userID)
+	})
+	router.GET("/analytics/dashboard", analyticsHandler.GetDashboard)
+	router.GET("/analytics/performance", analyticsHandler.GetPerformance)
+	router.GET("/analytics/networth", analyticsHandler.GetNetWorth)
+	router.GET("/analytics/movers", analyticsHandler.GetMovers)
+	router.GET("/analytics/returns/calendar", analyticsHandler.GetCalendarReturns)
+	router.GET("/analytics/risk", analyticsHandler.GetRisk)
+	router.GET("/analytics/attribution", analyticsHandler.GetAttribution)
+	router.GET("/analytics/currency-effect", analyticsHandler.GetCurrencyEffect)
+	router.GET("/backtest", backtestHandler.GetBacktest)
+
+	start := today.AddDate(-1, 0, 0).Format("2006-01-02")
+	end := today.AddDate(0, 0, -1).Format("2006-01-02")
+	paths := []string{
+		"/analytics/dashboard?groupBy=assetClass&benchmark=none",
+		"/analytics/performance?benchmark=none",
+		"/analytics/networth",
+		"/analytics/movers",
+		"/analytics/returns/calendar",
+		"/analytics/risk",
+		"/analytics/attribution",
+		"/analytics/currency-effect",
+		"/backtest?startDate=" + start + "&endDate=" + end,
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Code != http.StatusGatewayTimeout {
+				t.Fatalf("Expected 504, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var resp apierror.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Error.Code != apierror.CodeTimeout {
+				t.Errorf("Expected code %s, got %s", apierror.CodeTimeout, resp.Error.Code)
+			}
+		})
+	}
+}
+
+// streamRecorder is a ResponseRecorder gin can stream to, for a client that
+// stays connected
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (r streamRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func TestPerformanceEventsTimeoutWithSlowProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previousInterval := performanceHeartbeatInterval
+	performanceHeartbeatInterval = 10 * time.Millisecond
+	defer func() { performanceHeartbeatInterval = previousInterval }()
+
+	fixture := services.NewFixtureProvider().SetQuote("AAPL", "Apple Inc.", 110, "USD")
+	provider := slowHistoryProvider{fixture}
+	portfolioService := services.NewPortfolioServiceWithRepos(provider, fixture, repository.NewMemory())
+	analyticsService := services.NewAnalyticsService(portfolioService, fixture, provider)
+	handler := NewAnalyticsHandler(analyticsService, nil)
+
+	userID := primitive.NewObjectID()
+	tx := &models.Transaction{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 100, Currency: "USD", Date: time.Now().AddDate(0, -6, 0)}
+	if err := portfolioService.AddTransaction(userID, tx); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+
+	// The stream's own deadline replaces the shorter one of its group
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(true), middleware.RequestTimeout(time.Millisecond), func(c *gin.Context) {
+		c.Set("userID", userID)
+	})
+	router.GET("/analytics/performance/events", middleware.RequestTimeout(100*time.Millisecond), handler.GetPerformanceEvents)
+
+	w := streamRecorder{httptest.NewRecorder()}
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/analytics/performance/events?benchmark=none", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, ": heartbeat\n\n") {
+		t.Errorf("Expected heartbeat comments while waiting, got %q", body)
+	}
+	if !strings.Contains(body, "event:error\n") || !strings.Contains(body, string(apierror.CodeTimeout)) {
+		t.Errorf("Expected a timeout error event, got %q", body)
+	}
+	if strings.Contains(body, "event:result\n") {
+		t.Errorf("Expected no result after the deadline, got %q", body)
+	}
+}
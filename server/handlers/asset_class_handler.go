@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AssetClassHandler handles the user's asset class taxonomy
+type AssetClassHandler struct {
+	assetClassService *services.AssetClassService
+}
+
+// NewAssetClassHandler creates a new AssetClassHandler instance
+func NewAssetClassHandler(assetClassService *services.AssetClassService) *AssetClassHandler {
+	return &AssetClassHandler{
+		assetClassService: assetClassService,
+	}
+}
+
+// GetTree returns the asset classes with the authenticated user's subclasses
+func (h *AssetClassHandler) GetTree(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	tree, err := h.assetClassService.GetTree(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch asset classes"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"assetClasses": tree,
+	})
+}
+
+// CreateSubclass adds a subclass under an asset class
+func (h *AssetClassHandler) CreateSubclass(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.AssetSubclassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid asset subclass data"))
+		return
+	}
+
+	subclass, err := h.assetClassService.CreateSubclass(userID, req)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to create asset subclass"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"subclass": subclass,
+	})
+}
+
+// RenameSubclass renames an asset subclass
+func (h *AssetClassHandler) RenameSubclass(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	subclassID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid asset subclass ID"))
+		return
+	}
+
+	var req models.RenameAssetSubclassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid asset subclass data"))
+		return
+	}
+
+	if err := h.assetClassService.RenameSubclass(userID, subclassID, req.Name); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to rename asset subclass"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Asset subclass renamed successfully",
+	})
+}
+
+// DeleteSubclass deletes an asset subclass, leaving its portfolios in the
+// asset class
+func (h *AssetClassHandler) DeleteSubclass(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	subclassID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid asset subclass ID"))
+		return
+	}
+
+	if err := h.assetClassService.DeleteSubclass(userID, subclassID); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to delete asset subclass"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Asset subclass deleted successfully",
+	})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"stock-portfolio-tracker/apierror"
 	"stock-portfolio-tracker/models"
 	"stock-portfolio-tracker/services"
 
@@ -26,43 +27,27 @@ func (h *AssetStyleHandler) GetAssetStyles(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
 	// Get asset styles
 	assetStyles, err := h.assetStyleService.GetUserAssetStyles(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to fetch asset styles",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch asset styles"))
 		return
 	}
 
 	// Build response with usage counts
 	responses := make([]models.AssetStyleResponse, 0, len(assetStyles))
 	for _, style := range assetStyles {
-		usageCount, err := h.assetStyleService.GetAssetStyleUsageCount(style.ID)
+		usageCount, err := h.assetStyleService.GetAssetStyleUsageCount(userID, style.ID)
 		if err != nil {
 			// Log error but continue
 			usageCount = 0
@@ -72,6 +57,8 @@ func (h *AssetStyleHandler) GetAssetStyles(c *gin.Context) {
 			ID:         style.ID.Hex(),
 			UserID:     style.UserID.Hex(),
 			Name:       style.Name,
+			Color:      style.Color,
+			Icon:       style.Icon,
 			UsageCount: usageCount,
 			CreatedAt:  style.CreatedAt,
 			UpdatedAt:  style.UpdatedAt,
@@ -88,59 +75,32 @@ func (h *AssetStyleHandler) CreateAssetStyle(c *gin.Context) {
 	// Get user ID from context
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
 	// Parse request body
 	var req models.AssetStyleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid asset style data",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid asset style data"))
 		return
 	}
 
 	// Create asset style
-	assetStyle, err := h.assetStyleService.CreateAssetStyle(userID, req.Name)
+	assetStyle, err := h.assetStyleService.CreateAssetStyle(userID, req)
 	if err != nil {
 		if err == services.ErrDuplicateAssetStyle {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "DUPLICATE_ASSET_STYLE",
-					"message": "Asset style name already exists",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeDuplicateAssetStyle, "Asset style name already exists"))
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to create asset style",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to create asset style"))
 		return
 	}
 
@@ -150,6 +110,8 @@ func (h *AssetStyleHandler) CreateAssetStyle(c *gin.Context) {
 			ID:         assetStyle.ID.Hex(),
 			UserID:     assetStyle.UserID.Hex(),
 			Name:       assetStyle.Name,
+			Color:      assetStyle.Color,
+			Icon:       assetStyle.Icon,
 			UsageCount: 0,
 			CreatedAt:  assetStyle.CreatedAt,
 			UpdatedAt:  assetStyle.UpdatedAt,
@@ -162,23 +124,13 @@ func (h *AssetStyleHandler) UpdateAssetStyle(c *gin.Context) {
 	// Get user ID from context
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
@@ -186,57 +138,30 @@ func (h *AssetStyleHandler) UpdateAssetStyle(c *gin.Context) {
 	styleIDStr := c.Param("id")
 	styleID, err := primitive.ObjectIDFromHex(styleIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid asset style ID",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid asset style ID"))
 		return
 	}
 
 	// Parse request body
 	var req models.AssetStyleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid asset style data",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid asset style data"))
 		return
 	}
 
 	// Update asset style
-	err = h.assetStyleService.UpdateAssetStyle(userID, styleID, req.Name)
+	err = h.assetStyleService.UpdateAssetStyle(userID, styleID, req)
 	if err != nil {
 		if err == services.ErrAssetStyleNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": gin.H{
-					"code":    "NOT_FOUND",
-					"message": "Asset style not found",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeNotFound, "Asset style not found"))
 			return
 		}
 		if err == services.ErrDuplicateAssetStyle {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "DUPLICATE_ASSET_STYLE",
-					"message": "Asset style name already exists",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeDuplicateAssetStyle, "Asset style name already exists"))
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to update asset style",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to update asset style"))
 		return
 	}
 
@@ -250,23 +175,13 @@ func (h *AssetStyleHandler) DeleteAssetStyle(c *gin.Context) {
 	// Get user ID from context
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
@@ -274,12 +189,7 @@ func (h *AssetStyleHandler) DeleteAssetStyle(c *gin.Context) {
 	styleIDStr := c.Param("id")
 	styleID, err := primitive.ObjectIDFromHex(styleIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid asset style ID",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid asset style ID"))
 		return
 	}
 
@@ -291,12 +201,7 @@ func (h *AssetStyleHandler) DeleteAssetStyle(c *gin.Context) {
 	if req.NewStyleID != "" {
 		newStyleID, err = primitive.ObjectIDFromHex(req.NewStyleID)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "VALIDATION_ERROR",
-					"message": "Invalid replacement asset style ID",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeValidation, "Invalid replacement asset style ID"))
 			return
 		}
 	}
@@ -305,40 +210,19 @@ func (h *AssetStyleHandler) DeleteAssetStyle(c *gin.Context) {
 	err = h.assetStyleService.DeleteAssetStyle(userID, styleID, newStyleID)
 	if err != nil {
 		if err == services.ErrAssetStyleNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": gin.H{
-					"code":    "NOT_FOUND",
-					"message": "Asset style not found",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeNotFound, "Asset style not found"))
 			return
 		}
 		if err == services.ErrAssetStyleInUse {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "ASSET_STYLE_IN_USE",
-					"message": "Asset style is in use. Please provide a replacement style ID",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeAssetStyleInUse, "Asset style is in use. Please provide a replacement style ID"))
 			return
 		}
 		if err == services.ErrDefaultAssetStyle {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "CANNOT_DELETE_DEFAULT",
-					"message": "Cannot delete the default asset style",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeCannotDeleteDefault, "Cannot delete the default asset style"))
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to delete asset style",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to delete asset style"))
 		return
 	}
 
@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"net/http"
+	"stock-portfolio-tracker/apierror"
 	"stock-portfolio-tracker/middleware"
 	"stock-portfolio-tracker/models"
 	"stock-portfolio-tracker/services"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // AuthHandler handles authentication-related requests
@@ -50,24 +52,13 @@ type UserResponse struct {
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid input data",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid input data"))
 		return
 	}
 
 	// Validate password length
 	if len(req.Password) < 8 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Password must be at least 8 characters long",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Password must be at least 8 characters long"))
 		return
 	}
 
@@ -78,33 +69,18 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	user, err := h.authService.Register(req.Email, req.Password)
 	if err != nil {
 		if err == services.ErrUserExists {
-			c.JSON(http.StatusConflict, gin.H{
-				"error": gin.H{
-					"code":    "CONFLICT",
-					"message": "User with this email already exists",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeConflict, "User with this email already exists"))
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to register user",
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to register user"))
 		return
 	}
 
-	// Generate token for the new user
-	token, err := h.authService.GenerateToken(user.ID)
+	// Start a session for the new user
+	token, err := h.authService.StartSession(user.ID, middleware.RequestDevice(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to generate authentication token",
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to generate authentication token"))
 		return
 	}
 
@@ -121,13 +97,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid input data",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid input data"))
 		return
 	}
 
@@ -135,36 +105,21 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 
 	// Authenticate user
-	token, err := h.authService.Login(req.Email, req.Password)
+	token, err := h.authService.Login(req.Email, req.Password, middleware.RequestDevice(c))
 	if err != nil {
 		if err == services.ErrInvalidCredentials {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": gin.H{
-					"code":    "UNAUTHORIZED",
-					"message": "Invalid email or password",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeUnauthorized, "Invalid email or password"))
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to authenticate user",
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to authenticate user"))
 		return
 	}
 
 	// Get user info for response
 	user, err := h.authService.ValidateToken(token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to retrieve user information",
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to retrieve user information"))
 		return
 	}
 
@@ -182,35 +137,20 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	// Get user from context (set by auth middleware)
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	// Get user from context
 	userInterface, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to retrieve user information",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Failed to retrieve user information"))
 		return
 	}
 
 	user, ok := userInterface.(*models.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user data",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user data"))
 		return
 	}
 
@@ -219,3 +159,57 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 		Email: user.Email,
 	})
 }
+
+// ListSessions returns the current user's active sessions
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(userID, middleware.GetSessionID(c))
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to list sessions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession ends one of the current user's sessions
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid session ID"))
+		return
+	}
+
+	if err := h.authService.RevokeSession(userID, sessionID); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to revoke session"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeOtherSessions ends every session of the current user except the one
+// making the request
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.authService.RevokeOtherSessions(userID, middleware.GetSessionID(c))
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to revoke sessions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
@@ -1,110 +1,91 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/models"
 	"stock-portfolio-tracker/services"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // BacktestHandler handles backtest-related requests
 type BacktestHandler struct {
-	backtestService *services.BacktestService
+	backtestService    *services.BacktestService
+	backtestJobService *services.BacktestJobService
 }
 
 // NewBacktestHandler creates a new BacktestHandler instance
-func NewBacktestHandler(backtestService *services.BacktestService) *BacktestHandler {
+func NewBacktestHandler(backtestService *services.BacktestService, backtestJobService *services.BacktestJobService) *BacktestHandler {
 	return &BacktestHandler{
-		backtestService: backtestService,
+		backtestService:    backtestService,
+		backtestJobService: backtestJobService,
 	}
 }
 
 // GetBacktest returns backtest results for the authenticated user
 func (h *BacktestHandler) GetBacktest(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userIDInterface, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
-		return
-	}
-
-	userID, ok := userIDInterface.(primitive.ObjectID)
+	userID, ok := requireUserID(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
 		return
 	}
 
 	// Get query parameters
 	startDateStr := c.Query("startDate")
 	endDateStr := c.Query("endDate")
-	currency := c.DefaultQuery("currency", "USD")
 	benchmark := c.Query("benchmark")
 
 	// Validate required parameters
 	if startDateStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "startDate parameter is required",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "startDate parameter is required"))
 		return
 	}
 
 	if endDateStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "endDate parameter is required",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "endDate parameter is required"))
 		return
 	}
 
 	// Parse dates
 	startDate, err := time.Parse("2006-01-02", startDateStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": fmt.Sprintf("Invalid startDate format. Expected YYYY-MM-DD: %v", err),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid startDate format. Expected YYYY-MM-DD"))
 		return
 	}
 
 	endDate, err := time.Parse("2006-01-02", endDateStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": fmt.Sprintf("Invalid endDate format. Expected YYYY-MM-DD: %v", err),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid endDate format. Expected YYYY-MM-DD"))
 		return
 	}
 
 	// Validate currency
-	if currency != "USD" && currency != "RMB" && currency != "CNY" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid currency parameter. Must be USD or RMB",
-			},
-		})
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	// Validate benchmark blend expression
+	if _, err := services.ParseBenchmarkBlend(benchmark); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid benchmark parameter"))
+		return
+	}
+
+	// Get optional trading costs
+	costs, ok := parseBacktestCostsQuery(c)
+	if !ok {
+		return
+	}
+
+	// Get optional downsampling target
+	points, ok := parsePointsQuery(c)
+	if !ok {
 		return
 	}
 
@@ -112,17 +93,166 @@ func (h *BacktestHandler) GetBacktest(c *gin.Context) {
 	fmt.Printf("[BacktestHandler] Running backtest for user %s from %s to %s\n",
 		userID.Hex(), startDateStr, endDateStr)
 
-	result, err := h.backtestService.RunBacktest(userID, startDate, endDate, currency, benchmark)
+	result, err := h.backtestService.RunBacktest(c.Request.Context(), userID, startDate, endDate, currency, benchmark, costs)
 	if err != nil {
 		fmt.Printf("[BacktestHandler] Error running backtest: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "BACKTEST_ERROR",
-				"message": fmt.Sprintf("Failed to run backtest: %v", err),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeBacktestError, "Failed to run backtest"))
 		return
 	}
 
+	// Downsample after metrics are calculated so they reflect the full daily series
+	if points > 0 {
+		result.Performance, err = services.DownsampleBacktest(result.Performance, points)
+		if err != nil {
+			c.Error(apierror.New(apierror.CodeValidation, "Invalid points parameter. Must be an integer of at least 3"))
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, result)
 }
+
+// SubmitBacktestJob queues a backtest for background execution and returns the job
+func (h *BacktestHandler) SubmitBacktestJob(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.BacktestJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid backtest job data"))
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid startDate format. Expected YYYY-MM-DD"))
+		return
+	}
+
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid endDate format. Expected YYYY-MM-DD"))
+		return
+	}
+
+	currency := currencycode.Normalize(req.Currency)
+	if currency == "" {
+		currency = currencycode.USD
+	}
+
+	if _, err := services.ParseBenchmarkBlend(req.Benchmark); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid benchmark parameter"))
+		return
+	}
+
+	costs := services.BacktestCosts{
+		CommissionPerTrade: req.Commission,
+		SlippageBps:        req.SlippageBps,
+	}
+
+	job, err := h.backtestJobService.SubmitJob(userID, startDate, endDate, currency, req.Benchmark, costs)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyBacktestJobs) {
+			c.Error(apierror.New(apierror.CodeTooManyJobs, "Too many backtest jobs are already running. Wait for one to finish"))
+			return
+		}
+
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid backtest parameters"))
+		return
+	}
+
+	c.Header("Location", "/api/backtest/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetBacktestJob returns the status of a backtest job
+func (h *BacktestHandler) GetBacktestJob(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.backtestJobService.GetJob(userID, c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeNotFound, "Backtest job not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// GetBacktestJobResult returns the result of a completed backtest job
+func (h *BacktestHandler) GetBacktestJobResult(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	// Get optional downsampling target
+	points, ok := parsePointsQuery(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.backtestJobService.GetJobResult(userID, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrBacktestJobNotFound):
+			c.Error(apierror.New(apierror.CodeNotFound, "Backtest job not found"))
+		case errors.Is(err, services.ErrBacktestJobNotComplete):
+			c.Error(apierror.New(apierror.CodeJobNotComplete, "Backtest job is still running"))
+		default:
+			c.Error(apierror.Wrap(err, apierror.CodeBacktestError, "Failed to run backtest"))
+		}
+		return
+	}
+
+	// Downsample a copy so the stored result keeps the full daily series
+	response := *result
+	if points > 0 {
+		response.Performance, err = services.DownsampleBacktest(result.Performance, points)
+		if err != nil {
+			c.Error(apierror.New(apierror.CodeValidation, "Invalid points parameter. Must be an integer of at least 3"))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// parseBacktestCostsQuery reads the optional commission and slippageBps query
+// parameters, writing an error response if either is invalid
+func parseBacktestCostsQuery(c *gin.Context) (services.BacktestCosts, bool) {
+	var costs services.BacktestCosts
+
+	params := []struct {
+		name   string
+		target *float64
+	}{
+		{"commission", &costs.CommissionPerTrade},
+		{"slippageBps", &costs.SlippageBps},
+	}
+
+	for _, param := range params {
+		valueStr := c.Query(param.name)
+		if valueStr == "" {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(valueStr, 64)
+		if err != nil {
+			c.Error(apierror.New(apierror.CodeValidation, fmt.Sprintf("Invalid %s parameter. Must be a number", param.name)))
+			return costs, false
+		}
+		*param.target = value
+	}
+
+	if err := costs.Validate(); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid trading cost parameters"))
+		return costs, false
+	}
+
+	return costs, true
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BenchmarkHandler handles benchmark-related requests
+type BenchmarkHandler struct {
+	blendService   *services.BenchmarkBlendService
+	catalogService *services.BenchmarkCatalogService
+}
+
+// NewBenchmarkHandler creates a new BenchmarkHandler instance
+func NewBenchmarkHandler(blendService *services.BenchmarkBlendService, catalogService *services.BenchmarkCatalogService) *BenchmarkHandler {
+	return &BenchmarkHandler{
+		blendService:   blendService,
+		catalogService: catalogService,
+	}
+}
+
+// GetBenchmarks returns the benchmark catalog, including the authenticated
+// user's custom benchmarks
+func (h *BenchmarkHandler) GetBenchmarks(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	benchmarks, err := h.catalogService.GetCatalog(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch benchmarks"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"benchmarks": benchmarks,
+	})
+}
+
+// CreateCustomBenchmark saves a symbol as a custom benchmark
+func (h *BenchmarkHandler) CreateCustomBenchmark(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CustomBenchmarkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid custom benchmark data"))
+		return
+	}
+
+	benchmark, err := h.catalogService.CreateCustomBenchmark(c.Request.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCustomBenchmark):
+			c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid custom benchmark data"))
+		case errors.Is(err, services.ErrTooManyCustomBenchmarks):
+			c.Error(apierror.Wrap(err, apierror.CodeLimitExceeded, "Too many custom benchmarks"))
+		case errors.Is(err, services.ErrDuplicateCustomBenchmark):
+			c.Error(apierror.New(apierror.CodeDuplicateBenchmark, "This benchmark is already in the catalog"))
+		default:
+			c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to create custom benchmark"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, benchmark)
+}
+
+// DeleteCustomBenchmark deletes a custom benchmark
+func (h *BenchmarkHandler) DeleteCustomBenchmark(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	benchmarkID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid custom benchmark ID"))
+		return
+	}
+
+	if err := h.catalogService.DeleteCustomBenchmark(userID, benchmarkID); err != nil {
+		if errors.Is(err, services.ErrCustomBenchmarkNotFound) {
+			c.Error(apierror.New(apierror.CodeNotFound, "Custom benchmark not found"))
+			return
+		}
+
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to delete custom benchmark"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Custom benchmark deleted successfully",
+	})
+}
+
+// GetBlends returns the saved benchmark blends of the authenticated user
+func (h *BenchmarkHandler) GetBlends(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	blends, err := h.blendService.GetUserBlends(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch benchmark blends"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"blends": blends,
+	})
+}
+
+// CreateBlend saves a new benchmark blend. The blend can then be used as a
+// backtest benchmark with benchmark=blend:<id>.
+func (h *BenchmarkHandler) CreateBlend(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.BenchmarkBlendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid benchmark blend data"))
+		return
+	}
+
+	blend, err := h.blendService.CreateBlend(userID, req.Name, req.Components)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidBenchmarkBlend):
+			c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid benchmark blend data"))
+		case errors.Is(err, services.ErrDuplicateBenchmarkBlend):
+			c.Error(apierror.New(apierror.CodeDuplicateBenchmarkBlend, "A benchmark blend with this name already exists"))
+		default:
+			c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to create benchmark blend"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, blend)
+}
+
+// DeleteBlend deletes a saved benchmark blend
+func (h *BenchmarkHandler) DeleteBlend(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	blendID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid benchmark blend ID"))
+		return
+	}
+
+	if err := h.blendService.DeleteBlend(userID, blendID); err != nil {
+		if errors.Is(err, services.ErrBenchmarkBlendNotFound) {
+			c.Error(apierror.New(apierror.CodeNotFound, "Benchmark blend not found"))
+			return
+		}
+
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to delete benchmark blend"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Benchmark blend deleted successfully",
+	})
+}
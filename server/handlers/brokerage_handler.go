@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BrokerageHandler handles brokerage accounts linked through Plaid or SnapTrade
+type BrokerageHandler struct {
+	brokerageService *services.BrokerageService
+}
+
+// NewBrokerageHandler creates a new BrokerageHandler instance
+func NewBrokerageHandler(brokerageService *services.BrokerageService) *BrokerageHandler {
+	return &BrokerageHandler{
+		brokerageService: brokerageService,
+	}
+}
+
+// GetLinks returns the authenticated user's brokerage links and the
+// aggregators and exchanges available for new ones
+func (h *BrokerageHandler) GetLinks(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.brokerageService.ListLinks(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch brokerage links"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"links":     links,
+		"providers": h.brokerageService.Providers(),
+		"exchanges": h.brokerageService.Exchanges(),
+	})
+}
+
+// StartLink begins linking a brokerage. The response holds the token or URL
+// the client opens the aggregator's flow with.
+func (h *BrokerageHandler) StartLink(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.BrokerageLinkStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid brokerage link data"))
+		return
+	}
+
+	start, err := h.brokerageService.StartLink(userID, req.Provider)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to start brokerage link"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, start)
+}
+
+// LinkExchange links a crypto exchange account with a read-only API key
+func (h *BrokerageHandler) LinkExchange(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ExchangeLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid exchange link data"))
+		return
+	}
+
+	link, err := h.brokerageService.LinkExchange(userID, req)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to link exchange"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"link": link,
+	})
+}
+
+// CompleteLink finishes linking a brokerage
+func (h *BrokerageHandler) CompleteLink(c *gin.Context) {
+	userID, linkID, ok := parseBrokerageLinkID(c)
+	if !ok {
+		return
+	}
+
+	var req models.BrokerageLinkCompleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid brokerage link data"))
+		return
+	}
+
+	link, err := h.brokerageService.CompleteLink(userID, linkID, req)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to complete brokerage link"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"link": link,
+	})
+}
+
+// SyncLink syncs a brokerage link now
+func (h *BrokerageHandler) SyncLink(c *gin.Context) {
+	userID, linkID, ok := parseBrokerageLinkID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.brokerageService.SyncLink(userID, linkID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to sync brokerage link"))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DeleteLink unlinks a brokerage, keeping the transactions synced from it
+func (h *BrokerageHandler) DeleteLink(c *gin.Context) {
+	userID, linkID, ok := parseBrokerageLinkID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.brokerageService.DeleteLink(userID, linkID); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to delete brokerage link"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Brokerage link deleted successfully",
+	})
+}
+
+// parseBrokerageLinkID reads the authenticated user and the link ID route
+// parameter, reporting an error when either is missing or invalid
+func parseBrokerageLinkID(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	linkID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid brokerage link ID"))
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	return userID, linkID, true
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CashInterestHandler handles the interest rates set on cash balances
+type CashInterestHandler struct {
+	cashInterestService *services.CashInterestService
+}
+
+// NewCashInterestHandler creates a new CashInterestHandler instance
+func NewCashInterestHandler(cashInterestService *services.CashInterestService) *CashInterestHandler {
+	return &CashInterestHandler{
+		cashInterestService: cashInterestService,
+	}
+}
+
+// GetRates returns the cash interest rates of the authenticated user
+func (h *CashInterestHandler) GetRates(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	rates, err := h.cashInterestService.ListRates(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch cash interest rates"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rates": rates,
+	})
+}
+
+// SetRate sets the APY earned on a cash balance
+func (h *CashInterestHandler) SetRate(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CashInterestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid cash interest data"))
+		return
+	}
+
+	rate, err := h.cashInterestService.SetRate(userID, c.Param("symbol"), req.APY)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to set cash interest rate"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rate": rate,
+	})
+}
+
+// DeleteRate stops interest on a cash balance
+func (h *CashInterestHandler) DeleteRate(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.cashInterestService.DeleteRate(userID, c.Param("symbol")); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to delete cash interest rate"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cash interest rate deleted successfully",
+	})
+}
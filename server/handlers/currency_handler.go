@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/currencycode"
 	"stock-portfolio-tracker/services"
 	"strings"
 
@@ -25,71 +28,41 @@ func (h *CurrencyHandler) GetExchangeRate(c *gin.Context) {
 	from := c.Query("from")
 	to := c.Query("to")
 	
-	// Normalize currency codes to uppercase
-	from = strings.ToUpper(strings.TrimSpace(from))
-	to = strings.ToUpper(strings.TrimSpace(to))
+	// Normalize currency codes, reporting CNY as RMB
+	from = currencycode.Normalize(from)
+	to = currencycode.Normalize(to)
 	
 	// Validate currency codes
 	if from == "" || to == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Both 'from' and 'to' currency codes are required",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Both 'from' and 'to' currency codes are required"))
 		return
 	}
 	
 	// Validate currency code format (should be 3 letters)
 	if len(from) != 3 || len(to) != 3 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Currency codes must be 3 letters (e.g., USD, CNY)",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Currency codes must be 3 letters (e.g., USD, CNY)"))
 		return
 	}
 	
 	// Get exchange rate
 	rate, err := h.currencyService.GetExchangeRate(from, to)
 	if err != nil {
-		if err == services.ErrInvalidCurrencyCode {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "VALIDATION_ERROR",
-					"message": "Invalid currency code",
-				},
-			})
+		if errors.Is(err, services.ErrInvalidCurrencyCode) {
+			c.Error(apierror.New(apierror.CodeValidation, "Invalid currency code"))
 			return
 		}
 		
-		if err == services.ErrExchangeRateNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": gin.H{
-					"code":    "NOT_FOUND",
-					"message": "Exchange rate not found for the specified currency pair",
-				},
-			})
+		if errors.Is(err, services.ErrExchangeRateNotFound) {
+			c.Error(apierror.New(apierror.CodeNotFound, "Exchange rate not found for the specified currency pair"))
 			return
 		}
 		
-		if err == services.ErrCurrencyAPIError {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"error": gin.H{
-					"code":    "EXTERNAL_API_ERROR",
-					"message": "Failed to fetch exchange rate from external API",
-				},
-			})
+		if errors.Is(err, services.ErrCurrencyAPIError) {
+			c.Error(apierror.New(apierror.CodeExternalAPI, "Failed to fetch exchange rate from external API"))
 			return
 		}
 		
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to get exchange rate",
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to get exchange rate"))
 		return
 	}
 	
@@ -99,3 +72,84 @@ func (h *CurrencyHandler) GetExchangeRate(c *gin.Context) {
 		"rate": rate,
 	})
 }
+
+// GetRateTable handles fetching the exchange rates from a base currency to
+// every supported currency
+func (h *CurrencyHandler) GetRateTable(c *gin.Context) {
+	base := currencycode.Normalize(c.DefaultQuery("base", "USD"))
+	if len(base) != 3 {
+		c.Error(apierror.New(apierror.CodeValidation, "Currency codes must be 3 letters (e.g., USD, CNY)"))
+		return
+	}
+
+	rates, err := h.currencyService.GetRateTable(base)
+	if err != nil {
+		if errors.Is(err, services.ErrExchangeRateNotFound) {
+			c.Error(apierror.New(apierror.CodeNotFound, "Exchange rates not found for the specified base currency"))
+			return
+		}
+
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to get exchange rates"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"base":  base,
+		"rates": rates,
+	})
+}
+
+// convertBatchRequest is the body of a batch currency conversion
+type convertBatchRequest struct {
+	Conversions []struct {
+		Amount float64 `json:"amount"`
+		From   string  `json:"from" binding:"required,len=3"`
+		To     string  `json:"to" binding:"required,len=3"`
+	} `json:"conversions" binding:"required,min=1,max=100,dive"`
+}
+
+// ConvertBatch handles converting several amounts between currency pairs in
+// one request
+func (h *CurrencyHandler) ConvertBatch(c *gin.Context) {
+	var req convertBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid request body"))
+		return
+	}
+
+	conversions := make([]services.CurrencyConversion, len(req.Conversions))
+	for i, conversion := range req.Conversions {
+		conversions[i] = services.CurrencyConversion{
+			Amount: conversion.Amount,
+			From:   conversion.From,
+			To:     conversion.To,
+		}
+	}
+
+	results, err := h.currencyService.ConvertBatch(conversions)
+	if err != nil {
+		if errors.Is(err, services.ErrExchangeRateNotFound) {
+			c.Error(apierror.Wrap(err, apierror.CodeNotFound, "Exchange rate not found for a requested currency pair"))
+			return
+		}
+
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to convert amounts"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversions": results,
+	})
+}
+
+// parseDisplayCurrency reads the optional currency parameter, defaulting to
+// USD, writing a validation error and returning false when it is not a
+// supported display currency
+func parseDisplayCurrency(c *gin.Context) (string, bool) {
+	currency, err := services.NormalizeDisplayCurrency(c.DefaultQuery("currency", "USD"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid currency parameter. Must be one of "+strings.Join(services.DisplayCurrencies, ", ")))
+		return "", false
+	}
+	return currency, true
+}
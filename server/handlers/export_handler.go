@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportHandler handles account data export requests
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+// NewExportHandler creates a new ExportHandler instance
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// StartExport queues an export of all of the user's data and returns the job
+func (h *ExportHandler) StartExport(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.exportService.StartExport(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to start data export"))
+		return
+	}
+
+	c.Header("Location", c.FullPath())
+	c.JSON(http.StatusAccepted, withDownloadURL(c, job))
+}
+
+// GetExport returns the status of the user's latest export, with a download
+// link once its archive is ready
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.exportService.GetExport(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch data export"))
+		return
+	}
+
+	c.JSON(http.StatusOK, withDownloadURL(c, job))
+}
+
+// DownloadExport serves a completed export archive. The token in the link
+// authorizes the download, so no credentials are needed.
+func (h *ExportHandler) DownloadExport(c *gin.Context) {
+	archive, job, err := h.exportService.GetArchive(c.Param("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrExportNotComplete):
+			c.Error(apierror.New(apierror.CodeJobNotComplete, "Data export is still running"))
+		case errors.Is(err, services.ErrExportFailed):
+			c.Error(apierror.Wrap(err, apierror.CodeInternal, "Data export failed. Start a new export"))
+		default:
+			c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to download data export"))
+		}
+		return
+	}
+
+	filename := "portfolio-export-" + job.CompletedAt.UTC().Format("2006-01-02") + ".zip"
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// withDownloadURL links a completed export to its archive under the API
+// version the request came in on
+func withDownloadURL(c *gin.Context, job *services.ExportJob) *services.ExportJob {
+	if job.Status == services.ExportCompleted {
+		base := strings.TrimSuffix(c.FullPath(), "/me/export")
+		job.DownloadURL = base + "/exports/" + job.DownloadToken
+	}
+	return job
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureFlagHandler reports which features are rolled out to a user
+type FeatureFlagHandler struct {
+	featureFlagService *services.FeatureFlagService
+}
+
+// NewFeatureFlagHandler creates a new FeatureFlagHandler instance
+func NewFeatureFlagHandler(featureFlagService *services.FeatureFlagService) *FeatureFlagHandler {
+	return &FeatureFlagHandler{
+		featureFlagService: featureFlagService,
+	}
+}
+
+// GetFeatures returns every known feature flag evaluated for the
+// authenticated user
+func (h *FeatureFlagHandler) GetFeatures(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"features": h.featureFlagService.ForUser(userID),
+	})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/graphqlapi"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// GraphQLHandler handles GraphQL queries
+type GraphQLHandler struct {
+	schema *graphql.Schema
+}
+
+// NewGraphQLHandler creates a new GraphQLHandler instance
+func NewGraphQLHandler(schema *graphql.Schema) *GraphQLHandler {
+	return &GraphQLHandler{
+		schema: schema,
+	}
+}
+
+// GraphQLRequest is the body of a GraphQL query
+type GraphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Query executes a GraphQL query for the authenticated user. Field errors are
+// returned in the response's errors list alongside any partial data.
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req GraphQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid GraphQL request"))
+		return
+	}
+
+	ctx := graphqlapi.WithUser(c.Request.Context(), userID)
+	c.JSON(http.StatusOK, h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HouseholdHandler handles linked accounts and the household view
+type HouseholdHandler struct {
+	householdService *services.HouseholdService
+}
+
+// NewHouseholdHandler creates a new HouseholdHandler instance
+func NewHouseholdHandler(householdService *services.HouseholdService) *HouseholdHandler {
+	return &HouseholdHandler{
+		householdService: householdService,
+	}
+}
+
+// Invite grants another account read-only access to the authenticated user's data
+func (h *HouseholdHandler) Invite(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.AccountInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid invite data"))
+		return
+	}
+
+	link, err := h.householdService.Invite(userID, req.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInviteeNotFound):
+			c.Error(apierror.New(apierror.CodeNotFound, "No account exists for this email"))
+		case errors.Is(err, services.ErrCannotInviteSelf):
+			c.Error(apierror.New(apierror.CodeValidation, "You cannot invite your own account"))
+		case errors.Is(err, services.ErrDuplicateAccountLink):
+			c.Error(apierror.New(apierror.CodeDuplicateAccount, "This account has already been invited"))
+		default:
+			c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to create invite"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// GetLinks returns the access the user has granted and received
+func (h *HouseholdHandler) GetLinks(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	granted, received, err := h.householdService.GetLinks(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch account links"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"granted":  granted,
+		"received": received,
+	})
+}
+
+// AcceptInvite accepts a pending invite addressed to the authenticated user
+func (h *HouseholdHandler) AcceptInvite(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	linkID, ok := parseAccountLinkID(c)
+	if !ok {
+		return
+	}
+
+	link, err := h.householdService.AcceptInvite(userID, linkID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAccountLinkNotFound):
+			c.Error(apierror.New(apierror.CodeNotFound, "Invite not found"))
+		case errors.Is(err, services.ErrAccountLinkNotPending):
+			c.Error(apierror.New(apierror.CodeInviteNotPending, "Invite has already been accepted"))
+		default:
+			c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to accept invite"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// RemoveLink revokes granted access or leaves a received link
+func (h *HouseholdHandler) RemoveLink(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	linkID, ok := parseAccountLinkID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.householdService.RemoveLink(userID, linkID); err != nil {
+		if errors.Is(err, services.ErrAccountLinkNotFound) {
+			c.Error(apierror.New(apierror.CodeNotFound, "Account link not found"))
+			return
+		}
+
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to remove account link"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account link removed successfully",
+	})
+}
+
+// GetDashboard returns dashboard metrics merged across the household
+func (h *HouseholdHandler) GetDashboard(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	dashboard, err := h.householdService.GetHouseholdDashboard(userID, currency)
+	if err != nil {
+		fmt.Printf("Error fetching household dashboard for user %s: %v\n", userID.Hex(), err)
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch household dashboard"))
+		return
+	}
+
+	c.JSON(http.StatusOK, dashboard)
+}
+
+// GetPerformance returns historical performance merged across the household
+func (h *HouseholdHandler) GetPerformance(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	period := c.DefaultQuery("period", "1M")
+	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
+	if !validPeriods[period] {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid period parameter. Must be 1M, 3M, 6M, 1Y, or ALL"))
+		return
+	}
+
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	points, ok := parsePointsQuery(c)
+	if !ok {
+		return
+	}
+
+	performance, err := h.householdService.GetHouseholdPerformance(userID, period, currency)
+	if err != nil {
+		fmt.Printf("Error fetching household performance for user %s: %v\n", userID.Hex(), err)
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch household performance"))
+		return
+	}
+
+	if points > 0 {
+		performance.Data, err = services.DownsamplePerformance(performance.Data, points)
+		if err != nil {
+			c.Error(apierror.New(apierror.CodeValidation, "Invalid points parameter. Must be an integer of at least 3"))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, performance)
+}
+
+// parseAccountLinkID parses the :id path parameter, writing an error response if invalid
+func parseAccountLinkID(c *gin.Context) (primitive.ObjectID, bool) {
+	linkID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid account link ID"))
+		return primitive.NilObjectID, false
+	}
+	return linkID, true
+}
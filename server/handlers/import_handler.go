@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler handles broker statement imports
+type ImportHandler struct {
+	importService *services.ImportService
+}
+
+// NewImportHandler creates a new ImportHandler instance
+func NewImportHandler(importService *services.ImportService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+	}
+}
+
+// GetBrokers returns the supported statement formats
+func (h *ImportHandler) GetBrokers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"brokers": services.SupportedBrokers(),
+	})
+}
+
+// Preview parses an uploaded statement and reports the trades it contains and
+// which of them already exist, without saving anything
+func (h *ImportHandler) Preview(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	data, ok := readStatementUpload(c)
+	if !ok {
+		return
+	}
+
+	preview, err := h.importService.Preview(userID, c.PostForm("broker"), data)
+	if err != nil {
+		respondImportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// Commit imports the new trades of an uploaded statement, skipping duplicates
+func (h *ImportHandler) Commit(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	data, ok := readStatementUpload(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.importService.Commit(userID, c.PostForm("broker"), data)
+	if err != nil {
+		respondImportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// readStatementUpload reads the multipart "file" field, writing an error response on failure
+func readStatementUpload(c *gin.Context) ([]byte, bool) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "A statement file is required"))
+		return nil, false
+	}
+
+	if fileHeader.Size > services.MaxStatementSize {
+		c.Error(apierror.New(apierror.CodeStatementTooLarge, "Statement files must be 1MB or smaller"))
+		return nil, false
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Failed to read statement file"))
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, services.MaxStatementSize+1))
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Failed to read statement file"))
+		return nil, false
+	}
+
+	return data, true
+}
+
+// respondImportError maps statement import errors to responses
+func respondImportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnsupportedBroker):
+		c.Error(apierror.New(apierror.CodeUnsupportedBroker, "Unsupported broker").WithDetails(services.SupportedBrokers()))
+	case errors.Is(err, services.ErrBrokerNotDetectable):
+		c.Error(apierror.New(apierror.CodeUnrecognized, "Could not detect the statement format. Specify the broker explicitly").WithDetails(services.SupportedBrokers()))
+	case errors.Is(err, services.ErrUnrecognizedFormat), errors.Is(err, services.ErrEmptyStatement):
+		c.Error(apierror.Wrap(err, apierror.CodeUnrecognized, "No trades could be read from the statement"))
+	case errors.Is(err, services.ErrStatementTooLarge):
+		c.Error(apierror.New(apierror.CodeStatementTooLarge, "Statement files must be 1MB or smaller"))
+	default:
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to import statement"))
+	}
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetaHandler serves the fixed reference data clients render with
+type MetaHandler struct{}
+
+// NewMetaHandler creates a new MetaHandler instance
+func NewMetaHandler() *MetaHandler {
+	return &MetaHandler{}
+}
+
+// GetCurrencies returns the supported display currencies with the symbol,
+// decimal places and locale to format their values with
+func (h *MetaHandler) GetCurrencies(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.JSON(http.StatusOK, gin.H{
+		"currencies": services.DisplayCurrencyFormats(),
+	})
+}
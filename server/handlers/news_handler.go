@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewsHandler handles news feed requests
+type NewsHandler struct {
+	newsService *services.NewsService
+}
+
+// NewNewsHandler creates a new NewsHandler instance
+func NewNewsHandler(newsService *services.NewsService) *NewsHandler {
+	return &NewsHandler{
+		newsService: newsService,
+	}
+}
+
+// GetSymbolNews returns the latest headlines about a symbol
+func (h *NewsHandler) GetSymbolNews(c *gin.Context) {
+	symbol := strings.ToUpper(strings.TrimSpace(c.Param("symbol")))
+	if symbol == "" {
+		c.Error(apierror.New(apierror.CodeValidation, "Stock symbol is required"))
+		return
+	}
+
+	limit, ok := parseNewsLimit(c)
+	if !ok {
+		return
+	}
+
+	news, err := h.newsService.GetSymbolNews(c.Request.Context(), symbol, limit)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to get news"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"symbol": symbol,
+		"news":   news,
+	})
+}
+
+// GetPortfolioNews returns the latest headlines about the authenticated
+// user's holdings
+func (h *NewsHandler) GetPortfolioNews(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	limit, ok := parseNewsLimit(c)
+	if !ok {
+		return
+	}
+
+	news, err := h.newsService.GetPortfolioNews(c.Request.Context(), userID, limit)
+	if err != nil {
+		fmt.Printf("Error fetching portfolio news for user %s: %v\n", userID.Hex(), err)
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to get portfolio news"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"news": news,
+	})
+}
+
+// parseNewsLimit reads the optional limit parameter, writing a validation
+// error and returning false when it is out of range
+func parseNewsLimit(c *gin.Context) (int, bool) {
+	limitStr := c.Query("limit")
+	if limitStr == "" {
+		return services.DefaultNewsLimit, true
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > services.MaxNewsLimit {
+		c.Error(apierror.New(apierror.CodeValidation, fmt.Sprintf("Invalid limit parameter. Must be between 1 and %d", services.MaxNewsLimit)))
+		return 0, false
+	}
+	return limit, true
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PendingOrderHandler handles limit and stop orders waiting for their trigger price
+type PendingOrderHandler struct {
+	pendingOrderService *services.PendingOrderService
+}
+
+// NewPendingOrderHandler creates a new PendingOrderHandler instance
+func NewPendingOrderHandler(pendingOrderService *services.PendingOrderService) *PendingOrderHandler {
+	return &PendingOrderHandler{
+		pendingOrderService: pendingOrderService,
+	}
+}
+
+// CreateOrder records a pending order for the authenticated user
+func (h *PendingOrderHandler) CreateOrder(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.PendingOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid pending order data"))
+		return
+	}
+
+	order, err := h.pendingOrderService.CreateOrder(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTransaction) {
+			c.Error(apierror.New(apierror.CodeValidation, err.Error()))
+			return
+		}
+
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to create pending order"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"order": order,
+	})
+}
+
+// GetOrders returns the pending orders of the authenticated user
+func (h *PendingOrderHandler) GetOrders(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	orders, err := h.pendingOrderService.ListOrders(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch pending orders"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"orders": orders,
+	})
+}
+
+// CancelOrder cancels an open pending order
+func (h *PendingOrderHandler) CancelOrder(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid pending order ID"))
+		return
+	}
+
+	order, err := h.pendingOrderService.CancelOrder(userID, orderID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to cancel pending order"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"order": order,
+	})
+}
@@ -1,9 +1,16 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
 	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/middleware"
 	"stock-portfolio-tracker/models"
 	"stock-portfolio-tracker/services"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -26,47 +33,274 @@ func (h *PortfolioHandler) GetHoldings(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
-	// Get currency parameter (default to USD)
-	currency := c.DefaultQuery("currency", "USD")
-	if currency != "USD" && currency != "RMB" {
+	// Get currency parameter (default to USD). v1 silently falls back to USD
+	// for unknown currencies; v2 rejects them.
+	v2 := middleware.GetAPIVersion(c) == "v2"
+	currency, err := services.NormalizeDisplayCurrency(c.DefaultQuery("currency", "USD"))
+	if err != nil {
+		if v2 {
+			c.Error(apierror.New(apierror.CodeValidation, "Currency must be one of "+strings.Join(services.DisplayCurrencies, ", ")))
+			return
+		}
 		currency = "USD"
 	}
 
+	query, ok := parseHoldingsQuery(c)
+	if !ok {
+		return
+	}
+
+	// A past date reconstructs the holdings at that day's close instead
+	if asOfStr := c.Query("asOf"); asOfStr != "" {
+		asOf, err := time.Parse("2006-01-02", asOfStr)
+		if err != nil {
+			c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid asOf format. Expected YYYY-MM-DD"))
+			return
+		}
+
+		snapshot, err := h.portfolioService.GetHoldingsSnapshot(c.Request.Context(), userID, asOf, currency)
+		if err != nil {
+			c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to reconstruct holdings"))
+			return
+		}
+		snapshot.Holdings = query.Apply(snapshot.Holdings)
+		c.JSON(http.StatusOK, snapshot)
+		return
+	}
+
 	// Get holdings
 	holdings, err := h.portfolioService.GetUserHoldings(userID, currency)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to fetch holdings",
-				"details": err.Error(),
-			},
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch holdings"))
+		return
+	}
+
+	selected := query.Apply(holdings)
+	exchangeRates := h.portfolioService.HoldingsRateFreshness(selected, currency)
+	if v2 {
+		resp := newHoldingsResponseV2(holdings, selected, currency)
+		resp.ExchangeRates = exchangeRates
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	response := gin.H{
+		"holdings": selected,
+	}
+	if exchangeRates != nil {
+		response["exchangeRates"] = exchangeRates
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// parseHoldingsQuery reads the sort, order, minValue and symbol query
+// parameters that narrow and order the holdings list
+func parseHoldingsQuery(c *gin.Context) (services.HoldingsQuery, bool) {
+	query := services.HoldingsQuery{
+		Sort:   c.Query("sort"),
+		Symbol: c.Query("symbol"),
+	}
+	if query.Sort != "" && !services.ValidHoldingsSort(query.Sort) {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid sort parameter. Must be value, gainPercent, or symbol"))
+		return query, false
+	}
+
+	// Values and gains default to the largest first, symbols to A to Z
+	switch c.Query("order") {
+	case "asc":
+	case "desc":
+		query.Descending = true
+	case "":
+		query.Descending = query.Sort != services.HoldingsSortSymbol
+	default:
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid order parameter. Must be asc or desc"))
+		return query, false
+	}
+
+	if minValueStr := c.Query("minValue"); minValueStr != "" {
+		minValue, err := strconv.ParseFloat(minValueStr, 64)
+		if err != nil || minValue < 0 {
+			c.Error(apierror.New(apierror.CodeValidation, "Invalid minValue parameter. Must be a non-negative number"))
+			return query, false
+		}
+		query.MinValue = minValue
+	}
+	return query, true
+}
+
+// holdingV2 is a holding in the v2 holdings response
+type holdingV2 struct {
+	services.Holding
+	Weight float64 `json:"weight"` // Percentage of the total value
+}
+
+// holdingsResponseV2 is the v2 holdings response, which adds portfolio totals
+// and each holding's weight to the v1 shape. The totals and weights cover
+// every holding, even when the query selects only some.
+type holdingsResponseV2 struct {
+	Currency   string      `json:"currency"`
+	TotalValue float64     `json:"totalValue"`
+	TotalCost  float64     `json:"totalCost"`
+	Holdings   []holdingV2 `json:"holdings"`
+
+	ExchangeRates *services.RateFreshness `json:"exchangeRates,omitempty"`
+}
+
+func newHoldingsResponseV2(holdings, selected []services.Holding, currency string) holdingsResponseV2 {
+	resp := holdingsResponseV2{
+		Currency: currency,
+		Holdings: make([]holdingV2, 0, len(selected)),
+	}
+	for _, holding := range holdings {
+		resp.TotalValue += holding.CurrentValue
+		resp.TotalCost += holding.CostBasis
+	}
+	for _, holding := range selected {
+		weight := 0.0
+		if resp.TotalValue > 0 {
+			weight = holding.CurrentValue / resp.TotalValue * 100
+		}
+		resp.Holdings = append(resp.Holdings, holdingV2{Holding: holding, Weight: weight})
+	}
+	return resp
+}
+
+// ExportHoldings downloads the current holdings table as CSV or JSON for
+// spreadsheets
+func (h *PortfolioHandler) ExportHoldings(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid format parameter. Must be csv or json"))
+		return
+	}
+
+	rows, err := h.portfolioService.GetHoldingsExport(c.Request.Context(), userID, currency)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to export holdings"))
+		return
+	}
+
+	filename := "holdings-" + time.Now().Format("2006-01-02") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "private, no-store")
+	if format == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"currency": currency,
+			"holdings": rows,
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"holdings": holdings,
+	// Render into a buffer so a failure can still produce a JSON error
+	var buf bytes.Buffer
+	if err := services.WriteHoldingsCSV(&buf, rows); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to export holdings"))
+		return
+	}
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// GetHolding returns the full position view of one symbol: lots, average cost,
+// realized and unrealized gain, dividends, transactions and a price chart
+func (h *PortfolioHandler) GetHolding(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	period := strings.ToUpper(c.DefaultQuery("period", "1Y"))
+	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
+	if !validPeriods[period] {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid period parameter. Must be 1M, 3M, 6M, 1Y, or ALL"))
+		return
+	}
+
+	detail, err := h.portfolioService.GetPositionDetail(c.Request.Context(), userID, c.Param("symbol"), currency, period)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch position"))
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// SimulateTrades previews hypothetical trades against the user's portfolio
+// without recording them
+func (h *PortfolioHandler) SimulateTrades(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.TradeSimulationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid simulation data"))
+		return
+	}
+	if req.Currency == "" {
+		req.Currency = "USD"
+	}
+
+	simulation, err := h.portfolioService.SimulateTrades(c.Request.Context(), userID, req.Trades, req.Currency)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTransaction) {
+			c.Error(apierror.New(apierror.CodeValidation, err.Error()))
+			return
+		}
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to simulate trades"))
+		return
+	}
+
+	c.JSON(http.StatusOK, simulation)
+}
+
+// DisposePosition sells the rest of a position at a manual price
+func (h *PortfolioHandler) DisposePosition(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.DisposalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid disposal data"))
+		return
+	}
+
+	transaction, err := h.portfolioService.DisposePosition(userID, c.Param("symbol"), req)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to dispose of position"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Position disposed of successfully",
+		"transaction": transaction,
 	})
 }
 
@@ -75,36 +309,20 @@ func (h *PortfolioHandler) AddTransaction(c *gin.Context) {
 	// Get user ID from context
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
 	// Parse request body
 	var req models.TransactionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid transaction data",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid transaction data"))
 		return
 	}
 
@@ -117,46 +335,30 @@ func (h *PortfolioHandler) AddTransaction(c *gin.Context) {
 		Currency: req.Currency,
 		Fees:     req.Fees,
 		Date:     req.Date,
+		Note:     req.Note,
+		Tags:     req.Tags,
+	}
+	if req.Option != nil {
+		transaction.Option = req.Option.Contract(req.Symbol)
 	}
 
 	// Add transaction
 	if err := h.portfolioService.AddTransaction(userID, transaction); err != nil {
 		// Handle specific errors
 		if err == services.ErrInsufficientShares {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "INSUFFICIENT_SHARES",
-					"message": "Insufficient shares for sell transaction",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeInsufficientShares, "Insufficient shares for sell transaction"))
 			return
 		}
 		if err == services.ErrFutureDate {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "VALIDATION_ERROR",
-					"message": "Transaction date cannot be in the future",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeValidation, "Transaction date cannot be in the future"))
 			return
 		}
-		if err == services.ErrInvalidTransaction {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "VALIDATION_ERROR",
-					"message": err.Error(),
-				},
-			})
+		if errors.Is(err, services.ErrInvalidTransaction) {
+			c.Error(apierror.New(apierror.CodeValidation, err.Error()))
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to add transaction",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to add transaction"))
 		return
 	}
 
@@ -171,23 +373,13 @@ func (h *PortfolioHandler) UpdateTransaction(c *gin.Context) {
 	// Get user ID from context
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
@@ -195,25 +387,14 @@ func (h *PortfolioHandler) UpdateTransaction(c *gin.Context) {
 	txIDStr := c.Param("id")
 	txID, err := primitive.ObjectIDFromHex(txIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid transaction ID",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid transaction ID"))
 		return
 	}
 
 	// Parse request body
 	var req models.TransactionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid transaction data",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid transaction data"))
 		return
 	}
 
@@ -226,46 +407,34 @@ func (h *PortfolioHandler) UpdateTransaction(c *gin.Context) {
 		Currency: req.Currency,
 		Fees:     req.Fees,
 		Date:     req.Date,
+		Note:     req.Note,
+		Tags:     req.Tags,
+	}
+	if req.Option != nil {
+		transaction.Option = req.Option.Contract(req.Symbol)
 	}
 
 	// Update transaction
 	if err := h.portfolioService.UpdateTransaction(userID, txID, transaction); err != nil {
 		// Handle specific errors
 		if err == services.ErrTransactionNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": gin.H{
-					"code":    "NOT_FOUND",
-					"message": "Transaction not found",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeNotFound, "Transaction not found"))
 			return
 		}
 		if err == services.ErrInsufficientShares {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "INSUFFICIENT_SHARES",
-					"message": "Insufficient shares for sell transaction",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeInsufficientShares, "Insufficient shares for sell transaction"))
 			return
 		}
 		if err == services.ErrFutureDate {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "VALIDATION_ERROR",
-					"message": "Transaction date cannot be in the future",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeValidation, "Transaction date cannot be in the future"))
+			return
+		}
+		if errors.Is(err, services.ErrInvalidTransaction) {
+			c.Error(apierror.New(apierror.CodeValidation, err.Error()))
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to update transaction",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to update transaction"))
 		return
 	}
 
@@ -280,23 +449,13 @@ func (h *PortfolioHandler) DeleteTransaction(c *gin.Context) {
 	// Get user ID from context
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
@@ -304,34 +463,18 @@ func (h *PortfolioHandler) DeleteTransaction(c *gin.Context) {
 	txIDStr := c.Param("id")
 	txID, err := primitive.ObjectIDFromHex(txIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid transaction ID",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid transaction ID"))
 		return
 	}
 
 	// Delete transaction
 	if err := h.portfolioService.DeleteTransaction(userID, txID); err != nil {
 		if err == services.ErrTransactionNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": gin.H{
-					"code":    "NOT_FOUND",
-					"message": "Transaction not found",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeNotFound, "Transaction not found"))
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to delete transaction",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to delete transaction"))
 		return
 	}
 
@@ -340,55 +483,71 @@ func (h *PortfolioHandler) DeleteTransaction(c *gin.Context) {
 	})
 }
 
-// GetTransactionsBySymbol returns all transactions for a specific symbol
+// GetTransactions returns the user's transactions newest first, limited to
+// those carrying the tag query parameter when it is set
+func (h *PortfolioHandler) GetTransactions(c *gin.Context) {
+	// Get user ID from context
+	userIDInterface, exists := c.Get("userID")
+	if !exists {
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
+		return
+	}
+
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
+		return
+	}
+
+	var transactions []models.Transaction
+	var err error
+	if tag := c.Query("tag"); tag != "" {
+		transactions, err = h.portfolioService.GetTransactionsByTag(userID, tag)
+	} else {
+		transactions, err = h.portfolioService.GetTransactionsSince(userID, time.Time{})
+	}
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch transactions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": transactions,
+	})
+}
+
+// GetTransactionsBySymbol returns all transactions for a specific symbol,
+// limited to those carrying the tag query parameter when it is set
 func (h *PortfolioHandler) GetTransactionsBySymbol(c *gin.Context) {
 	// Get user ID from context
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
 	// Get symbol from URL
 	symbol := c.Param("symbol")
 	if symbol == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Symbol is required",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Symbol is required"))
 		return
 	}
 
 	// Get transactions
 	transactions, err := h.portfolioService.GetTransactionsBySymbol(userID, symbol)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to fetch transactions",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch transactions"))
 		return
 	}
+	if tag := c.Query("tag"); tag != "" {
+		transactions = services.FilterTransactionsByTag(transactions, tag)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"transactions": transactions,
@@ -400,23 +559,13 @@ func (h *PortfolioHandler) UpdatePortfolioMetadata(c *gin.Context) {
 	// Get user ID from context
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
@@ -424,50 +573,38 @@ func (h *PortfolioHandler) UpdatePortfolioMetadata(c *gin.Context) {
 	portfolioIDStr := c.Param("id")
 	portfolioID, err := primitive.ObjectIDFromHex(portfolioIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid portfolio ID",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid portfolio ID"))
 		return
 	}
 
 	// Parse request body
 	var req models.UpdatePortfolioMetadataRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid portfolio metadata",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid portfolio metadata"))
 		return
 	}
 
 	// Convert asset style ID
 	assetStyleID, err := primitive.ObjectIDFromHex(req.AssetStyleID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid asset style ID",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid asset style ID"))
 		return
 	}
 
+	// Convert the optional asset subclass ID
+	var assetSubclassID primitive.ObjectID
+	if req.AssetSubclassID != "" {
+		assetSubclassID, err = primitive.ObjectIDFromHex(req.AssetSubclassID)
+		if err != nil {
+			c.Error(apierror.New(apierror.CodeValidation, "Invalid asset subclass ID"))
+			return
+		}
+	}
+
 	// Update portfolio metadata
-	err = h.portfolioService.UpdatePortfolioMetadata(userID, portfolioID, assetStyleID, req.AssetClass)
+	err = h.portfolioService.UpdatePortfolioClassification(userID, portfolioID, assetStyleID, req.AssetClass, assetSubclassID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to update portfolio metadata",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to update portfolio metadata"))
 		return
 	}
 
@@ -476,28 +613,48 @@ func (h *PortfolioHandler) UpdatePortfolioMetadata(c *gin.Context) {
 	})
 }
 
+// UpdatePortfolioTags replaces the tags of a portfolio
+func (h *PortfolioHandler) UpdatePortfolioTags(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	portfolioID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid portfolio ID"))
+		return
+	}
+
+	var req models.PortfolioTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid portfolio tags"))
+		return
+	}
+
+	tags, err := h.portfolioService.SetPortfolioTags(userID, portfolioID, req.Tags)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to update portfolio tags"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tags": tags,
+	})
+}
+
 // GetPortfolio returns a portfolio by ID
 func (h *PortfolioHandler) GetPortfolio(c *gin.Context) {
 	// Get user ID from context
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
@@ -505,24 +662,14 @@ func (h *PortfolioHandler) GetPortfolio(c *gin.Context) {
 	portfolioIDStr := c.Param("id")
 	portfolioID, err := primitive.ObjectIDFromHex(portfolioIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid portfolio ID",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid portfolio ID"))
 		return
 	}
 
 	// Get portfolio
 	portfolio, err := h.portfolioService.GetPortfolioWithMetadata(userID, portfolioID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": gin.H{
-				"code":    "NOT_FOUND",
-				"message": "Portfolio not found",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeNotFound, "Portfolio not found"))
 		return
 	}
 
@@ -536,48 +683,27 @@ func (h *PortfolioHandler) CheckPortfolio(c *gin.Context) {
 	// Get user ID from context
 	userIDInterface, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "User not authenticated",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
 		return
 	}
 
 	userID, ok := userIDInterface.(primitive.ObjectID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Invalid user ID format",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
 		return
 	}
 
 	// Get symbol from URL
 	symbol := c.Param("symbol")
 	if symbol == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Symbol is required",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Symbol is required"))
 		return
 	}
 
 	// Check portfolio
 	exists, portfolio, err := h.portfolioService.CheckPortfolioExists(userID, symbol)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to check portfolio",
-				"details": err.Error(),
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to check portfolio"))
 		return
 	}
 
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PriceOverrideHandler handles the manual prices users set for symbols
+type PriceOverrideHandler struct {
+	priceOverrideService *services.PriceOverrideService
+}
+
+// NewPriceOverrideHandler creates a new PriceOverrideHandler instance
+func NewPriceOverrideHandler(priceOverrideService *services.PriceOverrideService) *PriceOverrideHandler {
+	return &PriceOverrideHandler{
+		priceOverrideService: priceOverrideService,
+	}
+}
+
+// GetOverrides returns the manual prices of the authenticated user
+func (h *PriceOverrideHandler) GetOverrides(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	overrides, err := h.priceOverrideService.ListOverrides(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch price overrides"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"overrides": overrides,
+	})
+}
+
+// SetOverride sets the manual price of a symbol
+func (h *PriceOverrideHandler) SetOverride(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.PriceOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid price override data"))
+		return
+	}
+
+	override, err := h.priceOverrideService.SetOverride(userID, c.Param("symbol"), req)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to set price override"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"override": override,
+	})
+}
+
+// DeleteOverride removes the manual price of a symbol
+func (h *PriceOverrideHandler) DeleteOverride(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.priceOverrideService.DeleteOverride(userID, c.Param("symbol")); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to delete price override"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Price override deleted successfully",
+	})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProfileHandler handles user profile requests
+type ProfileHandler struct {
+	profileService *services.ProfileService
+}
+
+// NewProfileHandler creates a new ProfileHandler instance
+func NewProfileHandler(profileService *services.ProfileService) *ProfileHandler {
+	return &ProfileHandler{
+		profileService: profileService,
+	}
+}
+
+// GetProfile returns the authenticated user's profile
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.profileService.GetProfile(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch profile"))
+		return
+	}
+
+	c.JSON(http.StatusOK, withAvatarURL(c, profile))
+}
+
+// UpdateProfile replaces the authenticated user's profile fields
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid profile data"))
+		return
+	}
+
+	profile, err := h.profileService.UpdateProfile(userID, models.UserProfile{
+		DisplayName:  req.DisplayName,
+		BaseCurrency: req.BaseCurrency,
+		Country:      req.Country,
+	})
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to update profile"))
+		return
+	}
+
+	c.JSON(http.StatusOK, withAvatarURL(c, profile))
+}
+
+// UploadAvatar stores the multipart "avatar" image as the user's avatar
+func (h *ProfileHandler) UploadAvatar(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "An avatar image is required"))
+		return
+	}
+	if fileHeader.Size > services.MaxAvatarSize {
+		c.Error(apierror.New(apierror.CodePayloadTooLarge, "Avatars must be 512KB or smaller"))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Failed to read avatar image"))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, services.MaxAvatarSize+1))
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Failed to read avatar image"))
+		return
+	}
+
+	profile, err := h.profileService.SetAvatar(userID, data)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to upload avatar"))
+		return
+	}
+
+	c.JSON(http.StatusOK, withAvatarURL(c, profile))
+}
+
+// GetAvatar serves the authenticated user's avatar image. Avatar URLs carry
+// the avatar's version, so the image may be cached.
+func (h *ProfileHandler) GetAvatar(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	data, contentType, err := h.profileService.GetAvatar(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch avatar"))
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// DeleteAvatar removes the authenticated user's avatar
+func (h *ProfileHandler) DeleteAvatar(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.profileService.DeleteAvatar(userID); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to delete avatar"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// withAvatarURL points the profile at its avatar under the API version the
+// request came in on
+func withAvatarURL(c *gin.Context, profile *services.Profile) *services.Profile {
+	if profile.HasAvatar {
+		base := strings.TrimSuffix(c.FullPath(), "/avatar")
+		profile.AvatarURL = base + "/avatar?v=" + profile.AvatarVersion
+	}
+	return profile
+}
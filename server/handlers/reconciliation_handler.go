@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReconciliationHandler handles holdings reconciliation against broker positions
+type ReconciliationHandler struct {
+	reconciliationService *services.ReconciliationService
+}
+
+// NewReconciliationHandler creates a new ReconciliationHandler instance
+func NewReconciliationHandler(reconciliationService *services.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		reconciliationService: reconciliationService,
+	}
+}
+
+// Reconcile compares tracked holdings with a broker position snapshot and
+// returns the discrepancies with suggested adjustment transactions
+func (h *ReconciliationHandler) Reconcile(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReconcileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid position snapshot"))
+		return
+	}
+
+	var asOf time.Time
+	if req.AsOf != nil {
+		asOf = *req.AsOf
+	}
+
+	result, err := h.reconciliationService.Reconcile(c.Request.Context(), userID, req.Positions, asOf)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTransaction) {
+			c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid position snapshot"))
+			return
+		}
+
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to reconcile holdings"))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportHandler handles report-related requests
+type ReportHandler struct {
+	reportService *services.ReportService
+}
+
+// NewReportHandler creates a new ReportHandler instance
+func NewReportHandler(reportService *services.ReportService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+	}
+}
+
+// GetPortfolioReport renders a portfolio report as PDF or HTML
+func (h *ReportHandler) GetPortfolioReport(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	period := c.DefaultQuery("period", "1Y")
+	format := c.DefaultQuery("format", services.ReportFormatPDF)
+
+	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
+	if !validPeriods[period] {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid period parameter. Must be 1M, 3M, 6M, 1Y, or ALL"))
+		return
+	}
+
+	currency, ok := parseDisplayCurrency(c)
+	if !ok {
+		return
+	}
+
+	contentTypes := map[string]string{
+		services.ReportFormatPDF:  "application/pdf",
+		services.ReportFormatHTML: "text/html; charset=utf-8",
+	}
+	contentType, ok := contentTypes[format]
+	if !ok {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid format parameter. Must be pdf or html"))
+		return
+	}
+
+	report, err := h.reportService.BuildPortfolioReport(userID, period, currency)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeReportError, "Failed to build portfolio report"))
+		return
+	}
+
+	// Render into a buffer so a rendering failure can still produce a JSON error
+	var buf bytes.Buffer
+	if err := services.RenderPortfolioReport(report, format, &buf); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeReportError, "Failed to render portfolio report"))
+		return
+	}
+
+	filename := fmt.Sprintf("portfolio-report-%s-%s.%s", period, report.GeneratedAt.Format("2006-01-02"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
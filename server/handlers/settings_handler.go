@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SettingsHandler handles user settings requests
+type SettingsHandler struct {
+	settingsService     *services.SettingsService
+	summaryEmailService *services.SummaryEmailService
+	chatChannels        *services.ChatChannels
+}
+
+// NewSettingsHandler creates a new SettingsHandler instance
+func NewSettingsHandler(settingsService *services.SettingsService, summaryEmailService *services.SummaryEmailService, chatChannels *services.ChatChannels) *SettingsHandler {
+	return &SettingsHandler{
+		settingsService:     settingsService,
+		summaryEmailService: summaryEmailService,
+		chatChannels:        chatChannels,
+	}
+}
+
+// GetSettings returns the authenticated user's settings
+func (h *SettingsHandler) GetSettings(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	settings, err := h.settingsService.GetSettings(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch settings"))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSummaryEmail opts the user in or out of recurring summary emails
+func (h *SettingsHandler) UpdateSummaryEmail(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.SummaryEmailSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid summary email settings"))
+		return
+	}
+
+	settings, err := h.settingsService.UpdateSummaryEmail(userID, req.Frequency, req.Currency)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to update settings"))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateDriftAlerts sets the user's target weights and drift alert threshold
+func (h *SettingsHandler) UpdateDriftAlerts(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.DriftAlertSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid drift alert settings"))
+		return
+	}
+
+	settings, err := h.settingsService.UpdateDriftAlerts(userID, models.DriftAlertSettings{
+		Enabled:   req.Enabled,
+		GroupBy:   req.GroupBy,
+		Currency:  req.Currency,
+		Threshold: req.Threshold,
+		Targets:   req.Targets,
+	})
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to update settings"))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateCrossAlerts turns moving average cross alerts on or off
+func (h *SettingsHandler) UpdateCrossAlerts(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CrossAlertSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid cross alert settings"))
+		return
+	}
+
+	settings, err := h.settingsService.UpdateCrossAlerts(userID, req.Enabled)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to update settings"))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateBenchmarks sets the benchmark the user's dashboard and performance are
+// compared against by default
+func (h *SettingsHandler) UpdateBenchmarks(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.BenchmarkSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid benchmark settings"))
+		return
+	}
+
+	settings, err := h.settingsService.UpdateDefaultBenchmark(userID, req.Default)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to update settings"))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateChat sets the Telegram chat and Slack webhook the user's
+// notifications are sent to
+func (h *SettingsHandler) UpdateChat(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ChatSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid chat settings"))
+		return
+	}
+
+	settings, err := h.settingsService.UpdateChat(userID, models.ChatSettings{
+		TelegramChatID:  req.TelegramChatID,
+		SlackWebhookURL: req.SlackWebhookURL,
+	})
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to update settings"))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// SendTestChatMessage sends a test message to the user's linked chat on the
+// telegram or slack channel
+func (h *SettingsHandler) SendTestChatMessage(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.chatChannels.SendTest(userID, c.Param("channel")); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to send test message"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Test message sent",
+	})
+}
+
+// SendTestSummaryEmail sends the user's summary email immediately
+func (h *SettingsHandler) SendTestSummaryEmail(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.summaryEmailService.SendSummaryNow(userID); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeNotificationError, "Failed to send summary email"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Summary email sent",
+	})
+}
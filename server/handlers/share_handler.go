@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareHandler handles read-only portfolio share links
+type ShareHandler struct {
+	shareService *services.ShareService
+}
+
+// NewShareHandler creates a new ShareHandler instance
+func NewShareHandler(shareService *services.ShareService) *ShareHandler {
+	return &ShareHandler{
+		shareService: shareService,
+	}
+}
+
+// CreateLink creates a share link for the authenticated user's dashboard.
+// The token is only returned in this response.
+func (h *ShareHandler) CreateLink(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid share link data"))
+		return
+	}
+
+	link, token, err := h.shareService.CreateLink(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyShareLinks) {
+			c.Error(apierror.New(apierror.CodeTooManyShareLinks, "Revoke an existing share link before creating another"))
+			return
+		}
+
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to create share link"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"link":  link,
+		"token": token,
+		"path":  sharePath(c, token),
+	})
+}
+
+// sharePath is the public path of a share link under the API version that
+// served the request, so links created through the unversioned /api alias
+// don't depend on it
+func sharePath(c *gin.Context, token string) string {
+	if version := middleware.GetAPIVersion(c); version != "" {
+		return "/api/" + version + "/share/" + token
+	}
+	return "/api/share/" + token
+}
+
+// GetLinks returns the share links of the authenticated user
+func (h *ShareHandler) GetLinks(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.shareService.GetUserLinks(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch share links"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"links": links,
+	})
+}
+
+// RevokeLink revokes a share link so its token stops working
+func (h *ShareHandler) RevokeLink(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	linkID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid share link ID"))
+		return
+	}
+
+	if err := h.shareService.RevokeLink(userID, linkID); err != nil {
+		if errors.Is(err, services.ErrShareLinkNotFound) {
+			c.Error(apierror.New(apierror.CodeNotFound, "Share link not found"))
+			return
+		}
+
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to revoke share link"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Share link revoked successfully",
+	})
+}
+
+// GetSharedDashboard returns the redacted dashboard behind a share token.
+// This endpoint does not require authentication.
+func (h *ShareHandler) GetSharedDashboard(c *gin.Context) {
+	dashboard, err := h.shareService.GetSharedDashboard(c.Param("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrShareLinkNotFound) {
+			c.Error(apierror.New(apierror.CodeNotFound, "Share link not found or no longer active"))
+			return
+		}
+
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to load shared dashboard"))
+		return
+	}
+
+	// Shared dashboards must not be cached by intermediaries once revoked
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex")
+	c.JSON(http.StatusOK, dashboard)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SheetsHandler handles the Google Sheet users push their holdings and
+// daily performance to
+type SheetsHandler struct {
+	sheetsService *services.SheetsService
+}
+
+// NewSheetsHandler creates a new SheetsHandler instance
+func NewSheetsHandler(sheetsService *services.SheetsService) *SheetsHandler {
+	return &SheetsHandler{
+		sheetsService: sheetsService,
+	}
+}
+
+// GetLink returns the authenticated user's linked sheet, null if they have
+// none, and whether sheets can be linked on this server
+func (h *SheetsHandler) GetLink(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	link, err := h.sheetsService.GetLink(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch linked sheet"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"link":      link,
+		"available": h.sheetsService.Available(),
+	})
+}
+
+// StartLink begins linking a sheet. The response holds Google's consent
+// screen URL, which redirects back with the code to authorize with.
+func (h *SheetsHandler) StartLink(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.SheetsLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid sheet link data"))
+		return
+	}
+
+	start, err := h.sheetsService.StartLink(userID, req)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to start linking the sheet"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, start)
+}
+
+// Authorize finishes linking a sheet with the code from Google's consent
+// screen and syncs it
+func (h *SheetsHandler) Authorize(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.SheetsAuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid authorization data"))
+		return
+	}
+
+	link, err := h.sheetsService.CompleteLink(userID, req)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to authorize the sheet"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"link": link,
+	})
+}
+
+// Sync pushes the holdings and performance to the linked sheet now
+func (h *SheetsHandler) Sync(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	link, err := h.sheetsService.SyncNow(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to sync the sheet"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"link": link,
+	})
+}
+
+// Unlink stops syncing the linked sheet, leaving its contents in place
+func (h *SheetsHandler) Unlink(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.sheetsService.Unlink(userID); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to unlink the sheet"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sheet unlinked successfully",
+	})
+}
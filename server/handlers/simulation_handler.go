@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultInflationRate is the annual inflation percentage used when none is given
+const defaultInflationRate = 3.0
+
+// SimulationHandler handles portfolio simulation requests
+type SimulationHandler struct {
+	withdrawalService *services.WithdrawalService
+}
+
+// NewSimulationHandler creates a new SimulationHandler instance
+func NewSimulationHandler(withdrawalService *services.WithdrawalService) *SimulationHandler {
+	return &SimulationHandler{
+		withdrawalService: withdrawalService,
+	}
+}
+
+// SimulateWithdrawals models withdrawals from the authenticated user's portfolio
+func (h *SimulationHandler) SimulateWithdrawals(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.WithdrawalSimulationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid simulation data"))
+		return
+	}
+
+	inflationRate := defaultInflationRate
+	if req.InflationRate != nil {
+		inflationRate = *req.InflationRate
+	}
+
+	result, err := h.withdrawalService.SimulateWithdrawals(c.Request.Context(), userID, services.WithdrawalSimulationParams{
+		Currency:          req.Currency,
+		InitialValue:      req.InitialValue,
+		AnnualWithdrawal:  req.AnnualWithdrawal,
+		WithdrawalRate:    req.WithdrawalRate,
+		Years:             req.Years,
+		InflationAdjusted: req.InflationAdjusted,
+		InflationRate:     inflationRate,
+		Method:            req.Method,
+		Simulations:       req.Simulations,
+		Seed:              req.Seed,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidSimulation):
+			c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid simulation parameters"))
+		case errors.Is(err, services.ErrInsufficientHistory):
+			c.Error(apierror.New(apierror.CodeInsufficientHistory, "At least 12 months of price history are needed to simulate withdrawals"))
+		default:
+			c.Error(apierror.Wrap(err, apierror.CodeSimulationError, "Failed to run withdrawal simulation"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
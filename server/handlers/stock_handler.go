@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"net/http"
+	"stock-portfolio-tracker/apierror"
 	"stock-portfolio-tracker/services"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,54 +28,16 @@ func (h *StockHandler) SearchStock(c *gin.Context) {
 	symbol = strings.ToUpper(strings.TrimSpace(symbol))
 	
 	if symbol == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Stock symbol is required",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Stock symbol is required"))
 		return
 	}
 	
 	// Get stock info (which includes search functionality)
-	info, err := h.stockService.GetStockInfo(symbol)
+	info, err := h.stockService.GetStockInfoContext(c.Request.Context(), symbol)
 	if err != nil {
-		if err == services.ErrStockNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": gin.H{
-					"code":    "NOT_FOUND",
-					"message": "Stock not found",
-				},
-			})
-			return
-		}
-		
-		if err == services.ErrInvalidSymbol {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "VALIDATION_ERROR",
-					"message": "Invalid stock symbol format",
-				},
-			})
-			return
-		}
-		
-		if err == services.ErrExternalAPI {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"error": gin.H{
-					"code":    "EXTERNAL_API_ERROR",
-					"message": "Failed to fetch stock data from external API",
-				},
-			})
-			return
-		}
-		
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to search stock",
-			},
-		})
+		// Unknown symbols and provider failures map to their codes in the
+		// error middleware
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to search stock"))
 		return
 	}
 	
@@ -86,53 +50,13 @@ func (h *StockHandler) GetStockInfo(c *gin.Context) {
 	symbol = strings.ToUpper(strings.TrimSpace(symbol))
 	
 	if symbol == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Stock symbol is required",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Stock symbol is required"))
 		return
 	}
 	
-	info, err := h.stockService.GetStockInfo(symbol)
+	info, err := h.stockService.GetStockInfoContext(c.Request.Context(), symbol)
 	if err != nil {
-		if err == services.ErrStockNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": gin.H{
-					"code":    "NOT_FOUND",
-					"message": "Stock not found",
-				},
-			})
-			return
-		}
-		
-		if err == services.ErrInvalidSymbol {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "VALIDATION_ERROR",
-					"message": "Invalid stock symbol format",
-				},
-			})
-			return
-		}
-		
-		if err == services.ErrExternalAPI {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"error": gin.H{
-					"code":    "EXTERNAL_API_ERROR",
-					"message": "Failed to fetch stock data from external API",
-				},
-			})
-			return
-		}
-		
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to get stock information",
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to get stock information"))
 		return
 	}
 	
@@ -145,12 +69,7 @@ func (h *StockHandler) GetStockHistory(c *gin.Context) {
 	symbol = strings.ToUpper(strings.TrimSpace(symbol))
 	
 	if symbol == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Stock symbol is required",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Stock symbol is required"))
 		return
 	}
 	
@@ -161,69 +80,113 @@ func (h *StockHandler) GetStockHistory(c *gin.Context) {
 	// Validate period
 	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true}
 	if !validPeriods[period] {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Invalid period. Valid values are: 1M, 3M, 6M, 1Y",
-			},
-		})
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid period. Valid values are: 1M, 3M, 6M, 1Y"))
 		return
 	}
 	
-	data, err := h.stockService.GetHistoricalData(symbol, period)
+	// adjusted=true returns dividend-adjusted closes for total return charts
+	adjusted := c.Query("adjusted") == "true"
+	
+	data, err := h.stockService.GetHistoricalDataContext(c.Request.Context(), symbol, period)
 	if err != nil {
-		if err == services.ErrStockNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": gin.H{
-					"code":    "NOT_FOUND",
-					"message": "Stock not found",
-				},
-			})
-			return
-		}
-		
-		if err == services.ErrInvalidSymbol {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "VALIDATION_ERROR",
-					"message": "Invalid stock symbol format",
-				},
-			})
-			return
-		}
-		
-		if err == services.ErrInvalidPeriod {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": gin.H{
-					"code":    "VALIDATION_ERROR",
-					"message": "Invalid period parameter",
-				},
-			})
-			return
-		}
-		
-		if err == services.ErrExternalAPI {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"error": gin.H{
-					"code":    "EXTERNAL_API_ERROR",
-					"message": "Failed to fetch historical data from external API",
-				},
-			})
-			return
-		}
-		
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "Failed to get historical data",
-			},
-		})
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to get historical data"))
+		return
+	}
+	if adjusted {
+		data = services.AdjustedHistory(data)
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":   symbol,
+		"period":   period,
+		"adjusted": adjusted,
+		"data":     data,
+	})
+}
+
+// GetIndicators charts a stock's closes with technical indicators, such as
+// ?set=rsi,macd,sma50
+func (h *StockHandler) GetIndicators(c *gin.Context) {
+	symbol := c.Param("symbol")
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	
+	if symbol == "" {
+		c.Error(apierror.New(apierror.CodeValidation, "Stock symbol is required"))
+		return
+	}
+	
+	// Get period from query parameter, default to 1Y
+	period := strings.ToUpper(c.DefaultQuery("period", "1Y"))
+	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
+	if !validPeriods[period] {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid period. Valid values are: 1M, 3M, 6M, 1Y, ALL"))
+		return
+	}
+	
+	var names []string
+	if set := c.Query("set"); set != "" {
+		names = strings.Split(set, ",")
+	}
+	
+	chart, err := h.stockService.GetIndicatorsContext(c.Request.Context(), symbol, period, names)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to compute indicators"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, chart)
+}
+
+// GetFundamentals handles fetching a stock's key statistics
+func (h *StockHandler) GetFundamentals(c *gin.Context) {
+	symbol := c.Param("symbol")
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	
+	if symbol == "" {
+		c.Error(apierror.New(apierror.CodeValidation, "Stock symbol is required"))
+		return
+	}
+	
+	fundamentals, err := h.stockService.GetFundamentalsContext(c.Request.Context(), symbol)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to get fundamentals"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, fundamentals)
+}
+
+// GetMarkets lists the supported exchanges with their current trading status
+func (h *StockHandler) GetMarkets(c *gin.Context) {
+	now := time.Now()
+	markets := services.Markets()
+	statuses := make([]services.MarketStatus, 0, len(markets))
+	for i := range markets {
+		statuses = append(statuses, markets[i].Status(now))
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"markets": statuses})
+}
+
+// GetSymbolMarket returns the exchange a symbol trades on, its currency,
+// trading status and suggested benchmarks
+func (h *StockHandler) GetSymbolMarket(c *gin.Context) {
+	symbol := c.Param("symbol")
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	
+	if symbol == "" {
+		c.Error(apierror.New(apierror.CodeValidation, "Stock symbol is required"))
+		return
+	}
+	
+	market := services.MarketForSymbol(symbol)
+	if market == nil {
+		c.Error(apierror.New(apierror.CodeNotFound, "Exchange not supported for this symbol"))
 		return
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"symbol": symbol,
-		"period": period,
-		"data":   data,
+		"market": market.Status(time.Now()),
 	})
 }
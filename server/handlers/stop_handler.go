@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StopHandler handles the stop-loss and trailing stop levels watched on positions
+type StopHandler struct {
+	stopService *services.StopService
+}
+
+// NewStopHandler creates a new StopHandler instance
+func NewStopHandler(stopService *services.StopService) *StopHandler {
+	return &StopHandler{
+		stopService: stopService,
+	}
+}
+
+// GetStops returns the authenticated user's positions with a stop
+func (h *StopHandler) GetStops(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	portfolios, err := h.stopService.ListStops(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch stops"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"portfolios": portfolios,
+	})
+}
+
+// SetStop sets the stop levels watched on a position
+func (h *StopHandler) SetStop(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.PositionStopRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid stop data"))
+		return
+	}
+
+	stop, err := h.stopService.SetStop(userID, c.Param("symbol"), req)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to set stop"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stop": stop,
+	})
+}
+
+// DeleteStop stops watching a position
+func (h *StopHandler) DeleteStop(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.stopService.RemoveStop(userID, c.Param("symbol")); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to remove stop"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Stop removed successfully",
+	})
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"stock-portfolio-tracker/apierror"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// requireUserID extracts the authenticated user ID, reporting an error if missing
+func requireUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userIDInterface, exists := c.Get("userID")
+	if !exists {
+		c.Error(apierror.New(apierror.CodeUnauthorized, "User not authenticated"))
+		return primitive.NilObjectID, false
+	}
+
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		c.Error(apierror.New(apierror.CodeInternal, "Invalid user ID format"))
+		return primitive.NilObjectID, false
+	}
+
+	return userID, true
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookHandler handles the webhooks that receive daily summaries and alerts
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookHandler creates a new WebhookHandler instance
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook registers a webhook for the authenticated user. The signing
+// secret is only returned here.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid webhook data"))
+		return
+	}
+
+	webhook, secret, err := h.webhookService.CreateWebhook(userID, req)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to create webhook"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": webhook,
+		"secret":  secret,
+	})
+}
+
+// GetWebhooks returns the webhooks of the authenticated user
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(userID)
+	if err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to fetch webhooks"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": webhooks,
+	})
+}
+
+// DeleteWebhook removes a webhook
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid webhook ID"))
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(userID, webhookID); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to delete webhook"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook deleted successfully",
+	})
+}
+
+// TestWebhook posts a signed test event to a webhook
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.New(apierror.CodeValidation, "Invalid webhook ID"))
+		return
+	}
+
+	if err := h.webhookService.SendTest(userID, webhookID); err != nil {
+		c.Error(apierror.Wrap(err, apierror.CodeInternal, "Failed to send test event"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Test event delivered",
+	})
+}
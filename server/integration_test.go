@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/middleware"
 	"stock-portfolio-tracker/models"
 	"stock-portfolio-tracker/routes"
 	"stock-portfolio-tracker/services"
@@ -23,9 +24,6 @@ func setupIntegrationTest(t *testing.T) (*gin.Engine, primitive.ObjectID, string
 	// Set Gin to test mode
 	gin.SetMode(gin.TestMode)
 
-	// Set required environment variables for testing
-	t.Setenv("JWT_SECRET", "test-secret-key-for-integration-tests")
-
 	// Connect to test database
 	mongoURI := "mongodb://localhost:27017/stock_portfolio_integration_test"
 	if err := database.Connect(mongoURI); err != nil {
@@ -41,7 +39,7 @@ func setupIntegrationTest(t *testing.T) (*gin.Engine, primitive.ObjectID, string
 	defer cancel()
 
 	// Hash password
-	authService := services.NewAuthService()
+	authService := services.NewAuthService("test-secret-key-for-integration-tests")
 	hashedPassword, err := authService.HashPassword(testPassword)
 	if err != nil {
 		t.Fatalf("Failed to hash password: %v", err)
@@ -67,20 +65,27 @@ func setupIntegrationTest(t *testing.T) (*gin.Engine, primitive.ObjectID, string
 	}
 
 	// Initialize services
-	stockService := services.NewStockAPIService()
-	currencyService := services.NewCurrencyService()
+	stockService := services.NewStockAPIService(services.StockAPIConfig{})
+	currencyService := services.NewCurrencyService(services.CurrencyConfig{})
 	portfolioService := services.NewPortfolioService(stockService, currencyService)
 	analyticsService := services.NewAnalyticsService(portfolioService, currencyService, stockService)
+	reconciliationService := services.NewReconciliationService(portfolioService, stockService)
+	newsService := services.NewNewsService(portfolioService, services.NewsConfig{})
+	backtestService := services.NewBacktestService(portfolioService, analyticsService, currencyService, stockService)
+	benchmarkService := services.NewBenchmarkComparisonService(backtestService, services.NewSettingsService())
 
 	// Initialize Gin router
 	router := gin.New()
 	router.Use(gin.Recovery())
+	router.Use(middleware.ErrorHandler(false))
 
 	// Setup routes
-	routes.SetupAuthRoutes(router, authService)
-	routes.SetupPortfolioRoutes(router, portfolioService, authService)
-	routes.SetupAnalyticsRoutes(router, analyticsService, authService)
-	routes.SetupAssetStyleRoutes(router, authService)
+	routes.SetupVersionedRoutes(router, func(api gin.IRouter) {
+		routes.SetupAuthRoutes(api, authService, middleware.AuthRateLimiter(30))
+		routes.SetupPortfolioRoutes(api, portfolioService, reconciliationService, newsService, authService)
+		routes.SetupAnalyticsRoutes(api, analyticsService, benchmarkService, authService, 15*time.Second, 5*time.Minute)
+		routes.SetupAssetStyleRoutes(api, authService)
+	})
 
 	// Cleanup function
 	cleanup := func() {
@@ -139,7 +144,7 @@ func TestCompleteTransactionFlow(t *testing.T) {
 	}
 
 	// Step 3: Create portfolio with metadata (simulating AssetClassDialog)
-	portfolioService := services.NewPortfolioService(services.NewStockAPIService(), services.NewCurrencyService())
+	portfolioService := services.NewPortfolioService(services.NewStockAPIService(services.StockAPIConfig{}), services.NewCurrencyService(services.CurrencyConfig{}))
 	portfolioID, err := portfolioService.CreatePortfolioWithMetadata(userID, "AAPL", defaultStyle.ID, "Stock")
 	if err != nil {
 		t.Fatalf("Failed to create portfolio with metadata: %v", err)
@@ -213,11 +218,11 @@ func TestGroupedQueryEndToEnd(t *testing.T) {
 
 	// Setup: Create asset styles
 	assetStyleService := services.NewAssetStyleService()
-	growthStyle, _ := assetStyleService.CreateAssetStyle(userID, "Growth")
-	valueStyle, _ := assetStyleService.CreateAssetStyle(userID, "Value")
+	growthStyle, _ := assetStyleService.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Growth"})
+	valueStyle, _ := assetStyleService.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Value"})
 
 	// Setup: Create portfolios with different classifications
-	portfolioService := services.NewPortfolioService(services.NewStockAPIService(), services.NewCurrencyService())
+	portfolioService := services.NewPortfolioService(services.NewStockAPIService(services.StockAPIConfig{}), services.NewCurrencyService(services.CurrencyConfig{}))
 	
 	// AAPL - Growth, Stock
 	aaplID, _ := portfolioService.CreatePortfolioWithMetadata(userID, "AAPL", growthStyle.ID, "Stock")
@@ -396,7 +401,7 @@ func TestAssetStyleManagementFlow(t *testing.T) {
 	}
 
 	// Step 3: Create portfolio using Tech Stocks style
-	portfolioService := services.NewPortfolioService(services.NewStockAPIService(), services.NewCurrencyService())
+	portfolioService := services.NewPortfolioService(services.NewStockAPIService(services.StockAPIConfig{}), services.NewCurrencyService(services.CurrencyConfig{}))
 	techStyleObjID, _ := primitive.ObjectIDFromHex(techStyleID)
 	portfolioID, err := portfolioService.CreatePortfolioWithMetadata(userID, "AAPL", techStyleObjID, "Stock")
 	if err != nil {
@@ -499,11 +504,11 @@ func TestEditAssetClassification(t *testing.T) {
 
 	// Setup: Create two asset styles
 	assetStyleService := services.NewAssetStyleService()
-	style1, _ := assetStyleService.CreateAssetStyle(userID, "Style 1")
-	style2, _ := assetStyleService.CreateAssetStyle(userID, "Style 2")
+	style1, _ := assetStyleService.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Style 1"})
+	style2, _ := assetStyleService.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Style 2"})
 
 	// Setup: Create portfolio with Style 1 and Stock
-	portfolioService := services.NewPortfolioService(services.NewStockAPIService(), services.NewCurrencyService())
+	portfolioService := services.NewPortfolioService(services.NewStockAPIService(services.StockAPIConfig{}), services.NewCurrencyService(services.CurrencyConfig{}))
 	portfolioID, _ := portfolioService.CreatePortfolioWithMetadata(userID, "AAPL", style1.ID, "Stock")
 
 	// Step 1: Verify initial classification
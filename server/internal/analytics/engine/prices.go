@@ -0,0 +1,141 @@
+// Package engine holds the price series arithmetic shared by the analytics and
+// backtest services: aligning histories on trading dates, looking up the price
+// in effect on a date, and the return, drawdown and volatility statistics
+// computed from value series. It works on plain values and knows nothing of
+// users, currencies or data providers.
+package engine
+
+import (
+	"sort"
+	"time"
+)
+
+// dateLayout keys prices by calendar day
+const dateLayout = "2006-01-02"
+
+// Price is a daily close
+type Price struct {
+	Date  time.Time `json:"date"`
+	Price float64   `json:"price"`
+	// AdjustedPrice is the close adjusted for later dividends and splits,
+	// zero when the provider does not report one
+	AdjustedPrice float64 `json:"-"`
+}
+
+// Sorted returns the prices in ascending date order without modifying the
+// input, which may be shared with a cache
+func Sorted(prices []Price) []Price {
+	if sort.SliceIsSorted(prices, func(i, j int) bool { return prices[i].Date.Before(prices[j].Date) }) {
+		return prices
+	}
+
+	sorted := make([]Price, len(prices))
+	copy(sorted, prices)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+	return sorted
+}
+
+// TradingDates returns every calendar day with a price in any of the
+// histories, on or after from, in ascending order. Each day is represented by
+// the timestamp of one of its prices.
+func TradingDates(histories map[string][]Price, from time.Time) []time.Time {
+	days := make(map[string]time.Time)
+	for _, prices := range histories {
+		for _, price := range prices {
+			key := price.Date.Format(dateLayout)
+			if _, exists := days[key]; !exists {
+				days[key] = price.Date
+			}
+		}
+	}
+
+	dates := make([]time.Time, 0, len(days))
+	for _, date := range days {
+		if !date.Before(from) {
+			dates = append(dates, date)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+	return dates
+}
+
+// PriceOn returns the price on date's calendar day or the closest earlier one.
+// Without an earlier price it falls back to the first price within 30 days
+// after date, and returns 0 when there is none.
+func PriceOn(prices []Price, date time.Time) float64 {
+	var closestPrice float64
+	var closestDate time.Time
+	var closestFuturePrice float64
+	var closestFutureDate time.Time
+
+	day := date.Format(dateLayout)
+	for _, price := range prices {
+		if price.Date.Format(dateLayout) == day {
+			return price.Price
+		}
+
+		if !price.Date.After(date) {
+			if closestDate.IsZero() || price.Date.After(closestDate) {
+				closestDate = price.Date
+				closestPrice = price.Price
+			}
+		} else if price.Date.Sub(date) <= 30*24*time.Hour {
+			if closestFutureDate.IsZero() || price.Date.Before(closestFutureDate) {
+				closestFutureDate = price.Date
+				closestFuturePrice = price.Price
+			}
+		}
+	}
+
+	if closestPrice > 0 {
+		return closestPrice
+	}
+	return closestFuturePrice
+}
+
+// CloseBefore returns the last positive close dated before end from a
+// date-sorted price history
+func CloseBefore(prices []Price, end time.Time) (float64, bool) {
+	price := 0.0
+	for _, p := range prices {
+		if !p.Date.Before(end) {
+			break
+		}
+		if p.Price > 0 {
+			price = p.Price
+		}
+	}
+	return price, price > 0
+}
+
+// Cursor walks a date-sorted price history forward in time, so pricing a
+// series of ascending dates costs one pass over the history
+type Cursor struct {
+	prices []Price
+	index  int
+	price  float64
+}
+
+// NewCursor creates a Cursor over date-sorted prices
+func NewCursor(prices []Price) *Cursor {
+	return &Cursor{prices: prices}
+}
+
+// At returns the price on date's calendar day or the closest earlier one, 0
+// before the first price. Calls must be made with non-decreasing dates.
+func (c *Cursor) At(date time.Time) float64 {
+	day := date.Format(dateLayout)
+	for c.index < len(c.prices) {
+		next := c.prices[c.index]
+		if next.Date.After(date) && next.Date.Format(dateLayout) != day {
+			break
+		}
+		c.price = next.Price
+		c.index++
+	}
+	return c.price
+}
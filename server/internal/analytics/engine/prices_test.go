@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, 1, d, 16, 0, 0, 0, time.UTC)
+}
+
+func TestSortedLeavesInputUntouched(t *testing.T) {
+	prices := []Price{{Date: day(3), Price: 3}, {Date: day(1), Price: 1}, {Date: day(2), Price: 2}}
+	sorted := Sorted(prices)
+
+	for i, want := range []float64{1, 2, 3} {
+		if sorted[i].Price != want {
+			t.Errorf("Expected price %.0f at %d, got %.0f", want, i, sorted[i].Price)
+		}
+	}
+	if prices[0].Price != 3 {
+		t.Errorf("Expected input to keep its order")
+	}
+}
+
+func TestTradingDates(t *testing.T) {
+	histories := map[string][]Price{
+		"AAPL": {{Date: day(1)}, {Date: day(2)}, {Date: day(4)}},
+		// Same days stamped at a different time, plus a day only this market traded
+		"0700.HK": {{Date: day(2).Add(-8 * time.Hour)}, {Date: day(3).Add(-8 * time.Hour)}},
+	}
+
+	dates := TradingDates(histories, day(2).Add(-12*time.Hour))
+	days := make([]int, len(dates))
+	for i, date := range dates {
+		days[i] = date.Day()
+	}
+	if want := []int{2, 3, 4}; !reflect.DeepEqual(days, want) {
+		t.Errorf("Expected trading days %v, got %v", want, days)
+	}
+}
+
+func TestPriceOn(t *testing.T) {
+	prices := []Price{{Date: day(5), Price: 5}, {Date: day(8), Price: 8}}
+
+	tests := []struct {
+		name string
+		date time.Time
+		want float64
+	}{
+		{"same calendar day", day(8).Add(-12 * time.Hour), 8},
+		{"closest earlier day", day(7), 5},
+		{"next day within 30 days", day(1), 5},
+		{"nothing within 30 days", day(5).AddDate(0, 0, -31), 0},
+	}
+	for _, test := range tests {
+		if got := PriceOn(prices, test.date); got != test.want {
+			t.Errorf("%s: expected %.0f, got %.0f", test.name, test.want, got)
+		}
+	}
+	if got := PriceOn(nil, day(1)); got != 0 {
+		t.Errorf("Expected 0 without prices, got %.0f", got)
+	}
+}
+
+func TestCloseBefore(t *testing.T) {
+	prices := []Price{{Date: day(1), Price: 10}, {Date: day(2), Price: 0}, {Date: day(3), Price: 12}}
+
+	if price, ok := CloseBefore(prices, day(3)); !ok || price != 10 {
+		t.Errorf("Expected the last positive close 10, got %.0f, %v", price, ok)
+	}
+	if _, ok := CloseBefore(prices, day(1)); ok {
+		t.Errorf("Expected no close before the first price")
+	}
+}
+
+func TestCursor(t *testing.T) {
+	cursor := NewCursor([]Price{{Date: day(1), Price: 10}, {Date: day(2), Price: 11}, {Date: day(4), Price: 13}})
+
+	tests := []struct {
+		date time.Time
+		want float64
+	}{
+		{day(1).Add(-time.Hour), 10}, // Same calendar day, earlier in it
+		{day(2), 11},
+		{day(3), 11}, // No price on day 3, carry forward day 2
+		{day(5), 13},
+	}
+	for _, test := range tests {
+		if got := cursor.At(test.date); got != test.want {
+			t.Errorf("At(%s) = %.0f, want %.0f", test.date.Format(dateLayout), got, test.want)
+		}
+	}
+
+	if got := NewCursor([]Price{{Date: day(2), Price: 11}}).At(day(1)); got != 0 {
+		t.Errorf("Expected 0 before the first price, got %.0f", got)
+	}
+}
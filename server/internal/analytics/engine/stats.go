@@ -0,0 +1,119 @@
+package engine
+
+import "math"
+
+// TradingDaysPerYear annualizes daily statistics
+const TradingDaysPerYear = 252
+
+// Returns returns the return of each value over the one before it, skipping
+// steps from a non-positive value
+func Returns(values []float64) []float64 {
+	if len(values) < 2 {
+		return nil
+	}
+	returns := make([]float64, 0, len(values)-1)
+	for i := 1; i < len(values); i++ {
+		if values[i-1] > 0 {
+			returns = append(returns, values[i]/values[i-1]-1)
+		}
+	}
+	return returns
+}
+
+// DailyReturns maps each trading day after the first to the return since the
+// previous trading day, keyed by calendar day. Non-positive prices are
+// skipped. Prices must be sorted by date.
+func DailyReturns(prices []Price) map[string]float64 {
+	returns := make(map[string]float64, len(prices))
+	previous := 0.0
+	for _, price := range prices {
+		if previous > 0 && price.Price > 0 {
+			returns[price.Date.Format(dateLayout)] = price.Price/previous - 1
+		}
+		if price.Price > 0 {
+			previous = price.Price
+		}
+	}
+	return returns
+}
+
+// Drawdown is the largest peak-to-trough decline of a value series
+type Drawdown struct {
+	Percent  float64 // Decline as a positive percentage of the peak
+	Absolute float64 // Decline in the series' units
+	Peak     int     // Index of the peak; 0 without a decline
+	Trough   int     // Index of the trough; 0 without a decline
+}
+
+// MaxDrawdown returns the largest decline from a running peak in values
+func MaxDrawdown(values []float64) Drawdown {
+	var result Drawdown
+	peak, peakIndex := 0.0, 0
+	for i, value := range values {
+		if value > peak {
+			peak, peakIndex = value, i
+		}
+		if peak <= 0 {
+			continue
+		}
+		if drawdown := (peak - value) / peak * 100; drawdown > result.Percent {
+			result = Drawdown{Percent: drawdown, Absolute: peak - value, Peak: peakIndex, Trough: i}
+		}
+	}
+	return result
+}
+
+// DrawdownSeries returns the percentage decline from the running peak at each value
+func DrawdownSeries(values []float64) []float64 {
+	drawdowns := make([]float64, len(values))
+	peak := 0.0
+	for i, value := range values {
+		if value > peak {
+			peak = value
+		}
+		if peak > 0 {
+			drawdowns[i] = (peak - value) / peak * 100
+		}
+	}
+	return drawdowns
+}
+
+// Mean returns the arithmetic mean of values
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// StandardDeviation returns the sample standard deviation of values
+func StandardDeviation(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := Mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// AnnualizedVolatility returns the annualized standard deviation of daily
+// returns as a percentage
+func AnnualizedVolatility(dailyReturns []float64) float64 {
+	return StandardDeviation(dailyReturns) * math.Sqrt(TradingDaysPerYear) * 100
+}
+
+// AnnualizedReturn returns the compound annual growth from start to end over
+// the given number of days as a percentage, 0 when it is undefined
+func AnnualizedReturn(start, end, days float64) float64 {
+	if start <= 0 || days <= 0 {
+		return 0
+	}
+	return (math.Pow(end/start, 365/days) - 1) * 100
+}
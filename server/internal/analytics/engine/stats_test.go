@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestReturns(t *testing.T) {
+	returns := Returns([]float64{100, 110, 0, 50, 55})
+	// The step from 0 is skipped
+	want := []float64{0.1, -1, 0.1}
+	if len(returns) != len(want) {
+		t.Fatalf("Expected %d returns, got %v", len(want), returns)
+	}
+	for i := range want {
+		if math.Abs(returns[i]-want[i]) > 1e-9 {
+			t.Errorf("Return %d: expected %.4f, got %.4f", i, want[i], returns[i])
+		}
+	}
+	if Returns([]float64{100}) != nil {
+		t.Errorf("Expected no returns from a single value")
+	}
+}
+
+func TestDailyReturns(t *testing.T) {
+	returns := DailyReturns([]Price{{Date: day(1), Price: 100}, {Date: day(2), Price: 0}, {Date: day(3), Price: 110}})
+	if len(returns) != 1 || math.Abs(returns["2024-01-03"]-0.1) > 1e-9 {
+		t.Errorf("Expected a 10%% return on 2024-01-03 across the missing price, got %v", returns)
+	}
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	drawdown := MaxDrawdown([]float64{100, 120, 90, 110, 60, 130})
+	if drawdown.Percent != 50 || drawdown.Absolute != 60 || drawdown.Peak != 1 || drawdown.Trough != 4 {
+		t.Errorf("Unexpected drawdown %+v", drawdown)
+	}
+
+	if drawdown := MaxDrawdown([]float64{100, 110, 120}); drawdown != (Drawdown{}) {
+		t.Errorf("Expected no drawdown for a rising series, got %+v", drawdown)
+	}
+}
+
+func TestDrawdownSeries(t *testing.T) {
+	got := DrawdownSeries([]float64{0, 100, 80, 120, 90})
+	if want := []float64{0, 0, 20, 0, 25}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected drawdowns %v, got %v", want, got)
+	}
+}
+
+func TestVolatilityAndAnnualizedReturn(t *testing.T) {
+	if got := StandardDeviation([]float64{2, 4, 4, 4, 5, 5, 7, 9}); math.Abs(got-math.Sqrt(32.0/7)) > 1e-9 {
+		t.Errorf("Expected sample standard deviation %.4f, got %.4f", math.Sqrt(32.0/7), got)
+	}
+	if got := AnnualizedVolatility([]float64{0.01, -0.01}); math.Abs(got-math.Sqrt(0.0002)*math.Sqrt(252)*100) > 1e-9 {
+		t.Errorf("Unexpected annualized volatility %.4f", got)
+	}
+	if got := AnnualizedVolatility([]float64{0.01}); got != 0 {
+		t.Errorf("Expected no volatility from a single return, got %.4f", got)
+	}
+
+	if got := AnnualizedReturn(100, 121, 730); math.Abs(got-10) > 1e-9 {
+		t.Errorf("Expected 10%% a year, got %.4f", got)
+	}
+	if got := AnnualizedReturn(0, 121, 730); got != 0 {
+		t.Errorf("Expected 0 without a start value, got %.4f", got)
+	}
+}
@@ -0,0 +1,245 @@
+// Package loadtest drives a running server with a steady request rate and
+// checks the observed latencies against performance budgets. It talks to the
+// server over HTTP only, so it measures what a client sees: routing,
+// authentication, database and market data caches included.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Target is an endpoint exercised by the load test
+type Target struct {
+	Name string // Budget key, e.g. "dashboard"
+	Path string // Path and query relative to the base URL
+}
+
+// DefaultTargets are the endpoints users hit on every page load
+var DefaultTargets = []Target{
+	{Name: "dashboard", Path: "/api/analytics/dashboard"},
+	{Name: "holdings", Path: "/api/portfolio/holdings"},
+	{Name: "performance", Path: "/api/analytics/performance?period=1Y"},
+}
+
+// Options controls the shape of a run
+type Options struct {
+	Duration    time.Duration // How long requests are issued for
+	Rate        int           // Requests per second across all targets
+	Concurrency int           // Most requests in flight at once
+	Timeout     time.Duration // Per request timeout
+}
+
+// ErrRateLimited is returned for a request the server's rate limit rejected
+var ErrRateLimited = errors.New("rate limited")
+
+// Stats summarizes the requests made to one target
+type Stats struct {
+	Name        string
+	Requests    int
+	Errors      int // Transport errors and non-2xx responses
+	RateLimited int // Errors that were 429 responses from the server's rate limit
+	P50         time.Duration
+	P95         time.Duration
+	P99         time.Duration
+	Max         time.Duration
+}
+
+// ErrorRate returns the fraction of requests that failed
+func (s Stats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// Violation is a target whose p95 latency exceeded its budget
+type Violation struct {
+	Name   string
+	P95    time.Duration
+	Budget time.Duration
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s p95 %s exceeds budget %s", v.Name, v.P95.Round(time.Millisecond), v.Budget)
+}
+
+// Run issues requests to the targets in round-robin order at opts.Rate for
+// opts.Duration, authenticated with token, and returns per-target stats in
+// target order. Requests still in flight when the duration ends are awaited.
+func Run(ctx context.Context, client *http.Client, baseURL, token string, targets []Target, opts Options) ([]Stats, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets")
+	}
+	if opts.Rate <= 0 || opts.Concurrency <= 0 || opts.Duration <= 0 {
+		return nil, fmt.Errorf("duration, rate and concurrency must be positive")
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	var mu sync.Mutex
+	latencies := make([][]time.Duration, len(targets))
+	failures := make([]int, len(targets))
+	rateLimited := make([]int, len(targets))
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+	ticker := time.NewTicker(time.Second / time.Duration(opts.Rate))
+	defer ticker.Stop()
+
+	slots := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return summarize(targets, latencies, failures, rateLimited), nil
+		case <-ticker.C:
+		}
+
+		// Skip the tick when every slot is busy rather than queueing, so a
+		// slow server is measured at the configured concurrency
+		select {
+		case slots <- struct{}{}:
+		default:
+			continue
+		}
+
+		index := i % len(targets)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			latency, err := request(client, baseURL+targets[index].Path, token, opts.Timeout)
+			mu.Lock()
+			defer mu.Unlock()
+			latencies[index] = append(latencies[index], latency)
+			if err != nil {
+				failures[index]++
+			}
+			if errors.Is(err, ErrRateLimited) {
+				rateLimited[index]++
+			}
+		}()
+	}
+}
+
+// request makes one authenticated GET and returns its latency, reading the
+// whole body so the time includes the response transfer
+func request(client *http.Client, url, token string, timeout time.Duration) (time.Duration, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return time.Since(start), err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	latency := time.Since(start)
+	if err != nil {
+		return latency, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return latency, fmt.Errorf("%s: %w", url, ErrRateLimited)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return latency, fmt.Errorf("%s: status %d", url, resp.StatusCode)
+	}
+	return latency, nil
+}
+
+func summarize(targets []Target, latencies [][]time.Duration, failures, rateLimited []int) []Stats {
+	stats := make([]Stats, len(targets))
+	for i, target := range targets {
+		sorted := append([]time.Duration(nil), latencies[i]...)
+		sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+		stats[i] = Stats{
+			Name:        target.Name,
+			Requests:    len(sorted),
+			Errors:      failures[i],
+			RateLimited: rateLimited[i],
+			P50:         Percentile(sorted, 50),
+			P95:         Percentile(sorted, 95),
+			P99:         Percentile(sorted, 99),
+		}
+		if len(sorted) > 0 {
+			stats[i].Max = sorted[len(sorted)-1]
+		}
+	}
+	return stats
+}
+
+// Percentile returns the nearest-rank percentile of sorted latencies, 0 when
+// there are none
+func Percentile(sorted []time.Duration, percentile float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(float64(len(sorted))*percentile/100)) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// ParseBudgets parses p95 budgets written as name=duration pairs separated by
+// commas, e.g. "dashboard=300ms,performance=1s"
+func ParseBudgets(spec string) (map[string]time.Duration, error) {
+	budgets := make(map[string]time.Duration)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("budget %q: expected name=duration", pair)
+		}
+		budget, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("budget %q: %w", pair, err)
+		}
+		if budget <= 0 {
+			return nil, fmt.Errorf("budget %q: must be positive", pair)
+		}
+		budgets[strings.TrimSpace(name)] = budget
+	}
+	return budgets, nil
+}
+
+// Check returns the targets whose p95 latency exceeded their budget. Targets
+// without a budget are not checked.
+func Check(stats []Stats, budgets map[string]time.Duration) []Violation {
+	var violations []Violation
+	for _, s := range stats {
+		budget, ok := budgets[s.Name]
+		if ok && s.P95 > budget {
+			violations = append(violations, Violation{Name: s.Name, P95: s.P95, Budget: budget})
+		}
+	}
+	return violations
+}
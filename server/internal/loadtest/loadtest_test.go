@@ -0,0 +1,171 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	tests := []struct {
+		percentile float64
+		want       time.Duration
+	}{
+		{50, 50 * time.Millisecond},
+		{95, 95 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+		{0, time.Millisecond},
+	}
+	for _, test := range tests {
+		if got := Percentile(sorted, test.percentile); got != test.want {
+			t.Errorf("p%.0f = %s, want %s", test.percentile, got, test.want)
+		}
+	}
+	if got := Percentile([]time.Duration{7 * time.Millisecond}, 95); got != 7*time.Millisecond {
+		t.Errorf("Expected the only latency, got %s", got)
+	}
+	if got := Percentile(nil, 95); got != 0 {
+		t.Errorf("Expected 0 without latencies, got %s", got)
+	}
+}
+
+func TestParseBudgets(t *testing.T) {
+	budgets, err := ParseBudgets("dashboard=300ms, performance = 1s,")
+	if err != nil {
+		t.Fatalf("Failed to parse budgets: %v", err)
+	}
+	if len(budgets) != 2 || budgets["dashboard"] != 300*time.Millisecond || budgets["performance"] != time.Second {
+		t.Errorf("Unexpected budgets %v", budgets)
+	}
+
+	for _, spec := range []string{"dashboard", "dashboard=fast", "dashboard=0s"} {
+		if _, err := ParseBudgets(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	stats := []Stats{
+		{Name: "dashboard", P95: 250 * time.Millisecond},
+		{Name: "performance", P95: 1200 * time.Millisecond},
+		{Name: "holdings", P95: time.Hour}, // No budget
+	}
+	violations := Check(stats, map[string]time.Duration{"dashboard": 300 * time.Millisecond, "performance": time.Second})
+	if len(violations) != 1 || violations[0].Name != "performance" {
+		t.Fatalf("Expected only performance to exceed its budget, got %v", violations)
+	}
+	if got := violations[0].String(); got != "performance p95 1.2s exceeds budget 1s" {
+		t.Errorf("Unexpected violation message %q", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var unauthorized atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			unauthorized.Add(1)
+		}
+		switch r.URL.Path {
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "/limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	targets := []Target{{Name: "ok", Path: "/ok"}, {Name: "broken", Path: "/broken"}, {Name: "limited", Path: "/limited"}}
+	stats, err := Run(context.Background(), server.Client(), server.URL+"/", "token", targets,
+		Options{Duration: 300 * time.Millisecond, Rate: 100, Concurrency: 4, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(stats) != 3 || stats[0].Name != "ok" || stats[1].Name != "broken" || stats[2].Name != "limited" {
+		t.Fatalf("Expected stats in target order, got %+v", stats)
+	}
+	if stats[0].Requests == 0 || stats[0].Errors != 0 {
+		t.Errorf("Expected successful requests to ok, got %+v", stats[0])
+	}
+	if stats[1].Requests == 0 || stats[1].ErrorRate() != 1 || stats[1].RateLimited != 0 {
+		t.Errorf("Expected every request to broken to fail, got %+v", stats[1])
+	}
+	if stats[2].Requests == 0 || stats[2].RateLimited != stats[2].Requests || stats[2].Errors != stats[2].Requests {
+		t.Errorf("Expected every request to limited to be rate limited, got %+v", stats[2])
+	}
+	if stats[0].P95 <= 0 || stats[0].P95 > stats[0].Max {
+		t.Errorf("Expected 0 < p95 <= max, got %+v", stats[0])
+	}
+	if unauthorized.Load() != 0 {
+		t.Errorf("Expected every request to carry the token")
+	}
+
+	if _, err := Run(context.Background(), server.Client(), server.URL, "token", nil, Options{Duration: time.Second, Rate: 1, Concurrency: 1}); err == nil {
+		t.Errorf("Expected a run without targets to fail")
+	}
+}
+
+func TestSeed(t *testing.T) {
+	registered := map[string]bool{}
+	var seeded []SeedTransaction
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/auth/register":
+			var credentials map[string]string
+			json.NewDecoder(r.Body).Decode(&credentials)
+			if registered[credentials["email"]] {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			registered[credentials["email"]] = true
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"token":"new"}`))
+		case "/api/auth/login":
+			w.Write([]byte(`{"token":"existing"}`))
+		case "/api/portfolio/transactions":
+			if r.Header.Get("Authorization") != "Bearer new" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var tx SeedTransaction
+			json.NewDecoder(r.Body).Decode(&tx)
+			seeded = append(seeded, tx)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	transactions := DefaultSeed(time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC))
+	token, err := Seed(context.Background(), server.Client(), server.URL, "load@example.com", "password", transactions)
+	if err != nil || token != "new" {
+		t.Fatalf("Expected a new user's token, got %q, %v", token, err)
+	}
+	if len(seeded) != len(transactions) || seeded[0].Symbol != transactions[0].Symbol {
+		t.Errorf("Expected %d seeded transactions, got %+v", len(transactions), seeded)
+	}
+
+	// A second run logs in and does not seed again
+	token, err = Seed(context.Background(), server.Client(), server.URL, "load@example.com", "password", transactions)
+	if err != nil || token != "existing" {
+		t.Fatalf("Expected the existing user's token, got %q, %v", token, err)
+	}
+	if len(seeded) != len(transactions) {
+		t.Errorf("Expected no transactions seeded for an existing user, got %d", len(seeded))
+	}
+}
@@ -0,0 +1,113 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SeedTransaction is a transaction recorded for a newly registered load test user
+type SeedTransaction struct {
+	Symbol   string    `json:"symbol"`
+	Action   string    `json:"action"`
+	Shares   float64   `json:"shares"`
+	Price    float64   `json:"price"`
+	Currency string    `json:"currency"`
+	Date     time.Time `json:"date"`
+}
+
+// DefaultSeed is a diversified portfolio built up over two years, so the
+// performance series covers the whole 1Y period with several symbols
+func DefaultSeed(now time.Time) []SeedTransaction {
+	monthsAgo := func(months int) time.Time {
+		return now.AddDate(0, -months, 0).Truncate(24 * time.Hour)
+	}
+	return []SeedTransaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 20, Price: 150, Currency: "USD", Date: monthsAgo(24)},
+		{Symbol: "MSFT", Action: "buy", Shares: 10, Price: 300, Currency: "USD", Date: monthsAgo(22)},
+		{Symbol: "NVDA", Action: "buy", Shares: 40, Price: 40, Currency: "USD", Date: monthsAgo(18)},
+		{Symbol: "GOOGL", Action: "buy", Shares: 15, Price: 130, Currency: "USD", Date: monthsAgo(15)},
+		{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 180, Currency: "USD", Date: monthsAgo(12)},
+		{Symbol: "AMZN", Action: "buy", Shares: 12, Price: 170, Currency: "USD", Date: monthsAgo(9)},
+		{Symbol: "NVDA", Action: "sell", Shares: 10, Price: 120, Currency: "USD", Date: monthsAgo(6)},
+		{Symbol: "600519.SS", Action: "buy", Shares: 10, Price: 1500, Currency: "RMB", Date: monthsAgo(5)},
+		{Symbol: "VOO", Action: "buy", Shares: 8, Price: 480, Currency: "USD", Date: monthsAgo(3)},
+	}
+}
+
+// authResponse is the part of the register and login responses the load test needs
+type authResponse struct {
+	Token string `json:"token"`
+}
+
+// Seed returns a token for the load test user. A new user is registered and
+// given the transactions; an existing user is logged in and their portfolio
+// left as it is, so repeated runs measure the same data.
+func Seed(ctx context.Context, client *http.Client, baseURL, email, password string, transactions []SeedTransaction) (string, error) {
+	baseURL = strings.TrimRight(baseURL, "/")
+	credentials := map[string]string{"email": email, "password": password}
+
+	var auth authResponse
+	status, err := post(ctx, client, baseURL+"/api/auth/register", "", credentials, &auth)
+	if err != nil {
+		return "", fmt.Errorf("register: %w", err)
+	}
+	switch status {
+	case http.StatusCreated:
+		for _, tx := range transactions {
+			status, err := post(ctx, client, baseURL+"/api/portfolio/transactions", auth.Token, tx, nil)
+			if err != nil {
+				return "", fmt.Errorf("seed %s %s: %w", tx.Action, tx.Symbol, err)
+			}
+			if status < 200 || status > 299 {
+				return "", fmt.Errorf("seed %s %s: status %d", tx.Action, tx.Symbol, status)
+			}
+		}
+		return auth.Token, nil
+
+	case http.StatusConflict:
+		status, err := post(ctx, client, baseURL+"/api/auth/login", "", credentials, &auth)
+		if err != nil {
+			return "", fmt.Errorf("login: %w", err)
+		}
+		if status != http.StatusOK {
+			return "", fmt.Errorf("login: status %d", status)
+		}
+		return auth.Token, nil
+
+	default:
+		return "", fmt.Errorf("register: status %d", status)
+	}
+}
+
+// post sends body as JSON and decodes a 2xx response into out when it is not nil
+func post(ctx context.Context, client *http.Client, url, token string, body, out any) (int, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+}
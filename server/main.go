@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"stock-portfolio-tracker/config"
 	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/grpcapi"
 	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/migrations"
+	"stock-portfolio-tracker/repository"
 	"stock-portfolio-tracker/routes"
 	"stock-portfolio-tracker/services"
+	"stock-portfolio-tracker/telemetry"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
@@ -20,29 +29,147 @@ func main() {
 		log.Println("No .env file found")
 	}
 
-	// Connect to MongoDB
-	mongoURI := os.Getenv("MONGODB_URI")
-	if mongoURI == "" {
-		log.Fatal("MONGODB_URI environment variable is required")
+	// Subcommands run instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "probe" {
+		os.Exit(runProbe(os.Args[2:]))
+	}
+
+	// Load and validate settings before touching any dependency
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
 	}
 
-	if err := database.Connect(mongoURI); err != nil {
+	// Export traces when an OTLP endpoint is configured
+	shutdownTracing, err := telemetry.Init(context.Background())
+	if err != nil {
+		log.Fatal("Failed to initialize tracing:", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Println("Failed to flush traces:", err)
+		}
+	}()
+
+	// Connect to MongoDB
+	if err := database.ConnectWithConfig(cfg.Mongo); err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 	defer database.Disconnect()
 
+	// Notifications are queued in the same transaction as the change that
+	// raises them, which needs a replica set
+	transactionsCtx, cancelTransactions := context.WithTimeout(context.Background(), 5*time.Second)
+	err = repository.CheckTransactions(transactionsCtx)
+	cancelTransactions()
+	if err != nil {
+		log.Fatal("Failed to check the database:", err)
+	}
+
 	// Create database indexes
 	if err := database.CreateIndexes(); err != nil {
 		log.Fatal("Failed to create database indexes:", err)
 	}
 
+	// Apply pending schema migrations
+	if cfg.Mongo.MigrateOnStartup {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		applied, err := migrations.Up(ctx, database.Database)
+		cancel()
+		if err != nil {
+			log.Fatal("Failed to apply database migrations:", err)
+		}
+		log.Printf("Applied %d database migrations", applied)
+	}
+
 	// Initialize services
-	authService := services.NewAuthService()
-	stockService := services.NewStockAPIService()
-	currencyService := services.NewCurrencyService()
+	authService := services.NewAuthService(cfg.Auth.JWTSecret)
+	symbolCatalog := services.NewSymbolCatalog()
+	if err := symbolCatalog.Load(); err != nil {
+		// Symbols are recorded again as they are quoted
+		log.Printf("Symbol catalog not loaded: %v", err)
+	}
+	stockService := services.NewStockAPIService(services.StockAPIConfig{
+		YahooTimeout:     cfg.Providers.YahooTimeout,
+		EastmoneyTimeout: cfg.Providers.EastmoneyTimeout,
+		YahooRate:        cfg.Providers.YahooRateLimit,
+		EastmoneyRate:    cfg.Providers.EastmoneyRateLimit,
+		Catalog:          symbolCatalog,
+		CacheTTL:         cfg.Cache.QuoteTTL,
+		QuoteCacheSize:   cfg.Cache.QuoteMaxEntries,
+		HistoryCacheSize: cfg.Cache.HistoryMaxEntries,
+	})
+	currencyService := services.NewCurrencyService(services.CurrencyConfig{
+		APIKey:   cfg.Providers.ExchangeRateAPIKey,
+		Timeout:  cfg.Providers.ExchangeRateTimeout,
+		Rate:     cfg.Providers.ExchangeRateRateLimit,
+		CacheTTL: cfg.Cache.ExchangeRateTTL,
+	})
 	portfolioService := services.NewPortfolioService(stockService, currencyService)
 	analyticsService := services.NewAnalyticsService(portfolioService, currencyService, stockService)
 	backtestService := services.NewBacktestService(portfolioService, analyticsService, currencyService, stockService)
+	backtestJobService := services.NewBacktestJobService(backtestService, 2)
+	withdrawalService := services.NewWithdrawalService(portfolioService, backtestService)
+	reportService := services.NewReportService(analyticsService, portfolioService)
+	settingsService := services.NewSettingsService()
+	benchmarkComparisonService := services.NewBenchmarkComparisonService(backtestService, settingsService)
+	profileService := services.NewProfileService()
+	exportService := services.NewExportService(settingsService)
+	shareService := services.NewShareService(analyticsService)
+	householdService := services.NewHouseholdService(analyticsService)
+	importService := services.NewImportService(portfolioService)
+	reconciliationService := services.NewReconciliationService(portfolioService, stockService)
+	// Every stored third-party credential is encrypted with the one key
+	var credentialCipher *services.TokenCipher
+	if cfg.Auth.CredentialKey != "" {
+		cipher, err := services.NewTokenCipher(cfg.Auth.CredentialKey)
+		if err != nil {
+			log.Fatal("Invalid credential key:", err)
+		}
+		credentialCipher = cipher
+	}
+	var brokerageAggregators []services.BrokerageAggregator
+	if cfg.Brokerage.PlaidClientID != "" {
+		brokerageAggregators = append(brokerageAggregators, services.NewPlaidAggregator(cfg.Brokerage.PlaidClientID, cfg.Brokerage.PlaidSecret, cfg.Brokerage.PlaidEnvironment))
+	}
+	if cfg.Brokerage.SnapTradeClientID != "" {
+		brokerageAggregators = append(brokerageAggregators, services.NewSnapTradeAggregator(cfg.Brokerage.SnapTradeClientID, cfg.Brokerage.SnapTradeConsumerKey))
+	}
+	brokerageService := services.NewBrokerageService(importService, credentialCipher, brokerageAggregators...)
+	// Exchange API keys are only stored encrypted
+	if credentialCipher != nil {
+		brokerageService.WithExchanges(services.NewBinanceConnector(credentialCipher), services.NewCoinbaseConnector(credentialCipher))
+	}
+	var sheetsClient *services.GoogleSheetsClient
+	if cfg.Sheets.Enabled() {
+		sheetsClient = services.NewGoogleSheetsClient(cfg.Sheets.GoogleClientID, cfg.Sheets.GoogleClientSecret, cfg.Sheets.RedirectURL)
+	}
+	sheetsService := services.NewSheetsService(analyticsService, sheetsClient, credentialCipher)
+	webhookService := services.NewWebhookService(analyticsService)
+	chatChannels := services.NewChatChannels(cfg.Chat.TelegramBotToken, settingsService.GetSettings)
+	notificationService := services.NewNotificationService(append([]services.NotificationChannel{
+		services.NewMailChannel(services.SMTPConfig{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+		}),
+		webhookService,
+	}, chatChannels.List()...)...)
+	summaryEmailService := services.NewSummaryEmailService(analyticsService, settingsService, notificationService)
+	driftAlertService := services.NewDriftAlertService(analyticsService, notificationService)
+	crossAlertService := services.NewCrossAlertService(portfolioService, notificationService)
+	pendingOrderService := services.NewPendingOrderService(portfolioService, stockService, notificationService)
+	cashInterestService := services.NewCashInterestService(portfolioService, stockService)
+	priceOverrideService := services.NewPriceOverrideService(portfolioService)
+	stopService := services.NewStopService(portfolioService, stockService, notificationService)
+	newsService := services.NewNewsService(portfolioService, services.NewsConfig{
+		Timeout:  cfg.Providers.YahooTimeout,
+		CacheTTL: cfg.Cache.NewsTTL,
+	})
 	
 	// Start cache cleanup for stock service (run every 10 minutes)
 	stockService.StartCacheCleanup(10 * time.Minute)
@@ -50,25 +177,112 @@ func main() {
 	// Start cache cleanup for currency service (run every 30 minutes)
 	currencyService.StartCacheCleanup(30 * time.Minute)
 
-	// Initialize Gin router
+	// Start cache cleanup for news headlines (run every 30 minutes)
+	newsService.StartCacheCleanup(30 * time.Minute)
+
+	// Start cache cleanup for computed dashboards (run every 5 minutes)
+	analyticsService.StartCacheCleanup(5 * time.Minute)
+
+	// Remove finished backtest jobs past their retention (run every 10 minutes)
+	backtestJobService.StartCleanup(10 * time.Minute)
+
+	// Remove expired data exports and their archives (run every 30 minutes)
+	exportService.StartCleanup(30 * time.Minute)
+
+	// Load the maintenance switch before serving, so an instance started
+	// during maintenance does not accept writes
+	maintenanceService := services.NewMaintenanceService()
+	if err := maintenanceService.Refresh(); err != nil {
+		log.Fatal("Failed to load maintenance mode:", err)
+	}
+
+	// Load feature flags before serving, so rolled out features are not
+	// briefly hidden after a deploy
+	featureFlagService := services.NewFeatureFlagService(cfg.Features.Overrides)
+	if err := featureFlagService.Refresh(); err != nil {
+		log.Fatal("Failed to load feature flags:", err)
+	}
+	if credentialCipher == nil && featureFlagService.EnabledForAnyone(services.FeatureExchangeLinks) {
+		log.Fatalf("The %s feature is enabled, but exchange API keys can't be stored without a credential key (CREDENTIAL_KEY)", services.FeatureExchangeLinks)
+	}
+
+	// Start recurring background jobs
+	scheduler := services.NewScheduler()
+	scheduler.Every("summary-emails", services.SummaryEmailJobInterval, summaryEmailService.SendDueSummaries)
+	scheduler.Every("pending-orders", services.PendingOrderJobInterval, pendingOrderService.CheckOrders)
+	scheduler.Every("cash-interest", services.CashInterestJobInterval, cashInterestService.AccrueInterest)
+	scheduler.Every("stops", services.StopJobInterval, stopService.CheckStops)
+	scheduler.Every("drift-alerts", services.DriftAlertJobInterval, driftAlertService.CheckDrift)
+	scheduler.Every("cross-alerts", services.CrossAlertJobInterval, crossAlertService.CheckCrosses)
+	scheduler.Every("webhook-summaries", services.WebhookSummaryJobInterval, webhookService.SendDailySummaries)
+	scheduler.Every("brokerage-sync", cfg.Brokerage.SyncInterval, brokerageService.SyncAll)
+	scheduler.Every("sheets-sync", cfg.Sheets.SyncInterval, sheetsService.SyncAll)
+	scheduler.Every("notification-outbox", services.OutboxJobInterval, notificationService.DeliverOutbox)
+	scheduler.Every("maintenance-mode", services.MaintenanceRefreshInterval, maintenanceService.Refresh)
+	scheduler.Every("feature-flags", cfg.Features.RefreshInterval, featureFlagService.Refresh)
+	scheduler.Every("symbol-catalog", services.SymbolCatalogRefreshInterval, symbolCatalog.Load)
+
+	// Prefetch quotes for active users' holdings now and on a schedule, so
+	// the first dashboards after a deploy don't all miss the cache
+	if cfg.Cache.WarmInterval > 0 {
+		cacheWarmer := services.NewCacheWarmer(portfolioService, stockService, cfg.Cache.WarmActiveWindow)
+		go func() {
+			if err := cacheWarmer.WarmCaches(); err != nil {
+				log.Printf("Startup cache warming failed: %v", err)
+			}
+		}()
+		scheduler.Every("cache-warming", cfg.Cache.WarmInterval, cacheWarmer.WarmCaches)
+	}
+	scheduler.Start()
+
+	// Initialize Gin router. Forwarded client addresses are only believed
+	// from the configured proxies.
 	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("Invalid trusted proxies:", err)
+	}
 
-	// Configure CORS middleware
-	corsOrigin := os.Getenv("CORS_ORIGIN")
-	if corsOrigin == "" {
-		corsOrigin = "http://localhost:3000"
+	// Trace every request; spans for queries and outbound calls made with the
+	// request context become its children
+	router.Use(otelgin.Middleware(telemetry.ServiceName()))
+
+	// Send plain HTTP requests arriving through the proxy to HTTPS, and set
+	// security headers on every response
+	if cfg.Server.RedirectHTTPS {
+		router.Use(middleware.HTTPSRedirect())
 	}
-	
+	router.Use(middleware.SecurityHeaders(middleware.SecurityOptions{
+		HSTSMaxAge:            cfg.Server.HSTSMaxAge,
+		ContentSecurityPolicy: cfg.Server.ContentSecurityPolicy,
+	}))
+
+	// Render errors reported by handlers and middleware as the standard
+	// error envelope
+	router.Use(middleware.ErrorHandler(cfg.Server.Production()))
+
+	// Block denied client addresses, and any outside the allow list if set
+	if len(cfg.Server.AllowedIPs) > 0 || len(cfg.Server.DeniedIPs) > 0 {
+		ipFilter, err := middleware.IPFilter(cfg.Server.AllowedIPs, cfg.Server.DeniedIPs)
+		if err != nil {
+			log.Fatal("Invalid IP filter:", err)
+		}
+		router.Use(ipFilter)
+	}
+
+	// Configure CORS middleware
 	corsConfig := cors.Config{
-		AllowOrigins:     []string{corsOrigin},
+		AllowOrigins:     cfg.Server.CORSOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-None-Match"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "Deprecation", "Sunset", "Link"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
 	router.Use(cors.New(corsConfig))
 
+	// Bound every request; analytics and backtest routes set their own deadline
+	router.Use(middleware.RequestTimeout(cfg.Server.RequestTimeout))
+
 	// Apply request logging middleware
 	router.Use(middleware.RequestLoggingMiddleware())
 
@@ -76,8 +290,11 @@ func main() {
 	router.Use(middleware.BodySizeLimitMiddleware())
 	router.Use(middleware.InputSanitizationMiddleware())
 
-	// Apply global rate limiting (100 requests per minute per IP)
-	router.Use(middleware.GlobalRateLimiter())
+	// Apply global rate limiting per client
+	router.Use(middleware.GlobalRateLimiter(cfg.RateLimit.GlobalPerMinute))
+
+	// Reject writes while the admin CLI has turned on maintenance mode
+	router.Use(middleware.MaintenanceMiddleware(maintenanceService))
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
@@ -91,28 +308,73 @@ func main() {
 		}
 
 		c.JSON(200, gin.H{
-			"status": "ok",
+			"status":      "ok",
+			"maintenance": maintenanceService.Current().Enabled,
+			"caches":      stockService.CacheStats(),
 		})
 	})
 
-	// Setup routes
-	routes.SetupAuthRoutes(router, authService)
-	routes.SetupStockRoutes(router, stockService)
-	routes.SetupPortfolioRoutes(router, portfolioService, authService)
-	routes.SetupCurrencyRoutes(router, currencyService)
-	routes.SetupAnalyticsRoutes(router, analyticsService, authService)
-	routes.SetupAssetStyleRoutes(router, authService)
-	routes.SetupBacktestRoutes(router, backtestService, authService)
+	// Setup routes under each API version. The auth rate limiter is shared so
+	// every prefix draws on the same per-client allowance.
+	authRateLimiter := middleware.AuthRateLimiter(cfg.RateLimit.AuthPerMinute)
+	routes.SetupVersionedRoutes(router, func(api gin.IRouter) {
+		routes.SetupAuthRoutes(api, authService, authRateLimiter)
+		routes.SetupStockRoutes(api, stockService, newsService)
+		routes.SetupPortfolioRoutes(api, portfolioService, reconciliationService, newsService, authService)
+		routes.SetupPendingOrderRoutes(api, pendingOrderService, authService)
+		routes.SetupCashInterestRoutes(api, cashInterestService, authService)
+		routes.SetupPriceOverrideRoutes(api, priceOverrideService, authService)
+		routes.SetupStopRoutes(api, stopService, authService)
+		routes.SetupWebhookRoutes(api, webhookService, authService)
+		routes.SetupCurrencyRoutes(api, currencyService)
+		routes.SetupMetaRoutes(api)
+		routes.SetupAnalyticsRoutes(api, analyticsService, benchmarkComparisonService, authService, cfg.Server.AnalyticsTimeout, cfg.Server.StreamTimeout)
+		routes.SetupAssetStyleRoutes(api, authService)
+		routes.SetupAssetClassRoutes(api, authService)
+		routes.SetupBacktestRoutes(api, backtestService, backtestJobService, authService, cfg.Server.BacktestTimeout)
+		routes.SetupBenchmarkRoutes(api, stockService, authService)
+		routes.SetupSimulationRoutes(api, withdrawalService, authService)
+		routes.SetupReportRoutes(api, reportService, authService)
+		routes.SetupSettingsRoutes(api, settingsService, summaryEmailService, chatChannels, authService)
+		routes.SetupProfileRoutes(api, profileService, authService)
+		routes.SetupExportRoutes(api, exportService, authService)
+		routes.SetupShareRoutes(api, shareService, authService)
+		routes.SetupHouseholdRoutes(api, householdService, authService)
+		routes.SetupImportRoutes(api, importService, authService)
+		routes.SetupBrokerageRoutes(api, brokerageService, featureFlagService, authService)
+		routes.SetupSheetsRoutes(api, sheetsService, authService)
+		routes.SetupGraphQLRoutes(api, portfolioService, stockService, analyticsService, authService)
+		routes.SetupFeatureFlagRoutes(api, featureFlagService, authService)
+	})
+
+	// Maintenance routes for the admin CLI, outside the versioned API
+	routes.SetupAdminRoutes(router.Group("/api"), cfg.Server.AdminToken, stockService, currencyService, symbolCatalog)
 
-	// Get port from environment or use default
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Serve the gRPC API alongside REST when a port is configured
+	if cfg.Server.GRPCPort != "" {
+		listener, err := net.Listen("tcp", ":"+cfg.Server.GRPCPort)
+		if err != nil {
+			log.Fatal("Failed to listen for gRPC:", err)
+		}
+		grpcServer := grpcapi.NewServer(authService, stockService, portfolioService, analyticsService)
+		go func() {
+			log.Printf("gRPC server starting on port %s", cfg.Server.GRPCPort)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatal("Failed to start gRPC server:", err)
+			}
+		}()
+		defer grpcServer.GracefulStop()
 	}
 
 	// Start server
-	log.Printf("Server starting on port %s", port)
-	if err := router.Run(":" + port); err != nil {
+	server := &http.Server{
+		Addr:         ":" + cfg.Server.Port,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+	log.Printf("Server starting on port %s", cfg.Server.Port)
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
 }
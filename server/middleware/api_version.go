@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersion describes a version of the REST API and its lifecycle
+type APIVersion struct {
+	Name string // e.g. "v1"
+
+	// DeprecatedAt and SunsetAt are zero while the version is current
+	DeprecatedAt time.Time
+	SunsetAt     time.Time
+
+	// Successor is the path prefix of the version replacing this one
+	Successor string
+}
+
+// Deprecated reports whether clients should migrate off the version
+func (v APIVersion) Deprecated() bool {
+	return !v.DeprecatedAt.IsZero()
+}
+
+// APIVersionMiddleware records the API version a request was routed to and
+// announces deprecation with the Deprecation (RFC 9745), Sunset (RFC 8594)
+// and successor Link headers
+func APIVersionMiddleware(version APIVersion) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("apiVersion", version.Name)
+
+		if version.Deprecated() {
+			c.Header("Deprecation", fmt.Sprintf("@%d", version.DeprecatedAt.Unix()))
+			if !version.SunsetAt.IsZero() {
+				c.Header("Sunset", version.SunsetAt.UTC().Format(http.TimeFormat))
+			}
+			if version.Successor != "" {
+				c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", version.Successor))
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// GetAPIVersion returns the API version the request was routed to, or an
+// empty string outside the versioned API
+func GetAPIVersion(c *gin.Context) string {
+	return c.GetString("apiVersion")
+}
@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"fmt"
-	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
 	"stock-portfolio-tracker/services"
 	"strings"
 
@@ -17,12 +19,7 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			fmt.Printf("Auth failed: No Authorization header for %s %s\n", c.Request.Method, c.Request.URL.Path)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": gin.H{
-					"code":    "UNAUTHORIZED",
-					"message": "Authorization header is required",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeUnauthorized, "Authorization header is required"))
 			c.Abort()
 			return
 		}
@@ -31,28 +28,18 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			fmt.Printf("Auth failed: Invalid Authorization header format for %s %s\n", c.Request.Method, c.Request.URL.Path)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": gin.H{
-					"code":    "UNAUTHORIZED",
-					"message": "Authorization header must be in format: Bearer <token>",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeUnauthorized, "Authorization header must be in format: Bearer <token>"))
 			c.Abort()
 			return
 		}
 
 		tokenString := parts[1]
 
-		// Validate token and get user
-		user, err := authService.ValidateToken(tokenString)
+		// Validate token, get user and record the session's activity
+		user, sessionID, err := authService.Authenticate(tokenString, RequestDevice(c))
 		if err != nil {
 			fmt.Printf("Auth failed: Token validation error for %s %s: %v\n", c.Request.Method, c.Request.URL.Path, err)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": gin.H{
-					"code":    "UNAUTHORIZED",
-					"message": "Invalid or expired token",
-				},
-			})
+			c.Error(apierror.New(apierror.CodeUnauthorized, "Invalid or expired token"))
 			c.Abort()
 			return
 		}
@@ -60,6 +47,9 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 		// Attach user ID to context for downstream handlers
 		c.Set("userID", user.ID)
 		c.Set("user", user)
+		c.Set("sessionID", sessionID)
+		// Let outbound provider requests made for the user take turns with other users
+		c.Request = c.Request.WithContext(services.WithProviderCaller(c.Request.Context(), user.ID.Hex()))
 
 		c.Next()
 	}
@@ -75,3 +65,34 @@ func GetUserID(c *gin.Context) (primitive.ObjectID, bool) {
 	id, ok := userID.(primitive.ObjectID)
 	return id, ok
 }
+
+// GetSessionID extracts the session ID of the request's token from the Gin
+// context. It is zero for tokens issued without a session.
+func GetSessionID(c *gin.Context) primitive.ObjectID {
+	sessionID, _ := c.Get("sessionID")
+	id, _ := sessionID.(primitive.ObjectID)
+	return id
+}
+
+// RequestDevice describes the client making the request
+func RequestDevice(c *gin.Context) models.SessionDevice {
+	return models.SessionDevice{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
+// AdminTokenMiddleware admits requests bearing the operators' shared admin
+// token, for maintenance endpoints that act on the whole server rather than
+// one user
+func AdminTokenMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.Error(apierror.New(apierror.CodeUnauthorized, "Invalid admin token"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"fmt"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serviceErrors maps service sentinel errors to API error codes, so handlers
+// can report service failures without checking for each sentinel
+var serviceErrors = []struct {
+	err     error
+	code    apierror.Code
+	message string
+}{
+	{services.ErrStockNotFound, apierror.CodeNotFound, "Stock not found"},
+	{services.ErrInvalidSymbol, apierror.CodeValidation, "Invalid stock symbol format"},
+	{services.ErrInvalidPeriod, apierror.CodeValidation, "Invalid period parameter"},
+	{services.ErrInvalidIndicator, apierror.CodeValidation, "Indicators must be rsi, macd or a window such as sma50, ema20 or rsi7, at most 10"},
+	{services.ErrExternalAPI, apierror.CodeExternalAPI, "Failed to fetch data from external API"},
+	{services.ErrCurrencyAPIError, apierror.CodeExternalAPI, "Failed to fetch exchange rates from external API"},
+	{services.ErrInvalidCurrencyCode, apierror.CodeValidation, "Invalid currency code"},
+	{services.ErrTransactionNotFound, apierror.CodeNotFound, "Transaction not found"},
+	{services.ErrPositionNotFound, apierror.CodeNotFound, "No transactions for this symbol"},
+	{services.ErrFutureSnapshot, apierror.CodeValidation, "asOf cannot be in the future"},
+	{services.ErrInsufficientShares, apierror.CodeInsufficientShares, "Insufficient shares for sell transaction"},
+	{services.ErrFutureDate, apierror.CodeValidation, "Transaction date cannot be in the future"},
+	{services.ErrInvalidTransaction, apierror.CodeValidation, "Invalid transaction data"},
+	{services.ErrAssetStyleNotFound, apierror.CodeNotFound, "Asset style not found"},
+	{services.ErrDuplicateAssetStyle, apierror.CodeDuplicateAssetStyle, "Asset style name already exists"},
+	{services.ErrInvalidStyleColor, apierror.CodeValidation, "Asset style colors must be hex colors such as #4f46e5"},
+	{services.ErrInvalidStyleIcon, apierror.CodeValidation, "Asset style icons must be lowercase letters, digits and dashes"},
+	{services.ErrAssetSubclassNotFound, apierror.CodeNotFound, "Asset subclass not found"},
+	{services.ErrDuplicateAssetSubclass, apierror.CodeConflict, "Asset subclass name already exists in this asset class"},
+	{services.ErrAssetSubclassMismatch, apierror.CodeValidation, "Asset subclass belongs to another asset class"},
+	{services.ErrInvalidAssetSubclass, apierror.CodeValidation, "Asset subclass names must be 1 to 50 characters"},
+	{services.ErrTooManyAssetSubclasses, apierror.CodeLimitExceeded, "Delete a subclass of this asset class before adding another"},
+	{services.ErrPortfolioNotFound, apierror.CodeNotFound, "Portfolio not found"},
+	{services.ErrInvalidPortfolioTag, apierror.CodeValidation, "Portfolio tags must be a name or name:value of up to 50 characters"},
+	{services.ErrInvalidGroupBy, apierror.CodeValidation, "Invalid groupBy parameter"},
+	{services.ErrInvalidDownsamplePoints, apierror.CodeValidation, "Invalid points parameter. Must be an integer of at least 3"},
+	{services.ErrInvalidReportFormat, apierror.CodeValidation, "Invalid report format. Must be pdf or html"},
+	{services.ErrPendingOrderNotFound, apierror.CodeNotFound, "Pending order not found"},
+	{services.ErrPendingOrderClosed, apierror.CodeConflict, "Pending order is no longer open"},
+	{services.ErrTooManyPendingOrders, apierror.CodeLimitExceeded, "Cancel an open pending order before creating another"},
+	{services.ErrPendingOrderCurrency, apierror.CodeValidation, "Pending order currency must match the symbol's trading currency"},
+	{services.ErrWebhookNotFound, apierror.CodeNotFound, "Webhook not found"},
+	{services.ErrInvalidWebhookURL, apierror.CodeValidation, "Webhook URLs must be http or https URLs"},
+	{services.ErrTooManyWebhooks, apierror.CodeLimitExceeded, "Delete a webhook before adding another"},
+	{services.ErrWebhookDeliveryFailed, apierror.CodeExternalAPI, "The webhook endpoint could not be reached or did not respond with 2xx"},
+	{services.ErrInvalidTelegramChat, apierror.CodeValidation, "Telegram chat IDs must be a number or a public @channel name"},
+	{services.ErrInvalidSlackWebhook, apierror.CodeValidation, "Slack webhook URLs must start with https://hooks.slack.com/"},
+	{services.ErrChatChannelUnavailable, apierror.CodeValidation, "This chat channel is not available on this server"},
+	{services.ErrChatNotLinked, apierror.CodeValidation, "Link this chat channel in your settings first"},
+	{services.ErrChatDeliveryFailed, apierror.CodeNotificationError, "Failed to send the chat message"},
+	{services.ErrBrokerageUnavailable, apierror.CodeValidation, "This brokerage aggregator is not available on this server"},
+	{services.ErrBrokerageLinkNotFound, apierror.CodeNotFound, "Brokerage link not found"},
+	{services.ErrBrokerageLinkNotPending, apierror.CodeConflict, "Brokerage link is already complete"},
+	{services.ErrBrokerageLinkPending, apierror.CodeConflict, "Finish linking the brokerage before syncing it"},
+	{services.ErrTooManyBrokerageLinks, apierror.CodeLimitExceeded, "Remove a brokerage link before adding another"},
+	{services.ErrBrokerageSyncFailed, apierror.CodeExternalAPI, "The brokerage aggregator request failed"},
+	{services.ErrExchangeKeyRejected, apierror.CodeValidation, "The exchange rejected the API key"},
+	{services.ErrExchangeKeyNotReadOnly, apierror.CodeValidation, "Use a read-only API key; this one can trade or withdraw"},
+	{services.ErrSheetsUnavailable, apierror.CodeValidation, "Google Sheets export is not available on this server"},
+	{services.ErrSheetsLinkNotFound, apierror.CodeNotFound, "No Google Sheet is linked"},
+	{services.ErrSheetsLinkPending, apierror.CodeConflict, "Finish authorizing Google before syncing the sheet"},
+	{services.ErrInvalidSheetsState, apierror.CodeValidation, "The Google authorization doesn't match the pending link; link the sheet again"},
+	{services.ErrInvalidSpreadsheet, apierror.CodeValidation, "Enter a Google Sheets URL or spreadsheet ID"},
+	{services.ErrSheetsSyncFailed, apierror.CodeExternalAPI, "The Google Sheets request failed"},
+	{services.ErrCashInterestNotFound, apierror.CodeNotFound, "Cash interest rate not found"},
+	{services.ErrPriceOverrideNotFound, apierror.CodeNotFound, "Price override not found"},
+	{services.ErrInvalidPriceOverride, apierror.CodeValidation, "Set a positive price effective today or earlier; cash can't be overridden"},
+	{services.ErrInvalidStop, apierror.CodeValidation, "Set a stop price or a trailing percentage below 100"},
+	{services.ErrPositionClosed, apierror.CodeConflict, "No shares are held in this position"},
+	{services.ErrInvalidDriftTargets, apierror.CodeValidation, "Target weights must add up to 100"},
+	{services.ErrSessionNotFound, apierror.CodeNotFound, "Session not found"},
+	{services.ErrAvatarTooLarge, apierror.CodePayloadTooLarge, "Avatars must be 512KB or smaller"},
+	{services.ErrUnsupportedAvatar, apierror.CodeValidation, "Avatars must be PNG, JPEG, GIF or WebP images"},
+	{services.ErrAvatarNotFound, apierror.CodeNotFound, "No avatar has been uploaded"},
+	{services.ErrExportNotFound, apierror.CodeNotFound, "Data export not found or expired"},
+	{services.ErrInvalidBenchmark, apierror.CodeValidation, "Invalid benchmark. Use a symbol, a blend like \"60% ^GSPC + 40% AGG\" or a saved blend"},
+	{services.ErrInvalidRename, apierror.CodeValidation, "A rename needs two different symbols"},
+	{services.ErrRenameCycle, apierror.CodeConflict, "The new symbol was renamed from the old one"},
+}
+
+func init() {
+	for _, e := range serviceErrors {
+		apierror.Register(e.err, e.code, e.message)
+	}
+}
+
+// ErrorHandler renders errors that handlers report with c.Error as the
+// standard error envelope. Known service errors are mapped to their codes;
+// anything else is an internal error, logged in full but, in production,
+// returned without details.
+func ErrorHandler(production bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		apiErr := apierror.From(c.Errors.Last().Err)
+		if apiErr.Status() >= 500 {
+			fmt.Printf("[Error] %s %s: %v\n", c.Request.Method, c.Request.URL.Path, apiErr)
+		}
+		c.JSON(apiErr.Status(), apiErr.Response(production))
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorHandlerRendersEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler(true))
+	router.GET("/quote", func(c *gin.Context) {
+		c.Error(apierror.Wrap(fmt.Errorf("%w: status code 502", services.ErrExternalAPI), apierror.CodeInternal, "Failed to get stock information"))
+	})
+	router.GET("/holdings", func(c *gin.Context) {
+		c.Error(apierror.Wrap(fmt.Errorf("mongo: connection refused"), apierror.CodeInternal, "Failed to fetch holdings"))
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		code   apierror.Code
+	}{
+		{"/quote", http.StatusServiceUnavailable, apierror.CodeExternalAPI},
+		{"/holdings", http.StatusInternalServerError, apierror.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			var resp apierror.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if w.Code != tt.status || resp.Error.Code != tt.code {
+				t.Errorf("Expected %d %s, got %d %s", tt.status, tt.code, w.Code, resp.Error.Code)
+			}
+			if w.Code >= 500 && resp.Error.Details != nil {
+				t.Errorf("Expected details hidden in production, got %v", resp.Error.Details)
+			}
+		})
+	}
+}
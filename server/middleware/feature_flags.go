@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RequireFeature hides a route from users who don't get the feature, as if
+// it did not exist. It must run after AuthMiddleware.
+func RequireFeature(featureFlagService *services.FeatureFlagService, key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("userID")
+		id, ok := userID.(primitive.ObjectID)
+		if !ok || !featureFlagService.Enabled(key, id) {
+			c.Error(apierror.New(apierror.CodeNotFound, "Not found"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"stock-portfolio-tracker/repository"
+	"stock-portfolio-tracker/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRequireFeature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	featureFlagService := services.NewFeatureFlagServiceWithRepos(repository.NewMemory(), map[string]bool{
+		"on":  true,
+		"off": false,
+	})
+	router := gin.New()
+	router.Use(ErrorHandler(true), func(c *gin.Context) {
+		if c.Query("anonymous") == "" {
+			c.Set("userID", primitive.NewObjectID())
+		}
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/on", RequireFeature(featureFlagService, "on"), ok)
+	router.GET("/off", RequireFeature(featureFlagService, "off"), ok)
+	router.GET("/unknown", RequireFeature(featureFlagService, "unknown"), ok)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/on", http.StatusOK},
+		{"/off", http.StatusNotFound},
+		{"/unknown", http.StatusNotFound},
+		{"/on?anonymous=1", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("Expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"stock-portfolio-tracker/apierror"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseIPNetworks parses CIDR ranges such as "10.0.0.0/8". A bare address
+// stands for itself.
+func ParseIPNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address or CIDR range %q", value)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address or CIDR range %q", value)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// IPFilter rejects requests from denied client addresses and, when an allow
+// list is given, from addresses outside it. The client address is the one
+// ClientIP resolves, so behind a load balancer the router's trusted proxies
+// must be set for the filter to see clients rather than the balancer.
+func IPFilter(allowed, denied []string) (gin.HandlerFunc, error) {
+	allowNetworks, err := ParseIPNetworks(allowed)
+	if err != nil {
+		return nil, err
+	}
+	denyNetworks, err := ParseIPNetworks(denied)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		ip := net.ParseIP(c.ClientIP())
+		if ip == nil || containsIP(denyNetworks, ip) || (len(allowNetworks) > 0 && !containsIP(allowNetworks, ip)) {
+			c.Error(apierror.New(apierror.CodeForbidden, "Access from this address is not allowed"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}, nil
+}
+
+// containsIP reports whether any of the networks contains the address
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
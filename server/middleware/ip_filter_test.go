@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIPFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatalf("Failed to set trusted proxies: %v", err)
+	}
+	filter, err := IPFilter(nil, []string{"198.51.100.7", "192.0.2.0/24"})
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+	router.Use(ErrorHandler(true), filter)
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		status       int
+	}{
+		{"direct client", "203.0.113.5:4000", "", http.StatusOK},
+		{"denied client", "198.51.100.7:4000", "", http.StatusForbidden},
+		{"denied range behind trusted proxy", "10.1.2.3:4000", "192.0.2.44", http.StatusForbidden},
+		{"allowed client behind trusted proxy", "10.1.2.3:4000", "203.0.113.5", http.StatusOK},
+		// An untrusted peer cannot hide behind a forged header
+		{"forged header", "198.51.100.7:4000", "203.0.113.5", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("Expected %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+
+	allowOnly, err := IPFilter([]string{"203.0.113.0/24"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+	router = gin.New()
+	router.Use(ErrorHandler(true), allowOnly)
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.RemoteAddr = "198.51.100.1:4000"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected clients outside the allow list to be rejected, got %d", w.Code)
+	}
+
+	if _, err := IPFilter(nil, []string{"not-an-ip"}); err == nil {
+		t.Error("Expected an invalid range to be rejected")
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maintenanceReadOnlyRoutes are POST routes that only read or compute, so
+// they keep working in maintenance mode. They are matched against the end of
+// the route pattern, so every API version is covered.
+var maintenanceReadOnlyRoutes = []string{
+	"/auth/login",
+	"/currency/convert",
+	"/graphql",
+	"/import/preview",
+	"/portfolio/reconcile",
+	"/portfolio/simulate",
+	"/simulations/withdrawals",
+}
+
+// MaintenanceMiddleware rejects requests that could change data with 503 and
+// a Retry-After header while maintenance mode is on. Reads, the read-only
+// POST routes above and the admin routes used to turn it off keep working.
+func MaintenanceMiddleware(maintenanceService *services.MaintenanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mode := maintenanceService.Current()
+		if !mode.Enabled || maintenanceAllowed(c) {
+			c.Next()
+			return
+		}
+
+		message := mode.Message
+		if message == "" {
+			message = "The service is in read-only maintenance mode. Please try again later."
+		}
+		c.Header("Retry-After", strconv.Itoa(mode.RetryAfter))
+		c.AbortWithStatusJSON(apierror.CodeMaintenance.Status(), apierror.Response{
+			Error: apierror.Body{
+				Code:       apierror.CodeMaintenance,
+				Message:    message,
+				RetryAfter: mode.RetryAfter,
+			},
+		})
+	}
+}
+
+// maintenanceAllowed reports whether a request may run in maintenance mode
+func maintenanceAllowed(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	route := c.FullPath()
+	if strings.HasPrefix(route, "/api/admin/") {
+		return true
+	}
+	for _, readOnly := range maintenanceReadOnlyRoutes {
+		if strings.HasSuffix(route, readOnly) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/repository"
+	"stock-portfolio-tracker/services"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	maintenanceService := services.NewMaintenanceServiceWithRepos(repository.NewMemory())
+	router := gin.New()
+	router.Use(MaintenanceMiddleware(maintenanceService))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/portfolio/holdings", ok)
+	router.POST("/api/portfolio/transactions", ok)
+	router.POST("/api/v1/portfolio/simulate", ok)
+	router.POST("/api/admin/cache/flush", ok)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	if w := serve(http.MethodPost, "/api/portfolio/transactions"); w.Code != http.StatusOK {
+		t.Fatalf("Expected writes outside maintenance, got %d", w.Code)
+	}
+
+	if _, err := maintenanceService.Set(true, "Migrating", 10*time.Minute); err != nil {
+		t.Fatalf("Failed to turn on maintenance mode: %v", err)
+	}
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, "/api/portfolio/holdings", http.StatusOK},
+		{http.MethodPost, "/api/portfolio/transactions", http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/v1/portfolio/simulate", http.StatusOK},
+		{http.MethodPost, "/api/admin/cache/flush", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := serve(tt.method, tt.path); w.Code != tt.status {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.status, w.Code)
+		}
+	}
+
+	w := serve(http.MethodPost, "/api/portfolio/transactions")
+	if got := w.Header().Get("Retry-After"); got != "600" {
+		t.Errorf("Expected Retry-After 600, got %q", got)
+	}
+	var resp apierror.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Error.Code != apierror.CodeMaintenance || resp.Error.Message != "Migrating" || resp.Error.RetryAfter != 600 {
+		t.Errorf("Unexpected error %+v", resp.Error)
+	}
+}
@@ -2,8 +2,7 @@ package middleware
 
 import (
 	"fmt"
-	"net/http"
-	"os"
+	"stock-portfolio-tracker/apierror"
 	"strconv"
 	"sync"
 	"time"
@@ -124,14 +123,13 @@ func RateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc {
 			}
 			c.Header("Retry-After", strconv.Itoa(retryAfter))
 			
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": gin.H{
-					"code":    "RATE_LIMIT_EXCEEDED",
-					"message": fmt.Sprintf("Too many requests. Please try again in %d seconds.", retryAfter),
-					"retryAfter": retryAfter,
+			c.AbortWithStatusJSON(apierror.CodeRateLimitExceeded.Status(), apierror.Response{
+				Error: apierror.Body{
+					Code:       apierror.CodeRateLimitExceeded,
+					Message:    fmt.Sprintf("Too many requests. Please try again in %d seconds.", retryAfter),
+					RetryAfter: retryAfter,
 				},
 			})
-			c.Abort()
 			return
 		}
 		
@@ -139,26 +137,14 @@ func RateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc {
 	}
 }
 
-// getEnvInt reads an integer from environment variable with a default value
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
-	}
-	return defaultValue
-}
-
-// GlobalRateLimiter creates a rate limiter with configurable requests per minute
-// Default: 500 requests per minute (can be overridden with RATE_LIMIT_GLOBAL env var)
-func GlobalRateLimiter() gin.HandlerFunc {
-	limit := getEnvInt("RATE_LIMIT_GLOBAL", 500)
+// GlobalRateLimiter creates a rate limiter allowing limit requests per minute
+// per client across all endpoints
+func GlobalRateLimiter(limit int) gin.HandlerFunc {
 	return RateLimitMiddleware(limit, 1*time.Minute)
 }
 
-// AuthRateLimiter creates a stricter rate limiter for auth endpoints
-// Default: 30 requests per minute (can be overridden with RATE_LIMIT_AUTH env var)
-func AuthRateLimiter() gin.HandlerFunc {
-	limit := getEnvInt("RATE_LIMIT_AUTH", 30)
+// AuthRateLimiter creates a stricter rate limiter for auth endpoints allowing
+// limit requests per minute per client
+func AuthRateLimiter(limit int) gin.HandlerFunc {
 	return RateLimitMiddleware(limit, 1*time.Minute)
 }
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(SecurityHeaders(SecurityOptions{HSTSMaxAge: 24 * time.Hour, ContentSecurityPolicy: "default-src 'none'"}))
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" || w.Header().Get("X-Frame-Options") != "DENY" ||
+		w.Header().Get("Content-Security-Policy") != "default-src 'none'" {
+		t.Errorf("Expected security headers, got %v", w.Header())
+	}
+	if hsts := w.Header().Get("Strict-Transport-Security"); hsts != "" {
+		t.Errorf("Expected no HSTS over plain HTTP, got %q", hsts)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if hsts := w.Header().Get("Strict-Transport-Security"); hsts != "max-age=86400; includeSubDomains" {
+		t.Errorf("Expected HSTS behind an HTTPS proxy, got %q", hsts)
+	}
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(HTTPSRedirect())
+	router.GET("/api/stocks/AAPL", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		proto    string
+		status   int
+		location string
+	}{
+		{"http", http.StatusPermanentRedirect, "https://api.example.com/api/stocks/AAPL?period=1M"},
+		{"https", http.StatusOK, ""},
+		{"", http.StatusOK, ""}, // Direct requests such as proxy health checks
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://api.example.com/api/stocks/AAPL?period=1M", nil)
+		if tt.proto != "" {
+			req.Header.Set("X-Forwarded-Proto", tt.proto)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status || w.Header().Get("Location") != tt.location {
+			t.Errorf("X-Forwarded-Proto %q: expected %d %q, got %d %q", tt.proto, tt.status, tt.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
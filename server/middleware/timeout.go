@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"stock-portfolio-tracker/apierror"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timeoutBaseKey holds the request context as it was before any deadline
+const timeoutBaseKey = "timeoutBaseContext"
+
+func init() {
+	apierror.Register(context.DeadlineExceeded, apierror.CodeTimeout, "The request took too long to complete")
+}
+
+// RequestTimeout sets a deadline on the request context. Queries and outbound
+// calls made with the request context are cancelled when it passes, and a
+// request that ends past its deadline without a response gets a 504. A
+// RequestTimeout on a route group replaces one applied to the whole router,
+// so routes can have a longer deadline than the default as well as a shorter
+// one.
+func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		base := c.Request.Context()
+		if original, ok := c.Get(timeoutBaseKey); ok {
+			base = original.(context.Context)
+		} else {
+			c.Set(timeoutBaseKey, base)
+		}
+
+		ctx, cancel := context.WithTimeout(base, timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		// The innermost timeout's context is the one handlers saw
+		if errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			c.Error(apierror.New(apierror.CodeTimeout, "The request took too long to complete"))
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"stock-portfolio-tracker/apierror"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler(true), RequestTimeout(20*time.Millisecond))
+
+	// Waits for the request context, as queries made with it do
+	wait := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(60 * time.Millisecond):
+			c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		}
+	}
+	router.GET("/slow", wait)
+	router.GET("/fast", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) })
+	router.Group("/backtest", RequestTimeout(time.Second)).GET("", wait)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/slow", http.StatusGatewayTimeout},
+		{"/fast", http.StatusOK},
+		{"/backtest", http.StatusOK}, // The group's longer deadline replaces the default
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("Expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusGatewayTimeout {
+				return
+			}
+
+			var resp apierror.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Error.Code != apierror.CodeTimeout {
+				t.Errorf("Expected %s, got %s", apierror.CodeTimeout, resp.Error.Code)
+			}
+		})
+	}
+}
@@ -2,12 +2,19 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
 	"log"
+	"reflect"
+	"regexp"
+	"stock-portfolio-tracker/apierror"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 )
 
 const (
@@ -57,12 +64,7 @@ func BodySizeLimitMiddleware() gin.HandlerFunc {
 		// Only check for requests with body
 		if c.Request.Method == "POST" || c.Request.Method == "PUT" || c.Request.Method == "PATCH" {
 			if c.Request.ContentLength > maxBodySize {
-				c.JSON(413, gin.H{
-					"error": gin.H{
-						"code":    "PAYLOAD_TOO_LARGE",
-						"message": "Request body too large. Maximum size is 1MB.",
-					},
-				})
+				c.Error(apierror.New(apierror.CodePayloadTooLarge, "Request body too large. Maximum size is 1MB."))
 				c.Abort()
 				return
 			}
@@ -70,23 +72,13 @@ func BodySizeLimitMiddleware() gin.HandlerFunc {
 			// Read and limit body
 			body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodySize+1))
 			if err != nil {
-				c.JSON(400, gin.H{
-					"error": gin.H{
-						"code":    "INVALID_REQUEST",
-						"message": "Failed to read request body.",
-					},
-				})
+				c.Error(apierror.New(apierror.CodeInvalidRequest, "Failed to read request body."))
 				c.Abort()
 				return
 			}
 			
 			if len(body) > maxBodySize {
-				c.JSON(413, gin.H{
-					"error": gin.H{
-						"code":    "PAYLOAD_TOO_LARGE",
-						"message": "Request body too large. Maximum size is 1MB.",
-					},
-				})
+				c.Error(apierror.New(apierror.CodePayloadTooLarge, "Request body too large. Maximum size is 1MB."))
 				c.Abort()
 				return
 			}
@@ -136,3 +128,115 @@ func InputSanitizationMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// FieldViolation describes one field of a request body that failed validation
+type FieldViolation struct {
+	Field string `json:"field"` // JSON path such as "trades[0].symbol"
+	Rule  string `json:"rule"`  // Validation tag, "unknown" or "type"
+	Param string `json:"param,omitempty"`
+}
+
+// ValidateJSON validates the JSON request body against the request type of a
+// route before the handler runs. Beyond what binding checks, unknown fields
+// are rejected at every level of nesting, so a misspelled field fails instead
+// of being silently dropped. Violations are reported as details of a
+// validation error. The body is left in place for the handler to bind.
+func ValidateJSON(schema interface{}) gin.HandlerFunc {
+	schemaType := reflect.TypeOf(schema)
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(apierror.New(apierror.CodeInvalidRequest, "Failed to read request body."))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		target := reflect.New(schemaType)
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(target.Interface()); err != nil {
+			violation, ok := decodeViolation(err)
+			if !ok {
+				c.Error(apierror.Wrap(err, apierror.CodeInvalidRequest, "Request body is not valid JSON"))
+			} else {
+				c.Error(apierror.Wrap(err, apierror.CodeValidation, "Invalid request body").WithDetails([]FieldViolation{violation}))
+			}
+			c.Abort()
+			return
+		}
+
+		if err := binding.Validator.ValidateStruct(target.Interface()); err != nil {
+			var fieldErrors validator.ValidationErrors
+			apiErr := apierror.Wrap(err, apierror.CodeValidation, "Invalid request body")
+			if errors.As(err, &fieldErrors) {
+				violations := make([]FieldViolation, 0, len(fieldErrors))
+				for _, fieldError := range fieldErrors {
+					violations = append(violations, FieldViolation{
+						Field: jsonPath(schemaType, fieldError.StructNamespace()),
+						Rule:  fieldError.Tag(),
+						Param: fieldError.Param(),
+					})
+				}
+				apiErr.WithDetails(violations)
+			}
+			c.Error(apiErr)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// jsonIndexPattern matches array indexes in a decoder field path
+var jsonIndexPattern = regexp.MustCompile(`\.(\d+)`)
+
+// decodeViolation describes a decoding error caused by a field, reporting
+// false for malformed JSON
+func decodeViolation(err error) (FieldViolation, bool) {
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		// The decoder reports array elements as "trades.0.shares"
+		field := jsonIndexPattern.ReplaceAllString(typeError.Field, "[$1]")
+		return FieldViolation{Field: field, Rule: "type", Param: typeError.Type.String()}, true
+	}
+	// encoding/json reports unknown fields only by message
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return FieldViolation{Field: strings.Trim(field, `"`), Rule: "unknown"}, true
+	}
+	return FieldViolation{}, false
+}
+
+// jsonPath converts a validator struct namespace such as
+// "TradeSimulationRequest.Trades[0].Symbol" to the JSON path of the field
+func jsonPath(root reflect.Type, namespace string) string {
+	segments := strings.Split(namespace, ".")[1:]
+	current := root
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		name, index := segment, ""
+		if i := strings.Index(segment, "["); i >= 0 {
+			name, index = segment[:i], segment[i:]
+		}
+
+		for current.Kind() == reflect.Ptr {
+			current = current.Elem()
+		}
+		field, ok := current.FieldByName(name)
+		if !ok {
+			path = append(path, segment)
+			continue
+		}
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+			name = tag
+		}
+		path = append(path, name+index)
+
+		current = field.Type
+		for current.Kind() == reflect.Ptr || current.Kind() == reflect.Slice || current.Kind() == reflect.Array || current.Kind() == reflect.Map {
+			current = current.Elem()
+		}
+	}
+	return strings.Join(path, ".")
+}
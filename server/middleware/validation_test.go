@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"stock-portfolio-tracker/apierror"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler(false))
+	router.POST("/simulate", ValidateJSON(models.TradeSimulationRequest{}), func(c *gin.Context) {
+		var req models.TradeSimulationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"trades": len(req.Trades)})
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		field  string
+		rule   string
+	}{
+		{"valid", `{"trades":[{"symbol":"AAPL","action":"buy","shares":1,"currency":"USD"}]}`, http.StatusOK, "", ""},
+		{"unknown nested field", `{"trades":[{"symbol":"AAPL","action":"buy","shares":1,"currency":"USD","shars":2}]}`, http.StatusBadRequest, "shars", "unknown"},
+		{"symbol too long", `{"trades":[{"symbol":"` + strings.Repeat("A", 40) + `","action":"buy","shares":1,"currency":"USD"}]}`, http.StatusBadRequest, "trades[0].symbol", "max"},
+		{"wrong type", `{"trades":[{"symbol":"AAPL","action":"buy","shares":"one","currency":"USD"}]}`, http.StatusBadRequest, "trades[0].shares", "type"},
+		{"malformed", `{"trades":`, http.StatusBadRequest, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/simulate", strings.NewReader(tt.body)))
+			if w.Code != tt.status {
+				t.Fatalf("Expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.field == "" {
+				return
+			}
+
+			var resp struct {
+				Error struct {
+					Code    apierror.Code    `json:"code"`
+					Details []FieldViolation `json:"details"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Error.Code != apierror.CodeValidation || len(resp.Error.Details) != 1 ||
+				resp.Error.Details[0].Field != tt.field || resp.Error.Details[0].Rule != tt.rule {
+				t.Errorf("Expected %s violation of %s, got %+v", tt.rule, tt.field, resp.Error)
+			}
+		})
+	}
+}
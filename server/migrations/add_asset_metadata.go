@@ -3,7 +3,6 @@ package migrations
 import (
 	"context"
 	"fmt"
-	"stock-portfolio-tracker/database"
 	"stock-portfolio-tracker/models"
 	"time"
 
@@ -12,16 +11,16 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// MigrateAssetMetadata migrates existing portfolios to include asset metadata
-func MigrateAssetMetadata() error {
+// migrateAssetMetadata gives every user a Default asset style and assigns it,
+// with the Stock asset class, to portfolios created before asset metadata
+// existed. It is safe to re-run on databases where the original one-off
+// migration already ran.
+func migrateAssetMetadata(ctx context.Context, db *mongo.Database) error {
 	fmt.Println("Starting asset metadata migration...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	usersCollection := database.Database.Collection("users")
-	assetStylesCollection := database.Database.Collection("asset_styles")
-	portfoliosCollection := database.Database.Collection("portfolios")
+	usersCollection := db.Collection("users")
+	assetStylesCollection := db.Collection("asset_styles")
+	portfoliosCollection := db.Collection("portfolios")
 
 	// Get all users
 	cursor, err := usersCollection.Find(ctx, bson.M{})
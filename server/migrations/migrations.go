@@ -0,0 +1,173 @@
+// Package migrations applies versioned schema and data changes to the
+// database. Each migration runs once per database: applied versions are
+// recorded in the schema_migrations collection.
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Collection records the applied migrations
+const Collection = "schema_migrations"
+
+// Migration is a schema or data change. Versions are never reused or
+// reordered once released; add new migrations to the end of All.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+}
+
+// All lists every migration in the order they are applied
+var All = []Migration{
+	{Version: 1, Name: "add_asset_metadata", Up: migrateAssetMetadata},
+}
+
+// Record is a migration's entry in the schema_migrations collection. A record
+// without AppliedAt belongs to a migration that is running, or that was
+// interrupted and needs its record removed by hand before it is retried.
+type Record struct {
+	Version   int        `bson:"_id"`
+	Name      string     `bson:"name"`
+	StartedAt time.Time  `bson:"started_at"`
+	AppliedAt *time.Time `bson:"applied_at,omitempty"`
+}
+
+// Status describes a migration and whether it has been applied
+type Status struct {
+	Migration
+	Record *Record // nil while pending
+}
+
+// validate checks that versions are positive, unique and ascending
+func validate(migrations []Migration) error {
+	previous := 0
+	for _, m := range migrations {
+		if m.Version <= previous {
+			return fmt.Errorf("migration %d (%s) must have a version greater than %d", m.Version, m.Name, previous)
+		}
+		if m.Name == "" || m.Up == nil {
+			return fmt.Errorf("migration %d must have a name and an Up function", m.Version)
+		}
+		previous = m.Version
+	}
+	return nil
+}
+
+// records loads the recorded migrations by version
+func records(ctx context.Context, db *mongo.Database) (map[int]Record, error) {
+	cursor, err := db.Collection(Collection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch applied migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var list []Record
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode applied migrations: %w", err)
+	}
+
+	byVersion := make(map[int]Record, len(list))
+	for _, record := range list {
+		byVersion[record.Version] = record
+	}
+	return byVersion, nil
+}
+
+// pending returns the migrations without a record, in order
+func pending(migrations []Migration, applied map[int]Record) []Migration {
+	var result []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// Up applies all pending migrations and returns how many ran
+func Up(ctx context.Context, db *mongo.Database) (int, error) {
+	return run(ctx, db, All)
+}
+
+// run applies the pending migrations in order, stopping at the first failure.
+// Each migration is claimed by inserting its record before it runs, so
+// instances starting at the same time never apply a migration twice.
+func run(ctx context.Context, db *mongo.Database, migrations []Migration) (int, error) {
+	if err := validate(migrations); err != nil {
+		return 0, err
+	}
+
+	applied, err := records(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	collection := db.Collection(Collection)
+	count := 0
+	for _, m := range pending(migrations, applied) {
+		record := Record{Version: m.Version, Name: m.Name, StartedAt: time.Now()}
+		if _, err := collection.InsertOne(ctx, record); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				// Another instance claimed it first
+				fmt.Printf("[Migrations] %d_%s is being applied elsewhere, skipping\n", m.Version, m.Name)
+				continue
+			}
+			return count, fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+		}
+
+		fmt.Printf("[Migrations] Applying %d_%s\n", m.Version, m.Name)
+		if err := m.Up(ctx, db); err != nil {
+			// Release the claim so the migration is retried next time
+			if _, deleteErr := collection.DeleteOne(ctx, bson.M{"_id": m.Version}); deleteErr != nil {
+				fmt.Printf("[Migrations] ERROR: Failed to release migration %d: %v\n", m.Version, deleteErr)
+			}
+			return count, fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": m.Version}, bson.M{
+			"$set": bson.M{"applied_at": time.Now()},
+		})
+		if err != nil {
+			return count, fmt.Errorf("failed to mark migration %d applied: %w", m.Version, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// List returns every known migration with its record, plus records of
+// migrations this build doesn't know about, ordered by version
+func List(ctx context.Context, db *mongo.Database) ([]Status, error) {
+	applied, err := records(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(All))
+	for _, m := range All {
+		status := Status{Migration: m}
+		if record, ok := applied[m.Version]; ok {
+			status.Record = &record
+			delete(applied, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	// Records from newer builds are listed so a rollback is noticed
+	unknown := make([]Status, 0, len(applied))
+	for _, record := range applied {
+		record := record
+		unknown = append(unknown, Status{Migration: Migration{Version: record.Version, Name: record.Name}, Record: &record})
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Version < unknown[j].Version })
+
+	return append(statuses, unknown...), nil
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func noop(ctx context.Context, db *mongo.Database) error { return nil }
+
+func TestRegisteredMigrationsAreValid(t *testing.T) {
+	if err := validate(All); err != nil {
+		t.Fatalf("Registered migrations are invalid: %v", err)
+	}
+}
+
+func TestValidateRejectsMisorderedMigrations(t *testing.T) {
+	cases := map[string][]Migration{
+		"duplicate":  {{Version: 1, Name: "a", Up: noop}, {Version: 1, Name: "b", Up: noop}},
+		"descending": {{Version: 2, Name: "a", Up: noop}, {Version: 1, Name: "b", Up: noop}},
+		"zero":       {{Version: 0, Name: "a", Up: noop}},
+		"no name":    {{Version: 1, Up: noop}},
+		"no up":      {{Version: 1, Name: "a"}},
+	}
+	for name, migrations := range cases {
+		if err := validate(migrations); err == nil {
+			t.Errorf("%s: expected validation to fail", name)
+		}
+	}
+}
+
+func TestPendingSkipsRecordedMigrations(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "first", Up: noop},
+		{Version: 2, Name: "second", Up: noop},
+		{Version: 3, Name: "third", Up: noop},
+	}
+	applied := map[int]Record{1: {Version: 1}, 3: {Version: 3}}
+
+	result := pending(migrations, applied)
+	names := make([]string, len(result))
+	for i, m := range result {
+		names[i] = m.Name
+	}
+	if strings.Join(names, ",") != "second" {
+		t.Errorf("Expected only the second migration to be pending, got %v", names)
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Account link statuses
+const (
+	AccountLinkPending  = "pending"
+	AccountLinkAccepted = "accepted"
+)
+
+// AccountLink grants a viewer read-only access to an owner's portfolio data.
+// The link takes effect once the viewer accepts the invite.
+type AccountLink struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OwnerID     primitive.ObjectID `bson:"owner_id" json:"ownerId"`
+	OwnerEmail  string             `bson:"owner_email" json:"ownerEmail"`
+	ViewerID    primitive.ObjectID `bson:"viewer_id" json:"viewerId"`
+	ViewerEmail string             `bson:"viewer_email" json:"viewerEmail"`
+	Status      string             `bson:"status" json:"status"`
+	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
+	AcceptedAt  *time.Time         `bson:"accepted_at,omitempty" json:"acceptedAt,omitempty"`
+}
+
+// AccountInviteRequest represents the request body for inviting a viewer
+type AccountInviteRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AssetSubclass is a user-defined second level under one of the fixed asset
+// classes, such as US Large Cap under Stock
+type AssetSubclass struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"userId"`
+	AssetClass string             `bson:"asset_class" json:"assetClass"`
+	Name       string             `bson:"name" json:"name"`
+	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// AssetSubclassRequest represents the request body for creating an asset subclass
+type AssetSubclassRequest struct {
+	AssetClass string `json:"assetClass" binding:"required,oneof=Stock ETF Bond 'Cash and Equivalents' Options Crypto"`
+	Name       string `json:"name" binding:"required,max=50"`
+}
+
+// RenameAssetSubclassRequest represents the request body for renaming an asset subclass
+type RenameAssetSubclassRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+// AssetClassNode is one asset class and its subclasses in a user's taxonomy
+type AssetClassNode struct {
+	Name       string          `json:"name"`
+	Subclasses []AssetSubclass `json:"subclasses"`
+}
@@ -11,13 +11,19 @@ type AssetStyle struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID    primitive.ObjectID `bson:"user_id" json:"userId" binding:"required"`
 	Name      string             `bson:"name" json:"name" binding:"required,max=50"`
+	Color     string             `bson:"color,omitempty" json:"color,omitempty"` // Hex color such as #4f46e5
+	Icon      string             `bson:"icon,omitempty" json:"icon,omitempty"`   // Icon name such as trending-up
 	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updatedAt"`
 }
 
-// AssetStyleRequest represents the request body for creating/updating an asset style
+// AssetStyleRequest represents the request body for creating/updating an
+// asset style. Updates replace the color and icon, so leaving them out
+// clears them.
 type AssetStyleRequest struct {
-	Name string `json:"name" binding:"required,max=50"`
+	Name  string `json:"name" binding:"required,max=50"`
+	Color string `json:"color" binding:"omitempty,max=7"`
+	Icon  string `json:"icon" binding:"omitempty,max=32"`
 }
 
 // AssetStyleResponse represents the response with usage count
@@ -25,6 +31,8 @@ type AssetStyleResponse struct {
 	ID         string    `json:"id"`
 	UserID     string    `json:"userId"`
 	Name       string    `json:"name"`
+	Color      string    `json:"color,omitempty"`
+	Icon       string    `json:"icon,omitempty"`
 	UsageCount int64     `json:"usageCount"`
 	CreatedAt  time.Time `json:"createdAt"`
 	UpdatedAt  time.Time `json:"updatedAt"`
@@ -0,0 +1,13 @@
+package models
+
+// BacktestJobRequest represents the request body for submitting an asynchronous backtest
+type BacktestJobRequest struct {
+	StartDate string `json:"startDate" binding:"required"`
+	EndDate   string `json:"endDate" binding:"required"`
+	Currency  string `json:"currency"`
+	Benchmark string `json:"benchmark"`
+
+	// Optional trading costs applied to simulated trades
+	Commission  float64 `json:"commission" binding:"gte=0"`
+	SlippageBps float64 `json:"slippageBps" binding:"gte=0"`
+}
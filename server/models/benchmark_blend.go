@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BenchmarkComponent represents one weighted symbol of a benchmark blend
+type BenchmarkComponent struct {
+	Symbol string  `bson:"symbol" json:"symbol" binding:"required,max=20"`
+	Weight float64 `bson:"weight" json:"weight" binding:"required,gt=0,lte=100"` // Percentage of the blend
+}
+
+// BenchmarkBlend represents a user-defined composite benchmark such as 60% ^GSPC + 40% AGG
+type BenchmarkBlend struct {
+	ID         primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID   `bson:"user_id" json:"userId"`
+	Name       string               `bson:"name" json:"name"`
+	Components []BenchmarkComponent `bson:"components" json:"components"`
+	CreatedAt  time.Time            `bson:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time            `bson:"updated_at" json:"updatedAt"`
+}
+
+// BenchmarkBlendRequest represents the request body for saving a benchmark blend
+type BenchmarkBlendRequest struct {
+	Name       string               `json:"name" binding:"required,max=50"`
+	Components []BenchmarkComponent `json:"components" binding:"required,min=1,max=10,dive"`
+}
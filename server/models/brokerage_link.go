@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Brokerage aggregators. Transactions synced through one carry its name as
+// their broker.
+const (
+	BrokerPlaid     = "plaid"
+	BrokerSnapTrade = "snaptrade"
+)
+
+// Crypto exchanges, linked with read-only API keys. Transactions synced from
+// one carry its name as their broker.
+const (
+	BrokerBinance  = "binance"
+	BrokerCoinbase = "coinbase"
+)
+
+// Brokerage link statuses
+const (
+	BrokerageLinkPending = "pending" // The user hasn't finished the aggregator's flow
+	BrokerageLinkActive  = "active"
+	BrokerageLinkError   = "error" // The last sync failed; the next one retries
+)
+
+// BrokerageLink connects the user's accounts at a brokerage through an
+// aggregator, so their positions and transactions are synced periodically
+type BrokerageLink struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"userId"`
+	Provider    string             `bson:"provider" json:"provider"`
+	Institution string             `bson:"institution,omitempty" json:"institution,omitempty"`
+	// Credential is the aggregator's access to the accounts: Plaid's access
+	// token, SnapTrade's user secret or the exchange API key's secret. It is
+	// encrypted at rest.
+	Credential string `bson:"credential,omitempty" json:"-"`
+	// APIKey is the exchange API key, for exchange links, encrypted at rest
+	APIKey string `bson:"api_key,omitempty" json:"-"`
+	// CredentialSealed is set while Credential and APIKey hold their
+	// encrypted form. Links stored before credentials were encrypted are
+	// sealed when they are next updated.
+	CredentialSealed bool `bson:"credential_sealed,omitempty" json:"-"`
+	// Assets are the exchange assets whose trades are fetched, kept for
+	// exchanges that only list trades per market so assets sold out since
+	// the previous sync aren't missed
+	Assets []string `bson:"assets,omitempty" json:"-"`
+	Status string   `bson:"status" json:"status"`
+	// SyncedThrough is when transactions were last fetched up to
+	SyncedThrough *time.Time           `bson:"synced_through,omitempty" json:"syncedThrough,omitempty"`
+	LastSyncedAt  *time.Time           `bson:"last_synced_at,omitempty" json:"lastSyncedAt,omitempty"`
+	LastSync      *BrokerageSyncCounts `bson:"last_sync,omitempty" json:"lastSync,omitempty"`
+	LastError     string               `bson:"last_error,omitempty" json:"lastError,omitempty"`
+	CreatedAt     time.Time            `bson:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time            `bson:"updated_at" json:"updatedAt"`
+}
+
+// BrokerageSyncCounts summarizes the outcome of a sync
+type BrokerageSyncCounts struct {
+	Imported int `bson:"imported" json:"imported"`
+	// Duplicates are trades already recorded, by an earlier sync or by hand
+	Duplicates int `bson:"duplicates" json:"duplicates"`
+	Failed     int `bson:"failed" json:"failed"`
+	// Discrepancies are the broker's positions whose shares differ from the
+	// tracked shares after the sync
+	Discrepancies int `bson:"discrepancies" json:"discrepancies"`
+}
+
+// BrokerageLinkStartRequest represents the request body for starting to link a brokerage
+type BrokerageLinkStartRequest struct {
+	Provider string `json:"provider" binding:"required,oneof=plaid snaptrade"`
+}
+
+// BrokerageLinkCompleteRequest represents the request body for finishing a
+// link with what the aggregator's flow returned
+type BrokerageLinkCompleteRequest struct {
+	// PublicToken is Plaid Link's public token; SnapTrade needs none
+	PublicToken string `json:"publicToken" binding:"max=512"`
+	Institution string `json:"institution" binding:"max=100"`
+}
+
+// ExchangeLinkRequest represents the request body for linking a crypto
+// exchange with an API key. The key must be read-only.
+type ExchangeLinkRequest struct {
+	Provider string `json:"provider" binding:"required,oneof=binance coinbase"`
+	// APIKey is Binance's API key or Coinbase's API key name
+	APIKey string `json:"apiKey" binding:"required,max=256"`
+	// APISecret is Binance's secret key or Coinbase's EC private key
+	APISecret string `json:"apiSecret" binding:"required,max=4096"`
+	Label     string `json:"label" binding:"max=100"`
+}
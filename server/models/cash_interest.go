@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CashInterestTag marks the automatic transactions that record accrued interest
+const CashInterestTag = "interest"
+
+// CashInterestRate represents the interest a user earns on one cash balance.
+// Interest compounds daily and is recorded as buys of the cash symbol.
+type CashInterestRate struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID primitive.ObjectID `bson:"user_id" json:"userId"`
+	Symbol string             `bson:"symbol" json:"symbol"`
+	// APY is the annual percentage yield, e.g. 4.5 for 4.5%
+	APY float64 `bson:"apy" json:"apy"`
+	// AccruedThrough is the UTC midnight up to which interest has been recorded
+	AccruedThrough time.Time `bson:"accrued_through" json:"accruedThrough"`
+	CreatedAt      time.Time `bson:"created_at" json:"createdAt"`
+	UpdatedAt      time.Time `bson:"updated_at" json:"updatedAt"`
+}
+
+// CashInterestRequest represents the request body for setting a cash balance's rate
+type CashInterestRequest struct {
+	APY float64 `json:"apy" binding:"gte=0,lte=100"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CustomBenchmark represents a symbol a user saved to compare against, such
+// as a fund that is not in the built-in benchmark catalog
+type CustomBenchmark struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"userId"`
+	Symbol    string             `bson:"symbol" json:"symbol"`
+	Name      string             `bson:"name" json:"name"`
+	Region    string             `bson:"region" json:"region"`
+	Currency  string             `bson:"currency" json:"currency"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// CustomBenchmarkRequest represents the request body for saving a custom benchmark
+type CustomBenchmarkRequest struct {
+	Symbol string `json:"symbol" binding:"required,max=20"`
+	Name   string `json:"name" binding:"max=50"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FeatureFlag gates a feature that is being rolled out. A user gets the
+// feature when the flag is enabled and the user is listed or falls in the
+// rollout percentage.
+type FeatureFlag struct {
+	Key         string               `bson:"_id" json:"key"`
+	Description string               `bson:"description,omitempty" json:"description,omitempty"`
+	Enabled     bool                 `bson:"enabled" json:"enabled"`                 // Off turns the feature off for everyone
+	Percentage  int                  `bson:"percentage" json:"percentage"`           // Share of users, 0 to 100
+	Users       []primitive.ObjectID `bson:"users,omitempty" json:"users,omitempty"` // Users who always get the feature
+	UpdatedAt   time.Time            `bson:"updated_at" json:"updatedAt"`
+}
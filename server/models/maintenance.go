@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// MaintenanceMode is the read-only maintenance switch shared by every server
+// instance. While it is enabled, requests that could change data are
+// rejected with 503 and reads keep working.
+type MaintenanceMode struct {
+	Enabled    bool       `bson:"enabled" json:"enabled"`
+	Message    string     `bson:"message,omitempty" json:"message,omitempty"`
+	RetryAfter int        `bson:"retry_after" json:"retryAfter"` // Seconds, announced to clients
+	Since      *time.Time `bson:"since,omitempty" json:"since,omitempty"`
+	UpdatedAt  time.Time  `bson:"updated_at" json:"updatedAt"`
+}
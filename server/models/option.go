@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Instrument types
+const (
+	InstrumentStock  = "stock"
+	InstrumentOption = "option"
+)
+
+// Option rights
+const (
+	OptionCall = "call"
+	OptionPut  = "put"
+)
+
+// DefaultOptionMultiplier is the number of underlying shares in a standard
+// equity option contract
+const DefaultOptionMultiplier = 100
+
+// optionSymbolPattern matches OCC option symbols such as AAPL250117C00150000:
+// the underlying, the expiry as YYMMDD, C or P, and the strike times 1000
+var optionSymbolPattern = regexp.MustCompile(`^([A-Z]{1,6})(\d{6})([CP])(\d{8})$`)
+
+// underlyingPattern matches the underlying tickers OCC symbols support
+var underlyingPattern = regexp.MustCompile(`^[A-Z]{1,6}$`)
+
+// OptionContract describes an option position's contract
+type OptionContract struct {
+	Underlying string    `bson:"underlying" json:"underlying"`
+	Expiry     time.Time `bson:"expiry" json:"expiry"`
+	Strike     float64   `bson:"strike" json:"strike"`
+	Right      string    `bson:"right" json:"right"`
+	Multiplier float64   `bson:"multiplier" json:"multiplier"`
+}
+
+// Symbol returns the contract's OCC symbol
+func (c OptionContract) Symbol() string {
+	right := "C"
+	if c.Right == OptionPut {
+		right = "P"
+	}
+	return fmt.Sprintf("%s%s%s%08d", strings.ToUpper(c.Underlying), c.Expiry.Format("060102"), right, int64(c.Strike*1000+0.5))
+}
+
+// Expired reports whether the contract has expired at the given time. A
+// contract can be traded until the end of its expiry day.
+func (c OptionContract) Expired(at time.Time) bool {
+	return !at.Before(c.Expiry.AddDate(0, 0, 1))
+}
+
+// IntrinsicValue returns the per-share value of exercising the contract at
+// the underlying price
+func (c OptionContract) IntrinsicValue(underlyingPrice float64) float64 {
+	value := underlyingPrice - c.Strike
+	if c.Right == OptionPut {
+		value = -value
+	}
+	if value < 0 {
+		return 0
+	}
+	return value
+}
+
+// Validate checks that the contract is complete
+func (c OptionContract) Validate() error {
+	switch {
+	case !underlyingPattern.MatchString(strings.ToUpper(c.Underlying)):
+		return fmt.Errorf("option underlying must be a US ticker of 1 to 6 letters")
+	case c.Expiry.IsZero():
+		return fmt.Errorf("option expiry is required")
+	case c.Strike <= 0:
+		return fmt.Errorf("option strike must be greater than zero")
+	case c.Right != OptionCall && c.Right != OptionPut:
+		return fmt.Errorf("option right must be 'call' or 'put'")
+	case c.Multiplier <= 0:
+		return fmt.Errorf("option multiplier must be greater than zero")
+	}
+	return nil
+}
+
+// ParseOptionSymbol parses an OCC option symbol. The multiplier is not part
+// of the symbol and is assumed to be DefaultOptionMultiplier.
+func ParseOptionSymbol(symbol string) (OptionContract, bool) {
+	match := optionSymbolPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(symbol)))
+	if match == nil {
+		return OptionContract{}, false
+	}
+	expiry, err := time.Parse("060102", match[2])
+	if err != nil {
+		return OptionContract{}, false
+	}
+	strike, err := strconv.ParseInt(match[4], 10, 64)
+	if err != nil {
+		return OptionContract{}, false
+	}
+
+	right := OptionCall
+	if match[3] == "P" {
+		right = OptionPut
+	}
+	return OptionContract{
+		Underlying: match[1],
+		Expiry:     expiry,
+		Strike:     float64(strike) / 1000,
+		Right:      right,
+		Multiplier: DefaultOptionMultiplier,
+	}, true
+}
+
+// OptionRequest describes the contract of an option transaction. The
+// transaction's symbol is the underlying.
+type OptionRequest struct {
+	Expiry     time.Time `json:"expiry" binding:"required"`
+	Strike     float64   `json:"strike" binding:"required,gt=0"`
+	Right      string    `json:"right" binding:"required,oneof=call put"`
+	Multiplier float64   `json:"multiplier" binding:"omitempty,gt=0"`
+}
+
+// Contract returns the option contract on the underlying, with the default
+// multiplier when none is given
+func (r OptionRequest) Contract(underlying string) *OptionContract {
+	multiplier := r.Multiplier
+	if multiplier == 0 {
+		multiplier = DefaultOptionMultiplier
+	}
+	return &OptionContract{
+		Underlying: strings.ToUpper(strings.TrimSpace(underlying)),
+		Expiry:     time.Date(r.Expiry.Year(), r.Expiry.Month(), r.Expiry.Day(), 0, 0, 0, 0, time.UTC),
+		Strike:     r.Strike,
+		Right:      r.Right,
+		Multiplier: multiplier,
+	}
+}
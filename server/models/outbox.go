@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Outbox message statuses
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxFailed    = "failed" // gave up after the last attempt
+)
+
+// OutboxMessage is a notification written in the same transaction as the
+// change that caused it and delivered afterwards by the outbox worker, so a
+// crash between the two can neither lose nor invent a notification
+type OutboxMessage struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"userId"`
+	Source        string             `bson:"source" json:"source"` // Job that queued it, e.g. "stops"
+	Subject       string             `bson:"subject" json:"subject"`
+	Text          string             `bson:"text" json:"text"`
+	HTML          string             `bson:"html,omitempty" json:"html,omitempty"`
+	Status        string             `bson:"status" json:"status"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at" json:"nextAttemptAt"`
+	LastError     string             `bson:"last_error,omitempty" json:"lastError,omitempty"`
+	// Channels records the attempts on each notification channel, so a retry
+	// only sends through the channels that have not delivered it yet
+	Channels    []OutboxChannelStatus `bson:"channels,omitempty" json:"channels,omitempty"`
+	CreatedAt   time.Time             `bson:"created_at" json:"createdAt"`
+	DeliveredAt *time.Time            `bson:"delivered_at,omitempty" json:"deliveredAt,omitempty"`
+}
+
+// OutboxChannelStatus is how delivery of an outbox message went on one
+// notification channel
+type OutboxChannelStatus struct {
+	Channel     string     `bson:"channel" json:"channel"`
+	DeliveredAt *time.Time `bson:"delivered_at,omitempty" json:"deliveredAt,omitempty"`
+	LastError   string     `bson:"last_error,omitempty" json:"lastError,omitempty"`
+}
+
+// ChannelStatus returns the status of the named channel, adding it when the
+// message was never tried on it
+func (m *OutboxMessage) ChannelStatus(channel string) *OutboxChannelStatus {
+	for i := range m.Channels {
+		if m.Channels[i].Channel == channel {
+			return &m.Channels[i]
+		}
+	}
+	m.Channels = append(m.Channels, OutboxChannelStatus{Channel: channel})
+	return &m.Channels[len(m.Channels)-1]
+}
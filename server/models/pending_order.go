@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Pending order types. A limit order triggers when the price reaches the
+// trigger price or better (at or below for a buy, at or above for a sell); a
+// stop order triggers when the price moves through it the other way.
+const (
+	PendingOrderLimit = "limit"
+	PendingOrderStop  = "stop"
+)
+
+// What happens when a pending order triggers
+const (
+	PendingOrderExecute = "execute" // record the trade as a transaction
+	PendingOrderNotify  = "notify"  // only notify the user
+)
+
+// Pending order statuses
+const (
+	PendingOrderOpen      = "open"
+	PendingOrderExecuted  = "executed"
+	PendingOrderTriggered = "triggered" // final for notify orders, claimed for execute orders
+	PendingOrderFailed    = "failed"    // triggered but the transaction was rejected
+	PendingOrderCancelled = "cancelled"
+	PendingOrderExpired   = "expired"
+)
+
+// PendingOrder represents an intended trade that is recorded or reported once
+// the symbol's price reaches the trigger price
+type PendingOrder struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID         primitive.ObjectID  `bson:"user_id" json:"userId"`
+	Symbol         string              `bson:"symbol" json:"symbol"`
+	Action         string              `bson:"action" json:"action"`
+	OrderType      string              `bson:"order_type" json:"orderType"`
+	TriggerPrice   float64             `bson:"trigger_price" json:"triggerPrice"`
+	Shares         float64             `bson:"shares" json:"shares"`
+	Currency       string              `bson:"currency" json:"currency"`
+	Fees           float64             `bson:"fees" json:"fees"`
+	Note           string              `bson:"note,omitempty" json:"note,omitempty"`
+	OnTrigger      string              `bson:"on_trigger" json:"onTrigger"`
+	Status         string              `bson:"status" json:"status"`
+	ExpiresAt      *time.Time          `bson:"expires_at,omitempty" json:"expiresAt,omitempty"`
+	TriggeredAt    *time.Time          `bson:"triggered_at,omitempty" json:"triggeredAt,omitempty"`
+	TriggeredPrice float64             `bson:"triggered_price,omitempty" json:"triggeredPrice,omitempty"`
+	TransactionID  *primitive.ObjectID `bson:"transaction_id,omitempty" json:"transactionId,omitempty"`
+	Error          string              `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt      time.Time           `bson:"created_at" json:"createdAt"`
+	UpdatedAt      time.Time           `bson:"updated_at" json:"updatedAt"`
+}
+
+// PendingOrderRequest represents the request body for creating a pending order
+type PendingOrderRequest struct {
+	Symbol        string  `json:"symbol" binding:"required,max=20"`
+	Action        string  `json:"action" binding:"required,oneof=buy sell"`
+	OrderType     string  `json:"orderType" binding:"required,oneof=limit stop"`
+	TriggerPrice  float64 `json:"triggerPrice" binding:"required,gt=0"`
+	Shares        float64 `json:"shares" binding:"required,gt=0"`
+	Currency      string  `json:"currency" binding:"required,oneof=USD RMB CNY"`
+	Fees          float64 `json:"fees" binding:"gte=0"`
+	Note          string  `json:"note" binding:"max=1000"`
+	OnTrigger     string  `json:"onTrigger" binding:"omitempty,oneof=execute notify"`
+	ExpiresInDays int     `json:"expiresInDays" binding:"omitempty,min=1,max=365"`
+}
@@ -8,17 +8,58 @@ import (
 
 // Portfolio represents a user's stock portfolio entry
 type Portfolio struct {
-	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
-	UserID       primitive.ObjectID  `bson:"user_id" json:"userId" binding:"required"`
-	Symbol       string              `bson:"symbol" json:"symbol" binding:"required"`
-	AssetStyleID *primitive.ObjectID `bson:"asset_style_id,omitempty" json:"assetStyleId"` // Reference to AssetStyle
-	AssetClass   string              `bson:"asset_class,omitempty" json:"assetClass"`      // Stock, ETF, Bond, Cash and Equivalents
-	CreatedAt    time.Time           `bson:"created_at" json:"createdAt"`
-	UpdatedAt    time.Time           `bson:"updated_at" json:"updatedAt"`
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID          primitive.ObjectID  `bson:"user_id" json:"userId" binding:"required"`
+	Symbol          string              `bson:"symbol" json:"symbol" binding:"required"`
+	AssetStyleID    *primitive.ObjectID `bson:"asset_style_id,omitempty" json:"assetStyleId"`                 // Reference to AssetStyle
+	AssetClass      string              `bson:"asset_class,omitempty" json:"assetClass"`                      // Stock, ETF, Bond, Cash and Equivalents, Options, Crypto
+	AssetSubclassID *primitive.ObjectID `bson:"asset_subclass_id,omitempty" json:"assetSubclassId,omitempty"` // Reference to an AssetSubclass of AssetClass
+	Tags            []string            `bson:"tags,omitempty" json:"tags,omitempty"`                         // Labels such as "speculative" or "horizon:retirement"
+	Option          *OptionContract     `bson:"option,omitempty" json:"option,omitempty"`                     // Set for option positions
+	Stop            *PositionStop       `bson:"stop,omitempty" json:"stop,omitempty"`                         // Set when the position is watched for a stop
+	CreatedAt       time.Time           `bson:"created_at" json:"createdAt"`
+	UpdatedAt       time.Time           `bson:"updated_at" json:"updatedAt"`
 }
 
 // UpdatePortfolioMetadataRequest represents the request body for updating portfolio metadata
 type UpdatePortfolioMetadataRequest struct {
-	AssetStyleID string `json:"assetStyleId" binding:"required"`
-	AssetClass   string `json:"assetClass" binding:"required,oneof=Stock ETF Bond 'Cash and Equivalents'"`
+	AssetStyleID    string `json:"assetStyleId" binding:"required"`
+	AssetClass      string `json:"assetClass" binding:"required,oneof=Stock ETF Bond 'Cash and Equivalents' Options Crypto"`
+	AssetSubclassID string `json:"assetSubclassId"` // Optional subclass of AssetClass
+}
+
+// PortfolioTagsRequest represents the request body for replacing a portfolio's
+// tags. A tag "name:value" files the holding under value when the dashboard is
+// grouped by tag:name.
+type PortfolioTagsRequest struct {
+	Tags []string `json:"tags" binding:"max=20,dive,max=50"`
+}
+
+// What triggered a position stop
+const (
+	StopTriggerPrice    = "stop"
+	StopTriggerTrailing = "trailing"
+)
+
+// PositionStop represents a stop-loss watch on a position. Prices are in the
+// symbol's trading currency. The stop triggers once, notifying the user, when
+// the price falls to StopPrice or TrailingPercent below the peak, and stays
+// triggered until it is set again.
+type PositionStop struct {
+	StopPrice       float64 `bson:"stop_price,omitempty" json:"stopPrice,omitempty"`
+	TrailingPercent float64 `bson:"trailing_percent,omitempty" json:"trailingPercent,omitempty"`
+	// PeakPrice is the highest close or quote since the position was opened
+	PeakPrice      float64    `bson:"peak_price" json:"peakPrice"`
+	PeakAt         time.Time  `bson:"peak_at" json:"peakAt"`
+	TriggeredAt    *time.Time `bson:"triggered_at,omitempty" json:"triggeredAt,omitempty"`
+	TriggeredBy    string     `bson:"triggered_by,omitempty" json:"triggeredBy,omitempty"`
+	TriggeredPrice float64    `bson:"triggered_price,omitempty" json:"triggeredPrice,omitempty"`
+	UpdatedAt      time.Time  `bson:"updated_at" json:"updatedAt"`
+}
+
+// PositionStopRequest represents the request body for setting a position's stop.
+// At least one of the levels must be set.
+type PositionStopRequest struct {
+	StopPrice       float64 `json:"stopPrice" binding:"gte=0"`
+	TrailingPercent float64 `json:"trailingPercent" binding:"gte=0,lt=100"`
 }
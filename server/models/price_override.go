@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PriceOverride is a price the user set for a symbol the providers misprice
+// or don't quote, such as an illiquid fund. From EffectiveDate on it is used
+// instead of the providers' price.
+type PriceOverride struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID primitive.ObjectID `bson:"user_id" json:"userId"`
+	Symbol string             `bson:"symbol" json:"symbol"`
+	Price  float64            `bson:"price" json:"price"`
+	// Currency is the symbol's trading currency, which Price is in
+	Currency      string    `bson:"currency" json:"currency"`
+	EffectiveDate time.Time `bson:"effective_date" json:"effectiveDate"`
+	Note          string    `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt     time.Time `bson:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time `bson:"updated_at" json:"updatedAt"`
+}
+
+// PriceOverrideRequest represents the request body for setting a symbol's
+// manual price, in its trading currency. The effective date defaults to today.
+type PriceOverrideRequest struct {
+	Price         float64   `json:"price" binding:"required,gt=0"`
+	EffectiveDate time.Time `json:"effectiveDate"`
+	Note          string    `json:"note" binding:"max=500"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// BrokerPosition is one position of a broker's position snapshot
+type BrokerPosition struct {
+	Symbol string  `json:"symbol" binding:"required,max=32"`
+	Shares float64 `json:"shares" binding:"gte=0"`
+	Price  float64 `json:"price" binding:"gte=0"` // Optional price used for suggested adjustments
+}
+
+// ReconcileRequest represents the request body for reconciling holdings with a broker
+type ReconcileRequest struct {
+	Positions []BrokerPosition `json:"positions" binding:"required,max=1000,dive"`
+	AsOf      *time.Time       `json:"asOf"` // Snapshot date, defaults to now
+}
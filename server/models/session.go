@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session represents one login of a user on a device. Every token issued at
+// login carries its session's ID, so revoking the session ends the token's
+// access before it expires.
+type Session struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"userId"`
+	UserAgent  string             `bson:"user_agent" json:"userAgent"`
+	IP         string             `bson:"ip" json:"ip"`
+	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+	LastSeenAt time.Time          `bson:"last_seen_at" json:"lastSeenAt"`
+	// ExpiresAt is when the session's token expires; expired sessions are
+	// removed by a TTL index
+	ExpiresAt time.Time  `bson:"expires_at" json:"expiresAt"`
+	RevokedAt *time.Time `bson:"revoked_at,omitempty" json:"-"`
+	// Current marks the session making the request
+	Current bool `bson:"-" json:"current"`
+}
+
+// SessionDevice describes the client a session is used from
+type SessionDevice struct {
+	UserAgent string
+	IP        string
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareLink represents a revocable, read-only link to a user's dashboard.
+// Only a hash of the token is stored; the token itself is returned once on creation.
+type ShareLink struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"userId"`
+	TokenHash      string             `bson:"token_hash" json:"-"`
+	Label          string             `bson:"label" json:"label"`
+	ShowValues     bool               `bson:"show_values" json:"showValues"` // false exposes percentages only
+	Currency       string             `bson:"currency" json:"currency"`
+	ExpiresAt      *time.Time         `bson:"expires_at,omitempty" json:"expiresAt,omitempty"`
+	RevokedAt      *time.Time         `bson:"revoked_at,omitempty" json:"revokedAt,omitempty"`
+	LastAccessedAt *time.Time         `bson:"last_accessed_at,omitempty" json:"lastAccessedAt,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// CreateShareLinkRequest represents the request body for creating a share link
+type CreateShareLinkRequest struct {
+	Label         string `json:"label" binding:"max=100"`
+	ShowValues    bool   `json:"showValues"`
+	Currency      string `json:"currency" binding:"omitempty,oneof=USD RMB CNY"`
+	ExpiresInDays int    `json:"expiresInDays" binding:"omitempty,min=1,max=365"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sheets link statuses
+const (
+	SheetsLinkPending = "pending" // The user hasn't finished Google's consent screen
+	SheetsLinkActive  = "active"
+	SheetsLinkError   = "error" // The last sync failed; the next one retries
+)
+
+// SheetsLink connects a user's Google Sheet, which their holdings and daily
+// performance are pushed to periodically. A user links at most one sheet.
+type SheetsLink struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"userId"`
+	SpreadsheetID string             `bson:"spreadsheet_id" json:"spreadsheetId"`
+	// Currency is the display currency amounts are written in
+	Currency string `bson:"currency" json:"currency"`
+	// State ties Google's consent redirect to the pending link
+	State string `bson:"state,omitempty" json:"-"`
+	// AccessToken and RefreshToken are the Google OAuth tokens, encrypted
+	AccessToken  string     `bson:"access_token,omitempty" json:"-"`
+	RefreshToken string     `bson:"refresh_token,omitempty" json:"-"`
+	TokenExpiry  time.Time  `bson:"token_expiry,omitempty" json:"-"`
+	Status       string     `bson:"status" json:"status"`
+	LastSyncedAt *time.Time `bson:"last_synced_at,omitempty" json:"lastSyncedAt,omitempty"`
+	// LastPerformanceDate is the day of the latest performance row appended,
+	// as YYYY-MM-DD, so each day is written once
+	LastPerformanceDate string    `bson:"last_performance_date,omitempty" json:"lastPerformanceDate,omitempty"`
+	LastError           string    `bson:"last_error,omitempty" json:"lastError,omitempty"`
+	CreatedAt           time.Time `bson:"created_at" json:"createdAt"`
+	UpdatedAt           time.Time `bson:"updated_at" json:"updatedAt"`
+}
+
+// SheetsLinkRequest represents the request body for starting to link a
+// Google Sheet
+type SheetsLinkRequest struct {
+	// Spreadsheet is the sheet's ID or its URL
+	Spreadsheet string `json:"spreadsheet" binding:"required,max=512"`
+	Currency    string `json:"currency" binding:"omitempty,oneof=USD RMB CNY"`
+}
+
+// SheetsAuthorizeRequest represents the request body for finishing a link
+// with what Google's consent screen redirected back with
+type SheetsAuthorizeRequest struct {
+	Code  string `json:"code" binding:"required,max=512"`
+	State string `json:"state" binding:"required,max=128"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// WithdrawalSimulationRequest represents the request body for a withdrawal simulation
+type WithdrawalSimulationRequest struct {
+	Currency          string   `json:"currency" binding:"omitempty,oneof=USD RMB CNY"`
+	InitialValue      float64  `json:"initialValue" binding:"gte=0"`           // Defaults to the current portfolio value
+	AnnualWithdrawal  float64  `json:"annualWithdrawal" binding:"gte=0"`       // First-year amount
+	WithdrawalRate    float64  `json:"withdrawalRate" binding:"gte=0,lte=100"` // Percentage of initial value, used without annualWithdrawal
+	Years             int      `json:"years" binding:"required,gte=1,lte=60"`
+	InflationAdjusted bool     `json:"inflationAdjusted"`
+	InflationRate     *float64 `json:"inflationRate" binding:"omitempty,gte=0,lte=20"` // Annual percentage, defaults to 3
+	Method            string   `json:"method" binding:"omitempty,oneof=historical montecarlo"`
+	Simulations       int      `json:"simulations" binding:"gte=0,lte=10000"`
+	Seed              int64    `json:"seed"`
+}
+
+// SimulatedTrade is a hypothetical transaction for a trade simulation
+type SimulatedTrade struct {
+	Symbol   string  `json:"symbol" binding:"required,max=32"`
+	Action   string  `json:"action" binding:"required,oneof=buy sell"`
+	Shares   float64 `json:"shares" binding:"required,gt=0"`
+	Price    float64 `json:"price" binding:"gte=0"` // Defaults to the current quote
+	Currency string  `json:"currency" binding:"required,oneof=USD RMB CNY"`
+	Fees     float64 `json:"fees" binding:"gte=0"`
+}
+
+// TradeSimulationRequest represents the request body for a what-if trade simulation
+type TradeSimulationRequest struct {
+	Currency string           `json:"currency" binding:"omitempty,oneof=USD RMB CNY"`
+	Trades   []SimulatedTrade `json:"trades" binding:"required,min=1,max=50,dive"`
+}
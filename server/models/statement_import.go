@@ -0,0 +1,23 @@
+package models
+
+// Supported broker statement formats
+const (
+	BrokerSchwab   = "schwab"
+	BrokerFidelity = "fidelity"
+	BrokerIBKR     = "ibkr"
+	BrokerFutu     = "futu"
+	BrokerOFX      = "ofx"
+	// BrokerCombined is the generic multi-leg CSV format mixing trades and
+	// cash movements in one file
+	BrokerCombined = "combined"
+)
+
+// Tags marking the cash transactions imported from a combined statement.
+// Interest uses CashInterestTag.
+const (
+	CashDepositTag    = "deposit"
+	CashWithdrawalTag = "withdrawal"
+	CashDividendTag   = "dividend"
+	CashFeeTag        = "fee"
+	CashTradeTag      = "settlement"
+)
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// SymbolMetadata describes a traded symbol as last reported by the market
+// data providers. It is recorded the first time the symbol is quoted, so its
+// name, currency and sector are known without asking the providers again.
+// A symbol whose ticker changed, such as FB becoming META, names the current
+// ticker in RenamedTo. The last quoted price is kept so holdings can still be
+// valued once the providers stop quoting the symbol, as after a delisting.
+type SymbolMetadata struct {
+	Symbol    string    `bson:"_id" json:"symbol"`
+	Name      string    `bson:"name" json:"name"`
+	Exchange  string    `bson:"exchange,omitempty" json:"exchange,omitempty"`
+	Currency  string    `bson:"currency" json:"currency"`
+	Sector    string    `bson:"sector,omitempty" json:"sector,omitempty"`
+	Type      string    `bson:"type,omitempty" json:"type,omitempty"` // Instrument type such as EQUITY or ETF
+	FirstSeen time.Time `bson:"first_seen" json:"firstSeen"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updatedAt"`
+
+	RenamedTo string     `bson:"renamed_to,omitempty" json:"renamedTo,omitempty"`
+	RenamedAt *time.Time `bson:"renamed_at,omitempty" json:"renamedAt,omitempty"`
+
+	LastPrice    float64    `bson:"last_price,omitempty" json:"lastPrice,omitempty"`
+	LastPriceAt  *time.Time `bson:"last_price_at,omitempty" json:"lastPriceAt,omitempty"`
+	MissingSince *time.Time `bson:"missing_since,omitempty" json:"missingSince,omitempty"` // When the providers first stopped finding it
+	DelistedAt   *time.Time `bson:"delisted_at,omitempty" json:"delistedAt,omitempty"`
+}
@@ -18,17 +18,43 @@ type Transaction struct {
 	Currency    string             `bson:"currency" json:"currency"`
 	Fees        float64            `bson:"fees" json:"fees"`
 	Date        time.Time          `bson:"date" json:"date"`
-	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updatedAt"`
+	Note        string             `bson:"note,omitempty" json:"note,omitempty"`
+	Tags        []string           `bson:"tags,omitempty" json:"tags,omitempty"`
+	// Imported transactions record the broker's own ID for the row, unique
+	// per user and broker, so importing a statement again skips it
+	Broker     string `bson:"broker,omitempty" json:"broker,omitempty"`
+	ExternalID string `bson:"external_id,omitempty" json:"externalId,omitempty"`
+	// Option transactions record the contract; Shares holds the underlying
+	// share equivalent, Contracts times the contract multiplier
+	InstrumentType string          `bson:"instrument_type,omitempty" json:"instrumentType,omitempty"`
+	Option         *OptionContract `bson:"option,omitempty" json:"option,omitempty"`
+	Contracts      float64         `bson:"contracts,omitempty" json:"contracts,omitempty"`
+	CreatedAt      time.Time       `bson:"created_at" json:"createdAt"`
+	UpdatedAt      time.Time       `bson:"updated_at" json:"updatedAt"`
 }
 
-// TransactionRequest represents the request body for creating/updating a transaction
+// DisposalRequest represents the request body for selling the rest of a
+// position at a manual price, e.g. after the symbol was delisted. The price
+// may be zero for shares that became worthless.
+type DisposalRequest struct {
+	Price float64   `json:"price" binding:"gte=0"`
+	Fees  float64   `json:"fees" binding:"gte=0"`
+	Date  time.Time `json:"date" binding:"required"`
+	Note  string    `json:"note" binding:"max=1000"`
+}
+
+// TransactionRequest represents the request body for creating/updating a transaction.
+// For an option, Symbol is the underlying (or an OCC symbol without Option),
+// Shares is the number of contracts and Price the premium per share.
 type TransactionRequest struct {
-	Symbol   string    `json:"symbol" binding:"required"`
-	Action   string    `json:"action" binding:"required,oneof=buy sell"`
-	Shares   float64   `json:"shares" binding:"required,gt=0"`
-	Price    float64   `json:"price" binding:"required,gt=0"`
-	Currency string    `json:"currency" binding:"required,oneof=USD RMB"`
-	Fees     float64   `json:"fees" binding:"gte=0"`
-	Date     time.Time `json:"date" binding:"required"`
+	Symbol   string         `json:"symbol" binding:"required,max=32"` // Long enough for OCC option symbols
+	Action   string         `json:"action" binding:"required,oneof=buy sell"`
+	Shares   float64        `json:"shares" binding:"required,gt=0"`
+	Price    float64        `json:"price" binding:"required,gt=0"`
+	Currency string         `json:"currency" binding:"required,oneof=USD RMB CNY"`
+	Fees     float64        `json:"fees" binding:"gte=0"`
+	Date     time.Time      `json:"date" binding:"required"`
+	Note     string         `json:"note" binding:"max=1000"`
+	Tags     []string       `json:"tags" binding:"max=20,dive,max=50"`
+	Option   *OptionRequest `json:"option"`
 }
@@ -8,9 +8,27 @@ import (
 
 // User represents a registered user in the system
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Email     string             `bson:"email" json:"email" binding:"required,email"`
-	Password  string             `bson:"password" json:"-"`
-	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updatedAt"`
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Email        string              `bson:"email" json:"email" binding:"required,email"`
+	Password     string              `bson:"password" json:"-"`
+	DisplayName  string              `bson:"display_name,omitempty" json:"displayName"`
+	BaseCurrency string              `bson:"base_currency,omitempty" json:"baseCurrency"`
+	Country      string              `bson:"country,omitempty" json:"country"` // ISO 3166-1 alpha-2
+	AvatarID     *primitive.ObjectID `bson:"avatar_id,omitempty" json:"-"`
+	CreatedAt    time.Time           `bson:"created_at" json:"createdAt"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updatedAt"`
+}
+
+// UserProfile holds the user fields the user can edit
+type UserProfile struct {
+	DisplayName  string
+	BaseCurrency string
+	Country      string
+}
+
+// ProfileRequest represents the request body for updating the user's profile
+type ProfileRequest struct {
+	DisplayName  string `json:"displayName" binding:"max=64"`
+	BaseCurrency string `json:"baseCurrency" binding:"omitempty,oneof=USD RMB CNY"`
+	Country      string `json:"country" binding:"omitempty,iso3166_1_alpha2"`
 }
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Summary email frequencies
+const (
+	SummaryFrequencyOff     = "off"
+	SummaryFrequencyWeekly  = "weekly"
+	SummaryFrequencyMonthly = "monthly"
+)
+
+// UserSettings represents per-user preferences
+type UserSettings struct {
+	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID   `bson:"user_id" json:"userId"`
+	SummaryEmail SummaryEmailSettings `bson:"summary_email" json:"summaryEmail"`
+	DriftAlerts  DriftAlertSettings   `bson:"drift_alerts" json:"driftAlerts"`
+	CrossAlerts  CrossAlertSettings   `bson:"cross_alerts" json:"crossAlerts"`
+	Benchmarks   BenchmarkSettings    `bson:"benchmarks" json:"benchmarks"`
+	Chat         ChatSettings         `bson:"chat" json:"chat"`
+	CreatedAt    time.Time            `bson:"created_at" json:"createdAt"`
+	UpdatedAt    time.Time            `bson:"updated_at" json:"updatedAt"`
+}
+
+// BenchmarkSettings holds the benchmark the user's portfolio is compared
+// against when a request doesn't name one
+type BenchmarkSettings struct {
+	// Default is a benchmark symbol, a blend expression such as
+	// "60% ^GSPC + 40% AGG" or a saved blend ("blend:<id>"). Empty for none.
+	Default string `bson:"default,omitempty" json:"default"`
+}
+
+// BenchmarkSettingsRequest represents the request body for updating the default benchmark
+type BenchmarkSettingsRequest struct {
+	Default string `json:"default" binding:"max=200"`
+}
+
+// ChatSettings holds where the user's notifications are sent besides email.
+// An empty destination turns the channel off.
+type ChatSettings struct {
+	TelegramChatID  string `bson:"telegram_chat_id,omitempty" json:"telegramChatId"`
+	SlackWebhookURL string `bson:"slack_webhook_url,omitempty" json:"slackWebhookUrl"`
+}
+
+// ChatSettingsRequest represents the request body for updating chat notification destinations
+type ChatSettingsRequest struct {
+	TelegramChatID  string `json:"telegramChatId" binding:"max=64"`
+	SlackWebhookURL string `json:"slackWebhookUrl" binding:"omitempty,url,max=512"`
+}
+
+// SummaryEmailSettings represents the recurring portfolio summary email preferences
+type SummaryEmailSettings struct {
+	Frequency  string     `bson:"frequency" json:"frequency"`
+	Currency   string     `bson:"currency" json:"currency"`
+	LastSentAt *time.Time `bson:"last_sent_at,omitempty" json:"lastSentAt,omitempty"`
+
+	// Allocation weights at the last summary, used to report drift in the next one
+	LastAllocation map[string]float64 `bson:"last_allocation,omitempty" json:"-"`
+}
+
+// SummaryEmailSettingsRequest represents the request body for updating summary email preferences
+type SummaryEmailSettingsRequest struct {
+	Frequency string `json:"frequency" binding:"required,oneof=off weekly monthly"`
+	Currency  string `json:"currency" binding:"omitempty,oneof=USD RMB CNY"`
+}
+
+// DriftAlertSettings represents target weights per asset class or style and
+// the drift from them that triggers a notification
+type DriftAlertSettings struct {
+	Enabled  bool   `bson:"enabled" json:"enabled"`
+	GroupBy  string `bson:"group_by" json:"groupBy"` // assetClass or assetStyle
+	Currency string `bson:"currency" json:"currency"`
+	// Threshold is the deviation from a target, in percentage points, that
+	// triggers an alert
+	Threshold float64 `bson:"threshold" json:"threshold"`
+	// Targets maps group names to target weights in percent, summing to 100.
+	// Groups without a target are held against a target of 0.
+	Targets map[string]float64 `bson:"targets,omitempty" json:"targets"`
+
+	// Groups out of band at the last alert, so an alert is only sent again
+	// when the set changes
+	Breached      []string   `bson:"breached,omitempty" json:"breached,omitempty"`
+	LastAlertedAt *time.Time `bson:"last_alerted_at,omitempty" json:"lastAlertedAt,omitempty"`
+}
+
+// DriftAlertSettingsRequest represents the request body for updating drift alert preferences
+type DriftAlertSettingsRequest struct {
+	Enabled   bool               `json:"enabled"`
+	GroupBy   string             `json:"groupBy" binding:"required,oneof=assetClass assetStyle"`
+	Currency  string             `json:"currency" binding:"omitempty,oneof=USD RMB CNY"`
+	Threshold float64            `json:"threshold" binding:"required,gt=0,lte=100"`
+	Targets   map[string]float64 `json:"targets" binding:"required,min=1,max=50,dive,keys,required,max=100,endkeys,gte=0,lte=100"`
+}
+
+// CrossAlertSettings represents notifications of the 50-day moving average of
+// a held symbol crossing its 200-day one, such as a golden cross
+type CrossAlertSettings struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+
+	// Recent crosses already alerted, so each is only sent once
+	Alerted       []AlertedCross `bson:"alerted,omitempty" json:"-"`
+	LastAlertedAt *time.Time     `bson:"last_alerted_at,omitempty" json:"lastAlertedAt,omitempty"`
+}
+
+// AlertedCross is a moving average cross the user was notified of
+type AlertedCross struct {
+	Symbol string    `bson:"symbol" json:"symbol"`
+	Type   string    `bson:"type" json:"type"` // golden or death
+	Date   time.Time `bson:"date" json:"date"`
+}
+
+// CrossAlertSettingsRequest represents the request body for updating moving average cross alert preferences
+type CrossAlertSettingsRequest struct {
+	Enabled bool `json:"enabled"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Events a webhook can subscribe to
+const (
+	WebhookEventSummary = "summary" // a daily portfolio summary
+	WebhookEventAlerts  = "alerts"  // stop, drift and pending order notifications
+)
+
+// Webhook is a URL that is posted JSON about the user's portfolio. Every post
+// is signed with Secret, which is only shown when the webhook is created.
+type Webhook struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"userId"`
+	URL            string             `bson:"url" json:"url"`
+	Secret         string             `bson:"secret" json:"-"`
+	Events         []string           `bson:"events" json:"events"`
+	Currency       string             `bson:"currency" json:"currency"` // Of daily summaries
+	LastSummaryAt  *time.Time         `bson:"last_summary_at,omitempty" json:"lastSummaryAt,omitempty"`
+	LastDeliveryAt *time.Time         `bson:"last_delivery_at,omitempty" json:"lastDeliveryAt,omitempty"`
+	LastError      string             `bson:"last_error,omitempty" json:"lastError,omitempty"` // Of the last delivery, if it failed
+	CreatedAt      time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// WebhookRequest represents the request body for registering a webhook
+type WebhookRequest struct {
+	URL      string   `json:"url" binding:"required,url,max=2048"`
+	Events   []string `json:"events" binding:"required,min=1,max=2,dive,oneof=summary alerts"`
+	Currency string   `json:"currency" binding:"omitempty,oneof=USD RMB CNY"`
+}
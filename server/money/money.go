@@ -0,0 +1,115 @@
+// Package money does currency arithmetic in integer minor units, such as
+// cents, fen or yen, so that sums of many amounts do not accumulate
+// floating-point drift. Every currency is rounded to its ISO 4217 minor unit
+// with the same rule, round half to even, which is also what MongoDB's $round
+// applies on the database side.
+package money
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// zeroDecimalCurrencies have no minor unit under ISO 4217
+var zeroDecimalCurrencies = map[string]bool{
+	"JPY": true,
+	"KRW": true,
+	"VND": true,
+	"CLP": true,
+	"ISK": true,
+}
+
+// Exponent returns the number of decimal places in a currency's minor unit:
+// 0 for currencies such as JPY and 2 for the rest, including RMB
+func Exponent(currency string) int {
+	if zeroDecimalCurrencies[strings.ToUpper(strings.TrimSpace(currency))] {
+		return 0
+	}
+	return 2
+}
+
+// ZeroDecimalCurrencies lists the currencies with no minor unit, for
+// rounding done outside Go such as in database pipelines
+func ZeroDecimalCurrencies() []string {
+	currencies := make([]string, 0, len(zeroDecimalCurrencies))
+	for currency := range zeroDecimalCurrencies {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// Amount is a quantity of money held in integer minor units of its currency
+type Amount struct {
+	Minor    int64
+	Currency string
+}
+
+// New converts a floating-point amount to minor units, rounding half to even.
+// The value is first snapped to a millionth of a minor unit so that binary
+// noise such as 1.0049999999 for 1.005 does not decide the rounding.
+func New(value float64, currency string) Amount {
+	scaled := value * math.Pow10(Exponent(currency))
+	scaled = math.Round(scaled*1e6) / 1e6
+	return Amount{Minor: int64(math.RoundToEven(scaled)), Currency: currency}
+}
+
+// Zero returns no money in a currency
+func Zero(currency string) Amount {
+	return Amount{Currency: currency}
+}
+
+// Round rounds a floating-point amount to its currency's minor unit
+func Round(value float64, currency string) float64 {
+	return New(value, currency).Float64()
+}
+
+// Float64 returns the amount in major units
+func (a Amount) Float64() float64 {
+	return float64(a.Minor) / math.Pow10(Exponent(a.Currency))
+}
+
+// Add returns a + b. Adding amounts in different currencies is a programming
+// error and panics.
+func (a Amount) Add(b Amount) Amount {
+	a.mustMatch(b)
+	return Amount{Minor: a.Minor + b.Minor, Currency: a.Currency}
+}
+
+// Sub returns a - b, panicking like Add on a currency mismatch
+func (a Amount) Sub(b Amount) Amount {
+	a.mustMatch(b)
+	return Amount{Minor: a.Minor - b.Minor, Currency: a.Currency}
+}
+
+// Mul returns the amount scaled by factor, rounded half to even
+func (a Amount) Mul(factor float64) Amount {
+	return Amount{Minor: int64(math.RoundToEven(float64(a.Minor) * factor)), Currency: a.Currency}
+}
+
+// IsZero reports whether the amount is zero
+func (a Amount) IsZero() bool {
+	return a.Minor == 0
+}
+
+// String formats the amount with its currency's decimal places, e.g. "12.30 USD"
+func (a Amount) String() string {
+	return fmt.Sprintf("%.*f %s", Exponent(a.Currency), a.Float64(), a.Currency)
+}
+
+func (a Amount) mustMatch(b Amount) {
+	if a.Currency != b.Currency {
+		panic(fmt.Sprintf("money: currency mismatch %s and %s", a.Currency, b.Currency))
+	}
+}
+
+// Sum adds floating-point amounts in minor units and returns the total
+func Sum(currency string, values ...float64) float64 {
+	total := Zero(currency)
+	for _, value := range values {
+		total = total.Add(New(value, currency))
+	}
+	return total.Float64()
+}
@@ -0,0 +1,55 @@
+package money
+
+import "testing"
+
+func TestRoundPerCurrency(t *testing.T) {
+	tests := []struct {
+		value    float64
+		currency string
+		want     float64
+	}{
+		{1.005, "USD", 1.00},
+		{1.015, "USD", 1.02},
+		{2.675, "RMB", 2.68},
+		{-0.125, "EUR", -0.12},
+		{1234.5, "JPY", 1234},
+		{1235.5, "jpy", 1236},
+	}
+	for _, tt := range tests {
+		if got := Round(tt.value, tt.currency); got != tt.want {
+			t.Errorf("Round(%v, %s) = %v, want %v", tt.value, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestSumDoesNotDrift(t *testing.T) {
+	values := make([]float64, 1000)
+	for i := range values {
+		values[i] = 0.1
+	}
+	if got := Sum("USD", values...); got != 100 {
+		t.Errorf("Expected 1000 × 0.10 to total exactly 100, got %v", got)
+	}
+}
+
+func TestAmountArithmetic(t *testing.T) {
+	cost := New(1000, "USD")
+	// Selling a third of the position removes a third of the cost, rounded
+	removed := cost.Mul(1.0 / 3)
+	if removed.Minor != 33333 {
+		t.Errorf("Expected 333.33 removed, got %s", removed)
+	}
+	if remaining := cost.Sub(removed); remaining.String() != "666.67 USD" {
+		t.Errorf("Expected 666.67 USD remaining, got %s", remaining)
+	}
+	if !Zero("JPY").IsZero() || New(150, "JPY").String() != "150 JPY" {
+		t.Errorf("Unexpected JPY formatting %s", New(150, "JPY"))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected adding USD to RMB to panic")
+		}
+	}()
+	New(1, "USD").Add(New(1, "RMB"))
+}
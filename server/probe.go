@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"stock-portfolio-tracker/config"
+	"stock-portfolio-tracker/services"
+	"strings"
+	"time"
+)
+
+// runProbe implements the probe subcommand, which checks each market data and
+// exchange rate provider directly and prints one JSON diagnostic per request:
+//
+//	server probe --symbol AAPL --provider yahoo
+//	server probe --symbol 600519.SS            # every provider
+//
+// It returns the exit status: 0 when every check passed, 1 when one failed
+// and 2 for invalid arguments.
+func runProbe(args []string) int {
+	flags := flag.NewFlagSet("probe", flag.ContinueOnError)
+	symbol := flags.String("symbol", "AAPL", "symbol to fetch a quote and history for")
+	provider := flags.String("provider", "all", "yahoo, eastmoney, frankfurter, exchangerate-api or all")
+	base := flags.String("base", "USD", "base currency for exchange rate providers")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout for the whole probe")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	// Provider settings come from the usual configuration, but the probe
+	// does not need the rest of it to be valid
+	cfg, err := config.Load()
+	if err != nil {
+		log.Println("Using default provider settings:", err)
+		defaults := config.Default()
+		cfg = &defaults
+	}
+
+	stockService := services.NewStockAPIService(services.StockAPIConfig{
+		YahooTimeout:     cfg.Providers.YahooTimeout,
+		EastmoneyTimeout: cfg.Providers.EastmoneyTimeout,
+	})
+	fxClient := &http.Client{Timeout: cfg.Providers.ExchangeRateTimeout}
+	fxProviders := map[string]services.FXProvider{
+		"frankfurter": services.NewFrankfurterProvider(fxClient),
+	}
+	if cfg.Providers.ExchangeRateAPIKey != "" {
+		fxProviders["exchangerate-api"] = services.NewExchangeRateAPIProvider(fxClient, cfg.Providers.ExchangeRateAPIKey)
+	}
+
+	var stockProviders []string
+	var fxNames []string
+	switch name := strings.ToLower(*provider); {
+	case name == "all":
+		stockProviders = services.StockProviders
+		fxNames = []string{"frankfurter"}
+		if _, ok := fxProviders["exchangerate-api"]; ok {
+			fxNames = append(fxNames, "exchangerate-api")
+		}
+	case name == "exchangerate-api" && fxProviders[name] == nil:
+		fmt.Fprintln(os.Stderr, "exchangerate-api needs EXCHANGE_RATE_API_KEY")
+		return 2
+	case fxProviders[name] != nil:
+		fxNames = []string{name}
+	case slices.Contains(services.StockProviders, name):
+		stockProviders = []string{name}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown provider %q\n", *provider)
+		flags.Usage()
+		return 2
+	}
+
+	// The services log progress with fmt.Printf; keep stdout for the
+	// diagnostics so they can be piped to jq
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = stdout }()
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var checks []services.ProbeCheck
+	for _, name := range stockProviders {
+		results, err := stockService.Probe(ctx, name, *symbol)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		checks = append(checks, results...)
+	}
+	for _, name := range fxNames {
+		checks = append(checks, services.ProbeFXProvider(ctx, fxProviders[name], strings.ToUpper(*base)))
+	}
+
+	status := 0
+	for _, check := range checks {
+		if err := encoder.Encode(check); err != nil {
+			log.Println("Failed to write diagnostics:", err)
+			return 1
+		}
+		if !check.OK {
+			status = 1
+		}
+	}
+	return status
+}
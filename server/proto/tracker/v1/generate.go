@@ -0,0 +1,5 @@
+package trackerv1
+
+// Regenerate the Go code after editing tracker.proto. Requires protoc with the
+// protoc-gen-go and protoc-gen-go-grpc plugins on the PATH.
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative tracker/v1/tracker.proto
@@ -0,0 +1,1311 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: tracker/v1/tracker.proto
+
+// Package tracker.v1 exposes the portfolio, stock and analytics services over
+// gRPC. Every call requires an "authorization: Bearer <token>" metadata entry
+// holding a token issued by /api/auth/login. Currencies are "USD" or "RMB"
+// ("CNY" is accepted as RMB) and periods are "1M", "3M", "6M", "1Y" or "ALL",
+// as in the REST API.
+
+package trackerv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetQuoteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetQuoteRequest) Reset() {
+	*x = GetQuoteRequest{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQuoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQuoteRequest) ProtoMessage() {}
+
+func (x *GetQuoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQuoteRequest.ProtoReflect.Descriptor instead.
+func (*GetQuoteRequest) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetQuoteRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+type Quote struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Price         float64                `protobuf:"fixed64,3,opt,name=price,proto3" json:"price,omitempty"`
+	Currency      string                 `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Quote) Reset() {
+	*x = Quote{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Quote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Quote) ProtoMessage() {}
+
+func (x *Quote) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Quote.ProtoReflect.Descriptor instead.
+func (*Quote) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Quote) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Quote) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Quote) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Quote) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+type GetHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Period        string                 `protobuf:"bytes,2,opt,name=period,proto3" json:"period,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHistoryRequest) Reset() {
+	*x = GetHistoryRequest{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryRequest) ProtoMessage() {}
+
+func (x *GetHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetHistoryRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *GetHistoryRequest) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+type PricePoint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Date          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	Price         float64                `protobuf:"fixed64,2,opt,name=price,proto3" json:"price,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PricePoint) Reset() {
+	*x = PricePoint{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PricePoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PricePoint) ProtoMessage() {}
+
+func (x *PricePoint) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PricePoint.ProtoReflect.Descriptor instead.
+func (*PricePoint) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PricePoint) GetDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Date
+	}
+	return nil
+}
+
+func (x *PricePoint) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+type GetHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Prices        []*PricePoint          `protobuf:"bytes,2,rep,name=prices,proto3" json:"prices,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHistoryResponse) Reset() {
+	*x = GetHistoryResponse{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryResponse) ProtoMessage() {}
+
+func (x *GetHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetHistoryResponse) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *GetHistoryResponse) GetPrices() []*PricePoint {
+	if x != nil {
+		return x.Prices
+	}
+	return nil
+}
+
+type ListHoldingsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Currency      string                 `protobuf:"bytes,1,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListHoldingsRequest) Reset() {
+	*x = ListHoldingsRequest{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListHoldingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListHoldingsRequest) ProtoMessage() {}
+
+func (x *ListHoldingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListHoldingsRequest.ProtoReflect.Descriptor instead.
+func (*ListHoldingsRequest) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListHoldingsRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+type Holding struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	PortfolioId     string                 `protobuf:"bytes,1,opt,name=portfolio_id,json=portfolioId,proto3" json:"portfolio_id,omitempty"`
+	Symbol          string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Name            string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Shares          float64                `protobuf:"fixed64,4,opt,name=shares,proto3" json:"shares,omitempty"`
+	CostBasis       float64                `protobuf:"fixed64,5,opt,name=cost_basis,json=costBasis,proto3" json:"cost_basis,omitempty"`
+	CurrentPrice    float64                `protobuf:"fixed64,6,opt,name=current_price,json=currentPrice,proto3" json:"current_price,omitempty"`
+	CurrentValue    float64                `protobuf:"fixed64,7,opt,name=current_value,json=currentValue,proto3" json:"current_value,omitempty"`
+	GainLoss        float64                `protobuf:"fixed64,8,opt,name=gain_loss,json=gainLoss,proto3" json:"gain_loss,omitempty"`
+	GainLossPercent float64                `protobuf:"fixed64,9,opt,name=gain_loss_percent,json=gainLossPercent,proto3" json:"gain_loss_percent,omitempty"`
+	Currency        string                 `protobuf:"bytes,10,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Holding) Reset() {
+	*x = Holding{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Holding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Holding) ProtoMessage() {}
+
+func (x *Holding) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Holding.ProtoReflect.Descriptor instead.
+func (*Holding) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Holding) GetPortfolioId() string {
+	if x != nil {
+		return x.PortfolioId
+	}
+	return ""
+}
+
+func (x *Holding) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Holding) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Holding) GetShares() float64 {
+	if x != nil {
+		return x.Shares
+	}
+	return 0
+}
+
+func (x *Holding) GetCostBasis() float64 {
+	if x != nil {
+		return x.CostBasis
+	}
+	return 0
+}
+
+func (x *Holding) GetCurrentPrice() float64 {
+	if x != nil {
+		return x.CurrentPrice
+	}
+	return 0
+}
+
+func (x *Holding) GetCurrentValue() float64 {
+	if x != nil {
+		return x.CurrentValue
+	}
+	return 0
+}
+
+func (x *Holding) GetGainLoss() float64 {
+	if x != nil {
+		return x.GainLoss
+	}
+	return 0
+}
+
+func (x *Holding) GetGainLossPercent() float64 {
+	if x != nil {
+		return x.GainLossPercent
+	}
+	return 0
+}
+
+func (x *Holding) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+type ListHoldingsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Currency      string                 `protobuf:"bytes,1,opt,name=currency,proto3" json:"currency,omitempty"`
+	Holdings      []*Holding             `protobuf:"bytes,2,rep,name=holdings,proto3" json:"holdings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListHoldingsResponse) Reset() {
+	*x = ListHoldingsResponse{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListHoldingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListHoldingsResponse) ProtoMessage() {}
+
+func (x *ListHoldingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListHoldingsResponse.ProtoReflect.Descriptor instead.
+func (*ListHoldingsResponse) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListHoldingsResponse) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *ListHoldingsResponse) GetHoldings() []*Holding {
+	if x != nil {
+		return x.Holdings
+	}
+	return nil
+}
+
+type ListTransactionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTransactionsRequest) Reset() {
+	*x = ListTransactionsRequest{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTransactionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTransactionsRequest) ProtoMessage() {}
+
+func (x *ListTransactionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTransactionsRequest.ProtoReflect.Descriptor instead.
+func (*ListTransactionsRequest) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListTransactionsRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+type Transaction struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	PortfolioId   string                 `protobuf:"bytes,2,opt,name=portfolio_id,json=portfolioId,proto3" json:"portfolio_id,omitempty"`
+	Symbol        string                 `protobuf:"bytes,3,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Action        string                 `protobuf:"bytes,4,opt,name=action,proto3" json:"action,omitempty"` // "buy" or "sell"
+	Shares        float64                `protobuf:"fixed64,5,opt,name=shares,proto3" json:"shares,omitempty"`
+	Price         float64                `protobuf:"fixed64,6,opt,name=price,proto3" json:"price,omitempty"`
+	Currency      string                 `protobuf:"bytes,7,opt,name=currency,proto3" json:"currency,omitempty"`
+	Fees          float64                `protobuf:"fixed64,8,opt,name=fees,proto3" json:"fees,omitempty"`
+	Date          *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=date,proto3" json:"date,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Transaction) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Transaction) GetPortfolioId() string {
+	if x != nil {
+		return x.PortfolioId
+	}
+	return ""
+}
+
+func (x *Transaction) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Transaction) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *Transaction) GetShares() float64 {
+	if x != nil {
+		return x.Shares
+	}
+	return 0
+}
+
+func (x *Transaction) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Transaction) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Transaction) GetFees() float64 {
+	if x != nil {
+		return x.Fees
+	}
+	return 0
+}
+
+func (x *Transaction) GetDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Date
+	}
+	return nil
+}
+
+type ListTransactionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transactions  []*Transaction         `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTransactionsResponse) Reset() {
+	*x = ListTransactionsResponse{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTransactionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTransactionsResponse) ProtoMessage() {}
+
+func (x *ListTransactionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTransactionsResponse.ProtoReflect.Descriptor instead.
+func (*ListTransactionsResponse) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListTransactionsResponse) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+type GetDashboardRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Currency      string                 `protobuf:"bytes,1,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDashboardRequest) Reset() {
+	*x = GetDashboardRequest{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDashboardRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDashboardRequest) ProtoMessage() {}
+
+func (x *GetDashboardRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDashboardRequest.ProtoReflect.Descriptor instead.
+func (*GetDashboardRequest) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetDashboardRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+type AllocationItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Value         float64                `protobuf:"fixed64,3,opt,name=value,proto3" json:"value,omitempty"`
+	Percentage    float64                `protobuf:"fixed64,4,opt,name=percentage,proto3" json:"percentage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocationItem) Reset() {
+	*x = AllocationItem{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocationItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocationItem) ProtoMessage() {}
+
+func (x *AllocationItem) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocationItem.ProtoReflect.Descriptor instead.
+func (*AllocationItem) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *AllocationItem) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *AllocationItem) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *AllocationItem) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *AllocationItem) GetPercentage() float64 {
+	if x != nil {
+		return x.Percentage
+	}
+	return 0
+}
+
+type Dashboard struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Currency         string                 `protobuf:"bytes,1,opt,name=currency,proto3" json:"currency,omitempty"`
+	TotalValue       float64                `protobuf:"fixed64,2,opt,name=total_value,json=totalValue,proto3" json:"total_value,omitempty"`
+	TotalGain        float64                `protobuf:"fixed64,3,opt,name=total_gain,json=totalGain,proto3" json:"total_gain,omitempty"`
+	PercentageReturn float64                `protobuf:"fixed64,4,opt,name=percentage_return,json=percentageReturn,proto3" json:"percentage_return,omitempty"`
+	DayChange        float64                `protobuf:"fixed64,5,opt,name=day_change,json=dayChange,proto3" json:"day_change,omitempty"`
+	DayChangePercent float64                `protobuf:"fixed64,6,opt,name=day_change_percent,json=dayChangePercent,proto3" json:"day_change_percent,omitempty"`
+	Allocation       []*AllocationItem      `protobuf:"bytes,7,rep,name=allocation,proto3" json:"allocation,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Dashboard) Reset() {
+	*x = Dashboard{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Dashboard) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Dashboard) ProtoMessage() {}
+
+func (x *Dashboard) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Dashboard.ProtoReflect.Descriptor instead.
+func (*Dashboard) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Dashboard) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Dashboard) GetTotalValue() float64 {
+	if x != nil {
+		return x.TotalValue
+	}
+	return 0
+}
+
+func (x *Dashboard) GetTotalGain() float64 {
+	if x != nil {
+		return x.TotalGain
+	}
+	return 0
+}
+
+func (x *Dashboard) GetPercentageReturn() float64 {
+	if x != nil {
+		return x.PercentageReturn
+	}
+	return 0
+}
+
+func (x *Dashboard) GetDayChange() float64 {
+	if x != nil {
+		return x.DayChange
+	}
+	return 0
+}
+
+func (x *Dashboard) GetDayChangePercent() float64 {
+	if x != nil {
+		return x.DayChangePercent
+	}
+	return 0
+}
+
+func (x *Dashboard) GetAllocation() []*AllocationItem {
+	if x != nil {
+		return x.Allocation
+	}
+	return nil
+}
+
+type GetPerformanceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Period        string                 `protobuf:"bytes,1,opt,name=period,proto3" json:"period,omitempty"`
+	Currency      string                 `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPerformanceRequest) Reset() {
+	*x = GetPerformanceRequest{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPerformanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPerformanceRequest) ProtoMessage() {}
+
+func (x *GetPerformanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPerformanceRequest.ProtoReflect.Descriptor instead.
+func (*GetPerformanceRequest) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetPerformanceRequest) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+func (x *GetPerformanceRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+type PerformancePoint struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Date             *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	Value            float64                `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	PercentageReturn float64                `protobuf:"fixed64,3,opt,name=percentage_return,json=percentageReturn,proto3" json:"percentage_return,omitempty"`
+	DayChange        float64                `protobuf:"fixed64,4,opt,name=day_change,json=dayChange,proto3" json:"day_change,omitempty"`
+	DayChangePercent float64                `protobuf:"fixed64,5,opt,name=day_change_percent,json=dayChangePercent,proto3" json:"day_change_percent,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *PerformancePoint) Reset() {
+	*x = PerformancePoint{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PerformancePoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PerformancePoint) ProtoMessage() {}
+
+func (x *PerformancePoint) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PerformancePoint.ProtoReflect.Descriptor instead.
+func (*PerformancePoint) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *PerformancePoint) GetDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Date
+	}
+	return nil
+}
+
+func (x *PerformancePoint) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *PerformancePoint) GetPercentageReturn() float64 {
+	if x != nil {
+		return x.PercentageReturn
+	}
+	return 0
+}
+
+func (x *PerformancePoint) GetDayChange() float64 {
+	if x != nil {
+		return x.DayChange
+	}
+	return 0
+}
+
+func (x *PerformancePoint) GetDayChangePercent() float64 {
+	if x != nil {
+		return x.DayChangePercent
+	}
+	return 0
+}
+
+type Performance struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Period        string                 `protobuf:"bytes,1,opt,name=period,proto3" json:"period,omitempty"`
+	Currency      string                 `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	Points        []*PerformancePoint    `protobuf:"bytes,3,rep,name=points,proto3" json:"points,omitempty"`
+	TotalReturn   float64                `protobuf:"fixed64,4,opt,name=total_return,json=totalReturn,proto3" json:"total_return,omitempty"`    // Percentage
+	PeriodReturn  float64                `protobuf:"fixed64,5,opt,name=period_return,json=periodReturn,proto3" json:"period_return,omitempty"` // Percentage
+	MaxDrawdown   float64                `protobuf:"fixed64,6,opt,name=max_drawdown,json=maxDrawdown,proto3" json:"max_drawdown,omitempty"`    // Percentage
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Performance) Reset() {
+	*x = Performance{}
+	mi := &file_tracker_v1_tracker_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Performance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Performance) ProtoMessage() {}
+
+func (x *Performance) ProtoReflect() protoreflect.Message {
+	mi := &file_tracker_v1_tracker_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Performance.ProtoReflect.Descriptor instead.
+func (*Performance) Descriptor() ([]byte, []int) {
+	return file_tracker_v1_tracker_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Performance) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+func (x *Performance) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Performance) GetPoints() []*PerformancePoint {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+func (x *Performance) GetTotalReturn() float64 {
+	if x != nil {
+		return x.TotalReturn
+	}
+	return 0
+}
+
+func (x *Performance) GetPeriodReturn() float64 {
+	if x != nil {
+		return x.PeriodReturn
+	}
+	return 0
+}
+
+func (x *Performance) GetMaxDrawdown() float64 {
+	if x != nil {
+		return x.MaxDrawdown
+	}
+	return 0
+}
+
+var File_tracker_v1_tracker_proto protoreflect.FileDescriptor
+
+const file_tracker_v1_tracker_proto_rawDesc = "" +
+	"\n" +
+	"\x18tracker/v1/tracker.proto\x12\n" +
+	"tracker.v1\x1a\x1fgoogle/protobuf/timestamp.proto\")\n" +
+	"\x0fGetQuoteRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\"e\n" +
+	"\x05Quote\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05price\x18\x03 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bcurrency\x18\x04 \x01(\tR\bcurrency\"C\n" +
+	"\x11GetHistoryRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x16\n" +
+	"\x06period\x18\x02 \x01(\tR\x06period\"R\n" +
+	"\n" +
+	"PricePoint\x12.\n" +
+	"\x04date\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04date\x12\x14\n" +
+	"\x05price\x18\x02 \x01(\x01R\x05price\"\\\n" +
+	"\x12GetHistoryResponse\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12.\n" +
+	"\x06prices\x18\x02 \x03(\v2\x16.tracker.v1.PricePointR\x06prices\"1\n" +
+	"\x13ListHoldingsRequest\x12\x1a\n" +
+	"\bcurrency\x18\x01 \x01(\tR\bcurrency\"\xbe\x02\n" +
+	"\aHolding\x12!\n" +
+	"\fportfolio_id\x18\x01 \x01(\tR\vportfolioId\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x16\n" +
+	"\x06shares\x18\x04 \x01(\x01R\x06shares\x12\x1d\n" +
+	"\n" +
+	"cost_basis\x18\x05 \x01(\x01R\tcostBasis\x12#\n" +
+	"\rcurrent_price\x18\x06 \x01(\x01R\fcurrentPrice\x12#\n" +
+	"\rcurrent_value\x18\a \x01(\x01R\fcurrentValue\x12\x1b\n" +
+	"\tgain_loss\x18\b \x01(\x01R\bgainLoss\x12*\n" +
+	"\x11gain_loss_percent\x18\t \x01(\x01R\x0fgainLossPercent\x12\x1a\n" +
+	"\bcurrency\x18\n" +
+	" \x01(\tR\bcurrency\"c\n" +
+	"\x14ListHoldingsResponse\x12\x1a\n" +
+	"\bcurrency\x18\x01 \x01(\tR\bcurrency\x12/\n" +
+	"\bholdings\x18\x02 \x03(\v2\x13.tracker.v1.HoldingR\bholdings\"1\n" +
+	"\x17ListTransactionsRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\"\xfe\x01\n" +
+	"\vTransaction\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12!\n" +
+	"\fportfolio_id\x18\x02 \x01(\tR\vportfolioId\x12\x16\n" +
+	"\x06symbol\x18\x03 \x01(\tR\x06symbol\x12\x16\n" +
+	"\x06action\x18\x04 \x01(\tR\x06action\x12\x16\n" +
+	"\x06shares\x18\x05 \x01(\x01R\x06shares\x12\x14\n" +
+	"\x05price\x18\x06 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bcurrency\x18\a \x01(\tR\bcurrency\x12\x12\n" +
+	"\x04fees\x18\b \x01(\x01R\x04fees\x12.\n" +
+	"\x04date\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x04date\"W\n" +
+	"\x18ListTransactionsResponse\x12;\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x17.tracker.v1.TransactionR\ftransactions\"1\n" +
+	"\x13GetDashboardRequest\x12\x1a\n" +
+	"\bcurrency\x18\x01 \x01(\tR\bcurrency\"r\n" +
+	"\x0eAllocationItem\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\x01R\x05value\x12\x1e\n" +
+	"\n" +
+	"percentage\x18\x04 \x01(\x01R\n" +
+	"percentage\"\x9d\x02\n" +
+	"\tDashboard\x12\x1a\n" +
+	"\bcurrency\x18\x01 \x01(\tR\bcurrency\x12\x1f\n" +
+	"\vtotal_value\x18\x02 \x01(\x01R\n" +
+	"totalValue\x12\x1d\n" +
+	"\n" +
+	"total_gain\x18\x03 \x01(\x01R\ttotalGain\x12+\n" +
+	"\x11percentage_return\x18\x04 \x01(\x01R\x10percentageReturn\x12\x1d\n" +
+	"\n" +
+	"day_change\x18\x05 \x01(\x01R\tdayChange\x12,\n" +
+	"\x12day_change_percent\x18\x06 \x01(\x01R\x10dayChangePercent\x12:\n" +
+	"\n" +
+	"allocation\x18\a \x03(\v2\x1a.tracker.v1.AllocationItemR\n" +
+	"allocation\"K\n" +
+	"\x15GetPerformanceRequest\x12\x16\n" +
+	"\x06period\x18\x01 \x01(\tR\x06period\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency\"\xd2\x01\n" +
+	"\x10PerformancePoint\x12.\n" +
+	"\x04date\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04date\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value\x12+\n" +
+	"\x11percentage_return\x18\x03 \x01(\x01R\x10percentageReturn\x12\x1d\n" +
+	"\n" +
+	"day_change\x18\x04 \x01(\x01R\tdayChange\x12,\n" +
+	"\x12day_change_percent\x18\x05 \x01(\x01R\x10dayChangePercent\"\xe2\x01\n" +
+	"\vPerformance\x12\x16\n" +
+	"\x06period\x18\x01 \x01(\tR\x06period\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency\x124\n" +
+	"\x06points\x18\x03 \x03(\v2\x1c.tracker.v1.PerformancePointR\x06points\x12!\n" +
+	"\ftotal_return\x18\x04 \x01(\x01R\vtotalReturn\x12#\n" +
+	"\rperiod_return\x18\x05 \x01(\x01R\fperiodReturn\x12!\n" +
+	"\fmax_drawdown\x18\x06 \x01(\x01R\vmaxDrawdown2\x97\x01\n" +
+	"\fStockService\x12:\n" +
+	"\bGetQuote\x12\x1b.tracker.v1.GetQuoteRequest\x1a\x11.tracker.v1.Quote\x12K\n" +
+	"\n" +
+	"GetHistory\x12\x1d.tracker.v1.GetHistoryRequest\x1a\x1e.tracker.v1.GetHistoryResponse2\xc4\x01\n" +
+	"\x10PortfolioService\x12Q\n" +
+	"\fListHoldings\x12\x1f.tracker.v1.ListHoldingsRequest\x1a .tracker.v1.ListHoldingsResponse\x12]\n" +
+	"\x10ListTransactions\x12#.tracker.v1.ListTransactionsRequest\x1a$.tracker.v1.ListTransactionsResponse2\xa8\x01\n" +
+	"\x10AnalyticsService\x12F\n" +
+	"\fGetDashboard\x12\x1f.tracker.v1.GetDashboardRequest\x1a\x15.tracker.v1.Dashboard\x12L\n" +
+	"\x0eGetPerformance\x12!.tracker.v1.GetPerformanceRequest\x1a\x17.tracker.v1.PerformanceB4Z2stock-portfolio-tracker/proto/tracker/v1;trackerv1b\x06proto3"
+
+var (
+	file_tracker_v1_tracker_proto_rawDescOnce sync.Once
+	file_tracker_v1_tracker_proto_rawDescData []byte
+)
+
+func file_tracker_v1_tracker_proto_rawDescGZIP() []byte {
+	file_tracker_v1_tracker_proto_rawDescOnce.Do(func() {
+		file_tracker_v1_tracker_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_tracker_v1_tracker_proto_rawDesc), len(file_tracker_v1_tracker_proto_rawDesc)))
+	})
+	return file_tracker_v1_tracker_proto_rawDescData
+}
+
+var file_tracker_v1_tracker_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_tracker_v1_tracker_proto_goTypes = []any{
+	(*GetQuoteRequest)(nil),          // 0: tracker.v1.GetQuoteRequest
+	(*Quote)(nil),                    // 1: tracker.v1.Quote
+	(*GetHistoryRequest)(nil),        // 2: tracker.v1.GetHistoryRequest
+	(*PricePoint)(nil),               // 3: tracker.v1.PricePoint
+	(*GetHistoryResponse)(nil),       // 4: tracker.v1.GetHistoryResponse
+	(*ListHoldingsRequest)(nil),      // 5: tracker.v1.ListHoldingsRequest
+	(*Holding)(nil),                  // 6: tracker.v1.Holding
+	(*ListHoldingsResponse)(nil),     // 7: tracker.v1.ListHoldingsResponse
+	(*ListTransactionsRequest)(nil),  // 8: tracker.v1.ListTransactionsRequest
+	(*Transaction)(nil),              // 9: tracker.v1.Transaction
+	(*ListTransactionsResponse)(nil), // 10: tracker.v1.ListTransactionsResponse
+	(*GetDashboardRequest)(nil),      // 11: tracker.v1.GetDashboardRequest
+	(*AllocationItem)(nil),           // 12: tracker.v1.AllocationItem
+	(*Dashboard)(nil),                // 13: tracker.v1.Dashboard
+	(*GetPerformanceRequest)(nil),    // 14: tracker.v1.GetPerformanceRequest
+	(*PerformancePoint)(nil),         // 15: tracker.v1.PerformancePoint
+	(*Performance)(nil),              // 16: tracker.v1.Performance
+	(*timestamppb.Timestamp)(nil),    // 17: google.protobuf.Timestamp
+}
+var file_tracker_v1_tracker_proto_depIdxs = []int32{
+	17, // 0: tracker.v1.PricePoint.date:type_name -> google.protobuf.Timestamp
+	3,  // 1: tracker.v1.GetHistoryResponse.prices:type_name -> tracker.v1.PricePoint
+	6,  // 2: tracker.v1.ListHoldingsResponse.holdings:type_name -> tracker.v1.Holding
+	17, // 3: tracker.v1.Transaction.date:type_name -> google.protobuf.Timestamp
+	9,  // 4: tracker.v1.ListTransactionsResponse.transactions:type_name -> tracker.v1.Transaction
+	12, // 5: tracker.v1.Dashboard.allocation:type_name -> tracker.v1.AllocationItem
+	17, // 6: tracker.v1.PerformancePoint.date:type_name -> google.protobuf.Timestamp
+	15, // 7: tracker.v1.Performance.points:type_name -> tracker.v1.PerformancePoint
+	0,  // 8: tracker.v1.StockService.GetQuote:input_type -> tracker.v1.GetQuoteRequest
+	2,  // 9: tracker.v1.StockService.GetHistory:input_type -> tracker.v1.GetHistoryRequest
+	5,  // 10: tracker.v1.PortfolioService.ListHoldings:input_type -> tracker.v1.ListHoldingsRequest
+	8,  // 11: tracker.v1.PortfolioService.ListTransactions:input_type -> tracker.v1.ListTransactionsRequest
+	11, // 12: tracker.v1.AnalyticsService.GetDashboard:input_type -> tracker.v1.GetDashboardRequest
+	14, // 13: tracker.v1.AnalyticsService.GetPerformance:input_type -> tracker.v1.GetPerformanceRequest
+	1,  // 14: tracker.v1.StockService.GetQuote:output_type -> tracker.v1.Quote
+	4,  // 15: tracker.v1.StockService.GetHistory:output_type -> tracker.v1.GetHistoryResponse
+	7,  // 16: tracker.v1.PortfolioService.ListHoldings:output_type -> tracker.v1.ListHoldingsResponse
+	10, // 17: tracker.v1.PortfolioService.ListTransactions:output_type -> tracker.v1.ListTransactionsResponse
+	13, // 18: tracker.v1.AnalyticsService.GetDashboard:output_type -> tracker.v1.Dashboard
+	16, // 19: tracker.v1.AnalyticsService.GetPerformance:output_type -> tracker.v1.Performance
+	14, // [14:20] is the sub-list for method output_type
+	8,  // [8:14] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_tracker_v1_tracker_proto_init() }
+func file_tracker_v1_tracker_proto_init() {
+	if File_tracker_v1_tracker_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_tracker_v1_tracker_proto_rawDesc), len(file_tracker_v1_tracker_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   17,
+			NumExtensions: 0,
+			NumServices:   3,
+		},
+		GoTypes:           file_tracker_v1_tracker_proto_goTypes,
+		DependencyIndexes: file_tracker_v1_tracker_proto_depIdxs,
+		MessageInfos:      file_tracker_v1_tracker_proto_msgTypes,
+	}.Build()
+	File_tracker_v1_tracker_proto = out.File
+	file_tracker_v1_tracker_proto_goTypes = nil
+	file_tracker_v1_tracker_proto_depIdxs = nil
+}
@@ -0,0 +1,1611 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/money"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewMemory returns empty in-memory repositories for tests. They are safe for
+// concurrent use and mirror the MongoDB implementations' filtering, ordering
+// and not-found behaviour.
+func NewMemory() Repositories {
+	symbols := &MemorySymbols{}
+	return Repositories{
+		Transactions:    &MemoryTransactions{Symbols: symbols},
+		Portfolios:      &MemoryPortfolios{},
+		AssetStyles:     &MemoryAssetStyles{},
+		AssetSubclasses: &MemoryAssetSubclasses{},
+		PendingOrders:   &MemoryPendingOrders{},
+		Webhooks:        &MemoryWebhooks{},
+		BrokerageLinks:  &MemoryBrokerageLinks{},
+		SheetsLinks:     &MemorySheetsLinks{},
+		CashInterest:    &MemoryCashInterest{},
+		PriceOverrides:  &MemoryPriceOverrides{},
+		Sessions:        &MemorySessions{},
+		Users:           &MemoryUsers{},
+		Avatars:         &MemoryAvatars{},
+		Settings:        &MemorySettings{},
+		Outbox:          &MemoryOutbox{},
+		Maintenance:     &MemoryMaintenance{},
+		FeatureFlags:    &MemoryFeatureFlags{},
+		Symbols:         symbols,
+		Tx:              MemoryTransactor{},
+	}
+}
+
+// MemoryTransactions is an in-memory TransactionRepo
+type MemoryTransactions struct {
+	mu   sync.RWMutex
+	docs []models.Transaction
+
+	// Symbols resolves renamed symbols, none when nil
+	Symbols *MemorySymbols
+}
+
+func (r *MemoryTransactions) Insert(ctx context.Context, tx *models.Transaction) error {
+	if err := checkOwner(tx.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if tx.ExternalID != "" {
+		for _, existing := range r.docs {
+			if existing.UserID == tx.UserID && existing.Broker == tx.Broker && existing.ExternalID == tx.ExternalID {
+				return ErrDuplicate
+			}
+		}
+	}
+	r.docs = append(r.docs, *tx)
+	return nil
+}
+
+func (r *MemoryTransactions) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, tx := range r.docs {
+		if tx.ID == id && tx.UserID == userID {
+			return &tx, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryTransactions) Replace(ctx context.Context, tx *models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == tx.ID && r.docs[i].UserID == tx.UserID {
+			r.docs[i] = *tx
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryTransactions) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, tx := range r.docs {
+		if tx.ID == id && tx.UserID == userID {
+			r.docs = append(r.docs[:i], r.docs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// filter returns copies of the user's transactions matching keep
+func (r *MemoryTransactions) filter(userID primitive.ObjectID, keep func(models.Transaction) bool) []models.Transaction {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	transactions := []models.Transaction{}
+	for _, tx := range r.docs {
+		if tx.UserID == userID && keep(tx) {
+			transactions = append(transactions, tx)
+		}
+	}
+	return transactions
+}
+
+// sortByDate orders transactions by date then creation time, oldest first
+func sortByDate(transactions []models.Transaction) {
+	sort.SliceStable(transactions, func(i, j int) bool {
+		if !transactions[i].Date.Equal(transactions[j].Date) {
+			return transactions[i].Date.Before(transactions[j].Date)
+		}
+		return transactions[i].CreatedAt.Before(transactions[j].CreatedAt)
+	})
+}
+
+func (r *MemoryTransactions) FindBySymbol(ctx context.Context, userID primitive.ObjectID, symbol string) ([]models.Transaction, error) {
+	return r.filter(userID, func(tx models.Transaction) bool {
+		return tx.Symbol == symbol || r.Symbols.resolve(tx.Symbol) == symbol
+	}), nil
+}
+
+func (r *MemoryTransactions) FindByTag(ctx context.Context, userID primitive.ObjectID, tag string) ([]models.Transaction, error) {
+	return r.newestFirst(userID, func(tx models.Transaction) bool {
+		for _, t := range tx.Tags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (r *MemoryTransactions) FindSince(ctx context.Context, userID primitive.ObjectID, since time.Time) ([]models.Transaction, error) {
+	return r.newestFirst(userID, func(tx models.Transaction) bool { return !tx.Date.Before(since) }), nil
+}
+
+// newestFirst returns the user's transactions matching keep, newest first
+func (r *MemoryTransactions) newestFirst(userID primitive.ObjectID, keep func(models.Transaction) bool) []models.Transaction {
+	transactions := r.filter(userID, keep)
+	sortByDate(transactions)
+	for i, j := 0, len(transactions)-1; i < j; i, j = i+1, j-1 {
+		transactions[i], transactions[j] = transactions[j], transactions[i]
+	}
+	return transactions
+}
+
+func (r *MemoryTransactions) FindBySymbolsBetween(ctx context.Context, userID primitive.ObjectID, symbols []string, start, end time.Time) ([]models.Transaction, error) {
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[symbol] = true
+	}
+	return r.filter(userID, func(tx models.Transaction) bool {
+		return wanted[tx.Symbol] && !tx.Date.Before(start) && !tx.Date.After(end)
+	}), nil
+}
+
+func (r *MemoryTransactions) FindByExternalIDs(ctx context.Context, userID primitive.ObjectID, broker string, ids []string) ([]models.Transaction, error) {
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	return r.filter(userID, func(tx models.Transaction) bool {
+		return tx.Broker == broker && tx.ExternalID != "" && wanted[tx.ExternalID]
+	}), nil
+}
+
+func (r *MemoryTransactions) Stream(ctx context.Context, userID primitive.ObjectID, fn func(models.Transaction) error) error {
+	transactions := r.filter(userID, func(models.Transaction) bool { return true })
+	sortByDate(transactions)
+	for _, tx := range transactions {
+		projected := models.Transaction{ID: tx.ID, Symbol: tx.Symbol, Action: tx.Action, Shares: tx.Shares, Date: tx.Date}
+		if err := fn(projected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Positions folds transactions the same way as the MongoDB holdings pipeline,
+// keeping the cost basis in minor units of the position's currency.
+// Positions are ordered by symbol.
+func (r *MemoryTransactions) Positions(ctx context.Context, userID primitive.ObjectID) ([]Position, error) {
+	return foldPositions(r.renamed(r.filter(userID, func(models.Transaction) bool { return true }))), nil
+}
+
+func (r *MemoryTransactions) PositionsBefore(ctx context.Context, userID primitive.ObjectID, before time.Time) ([]Position, error) {
+	return foldPositions(r.renamed(r.filter(userID, func(tx models.Transaction) bool { return tx.Date.Before(before) }))), nil
+}
+
+// renamed moves transactions in renamed symbols to the current ticker, like
+// the lookup in holdingsPipeline
+func (r *MemoryTransactions) renamed(transactions []models.Transaction) []models.Transaction {
+	for i := range transactions {
+		transactions[i].Symbol = r.Symbols.resolve(transactions[i].Symbol)
+	}
+	return transactions
+}
+
+// foldPositions folds transactions into open positions with the average cost
+// method, like holdingsPipeline
+func foldPositions(transactions []models.Transaction) []Position {
+	sortByDate(transactions)
+
+	bySymbol := make(map[string]*Position)
+	costs := make(map[string]money.Amount)
+	for _, tx := range transactions {
+		position, ok := bySymbol[tx.Symbol]
+		if !ok {
+			position = &Position{Symbol: tx.Symbol, Currency: tx.Currency}
+			bySymbol[tx.Symbol] = position
+			costs[tx.Symbol] = money.Zero(tx.Currency)
+		}
+		switch {
+		case tx.Action == "buy":
+			position.Shares += tx.Shares
+			costs[tx.Symbol] = costs[tx.Symbol].Add(money.New(tx.Price*tx.Shares+tx.Fees, position.Currency))
+		case tx.Action == "sell" && position.Shares > 0:
+			cost := costs[tx.Symbol]
+			costs[tx.Symbol] = cost.Sub(cost.Mul(tx.Shares / position.Shares))
+			position.Shares -= tx.Shares
+		}
+	}
+
+	positions := []Position{}
+	for symbol, position := range bySymbol {
+		if position.Shares > 0 {
+			position.Cost = costs[symbol].Float64()
+			positions = append(positions, *position)
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Symbol < positions[j].Symbol })
+	return positions
+}
+
+func (r *MemoryTransactions) Version(ctx context.Context, userID primitive.ObjectID) (Version, error) {
+	var version Version
+	for _, tx := range r.filter(userID, func(models.Transaction) bool { return true }) {
+		version.Count++
+		if tx.UpdatedAt.After(version.LatestUpdate) {
+			version.LatestUpdate = tx.UpdatedAt
+		}
+	}
+	return version, nil
+}
+
+// MemoryPortfolios is an in-memory PortfolioRepo
+type MemoryPortfolios struct {
+	mu   sync.RWMutex
+	docs []models.Portfolio
+}
+
+func (r *MemoryPortfolios) Insert(ctx context.Context, portfolio *models.Portfolio) error {
+	if err := checkOwner(portfolio.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, *portfolio)
+	return nil
+}
+
+// find returns a copy of the first of the user's portfolios matching keep
+func (r *MemoryPortfolios) find(userID primitive.ObjectID, keep func(models.Portfolio) bool) (*models.Portfolio, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, portfolio := range r.docs {
+		if portfolio.UserID == userID && keep(portfolio) {
+			return &portfolio, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryPortfolios) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.Portfolio, error) {
+	return r.find(userID, func(p models.Portfolio) bool { return p.ID == id })
+}
+
+func (r *MemoryPortfolios) FindBySymbol(ctx context.Context, userID primitive.ObjectID, symbol string) (*models.Portfolio, error) {
+	return r.find(userID, func(p models.Portfolio) bool { return p.Symbol == symbol })
+}
+
+func (r *MemoryPortfolios) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Portfolio, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	portfolios := []models.Portfolio{}
+	for _, portfolio := range r.docs {
+		if portfolio.UserID == userID {
+			portfolios = append(portfolios, portfolio)
+		}
+	}
+	return portfolios, nil
+}
+
+func (r *MemoryPortfolios) UpdateMetadata(ctx context.Context, userID, id, assetStyleID primitive.ObjectID, assetClass string, assetSubclassID *primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == id && r.docs[i].UserID == userID {
+			styleID := assetStyleID
+			r.docs[i].AssetStyleID = &styleID
+			r.docs[i].AssetClass = assetClass
+			r.docs[i].AssetSubclassID = assetSubclassID
+			r.docs[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryPortfolios) ReassignAssetStyle(ctx context.Context, userID, from, to primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].UserID == userID && r.docs[i].AssetStyleID != nil && *r.docs[i].AssetStyleID == from {
+			styleID := to
+			r.docs[i].AssetStyleID = &styleID
+			r.docs[i].UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (r *MemoryPortfolios) ClearAssetSubclass(ctx context.Context, userID, assetSubclassID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].UserID == userID && r.docs[i].AssetSubclassID != nil && *r.docs[i].AssetSubclassID == assetSubclassID {
+			r.docs[i].AssetSubclassID = nil
+			r.docs[i].UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (r *MemoryPortfolios) CountByAssetStyle(ctx context.Context, userID, assetStyleID primitive.ObjectID) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var count int64
+	for _, portfolio := range r.docs {
+		if portfolio.UserID == userID && portfolio.AssetStyleID != nil && *portfolio.AssetStyleID == assetStyleID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryPortfolios) SetTags(ctx context.Context, userID, id primitive.ObjectID, tags []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == id && r.docs[i].UserID == userID {
+			r.docs[i].Tags = nil
+			if len(tags) > 0 {
+				r.docs[i].Tags = append([]string(nil), tags...)
+			}
+			r.docs[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryPortfolios) SetStop(ctx context.Context, userID, id primitive.ObjectID, stop *models.PositionStop) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == id && r.docs[i].UserID == userID {
+			r.docs[i].Stop = copyStop(stop)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryPortfolios) FindWatchedStops(ctx context.Context) ([]models.Portfolio, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	portfolios := []models.Portfolio{}
+	for _, portfolio := range r.docs {
+		if portfolio.Stop != nil && portfolio.Stop.TriggeredAt == nil {
+			portfolio.Stop = copyStop(portfolio.Stop)
+			portfolios = append(portfolios, portfolio)
+		}
+	}
+	return portfolios, nil
+}
+
+func (r *MemoryPortfolios) UpdateStop(ctx context.Context, portfolio *models.Portfolio, from time.Time) error {
+	if err := checkOwner(portfolio.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		doc := &r.docs[i]
+		if doc.ID == portfolio.ID && doc.UserID == portfolio.UserID && doc.Stop != nil && doc.Stop.UpdatedAt.Equal(from) {
+			doc.Stop = copyStop(portfolio.Stop)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// copyStop copies a stop so stored entries don't alias the caller's
+func copyStop(stop *models.PositionStop) *models.PositionStop {
+	if stop == nil {
+		return nil
+	}
+	copied := *stop
+	return &copied
+}
+
+func (r *MemoryPortfolios) Version(ctx context.Context, userID primitive.ObjectID) (Version, error) {
+	portfolios, _ := r.FindByUser(ctx, userID)
+	version := Version{Count: int64(len(portfolios))}
+	for _, portfolio := range portfolios {
+		if portfolio.UpdatedAt.After(version.LatestUpdate) {
+			version.LatestUpdate = portfolio.UpdatedAt
+		}
+	}
+	return version, nil
+}
+
+// MemoryAssetStyles is an in-memory AssetStyleRepo
+type MemoryAssetStyles struct {
+	mu   sync.RWMutex
+	docs []models.AssetStyle
+}
+
+func (r *MemoryAssetStyles) Insert(ctx context.Context, style *models.AssetStyle) error {
+	if err := checkOwner(style.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, *style)
+	return nil
+}
+
+// find returns a copy of the first of the user's styles matching keep
+func (r *MemoryAssetStyles) find(userID primitive.ObjectID, keep func(models.AssetStyle) bool) (*models.AssetStyle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, style := range r.docs {
+		if style.UserID == userID && keep(style) {
+			return &style, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryAssetStyles) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.AssetStyle, error) {
+	return r.find(userID, func(s models.AssetStyle) bool { return s.ID == id })
+}
+
+func (r *MemoryAssetStyles) FindByName(ctx context.Context, userID primitive.ObjectID, name string) (*models.AssetStyle, error) {
+	return r.find(userID, func(s models.AssetStyle) bool { return s.Name == name })
+}
+
+func (r *MemoryAssetStyles) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.AssetStyle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	styles := []models.AssetStyle{}
+	for _, style := range r.docs {
+		if style.UserID == userID {
+			styles = append(styles, style)
+		}
+	}
+	return styles, nil
+}
+
+func (r *MemoryAssetStyles) Update(ctx context.Context, style *models.AssetStyle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == style.ID && r.docs[i].UserID == style.UserID {
+			r.docs[i].Name = style.Name
+			r.docs[i].Color = style.Color
+			r.docs[i].Icon = style.Icon
+			r.docs[i].UpdatedAt = style.UpdatedAt
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryAssetStyles) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, style := range r.docs {
+		if style.ID == id && style.UserID == userID {
+			r.docs = append(r.docs[:i], r.docs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryAssetStyles) Version(ctx context.Context, userID primitive.ObjectID) (Version, error) {
+	styles, _ := r.FindByUser(ctx, userID)
+	version := Version{Count: int64(len(styles))}
+	for _, style := range styles {
+		if style.UpdatedAt.After(version.LatestUpdate) {
+			version.LatestUpdate = style.UpdatedAt
+		}
+	}
+	return version, nil
+}
+
+// MemoryAssetSubclasses is an in-memory AssetSubclassRepo
+type MemoryAssetSubclasses struct {
+	mu   sync.RWMutex
+	docs []models.AssetSubclass
+}
+
+func (r *MemoryAssetSubclasses) Insert(ctx context.Context, subclass *models.AssetSubclass) error {
+	if err := checkOwner(subclass.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, *subclass)
+	return nil
+}
+
+func (r *MemoryAssetSubclasses) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.AssetSubclass, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, subclass := range r.docs {
+		if subclass.ID == id && subclass.UserID == userID {
+			return &subclass, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryAssetSubclasses) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.AssetSubclass, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	subclasses := []models.AssetSubclass{}
+	for _, subclass := range r.docs {
+		if subclass.UserID == userID {
+			subclasses = append(subclasses, subclass)
+		}
+	}
+	sort.Slice(subclasses, func(i, j int) bool { return subclasses[i].Name < subclasses[j].Name })
+	return subclasses, nil
+}
+
+func (r *MemoryAssetSubclasses) Rename(ctx context.Context, userID, id primitive.ObjectID, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == id && r.docs[i].UserID == userID {
+			r.docs[i].Name = name
+			r.docs[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryAssetSubclasses) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, subclass := range r.docs {
+		if subclass.ID == id && subclass.UserID == userID {
+			r.docs = append(r.docs[:i], r.docs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryAssetSubclasses) Version(ctx context.Context, userID primitive.ObjectID) (Version, error) {
+	subclasses, _ := r.FindByUser(ctx, userID)
+	version := Version{Count: int64(len(subclasses))}
+	for _, subclass := range subclasses {
+		if subclass.UpdatedAt.After(version.LatestUpdate) {
+			version.LatestUpdate = subclass.UpdatedAt
+		}
+	}
+	return version, nil
+}
+
+// MemoryPendingOrders is an in-memory PendingOrderRepo
+type MemoryPendingOrders struct {
+	mu   sync.RWMutex
+	docs []models.PendingOrder
+}
+
+func (r *MemoryPendingOrders) Insert(ctx context.Context, order *models.PendingOrder) error {
+	if err := checkOwner(order.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, *order)
+	return nil
+}
+
+func (r *MemoryPendingOrders) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.PendingOrder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, order := range r.docs {
+		if order.ID == id && order.UserID == userID {
+			return &order, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryPendingOrders) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.PendingOrder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	orders := []models.PendingOrder{}
+	for _, order := range r.docs {
+		if order.UserID == userID {
+			orders = append(orders, order)
+		}
+	}
+	sort.SliceStable(orders, func(i, j int) bool { return orders[i].CreatedAt.After(orders[j].CreatedAt) })
+	return orders, nil
+}
+
+func (r *MemoryPendingOrders) FindOpen(ctx context.Context) ([]models.PendingOrder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	orders := []models.PendingOrder{}
+	for _, order := range r.docs {
+		if order.Status == models.PendingOrderOpen {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+func (r *MemoryPendingOrders) Transition(ctx context.Context, order *models.PendingOrder, from string) error {
+	if err := checkOwner(order.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == order.ID && r.docs[i].UserID == order.UserID && r.docs[i].Status == from {
+			r.docs[i] = *order
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// MemoryBrokerageLinks is an in-memory BrokerageLinkRepo
+type MemoryBrokerageLinks struct {
+	mu   sync.RWMutex
+	docs []models.BrokerageLink
+}
+
+func (r *MemoryBrokerageLinks) Insert(ctx context.Context, link *models.BrokerageLink) error {
+	if err := checkOwner(link.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, *link)
+	return nil
+}
+
+func (r *MemoryBrokerageLinks) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.BrokerageLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, link := range r.docs {
+		if link.ID == id && link.UserID == userID {
+			return &link, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryBrokerageLinks) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.BrokerageLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	links := []models.BrokerageLink{}
+	for _, link := range r.docs {
+		if link.UserID == userID {
+			links = append(links, link)
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool { return links[i].CreatedAt.Before(links[j].CreatedAt) })
+	return links, nil
+}
+
+func (r *MemoryBrokerageLinks) FindSyncable(ctx context.Context) ([]models.BrokerageLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	links := []models.BrokerageLink{}
+	for _, link := range r.docs {
+		if link.Status == models.BrokerageLinkActive || link.Status == models.BrokerageLinkError {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (r *MemoryBrokerageLinks) Update(ctx context.Context, link *models.BrokerageLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == link.ID && r.docs[i].UserID == link.UserID {
+			r.docs[i] = *link
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryBrokerageLinks) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, link := range r.docs {
+		if link.ID == id && link.UserID == userID {
+			r.docs = append(r.docs[:i], r.docs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryBrokerageLinks) ClaimSync(ctx context.Context, userID, id primitive.ObjectID, from *time.Time, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		doc := &r.docs[i]
+		if doc.ID != id || doc.UserID != userID {
+			continue
+		}
+		if (from == nil) != (doc.LastSyncedAt == nil) || (from != nil && !from.Equal(*doc.LastSyncedAt)) {
+			return ErrNotFound
+		}
+		doc.LastSyncedAt = &at
+		return nil
+	}
+	return ErrNotFound
+}
+
+// MemorySheetsLinks is an in-memory SheetsLinkRepo
+type MemorySheetsLinks struct {
+	mu   sync.RWMutex
+	docs []models.SheetsLink
+}
+
+func (r *MemorySheetsLinks) Insert(ctx context.Context, link *models.SheetsLink) error {
+	if err := checkOwner(link.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, doc := range r.docs {
+		if doc.UserID == link.UserID {
+			return ErrDuplicate
+		}
+	}
+	r.docs = append(r.docs, *link)
+	return nil
+}
+
+func (r *MemorySheetsLinks) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.SheetsLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, link := range r.docs {
+		if link.UserID == userID {
+			return &link, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemorySheetsLinks) FindSyncable(ctx context.Context) ([]models.SheetsLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	links := []models.SheetsLink{}
+	for _, link := range r.docs {
+		if link.Status == models.SheetsLinkActive || link.Status == models.SheetsLinkError {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (r *MemorySheetsLinks) Update(ctx context.Context, link *models.SheetsLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == link.ID && r.docs[i].UserID == link.UserID {
+			r.docs[i] = *link
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemorySheetsLinks) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, link := range r.docs {
+		if link.UserID == userID {
+			r.docs = append(r.docs[:i], r.docs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemorySheetsLinks) ClaimSync(ctx context.Context, userID, id primitive.ObjectID, from *time.Time, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		doc := &r.docs[i]
+		if doc.ID != id || doc.UserID != userID {
+			continue
+		}
+		if (from == nil) != (doc.LastSyncedAt == nil) || (from != nil && !from.Equal(*doc.LastSyncedAt)) {
+			return ErrNotFound
+		}
+		doc.LastSyncedAt = &at
+		return nil
+	}
+	return ErrNotFound
+}
+
+// MemoryWebhooks is an in-memory WebhookRepo
+type MemoryWebhooks struct {
+	mu   sync.RWMutex
+	docs []models.Webhook
+}
+
+func (r *MemoryWebhooks) Insert(ctx context.Context, webhook *models.Webhook) error {
+	if err := checkOwner(webhook.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, *webhook)
+	return nil
+}
+
+func (r *MemoryWebhooks) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, webhook := range r.docs {
+		if webhook.ID == id && webhook.UserID == userID {
+			return &webhook, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryWebhooks) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	webhooks := []models.Webhook{}
+	for _, webhook := range r.docs {
+		if webhook.UserID == userID {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	sort.SliceStable(webhooks, func(i, j int) bool { return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt) })
+	return webhooks, nil
+}
+
+func (r *MemoryWebhooks) FindByEvent(ctx context.Context, event string) ([]models.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	webhooks := []models.Webhook{}
+	for _, webhook := range r.docs {
+		if slices.Contains(webhook.Events, event) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (r *MemoryWebhooks) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, webhook := range r.docs {
+		if webhook.ID == id && webhook.UserID == userID {
+			r.docs = append(r.docs[:i], r.docs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryWebhooks) RecordDelivery(ctx context.Context, userID, id primitive.ObjectID, at time.Time, lastError string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == id && r.docs[i].UserID == userID {
+			r.docs[i].LastDeliveryAt = &at
+			r.docs[i].LastError = lastError
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryWebhooks) ClaimSummary(ctx context.Context, userID, id primitive.ObjectID, from *time.Time, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		doc := &r.docs[i]
+		if doc.ID != id || doc.UserID != userID {
+			continue
+		}
+		if (from == nil) != (doc.LastSummaryAt == nil) || (from != nil && !from.Equal(*doc.LastSummaryAt)) {
+			return ErrNotFound
+		}
+		doc.LastSummaryAt = &at
+		return nil
+	}
+	return ErrNotFound
+}
+
+// MemoryCashInterest is an in-memory CashInterestRepo
+type MemoryCashInterest struct {
+	mu   sync.RWMutex
+	docs []models.CashInterestRate
+}
+
+func (r *MemoryCashInterest) Insert(ctx context.Context, rate *models.CashInterestRate) error {
+	if err := checkOwner(rate.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, *rate)
+	return nil
+}
+
+func (r *MemoryCashInterest) FindBySymbol(ctx context.Context, userID primitive.ObjectID, symbol string) (*models.CashInterestRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, rate := range r.docs {
+		if rate.UserID == userID && rate.Symbol == symbol {
+			return &rate, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryCashInterest) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.CashInterestRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rates := []models.CashInterestRate{}
+	for _, rate := range r.docs {
+		if rate.UserID == userID {
+			rates = append(rates, rate)
+		}
+	}
+	sort.SliceStable(rates, func(i, j int) bool { return rates[i].Symbol < rates[j].Symbol })
+	return rates, nil
+}
+
+func (r *MemoryCashInterest) FindAccruing(ctx context.Context) ([]models.CashInterestRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rates := []models.CashInterestRate{}
+	for _, rate := range r.docs {
+		if rate.APY > 0 {
+			rates = append(rates, rate)
+		}
+	}
+	return rates, nil
+}
+
+func (r *MemoryCashInterest) Update(ctx context.Context, rate *models.CashInterestRate, from time.Time) error {
+	if err := checkOwner(rate.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == rate.ID && r.docs[i].UserID == rate.UserID && r.docs[i].AccruedThrough.Equal(from) {
+			r.docs[i] = *rate
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryCashInterest) Delete(ctx context.Context, userID primitive.ObjectID, symbol string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].UserID == userID && r.docs[i].Symbol == symbol {
+			r.docs = append(r.docs[:i], r.docs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// MemoryPriceOverrides is an in-memory PriceOverrideRepo
+type MemoryPriceOverrides struct {
+	mu   sync.RWMutex
+	docs []models.PriceOverride
+}
+
+func (r *MemoryPriceOverrides) Insert(ctx context.Context, override *models.PriceOverride) error {
+	if err := checkOwner(override.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, *override)
+	return nil
+}
+
+func (r *MemoryPriceOverrides) FindBySymbol(ctx context.Context, userID primitive.ObjectID, symbol string) (*models.PriceOverride, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, override := range r.docs {
+		if override.UserID == userID && override.Symbol == symbol {
+			return &override, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryPriceOverrides) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.PriceOverride, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	overrides := []models.PriceOverride{}
+	for _, override := range r.docs {
+		if override.UserID == userID {
+			overrides = append(overrides, override)
+		}
+	}
+	sort.SliceStable(overrides, func(i, j int) bool { return overrides[i].Symbol < overrides[j].Symbol })
+	return overrides, nil
+}
+
+func (r *MemoryPriceOverrides) Update(ctx context.Context, override *models.PriceOverride) error {
+	if err := checkOwner(override.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == override.ID && r.docs[i].UserID == override.UserID {
+			r.docs[i].Price = override.Price
+			r.docs[i].Currency = override.Currency
+			r.docs[i].EffectiveDate = override.EffectiveDate
+			r.docs[i].Note = override.Note
+			r.docs[i].UpdatedAt = override.UpdatedAt
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryPriceOverrides) Delete(ctx context.Context, userID primitive.ObjectID, symbol string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].UserID == userID && r.docs[i].Symbol == symbol {
+			r.docs = append(r.docs[:i], r.docs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryPriceOverrides) Version(ctx context.Context, userID primitive.ObjectID) (Version, error) {
+	overrides, _ := r.FindByUser(ctx, userID)
+	version := Version{Count: int64(len(overrides))}
+	for _, override := range overrides {
+		if override.UpdatedAt.After(version.LatestUpdate) {
+			version.LatestUpdate = override.UpdatedAt
+		}
+	}
+	return version, nil
+}
+
+// MemorySessions is an in-memory SessionRepo
+type MemorySessions struct {
+	mu   sync.RWMutex
+	docs []models.Session
+}
+
+func (r *MemorySessions) Insert(ctx context.Context, session *models.Session) error {
+	if err := checkOwner(session.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, *session)
+	return nil
+}
+
+func (r *MemorySessions) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, session := range r.docs {
+		if session.ID == id && session.UserID == userID {
+			return &session, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemorySessions) FindActive(ctx context.Context, userID primitive.ObjectID, now time.Time) ([]models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sessions := []models.Session{}
+	for _, session := range r.docs {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+	return sessions, nil
+}
+
+func (r *MemorySessions) Touch(ctx context.Context, userID, id primitive.ObjectID, ip string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == id && r.docs[i].UserID == userID {
+			r.docs[i].IP = ip
+			r.docs[i].LastSeenAt = at
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemorySessions) Revoke(ctx context.Context, userID, id primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == id && r.docs[i].UserID == userID && r.docs[i].RevokedAt == nil {
+			r.docs[i].RevokedAt = &at
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemorySessions) RevokeAllExcept(ctx context.Context, userID, keep primitive.ObjectID, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var revoked int64
+	for i := range r.docs {
+		if r.docs[i].UserID == userID && r.docs[i].ID != keep && r.docs[i].RevokedAt == nil {
+			r.docs[i].RevokedAt = &at
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (r *MemorySessions) FindActiveUsers(ctx context.Context, since time.Time) ([]primitive.ObjectID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seen := make(map[primitive.ObjectID]bool)
+	userIDs := []primitive.ObjectID{}
+	for _, session := range r.docs {
+		if session.RevokedAt == nil && !session.LastSeenAt.Before(since) && !seen[session.UserID] {
+			seen[session.UserID] = true
+			userIDs = append(userIDs, session.UserID)
+		}
+	}
+	return userIDs, nil
+}
+
+// MemoryUsers is an in-memory UserRepo
+type MemoryUsers struct {
+	mu   sync.RWMutex
+	docs []models.User
+}
+
+func (r *MemoryUsers) Insert(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, *user)
+	return nil
+}
+
+func (r *MemoryUsers) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, user := range r.docs {
+		if user.ID == id {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUsers) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, user := range r.docs {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUsers) UpdateProfile(ctx context.Context, id primitive.ObjectID, profile models.UserProfile, at time.Time) error {
+	return r.update(id, func(user *models.User) {
+		user.DisplayName = profile.DisplayName
+		user.BaseCurrency = profile.BaseCurrency
+		user.Country = profile.Country
+		user.UpdatedAt = at
+	})
+}
+
+func (r *MemoryUsers) SetAvatar(ctx context.Context, id primitive.ObjectID, avatarID *primitive.ObjectID, at time.Time) error {
+	return r.update(id, func(user *models.User) {
+		user.AvatarID = avatarID
+		user.UpdatedAt = at
+	})
+}
+
+func (r *MemoryUsers) SetPassword(ctx context.Context, id primitive.ObjectID, hash string, at time.Time) error {
+	return r.update(id, func(user *models.User) {
+		user.Password = hash
+		user.UpdatedAt = at
+	})
+}
+
+func (r *MemoryUsers) FindIDs(ctx context.Context) ([]primitive.ObjectID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]primitive.ObjectID, 0, len(r.docs))
+	for _, user := range r.docs {
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+// update applies apply to the user, returning ErrNotFound if there is none
+func (r *MemoryUsers) update(id primitive.ObjectID, apply func(user *models.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == id {
+			apply(&r.docs[i])
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// memoryAvatar is an avatar held by MemoryAvatars
+type memoryAvatar struct {
+	userID      primitive.ObjectID
+	contentType string
+	data        []byte
+}
+
+// MemoryAvatars is an in-memory AvatarRepo
+type MemoryAvatars struct {
+	mu    sync.RWMutex
+	files map[primitive.ObjectID]memoryAvatar
+}
+
+func (r *MemoryAvatars) Put(ctx context.Context, userID primitive.ObjectID, contentType string, data []byte) (primitive.ObjectID, error) {
+	if err := checkOwner(userID); err != nil {
+		return primitive.NilObjectID, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.files == nil {
+		r.files = map[primitive.ObjectID]memoryAvatar{}
+	}
+	id := primitive.NewObjectID()
+	r.files[id] = memoryAvatar{userID: userID, contentType: contentType, data: append([]byte(nil), data...)}
+	return id, nil
+}
+
+func (r *MemoryAvatars) Get(ctx context.Context, userID, id primitive.ObjectID) ([]byte, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	file, ok := r.files[id]
+	if !ok || file.userID != userID {
+		return nil, "", ErrNotFound
+	}
+	return append([]byte(nil), file.data...), file.contentType, nil
+}
+
+func (r *MemoryAvatars) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if file, ok := r.files[id]; !ok || file.userID != userID {
+		return ErrNotFound
+	}
+	delete(r.files, id)
+	return nil
+}
+
+// MemorySettings is an in-memory SettingsRepo
+type MemorySettings struct {
+	mu   sync.RWMutex
+	docs []models.UserSettings
+}
+
+// Put stores a user's settings, replacing any with the same ID, for tests to
+// seed
+func (r *MemorySettings) Put(settings models.UserSettings) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == settings.ID {
+			r.docs[i] = settings
+			return
+		}
+	}
+	r.docs = append(r.docs, settings)
+}
+
+// Get returns a copy of the settings with the ID, for tests to inspect
+func (r *MemorySettings) Get(id primitive.ObjectID) (models.UserSettings, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, settings := range r.docs {
+		if settings.ID == id {
+			return settings, true
+		}
+	}
+	return models.UserSettings{}, false
+}
+
+func (r *MemorySettings) FindDriftAlertSubscribers(ctx context.Context) ([]models.UserSettings, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	subscribers := []models.UserSettings{}
+	for _, settings := range r.docs {
+		if settings.DriftAlerts.Enabled {
+			subscribers = append(subscribers, settings)
+		}
+	}
+	return subscribers, nil
+}
+
+func (r *MemorySettings) ClaimDriftAlert(ctx context.Context, userID, id primitive.ObjectID, expected, breached []string, alertedAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		settings := &r.docs[i]
+		if settings.ID != id || settings.UserID != userID || !slices.Equal(settings.DriftAlerts.Breached, expected) {
+			continue
+		}
+		settings.DriftAlerts.Breached = append([]string(nil), breached...)
+		if alertedAt != nil {
+			at := *alertedAt
+			settings.DriftAlerts.LastAlertedAt = &at
+		}
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (r *MemorySettings) FindCrossAlertSubscribers(ctx context.Context) ([]models.UserSettings, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	subscribers := []models.UserSettings{}
+	for _, settings := range r.docs {
+		if settings.CrossAlerts.Enabled {
+			subscribers = append(subscribers, settings)
+		}
+	}
+	return subscribers, nil
+}
+
+func (r *MemorySettings) ClaimCrossAlert(ctx context.Context, userID, id primitive.ObjectID, expected, alerted []models.AlertedCross, alertedAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sameCross := func(a, b models.AlertedCross) bool {
+		return a.Symbol == b.Symbol && a.Type == b.Type && a.Date.Equal(b.Date)
+	}
+	for i := range r.docs {
+		settings := &r.docs[i]
+		if settings.ID != id || settings.UserID != userID || !slices.EqualFunc(settings.CrossAlerts.Alerted, expected, sameCross) {
+			continue
+		}
+		settings.CrossAlerts.Alerted = append([]models.AlertedCross(nil), alerted...)
+		if alertedAt != nil {
+			at := *alertedAt
+			settings.CrossAlerts.LastAlertedAt = &at
+		}
+		return nil
+	}
+	return ErrNotFound
+}
+
+// MemoryOutbox is an in-memory OutboxRepo
+type MemoryOutbox struct {
+	mu   sync.RWMutex
+	docs []models.OutboxMessage
+}
+
+func (r *MemoryOutbox) Insert(ctx context.Context, msg *models.OutboxMessage) error {
+	if err := checkOwner(msg.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, cloneOutboxMessage(*msg))
+	return nil
+}
+
+func (r *MemoryOutbox) ClaimDue(ctx context.Context, now, leaseUntil time.Time) (*models.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	due := -1
+	for i, msg := range r.docs {
+		if msg.Status == models.OutboxPending && !msg.NextAttemptAt.After(now) &&
+			(due < 0 || msg.NextAttemptAt.Before(r.docs[due].NextAttemptAt)) {
+			due = i
+		}
+	}
+	if due < 0 {
+		return nil, ErrNotFound
+	}
+	r.docs[due].NextAttemptAt = leaseUntil
+	r.docs[due].Attempts++
+	msg := cloneOutboxMessage(r.docs[due])
+	return &msg, nil
+}
+
+func (r *MemoryOutbox) Update(ctx context.Context, msg *models.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.docs {
+		if r.docs[i].ID == msg.ID {
+			r.docs[i] = cloneOutboxMessage(*msg)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// Messages returns a copy of every queued message, for tests to inspect
+func (r *MemoryOutbox) Messages() []models.OutboxMessage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	messages := make([]models.OutboxMessage, len(r.docs))
+	for i, msg := range r.docs {
+		messages[i] = cloneOutboxMessage(msg)
+	}
+	return messages
+}
+
+// cloneOutboxMessage copies a message so its channel statuses aren't shared
+// with the stored one
+func cloneOutboxMessage(msg models.OutboxMessage) models.OutboxMessage {
+	msg.Channels = append([]models.OutboxChannelStatus(nil), msg.Channels...)
+	return msg
+}
+
+// MemoryMaintenance is an in-memory MaintenanceRepo
+type MemoryMaintenance struct {
+	mu   sync.RWMutex
+	mode *models.MaintenanceMode
+}
+
+func (r *MemoryMaintenance) Get(ctx context.Context) (*models.MaintenanceMode, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.mode == nil {
+		return nil, ErrNotFound
+	}
+	mode := *r.mode
+	return &mode, nil
+}
+
+func (r *MemoryMaintenance) Set(ctx context.Context, mode *models.MaintenanceMode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *mode
+	r.mode = &stored
+	return nil
+}
+
+// MemoryFeatureFlags is an in-memory FeatureFlagRepo
+type MemoryFeatureFlags struct {
+	mu    sync.RWMutex
+	flags map[string]models.FeatureFlag
+}
+
+func (r *MemoryFeatureFlags) FindAll(ctx context.Context) ([]models.FeatureFlag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	flags := []models.FeatureFlag{}
+	for _, flag := range r.flags {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Key < flags[j].Key })
+	return flags, nil
+}
+
+func (r *MemoryFeatureFlags) Save(ctx context.Context, flag *models.FeatureFlag) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.flags == nil {
+		r.flags = make(map[string]models.FeatureFlag)
+	}
+	r.flags[flag.Key] = *flag
+	return nil
+}
+
+func (r *MemoryFeatureFlags) Delete(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.flags[key]; !ok {
+		return ErrNotFound
+	}
+	delete(r.flags, key)
+	return nil
+}
+
+// MemorySymbols is an in-memory SymbolRepo
+type MemorySymbols struct {
+	mu      sync.RWMutex
+	symbols map[string]models.SymbolMetadata
+}
+
+func (r *MemorySymbols) FindAll(ctx context.Context) ([]models.SymbolMetadata, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	symbols := []models.SymbolMetadata{}
+	for _, symbol := range r.symbols {
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+func (r *MemorySymbols) Save(ctx context.Context, symbol *models.SymbolMetadata) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.symbols == nil {
+		r.symbols = make(map[string]models.SymbolMetadata)
+	}
+	stored := *symbol
+	if existing, ok := r.symbols[symbol.Symbol]; ok {
+		stored.FirstSeen = existing.FirstSeen
+		stored.RenamedTo = existing.RenamedTo
+		stored.RenamedAt = existing.RenamedAt
+	}
+	r.symbols[symbol.Symbol] = stored
+	return nil
+}
+
+func (r *MemorySymbols) Rename(ctx context.Context, from, to string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.symbols == nil {
+		r.symbols = make(map[string]models.SymbolMetadata)
+	}
+	for key, symbol := range r.symbols {
+		if symbol.RenamedTo == from {
+			symbol.RenamedTo, symbol.RenamedAt = to, &at
+			r.symbols[key] = symbol
+		}
+	}
+	symbol, ok := r.symbols[from]
+	if !ok {
+		symbol = models.SymbolMetadata{Symbol: from, FirstSeen: at}
+	}
+	symbol.RenamedTo, symbol.RenamedAt, symbol.UpdatedAt = to, &at, at
+	r.symbols[from] = symbol
+	return nil
+}
+
+// resolve returns the current ticker of a symbol. A nil catalog renames
+// nothing.
+func (r *MemorySymbols) resolve(symbol string) string {
+	if r == nil {
+		return symbol
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if renamed := r.symbols[symbol].RenamedTo; renamed != "" {
+		return renamed
+	}
+	return symbol
+}
+
+// MemoryTransactor runs functions directly. The in-memory repositories have
+// no rollback, so a failing function keeps the writes it made.
+type MemoryTransactor struct{}
+
+func (MemoryTransactor) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math"
+	"stock-portfolio-tracker/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTransaction(userID primitive.ObjectID, symbol, action string, shares, price float64, date time.Time) *models.Transaction {
+	return &models.Transaction{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Symbol:    symbol,
+		Action:    action,
+		Shares:    shares,
+		Price:     price,
+		Currency:  "USD",
+		Date:      date,
+		CreatedAt: date,
+		UpdatedAt: date,
+	}
+}
+
+func TestMemoryPositionsAverageCost(t *testing.T) {
+	ctx := context.Background()
+	repo := &MemoryTransactions{}
+	userID := primitive.NewObjectID()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Inserted out of order: the fold must follow the transaction dates
+	transactions := []*models.Transaction{
+		newTransaction(userID, "AAPL", "sell", 5, 200, day.AddDate(0, 0, 2)),
+		newTransaction(userID, "AAPL", "buy", 10, 100, day),
+		newTransaction(userID, "AAPL", "buy", 10, 150, day.AddDate(0, 0, 1)),
+		newTransaction(userID, "MSFT", "buy", 3, 300, day),
+		newTransaction(userID, "MSFT", "sell", 3, 310, day.AddDate(0, 0, 1)),
+		newTransaction(primitive.NewObjectID(), "AAPL", "buy", 1, 1, day),
+	}
+	transactions[1].Fees = 10
+	for _, tx := range transactions {
+		if err := repo.Insert(ctx, tx); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+
+	positions, err := repo.Positions(ctx, userID)
+	if err != nil {
+		t.Fatalf("Positions: %v", err)
+	}
+
+	// Closed MSFT position is dropped; AAPL keeps 15 of 20 shares at the
+	// average cost of (1000 + 10 + 1500) / 20
+	if len(positions) != 1 {
+		t.Fatalf("Expected 1 open position, got %+v", positions)
+	}
+	if positions[0].Symbol != "AAPL" || positions[0].Shares != 15 {
+		t.Errorf("Expected 15 AAPL shares, got %+v", positions[0])
+	}
+	if want := 2510.0 * 15 / 20; math.Abs(positions[0].Cost-want) > 1e-9 {
+		t.Errorf("Expected cost %.2f, got %.2f", want, positions[0].Cost)
+	}
+}
+
+func TestMemoryTransactionsOrderingAndNotFound(t *testing.T) {
+	ctx := context.Background()
+	repo := &MemoryTransactions{}
+	userID := primitive.NewObjectID()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	older := newTransaction(userID, "AAPL", "buy", 1, 100, day)
+	newer := newTransaction(userID, "MSFT", "buy", 1, 100, day.AddDate(0, 1, 0))
+	repo.Insert(ctx, older)
+	repo.Insert(ctx, newer)
+
+	since, _ := repo.FindSince(ctx, userID, day)
+	if len(since) != 2 || since[0].ID != newer.ID {
+		t.Errorf("Expected newest first, got %+v", since)
+	}
+
+	var streamed []string
+	repo.Stream(ctx, userID, func(tx models.Transaction) error {
+		streamed = append(streamed, tx.Symbol)
+		return nil
+	})
+	if len(streamed) != 2 || streamed[0] != "AAPL" {
+		t.Errorf("Expected oldest first, got %v", streamed)
+	}
+
+	if _, err := repo.FindByID(ctx, primitive.NewObjectID(), older.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for another user's transaction, got %v", err)
+	}
+	if err := repo.Delete(ctx, userID, older.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.Delete(ctx, userID, older.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+
+	version, _ := repo.Version(ctx, userID)
+	if version.Count != 1 || !version.LatestUpdate.Equal(newer.UpdatedAt) {
+		t.Errorf("Unexpected version %+v", version)
+	}
+}
+
+func TestMemoryPositionsFollowRenames(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemory()
+	userID := primitive.NewObjectID()
+	day := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tx := range []*models.Transaction{
+		newTransaction(userID, "FB", "buy", 10, 200, day),
+		newTransaction(userID, "META", "sell", 4, 300, day.AddDate(0, 1, 0)),
+	} {
+		if err := repos.Transactions.Insert(ctx, tx); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+	if err := repos.Symbols.Rename(ctx, "FB", "META", day.AddDate(0, 0, 9)); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+
+	// The sell in the new ticker reduces the position bought in the old one
+	positions, err := repos.Transactions.Positions(ctx, userID)
+	if err != nil {
+		t.Fatalf("Positions: %v", err)
+	}
+	if len(positions) != 1 || positions[0].Symbol != "META" || positions[0].Shares != 6 || math.Abs(positions[0].Cost-1200) > 1e-9 {
+		t.Fatalf("Expected one consolidated META position, got %+v", positions)
+	}
+
+	transactions, err := repos.Transactions.FindBySymbol(ctx, userID, "META")
+	if err != nil || len(transactions) != 2 {
+		t.Errorf("Expected META to include the FB transactions, got %d, %v", len(transactions), err)
+	}
+
+	// A later rename moves earlier ones along with it
+	if err := repos.Symbols.Rename(ctx, "META", "MVRS", day.AddDate(1, 0, 0)); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	positions, _ = repos.Transactions.Positions(ctx, userID)
+	if len(positions) != 1 || positions[0].Symbol != "MVRS" || positions[0].Shares != 6 {
+		t.Errorf("Expected the position under the latest ticker, got %+v", positions)
+	}
+}
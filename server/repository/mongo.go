@@ -0,0 +1,1517 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/money"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongo returns repositories backed by the connected database. Collections
+// are resolved on each call, so the repositories may be created before
+// database.Connect.
+func NewMongo() Repositories {
+	return Repositories{
+		Transactions:    mongoTransactions{},
+		Portfolios:      mongoPortfolios{},
+		AssetStyles:     mongoAssetStyles{},
+		AssetSubclasses: mongoAssetSubclasses{},
+		PendingOrders:   mongoPendingOrders{},
+		Webhooks:        mongoWebhooks{},
+		BrokerageLinks:  mongoBrokerageLinks{},
+		SheetsLinks:     mongoSheetsLinks{},
+		CashInterest:    mongoCashInterest{},
+		PriceOverrides:  mongoPriceOverrides{},
+		Sessions:        mongoSessions{},
+		Users:           mongoUsers{},
+		Avatars:         mongoAvatars{},
+		Settings:        mongoSettings{},
+		Outbox:          mongoOutbox{},
+		Maintenance:     mongoMaintenance{},
+		FeatureFlags:    mongoFeatureFlags{},
+		Symbols:         mongoSymbols{},
+		Tx:              &mongoTransactor{},
+	}
+}
+
+// findOne decodes the first unscoped document matching filter into out
+func findOne(ctx context.Context, collection *mongo.Collection, filter bson.M, out interface{}) error {
+	err := collection.FindOne(ctx, filter).Decode(out)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// version counts the user's documents and finds the latest update. The count
+// catches deletions, which leave no timestamp behind.
+func (s userScope) version(ctx context.Context) (Version, error) {
+	count, err := s.CountDocuments(ctx, nil)
+	if err != nil {
+		return Version{}, fmt.Errorf("failed to count %s: %w", s.collection.Name(), err)
+	}
+
+	var latest struct {
+		UpdatedAt time.Time `bson:"updated_at"`
+	}
+	findOptions := options.FindOne().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetProjection(bson.M{"updated_at": 1})
+	err = s.FindOne(ctx, nil, &latest, findOptions)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return Version{}, fmt.Errorf("failed to fetch latest %s update: %w", s.collection.Name(), err)
+	}
+
+	return Version{Count: count, LatestUpdate: latest.UpdatedAt}, nil
+}
+
+// mongoTransactions stores transactions in the transactions collection
+type mongoTransactions struct{}
+
+func (mongoTransactions) scope(userID primitive.ObjectID) userScope {
+	return scope(database.Database.Collection("transactions"), userID)
+}
+
+func (r mongoTransactions) Insert(ctx context.Context, tx *models.Transaction) error {
+	if err := checkOwner(tx.UserID); err != nil {
+		return err
+	}
+	_, err := database.Database.Collection("transactions").InsertOne(ctx, tx)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r mongoTransactions) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.Transaction, error) {
+	var tx models.Transaction
+	if err := r.scope(userID).FindOne(ctx, bson.M{"_id": id}, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (r mongoTransactions) Replace(ctx context.Context, tx *models.Transaction) error {
+	if err := checkOwner(tx.UserID); err != nil {
+		return err
+	}
+	return r.scope(tx.UserID).ReplaceOne(ctx, bson.M{"_id": tx.ID}, tx)
+}
+
+func (r mongoTransactions) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	return r.scope(userID).DeleteOne(ctx, bson.M{"_id": id})
+}
+
+func (r mongoTransactions) find(ctx context.Context, userID primitive.ObjectID, filter bson.M, opts ...*options.FindOptions) ([]models.Transaction, error) {
+	cursor, err := r.scope(userID).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	transactions := []models.Transaction{}
+	if err := cursor.All(ctx, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+func (r mongoTransactions) FindBySymbol(ctx context.Context, userID primitive.ObjectID, symbol string) ([]models.Transaction, error) {
+	symbols := []string{symbol}
+	cursor, err := mongoSymbols{}.collection().Find(ctx, bson.M{"renamed_to": symbol}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var renamed []models.SymbolMetadata
+	if err := cursor.All(ctx, &renamed); err != nil {
+		return nil, err
+	}
+	for _, former := range renamed {
+		symbols = append(symbols, former.Symbol)
+	}
+	return r.find(ctx, userID, bson.M{"symbol": bson.M{"$in": symbols}})
+}
+
+func (r mongoTransactions) FindByTag(ctx context.Context, userID primitive.ObjectID, tag string) ([]models.Transaction, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "created_at", Value: -1}})
+	return r.find(ctx, userID, bson.M{"tags": tag}, findOptions)
+}
+
+func (r mongoTransactions) FindSince(ctx context.Context, userID primitive.ObjectID, since time.Time) ([]models.Transaction, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "created_at", Value: -1}})
+	return r.find(ctx, userID, bson.M{"date": bson.M{"$gte": since}}, findOptions)
+}
+
+func (r mongoTransactions) FindBySymbolsBetween(ctx context.Context, userID primitive.ObjectID, symbols []string, start, end time.Time) ([]models.Transaction, error) {
+	return r.find(ctx, userID, bson.M{
+		"symbol": bson.M{"$in": symbols},
+		"date":   bson.M{"$gte": start, "$lte": end},
+	})
+}
+
+func (r mongoTransactions) FindByExternalIDs(ctx context.Context, userID primitive.ObjectID, broker string, ids []string) ([]models.Transaction, error) {
+	return r.find(ctx, userID, bson.M{
+		"broker":      broker,
+		"external_id": bson.M{"$in": ids},
+	})
+}
+
+func (r mongoTransactions) Stream(ctx context.Context, userID primitive.ObjectID, fn func(models.Transaction) error) error {
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "date", Value: 1}}).
+		SetProjection(bson.M{"symbol": 1, "action": 1, "shares": 1, "date": 1})
+	cursor, err := r.scope(userID).Find(ctx, nil, findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var tx models.Transaction
+		if err := cursor.Decode(&tx); err != nil {
+			return fmt.Errorf("failed to decode transaction: %w", err)
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// holdingsPipeline builds the aggregation pipeline that folds a user's transactions
+// into net shares and average-cost basis per symbol on the database side.
+// Transactions are sorted by date before grouping so the fold matches the
+// average cost method: a sell removes cost at the running cost per share.
+// Cost is folded in decimal and rounded to the currency's minor unit at each
+// step, matching money.Amount in the in-memory fold.
+func holdingsPipeline() mongo.Pipeline {
+	// Decimal places of the position currency's minor unit
+	exponent := bson.M{"$cond": bson.A{bson.M{"$in": bson.A{"$currency", money.ZeroDecimalCurrencies()}}, 0, 2}}
+
+	return mongo.Pipeline{
+		// Count transactions in renamed symbols towards the current ticker
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "symbols",
+			"localField":   "symbol",
+			"foreignField": "_id",
+			"as":           "catalog",
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"symbol": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$catalog.renamed_to", 0}}, "$symbol"}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: 1}, {Key: "created_at", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$symbol",
+			"currency": bson.M{"$first": "$currency"},
+			"legs": bson.M{"$push": bson.M{
+				"action": "$action",
+				"shares": "$shares",
+				// Cost basis includes price * shares + fees
+				"cost": bson.M{"$add": bson.A{
+					bson.M{"$multiply": bson.A{bson.M{"$toDecimal": "$price"}, bson.M{"$toDecimal": "$shares"}}},
+					bson.M{"$toDecimal": bson.M{"$ifNull": bson.A{"$fees", 0}}},
+				}},
+			}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"currency": 1,
+			"position": bson.M{"$reduce": bson.M{
+				"input":        "$legs",
+				"initialValue": bson.M{"shares": 0.0, "cost": bson.M{"$toDecimal": 0}},
+				"in": bson.M{"$switch": bson.M{
+					"branches": bson.A{
+						bson.M{
+							"case": bson.M{"$eq": bson.A{"$$this.action", "buy"}},
+							"then": bson.M{
+								"shares": bson.M{"$add": bson.A{"$$value.shares", "$$this.shares"}},
+								"cost": bson.M{"$add": bson.A{
+									"$$value.cost",
+									bson.M{"$round": bson.A{"$$this.cost", exponent}},
+								}},
+							},
+						},
+						bson.M{
+							// Sells only reduce the position while shares are held
+							"case": bson.M{"$and": bson.A{
+								bson.M{"$eq": bson.A{"$$this.action", "sell"}},
+								bson.M{"$gt": bson.A{"$$value.shares", 0}},
+							}},
+							"then": bson.M{
+								"shares": bson.M{"$subtract": bson.A{"$$value.shares", "$$this.shares"}},
+								"cost": bson.M{"$subtract": bson.A{
+									"$$value.cost",
+									bson.M{"$round": bson.A{
+										bson.M{"$multiply": bson.A{
+											"$$value.cost",
+											bson.M{"$divide": bson.A{"$$this.shares", "$$value.shares"}},
+										}},
+										exponent,
+									}},
+								}},
+							},
+						},
+					},
+					"default": "$$value",
+				}},
+			}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"currency": 1,
+			"shares":   "$position.shares",
+			"cost":     bson.M{"$toDouble": "$position.cost"},
+		}}},
+		// Filter out holdings with zero shares
+		{{Key: "$match", Value: bson.M{"shares": bson.M{"$gt": 0}}}},
+	}
+}
+
+func (r mongoTransactions) Positions(ctx context.Context, userID primitive.ObjectID) ([]Position, error) {
+	return r.aggregatePositions(ctx, userID, holdingsPipeline())
+}
+
+func (r mongoTransactions) PositionsBefore(ctx context.Context, userID primitive.ObjectID, before time.Time) ([]Position, error) {
+	pipeline := append(mongo.Pipeline{{{Key: "$match", Value: bson.M{"date": bson.M{"$lt": before}}}}}, holdingsPipeline()...)
+	return r.aggregatePositions(ctx, userID, pipeline)
+}
+
+func (r mongoTransactions) aggregatePositions(ctx context.Context, userID primitive.ObjectID, pipeline mongo.Pipeline) ([]Position, error) {
+	cursor, err := r.scope(userID).Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var positions []Position
+	if err := cursor.All(ctx, &positions); err != nil {
+		return nil, fmt.Errorf("failed to decode positions: %w", err)
+	}
+	return positions, nil
+}
+
+func (r mongoTransactions) Version(ctx context.Context, userID primitive.ObjectID) (Version, error) {
+	return r.scope(userID).version(ctx)
+}
+
+// mongoPortfolios stores portfolio entries in the portfolios collection
+type mongoPortfolios struct{}
+
+func (mongoPortfolios) scope(userID primitive.ObjectID) userScope {
+	return scope(database.Database.Collection("portfolios"), userID)
+}
+
+func (r mongoPortfolios) Insert(ctx context.Context, portfolio *models.Portfolio) error {
+	if err := checkOwner(portfolio.UserID); err != nil {
+		return err
+	}
+	_, err := database.Database.Collection("portfolios").InsertOne(ctx, portfolio)
+	return err
+}
+
+func (r mongoPortfolios) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.Portfolio, error) {
+	var portfolio models.Portfolio
+	if err := r.scope(userID).FindOne(ctx, bson.M{"_id": id}, &portfolio); err != nil {
+		return nil, err
+	}
+	return &portfolio, nil
+}
+
+func (r mongoPortfolios) FindBySymbol(ctx context.Context, userID primitive.ObjectID, symbol string) (*models.Portfolio, error) {
+	var portfolio models.Portfolio
+	if err := r.scope(userID).FindOne(ctx, bson.M{"symbol": symbol}, &portfolio); err != nil {
+		return nil, err
+	}
+	return &portfolio, nil
+}
+
+func (r mongoPortfolios) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Portfolio, error) {
+	cursor, err := r.scope(userID).Find(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	portfolios := []models.Portfolio{}
+	if err := cursor.All(ctx, &portfolios); err != nil {
+		return nil, err
+	}
+	return portfolios, nil
+}
+
+func (r mongoPortfolios) UpdateMetadata(ctx context.Context, userID, id, assetStyleID primitive.ObjectID, assetClass string, assetSubclassID *primitive.ObjectID) error {
+	set := bson.M{
+		"asset_style_id": assetStyleID,
+		"asset_class":    assetClass,
+		"updated_at":     time.Now(),
+	}
+	update := bson.M{"$set": set}
+	if assetSubclassID != nil {
+		set["asset_subclass_id"] = *assetSubclassID
+	} else {
+		update["$unset"] = bson.M{"asset_subclass_id": ""}
+	}
+	return r.scope(userID).UpdateOne(ctx, bson.M{"_id": id}, update)
+}
+
+func (r mongoPortfolios) ClearAssetSubclass(ctx context.Context, userID, assetSubclassID primitive.ObjectID) error {
+	return r.scope(userID).UpdateMany(ctx, bson.M{"asset_subclass_id": assetSubclassID}, bson.M{
+		"$unset": bson.M{"asset_subclass_id": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	})
+}
+
+func (r mongoPortfolios) ReassignAssetStyle(ctx context.Context, userID, from, to primitive.ObjectID) error {
+	return r.scope(userID).UpdateMany(ctx, bson.M{"asset_style_id": from}, bson.M{
+		"$set": bson.M{
+			"asset_style_id": to,
+			"updated_at":     time.Now(),
+		},
+	})
+}
+
+func (r mongoPortfolios) CountByAssetStyle(ctx context.Context, userID, assetStyleID primitive.ObjectID) (int64, error) {
+	return r.scope(userID).CountDocuments(ctx, bson.M{"asset_style_id": assetStyleID})
+}
+
+func (r mongoPortfolios) SetTags(ctx context.Context, userID, id primitive.ObjectID, tags []string) error {
+	if len(tags) == 0 {
+		return r.scope(userID).UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+			"$unset": bson.M{"tags": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		})
+	}
+	return r.scope(userID).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"tags":       tags,
+		"updated_at": time.Now(),
+	}})
+}
+
+func (r mongoPortfolios) SetStop(ctx context.Context, userID, id primitive.ObjectID, stop *models.PositionStop) error {
+	if stop == nil {
+		return r.scope(userID).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$unset": bson.M{"stop": ""}})
+	}
+	return r.scope(userID).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"stop": stop}})
+}
+
+// FindWatchedStops is deliberately unscoped like FindOpen: the stop job works
+// across users and every stop it updates goes back through the owner's scope
+func (r mongoPortfolios) FindWatchedStops(ctx context.Context) ([]models.Portfolio, error) {
+	cursor, err := database.Database.Collection("portfolios").Find(ctx, bson.M{
+		"stop":              bson.M{"$exists": true},
+		"stop.triggered_at": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	portfolios := []models.Portfolio{}
+	if err := cursor.All(ctx, &portfolios); err != nil {
+		return nil, err
+	}
+	return portfolios, nil
+}
+
+func (r mongoPortfolios) UpdateStop(ctx context.Context, portfolio *models.Portfolio, from time.Time) error {
+	if err := checkOwner(portfolio.UserID); err != nil {
+		return err
+	}
+	return r.scope(portfolio.UserID).UpdateOne(ctx,
+		bson.M{"_id": portfolio.ID, "stop.updated_at": from},
+		bson.M{"$set": bson.M{"stop": portfolio.Stop}},
+	)
+}
+
+func (r mongoPortfolios) Version(ctx context.Context, userID primitive.ObjectID) (Version, error) {
+	return r.scope(userID).version(ctx)
+}
+
+// mongoAssetStyles stores asset styles in the asset_styles collection
+type mongoAssetStyles struct{}
+
+func (mongoAssetStyles) scope(userID primitive.ObjectID) userScope {
+	return scope(database.Database.Collection("asset_styles"), userID)
+}
+
+func (r mongoAssetStyles) Insert(ctx context.Context, style *models.AssetStyle) error {
+	if err := checkOwner(style.UserID); err != nil {
+		return err
+	}
+	_, err := database.Database.Collection("asset_styles").InsertOne(ctx, style)
+	return err
+}
+
+func (r mongoAssetStyles) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.AssetStyle, error) {
+	var style models.AssetStyle
+	if err := r.scope(userID).FindOne(ctx, bson.M{"_id": id}, &style); err != nil {
+		return nil, err
+	}
+	return &style, nil
+}
+
+func (r mongoAssetStyles) FindByName(ctx context.Context, userID primitive.ObjectID, name string) (*models.AssetStyle, error) {
+	var style models.AssetStyle
+	if err := r.scope(userID).FindOne(ctx, bson.M{"name": name}, &style); err != nil {
+		return nil, err
+	}
+	return &style, nil
+}
+
+func (r mongoAssetStyles) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.AssetStyle, error) {
+	cursor, err := r.scope(userID).Find(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	styles := []models.AssetStyle{}
+	if err := cursor.All(ctx, &styles); err != nil {
+		return nil, err
+	}
+	return styles, nil
+}
+
+func (r mongoAssetStyles) Update(ctx context.Context, style *models.AssetStyle) error {
+	return r.scope(style.UserID).UpdateOne(ctx, bson.M{"_id": style.ID}, bson.M{
+		"$set": bson.M{
+			"name":       style.Name,
+			"color":      style.Color,
+			"icon":       style.Icon,
+			"updated_at": style.UpdatedAt,
+		},
+	})
+}
+
+func (r mongoAssetStyles) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	return r.scope(userID).DeleteOne(ctx, bson.M{"_id": id})
+}
+
+func (r mongoAssetStyles) Version(ctx context.Context, userID primitive.ObjectID) (Version, error) {
+	return r.scope(userID).version(ctx)
+}
+
+// mongoAssetSubclasses stores asset subclasses in the asset_subclasses collection
+type mongoAssetSubclasses struct{}
+
+func (mongoAssetSubclasses) collection() *mongo.Collection {
+	return database.Database.Collection("asset_subclasses")
+}
+
+func (r mongoAssetSubclasses) scope(userID primitive.ObjectID) userScope {
+	return scope(r.collection(), userID)
+}
+
+func (r mongoAssetSubclasses) Insert(ctx context.Context, subclass *models.AssetSubclass) error {
+	if err := checkOwner(subclass.UserID); err != nil {
+		return err
+	}
+	_, err := r.collection().InsertOne(ctx, subclass)
+	return err
+}
+
+func (r mongoAssetSubclasses) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.AssetSubclass, error) {
+	var subclass models.AssetSubclass
+	if err := r.scope(userID).FindOne(ctx, bson.M{"_id": id}, &subclass); err != nil {
+		return nil, err
+	}
+	return &subclass, nil
+}
+
+func (r mongoAssetSubclasses) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.AssetSubclass, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.scope(userID).Find(ctx, nil, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	subclasses := []models.AssetSubclass{}
+	if err := cursor.All(ctx, &subclasses); err != nil {
+		return nil, err
+	}
+	return subclasses, nil
+}
+
+func (r mongoAssetSubclasses) Rename(ctx context.Context, userID, id primitive.ObjectID, name string) error {
+	return r.scope(userID).UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{
+			"name":       name,
+			"updated_at": time.Now(),
+		},
+	})
+}
+
+func (r mongoAssetSubclasses) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	return r.scope(userID).DeleteOne(ctx, bson.M{"_id": id})
+}
+
+func (r mongoAssetSubclasses) Version(ctx context.Context, userID primitive.ObjectID) (Version, error) {
+	return r.scope(userID).version(ctx)
+}
+
+// mongoPendingOrders stores pending orders in the pending_orders collection
+type mongoPendingOrders struct{}
+
+func (mongoPendingOrders) scope(userID primitive.ObjectID) userScope {
+	return scope(database.Database.Collection("pending_orders"), userID)
+}
+
+func (r mongoPendingOrders) Insert(ctx context.Context, order *models.PendingOrder) error {
+	if err := checkOwner(order.UserID); err != nil {
+		return err
+	}
+	_, err := database.Database.Collection("pending_orders").InsertOne(ctx, order)
+	return err
+}
+
+func (r mongoPendingOrders) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.PendingOrder, error) {
+	var order models.PendingOrder
+	if err := r.scope(userID).FindOne(ctx, bson.M{"_id": id}, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r mongoPendingOrders) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.PendingOrder, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.scope(userID).Find(ctx, nil, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orders := []models.PendingOrder{}
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// FindOpen is deliberately unscoped: the trigger job works across users and
+// every order it closes goes back through the owner's scope
+func (r mongoPendingOrders) FindOpen(ctx context.Context) ([]models.PendingOrder, error) {
+	cursor, err := database.Database.Collection("pending_orders").Find(ctx, bson.M{"status": models.PendingOrderOpen})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orders := []models.PendingOrder{}
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r mongoPendingOrders) Transition(ctx context.Context, order *models.PendingOrder, from string) error {
+	if err := checkOwner(order.UserID); err != nil {
+		return err
+	}
+	return r.scope(order.UserID).ReplaceOne(ctx, bson.M{"_id": order.ID, "status": from}, order)
+}
+
+// mongoBrokerageLinks stores brokerage links in the brokerage_links collection
+type mongoBrokerageLinks struct{}
+
+func (mongoBrokerageLinks) collection() *mongo.Collection {
+	return database.Database.Collection("brokerage_links")
+}
+
+func (r mongoBrokerageLinks) scope(userID primitive.ObjectID) userScope {
+	return scope(r.collection(), userID)
+}
+
+func (r mongoBrokerageLinks) Insert(ctx context.Context, link *models.BrokerageLink) error {
+	if err := checkOwner(link.UserID); err != nil {
+		return err
+	}
+	_, err := r.collection().InsertOne(ctx, link)
+	return err
+}
+
+func (r mongoBrokerageLinks) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.BrokerageLink, error) {
+	var link models.BrokerageLink
+	if err := r.scope(userID).FindOne(ctx, bson.M{"_id": id}, &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r mongoBrokerageLinks) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.BrokerageLink, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.scope(userID).Find(ctx, nil, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	links := []models.BrokerageLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// FindSyncable is deliberately unscoped like FindOpen: the sync job works
+// across users and every link it claims goes back through the owner's scope
+func (r mongoBrokerageLinks) FindSyncable(ctx context.Context) ([]models.BrokerageLink, error) {
+	filter := bson.M{"status": bson.M{"$in": []string{models.BrokerageLinkActive, models.BrokerageLinkError}}}
+	cursor, err := r.collection().Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	links := []models.BrokerageLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r mongoBrokerageLinks) Update(ctx context.Context, link *models.BrokerageLink) error {
+	return r.scope(link.UserID).ReplaceOne(ctx, bson.M{"_id": link.ID}, link)
+}
+
+func (r mongoBrokerageLinks) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	return r.scope(userID).DeleteOne(ctx, bson.M{"_id": id})
+}
+
+func (r mongoBrokerageLinks) ClaimSync(ctx context.Context, userID, id primitive.ObjectID, from *time.Time, at time.Time) error {
+	filter := bson.M{"_id": id}
+	if from == nil {
+		filter["last_synced_at"] = bson.M{"$exists": false}
+	} else {
+		filter["last_synced_at"] = *from
+	}
+	return r.scope(userID).UpdateOne(ctx, filter, bson.M{"$set": bson.M{"last_synced_at": at}})
+}
+
+// mongoSheetsLinks stores linked Google Sheets in the sheets_links collection
+type mongoSheetsLinks struct{}
+
+func (mongoSheetsLinks) collection() *mongo.Collection {
+	return database.Database.Collection("sheets_links")
+}
+
+func (r mongoSheetsLinks) scope(userID primitive.ObjectID) userScope {
+	return scope(r.collection(), userID)
+}
+
+func (r mongoSheetsLinks) Insert(ctx context.Context, link *models.SheetsLink) error {
+	if err := checkOwner(link.UserID); err != nil {
+		return err
+	}
+	_, err := r.collection().InsertOne(ctx, link)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r mongoSheetsLinks) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.SheetsLink, error) {
+	var link models.SheetsLink
+	if err := r.scope(userID).FindOne(ctx, nil, &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// FindSyncable is deliberately unscoped like the brokerage links': the sync
+// job works across users and every link it claims goes back through the
+// owner's scope
+func (r mongoSheetsLinks) FindSyncable(ctx context.Context) ([]models.SheetsLink, error) {
+	filter := bson.M{"status": bson.M{"$in": []string{models.SheetsLinkActive, models.SheetsLinkError}}}
+	cursor, err := r.collection().Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	links := []models.SheetsLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r mongoSheetsLinks) Update(ctx context.Context, link *models.SheetsLink) error {
+	return r.scope(link.UserID).ReplaceOne(ctx, bson.M{"_id": link.ID}, link)
+}
+
+func (r mongoSheetsLinks) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	return r.scope(userID).DeleteOne(ctx, nil)
+}
+
+func (r mongoSheetsLinks) ClaimSync(ctx context.Context, userID, id primitive.ObjectID, from *time.Time, at time.Time) error {
+	filter := bson.M{"_id": id}
+	if from == nil {
+		filter["last_synced_at"] = bson.M{"$exists": false}
+	} else {
+		filter["last_synced_at"] = *from
+	}
+	return r.scope(userID).UpdateOne(ctx, filter, bson.M{"$set": bson.M{"last_synced_at": at}})
+}
+
+// mongoWebhooks stores webhooks in the webhooks collection
+type mongoWebhooks struct{}
+
+func (mongoWebhooks) collection() *mongo.Collection {
+	return database.Database.Collection("webhooks")
+}
+
+func (r mongoWebhooks) scope(userID primitive.ObjectID) userScope {
+	return scope(r.collection(), userID)
+}
+
+func (r mongoWebhooks) Insert(ctx context.Context, webhook *models.Webhook) error {
+	if err := checkOwner(webhook.UserID); err != nil {
+		return err
+	}
+	_, err := r.collection().InsertOne(ctx, webhook)
+	return err
+}
+
+func (r mongoWebhooks) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := r.scope(userID).FindOne(ctx, bson.M{"_id": id}, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r mongoWebhooks) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Webhook, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.scope(userID).Find(ctx, nil, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []models.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// FindByEvent is deliberately unscoped like FindOpen: the summary job works
+// across users and every webhook it claims goes back through the owner's scope
+func (r mongoWebhooks) FindByEvent(ctx context.Context, event string) ([]models.Webhook, error) {
+	cursor, err := r.collection().Find(ctx, bson.M{"events": event})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []models.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r mongoWebhooks) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	return r.scope(userID).DeleteOne(ctx, bson.M{"_id": id})
+}
+
+func (r mongoWebhooks) RecordDelivery(ctx context.Context, userID, id primitive.ObjectID, at time.Time, lastError string) error {
+	update := bson.M{"$set": bson.M{"last_delivery_at": at}}
+	if lastError == "" {
+		update["$unset"] = bson.M{"last_error": ""}
+	} else {
+		update["$set"].(bson.M)["last_error"] = lastError
+	}
+	return r.scope(userID).UpdateOne(ctx, bson.M{"_id": id}, update)
+}
+
+func (r mongoWebhooks) ClaimSummary(ctx context.Context, userID, id primitive.ObjectID, from *time.Time, at time.Time) error {
+	filter := bson.M{"_id": id}
+	if from == nil {
+		filter["last_summary_at"] = bson.M{"$exists": false}
+	} else {
+		filter["last_summary_at"] = *from
+	}
+	return r.scope(userID).UpdateOne(ctx, filter, bson.M{"$set": bson.M{"last_summary_at": at}})
+}
+
+// mongoCashInterest stores cash interest rates in the cash_interest_rates collection
+type mongoCashInterest struct{}
+
+func (mongoCashInterest) collection() *mongo.Collection {
+	return database.Database.Collection("cash_interest_rates")
+}
+
+func (r mongoCashInterest) scope(userID primitive.ObjectID) userScope {
+	return scope(r.collection(), userID)
+}
+
+func (r mongoCashInterest) Insert(ctx context.Context, rate *models.CashInterestRate) error {
+	if err := checkOwner(rate.UserID); err != nil {
+		return err
+	}
+	_, err := r.collection().InsertOne(ctx, rate)
+	return err
+}
+
+func (r mongoCashInterest) FindBySymbol(ctx context.Context, userID primitive.ObjectID, symbol string) (*models.CashInterestRate, error) {
+	var rate models.CashInterestRate
+	if err := r.scope(userID).FindOne(ctx, bson.M{"symbol": symbol}, &rate); err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r mongoCashInterest) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.CashInterestRate, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "symbol", Value: 1}})
+	cursor, err := r.scope(userID).Find(ctx, nil, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rates := []models.CashInterestRate{}
+	if err := cursor.All(ctx, &rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// FindAccruing is deliberately unscoped like FindOpen: the accrual job works
+// across users and every rate it advances goes back through the owner's scope
+func (r mongoCashInterest) FindAccruing(ctx context.Context) ([]models.CashInterestRate, error) {
+	cursor, err := r.collection().Find(ctx, bson.M{"apy": bson.M{"$gt": 0}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rates := []models.CashInterestRate{}
+	if err := cursor.All(ctx, &rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r mongoCashInterest) Update(ctx context.Context, rate *models.CashInterestRate, from time.Time) error {
+	if err := checkOwner(rate.UserID); err != nil {
+		return err
+	}
+	return r.scope(rate.UserID).ReplaceOne(ctx, bson.M{"_id": rate.ID, "accrued_through": from}, rate)
+}
+
+func (r mongoCashInterest) Delete(ctx context.Context, userID primitive.ObjectID, symbol string) error {
+	return r.scope(userID).DeleteOne(ctx, bson.M{"symbol": symbol})
+}
+
+// mongoPriceOverrides stores manual prices in the price_overrides collection
+type mongoPriceOverrides struct{}
+
+func (mongoPriceOverrides) collection() *mongo.Collection {
+	return database.Database.Collection("price_overrides")
+}
+
+func (r mongoPriceOverrides) scope(userID primitive.ObjectID) userScope {
+	return scope(r.collection(), userID)
+}
+
+func (r mongoPriceOverrides) Insert(ctx context.Context, override *models.PriceOverride) error {
+	if err := checkOwner(override.UserID); err != nil {
+		return err
+	}
+	_, err := r.collection().InsertOne(ctx, override)
+	return err
+}
+
+func (r mongoPriceOverrides) FindBySymbol(ctx context.Context, userID primitive.ObjectID, symbol string) (*models.PriceOverride, error) {
+	var override models.PriceOverride
+	if err := r.scope(userID).FindOne(ctx, bson.M{"symbol": symbol}, &override); err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+func (r mongoPriceOverrides) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.PriceOverride, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "symbol", Value: 1}})
+	cursor, err := r.scope(userID).Find(ctx, nil, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	overrides := []models.PriceOverride{}
+	if err := cursor.All(ctx, &overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+func (r mongoPriceOverrides) Update(ctx context.Context, override *models.PriceOverride) error {
+	return r.scope(override.UserID).UpdateOne(ctx, bson.M{"_id": override.ID}, bson.M{"$set": bson.M{
+		"price":          override.Price,
+		"currency":       override.Currency,
+		"effective_date": override.EffectiveDate,
+		"note":           override.Note,
+		"updated_at":     override.UpdatedAt,
+	}})
+}
+
+func (r mongoPriceOverrides) Delete(ctx context.Context, userID primitive.ObjectID, symbol string) error {
+	return r.scope(userID).DeleteOne(ctx, bson.M{"symbol": symbol})
+}
+
+func (r mongoPriceOverrides) Version(ctx context.Context, userID primitive.ObjectID) (Version, error) {
+	return r.scope(userID).version(ctx)
+}
+
+// mongoSessions stores login sessions in the sessions collection
+type mongoSessions struct{}
+
+func (mongoSessions) collection() *mongo.Collection {
+	return database.Database.Collection("sessions")
+}
+
+func (r mongoSessions) scope(userID primitive.ObjectID) userScope {
+	return scope(r.collection(), userID)
+}
+
+func (r mongoSessions) Insert(ctx context.Context, session *models.Session) error {
+	if err := checkOwner(session.UserID); err != nil {
+		return err
+	}
+	_, err := r.collection().InsertOne(ctx, session)
+	return err
+}
+
+func (r mongoSessions) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.Session, error) {
+	var session models.Session
+	if err := r.scope(userID).FindOne(ctx, bson.M{"_id": id}, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r mongoSessions) FindActive(ctx context.Context, userID primitive.ObjectID, now time.Time) ([]models.Session, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}})
+	cursor, err := r.scope(userID).Find(ctx, bson.M{
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []models.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r mongoSessions) Touch(ctx context.Context, userID, id primitive.ObjectID, ip string, at time.Time) error {
+	return r.scope(userID).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"ip": ip, "last_seen_at": at}})
+}
+
+func (r mongoSessions) Revoke(ctx context.Context, userID, id primitive.ObjectID, at time.Time) error {
+	return r.scope(userID).UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": at}})
+}
+
+func (r mongoSessions) RevokeAllExcept(ctx context.Context, userID, keep primitive.ObjectID, at time.Time) (int64, error) {
+	result, err := r.collection().UpdateMany(ctx,
+		r.scope(userID).filter(bson.M{"_id": bson.M{"$ne": keep}, "revoked_at": bson.M{"$exists": false}}),
+		bson.M{"$set": bson.M{"revoked_at": at}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// FindActiveUsers is deliberately unscoped like FindOpen: it only returns
+// user IDs, and the warming job reads each user's data through their scope
+func (r mongoSessions) FindActiveUsers(ctx context.Context, since time.Time) ([]primitive.ObjectID, error) {
+	values, err := r.collection().Distinct(ctx, "user_id", bson.M{
+		"last_seen_at": bson.M{"$gte": since},
+		"revoked_at":   bson.M{"$exists": false},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if userID, ok := value.(primitive.ObjectID); ok {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs, nil
+}
+
+// mongoUsers stores accounts in the users collection
+type mongoUsers struct{}
+
+func (mongoUsers) collection() *mongo.Collection {
+	return database.Database.Collection("users")
+}
+
+func (r mongoUsers) Insert(ctx context.Context, user *models.User) error {
+	_, err := r.collection().InsertOne(ctx, user)
+	return err
+}
+
+func (r mongoUsers) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	var user models.User
+	if err := findOne(ctx, r.collection(), bson.M{"_id": id}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r mongoUsers) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := findOne(ctx, r.collection(), bson.M{"email": email}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r mongoUsers) UpdateProfile(ctx context.Context, id primitive.ObjectID, profile models.UserProfile, at time.Time) error {
+	return r.update(ctx, id, bson.M{"$set": bson.M{
+		"display_name":  profile.DisplayName,
+		"base_currency": profile.BaseCurrency,
+		"country":       profile.Country,
+		"updated_at":    at,
+	}})
+}
+
+func (r mongoUsers) SetAvatar(ctx context.Context, id primitive.ObjectID, avatarID *primitive.ObjectID, at time.Time) error {
+	if avatarID == nil {
+		return r.update(ctx, id, bson.M{"$set": bson.M{"updated_at": at}, "$unset": bson.M{"avatar_id": ""}})
+	}
+	return r.update(ctx, id, bson.M{"$set": bson.M{"avatar_id": *avatarID, "updated_at": at}})
+}
+
+func (r mongoUsers) SetPassword(ctx context.Context, id primitive.ObjectID, hash string, at time.Time) error {
+	return r.update(ctx, id, bson.M{"$set": bson.M{"password": hash, "updated_at": at}})
+}
+
+// FindIDs is deliberately unscoped like FindOpen: it only returns user IDs,
+// and maintenance jobs read each user's data through their scope
+func (r mongoUsers) FindIDs(ctx context.Context) ([]primitive.ObjectID, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetProjection(bson.M{"_id": 1})
+	cursor, err := r.collection().Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		ids = append(ids, doc.ID)
+	}
+	return ids, cursor.Err()
+}
+
+// update applies update to the user, returning ErrNotFound if there is none
+func (r mongoUsers) update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	result, err := r.collection().UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// mongoAvatars stores profile pictures in the avatars GridFS bucket, with the
+// owner and content type in each file's metadata
+type mongoAvatars struct{}
+
+// avatarMetadata is the metadata stored with each avatar file
+type avatarMetadata struct {
+	UserID      primitive.ObjectID `bson:"user_id"`
+	ContentType string             `bson:"content_type"`
+}
+
+// bucket opens the avatars bucket with ctx's deadline, as GridFS streams
+// don't take a context
+func (mongoAvatars) bucket(ctx context.Context) (*gridfs.Bucket, error) {
+	bucket, err := gridfs.NewBucket(database.Database, options.GridFSBucket().SetName("avatars"))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		bucket.SetReadDeadline(deadline)
+		bucket.SetWriteDeadline(deadline)
+	}
+	return bucket, nil
+}
+
+func (r mongoAvatars) Put(ctx context.Context, userID primitive.ObjectID, contentType string, data []byte) (primitive.ObjectID, error) {
+	if err := checkOwner(userID); err != nil {
+		return primitive.NilObjectID, err
+	}
+	bucket, err := r.bucket(ctx)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	uploadOptions := options.GridFSUpload().SetMetadata(avatarMetadata{UserID: userID, ContentType: contentType})
+	return bucket.UploadFromStream(userID.Hex(), bytes.NewReader(data), uploadOptions)
+}
+
+func (r mongoAvatars) Get(ctx context.Context, userID, id primitive.ObjectID) ([]byte, string, error) {
+	bucket, err := r.bucket(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The metadata check keeps one user from reading another's avatar
+	var file struct {
+		Metadata avatarMetadata `bson:"metadata"`
+	}
+	err = bucket.GetFilesCollection().FindOne(ctx, bson.M{"_id": id, "metadata.user_id": userID}).Decode(&file)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	var data bytes.Buffer
+	if _, err := bucket.DownloadToStream(id, &data); err != nil {
+		if errors.Is(err, gridfs.ErrFileNotFound) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", err
+	}
+	return data.Bytes(), file.Metadata.ContentType, nil
+}
+
+func (r mongoAvatars) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	bucket, err := r.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	count, err := bucket.GetFilesCollection().CountDocuments(ctx, bson.M{"_id": id, "metadata.user_id": userID})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	err = bucket.DeleteContext(ctx, id)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// mongoSettings stores user settings in the user_settings collection
+type mongoSettings struct{}
+
+func (mongoSettings) collection() *mongo.Collection {
+	return database.Database.Collection("user_settings")
+}
+
+func (mongoSettings) scope(userID primitive.ObjectID) userScope {
+	return scope(database.Database.Collection("user_settings"), userID)
+}
+
+func (r mongoSettings) FindDriftAlertSubscribers(ctx context.Context) ([]models.UserSettings, error) {
+	cursor, err := r.collection().Find(ctx, bson.M{"drift_alerts.enabled": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	subscribers := []models.UserSettings{}
+	if err := cursor.All(ctx, &subscribers); err != nil {
+		return nil, err
+	}
+	return subscribers, nil
+}
+
+func (r mongoSettings) ClaimDriftAlert(ctx context.Context, userID, id primitive.ObjectID, expected, breached []string, alertedAt *time.Time) error {
+	filter := bson.M{"_id": id}
+	if len(expected) == 0 {
+		filter["drift_alerts.breached"] = bson.M{"$in": bson.A{nil, bson.A{}}}
+	} else {
+		filter["drift_alerts.breached"] = expected
+	}
+
+	set := bson.M{"drift_alerts.breached": breached}
+	if alertedAt != nil {
+		set["drift_alerts.last_alerted_at"] = *alertedAt
+	}
+	return r.scope(userID).UpdateOne(ctx, filter, bson.M{"$set": set})
+}
+
+func (r mongoSettings) FindCrossAlertSubscribers(ctx context.Context) ([]models.UserSettings, error) {
+	cursor, err := r.collection().Find(ctx, bson.M{"cross_alerts.enabled": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	subscribers := []models.UserSettings{}
+	if err := cursor.All(ctx, &subscribers); err != nil {
+		return nil, err
+	}
+	return subscribers, nil
+}
+
+func (r mongoSettings) ClaimCrossAlert(ctx context.Context, userID, id primitive.ObjectID, expected, alerted []models.AlertedCross, alertedAt *time.Time) error {
+	filter := bson.M{"_id": id}
+	if len(expected) == 0 {
+		filter["cross_alerts.alerted"] = bson.M{"$in": bson.A{nil, bson.A{}}}
+	} else {
+		filter["cross_alerts.alerted"] = expected
+	}
+
+	set := bson.M{"cross_alerts.alerted": alerted}
+	if alertedAt != nil {
+		set["cross_alerts.last_alerted_at"] = *alertedAt
+	}
+	return r.scope(userID).UpdateOne(ctx, filter, bson.M{"$set": set})
+}
+
+// mongoOutbox stores queued notifications in the outbox collection
+type mongoOutbox struct{}
+
+func (mongoOutbox) collection() *mongo.Collection {
+	return database.Database.Collection("outbox")
+}
+
+func (r mongoOutbox) Insert(ctx context.Context, msg *models.OutboxMessage) error {
+	if err := checkOwner(msg.UserID); err != nil {
+		return err
+	}
+	_, err := r.collection().InsertOne(ctx, msg)
+	return err
+}
+
+func (r mongoOutbox) ClaimDue(ctx context.Context, now, leaseUntil time.Time) (*models.OutboxMessage, error) {
+	var msg models.OutboxMessage
+	err := r.collection().FindOneAndUpdate(ctx,
+		bson.M{"status": models.OutboxPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"next_attempt_at": leaseUntil}, "$inc": bson.M{"attempts": 1}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&msg)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (r mongoOutbox) Update(ctx context.Context, msg *models.OutboxMessage) error {
+	result, err := r.collection().ReplaceOne(ctx, bson.M{"_id": msg.ID}, msg)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// mongoMaintenance stores the maintenance switch as one document in the
+// system_settings collection
+type mongoMaintenance struct{}
+
+func (mongoMaintenance) collection() *mongo.Collection {
+	return database.Database.Collection("system_settings")
+}
+
+func (r mongoMaintenance) Get(ctx context.Context) (*models.MaintenanceMode, error) {
+	var mode models.MaintenanceMode
+	if err := findOne(ctx, r.collection(), bson.M{"_id": "maintenance"}, &mode); err != nil {
+		return nil, err
+	}
+	return &mode, nil
+}
+
+func (r mongoMaintenance) Set(ctx context.Context, mode *models.MaintenanceMode) error {
+	_, err := r.collection().ReplaceOne(ctx, bson.M{"_id": "maintenance"}, mode, options.Replace().SetUpsert(true))
+	return err
+}
+
+// mongoFeatureFlags stores feature flags in the feature_flags collection
+type mongoFeatureFlags struct{}
+
+func (mongoFeatureFlags) collection() *mongo.Collection {
+	return database.Database.Collection("feature_flags")
+}
+
+func (r mongoFeatureFlags) FindAll(ctx context.Context) ([]models.FeatureFlag, error) {
+	cursor, err := r.collection().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	flags := []models.FeatureFlag{}
+	if err := cursor.All(ctx, &flags); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+func (r mongoFeatureFlags) Save(ctx context.Context, flag *models.FeatureFlag) error {
+	_, err := r.collection().ReplaceOne(ctx, bson.M{"_id": flag.Key}, flag, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r mongoFeatureFlags) Delete(ctx context.Context, key string) error {
+	result, err := r.collection().DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// mongoSymbols stores symbol metadata in the symbols collection
+type mongoSymbols struct{}
+
+func (mongoSymbols) collection() *mongo.Collection {
+	return database.Database.Collection("symbols")
+}
+
+func (r mongoSymbols) FindAll(ctx context.Context) ([]models.SymbolMetadata, error) {
+	cursor, err := r.collection().Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	symbols := []models.SymbolMetadata{}
+	if err := cursor.All(ctx, &symbols); err != nil {
+		return nil, err
+	}
+	return symbols, nil
+}
+
+func (r mongoSymbols) Save(ctx context.Context, symbol *models.SymbolMetadata) error {
+	update := bson.M{
+		"$set": bson.M{
+			"name":       symbol.Name,
+			"exchange":   symbol.Exchange,
+			"currency":   symbol.Currency,
+			"sector":     symbol.Sector,
+			"type":       symbol.Type,
+			"updated_at": symbol.UpdatedAt,
+
+			"last_price":    symbol.LastPrice,
+			"last_price_at": symbol.LastPriceAt,
+			"missing_since": symbol.MissingSince,
+			"delisted_at":   symbol.DelistedAt,
+		},
+		"$setOnInsert": bson.M{"first_seen": symbol.FirstSeen},
+	}
+	_, err := r.collection().UpdateOne(ctx, bson.M{"_id": symbol.Symbol}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r mongoSymbols) Rename(ctx context.Context, from, to string, at time.Time) error {
+	rename := bson.M{"$set": bson.M{"renamed_to": to, "renamed_at": at}}
+	if _, err := r.collection().UpdateMany(ctx, bson.M{"renamed_to": from}, rename); err != nil {
+		return err
+	}
+	update := bson.M{
+		"$set":         bson.M{"renamed_to": to, "renamed_at": at, "updated_at": at},
+		"$setOnInsert": bson.M{"first_seen": at},
+	}
+	_, err := r.collection().UpdateOne(ctx, bson.M{"_id": from}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// mongoTransactor runs functions in multi-document transactions. These need
+// a replica set or sharded cluster; a standalone server fails with
+// ErrTransactionsUnsupported rather than running functions without one.
+type mongoTransactor struct {
+	mu        sync.Mutex
+	checked   bool
+	supported bool
+}
+
+func (t *mongoTransactor) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	supported, err := t.transactionsSupported(ctx)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return ErrTransactionsUnsupported
+	}
+
+	session, err := database.Client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// transactionsSupported asks the server whether it is part of a replica set
+// or a mongos router, remembering the answer once it has one
+func (t *mongoTransactor) transactionsSupported(ctx context.Context) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.checked {
+		return t.supported, nil
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := database.Database.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, fmt.Errorf("failed to check transaction support: %w", err)
+	}
+	t.checked = true
+	t.supported = hello.SetName != "" || hello.Msg == "isdbgrid"
+	return t.supported, nil
+}
+
+// CheckTransactions returns ErrTransactionsUnsupported when the connected
+// server can't run transactions, so the server can refuse to start instead
+// of failing every change that queues a notification
+func CheckTransactions(ctx context.Context) error {
+	supported, err := (&mongoTransactor{}).transactionsSupported(ctx)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return ErrTransactionsUnsupported
+	}
+	return nil
+}
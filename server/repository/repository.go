@@ -0,0 +1,353 @@
+// Package repository abstracts the storage of users, transactions, portfolios,
+// asset styles and pending orders behind interfaces, with a MongoDB implementation used by the
+// server and an in-memory one that lets services be tested without a database.
+package repository
+
+import (
+	"context"
+	"errors"
+	"stock-portfolio-tracker/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound is returned when no document matches a lookup, update or delete
+var ErrNotFound = errors.New("document not found")
+
+// ErrDuplicate is returned when an insert would break a uniqueness constraint
+var ErrDuplicate = errors.New("duplicate document")
+
+// ErrTransactionsUnsupported is returned by a Transactor whose database can't
+// run multi-document transactions
+var ErrTransactionsUnsupported = errors.New("database does not support transactions; run MongoDB as a replica set")
+
+// Position is a user's net holding in one symbol, folded from its transactions
+// with the average cost method
+type Position struct {
+	Symbol   string  `bson:"_id"`
+	Shares   float64 `bson:"shares"`
+	Cost     float64 `bson:"cost"`
+	Currency string  `bson:"currency"`
+}
+
+// Version summarises a user's documents in a collection. It changes whenever a
+// document is created, updated or deleted.
+type Version struct {
+	Count        int64
+	LatestUpdate time.Time
+}
+
+// TransactionRepo stores buy and sell transactions. Every lookup is scoped to
+// a user.
+type TransactionRepo interface {
+	Insert(ctx context.Context, tx *models.Transaction) error
+	FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.Transaction, error)
+	// Replace overwrites the transaction with tx's ID owned by tx's user
+	Replace(ctx context.Context, tx *models.Transaction) error
+	Delete(ctx context.Context, userID, id primitive.ObjectID) error
+	// FindBySymbol returns the transactions in symbol, including those
+	// recorded under the tickers it was renamed from
+	FindBySymbol(ctx context.Context, userID primitive.ObjectID, symbol string) ([]models.Transaction, error)
+	// FindByTag returns transactions carrying the tag, newest first
+	FindByTag(ctx context.Context, userID primitive.ObjectID, tag string) ([]models.Transaction, error)
+	// FindSince returns transactions dated on or after since, newest first
+	FindSince(ctx context.Context, userID primitive.ObjectID, since time.Time) ([]models.Transaction, error)
+	// FindBySymbolsBetween returns transactions in the symbols dated within
+	// [start, end]
+	FindBySymbolsBetween(ctx context.Context, userID primitive.ObjectID, symbols []string, start, end time.Time) ([]models.Transaction, error)
+	// FindByExternalIDs returns the transactions imported from the broker
+	// with any of the external IDs
+	FindByExternalIDs(ctx context.Context, userID primitive.ObjectID, broker string, ids []string) ([]models.Transaction, error)
+	// Stream calls fn with each transaction, oldest first. Only the symbol,
+	// action, shares and date are loaded.
+	Stream(ctx context.Context, userID primitive.ObjectID, fn func(models.Transaction) error) error
+	// Positions returns the user's open positions. Transactions in renamed
+	// symbols count towards the position in the current ticker.
+	Positions(ctx context.Context, userID primitive.ObjectID) ([]Position, error)
+	// PositionsBefore returns the positions that were open after the
+	// transactions dated before the given time
+	PositionsBefore(ctx context.Context, userID primitive.ObjectID, before time.Time) ([]Position, error)
+	Version(ctx context.Context, userID primitive.ObjectID) (Version, error)
+}
+
+// PortfolioRepo stores portfolio entries, one per user and symbol
+type PortfolioRepo interface {
+	Insert(ctx context.Context, portfolio *models.Portfolio) error
+	FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.Portfolio, error)
+	FindBySymbol(ctx context.Context, userID primitive.ObjectID, symbol string) (*models.Portfolio, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Portfolio, error)
+	// UpdateMetadata sets the entry's asset style and class, and its asset
+	// subclass or, when nil, removes it
+	UpdateMetadata(ctx context.Context, userID, id, assetStyleID primitive.ObjectID, assetClass string, assetSubclassID *primitive.ObjectID) error
+	// ReassignAssetStyle moves the user's entries in one asset style to another
+	ReassignAssetStyle(ctx context.Context, userID, from, to primitive.ObjectID) error
+	// ClearAssetSubclass removes an asset subclass from the user's entries in it
+	ClearAssetSubclass(ctx context.Context, userID, assetSubclassID primitive.ObjectID) error
+	// CountByAssetStyle counts the user's entries in an asset style
+	CountByAssetStyle(ctx context.Context, userID, assetStyleID primitive.ObjectID) (int64, error)
+	// SetTags replaces an entry's tags, removing them when tags is empty
+	SetTags(ctx context.Context, userID, id primitive.ObjectID, tags []string) error
+	// SetStop sets or, when stop is nil, removes an entry's stop
+	SetStop(ctx context.Context, userID, id primitive.ObjectID, stop *models.PositionStop) error
+	// FindWatchedStops returns the entries of every user with a stop that has
+	// not triggered, for the job that watches quotes
+	FindWatchedStops(ctx context.Context) ([]models.Portfolio, error)
+	// UpdateStop replaces the entry's stop if it was still last updated at
+	// from, so a stop is only ever triggered once. It returns ErrNotFound
+	// otherwise.
+	UpdateStop(ctx context.Context, portfolio *models.Portfolio, from time.Time) error
+	Version(ctx context.Context, userID primitive.ObjectID) (Version, error)
+}
+
+// AssetStyleRepo stores user-defined asset styles
+type AssetStyleRepo interface {
+	Insert(ctx context.Context, style *models.AssetStyle) error
+	FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.AssetStyle, error)
+	FindByName(ctx context.Context, userID primitive.ObjectID, name string) (*models.AssetStyle, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.AssetStyle, error)
+	// Update saves the style's name, color and icon
+	Update(ctx context.Context, style *models.AssetStyle) error
+	Delete(ctx context.Context, userID, id primitive.ObjectID) error
+	Version(ctx context.Context, userID primitive.ObjectID) (Version, error)
+}
+
+// AssetSubclassRepo stores user-defined asset subclasses
+type AssetSubclassRepo interface {
+	Insert(ctx context.Context, subclass *models.AssetSubclass) error
+	FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.AssetSubclass, error)
+	// FindByUser returns the user's subclasses ordered by name
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.AssetSubclass, error)
+	Rename(ctx context.Context, userID, id primitive.ObjectID, name string) error
+	Delete(ctx context.Context, userID, id primitive.ObjectID) error
+	Version(ctx context.Context, userID primitive.ObjectID) (Version, error)
+}
+
+// PendingOrderRepo stores intended trades waiting for their trigger price
+type PendingOrderRepo interface {
+	Insert(ctx context.Context, order *models.PendingOrder) error
+	FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.PendingOrder, error)
+	// FindByUser returns the user's orders, newest first
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.PendingOrder, error)
+	// FindOpen returns the open orders of every user, for the job that
+	// watches quotes
+	FindOpen(ctx context.Context) ([]models.PendingOrder, error)
+	// Transition replaces the order if it still has the from status, so an
+	// order is only ever closed once. It returns ErrNotFound otherwise.
+	Transition(ctx context.Context, order *models.PendingOrder, from string) error
+}
+
+// BrokerageLinkRepo stores linked brokerage accounts
+type BrokerageLinkRepo interface {
+	Insert(ctx context.Context, link *models.BrokerageLink) error
+	FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.BrokerageLink, error)
+	// FindByUser returns the user's links, oldest first
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.BrokerageLink, error)
+	// FindSyncable returns the active and failed links of every user, for
+	// the sync job
+	FindSyncable(ctx context.Context) ([]models.BrokerageLink, error)
+	Update(ctx context.Context, link *models.BrokerageLink) error
+	Delete(ctx context.Context, userID, id primitive.ObjectID) error
+	// ClaimSync sets the link's last sync time to at if it is still from,
+	// nil meaning never synced, so concurrent jobs sync a link once. It
+	// returns ErrNotFound if another job claimed it first.
+	ClaimSync(ctx context.Context, userID, id primitive.ObjectID, from *time.Time, at time.Time) error
+}
+
+// SheetsLinkRepo stores the Google Sheets users have linked, one per user
+type SheetsLinkRepo interface {
+	Insert(ctx context.Context, link *models.SheetsLink) error
+	// FindByUser returns the user's link, or ErrNotFound if they have none
+	FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.SheetsLink, error)
+	// FindSyncable returns the active and failed links of every user, for
+	// the sync job
+	FindSyncable(ctx context.Context) ([]models.SheetsLink, error)
+	Update(ctx context.Context, link *models.SheetsLink) error
+	Delete(ctx context.Context, userID primitive.ObjectID) error
+	// ClaimSync sets the link's last sync time to at if it is still from,
+	// nil meaning never synced, so concurrent jobs sync a link once. It
+	// returns ErrNotFound if another job claimed it first.
+	ClaimSync(ctx context.Context, userID, id primitive.ObjectID, from *time.Time, at time.Time) error
+}
+
+// WebhookRepo stores the URLs users have posted portfolio events to
+type WebhookRepo interface {
+	Insert(ctx context.Context, webhook *models.Webhook) error
+	FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.Webhook, error)
+	// FindByUser returns the user's webhooks, oldest first
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Webhook, error)
+	// FindByEvent returns the webhooks of every user subscribed to the event,
+	// for the daily summary job
+	FindByEvent(ctx context.Context, event string) ([]models.Webhook, error)
+	Delete(ctx context.Context, userID, id primitive.ObjectID) error
+	// RecordDelivery notes when the webhook was last posted to and the
+	// error, empty on success
+	RecordDelivery(ctx context.Context, userID, id primitive.ObjectID, at time.Time, lastError string) error
+	// ClaimSummary moves the webhook's last summary time from from, nil when
+	// it never had one, to at, so each summary is only sent once. It returns
+	// ErrNotFound if another writer moved it first.
+	ClaimSummary(ctx context.Context, userID, id primitive.ObjectID, from *time.Time, at time.Time) error
+}
+
+// CashInterestRepo stores the interest rates set on cash balances, one per
+// user and cash symbol
+type CashInterestRepo interface {
+	Insert(ctx context.Context, rate *models.CashInterestRate) error
+	FindBySymbol(ctx context.Context, userID primitive.ObjectID, symbol string) (*models.CashInterestRate, error)
+	// FindByUser returns the user's rates ordered by symbol
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.CashInterestRate, error)
+	// FindAccruing returns the rates of every user with a positive APY, for
+	// the job that records interest
+	FindAccruing(ctx context.Context) ([]models.CashInterestRate, error)
+	// Update replaces the rate if it is still accrued through from, so a day's
+	// interest is only ever recorded once. It returns ErrNotFound otherwise.
+	Update(ctx context.Context, rate *models.CashInterestRate, from time.Time) error
+	Delete(ctx context.Context, userID primitive.ObjectID, symbol string) error
+}
+
+// PriceOverrideRepo stores the manual prices users set, one per user and
+// symbol
+type PriceOverrideRepo interface {
+	Insert(ctx context.Context, override *models.PriceOverride) error
+	FindBySymbol(ctx context.Context, userID primitive.ObjectID, symbol string) (*models.PriceOverride, error)
+	// FindByUser returns the user's overrides ordered by symbol
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.PriceOverride, error)
+	// Update saves the override's price, currency, effective date and note
+	Update(ctx context.Context, override *models.PriceOverride) error
+	Delete(ctx context.Context, userID primitive.ObjectID, symbol string) error
+	Version(ctx context.Context, userID primitive.ObjectID) (Version, error)
+}
+
+// SessionRepo stores login sessions
+type SessionRepo interface {
+	Insert(ctx context.Context, session *models.Session) error
+	FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.Session, error)
+	// FindActive returns the user's sessions that are neither revoked nor
+	// expired at now, most recently seen first
+	FindActive(ctx context.Context, userID primitive.ObjectID, now time.Time) ([]models.Session, error)
+	// Touch records that the session was used from ip at the given time
+	Touch(ctx context.Context, userID, id primitive.ObjectID, ip string, at time.Time) error
+	// Revoke ends an active session. It returns ErrNotFound if the session
+	// does not exist or was already revoked.
+	Revoke(ctx context.Context, userID, id primitive.ObjectID, at time.Time) error
+	// RevokeAllExcept ends every active session of the user but keep,
+	// returning how many were revoked
+	RevokeAllExcept(ctx context.Context, userID, keep primitive.ObjectID, at time.Time) (int64, error)
+	// FindActiveUsers returns every user with a session that has not been
+	// revoked and was used since the given time, for the cache warming job
+	FindActiveUsers(ctx context.Context, since time.Time) ([]primitive.ObjectID, error)
+}
+
+// UserRepo stores user accounts
+type UserRepo interface {
+	Insert(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdateProfile(ctx context.Context, id primitive.ObjectID, profile models.UserProfile, at time.Time) error
+	// SetAvatar points the user at a stored avatar, or clears it when
+	// avatarID is nil
+	SetAvatar(ctx context.Context, id primitive.ObjectID, avatarID *primitive.ObjectID, at time.Time) error
+	// SetPassword replaces the user's password hash
+	SetPassword(ctx context.Context, id primitive.ObjectID, hash string, at time.Time) error
+	// FindIDs returns the ID of every user, in creation order, for
+	// maintenance jobs that work through all accounts
+	FindIDs(ctx context.Context) ([]primitive.ObjectID, error)
+}
+
+// AvatarRepo stores users' profile pictures
+type AvatarRepo interface {
+	Put(ctx context.Context, userID primitive.ObjectID, contentType string, data []byte) (primitive.ObjectID, error)
+	Get(ctx context.Context, userID, id primitive.ObjectID) (data []byte, contentType string, err error)
+	Delete(ctx context.Context, userID, id primitive.ObjectID) error
+}
+
+// OutboxRepo stores notifications waiting to be delivered. Its queries are
+// unscoped like FindOpen: the delivery worker serves every user.
+type OutboxRepo interface {
+	Insert(ctx context.Context, msg *models.OutboxMessage) error
+	// ClaimDue leases the pending message that has been due longest by
+	// pushing its next attempt to leaseUntil and counting the attempt, so
+	// concurrent workers never deliver it twice. It returns ErrNotFound
+	// when no message is due.
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time) (*models.OutboxMessage, error)
+	// Update records the outcome of a delivery attempt
+	Update(ctx context.Context, msg *models.OutboxMessage) error
+}
+
+// SettingsRepo stores the settings of each user. Its subscriber queries are
+// unscoped like FindOpen: the alert jobs serve every user.
+type SettingsRepo interface {
+	// FindDriftAlertSubscribers returns the settings of every user with drift
+	// alerts enabled
+	FindDriftAlertSubscribers(ctx context.Context) ([]models.UserSettings, error)
+	// ClaimDriftAlert records breached as the user's out-of-band groups if
+	// they are still expected, stamping alertedAt when it is set, so
+	// concurrent jobs never alert the same drift twice. It returns
+	// ErrNotFound if another writer changed them first.
+	ClaimDriftAlert(ctx context.Context, userID, id primitive.ObjectID, expected, breached []string, alertedAt *time.Time) error
+	// FindCrossAlertSubscribers returns the settings of every user with
+	// moving average cross alerts enabled
+	FindCrossAlertSubscribers(ctx context.Context) ([]models.UserSettings, error)
+	// ClaimCrossAlert records alerted as the user's recent crosses if they
+	// are still expected, stamping alertedAt when it is set. It returns
+	// ErrNotFound if another writer changed them first.
+	ClaimCrossAlert(ctx context.Context, userID, id primitive.ObjectID, expected, alerted []models.AlertedCross, alertedAt *time.Time) error
+}
+
+// MaintenanceRepo stores the maintenance mode switch
+type MaintenanceRepo interface {
+	// Get returns the stored switch, or ErrNotFound if it was never set
+	Get(ctx context.Context) (*models.MaintenanceMode, error)
+	Set(ctx context.Context, mode *models.MaintenanceMode) error
+}
+
+// FeatureFlagRepo stores feature flags, keyed by name
+type FeatureFlagRepo interface {
+	// FindAll returns every flag ordered by key
+	FindAll(ctx context.Context) ([]models.FeatureFlag, error)
+	// Save creates the flag or replaces the one with the same key
+	Save(ctx context.Context, flag *models.FeatureFlag) error
+	Delete(ctx context.Context, key string) error
+}
+
+// SymbolRepo stores the symbol metadata catalog, keyed by symbol
+type SymbolRepo interface {
+	FindAll(ctx context.Context) ([]models.SymbolMetadata, error)
+	// Save creates or updates the symbol's metadata, keeping the time it was
+	// first seen and any rename when it already exists
+	Save(ctx context.Context, symbol *models.SymbolMetadata) error
+	// Rename records that from now trades as to. Symbols renamed to from
+	// earlier are pointed at to as well, so a rename is never more than one
+	// hop away.
+	Rename(ctx context.Context, from, to string, at time.Time) error
+}
+
+// Transactor runs a function in a database transaction, committing when it
+// returns nil and rolling back otherwise. Repository calls made with the
+// context passed to fn take part in the transaction.
+type Transactor interface {
+	Run(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Repositories bundles the repositories services depend on
+type Repositories struct {
+	Transactions    TransactionRepo
+	Portfolios      PortfolioRepo
+	AssetStyles     AssetStyleRepo
+	AssetSubclasses AssetSubclassRepo
+	PendingOrders   PendingOrderRepo
+	Webhooks        WebhookRepo
+	BrokerageLinks  BrokerageLinkRepo
+	SheetsLinks     SheetsLinkRepo
+	CashInterest    CashInterestRepo
+	PriceOverrides  PriceOverrideRepo
+	Sessions        SessionRepo
+	Users           UserRepo
+	Settings        SettingsRepo
+	Avatars         AvatarRepo
+	Outbox          OutboxRepo
+	Maintenance     MaintenanceRepo
+	FeatureFlags    FeatureFlagRepo
+	Symbols         SymbolRepo
+	Tx              Transactor
+}
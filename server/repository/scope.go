@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrMissingOwner is returned when a user-owned document is stored without a
+// user ID
+var ErrMissingOwner = errors.New("document has no owning user")
+
+// userScope restricts a collection to one user's documents. Every query it
+// issues carries the user_id filter, so repository methods only describe what
+// they look for and can't forget whose data they touch.
+type userScope struct {
+	collection *mongo.Collection
+	userID     primitive.ObjectID
+}
+
+// scope restricts the collection to the user's documents
+func scope(collection *mongo.Collection, userID primitive.ObjectID) userScope {
+	return userScope{collection: collection, userID: userID}
+}
+
+// filter returns a copy of f limited to the user. A user_id already in f is
+// replaced rather than trusted.
+func (s userScope) filter(f bson.M) bson.M {
+	scoped := make(bson.M, len(f)+1)
+	for key, value := range f {
+		scoped[key] = value
+	}
+	scoped["user_id"] = s.userID
+	return scoped
+}
+
+// checkOwner verifies a document being stored belongs to a user
+func checkOwner(userID primitive.ObjectID) error {
+	if userID.IsZero() {
+		return ErrMissingOwner
+	}
+	return nil
+}
+
+func (s userScope) FindOne(ctx context.Context, f bson.M, out interface{}, opts ...*options.FindOneOptions) error {
+	err := s.collection.FindOne(ctx, s.filter(f), opts...).Decode(out)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s userScope) Find(ctx context.Context, f bson.M, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	return s.collection.Find(ctx, s.filter(f), opts...)
+}
+
+func (s userScope) CountDocuments(ctx context.Context, f bson.M) (int64, error) {
+	return s.collection.CountDocuments(ctx, s.filter(f))
+}
+
+// Aggregate runs the pipeline over the user's documents only
+func (s userScope) Aggregate(ctx context.Context, pipeline mongo.Pipeline, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	scoped := append(mongo.Pipeline{{{Key: "$match", Value: s.filter(nil)}}}, pipeline...)
+	return s.collection.Aggregate(ctx, scoped, opts...)
+}
+
+// UpdateOne updates the matching document and returns ErrNotFound when none
+// matches
+func (s userScope) UpdateOne(ctx context.Context, f bson.M, update interface{}) error {
+	result, err := s.collection.UpdateOne(ctx, s.filter(f), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s userScope) UpdateMany(ctx context.Context, f bson.M, update interface{}) error {
+	_, err := s.collection.UpdateMany(ctx, s.filter(f), update)
+	return err
+}
+
+// ReplaceOne replaces the matching document and returns ErrNotFound when none
+// matches
+func (s userScope) ReplaceOne(ctx context.Context, f bson.M, replacement interface{}) error {
+	result, err := s.collection.ReplaceOne(ctx, s.filter(f), replacement)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteOne deletes the matching document and returns ErrNotFound when none
+// matches
+func (s userScope) DeleteOne(ctx context.Context, f bson.M) error {
+	result, err := s.collection.DeleteOne(ctx, s.filter(f))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"stock-portfolio-tracker/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestScopeFilterForcesUser(t *testing.T) {
+	userID := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+	s := scope(nil, userID)
+
+	original := bson.M{"symbol": "AAPL", "user_id": other}
+	filter := s.filter(original)
+	if filter["user_id"] != userID {
+		t.Errorf("Expected user_id %v, got %v", userID, filter["user_id"])
+	}
+	if filter["symbol"] != "AAPL" {
+		t.Errorf("Expected symbol to be kept, got %v", filter["symbol"])
+	}
+	if original["user_id"] != other {
+		t.Error("Expected the caller's filter to be left unchanged")
+	}
+
+	if empty := s.filter(nil); len(empty) != 1 || empty["user_id"] != userID {
+		t.Errorf("Expected nil filter to become the user filter, got %v", empty)
+	}
+}
+
+func TestMemoryReposRejectMissingOwner(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemory()
+
+	tx := newTransaction(primitive.NilObjectID, "AAPL", "buy", 1, 100, time.Now())
+	if err := repos.Transactions.Insert(ctx, tx); !errors.Is(err, ErrMissingOwner) {
+		t.Errorf("Expected ErrMissingOwner for transaction, got %v", err)
+	}
+	if err := repos.Portfolios.Insert(ctx, &models.Portfolio{ID: primitive.NewObjectID(), Symbol: "AAPL"}); !errors.Is(err, ErrMissingOwner) {
+		t.Errorf("Expected ErrMissingOwner for portfolio, got %v", err)
+	}
+	if err := repos.AssetStyles.Insert(ctx, &models.AssetStyle{ID: primitive.NewObjectID(), Name: "Growth"}); !errors.Is(err, ErrMissingOwner) {
+		t.Errorf("Expected ErrMissingOwner for asset style, got %v", err)
+	}
+}
+
+func TestMemoryReposIsolateUsers(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemory()
+	owner := primitive.NewObjectID()
+	intruder := primitive.NewObjectID()
+
+	styleID := primitive.NewObjectID()
+	portfolio := &models.Portfolio{ID: primitive.NewObjectID(), UserID: owner, Symbol: "AAPL", AssetStyleID: &styleID}
+	if err := repos.Portfolios.Insert(ctx, portfolio); err != nil {
+		t.Fatalf("Failed to insert portfolio: %v", err)
+	}
+	tx := newTransaction(owner, "AAPL", "buy", 1, 100, time.Now())
+	if err := repos.Transactions.Insert(ctx, tx); err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+
+	if _, err := repos.Portfolios.FindByID(ctx, intruder, portfolio.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound reading another user's portfolio, got %v", err)
+	}
+	if count, _ := repos.Portfolios.CountByAssetStyle(ctx, intruder, styleID); count != 0 {
+		t.Errorf("Expected another user's portfolios not to be counted, got %d", count)
+	}
+	if count, _ := repos.Portfolios.CountByAssetStyle(ctx, owner, styleID); count != 1 {
+		t.Errorf("Expected owner's usage count 1, got %d", count)
+	}
+
+	stolen := *tx
+	stolen.UserID = intruder
+	stolen.Shares = 1000
+	if err := repos.Transactions.Replace(ctx, &stolen); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound replacing another user's transaction, got %v", err)
+	}
+	if err := repos.Transactions.Delete(ctx, intruder, tx.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting another user's transaction, got %v", err)
+	}
+	if got, err := repos.Transactions.FindByID(ctx, owner, tx.ID); err != nil || got.Shares != 1 {
+		t.Errorf("Expected owner's transaction untouched, got %+v, %v", got, err)
+	}
+}
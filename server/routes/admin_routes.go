@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAdminRoutes sets up the maintenance routes used by the admin CLI,
+// authenticated with the shared admin token. They are not mounted when no
+// token is configured.
+func SetupAdminRoutes(router gin.IRouter, token string, stockService *services.StockAPIService, currencyService *services.CurrencyService, symbolCatalog *services.SymbolCatalog) {
+	if token == "" {
+		return
+	}
+	adminHandler := handlers.NewAdminHandler(stockService, currencyService, symbolCatalog)
+
+	adminGroup := router.Group("/admin", middleware.AdminTokenMiddleware(token))
+	{
+		adminGroup.POST("/cache/flush", adminHandler.FlushCaches)
+		adminGroup.POST("/symbols/renames", adminHandler.RenameSymbol)
+	}
+}
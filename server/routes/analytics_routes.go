@@ -4,22 +4,46 @@ import (
 	"stock-portfolio-tracker/handlers"
 	"stock-portfolio-tracker/middleware"
 	"stock-portfolio-tracker/services"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupAnalyticsRoutes configures analytics-related routes
-func SetupAnalyticsRoutes(router *gin.Engine, analyticsService *services.AnalyticsService, authService *services.AuthService) {
-	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+// SetupAnalyticsRoutes configures analytics-related routes, each bounded by
+// timeout, except event streams, which are bounded by streamTimeout
+func SetupAnalyticsRoutes(router gin.IRouter, analyticsService *services.AnalyticsService, benchmarkService *services.BenchmarkComparisonService, authService *services.AuthService, timeout time.Duration, streamTimeout time.Duration) {
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService, benchmarkService)
 
 	// Analytics routes group - all protected
-	analyticsGroup := router.Group("/api/analytics")
-	analyticsGroup.Use(middleware.AuthMiddleware(authService))
+	analyticsGroup := router.Group("/analytics")
+	analyticsGroup.Use(middleware.RequestTimeout(timeout), middleware.AuthMiddleware(authService))
 	{
 		// Dashboard metrics
 		analyticsGroup.GET("/dashboard", analyticsHandler.GetDashboard)
 
 		// Historical performance
 		analyticsGroup.GET("/performance", analyticsHandler.GetPerformance)
+		analyticsGroup.GET("/performance/events", middleware.RequestTimeout(streamTimeout), analyticsHandler.GetPerformanceEvents)
+
+		// Net worth timeline across investments and cash
+		analyticsGroup.GET("/networth", analyticsHandler.GetNetWorth)
+
+		// Best and worst performing holdings
+		analyticsGroup.GET("/movers", analyticsHandler.GetMovers)
+
+		// Month-, quarter- and year-to-date and per-year returns
+		analyticsGroup.GET("/returns/calendar", analyticsHandler.GetCalendarReturns)
+
+		// Risk metrics
+		analyticsGroup.GET("/risk", analyticsHandler.GetRisk)
+
+		// Per-holding contribution to the period return
+		analyticsGroup.GET("/attribution", analyticsHandler.GetAttribution)
+
+		// Asset return versus FX translation effect
+		analyticsGroup.GET("/currency-effect", analyticsHandler.GetCurrencyEffect)
+
+		// Dividend calendar and projected income
+		analyticsGroup.GET("/income", analyticsHandler.GetIncome)
 	}
 }
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAssetClassRoutes sets up the asset class taxonomy routes
+func SetupAssetClassRoutes(router gin.IRouter, authService *services.AuthService) {
+	assetClassHandler := handlers.NewAssetClassHandler(services.NewAssetClassService())
+
+	// Asset class routes (all require authentication)
+	assetClassGroup := router.Group("/asset-classes")
+	assetClassGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		assetClassGroup.GET("", assetClassHandler.GetTree)
+		assetClassGroup.POST("/subclasses", middleware.ValidateJSON(models.AssetSubclassRequest{}), assetClassHandler.CreateSubclass)
+		assetClassGroup.PUT("/subclasses/:id", middleware.ValidateJSON(models.RenameAssetSubclassRequest{}), assetClassHandler.RenameSubclass)
+		assetClassGroup.DELETE("/subclasses/:id", assetClassHandler.DeleteSubclass)
+	}
+}
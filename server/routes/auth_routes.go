@@ -8,19 +8,25 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// SetupAuthRoutes configures authentication routes
-func SetupAuthRoutes(router *gin.Engine, authService *services.AuthService) {
+// SetupAuthRoutes configures authentication routes behind authRateLimiter,
+// which is shared by every API version so clients cannot multiply their
+// allowance by switching prefixes
+func SetupAuthRoutes(router gin.IRouter, authService *services.AuthService, authRateLimiter gin.HandlerFunc) {
 	authHandler := handlers.NewAuthHandler(authService)
 
-	// Auth routes group with stricter rate limiting (10 requests per minute)
-	authGroup := router.Group("/api/auth")
-	authGroup.Use(middleware.AuthRateLimiter())
+	// Auth routes group with stricter rate limiting
+	authGroup := router.Group("/auth")
+	authGroup.Use(authRateLimiter)
 	{
 		// Public routes
 		authGroup.POST("/register", authHandler.Register)
 		authGroup.POST("/login", authHandler.Login)
 
 		// Protected routes
-		authGroup.GET("/me", middleware.AuthMiddleware(authService), authHandler.GetCurrentUser)
+		authMiddleware := middleware.AuthMiddleware(authService)
+		authGroup.GET("/me", authMiddleware, authHandler.GetCurrentUser)
+		authGroup.GET("/sessions", authMiddleware, authHandler.ListSessions)
+		authGroup.DELETE("/sessions", authMiddleware, authHandler.RevokeOtherSessions)
+		authGroup.DELETE("/sessions/:id", authMiddleware, authHandler.RevokeSession)
 	}
 }
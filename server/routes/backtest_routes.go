@@ -3,20 +3,28 @@ package routes
 import (
 	"stock-portfolio-tracker/handlers"
 	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
 	"stock-portfolio-tracker/services"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupBacktestRoutes configures backtest-related routes
-func SetupBacktestRoutes(router *gin.Engine, backtestService *services.BacktestService, authService *services.AuthService) {
-	backtestHandler := handlers.NewBacktestHandler(backtestService)
+// SetupBacktestRoutes configures backtest-related routes. Synchronous
+// backtests are bounded by timeout.
+func SetupBacktestRoutes(router gin.IRouter, backtestService *services.BacktestService, backtestJobService *services.BacktestJobService, authService *services.AuthService, timeout time.Duration) {
+	backtestHandler := handlers.NewBacktestHandler(backtestService, backtestJobService)
 
 	// Backtest routes group - all protected
-	backtestGroup := router.Group("/api/backtest")
-	backtestGroup.Use(middleware.AuthMiddleware(authService))
+	backtestGroup := router.Group("/backtest")
+	backtestGroup.Use(middleware.RequestTimeout(timeout), middleware.AuthMiddleware(authService))
 	{
 		// Run backtest
 		backtestGroup.GET("", backtestHandler.GetBacktest)
+
+		// Long-running backtests executed in the background
+		backtestGroup.POST("/jobs", middleware.ValidateJSON(models.BacktestJobRequest{}), backtestHandler.SubmitBacktestJob)
+		backtestGroup.GET("/jobs/:id", backtestHandler.GetBacktestJob)
+		backtestGroup.GET("/jobs/:id/result", backtestHandler.GetBacktestJobResult)
 	}
 }
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupBenchmarkRoutes configures benchmark-related routes
+func SetupBenchmarkRoutes(router gin.IRouter, stockService *services.StockAPIService, authService *services.AuthService) {
+	blendService := services.NewBenchmarkBlendService()
+	catalogService := services.NewBenchmarkCatalogService(stockService)
+	benchmarkHandler := handlers.NewBenchmarkHandler(blendService, catalogService)
+
+	// Benchmark routes group - all protected
+	benchmarkGroup := router.Group("/benchmarks")
+	benchmarkGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		// Benchmark catalog with the user's custom benchmarks
+		benchmarkGroup.GET("", benchmarkHandler.GetBenchmarks)
+		benchmarkGroup.POST("/custom", middleware.ValidateJSON(models.CustomBenchmarkRequest{}), benchmarkHandler.CreateCustomBenchmark)
+		benchmarkGroup.DELETE("/custom/:id", benchmarkHandler.DeleteCustomBenchmark)
+
+		// Saved benchmark blends
+		benchmarkGroup.GET("/blends", benchmarkHandler.GetBlends)
+		benchmarkGroup.POST("/blends", middleware.ValidateJSON(models.BenchmarkBlendRequest{}), benchmarkHandler.CreateBlend)
+		benchmarkGroup.DELETE("/blends/:id", benchmarkHandler.DeleteBlend)
+	}
+}
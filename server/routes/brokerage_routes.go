@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupBrokerageRoutes configures brokerage account linking routes. Linking
+// an exchange is limited to users who get the exchange links feature.
+func SetupBrokerageRoutes(router gin.IRouter, brokerageService *services.BrokerageService, featureFlagService *services.FeatureFlagService, authService *services.AuthService) {
+	brokerageHandler := handlers.NewBrokerageHandler(brokerageService)
+
+	// Brokerage links routes group - all protected
+	linksGroup := router.Group("/brokerage/links")
+	linksGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		linksGroup.GET("", brokerageHandler.GetLinks)
+		linksGroup.POST("", middleware.ValidateJSON(models.BrokerageLinkStartRequest{}), brokerageHandler.StartLink)
+		linksGroup.POST("/exchange", middleware.RequireFeature(featureFlagService, services.FeatureExchangeLinks),
+			middleware.ValidateJSON(models.ExchangeLinkRequest{}), brokerageHandler.LinkExchange)
+		linksGroup.POST("/:id/complete", middleware.ValidateJSON(models.BrokerageLinkCompleteRequest{}), brokerageHandler.CompleteLink)
+		linksGroup.POST("/:id/sync", brokerageHandler.SyncLink)
+		linksGroup.DELETE("/:id", brokerageHandler.DeleteLink)
+	}
+}
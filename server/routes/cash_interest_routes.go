@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupCashInterestRoutes configures cash balance interest rate routes
+func SetupCashInterestRoutes(router gin.IRouter, cashInterestService *services.CashInterestService, authService *services.AuthService) {
+	cashInterestHandler := handlers.NewCashInterestHandler(cashInterestService)
+
+	// Cash interest routes group - all protected
+	interestGroup := router.Group("/portfolio/cash-interest")
+	interestGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		interestGroup.GET("", cashInterestHandler.GetRates)
+		interestGroup.PUT("/:symbol", middleware.ValidateJSON(models.CashInterestRequest{}), cashInterestHandler.SetRate)
+		interestGroup.DELETE("/:symbol", cashInterestHandler.DeleteRate)
+	}
+}
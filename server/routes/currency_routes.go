@@ -8,11 +8,13 @@ import (
 )
 
 // SetupCurrencyRoutes sets up currency-related routes
-func SetupCurrencyRoutes(router *gin.Engine, currencyService *services.CurrencyService) {
+func SetupCurrencyRoutes(router gin.IRouter, currencyService *services.CurrencyService) {
 	currencyHandler := handlers.NewCurrencyHandler(currencyService)
 	
-	currencyGroup := router.Group("/api/currency")
+	currencyGroup := router.Group("/currency")
 	{
 		currencyGroup.GET("/rate", currencyHandler.GetExchangeRate)
+		currencyGroup.GET("/rates", currencyHandler.GetRateTable)
+		currencyGroup.POST("/convert", currencyHandler.ConvertBatch)
 	}
 }
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupExportRoutes configures account data export routes
+func SetupExportRoutes(router gin.IRouter, exportService *services.ExportService, authService *services.AuthService) {
+	exportHandler := handlers.NewExportHandler(exportService)
+	authMiddleware := middleware.AuthMiddleware(authService)
+
+	// Protected routes for starting and following an export
+	router.POST("/me/export", authMiddleware, exportHandler.StartExport)
+	router.GET("/me/export", authMiddleware, exportHandler.GetExport)
+
+	// Public download link carrying its own token
+	router.GET("/exports/:token", exportHandler.DownloadExport)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupFeatureFlagRoutes configures the route clients use to learn which
+// features the user gets
+func SetupFeatureFlagRoutes(router gin.IRouter, featureFlagService *services.FeatureFlagService, authService *services.AuthService) {
+	featureFlagHandler := handlers.NewFeatureFlagHandler(featureFlagService)
+
+	router.GET("/features", middleware.AuthMiddleware(authService), featureFlagHandler.GetFeatures)
+}
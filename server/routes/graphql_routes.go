@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"fmt"
+	"stock-portfolio-tracker/graphqlapi"
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupGraphQLRoutes configures the GraphQL endpoint
+func SetupGraphQLRoutes(router gin.IRouter, portfolioService *services.PortfolioService, stockService *services.StockAPIService, analyticsService *services.AnalyticsService, authService *services.AuthService) {
+	schema, err := graphqlapi.NewSchema(portfolioService, stockService, analyticsService, services.NewAssetStyleService())
+	if err != nil {
+		// The schema is embedded in the binary, so this is a programming error
+		panic(fmt.Sprintf("failed to parse GraphQL schema: %v", err))
+	}
+	graphqlHandler := handlers.NewGraphQLHandler(schema)
+
+	// GraphQL route - protected
+	router.POST("/graphql", middleware.AuthMiddleware(authService), graphqlHandler.Query)
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupHouseholdRoutes configures linked account and household routes
+func SetupHouseholdRoutes(router gin.IRouter, householdService *services.HouseholdService, authService *services.AuthService) {
+	householdHandler := handlers.NewHouseholdHandler(householdService)
+
+	// Account sharing routes - all protected
+	sharingGroup := router.Group("/sharing")
+	sharingGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		sharingGroup.GET("", householdHandler.GetLinks)
+		sharingGroup.POST("/invite", middleware.ValidateJSON(models.AccountInviteRequest{}), householdHandler.Invite)
+		sharingGroup.POST("/:id/accept", householdHandler.AcceptInvite)
+		sharingGroup.DELETE("/:id", householdHandler.RemoveLink)
+	}
+
+	// Household view routes - all protected
+	householdGroup := router.Group("/household")
+	householdGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		householdGroup.GET("/dashboard", householdHandler.GetDashboard)
+		householdGroup.GET("/performance", householdHandler.GetPerformance)
+	}
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupImportRoutes configures broker statement import routes
+func SetupImportRoutes(router gin.IRouter, importService *services.ImportService, authService *services.AuthService) {
+	importHandler := handlers.NewImportHandler(importService)
+
+	// Import routes group - all protected
+	importGroup := router.Group("/import")
+	importGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		importGroup.GET("/brokers", importHandler.GetBrokers)
+		importGroup.POST("/preview", importHandler.Preview)
+		importGroup.POST("/commit", importHandler.Commit)
+	}
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupMetaRoutes sets up the public reference data routes
+func SetupMetaRoutes(router gin.IRouter) {
+	metaHandler := handlers.NewMetaHandler()
+
+	metaGroup := router.Group("/meta")
+	{
+		metaGroup.GET("/currencies", metaHandler.GetCurrencies)
+	}
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupPendingOrderRoutes configures pending limit/stop order routes
+func SetupPendingOrderRoutes(router gin.IRouter, pendingOrderService *services.PendingOrderService, authService *services.AuthService) {
+	pendingOrderHandler := handlers.NewPendingOrderHandler(pendingOrderService)
+
+	// Pending orders routes group - all protected
+	ordersGroup := router.Group("/portfolio/pending-orders")
+	ordersGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		ordersGroup.GET("", pendingOrderHandler.GetOrders)
+		ordersGroup.POST("", middleware.ValidateJSON(models.PendingOrderRequest{}), pendingOrderHandler.CreateOrder)
+		ordersGroup.DELETE("/:id", pendingOrderHandler.CancelOrder)
+	}
+}
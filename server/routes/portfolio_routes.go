@@ -3,35 +3,52 @@ package routes
 import (
 	"stock-portfolio-tracker/handlers"
 	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
 	"stock-portfolio-tracker/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupPortfolioRoutes configures portfolio-related routes
-func SetupPortfolioRoutes(router *gin.Engine, portfolioService *services.PortfolioService, authService *services.AuthService) {
+func SetupPortfolioRoutes(router gin.IRouter, portfolioService *services.PortfolioService, reconciliationService *services.ReconciliationService, newsService *services.NewsService, authService *services.AuthService) {
 	portfolioHandler := handlers.NewPortfolioHandler(portfolioService)
+	reconciliationHandler := handlers.NewReconciliationHandler(reconciliationService)
+	newsHandler := handlers.NewNewsHandler(newsService)
 
 	// Portfolio routes group - all protected
-	portfolioGroup := router.Group("/api/portfolio")
+	portfolioGroup := router.Group("/portfolio")
 	portfolioGroup.Use(middleware.AuthMiddleware(authService))
 	{
 		// Holdings
 		portfolioGroup.GET("/holdings", portfolioHandler.GetHoldings)
+		portfolioGroup.GET("/holdings/export", portfolioHandler.ExportHoldings)
+		portfolioGroup.GET("/holdings/:symbol", portfolioHandler.GetHolding)
+		portfolioGroup.POST("/holdings/:symbol/dispose", middleware.ValidateJSON(models.DisposalRequest{}), portfolioHandler.DisposePosition)
 
 		// Transactions
-		portfolioGroup.POST("/transactions", portfolioHandler.AddTransaction)
-		portfolioGroup.PUT("/transactions/:id", portfolioHandler.UpdateTransaction)
+		portfolioGroup.GET("/transactions", portfolioHandler.GetTransactions)
+		portfolioGroup.POST("/transactions", middleware.ValidateJSON(models.TransactionRequest{}), portfolioHandler.AddTransaction)
+		portfolioGroup.PUT("/transactions/:id", middleware.ValidateJSON(models.TransactionRequest{}), portfolioHandler.UpdateTransaction)
 		portfolioGroup.DELETE("/transactions/:id", portfolioHandler.DeleteTransaction)
 		portfolioGroup.GET("/transactions/:symbol", portfolioHandler.GetTransactionsBySymbol)
+
+		// What-if trades, not recorded
+		portfolioGroup.POST("/simulate", middleware.ValidateJSON(models.TradeSimulationRequest{}), portfolioHandler.SimulateTrades)
+
+		// Reconciliation against broker positions
+		portfolioGroup.POST("/reconcile", middleware.ValidateJSON(models.ReconcileRequest{}), reconciliationHandler.Reconcile)
+
+		// Headlines about the user's holdings
+		portfolioGroup.GET("/news", newsHandler.GetPortfolioNews)
 	}
 
 	// Portfolios routes group - all protected
-	portfoliosGroup := router.Group("/api/portfolios")
+	portfoliosGroup := router.Group("/portfolios")
 	portfoliosGroup.Use(middleware.AuthMiddleware(authService))
 	{
 		portfoliosGroup.GET("/:id", portfolioHandler.GetPortfolio)
-		portfoliosGroup.PUT("/:id/metadata", portfolioHandler.UpdatePortfolioMetadata)
+		portfoliosGroup.PUT("/:id/metadata", middleware.ValidateJSON(models.UpdatePortfolioMetadataRequest{}), portfolioHandler.UpdatePortfolioMetadata)
+		portfoliosGroup.PUT("/:id/tags", middleware.ValidateJSON(models.PortfolioTagsRequest{}), portfolioHandler.UpdatePortfolioTags)
 		portfoliosGroup.GET("/check/:symbol", portfolioHandler.CheckPortfolio)
 	}
 }
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupPriceOverrideRoutes configures manual price routes
+func SetupPriceOverrideRoutes(router gin.IRouter, priceOverrideService *services.PriceOverrideService, authService *services.AuthService) {
+	priceOverrideHandler := handlers.NewPriceOverrideHandler(priceOverrideService)
+
+	// Price override routes group - all protected
+	overridesGroup := router.Group("/portfolio/price-overrides")
+	overridesGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		overridesGroup.GET("", priceOverrideHandler.GetOverrides)
+		overridesGroup.PUT("/:symbol", middleware.ValidateJSON(models.PriceOverrideRequest{}), priceOverrideHandler.SetOverride)
+		overridesGroup.DELETE("/:symbol", priceOverrideHandler.DeleteOverride)
+	}
+}
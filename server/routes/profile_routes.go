@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupProfileRoutes configures the current user's profile routes
+func SetupProfileRoutes(router gin.IRouter, profileService *services.ProfileService, authService *services.AuthService) {
+	profileHandler := handlers.NewProfileHandler(profileService)
+
+	// Profile routes group - all protected
+	profileGroup := router.Group("/me")
+	profileGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		profileGroup.GET("", profileHandler.GetProfile)
+		profileGroup.PUT("", middleware.ValidateJSON(models.ProfileRequest{}), profileHandler.UpdateProfile)
+
+		// Avatar image
+		profileGroup.GET("/avatar", profileHandler.GetAvatar)
+		profileGroup.PUT("/avatar", profileHandler.UploadAvatar)
+		profileGroup.DELETE("/avatar", profileHandler.DeleteAvatar)
+	}
+}
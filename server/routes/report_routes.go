@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupReportRoutes configures report-related routes
+func SetupReportRoutes(router gin.IRouter, reportService *services.ReportService, authService *services.AuthService) {
+	reportHandler := handlers.NewReportHandler(reportService)
+
+	// Report routes group - all protected
+	reportGroup := router.Group("/reports")
+	reportGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		// Rendered portfolio report (PDF or HTML)
+		reportGroup.GET("/portfolio", reportHandler.GetPortfolioReport)
+	}
+}
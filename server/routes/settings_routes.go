@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupSettingsRoutes configures user settings routes
+func SetupSettingsRoutes(router gin.IRouter, settingsService *services.SettingsService, summaryEmailService *services.SummaryEmailService, chatChannels *services.ChatChannels, authService *services.AuthService) {
+	settingsHandler := handlers.NewSettingsHandler(settingsService, summaryEmailService, chatChannels)
+
+	// Settings routes group - all protected
+	settingsGroup := router.Group("/settings")
+	settingsGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		settingsGroup.GET("", settingsHandler.GetSettings)
+
+		// Recurring summary emails
+		settingsGroup.PUT("/summary-email", middleware.ValidateJSON(models.SummaryEmailSettingsRequest{}), settingsHandler.UpdateSummaryEmail)
+		settingsGroup.POST("/summary-email/test", settingsHandler.SendTestSummaryEmail)
+
+		// Target weights and drift alerts
+		settingsGroup.PUT("/drift-alerts", middleware.ValidateJSON(models.DriftAlertSettingsRequest{}), settingsHandler.UpdateDriftAlerts)
+
+		// Golden and death cross alerts on held symbols
+		settingsGroup.PUT("/cross-alerts", middleware.ValidateJSON(models.CrossAlertSettingsRequest{}), settingsHandler.UpdateCrossAlerts)
+
+		// Telegram and Slack notification destinations
+		settingsGroup.PUT("/chat", middleware.ValidateJSON(models.ChatSettingsRequest{}), settingsHandler.UpdateChat)
+		settingsGroup.POST("/chat/:channel/test", settingsHandler.SendTestChatMessage)
+
+		// Default benchmark for dashboard and performance comparisons
+		settingsGroup.PUT("/benchmarks", middleware.ValidateJSON(models.BenchmarkSettingsRequest{}), settingsHandler.UpdateBenchmarks)
+	}
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupShareRoutes configures portfolio share link routes
+func SetupShareRoutes(router gin.IRouter, shareService *services.ShareService, authService *services.AuthService) {
+	shareHandler := handlers.NewShareHandler(shareService)
+	authMiddleware := middleware.AuthMiddleware(authService)
+
+	shareGroup := router.Group("/share")
+	{
+		// Protected routes for managing links
+		shareGroup.POST("", authMiddleware, middleware.ValidateJSON(models.CreateShareLinkRequest{}), shareHandler.CreateLink)
+		shareGroup.GET("", authMiddleware, shareHandler.GetLinks)
+		shareGroup.DELETE("/:id", authMiddleware, shareHandler.RevokeLink)
+
+		// Public read-only dashboard
+		shareGroup.GET("/:token", shareHandler.GetSharedDashboard)
+	}
+}
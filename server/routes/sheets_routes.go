@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupSheetsRoutes configures the Google Sheets export routes
+func SetupSheetsRoutes(router gin.IRouter, sheetsService *services.SheetsService, authService *services.AuthService) {
+	sheetsHandler := handlers.NewSheetsHandler(sheetsService)
+
+	// Linked sheet routes group - all protected
+	sheetsGroup := router.Group("/settings/sheets")
+	sheetsGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		sheetsGroup.GET("", sheetsHandler.GetLink)
+		sheetsGroup.POST("/link", middleware.ValidateJSON(models.SheetsLinkRequest{}), sheetsHandler.StartLink)
+		sheetsGroup.POST("/authorize", middleware.ValidateJSON(models.SheetsAuthorizeRequest{}), sheetsHandler.Authorize)
+		sheetsGroup.POST("/sync", sheetsHandler.Sync)
+		sheetsGroup.DELETE("", sheetsHandler.Unlink)
+	}
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupSimulationRoutes configures portfolio simulation routes
+func SetupSimulationRoutes(router gin.IRouter, withdrawalService *services.WithdrawalService, authService *services.AuthService) {
+	simulationHandler := handlers.NewSimulationHandler(withdrawalService)
+
+	// Simulation routes group - all protected
+	simulationGroup := router.Group("/simulations")
+	simulationGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		// Withdrawal-rate / retirement simulation
+		simulationGroup.POST("/withdrawals", middleware.ValidateJSON(models.WithdrawalSimulationRequest{}), simulationHandler.SimulateWithdrawals)
+	}
+}
@@ -8,13 +8,19 @@ import (
 )
 
 // SetupStockRoutes sets up stock-related routes
-func SetupStockRoutes(router *gin.Engine, stockService *services.StockAPIService) {
+func SetupStockRoutes(router gin.IRouter, stockService *services.StockAPIService, newsService *services.NewsService) {
 	stockHandler := handlers.NewStockHandler(stockService)
+	newsHandler := handlers.NewNewsHandler(newsService)
 	
-	stockGroup := router.Group("/api/stocks")
+	stockGroup := router.Group("/stocks")
 	{
+		stockGroup.GET("/markets", stockHandler.GetMarkets)
 		stockGroup.GET("/search/:symbol", stockHandler.SearchStock)
 		stockGroup.GET("/:symbol/info", stockHandler.GetStockInfo)
 		stockGroup.GET("/:symbol/history", stockHandler.GetStockHistory)
+		stockGroup.GET("/:symbol/indicators", stockHandler.GetIndicators)
+		stockGroup.GET("/:symbol/market", stockHandler.GetSymbolMarket)
+		stockGroup.GET("/:symbol/fundamentals", stockHandler.GetFundamentals)
+		stockGroup.GET("/:symbol/news", newsHandler.GetSymbolNews)
 	}
 }
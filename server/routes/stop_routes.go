@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupStopRoutes configures position stop-loss routes
+func SetupStopRoutes(router gin.IRouter, stopService *services.StopService, authService *services.AuthService) {
+	stopHandler := handlers.NewStopHandler(stopService)
+
+	// Stop routes group - all protected
+	stopsGroup := router.Group("/portfolio/stops")
+	stopsGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		stopsGroup.GET("", stopHandler.GetStops)
+		stopsGroup.PUT("/:symbol", middleware.ValidateJSON(models.PositionStopRequest{}), stopHandler.SetStop)
+		stopsGroup.DELETE("/:symbol", stopHandler.DeleteStop)
+	}
+}
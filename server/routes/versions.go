@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/middleware"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API versions. Breaking response-shape changes ship in the newest version;
+// older versions keep their shape until their sunset date.
+var (
+	APIv1 = middleware.APIVersion{
+		Name:         "v1",
+		DeprecatedAt: time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC),
+		SunsetAt:     time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC),
+		Successor:    "/api/v2",
+	}
+	APIv2 = middleware.APIVersion{
+		Name: "v2",
+	}
+)
+
+// SetupVersionedRoutes mounts the API registered by setup under /api/v1 and
+// /api/v2. The unversioned /api prefix predates versioning and stays an alias
+// of v1 until v1's sunset.
+func SetupVersionedRoutes(router *gin.Engine, setup func(api gin.IRouter)) {
+	setup(router.Group("/api", middleware.APIVersionMiddleware(APIv1)))
+	setup(router.Group("/api/v1", middleware.APIVersionMiddleware(APIv1)))
+	setup(router.Group("/api/v2", middleware.APIVersionMiddleware(APIv2)))
+}
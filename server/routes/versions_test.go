@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"stock-portfolio-tracker/middleware"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVersionedRoutesAnnounceDeprecation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetupVersionedRoutes(router, func(api gin.IRouter) {
+		api.GET("/ping", func(c *gin.Context) {
+			c.String(http.StatusOK, middleware.GetAPIVersion(c))
+		})
+	})
+
+	tests := []struct {
+		path       string
+		version    string
+		deprecated bool
+	}{
+		{"/api/ping", "v1", true},
+		{"/api/v1/ping", "v1", true},
+		{"/api/v2/ping", "v2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != http.StatusOK || w.Body.String() != tt.version {
+				t.Fatalf("Expected 200 %s, got %d %q", tt.version, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Deprecation") != ""; got != tt.deprecated {
+				t.Errorf("Expected Deprecation header present=%v, got %q", tt.deprecated, w.Header().Get("Deprecation"))
+			}
+			if tt.deprecated {
+				if w.Header().Get("Deprecation") != "@1792108800" {
+					t.Errorf("Unexpected Deprecation header %q", w.Header().Get("Deprecation"))
+				}
+				if w.Header().Get("Sunset") != "Fri, 30 Apr 2027 00:00:00 GMT" {
+					t.Errorf("Unexpected Sunset header %q", w.Header().Get("Sunset"))
+				}
+				if w.Header().Get("Link") != `</api/v2>; rel="successor-version"` {
+					t.Errorf("Unexpected Link header %q", w.Header().Get("Link"))
+				}
+			} else if w.Header().Get("Sunset") != "" {
+				t.Errorf("Expected no Sunset header, got %q", w.Header().Get("Sunset"))
+			}
+		})
+	}
+}
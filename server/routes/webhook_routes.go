@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"stock-portfolio-tracker/handlers"
+	"stock-portfolio-tracker/middleware"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupWebhookRoutes configures summary and alert webhook routes
+func SetupWebhookRoutes(router gin.IRouter, webhookService *services.WebhookService, authService *services.AuthService) {
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+
+	// Webhooks routes group - all protected
+	webhooksGroup := router.Group("/webhooks")
+	webhooksGroup.Use(middleware.AuthMiddleware(authService))
+	{
+		webhooksGroup.GET("", webhookHandler.GetWebhooks)
+		webhooksGroup.POST("", middleware.ValidateJSON(models.WebhookRequest{}), webhookHandler.CreateWebhook)
+		webhooksGroup.DELETE("/:id", webhookHandler.DeleteWebhook)
+		webhooksGroup.POST("/:id/test", webhookHandler.TestWebhook)
+	}
+}
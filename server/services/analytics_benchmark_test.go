@@ -77,8 +77,8 @@ func BenchmarkGetGroupedDashboardMetrics(b *testing.B) {
 	portfolioCollection.InsertMany(ctx, portfolios)
 
 	// Create services
-	stockService := NewStockAPIService()
-	currencyService := NewCurrencyService()
+	stockService := NewStockAPIService(StockAPIConfig{})
+	currencyService := NewCurrencyService(CurrencyConfig{})
 	portfolioService := NewPortfolioService(stockService, currencyService)
 	analyticsService := NewAnalyticsService(portfolioService, currencyService, stockService)
 
@@ -93,7 +93,7 @@ func BenchmarkGetGroupedDashboardMetrics(b *testing.B) {
 
 	// Run benchmark
 	for i := 0; i < b.N; i++ {
-		_, err := analyticsService.GetGroupedDashboardMetrics(userID, "USD", "assetStyle")
+		_, err := analyticsService.calculateGroupedDashboardMetrics(context.Background(), userID, "USD", "assetStyle")
 		if err != nil {
 			b.Fatal("GetGroupedDashboardMetrics failed:", err)
 		}
@@ -158,8 +158,8 @@ func BenchmarkGetGroupedDashboardMetricsByAssetClass(b *testing.B) {
 	portfolioCollection.InsertMany(ctx, portfolios)
 
 	// Create services
-	stockService := NewStockAPIService()
-	currencyService := NewCurrencyService()
+	stockService := NewStockAPIService(StockAPIConfig{})
+	currencyService := NewCurrencyService(CurrencyConfig{})
 	portfolioService := NewPortfolioService(stockService, currencyService)
 	analyticsService := NewAnalyticsService(portfolioService, currencyService, stockService)
 
@@ -174,7 +174,7 @@ func BenchmarkGetGroupedDashboardMetricsByAssetClass(b *testing.B) {
 
 	// Run benchmark
 	for i := 0; i < b.N; i++ {
-		_, err := analyticsService.GetGroupedDashboardMetrics(userID, "USD", "assetClass")
+		_, err := analyticsService.calculateGroupedDashboardMetrics(context.Background(), userID, "USD", "assetClass")
 		if err != nil {
 			b.Fatal("GetGroupedDashboardMetrics failed:", err)
 		}
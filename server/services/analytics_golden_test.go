@@ -0,0 +1,247 @@
+package services
+
+import (
+	"encoding/json"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// updateGolden rewrites the golden files from the current output:
+//
+//	go test ./services -run TestAnalyticsGolden -update
+//
+// Review the diff before committing; a changed number is a changed result.
+var updateGolden = flag.Bool("update", false, "rewrite analytics golden files")
+
+// analyticsFixture is a synthetic portfolio: daily closes ending today and
+// transactions dated in days relative to today
+type analyticsFixture struct {
+	Description string `json:"description"`
+	Currency    string `json:"currency"`
+	Rates       []struct {
+		From string  `json:"from"`
+		To   string  `json:"to"`
+		Rate float64 `json:"rate"`
+	} `json:"rates"`
+	Symbols []struct {
+		Symbol   string    `json:"symbol"`
+		Currency string    `json:"currency"`
+		Closes   []float64 `json:"closes"`
+	} `json:"symbols"`
+	Transactions []struct {
+		Symbol   string  `json:"symbol"`
+		Action   string  `json:"action"`
+		Shares   float64 `json:"shares"`
+		Price    float64 `json:"price"`
+		Currency string  `json:"currency"`
+		Day      int     `json:"day"`
+	} `json:"transactions"`
+}
+
+// goldenAnalytics is the analytics output for a fixture. Dates are days
+// relative to today and numbers are rounded to six decimals, so the file
+// does not change with the day the test runs or with floating point noise.
+type goldenAnalytics struct {
+	Performance []goldenPoint  `json:"performance"`
+	TotalReturn ReturnMetric   `json:"totalReturn"`
+	BestDay     goldenDay      `json:"bestDay"`
+	WorstDay    goldenDay      `json:"worstDay"`
+	MaxDrawdown goldenDrawdown `json:"maxDrawdown"`
+	Recovery    RecoveryMetric `json:"recovery"`
+	// From the backtest metrics over the same value series
+	AnnualizedReturn float64 `json:"annualizedReturn"`
+	Volatility       float64 `json:"volatility"`
+	SharpeRatio      float64 `json:"sharpeRatio"`
+}
+
+type goldenPoint struct {
+	Day              int     `json:"day"`
+	Value            float64 `json:"value"`
+	PercentageReturn float64 `json:"percentageReturn"`
+	DayChange        float64 `json:"dayChange"`
+	DayChangePercent float64 `json:"dayChangePercent"`
+}
+
+type goldenDay struct {
+	Day           int     `json:"day"`
+	Change        float64 `json:"change"`
+	ChangePercent float64 `json:"changePercent"`
+}
+
+type goldenDrawdown struct {
+	Percentage  float64 `json:"percentage"`
+	Absolute    float64 `json:"absolute"`
+	PeakDay     int     `json:"peakDay"`
+	TroughDay   int     `json:"troughDay"`
+	PeakValue   float64 `json:"peakValue"`
+	TroughValue float64 `json:"troughValue"`
+}
+
+// TestAnalyticsGolden runs each fixture in testdata/analytics_golden through
+// the performance, drawdown, recovery, volatility and Sharpe calculations and
+// compares the results with the fixture's .golden file
+func TestAnalyticsGolden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "analytics_golden", "*.json"))
+	if err != nil {
+		t.Fatalf("Failed to list fixtures: %v", err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatalf("Expected analytics fixtures in testdata/analytics_golden")
+	}
+
+	for _, path := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+			var fixture analyticsFixture
+			if err := json.Unmarshal(data, &fixture); err != nil {
+				t.Fatalf("Failed to parse fixture: %v", err)
+			}
+
+			got := runAnalyticsFixture(t, fixture)
+			goldenPath := strings.TrimSuffix(path, ".json") + ".golden"
+			if *updateGolden {
+				out, err := json.MarshalIndent(got, "", "  ")
+				if err != nil {
+					t.Fatalf("Failed to encode golden output: %v", err)
+				}
+				if err := os.WriteFile(goldenPath, append(out, '\n'), 0o644); err != nil {
+					t.Fatalf("Failed to write golden file: %v", err)
+				}
+				return
+			}
+
+			data, err = os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+			}
+			var want goldenAnalytics
+			if err := json.Unmarshal(data, &want); err != nil {
+				t.Fatalf("Failed to parse golden file: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.MarshalIndent(got, "", "  ")
+				t.Errorf("Analytics differ from %s:\n%s", goldenPath, gotJSON)
+			}
+		})
+	}
+}
+
+// runAnalyticsFixture loads a fixture into in-memory repositories and fixture
+// prices and computes its golden output
+func runAnalyticsFixture(t *testing.T, fixture analyticsFixture) goldenAnalytics {
+	t.Helper()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	provider := NewFixtureProvider()
+	for _, rate := range fixture.Rates {
+		provider.SetRate(rate.From, rate.To, rate.Rate)
+	}
+	for _, symbol := range fixture.Symbols {
+		last := symbol.Closes[len(symbol.Closes)-1]
+		provider.SetQuote(symbol.Symbol, symbol.Symbol, last, symbol.Currency).
+			SetHistory(symbol.Symbol, today, symbol.Closes...)
+	}
+	service, portfolioService := newFixtureAnalyticsService(provider)
+	userID := primitive.NewObjectID()
+	for _, tx := range fixture.Transactions {
+		transaction := &models.Transaction{
+			Symbol:   tx.Symbol,
+			Action:   tx.Action,
+			Shares:   tx.Shares,
+			Price:    tx.Price,
+			Currency: tx.Currency,
+			Date:     today.AddDate(0, 0, tx.Day),
+		}
+		if err := portfolioService.AddTransaction(userID, transaction); err != nil {
+			t.Fatalf("Failed to add %s %s transaction: %v", tx.Action, tx.Symbol, err)
+		}
+	}
+
+	response, err := service.GetHistoricalPerformanceWithMetrics(userID, "1M", fixture.Currency)
+	if err != nil {
+		t.Fatalf("Failed to get performance: %v", err)
+	}
+	if len(response.Performance) < 2 {
+		t.Fatalf("Expected a performance series, got %d points", len(response.Performance))
+	}
+
+	day := func(date time.Time) int {
+		return int(math.Round(date.Sub(today).Hours() / 24))
+	}
+	metrics := response.Metrics
+	got := goldenAnalytics{
+		TotalReturn: ReturnMetric{
+			Absolute:   roundGolden(metrics.TotalReturn.Absolute),
+			Percentage: roundGolden(metrics.TotalReturn.Percentage),
+		},
+		BestDay: goldenDay{
+			Day:           day(metrics.BestDay.Date),
+			Change:        roundGolden(metrics.BestDay.Change),
+			ChangePercent: roundGolden(metrics.BestDay.ChangePercent),
+		},
+		WorstDay: goldenDay{
+			Day:           day(metrics.WorstDay.Date),
+			Change:        roundGolden(metrics.WorstDay.Change),
+			ChangePercent: roundGolden(metrics.WorstDay.ChangePercent),
+		},
+		MaxDrawdown: goldenDrawdown{
+			Percentage:  roundGolden(metrics.MaxDrawdown.Percentage),
+			Absolute:    roundGolden(metrics.MaxDrawdown.Absolute),
+			PeakDay:     day(metrics.MaxDrawdown.PeakDate),
+			TroughDay:   day(metrics.MaxDrawdown.TroughDate),
+			PeakValue:   roundGolden(metrics.MaxDrawdown.PeakValue),
+			TroughValue: roundGolden(metrics.MaxDrawdown.TroughValue),
+		},
+		Recovery: RecoveryMetric{
+			Status:      metrics.RecoveryTime.Status,
+			Days:        metrics.RecoveryTime.Days,
+			AverageDays: roundGolden(metrics.RecoveryTime.AverageDays),
+		},
+	}
+
+	backtestPoints := make([]BacktestDataPoint, len(response.Performance))
+	for i, point := range response.Performance {
+		got.Performance = append(got.Performance, goldenPoint{
+			Day:              day(point.Date),
+			Value:            roundGolden(point.Value),
+			PercentageReturn: roundGolden(point.PercentageReturn),
+			DayChange:        roundGolden(point.DayChange),
+			DayChangePercent: roundGolden(point.DayChangePercent),
+		})
+		backtestPoints[i] = BacktestDataPoint{Date: point.Date, PortfolioValue: point.Value}
+	}
+
+	first, last := response.Performance[0], response.Performance[len(response.Performance)-1]
+	backtest, err := (&BacktestService{}).calculateBacktestMetrics(backtestPoints, first.Value, first.Date, last.Date)
+	if err != nil {
+		t.Fatalf("Failed to calculate backtest metrics: %v", err)
+	}
+	got.AnnualizedReturn = roundGolden(backtest.AnnualizedReturn)
+	got.Volatility = roundGolden(backtest.Volatility)
+	got.SharpeRatio = roundGolden(backtest.SharpeRatio)
+
+	return got
+}
+
+// roundGolden rounds to six decimals, well above floating point noise and
+// well below any difference a change to the math would make
+func roundGolden(value float64) float64 {
+	rounded := math.Round(value*1e6) / 1e6
+	if rounded == 0 {
+		return 0 // Normalize -0
+	}
+	return rounded
+}
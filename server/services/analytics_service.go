@@ -2,13 +2,19 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"slices"
 	"sort"
-	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/internal/analytics/engine"
 	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/money"
+	"strings"
+	"sync"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -21,6 +27,12 @@ type DashboardMetrics struct {
 	DayChangePercent  float64          `json:"dayChangePercent"`
 	Allocation        []AllocationItem `json:"allocation"`
 	Currency          string           `json:"currency"`
+	ExchangeRates     *RateFreshness   `json:"exchangeRates,omitempty"`
+	Benchmark         *BenchmarkDayChange `json:"benchmark,omitempty"`
+
+	// Move in value since the regular session from the holdings' latest
+	// pre-market or after-hours trades, when any are reported
+	ExtendedHoursChange float64 `json:"extendedHoursChange,omitempty"`
 }
 
 // AllocationItem represents a single allocation entry
@@ -38,6 +50,7 @@ type PerformanceDataPoint struct {
 	PercentageReturn float64   `json:"percentageReturn"` // Percentage from start
 	DayChange        float64   `json:"dayChange"`        // Day-over-day change
 	DayChangePercent float64   `json:"dayChangePercent"` // Day-over-day %
+	BenchmarkReturn  *float64  `json:"benchmarkReturn,omitempty"` // Benchmark's percentage from start
 }
 
 // PerformanceMetrics represents comprehensive performance metrics
@@ -82,10 +95,15 @@ type RecoveryMetric struct {
 
 // PerformanceResponse represents the complete performance response with data and metrics
 type PerformanceResponse struct {
-	Period      string                   `json:"period"`
-	Currency    string                   `json:"currency"`
-	Performance []PerformanceDataPoint   `json:"performance"`
-	Metrics     *PerformanceMetrics      `json:"metrics"`
+	Period        string                 `json:"period"`
+	Currency      string                 `json:"currency"`
+	Performance   []PerformanceDataPoint `json:"performance"`
+	Metrics       *PerformanceMetrics    `json:"metrics"`
+	ExchangeRates *RateFreshness         `json:"exchangeRates,omitempty"`
+	Benchmark     *BenchmarkInfo         `json:"benchmark,omitempty"`
+	// Optional series, included on request
+	RollingReturns []RollingReturnSeries `json:"rollingReturns,omitempty"`
+	Drawdowns      []DrawdownPoint       `json:"drawdowns,omitempty"`
 }
 
 // GroupedHolding represents holdings grouped by a dimension
@@ -93,7 +111,25 @@ type GroupedHolding struct {
 	GroupName   string    `json:"groupName"`
 	GroupValue  float64   `json:"groupValue"`
 	Percentage  float64   `json:"percentage"`
+	Weight           float64 `json:"weight"` // Share of the total value, from 0 to 1
+	CostBasis        float64 `json:"costBasis"`
+	GainLoss         float64 `json:"gainLoss"`
+	GainLossPercent  float64 `json:"gainLossPercent"`
+	DayChange        float64 `json:"dayChange"`
+	DayChangePercent float64 `json:"dayChangePercent"`
+	Color       string    `json:"color,omitempty"` // Asset style color, when grouped by asset style
+	Icon        string    `json:"icon,omitempty"`  // Asset style icon, when grouped by asset style
+	Parent      string    `json:"parent,omitempty"` // Asset class, when grouped by asset subclass
 	Holdings    []Holding `json:"holdings"`
+	Groups      []GroupedHolding `json:"groups,omitempty"` // The holdings grouped by the next groupBy dimension
+}
+
+// GroupSubtotal rolls the groups of one parent up, such as the subclasses of
+// an asset class
+type GroupSubtotal struct {
+	GroupName  string  `json:"groupName"`
+	GroupValue float64 `json:"groupValue"`
+	Percentage float64 `json:"percentage"`
 }
 
 // GroupedDashboardMetrics represents dashboard metrics grouped by specified dimension
@@ -104,43 +140,134 @@ type GroupedDashboardMetrics struct {
 	DayChange         float64          `json:"dayChange"`
 	DayChangePercent  float64          `json:"dayChangePercent"`
 	Groups            []GroupedHolding `json:"groups"`
+	Subtotals         []GroupSubtotal  `json:"subtotals,omitempty"` // Per asset class, when grouped by asset subclass
 	Currency          string           `json:"currency"`
 	GroupBy           string           `json:"groupBy"`
+	ExchangeRates     *RateFreshness   `json:"exchangeRates,omitempty"`
+	Benchmark         *BenchmarkDayChange `json:"benchmark,omitempty"`
+}
+
+// dashboardCacheDuration bounds how long a computed dashboard is reused while
+// the user's data is unchanged, so quotes still refresh regularly
+const dashboardCacheDuration = 30 * time.Second
+
+// cachedDashboard represents a computed dashboard response with expiration
+type cachedDashboard struct {
+	Data        interface{}
+	DataVersion uint64
+	ExpiresAt   time.Time
 }
 
 // AnalyticsService handles analytics and performance calculations
 type AnalyticsService struct {
 	portfolioService *PortfolioService
-	currencyService  *CurrencyService
-	stockService     *StockAPIService
+	currencyService  CurrencyProvider
+	stockService     StockDataProvider
+	dashboardCache   map[string]*cachedDashboard
+	cacheMutex       sync.RWMutex
 }
 
 // NewAnalyticsService creates a new AnalyticsService instance
-func NewAnalyticsService(portfolioService *PortfolioService, currencyService *CurrencyService, stockService *StockAPIService) *AnalyticsService {
+func NewAnalyticsService(portfolioService *PortfolioService, currencyService CurrencyProvider, stockService StockDataProvider) *AnalyticsService {
 	return &AnalyticsService{
 		portfolioService: portfolioService,
 		currencyService:  currencyService,
 		stockService:     stockService,
+		dashboardCache:   make(map[string]*cachedDashboard),
+	}
+}
+
+// dashboardCacheKey builds the cache key for a user's dashboard view
+func dashboardCacheKey(userID primitive.ObjectID, currency string, groupBy string) string {
+	return userID.Hex() + "|" + currencycode.Normalize(currency) + "|" + groupBy
+}
+
+// getCachedDashboard returns a cached dashboard if it has not expired and the
+// user's data has not changed since it was computed
+func (s *AnalyticsService) getCachedDashboard(key string, userID primitive.ObjectID) (interface{}, bool) {
+	s.cacheMutex.RLock()
+	defer s.cacheMutex.RUnlock()
+
+	cached, exists := s.dashboardCache[key]
+	if !exists || time.Now().After(cached.ExpiresAt) || cached.DataVersion != dataVersions.current(userID) {
+		return nil, false
+	}
+	return cached.Data, true
+}
+
+// setCachedDashboard stores a computed dashboard tagged with the data version
+// that was current before computation started
+func (s *AnalyticsService) setCachedDashboard(key string, version uint64, data interface{}) {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+
+	s.dashboardCache[key] = &cachedDashboard{
+		Data:        data,
+		DataVersion: version,
+		ExpiresAt:   time.Now().Add(dashboardCacheDuration),
 	}
 }
 
-// GetDashboardMetrics calculates and returns dashboard metrics for a user
+// cleanupExpiredCache removes expired dashboard entries
+func (s *AnalyticsService) cleanupExpiredCache() {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+
+	now := time.Now()
+	for key, cached := range s.dashboardCache {
+		if now.After(cached.ExpiresAt) {
+			delete(s.dashboardCache, key)
+		}
+	}
+}
+
+// StartCacheCleanup starts a background goroutine to periodically clean expired cache entries
+func (s *AnalyticsService) StartCacheCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			s.cleanupExpiredCache()
+		}
+	}()
+}
+
+// GetDashboardMetrics returns dashboard metrics for a user, reusing a recent
+// result when the user's transactions and portfolios are unchanged.
+// The returned value is shared with the cache and must not be modified.
 func (s *AnalyticsService) GetDashboardMetrics(userID primitive.ObjectID, currency string) (*DashboardMetrics, error) {
-	fmt.Printf("[Analytics] GetDashboardMetrics called - UserID: %s, Currency: %s\n", userID.Hex(), currency)
-	
-	// Validate currency
-	if currency != "USD" && currency != "RMB" && currency != "CNY" {
-		return nil, fmt.Errorf("invalid currency: must be USD or RMB")
+	return s.GetDashboardMetricsContext(context.Background(), userID, currency)
+}
+
+// GetDashboardMetricsContext is GetDashboardMetrics with the holdings queries
+// and price lookups made under ctx, so they are traced with the request
+func (s *AnalyticsService) GetDashboardMetricsContext(ctx context.Context, userID primitive.ObjectID, currency string) (*DashboardMetrics, error) {
+	key := dashboardCacheKey(userID, currency, "")
+	if cached, ok := s.getCachedDashboard(key, userID); ok {
+		return cached.(*DashboardMetrics), nil
+	}
+
+	version := dataVersions.current(userID)
+	metrics, err := s.calculateDashboardMetrics(ctx, userID, currency)
+	if err != nil {
+		return nil, err
 	}
+	s.setCachedDashboard(key, version, metrics)
+	return metrics, nil
+}
+
+// calculateDashboardMetrics calculates dashboard metrics for a user
+func (s *AnalyticsService) calculateDashboardMetrics(ctx context.Context, userID primitive.ObjectID, currency string) (*DashboardMetrics, error) {
+	fmt.Printf("[Analytics] GetDashboardMetrics called - UserID: %s, Currency: %s\n", userID.Hex(), currency)
 	
-	// Normalize CNY to RMB
-	if currency == "CNY" {
-		currency = "RMB"
+	// Validate currency, normalizing CNY to RMB
+	currency, err := NormalizeDisplayCurrency(currency)
+	if err != nil {
+		return nil, err
 	}
 	
 	// Fetch user holdings in the requested currency
 	fmt.Printf("[Analytics] Fetching holdings for user %s in currency %s\n", userID.Hex(), currency)
-	holdings, err := s.portfolioService.GetUserHoldings(userID, currency)
+	holdings, err := s.portfolioService.GetUserHoldingsContext(ctx, userID, currency)
 	if err != nil {
 		fmt.Printf("[Analytics] ERROR: Failed to fetch holdings for user %s: %v\n", userID.Hex(), err)
 		return nil, fmt.Errorf("failed to fetch holdings: %w", err)
@@ -162,45 +289,48 @@ func (s *AnalyticsService) GetDashboardMetrics(userID primitive.ObjectID, curren
 	
 	// Calculate total portfolio value, cost basis, and day change
 	// Holdings are already in the requested currency from GetUserHoldings
-	var totalValue float64
-	var totalCostBasis float64
-	var dayChange float64
+	totalValue := money.Zero(currency)
+	totalCostBasis := money.Zero(currency)
 	allocation := make([]AllocationItem, 0, len(holdings))
 	
 	// Get previous day's closing prices for all symbols
-	previousDayValue := 0.0
+	previousDayValue := money.Zero(currency)
+	extendedHoursChange := money.Zero(currency)
 	for _, holding := range holdings {
 		fmt.Printf("[Analytics] Processing holding: %s (%.2f shares, value: %.2f %s)\n", 
 			holding.Symbol, holding.Shares, holding.CurrentValue, holding.Currency)
 		
-		totalValue += holding.CurrentValue
-		totalCostBasis += holding.CostBasis
+		totalValue = totalValue.Add(money.New(holding.CurrentValue, currency))
+		totalCostBasis = totalCostBasis.Add(money.New(holding.CostBasis, currency))
+		if holding.ExtendedHours != nil {
+			extendedHoursChange = extendedHoursChange.Add(money.New(holding.ExtendedHours.ValueChange, currency))
+		}
 		
-		// Calculate previous day value for this holding
-		prevDayPrice, err := s.getPreviousDayPrice(holding.Symbol)
-		if err != nil {
+		// Calculate previous day value for this holding. A manual price has
+		// no previous close, so the holding is held flat.
+		prevDayPrice, err := s.getPreviousDayPrice(ctx, holding.Symbol)
+		if holding.PriceOverride != nil {
+			previousDayValue = previousDayValue.Add(money.New(holding.CurrentValue, currency))
+		} else if err != nil {
 			fmt.Printf("[Analytics] Warning: Could not get previous day price for %s: %v\n", holding.Symbol, err)
 			// If we can't get previous day price, assume no change for this holding
-			previousDayValue += holding.CurrentValue
+			previousDayValue = previousDayValue.Add(money.New(holding.CurrentValue, currency))
 		} else {
 			prevValue := holding.Shares * prevDayPrice
 			
 			// Convert to target currency if needed
-			symbolCurrency := "USD"
-			if s.stockService.IsChinaStock(holding.Symbol) {
-				symbolCurrency = "CNY"
-			}
+			symbolCurrency := s.stockService.SymbolCurrency(holding.Symbol)
 			
 			if symbolCurrency != currency {
 				convertedPrevValue, err := s.currencyService.ConvertAmount(prevValue, symbolCurrency, currency)
 				if err != nil {
 					fmt.Printf("[Analytics] Warning: Could not convert currency for %s: %v\n", holding.Symbol, err)
-					previousDayValue += holding.CurrentValue
+					previousDayValue = previousDayValue.Add(money.New(holding.CurrentValue, currency))
 				} else {
-					previousDayValue += convertedPrevValue
+					previousDayValue = previousDayValue.Add(money.New(convertedPrevValue, currency))
 				}
 			} else {
-				previousDayValue += prevValue
+				previousDayValue = previousDayValue.Add(money.New(prevValue, currency))
 			}
 		}
 		
@@ -214,48 +344,57 @@ func (s *AnalyticsService) GetDashboardMetrics(userID primitive.ObjectID, curren
 	}
 	
 	// Calculate day change
-	dayChange = totalValue - previousDayValue
+	dayChange := totalValue.Sub(previousDayValue)
 	
 	// Calculate percentages for allocation
 	for i := range allocation {
-		if totalValue > 0 {
-			allocation[i].Percentage = (allocation[i].Value / totalValue) * 100
+		if totalValue.Minor > 0 {
+			allocation[i].Percentage = (allocation[i].Value / totalValue.Float64()) * 100
 		}
 	}
 	
 	// Calculate total gain/loss
-	totalGain := totalValue - totalCostBasis
+	totalGain := totalValue.Sub(totalCostBasis)
 	
 	// Calculate percentage return
 	percentageReturn := 0.0
-	if totalCostBasis > 0 {
-		percentageReturn = (totalGain / totalCostBasis) * 100
+	if totalCostBasis.Minor > 0 {
+		percentageReturn = (totalGain.Float64() / totalCostBasis.Float64()) * 100
 	}
 	
 	// Calculate day change percentage
 	dayChangePercent := 0.0
-	if previousDayValue > 0 {
-		dayChangePercent = (dayChange / previousDayValue) * 100
+	if previousDayValue.Minor > 0 {
+		dayChangePercent = (dayChange.Float64() / previousDayValue.Float64()) * 100
 	}
 	
 	fmt.Printf("[Analytics] Dashboard metrics calculated - TotalValue: %.2f, TotalGain: %.2f, Return: %.2f%%, DayChange: %.2f (%.2f%%)\n", 
-		totalValue, totalGain, percentageReturn, dayChange, dayChangePercent)
+		totalValue.Float64(), totalGain.Float64(), percentageReturn, dayChange.Float64(), dayChangePercent)
 	
 	return &DashboardMetrics{
-		TotalValue:        totalValue,
-		TotalGain:         totalGain,
+		TotalValue:        totalValue.Float64(),
+		TotalGain:         totalGain.Float64(),
 		PercentageReturn:  percentageReturn,
-		DayChange:         dayChange,
+		DayChange:         dayChange.Float64(),
 		DayChangePercent:  dayChangePercent,
 		Allocation:        allocation,
 		Currency:          currency,
+		ExchangeRates:     s.portfolioService.HoldingsRateFreshness(holdings, currency),
+		ExtendedHoursChange: extendedHoursChange.Float64(),
 	}, nil
 }
 
 // GetHistoricalPerformanceWithMetrics calculates historical portfolio performance with metrics
 func (s *AnalyticsService) GetHistoricalPerformanceWithMetrics(userID primitive.ObjectID, period string, currency string) (*PerformanceResponse, error) {
+	return s.GetHistoricalPerformanceWithProgress(context.Background(), userID, period, currency, nil)
+}
+
+// GetHistoricalPerformanceWithProgress is GetHistoricalPerformanceWithMetrics
+// reporting its progress, symbols fetched and then dates valued, to progress.
+// Prices are fetched under ctx.
+func (s *AnalyticsService) GetHistoricalPerformanceWithProgress(ctx context.Context, userID primitive.ObjectID, period string, currency string, progress PerformanceProgressFunc) (*PerformanceResponse, error) {
 	// Get performance data points
-	dataPoints, err := s.GetHistoricalPerformance(userID, period, currency)
+	dataPoints, symbols, err := s.historicalPerformance(ctx, userID, period, currency, progress)
 	if err != nil {
 		return nil, err
 	}
@@ -275,283 +414,304 @@ func (s *AnalyticsService) GetHistoricalPerformanceWithMetrics(userID primitive.
 	}
 	
 	return &PerformanceResponse{
-		Period:      period,
-		Currency:    currency,
-		Performance: dataPoints,
-		Metrics:     metrics,
+		Period:        period,
+		Currency:      currency,
+		Performance:   dataPoints,
+		Metrics:       metrics,
+		ExchangeRates: s.portfolioService.symbolsRateFreshness(symbols, currency),
 	}, nil
 }
 
 // GetHistoricalPerformance calculates historical portfolio performance
 func (s *AnalyticsService) GetHistoricalPerformance(userID primitive.ObjectID, period string, currency string) ([]PerformanceDataPoint, error) {
+	dataPoints, _, err := s.historicalPerformance(context.Background(), userID, period, currency, nil)
+	return dataPoints, err
+}
+
+// historicalPerformance calculates historical portfolio performance and
+// returns the symbols it was priced from. progress may be nil.
+func (s *AnalyticsService) historicalPerformance(ctx context.Context, userID primitive.ObjectID, period string, currency string, progress PerformanceProgressFunc) ([]PerformanceDataPoint, []string, error) {
 	// Validate period
 	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
 	if !validPeriods[period] {
-		return nil, fmt.Errorf("invalid period: must be 1M, 3M, 6M, 1Y, or ALL")
+		return nil, nil, fmt.Errorf("invalid period: must be 1M, 3M, 6M, 1Y, or ALL")
 	}
 	
-	// Validate currency
-	if currency != "USD" && currency != "RMB" && currency != "CNY" {
-		return nil, fmt.Errorf("invalid currency: must be USD or RMB")
+	// Validate currency, normalizing CNY to RMB
+	currency, err := NormalizeDisplayCurrency(currency)
+	if err != nil {
+		return nil, nil, err
 	}
 	
-	// Normalize CNY to RMB
-	if currency == "CNY" {
-		currency = "RMB"
+	// Dividend-adjusted closes, so dividend payers' returns are total returns
+	dates, cursors, err := s.loadSeriesCursors(ctx, userID, period, currency, true, progress)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(cursors) == 0 {
+		return []PerformanceDataPoint{}, nil, nil
 	}
 	
-	// Calculate time range based on period
-	endTime := time.Now()
-	var startTime time.Time
-	
-	switch period {
-	case "1M":
-		startTime = endTime.AddDate(0, -1, 0)
-	case "3M":
-		startTime = endTime.AddDate(0, -3, 0)
-	case "6M":
-		startTime = endTime.AddDate(0, -6, 0)
-	case "1Y":
-		startTime = endTime.AddDate(-1, 0, 0)
-	case "ALL":
-		// For ALL, use a very old date (10 years ago)
-		startTime = endTime.AddDate(-10, 0, 0)
+	symbols := make([]string, 0, len(cursors))
+	for _, c := range cursors {
+		symbols = append(symbols, c.symbol)
 	}
 	
-	// Fetch all user transactions
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// Calculate portfolio value for each date
+	performanceData := make([]PerformanceDataPoint, 0, len(dates))
 	
-	collection := database.Database.Collection("transactions")
-	cursor, err := collection.Find(ctx, bson.M{"user_id": userID})
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
+	for i, date := range dates {
+		portfolioValue := money.Zero(currency)
+		for _, c := range cursors {
+			portfolioValue = portfolioValue.Add(money.New(c.valueAt(date), currency))
+		}
+		if (i+1)%performanceProgressInterval == 0 || i == len(dates)-1 {
+			progress.report(PerformanceStageDates, i+1, len(dates))
+		}
+		
+		performanceData = append(performanceData, PerformanceDataPoint{
+			Date:             date,
+			Value:            portfolioValue.Float64(),
+			PercentageReturn: 0, // Will calculate after all points are collected
+			DayChange:        0, // Will calculate after all points are collected
+			DayChangePercent: 0, // Will calculate after all points are collected
+		})
 	}
-	defer cursor.Close(ctx)
 	
-	var allTransactions []models.Transaction
-	if err := cursor.All(ctx, &allTransactions); err != nil {
-		return nil, fmt.Errorf("failed to decode transactions: %w", err)
-	}
+	// Calculate percentage return and day-over-day changes
+	applyPerformanceReturns(performanceData)
 	
-	// If no transactions, return empty data
-	if len(allTransactions) == 0 {
-		return []PerformanceDataPoint{}, nil
-	}
+	return performanceData, symbols, nil
+}
+
+// loadSeriesCursors loads the user's position timelines and price histories for
+// a period and returns the dates of the series with one cursor per symbol.
+// Adjusted prices the series with dividend-adjusted closes, for total returns.
+// The currency must already be validated and normalized. progress, which may
+// be nil, is told of each symbol fetched. The histories are fetched under
+// ctx, and the first fetch failing because ctx is done fails the series.
+func (s *AnalyticsService) loadSeriesCursors(ctx context.Context, userID primitive.ObjectID, period string, currency string, adjusted bool, progress PerformanceProgressFunc) ([]time.Time, []*symbolSeriesCursor, error) {
+	// Calculate time range based on period
+	endTime := time.Now()
+	startTime := PeriodStart(period, endTime)
+	
+	// Stream the user's transactions in date order, keeping only the fields the
+	// position timeline needs
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 	
-	// Sort transactions by date
-	sort.Slice(allTransactions, func(i, j int) bool {
-		return allTransactions[i].Date.Before(allTransactions[j].Date)
+	// Precompute cumulative share positions per symbol in a single pass
+	positions := make(map[string][]positionChange)
+	err := s.portfolioService.repos.Transactions.Stream(queryCtx, userID, func(tx models.Transaction) error {
+		positions[tx.Symbol] = appendPositionChange(positions[tx.Symbol], tx)
+		return nil
 	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read transactions: %w", err)
+	}
 	
-	// Get unique symbols from all transactions
-	symbolSet := make(map[string]bool)
-	for _, tx := range allTransactions {
-		symbolSet[tx.Symbol] = true
+	// If no transactions, return empty data
+	if len(positions) == 0 {
+		return nil, nil, nil
 	}
 	
-	symbols := make([]string, 0, len(symbolSet))
-	for symbol := range symbolSet {
-		symbols = append(symbols, symbol)
+	// Manual prices replace the providers' closes from their effective dates
+	overrides, err := s.portfolioService.priceOverrides(queryCtx, userID)
+	if err != nil {
+		return nil, nil, err
 	}
 	
 	// Fetch historical prices for all symbols
 	historicalPrices := make(map[string][]HistoricalPrice)
-	for _, symbol := range symbols {
-		prices, err := s.stockService.GetHistoricalData(symbol, period)
+	fetched := 0
+	for symbol := range positions {
+		prices, err := s.stockService.GetHistoricalDataContext(ctx, symbol, period)
+		fetched++
+		progress.report(PerformanceStageSymbols, fetched, len(positions))
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, nil, ctxErr
+			}
 			// Log error but continue with other symbols
 			fmt.Printf("Warning: failed to fetch historical data for %s: %v\n", symbol, err)
 			continue
 		}
+		if adjusted {
+			prices = AdjustedHistory(prices)
+		}
+		if override, ok := overrides[symbol]; ok {
+			prices = overrideHistory(prices, override)
+		}
 		historicalPrices[symbol] = prices
 	}
 	
 	// If no historical data available, return empty
 	if len(historicalPrices) == 0 {
-		return []PerformanceDataPoint{}, nil
-	}
-	
-	// Build a map of dates to calculate portfolio value for each day
-	dateMap := make(map[string]time.Time)
-	for _, prices := range historicalPrices {
-		for _, price := range prices {
-			dateKey := price.Date.Format("2006-01-02")
-			if _, exists := dateMap[dateKey]; !exists {
-				dateMap[dateKey] = price.Date
-			}
-		}
+		return nil, nil, nil
 	}
 	
-	// Convert to sorted slice of dates
-	dates := make([]time.Time, 0, len(dateMap))
-	for _, date := range dateMap {
-		// Only include dates within the period
-		if date.After(startTime) || date.Equal(startTime) {
-			dates = append(dates, date)
-		}
-	}
+	// Calculate portfolio value for each trading day within the period
+	dates := engine.TradingDates(historicalPrices, startTime)
 	
-	sort.Slice(dates, func(i, j int) bool {
-		return dates[i].Before(dates[j])
-	})
-	
-	// Calculate portfolio value for each date
-	performanceData := make([]PerformanceDataPoint, 0, len(dates))
-	
-	for _, date := range dates {
-		portfolioValue := 0.0
+	// Set up one forward-only cursor per symbol. Dates are walked in ascending
+	// order, so each cursor only ever advances and the whole series costs
+	// O(dates × symbols) instead of rescanning every transaction per date.
+	cursors := make([]*symbolSeriesCursor, 0, len(historicalPrices))
+	for symbol, prices := range historicalPrices {
+		// Get the currency for this symbol
+		symbolCurrency := s.stockService.SymbolCurrency(symbol)
 		
-		// For each symbol, calculate shares held on this date
-		for symbol, prices := range historicalPrices {
-			// Calculate shares held on this date
-			sharesHeld := 0.0
-			
-			for _, tx := range allTransactions {
-				// Only consider transactions up to this date
-				if tx.Symbol == symbol && (tx.Date.Before(date) || tx.Date.Equal(date)) {
-					if tx.Action == "buy" {
-						sharesHeld += tx.Shares
-					} else if tx.Action == "sell" {
-						sharesHeld -= tx.Shares
-					}
-				}
-			}
-			
-			// If no shares held, skip
-			if sharesHeld <= 0 {
-				continue
-			}
-			
-			// Find the price for this date (or closest previous date)
-			price := s.findPriceForDate(prices, date)
-			if price <= 0 {
-				continue
-			}
-			
-			// Get the currency for this symbol
-			symbolCurrency := "USD"
-			if s.stockService.IsChinaStock(symbol) {
-				symbolCurrency = "CNY"
-			}
-			
-			// Calculate value
-			value := sharesHeld * price
-			
-			// Convert to requested currency if needed
-			if symbolCurrency != currency {
-				convertedValue, err := s.currencyService.ConvertAmount(value, symbolCurrency, currency)
-				if err != nil {
-					// Log error but use unconverted value
-					fmt.Printf("Warning: failed to convert currency for %s on %s: %v\n", symbol, date.Format("2006-01-02"), err)
-				} else {
-					value = convertedValue
-				}
+		// Resolve the conversion rate once per symbol instead of once per date
+		rate := 1.0
+		if symbolCurrency != currency {
+			converted, err := s.currencyService.GetExchangeRate(symbolCurrency, currency)
+			if err != nil {
+				// Log error but use unconverted value
+				fmt.Printf("Warning: failed to get exchange rate for %s: %v\n", symbol, err)
+			} else {
+				rate = converted
 			}
-			
-			portfolioValue += value
 		}
 		
-		performanceData = append(performanceData, PerformanceDataPoint{
-			Date:             date,
-			Value:            portfolioValue,
-			PercentageReturn: 0, // Will calculate after all points are collected
-			DayChange:        0, // Will calculate after all points are collected
-			DayChangePercent: 0, // Will calculate after all points are collected
+		cursors = append(cursors, &symbolSeriesCursor{
+			symbol:    symbol,
+			prices:    engine.NewCursor(engine.Sorted(prices)),
+			positions: positions[symbol],
+			rate:      rate,
 		})
 	}
 	
-	// Calculate percentage return and day-over-day changes
-	if len(performanceData) > 0 {
-		// Find the first non-zero value as the initial value for percentage calculation
-		initialValue := 0.0
-		initialIndex := 0
-		for i, point := range performanceData {
-			if point.Value > 0 {
-				initialValue = point.Value
-				initialIndex = i
-				break
-			}
+	return dates, cursors, nil
+}
+
+// applyPerformanceReturns fills in the cumulative return and day-over-day
+// changes of a value series
+func applyPerformanceReturns(performanceData []PerformanceDataPoint) {
+	if len(performanceData) == 0 {
+		return
+	}
+	
+	// Find the first non-zero value as the initial value for percentage calculation
+	initialValue := 0.0
+	initialIndex := 0
+	for i, point := range performanceData {
+		if point.Value > 0 {
+			initialValue = point.Value
+			initialIndex = i
+			break
+		}
+	}
+	
+	for i := range performanceData {
+		// Calculate percentage return from initial value
+		if initialValue > 0 && i >= initialIndex {
+			performanceData[i].PercentageReturn = ((performanceData[i].Value - initialValue) / initialValue) * 100
 		}
 		
-		for i := range performanceData {
-			// Calculate percentage return from initial value
-			if initialValue > 0 && i >= initialIndex {
-				performanceData[i].PercentageReturn = ((performanceData[i].Value - initialValue) / initialValue) * 100
-			}
+		// Calculate day-over-day change
+		if i > 0 {
+			prevValue := performanceData[i-1].Value
+			performanceData[i].DayChange = performanceData[i].Value - prevValue
 			
-			// Calculate day-over-day change
-			if i > 0 {
-				prevValue := performanceData[i-1].Value
-				performanceData[i].DayChange = performanceData[i].Value - prevValue
-				
-				if prevValue > 0 {
-					performanceData[i].DayChangePercent = (performanceData[i].DayChange / prevValue) * 100
-				}
+			if prevValue > 0 {
+				performanceData[i].DayChangePercent = (performanceData[i].DayChange / prevValue) * 100
 			}
 		}
 	}
+}
+
+// positionChange records the cumulative shares held after a transaction
+type positionChange struct {
+	Date   time.Time
+	Shares float64
+}
+
+// appendPositionChange extends a symbol's position timeline with a transaction.
+// Transactions must be appended in date order.
+func appendPositionChange(timeline []positionChange, tx models.Transaction) []positionChange {
+	shares := 0.0
+	if len(timeline) > 0 {
+		shares = timeline[len(timeline)-1].Shares
+	}
 	
-	return performanceData, nil
+	if tx.Action == "buy" {
+		shares += tx.Shares
+	} else if tx.Action == "sell" {
+		shares -= tx.Shares
+	}
+	
+	return append(timeline, positionChange{Date: tx.Date, Shares: shares})
 }
 
-// findPriceForDate finds the price for a specific date or the closest previous date
-func (s *AnalyticsService) findPriceForDate(prices []HistoricalPrice, targetDate time.Time) float64 {
-	if len(prices) == 0 {
-		return 0
+// symbolSeriesCursor walks a symbol's prices and position timeline forward in time
+type symbolSeriesCursor struct {
+	symbol      string
+	prices      *engine.Cursor
+	positions   []positionChange
+	rate        float64
+	positionIdx int
+	price       float64
+	shares      float64
+}
+
+// valueAt returns the symbol's value in the target currency on the given date.
+// Calls must be made with non-decreasing dates.
+func (c *symbolSeriesCursor) valueAt(date time.Time) float64 {
+	// Apply every transaction up to and including this date
+	for c.positionIdx < len(c.positions) && !c.positions[c.positionIdx].Date.After(date) {
+		c.shares = c.positions[c.positionIdx].Shares
+		c.positionIdx++
 	}
 	
-	// Find exact match or closest previous date
-	var closestPrice float64
-	var closestDate time.Time
+	// Use the price for this date or the closest previous date
+	c.price = c.prices.At(date)
 	
-	for _, price := range prices {
-		// If exact match, return immediately
-		if price.Date.Format("2006-01-02") == targetDate.Format("2006-01-02") {
-			return price.Price
-		}
-		
-		// If this price is before target date and closer than previous closest
-		if price.Date.Before(targetDate) || price.Date.Equal(targetDate) {
-			if closestDate.IsZero() || price.Date.After(closestDate) {
-				closestDate = price.Date
-				closestPrice = price.Price
-			}
-		}
+	// If no shares held or no price yet, the symbol contributes nothing
+	if c.shares <= 0 || c.price <= 0 {
+		return 0
 	}
 	
-	return closestPrice
+	return c.shares * c.price * c.rate
 }
 
-// GetGroupedDashboardMetrics returns dashboard metrics grouped by specified dimension
-// Optimized version using efficient data fetching and in-memory grouping
-func (s *AnalyticsService) GetGroupedDashboardMetrics(userID primitive.ObjectID, currency string, groupBy string) (*GroupedDashboardMetrics, error) {
-	fmt.Printf("[Analytics] GetGroupedDashboardMetrics called - UserID: %s, Currency: %s, GroupBy: %s\n", userID.Hex(), currency, groupBy)
-
-	// Validate currency
-	if currency != "USD" && currency != "RMB" && currency != "CNY" {
-		return nil, fmt.Errorf("invalid currency: must be USD or RMB")
+// GetGroupedDashboardMetrics returns dashboard metrics grouped by specified dimension,
+// reusing a recent result when the user's transactions and portfolios are unchanged.
+// The returned value is shared with the cache and must not be modified.
+func (s *AnalyticsService) GetGroupedDashboardMetrics(ctx context.Context, userID primitive.ObjectID, currency string, groupBy string) (*GroupedDashboardMetrics, error) {
+	key := dashboardCacheKey(userID, currency, groupBy)
+	if cached, ok := s.getCachedDashboard(key, userID); ok {
+		return cached.(*GroupedDashboardMetrics), nil
 	}
 
-	// Normalize CNY to RMB
-	if currency == "CNY" {
-		currency = "RMB"
+	version := dataVersions.current(userID)
+	metrics, err := s.calculateGroupedDashboardMetrics(ctx, userID, currency, groupBy)
+	if err != nil {
+		return nil, err
 	}
+	s.setCachedDashboard(key, version, metrics)
+	return metrics, nil
+}
+
+// calculateGroupedDashboardMetrics computes dashboard metrics grouped by specified dimension
+// Optimized version using efficient data fetching and in-memory grouping
+func (s *AnalyticsService) calculateGroupedDashboardMetrics(ctx context.Context, userID primitive.ObjectID, currency string, groupBy string) (*GroupedDashboardMetrics, error) {
+	fmt.Printf("[Analytics] GetGroupedDashboardMetrics called - UserID: %s, Currency: %s, GroupBy: %s\n", userID.Hex(), currency, groupBy)
 
-	// Validate groupBy parameter
-	validGroupBy := map[string]bool{
-		"assetStyle": true,
-		"assetClass": true,
-		"currency":   true,
-		"none":       true,
+	// Validate currency, normalizing CNY to RMB
+	currency, err := NormalizeDisplayCurrency(currency)
+	if err != nil {
+		return nil, err
 	}
 
-	if !validGroupBy[groupBy] {
-		return nil, fmt.Errorf("invalid groupBy parameter: must be assetStyle, assetClass, currency, or none")
+	// Validate groupBy parameter, one dimension or several to nest
+	dimensions, err := ParseGroupBy(groupBy)
+	if err != nil {
+		return nil, err
 	}
+	groupBy = strings.Join(dimensions, ",")
 
 	// Fetch user holdings (already optimized with proper indexes)
-	holdings, err := s.portfolioService.GetUserHoldings(userID, currency)
+	holdings, err := s.portfolioService.GetUserHoldingsContext(ctx, userID, currency)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch holdings: %w", err)
 	}
@@ -570,7 +730,7 @@ func (s *AnalyticsService) GetGroupedDashboardMetrics(userID primitive.ObjectID,
 		}, nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	// Fetch portfolios and asset styles in parallel for better performance
@@ -588,38 +748,14 @@ func (s *AnalyticsService) GetGroupedDashboardMetrics(userID primitive.ObjectID,
 
 	// Fetch portfolios in goroutine
 	go func() {
-		portfolioCollection := database.Database.Collection("portfolios")
-		cursor, err := portfolioCollection.Find(ctx, bson.M{"user_id": userID})
-		if err != nil {
-			portfolioChan <- portfolioResult{err: err}
-			return
-		}
-		defer cursor.Close(ctx)
-
-		var portfolios []models.Portfolio
-		if err := cursor.All(ctx, &portfolios); err != nil {
-			portfolioChan <- portfolioResult{err: err}
-			return
-		}
-		portfolioChan <- portfolioResult{portfolios: portfolios}
+		portfolios, err := s.portfolioService.repos.Portfolios.FindByUser(queryCtx, userID)
+		portfolioChan <- portfolioResult{portfolios: portfolios, err: err}
 	}()
 
 	// Fetch asset styles in goroutine
 	go func() {
-		assetStyleCollection := database.Database.Collection("asset_styles")
-		cursor, err := assetStyleCollection.Find(ctx, bson.M{"user_id": userID})
-		if err != nil {
-			assetStyleChan <- assetStyleResult{err: err}
-			return
-		}
-		defer cursor.Close(ctx)
-
-		var assetStyles []models.AssetStyle
-		if err := cursor.All(ctx, &assetStyles); err != nil {
-			assetStyleChan <- assetStyleResult{err: err}
-			return
-		}
-		assetStyleChan <- assetStyleResult{assetStyles: assetStyles}
+		assetStyles, err := s.portfolioService.repos.AssetStyles.FindByUser(queryCtx, userID)
+		assetStyleChan <- assetStyleResult{assetStyles: assetStyles, err: err}
 	}()
 
 	// Wait for both results
@@ -640,78 +776,82 @@ func (s *AnalyticsService) GetGroupedDashboardMetrics(userID primitive.ObjectID,
 	}
 
 	assetStyleMap := make(map[primitive.ObjectID]string, len(assetStyleRes.assetStyles))
+	stylesByName := make(map[string]models.AssetStyle, len(assetStyleRes.assetStyles))
 	for _, style := range assetStyleRes.assetStyles {
 		assetStyleMap[style.ID] = style.Name
+		stylesByName[style.Name] = style
 	}
 
-	// Group holdings based on groupBy parameter
-	var groups map[string][]Holding
-
-	switch groupBy {
-	case "assetStyle":
-		groups = s.groupByAssetStyle(holdings, portfolioMap, assetStyleMap)
-	case "assetClass":
-		groups = s.groupByAssetClass(holdings, portfolioMap)
-	case "currency":
-		groups = s.groupByCurrency(holdings, portfolioMap)
-	case "none":
-		// No grouping, return all holdings in a single group
-		groups = map[string][]Holding{"All Holdings": holdings}
+	grouping := &holdingGrouping{
+		portfolioMap:  portfolioMap,
+		assetStyleMap: assetStyleMap,
+		stylesByName:  stylesByName,
+	}
+	if slices.Contains(dimensions, "assetSubclass") {
+		grouping.subclasses, err = s.portfolioService.repos.AssetSubclasses.FindByUser(queryCtx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch asset subclasses: %w", err)
+		}
 	}
 
+	// Group holdings by the outermost dimension
+	groups, labels := s.groupHoldings(dimensions[0], holdings, grouping)
+
 	// Calculate totals and group metrics in a single pass
-	var totalValue float64
-	var totalCostBasis float64
-	var previousDayValue float64
+	totalValue := money.Zero(currency)
+	totalCostBasis := money.Zero(currency)
+	previousDayValue := money.Zero(currency)
 	groupedHoldings := make([]GroupedHolding, 0, len(groups))
 
 	for groupName, groupHoldings := range groups {
-		var groupValue float64
-		for _, holding := range groupHoldings {
-			groupValue += holding.CurrentValue
-			totalValue += holding.CurrentValue
-			totalCostBasis += holding.CostBasis
-			
+		groupValue := money.Zero(currency)
+		groupCostBasis := money.Zero(currency)
+		groupPreviousValue := money.Zero(currency)
+		for i := range groupHoldings {
+			holding := &groupHoldings[i]
+			groupValue = groupValue.Add(money.New(holding.CurrentValue, currency))
+			groupCostBasis = groupCostBasis.Add(money.New(holding.CostBasis, currency))
+			totalValue = totalValue.Add(money.New(holding.CurrentValue, currency))
+			totalCostBasis = totalCostBasis.Add(money.New(holding.CostBasis, currency))
+
 			// Calculate previous day value for this holding
-			prevDayPrice, err := s.getPreviousDayPrice(holding.Symbol)
-			if err != nil {
-				fmt.Printf("[Analytics] Warning: Could not get previous day price for %s: %v\n", holding.Symbol, err)
-				previousDayValue += holding.CurrentValue
-			} else {
-				prevValue := holding.Shares * prevDayPrice
-				
-				// Convert to target currency if needed
-				symbolCurrency := "USD"
-				if s.stockService.IsChinaStock(holding.Symbol) {
-					symbolCurrency = "CNY"
-				}
-				
-				if symbolCurrency != currency {
-					convertedPrevValue, err := s.currencyService.ConvertAmount(prevValue, symbolCurrency, currency)
-					if err != nil {
-						fmt.Printf("[Analytics] Warning: Could not convert currency for %s: %v\n", holding.Symbol, err)
-						previousDayValue += holding.CurrentValue
-					} else {
-						previousDayValue += convertedPrevValue
-					}
-				} else {
-					previousDayValue += prevValue
-				}
-			}
+			prevValue := s.previousDayValue(ctx, *holding, currency)
+			groupPreviousValue = groupPreviousValue.Add(prevValue)
+			previousDayValue = previousDayValue.Add(prevValue)
+			holding.DayChange = money.New(holding.CurrentValue, currency).Sub(prevValue).Float64()
+			holding.DayChangePercent = changePercent(holding.DayChange, prevValue.Float64())
 		}
 
-		groupedHoldings = append(groupedHoldings, GroupedHolding{
-			GroupName:  groupName,
-			GroupValue: groupValue,
-			Percentage: 0, // Will calculate after we have totalValue
-			Holdings:   groupHoldings,
-		})
+		groupDayChange := groupValue.Sub(groupPreviousValue)
+		groupGain := groupValue.Sub(groupCostBasis)
+		group := GroupedHolding{
+			GroupValue:       groupValue.Float64(),
+			Percentage:       0, // Will calculate after we have totalValue
+			CostBasis:        groupCostBasis.Float64(),
+			GainLoss:         groupGain.Float64(),
+			GainLossPercent:  changePercent(groupGain.Float64(), groupCostBasis.Float64()),
+			DayChange:        groupDayChange.Float64(),
+			DayChangePercent: changePercent(groupDayChange.Float64(), groupPreviousValue.Float64()),
+			Holdings:         groupHoldings,
+		}
+		grouping.describe(&group, dimensions[0], groupName, labels)
+		groupedHoldings = append(groupedHoldings, group)
 	}
 
-	// Calculate percentages in a second pass
+	// Previous closes that couldn't be fetched in time would understate the
+	// day change, so a cancelled request fails rather than being cached
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Calculate percentages and nest the inner dimensions in a second pass
 	for i := range groupedHoldings {
-		if totalValue > 0 {
-			groupedHoldings[i].Percentage = (groupedHoldings[i].GroupValue / totalValue) * 100
+		if totalValue.Minor > 0 {
+			groupedHoldings[i].Weight = groupedHoldings[i].GroupValue / totalValue.Float64()
+			groupedHoldings[i].Percentage = groupedHoldings[i].Weight * 100
+		}
+		if len(dimensions) > 1 {
+			groupedHoldings[i].Groups = s.nestGroups(groupedHoldings[i].Holdings, dimensions[1:], grouping, currency, totalValue)
 		}
 	}
 
@@ -720,29 +860,36 @@ func (s *AnalyticsService) GetGroupedDashboardMetrics(userID primitive.ObjectID,
 		return groupedHoldings[i].GroupValue > groupedHoldings[j].GroupValue
 	})
 
+	var subtotals []GroupSubtotal
+	if dimensions[0] == "assetSubclass" {
+		subtotals = subtotalGroups(groupedHoldings, currency, totalValue)
+	}
+
 	// Calculate total gain and percentage return
-	totalGain := totalValue - totalCostBasis
+	totalGain := totalValue.Sub(totalCostBasis)
 	percentageReturn := 0.0
-	if totalCostBasis > 0 {
-		percentageReturn = (totalGain / totalCostBasis) * 100
+	if totalCostBasis.Minor > 0 {
+		percentageReturn = (totalGain.Float64() / totalCostBasis.Float64()) * 100
 	}
 	
 	// Calculate day change
-	dayChange := totalValue - previousDayValue
+	dayChange := totalValue.Sub(previousDayValue)
 	dayChangePercent := 0.0
-	if previousDayValue > 0 {
-		dayChangePercent = (dayChange / previousDayValue) * 100
+	if previousDayValue.Minor > 0 {
+		dayChangePercent = (dayChange.Float64() / previousDayValue.Float64()) * 100
 	}
 
 	return &GroupedDashboardMetrics{
-		TotalValue:        totalValue,
-		TotalGain:         totalGain,
+		TotalValue:        totalValue.Float64(),
+		TotalGain:         totalGain.Float64(),
 		PercentageReturn:  percentageReturn,
-		DayChange:         dayChange,
+		DayChange:         dayChange.Float64(),
 		DayChangePercent:  dayChangePercent,
 		Groups:            groupedHoldings,
+		Subtotals:         subtotals,
 		Currency:          currency,
 		GroupBy:           groupBy,
+		ExchangeRates:     s.portfolioService.HoldingsRateFreshness(holdings, currency),
 	}, nil
 }
 
@@ -787,6 +934,89 @@ func (s *AnalyticsService) groupByAssetClass(holdings []Holding, portfolioMap ma
 	return groups
 }
 
+// subclassGroup labels an asset subclass group with its own name and asset class
+type subclassGroup struct {
+	parent string
+	name   string
+}
+
+// groupByAssetSubclass groups holdings by asset subclass. Groups are keyed
+// by asset class and subclass, as subclass names are only unique within a
+// class; labels gives each key's subclass and class. Holdings in a class but
+// no subclass are grouped as Other within their class.
+func (s *AnalyticsService) groupByAssetSubclass(holdings []Holding, portfolioMap map[string]*models.Portfolio, subclasses []models.AssetSubclass) (map[string][]Holding, map[string]subclassGroup) {
+	names := make(map[primitive.ObjectID]string, len(subclasses))
+	for _, subclass := range subclasses {
+		names[subclass.ID] = subclass.Name
+	}
+
+	groups := make(map[string][]Holding)
+	labels := make(map[string]subclassGroup)
+	for _, holding := range holdings {
+		label := subclassGroup{parent: "Uncategorized", name: "Uncategorized"}
+		if portfolio, exists := portfolioMap[holding.Symbol]; exists && portfolio.AssetClass != "" {
+			label = subclassGroup{parent: portfolio.AssetClass, name: "Other"}
+			if portfolio.AssetSubclassID != nil {
+				if name, ok := names[*portfolio.AssetSubclassID]; ok {
+					label.name = name
+				}
+			}
+		}
+
+		key := label.parent + " / " + label.name
+		groups[key] = append(groups[key], holding)
+		labels[key] = label
+	}
+
+	return groups, labels
+}
+
+// subtotalGroups rolls subclass groups up to their asset classes, largest
+// first
+func subtotalGroups(groups []GroupedHolding, currency string, totalValue money.Amount) []GroupSubtotal {
+	values := make(map[string]money.Amount)
+	var order []string
+	for _, group := range groups {
+		value, seen := values[group.Parent]
+		if !seen {
+			value = money.Zero(currency)
+			order = append(order, group.Parent)
+		}
+		values[group.Parent] = value.Add(money.New(group.GroupValue, currency))
+	}
+
+	subtotals := make([]GroupSubtotal, 0, len(order))
+	for _, parent := range order {
+		subtotal := GroupSubtotal{GroupName: parent, GroupValue: values[parent].Float64()}
+		if totalValue.Minor > 0 {
+			subtotal.Percentage = subtotal.GroupValue / totalValue.Float64() * 100
+		}
+		subtotals = append(subtotals, subtotal)
+	}
+	sort.SliceStable(subtotals, func(i, j int) bool {
+		return subtotals[i].GroupValue > subtotals[j].GroupValue
+	})
+	return subtotals
+}
+
+// groupByTag groups holdings by their portfolio tags in the dimension name,
+// collecting holdings without one as Untagged
+func (s *AnalyticsService) groupByTag(holdings []Holding, portfolioMap map[string]*models.Portfolio, name string) map[string][]Holding {
+	groups := make(map[string][]Holding)
+
+	for _, holding := range holdings {
+		groupName := untaggedGroup
+		if portfolio, exists := portfolioMap[holding.Symbol]; exists {
+			if group := tagGroup(portfolio.Tags, name); group != "" {
+				groupName = group
+			}
+		}
+		groups[groupName] = append(groups[groupName], holding)
+	}
+
+	return groups
+}
+
 // groupByCurrency groups holdings by currency
 func (s *AnalyticsService) groupByCurrency(holdings []Holding, portfolioMap map[string]*models.Portfolio) map[string][]Holding {
 	groups := make(map[string][]Holding)
@@ -810,9 +1040,9 @@ func (s *AnalyticsService) groupByCurrency(holdings []Holding, portfolioMap map[
 			} else {
 				currency = "USD"
 			}
-		} else if s.stockService.IsChinaStock(portfolio.Symbol) {
-			// Check if it's a China stock
-			currency = "RMB"
+		} else {
+			// Otherwise use the currency of the symbol's exchange
+			currency = currencycode.Normalize(s.stockService.SymbolCurrency(portfolio.Symbol))
 		}
 
 		groups[currency] = append(groups[currency], holding)
@@ -1089,70 +1319,189 @@ func (s *AnalyticsService) CalculateMaxDrawdown(dataPoints []PerformanceDataPoin
 		}, nil
 	}
 	
-	// Initialize tracking variables
-	peak := dataPoints[0].Value
-	peakDate := dataPoints[0].Date
-	maxDrawdown := 0.0
-	maxDrawdownAbsolute := 0.0
-	troughDate := dataPoints[0].Date
-	troughValue := dataPoints[0].Value
-	finalPeakDate := peakDate
-	finalPeakValue := peak
-	
-	// Iterate through all data points
-	for _, point := range dataPoints {
-		// Update peak if current value is higher
-		if point.Value > peak {
-			peak = point.Value
-			peakDate = point.Date
-		}
-		
-		// Calculate current drawdown from peak
-		if peak > 0 {
-			drawdown := ((peak - point.Value) / peak) * 100
-			drawdownAbsolute := peak - point.Value
-			
-			// Update max drawdown if current is larger
-			if drawdown > maxDrawdown {
-				maxDrawdown = drawdown
-				maxDrawdownAbsolute = drawdownAbsolute
-				troughDate = point.Date
-				troughValue = point.Value
-				finalPeakDate = peakDate
-				finalPeakValue = peak
-			}
-		}
+	values := make([]float64, len(dataPoints))
+	for i, point := range dataPoints {
+		values[i] = point.Value
 	}
+	drawdown := engine.MaxDrawdown(values)
 	
 	return &DrawdownMetric{
-		Percentage:  maxDrawdown,
-		Absolute:    maxDrawdownAbsolute,
-		PeakDate:    finalPeakDate,
-		TroughDate:  troughDate,
-		PeakValue:   finalPeakValue,
-		TroughValue: troughValue,
+		Percentage:  drawdown.Percent,
+		Absolute:    drawdown.Absolute,
+		PeakDate:    dataPoints[drawdown.Peak].Date,
+		TroughDate:  dataPoints[drawdown.Trough].Date,
+		PeakValue:   dataPoints[drawdown.Peak].Value,
+		TroughValue: dataPoints[drawdown.Trough].Value,
 	}, nil
 }
 
-// getPreviousDayPrice fetches the previous trading day's closing price for a symbol
-func (s *AnalyticsService) getPreviousDayPrice(symbol string) (float64, error) {
-	// Fetch 5 days of historical data to ensure we get at least 2 data points
-	// (accounting for weekends and holidays)
-	historicalData, err := s.stockService.GetHistoricalData(symbol, "1M")
+// PeriodStart returns the start of a performance period ending at end.
+// ALL covers the last ten years, the longest history fetched for any symbol.
+func PeriodStart(period string, end time.Time) time.Time {
+	switch period {
+	case "3M":
+		return end.AddDate(0, -3, 0)
+	case "6M":
+		return end.AddDate(0, -6, 0)
+	case "1Y":
+		return end.AddDate(-1, 0, 0)
+	case "ALL":
+		return end.AddDate(-10, 0, 0)
+	default:
+		return end.AddDate(0, -1, 0)
+	}
+}
+
+// HoldingMover represents a holding's price movement over a period
+type HoldingMover struct {
+	Symbol        string  `json:"symbol"`
+	Name          string  `json:"name"`
+	Value         float64 `json:"value"`
+	Change        float64 `json:"change"`        // In the requested currency
+	ChangePercent float64 `json:"changePercent"`
+}
+
+// GetDayMovers returns each holding's change since the previous close, sorted
+// from best to worst percentage change. Holdings without a previous close are skipped.
+func (s *AnalyticsService) GetDayMovers(userID primitive.ObjectID, currency string) ([]HoldingMover, error) {
+	currency = currencycode.Normalize(currency)
+
+	holdings, err := s.portfolioService.GetUserHoldings(userID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch holdings: %w", err)
+	}
+
+	movers := make([]HoldingMover, 0, len(holdings))
+	for _, holding := range holdings {
+		// A manual price has no previous close to move from
+		if holding.PriceOverride != nil {
+			continue
+		}
+		prevDayPrice, err := s.getPreviousDayPrice(context.Background(), holding.Symbol)
+		if err != nil || prevDayPrice <= 0 {
+			continue
+		}
+
+		previousValue := holding.Shares * prevDayPrice
+		symbolCurrency := s.stockService.SymbolCurrency(holding.Symbol)
+		if symbolCurrency != currency {
+			previousValue, err = s.currencyService.ConvertAmount(previousValue, symbolCurrency, currency)
+			if err != nil {
+				continue
+			}
+		}
+		if previousValue <= 0 {
+			continue
+		}
+
+		movers = append(movers, HoldingMover{
+			Symbol:        holding.Symbol,
+			Name:          holding.Name,
+			Value:         holding.CurrentValue,
+			Change:        holding.CurrentValue - previousValue,
+			ChangePercent: (holding.CurrentValue/previousValue - 1) * 100,
+		})
+	}
+
+	return sortMovers(movers), nil
+}
+
+// getPreviousDayPrice returns the official close of the trading session before
+// the current one. The quote's previous close is used when the provider reports
+// it; otherwise it is read from the price history.
+func (s *AnalyticsService) getPreviousDayPrice(ctx context.Context, symbol string) (float64, error) {
+	if price, ok := s.quotePreviousClose(ctx, symbol); ok {
+		return price, nil
+	}
+
+	historicalData, err := s.stockService.GetHistoricalDataContext(ctx, symbol, "1M")
 	if err != nil {
 		return 0, fmt.Errorf("failed to fetch historical data: %w", err)
 	}
-	
-	if len(historicalData) < 2 {
+
+	price, ok := previousCloseFromHistory(symbol, engine.Sorted(historicalData), time.Now())
+	if !ok {
 		return 0, fmt.Errorf("insufficient historical data")
 	}
-	
-	// Sort by date descending to get most recent prices
-	sort.Slice(historicalData, func(i, j int) bool {
-		return historicalData[i].Date.After(historicalData[j].Date)
-	})
-	
-	// The second most recent price is the previous day's close
-	// (most recent is today's price, which might be intraday)
-	return historicalData[1].Price, nil
+	return price, nil
+}
+
+// previousDayValue returns what a holding was worth at the previous close in
+// currency, or its current value when the previous close or the rate to
+// convert it is unavailable or the holding is at a manual price
+func (s *AnalyticsService) previousDayValue(ctx context.Context, holding Holding, currency string) money.Amount {
+	if holding.PriceOverride != nil {
+		return money.New(holding.CurrentValue, currency)
+	}
+	prevDayPrice, err := s.getPreviousDayPrice(ctx, holding.Symbol)
+	if err != nil {
+		fmt.Printf("[Analytics] Warning: Could not get previous day price for %s: %v\n", holding.Symbol, err)
+		return money.New(holding.CurrentValue, currency)
+	}
+	prevValue := holding.Shares * prevDayPrice
+
+	// Convert to target currency if needed
+	symbolCurrency := s.stockService.SymbolCurrency(holding.Symbol)
+	if symbolCurrency != currency {
+		convertedPrevValue, err := s.currencyService.ConvertAmount(prevValue, symbolCurrency, currency)
+		if err != nil {
+			fmt.Printf("[Analytics] Warning: Could not convert currency for %s: %v\n", holding.Symbol, err)
+			return money.New(holding.CurrentValue, currency)
+		}
+		prevValue = convertedPrevValue
+	}
+	return money.New(prevValue, currency)
+}
+
+// quotePreviousClose returns the previous close reported with a symbol's quote
+func (s *AnalyticsService) quotePreviousClose(ctx context.Context, symbol string) (float64, bool) {
+	info, err := s.stockService.GetStockInfoContext(ctx, symbol)
+	if err != nil || info.PreviousClose <= 0 {
+		return 0, false
+	}
+	return info.PreviousClose, true
+}
+
+// previousCloseFromHistory returns the last close dated before the trading day
+// the symbol's exchange is on, from date-sorted prices. Before the open, and
+// after it until the data provider adds today's bar, the latest bar belongs to
+// the previous session rather than the current one. Symbols on unknown
+// exchanges fall back to the second most recent close.
+func previousCloseFromHistory(symbol string, prices []HistoricalPrice, now time.Time) (float64, bool) {
+	market := MarketForSymbol(symbol)
+	if market == nil {
+		if len(prices) < 2 {
+			return 0, false
+		}
+		return prices[len(prices)-2].Price, true
+	}
+
+	location := market.Location()
+	session := market.SessionDate(now)
+	for i := len(prices) - 1; i >= 0; i-- {
+		local := prices[i].Date.In(location)
+		date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+		if date.Before(session) {
+			return prices[i].Price, true
+		}
+	}
+	return 0, false
+}
+
+// ResponseETag computes an entity tag for an analytics response from the user's
+// data version, the price cache version, and the request parameters, so
+// unchanged responses can be revalidated without being recomputed
+func (s *AnalyticsService) ResponseETag(userID primitive.ObjectID, params ...string) (string, error) {
+	dataVersion, err := s.portfolioService.GetDataVersion(userID)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s|%s|%s", userID.Hex(), dataVersion, s.stockService.CacheVersion())
+	for _, param := range params {
+		fmt.Fprintf(hash, "|%s", param)
+	}
+
+	return fmt.Sprintf(`"%s"`, hex.EncodeToString(hash.Sum(nil))[:32]), nil
 }
@@ -2,8 +2,11 @@ package services
 
 import (
 	"context"
+	"math"
 	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/internal/analytics/engine"
 	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
 	"testing"
 	"time"
 
@@ -19,8 +22,8 @@ func setupAnalyticsTest(t *testing.T) (*AnalyticsService, primitive.ObjectID, fu
 		t.Fatalf("Failed to connect to test database: %v", err)
 	}
 
-	stockService := NewStockAPIService()
-	currencyService := NewCurrencyService()
+	stockService := NewStockAPIService(StockAPIConfig{})
+	currencyService := NewCurrencyService(CurrencyConfig{})
 	portfolioService := NewPortfolioService(stockService, currencyService)
 	service := NewAnalyticsService(portfolioService, currencyService, stockService)
 
@@ -100,7 +103,7 @@ func TestGetGroupedDashboardMetricsByAssetStyle(t *testing.T) {
 	// For now, we just test that the method doesn't error
 
 	// Get grouped metrics
-	metrics, err := service.GetGroupedDashboardMetrics(userID, "USD", "assetStyle")
+	metrics, err := service.GetGroupedDashboardMetrics(context.Background(), userID, "USD", "assetStyle")
 	if err != nil {
 		t.Fatalf("Failed to get grouped dashboard metrics: %v", err)
 	}
@@ -162,7 +165,7 @@ func TestGetGroupedDashboardMetricsByAssetClass(t *testing.T) {
 	}
 
 	// Get grouped metrics
-	metrics, err := service.GetGroupedDashboardMetrics(userID, "USD", "assetClass")
+	metrics, err := service.GetGroupedDashboardMetrics(context.Background(), userID, "USD", "assetClass")
 	if err != nil {
 		t.Fatalf("Failed to get grouped dashboard metrics: %v", err)
 	}
@@ -177,7 +180,7 @@ func TestGetGroupedDashboardMetricsInvalidGroupBy(t *testing.T) {
 	defer cleanup()
 
 	// Try to get metrics with invalid groupBy
-	_, err := service.GetGroupedDashboardMetrics(userID, "USD", "invalid")
+	_, err := service.GetGroupedDashboardMetrics(context.Background(), userID, "USD", "invalid")
 	if err == nil {
 		t.Error("Expected error for invalid groupBy parameter")
 	}
@@ -188,7 +191,7 @@ func TestGetGroupedDashboardMetricsByCurrency(t *testing.T) {
 	defer cleanup()
 
 	// Get grouped metrics by currency
-	metrics, err := service.GetGroupedDashboardMetrics(userID, "USD", "currency")
+	metrics, err := service.GetGroupedDashboardMetrics(context.Background(), userID, "USD", "currency")
 	if err != nil {
 		t.Fatalf("Failed to get grouped dashboard metrics: %v", err)
 	}
@@ -197,3 +200,265 @@ func TestGetGroupedDashboardMetricsByCurrency(t *testing.T) {
 		t.Errorf("Expected groupBy 'currency', got '%s'", metrics.GroupBy)
 	}
 }
+
+func TestSymbolSeriesCursorValueAt(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 16, 0, 0, 0, time.UTC)
+	}
+
+	var positions []positionChange
+	positions = appendPositionChange(positions, models.Transaction{Action: "buy", Shares: 10, Date: day(2)})
+	positions = appendPositionChange(positions, models.Transaction{Action: "sell", Shares: 4, Date: day(4)})
+
+	cursor := &symbolSeriesCursor{
+		prices: engine.NewCursor(engine.Sorted([]HistoricalPrice{
+			{Date: day(3), Price: 12},
+			{Date: day(1), Price: 10},
+			{Date: day(2), Price: 11},
+		})),
+		positions: positions,
+		rate:      2,
+	}
+
+	tests := []struct {
+		date time.Time
+		want float64
+	}{
+		{day(1), 0},          // no shares held yet
+		{day(2), 10 * 11 * 2}, // bought on the same day
+		{day(3), 10 * 12 * 2},
+		{day(4), 6 * 12 * 2}, // no price on day 4, carry forward day 3
+	}
+
+	for _, tt := range tests {
+		if got := cursor.valueAt(tt.date); got != tt.want {
+			t.Errorf("valueAt(%s) = %.2f, want %.2f", tt.date.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestDashboardCacheInvalidatedByDataChange(t *testing.T) {
+	service := NewAnalyticsService(nil, nil, nil)
+	userID := primitive.NewObjectID()
+	key := dashboardCacheKey(userID, "CNY", "assetStyle")
+
+	if key != dashboardCacheKey(userID, "RMB", "assetStyle") {
+		t.Errorf("Expected CNY and RMB to share a cache key")
+	}
+
+	metrics := &GroupedDashboardMetrics{TotalValue: 100}
+	service.setCachedDashboard(key, dataVersions.current(userID), metrics)
+
+	cached, ok := service.getCachedDashboard(key, userID)
+	if !ok || cached.(*GroupedDashboardMetrics) != metrics {
+		t.Fatalf("Expected cached metrics to be returned")
+	}
+
+	dataVersions.bump(userID)
+
+	if _, ok := service.getCachedDashboard(key, userID); ok {
+		t.Errorf("Expected cache miss after the user's data changed")
+	}
+}
+
+// newFixtureAnalyticsService creates an AnalyticsService over in-memory
+// repositories and fixture prices, so analytics can be tested without MongoDB
+// or market data APIs
+func newFixtureAnalyticsService(provider *FixtureProvider) (*AnalyticsService, *PortfolioService) {
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	return NewAnalyticsService(portfolioService, provider, provider), portfolioService
+}
+
+func TestAnalyticsWithFixtureProvider(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 110, "USD").
+		SetPreviousClose("AAPL", 105).
+		SetHistory("AAPL", today, 100, 102, 101, 105, 110).
+		SetRate("USD", "RMB", 7)
+	service, portfolioService := newFixtureAnalyticsService(provider)
+	userID := primitive.NewObjectID()
+
+	buy := &models.Transaction{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 100, Currency: "USD", Date: today.AddDate(0, 0, -10)}
+	if err := portfolioService.AddTransaction(userID, buy); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+
+	metrics, err := service.GetDashboardMetrics(userID, "USD")
+	if err != nil {
+		t.Fatalf("Failed to get dashboard metrics: %v", err)
+	}
+	if metrics.TotalValue != 1100 || metrics.TotalGain != 100 || metrics.PercentageReturn != 10 {
+		t.Errorf("Unexpected totals: %+v", metrics)
+	}
+	// Previous close is 105, so the day change is 10 × 5
+	if metrics.DayChange != 50 {
+		t.Errorf("Expected day change 50, got %.2f", metrics.DayChange)
+	}
+
+	converted, err := service.GetDashboardMetrics(userID, "CNY")
+	if err != nil {
+		t.Fatalf("Failed to get dashboard metrics in CNY: %v", err)
+	}
+	if converted.TotalValue != 7700 || converted.Currency != "RMB" {
+		t.Errorf("Expected 7700 RMB, got %.2f %s", converted.TotalValue, converted.Currency)
+	}
+
+	performance, err := service.GetHistoricalPerformance(userID, "1M", "USD")
+	if err != nil {
+		t.Fatalf("Failed to get performance: %v", err)
+	}
+	want := []float64{1000, 1020, 1010, 1050, 1100}
+	if len(performance) != len(want) {
+		t.Fatalf("Expected %d points, got %d", len(want), len(performance))
+	}
+	for i, point := range performance {
+		if point.Value != want[i] {
+			t.Errorf("Point %d value = %.2f, want %.2f", i, point.Value, want[i])
+		}
+	}
+	if last := performance[len(performance)-1]; math.Abs(last.PercentageReturn-10) > 1e-9 {
+		t.Errorf("Expected 10%% cumulative return, got %.4f", last.PercentageReturn)
+	}
+}
+
+func TestPerformanceUsesAdjustedCloses(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	// A 2 dividend went ex on the third day: the price fell, the adjusted
+	// closes before it are scaled down instead
+	provider := NewFixtureProvider().
+		SetQuote("KO", "Coca-Cola", 100, "USD").
+		SetHistory("KO", today, 100, 100, 98, 100).
+		SetAdjustedCloses("KO", 98, 98, 98, 100)
+	service, portfolioService := newFixtureAnalyticsService(provider)
+	userID := primitive.NewObjectID()
+
+	buy := &models.Transaction{Symbol: "KO", Action: "buy", Shares: 10, Price: 100, Currency: "USD", Date: today.AddDate(0, 0, -10)}
+	if err := portfolioService.AddTransaction(userID, buy); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+
+	performance, err := service.GetHistoricalPerformance(userID, "1M", "USD")
+	if err != nil {
+		t.Fatalf("Failed to get performance: %v", err)
+	}
+	if len(performance) != 4 || performance[2].DayChange != 0 || performance[3].Value != 1000 {
+		t.Fatalf("Expected no drop on the ex-dividend day, got %+v", performance)
+	}
+	if last := performance[3]; math.Abs(last.PercentageReturn-100.0*2/98) > 1e-9 {
+		t.Errorf("Expected the dividend in the total return, got %.4f", last.PercentageReturn)
+	}
+
+	// Price-only history is unchanged for callers that want it
+	prices, _ := provider.GetHistoricalData("KO", "1M")
+	if prices[2].Price != 98 || AdjustedHistory(prices)[0].Price != 98 {
+		t.Errorf("Unexpected closes %+v", prices)
+	}
+}
+
+func TestFixtureProviderPeriodsAndRates(t *testing.T) {
+	end := time.Date(2024, 6, 28, 0, 0, 0, 0, time.UTC)
+	closes := make([]float64, 120)
+	provider := NewFixtureProvider().SetHistory("MSFT", end, closes...).SetRate("USD", "CNY", 8)
+
+	// Periods count back from the last fixture date, not the wall clock
+	month, err := provider.GetHistoricalData("MSFT", "1M")
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(month) != 32 || !month[0].Date.Equal(end.AddDate(0, -1, 0)) {
+		t.Errorf("Expected 32 daily closes from %s, got %d from %s", end.AddDate(0, -1, 0), len(month), month[0].Date)
+	}
+	if _, err := provider.GetHistoricalData("MSFT", "2W"); err != ErrInvalidPeriod {
+		t.Errorf("Expected ErrInvalidPeriod, got %v", err)
+	}
+	if _, err := provider.GetStockInfo("NOPE"); err != ErrStockNotFound {
+		t.Errorf("Expected ErrStockNotFound, got %v", err)
+	}
+
+	if rate, _ := provider.GetExchangeRate("RMB", "USD"); rate != 0.125 {
+		t.Errorf("Expected inverse rate 0.125, got %v", rate)
+	}
+	if _, err := provider.GetExchangeRate("USD", "EUR"); err != ErrCurrencyAPIError {
+		t.Errorf("Expected ErrCurrencyAPIError for an unregistered pair, got %v", err)
+	}
+}
+
+func TestPreviousCloseFromHistory(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	// Daily bars stamped at the 09:30 open, Wednesday 8 May to Friday 10 May 2024
+	prices := []HistoricalPrice{
+		{Date: time.Date(2024, 5, 8, 9, 30, 0, 0, newYork), Price: 100},
+		{Date: time.Date(2024, 5, 9, 9, 30, 0, 0, newYork), Price: 101},
+		{Date: time.Date(2024, 5, 10, 9, 30, 0, 0, newYork), Price: 102},
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want float64
+	}{
+		{"during Friday's session", time.Date(2024, 5, 10, 11, 0, 0, 0, newYork), 101},
+		{"at the weekend", time.Date(2024, 5, 11, 12, 0, 0, 0, newYork), 101},
+		{"before Monday's open", time.Date(2024, 5, 13, 8, 0, 0, 0, newYork), 101},
+		{"after Monday's open without its bar", time.Date(2024, 5, 13, 9, 35, 0, 0, newYork), 102},
+	}
+	for _, test := range tests {
+		got, ok := previousCloseFromHistory("AAPL", prices, test.now)
+		if !ok || got != test.want {
+			t.Errorf("%s: expected previous close %.0f, got %.0f (ok=%v)", test.name, test.want, got, ok)
+		}
+	}
+
+	if _, ok := previousCloseFromHistory("AAPL", prices[2:], time.Date(2024, 5, 10, 11, 0, 0, 0, newYork)); ok {
+		t.Errorf("Expected no previous close when only the current session has a bar")
+	}
+}
+
+func TestGroupedDashboardIncludesStyleAppearance(t *testing.T) {
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 110, "USD").
+		SetQuote("KO", "Coca-Cola", 60, "USD")
+	service, portfolioService := newFixtureAnalyticsService(provider)
+	styles := NewAssetStyleServiceWithRepos(portfolioService.repos)
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -5)
+
+	growth, err := styles.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Growth", Color: "#4f46e5", Icon: "rocket"})
+	if err != nil {
+		t.Fatalf("Failed to create style: %v", err)
+	}
+	for _, tx := range []*models.Transaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 100, Currency: "USD", Date: date},
+		{Symbol: "KO", Action: "buy", Shares: 10, Price: 50, Currency: "USD", Date: date},
+	} {
+		if err := portfolioService.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+	if _, err := portfolioService.CreatePortfolioWithMetadata(userID, "AAPL", growth.ID, "Stock"); err != nil {
+		t.Fatalf("Failed to classify AAPL: %v", err)
+	}
+
+	metrics, err := service.GetGroupedDashboardMetrics(context.Background(), userID, "USD", "assetStyle")
+	if err != nil {
+		t.Fatalf("Failed to get grouped metrics: %v", err)
+	}
+	for _, group := range metrics.Groups {
+		switch group.GroupName {
+		case "Growth":
+			if group.Color != "#4f46e5" || group.Icon != "rocket" {
+				t.Errorf("Expected the style's color and icon, got %q and %q", group.Color, group.Icon)
+			}
+		case "Uncategorized":
+			if group.Color != "" || group.Icon != "" {
+				t.Errorf("Expected no appearance for uncategorized holdings, got %q and %q", group.Color, group.Icon)
+			}
+		default:
+			t.Errorf("Unexpected group %q", group.GroupName)
+		}
+	}
+	if len(metrics.Groups) != 2 {
+		t.Errorf("Expected 2 groups, got %d", len(metrics.Groups))
+	}
+}
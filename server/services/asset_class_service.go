@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrAssetSubclassNotFound  = errors.New("asset subclass not found")
+	ErrDuplicateAssetSubclass = errors.New("asset subclass name already exists in this asset class")
+	ErrAssetSubclassMismatch  = errors.New("asset subclass belongs to another asset class")
+	ErrInvalidAssetSubclass   = errors.New("asset subclass names must be 1 to 50 characters")
+	ErrTooManyAssetSubclasses = errors.New("too many subclasses in this asset class")
+)
+
+// AssetClasses are the fixed top level of the asset class taxonomy
+var AssetClasses = []string{"Stock", "ETF", "Bond", "Cash and Equivalents", "Options", "Crypto"}
+
+// maxAssetSubclasses bounds the subclasses of each asset class
+const maxAssetSubclasses = 20
+
+// AssetClassService manages the second level of each user's asset class
+// taxonomy, subclasses such as US Large Cap under Stock
+type AssetClassService struct {
+	repos repository.Repositories
+}
+
+// NewAssetClassService creates a new AssetClassService instance backed by MongoDB
+func NewAssetClassService() *AssetClassService {
+	return NewAssetClassServiceWithRepos(repository.NewMongo())
+}
+
+// NewAssetClassServiceWithRepos creates an AssetClassService over the given
+// repositories
+func NewAssetClassServiceWithRepos(repos repository.Repositories) *AssetClassService {
+	return &AssetClassService{repos: repos}
+}
+
+// GetTree returns every asset class with the user's subclasses of it
+func (s *AssetClassService) GetTree(userID primitive.ObjectID) ([]models.AssetClassNode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subclasses, err := s.repos.AssetSubclasses.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch asset subclasses: %w", err)
+	}
+	return assetClassTree(subclasses), nil
+}
+
+// CreateSubclass adds a subclass under one of the asset classes
+func (s *AssetClassService) CreateSubclass(userID primitive.ObjectID, req models.AssetSubclassRequest) (*models.AssetSubclass, error) {
+	if !slices.Contains(AssetClasses, req.AssetClass) {
+		return nil, fmt.Errorf("%w: invalid asset class", ErrInvalidTransaction)
+	}
+	name, err := normalizeSubclassName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subclasses, err := s.repos.AssetSubclasses.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch asset subclasses: %w", err)
+	}
+	siblings := 0
+	for _, subclass := range subclasses {
+		if subclass.AssetClass != req.AssetClass {
+			continue
+		}
+		if strings.EqualFold(subclass.Name, name) {
+			return nil, ErrDuplicateAssetSubclass
+		}
+		siblings++
+	}
+	if siblings >= maxAssetSubclasses {
+		return nil, ErrTooManyAssetSubclasses
+	}
+
+	now := time.Now()
+	subclass := &models.AssetSubclass{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		AssetClass: req.AssetClass,
+		Name:       name,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.repos.AssetSubclasses.Insert(ctx, subclass); err != nil {
+		return nil, fmt.Errorf("failed to create asset subclass: %w", err)
+	}
+
+	dataVersions.bump(userID)
+	return subclass, nil
+}
+
+// RenameSubclass renames a subclass. Portfolios refer to subclasses by ID,
+// so they follow the rename.
+func (s *AssetClassService) RenameSubclass(userID, subclassID primitive.ObjectID, name string) error {
+	name, err := normalizeSubclassName(name)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subclass, err := s.repos.AssetSubclasses.FindByID(ctx, userID, subclassID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrAssetSubclassNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find asset subclass: %w", err)
+	}
+
+	subclasses, err := s.repos.AssetSubclasses.FindByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch asset subclasses: %w", err)
+	}
+	for _, sibling := range subclasses {
+		if sibling.ID != subclassID && sibling.AssetClass == subclass.AssetClass && strings.EqualFold(sibling.Name, name) {
+			return ErrDuplicateAssetSubclass
+		}
+	}
+
+	if err := s.repos.AssetSubclasses.Rename(ctx, userID, subclassID, name); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrAssetSubclassNotFound
+		}
+		return fmt.Errorf("failed to rename asset subclass: %w", err)
+	}
+
+	dataVersions.bump(userID)
+	return nil
+}
+
+// DeleteSubclass deletes a subclass. Portfolios in it stay in its asset
+// class without a subclass.
+func (s *AssetClassService) DeleteSubclass(userID, subclassID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := s.repos.Tx.Run(ctx, func(ctx context.Context) error {
+		if err := s.repos.AssetSubclasses.Delete(ctx, userID, subclassID); err != nil {
+			return err
+		}
+		return s.repos.Portfolios.ClearAssetSubclass(ctx, userID, subclassID)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrAssetSubclassNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete asset subclass: %w", err)
+	}
+
+	dataVersions.bump(userID)
+	return nil
+}
+
+// assetClassTree arranges subclasses under their asset classes, listing
+// every asset class in order even without subclasses
+func assetClassTree(subclasses []models.AssetSubclass) []models.AssetClassNode {
+	tree := make([]models.AssetClassNode, len(AssetClasses))
+	index := make(map[string]int, len(AssetClasses))
+	for i, class := range AssetClasses {
+		tree[i] = models.AssetClassNode{Name: class, Subclasses: []models.AssetSubclass{}}
+		index[class] = i
+	}
+	for _, subclass := range subclasses {
+		if i, ok := index[subclass.AssetClass]; ok {
+			tree[i].Subclasses = append(tree[i].Subclasses, subclass)
+		}
+	}
+	return tree
+}
+
+// normalizeSubclassName trims a subclass name and checks its length
+func normalizeSubclassName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || len([]rune(name)) > 50 {
+		return "", ErrInvalidAssetSubclass
+	}
+	return name, nil
+}
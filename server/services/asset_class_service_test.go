@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"stock-portfolio-tracker/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAssetClassTree(t *testing.T) {
+	service, _ := newMemoryPortfolioService()
+	classes := NewAssetClassServiceWithRepos(service.repos)
+	userID := primitive.NewObjectID()
+
+	if _, err := classes.CreateSubclass(userID, models.AssetSubclassRequest{AssetClass: "Stock", Name: "  US   Large Cap "}); err != nil {
+		t.Fatalf("Failed to create subclass: %v", err)
+	}
+	if _, err := classes.CreateSubclass(userID, models.AssetSubclassRequest{AssetClass: "Stock", Name: "us large cap"}); err != ErrDuplicateAssetSubclass {
+		t.Errorf("Expected ErrDuplicateAssetSubclass, got %v", err)
+	}
+	// Names only need to be unique within their asset class
+	if _, err := classes.CreateSubclass(userID, models.AssetSubclassRequest{AssetClass: "ETF", Name: "US Large Cap"}); err != nil {
+		t.Fatalf("Failed to create subclass in another class: %v", err)
+	}
+
+	tree, err := classes.GetTree(userID)
+	if err != nil {
+		t.Fatalf("Failed to get tree: %v", err)
+	}
+	if len(tree) != len(AssetClasses) {
+		t.Fatalf("Expected every asset class in the tree, got %d", len(tree))
+	}
+	if tree[0].Name != "Stock" || len(tree[0].Subclasses) != 1 || tree[0].Subclasses[0].Name != "US Large Cap" {
+		t.Errorf("Expected Stock to hold US Large Cap, got %+v", tree[0])
+	}
+	if tree[2].Name != "Bond" || tree[2].Subclasses == nil || len(tree[2].Subclasses) != 0 {
+		t.Errorf("Expected Bond with an empty subclass list, got %+v", tree[2])
+	}
+}
+
+func TestDeleteAssetSubclassKeepsPortfolioClass(t *testing.T) {
+	service, repos := newMemoryPortfolioService()
+	classes := NewAssetClassServiceWithRepos(repos)
+	userID := primitive.NewObjectID()
+
+	bonds, err := classes.CreateSubclass(userID, models.AssetSubclassRequest{AssetClass: "Bond", Name: "Treasuries"})
+	if err != nil {
+		t.Fatalf("Failed to create subclass: %v", err)
+	}
+	portfolioID, err := service.CreatePortfolioWithMetadata(userID, "AAPL", primitive.NilObjectID, "Stock")
+	if err != nil {
+		t.Fatalf("Failed to create portfolio: %v", err)
+	}
+
+	if err := service.UpdatePortfolioClassification(userID, portfolioID, primitive.NilObjectID, "Stock", bonds.ID); err != ErrAssetSubclassMismatch {
+		t.Errorf("Expected ErrAssetSubclassMismatch, got %v", err)
+	}
+	if err := service.UpdatePortfolioClassification(userID, portfolioID, primitive.NilObjectID, "Bond", primitive.NewObjectID()); err != ErrAssetSubclassNotFound {
+		t.Errorf("Expected ErrAssetSubclassNotFound, got %v", err)
+	}
+	if err := service.UpdatePortfolioClassification(userID, portfolioID, primitive.NilObjectID, "Bond", bonds.ID); err != nil {
+		t.Fatalf("Failed to classify portfolio: %v", err)
+	}
+
+	if err := classes.DeleteSubclass(userID, bonds.ID); err != nil {
+		t.Fatalf("Failed to delete subclass: %v", err)
+	}
+	portfolio, err := service.GetPortfolioWithMetadata(userID, portfolioID)
+	if err != nil {
+		t.Fatalf("Failed to get portfolio: %v", err)
+	}
+	if portfolio.AssetClass != "Bond" || portfolio.AssetSubclassID != nil {
+		t.Errorf("Expected the portfolio to stay in Bond without a subclass, got %q and %v", portfolio.AssetClass, portfolio.AssetSubclassID)
+	}
+	if err := classes.DeleteSubclass(userID, bonds.ID); err != ErrAssetSubclassNotFound {
+		t.Errorf("Expected ErrAssetSubclassNotFound, got %v", err)
+	}
+}
+
+func TestSubclassGroupingWithSubtotals(t *testing.T) {
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 100, "USD").
+		SetQuote("MSFT", "Microsoft", 100, "USD").
+		SetQuote("TLT", "Treasury Bond ETF", 100, "USD")
+	service, portfolioService := newFixtureAnalyticsService(provider)
+	classes := NewAssetClassServiceWithRepos(portfolioService.repos)
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -5)
+
+	largeCap, err := classes.CreateSubclass(userID, models.AssetSubclassRequest{AssetClass: "Stock", Name: "Large Cap"})
+	if err != nil {
+		t.Fatalf("Failed to create subclass: %v", err)
+	}
+	for _, tx := range []*models.Transaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 5, Price: 100, Currency: "USD", Date: date},
+		{Symbol: "MSFT", Action: "buy", Shares: 3, Price: 100, Currency: "USD", Date: date},
+		{Symbol: "TLT", Action: "buy", Shares: 2, Price: 100, Currency: "USD", Date: date},
+	} {
+		if err := portfolioService.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+	aaplID, err := portfolioService.CreatePortfolioWithMetadata(userID, "AAPL", primitive.NilObjectID, "Stock")
+	if err != nil {
+		t.Fatalf("Failed to classify AAPL: %v", err)
+	}
+	if err := portfolioService.UpdatePortfolioClassification(userID, aaplID, primitive.NilObjectID, "Stock", largeCap.ID); err != nil {
+		t.Fatalf("Failed to assign subclass: %v", err)
+	}
+	if _, err := portfolioService.CreatePortfolioWithMetadata(userID, "MSFT", primitive.NilObjectID, "Stock"); err != nil {
+		t.Fatalf("Failed to classify MSFT: %v", err)
+	}
+
+	metrics, err := service.GetGroupedDashboardMetrics(context.Background(), userID, "USD", "assetSubclass")
+	if err != nil {
+		t.Fatalf("Failed to get grouped metrics: %v", err)
+	}
+
+	parents := make(map[string]string)
+	for _, group := range metrics.Groups {
+		parents[group.GroupName] = group.Parent
+	}
+	for name, parent := range map[string]string{"Large Cap": "Stock", "Other": "Stock", "Uncategorized": "Uncategorized"} {
+		if parents[name] != parent {
+			t.Errorf("Expected group %q under %q, got %q", name, parent, parents[name])
+		}
+	}
+	if len(metrics.Subtotals) != 2 {
+		t.Fatalf("Expected 2 subtotals, got %d", len(metrics.Subtotals))
+	}
+	if stock := metrics.Subtotals[0]; stock.GroupName != "Stock" || stock.GroupValue != 800 || stock.Percentage != 80 {
+		t.Errorf("Expected Stock to subtotal 800 (80%%), got %+v", stock)
+	}
+}
@@ -4,13 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"stock-portfolio-tracker/database"
+	"regexp"
 	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var (
@@ -18,36 +18,49 @@ var (
 	ErrAssetStyleInUse     = errors.New("asset style is in use, please provide a replacement style ID")
 	ErrAssetStyleNotFound  = errors.New("asset style not found")
 	ErrDefaultAssetStyle   = errors.New("cannot delete the default asset style")
+	ErrInvalidStyleColor   = errors.New("asset style colors must be hex colors such as #4f46e5")
+	ErrInvalidStyleIcon    = errors.New("asset style icons must be lowercase letters, digits and dashes")
+)
+
+var (
+	styleColorPattern = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+	styleIconPattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
 )
 
 // AssetStyleService handles asset style operations
-type AssetStyleService struct{}
+type AssetStyleService struct {
+	repos repository.Repositories
+}
 
-// NewAssetStyleService creates a new AssetStyleService instance
+// NewAssetStyleService creates a new AssetStyleService instance backed by MongoDB
 func NewAssetStyleService() *AssetStyleService {
-	return &AssetStyleService{}
+	return NewAssetStyleServiceWithRepos(repository.NewMongo())
+}
+
+// NewAssetStyleServiceWithRepos creates an AssetStyleService over the given
+// repositories
+func NewAssetStyleServiceWithRepos(repos repository.Repositories) *AssetStyleService {
+	return &AssetStyleService{repos: repos}
 }
 
 // CreateAssetStyle creates a new asset style for a user
-func (s *AssetStyleService) CreateAssetStyle(userID primitive.ObjectID, name string) (*models.AssetStyle, error) {
+func (s *AssetStyleService) CreateAssetStyle(userID primitive.ObjectID, req models.AssetStyleRequest) (*models.AssetStyle, error) {
+	color, icon, err := normalizeStyleAppearance(req.Color, req.Icon)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("asset_styles")
-
 	// Check if asset style with same name already exists for this user
-	var existing models.AssetStyle
-	err := collection.FindOne(ctx, bson.M{
-		"user_id": userID,
-		"name":    name,
-	}).Decode(&existing)
-
+	_, err = s.repos.AssetStyles.FindByName(ctx, userID, req.Name)
 	if err == nil {
 		// Asset style with this name already exists
 		return nil, ErrDuplicateAssetStyle
 	}
 
-	if err != mongo.ErrNoDocuments {
+	if !errors.Is(err, repository.ErrNotFound) {
 		return nil, fmt.Errorf("failed to check existing asset style: %w", err)
 	}
 
@@ -55,12 +68,14 @@ func (s *AssetStyleService) CreateAssetStyle(userID primitive.ObjectID, name str
 	assetStyle := &models.AssetStyle{
 		ID:        primitive.NewObjectID(),
 		UserID:    userID,
-		Name:      name,
+		Name:      req.Name,
+		Color:     color,
+		Icon:      icon,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	_, err = collection.InsertOne(ctx, assetStyle)
+	err = s.repos.AssetStyles.Insert(ctx, assetStyle)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create asset style: %w", err)
 	}
@@ -73,37 +88,27 @@ func (s *AssetStyleService) GetUserAssetStyles(userID primitive.ObjectID) ([]mod
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("asset_styles")
-
-	cursor, err := collection.Find(ctx, bson.M{"user_id": userID})
+	assetStyles, err := s.repos.AssetStyles.FindByUser(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch asset styles: %w", err)
 	}
-	defer cursor.Close(ctx)
-
-	var assetStyles []models.AssetStyle
-	if err := cursor.All(ctx, &assetStyles); err != nil {
-		return nil, fmt.Errorf("failed to decode asset styles: %w", err)
-	}
 
 	return assetStyles, nil
 }
 
-// UpdateAssetStyle updates an asset style name
-func (s *AssetStyleService) UpdateAssetStyle(userID primitive.ObjectID, styleID primitive.ObjectID, name string) error {
+// UpdateAssetStyle updates an asset style's name, color and icon
+func (s *AssetStyleService) UpdateAssetStyle(userID primitive.ObjectID, styleID primitive.ObjectID, req models.AssetStyleRequest) error {
+	color, icon, err := normalizeStyleAppearance(req.Color, req.Icon)
+	if err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("asset_styles")
-
 	// Check if asset style exists and belongs to user
-	var existing models.AssetStyle
-	err := collection.FindOne(ctx, bson.M{
-		"_id":     styleID,
-		"user_id": userID,
-	}).Decode(&existing)
-
-	if err == mongo.ErrNoDocuments {
+	assetStyle, err := s.repos.AssetStyles.FindByID(ctx, userID, styleID)
+	if errors.Is(err, repository.ErrNotFound) {
 		return ErrAssetStyleNotFound
 	}
 	if err != nil {
@@ -111,43 +116,28 @@ func (s *AssetStyleService) UpdateAssetStyle(userID primitive.ObjectID, styleID
 	}
 
 	// Check if new name conflicts with another asset style
-	var duplicate models.AssetStyle
-	err = collection.FindOne(ctx, bson.M{
-		"user_id": userID,
-		"name":    name,
-		"_id":     bson.M{"$ne": styleID},
-	}).Decode(&duplicate)
-
-	if err == nil {
+	duplicate, err := s.repos.AssetStyles.FindByName(ctx, userID, req.Name)
+	if err == nil && duplicate.ID != styleID {
 		// Another asset style with this name exists
 		return ErrDuplicateAssetStyle
 	}
 
-	if err != mongo.ErrNoDocuments {
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
 		return fmt.Errorf("failed to check duplicate name: %w", err)
 	}
 
 	// Update the asset style
-	update := bson.M{
-		"$set": bson.M{
-			"name":       name,
-			"updated_at": time.Now(),
-		},
+	assetStyle.Name, assetStyle.Color, assetStyle.Icon = req.Name, color, icon
+	assetStyle.UpdatedAt = time.Now()
+	err = s.repos.AssetStyles.Update(ctx, assetStyle)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrAssetStyleNotFound
 	}
-
-	result, err := collection.UpdateOne(ctx, bson.M{
-		"_id":     styleID,
-		"user_id": userID,
-	}, update)
-
 	if err != nil {
 		return fmt.Errorf("failed to update asset style: %w", err)
 	}
 
-	if result.MatchedCount == 0 {
-		return ErrAssetStyleNotFound
-	}
-
+	dataVersions.bump(userID)
 	return nil
 }
 
@@ -156,16 +146,9 @@ func (s *AssetStyleService) DeleteAssetStyle(userID primitive.ObjectID, styleID
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	assetStyleCollection := database.Database.Collection("asset_styles")
-
 	// Check if asset style exists and belongs to user
-	var assetStyle models.AssetStyle
-	err := assetStyleCollection.FindOne(ctx, bson.M{
-		"_id":     styleID,
-		"user_id": userID,
-	}).Decode(&assetStyle)
-
-	if err == mongo.ErrNoDocuments {
+	assetStyle, err := s.repos.AssetStyles.FindByID(ctx, userID, styleID)
+	if errors.Is(err, repository.ErrNotFound) {
 		return ErrAssetStyleNotFound
 	}
 	if err != nil {
@@ -178,7 +161,7 @@ func (s *AssetStyleService) DeleteAssetStyle(userID primitive.ObjectID, styleID
 	}
 
 	// Check usage count
-	usageCount, err := s.GetAssetStyleUsageCount(styleID)
+	usageCount, err := s.GetAssetStyleUsageCount(userID, styleID)
 	if err != nil {
 		return fmt.Errorf("failed to check usage count: %w", err)
 	}
@@ -190,13 +173,8 @@ func (s *AssetStyleService) DeleteAssetStyle(userID primitive.ObjectID, styleID
 		}
 
 		// Verify new style exists and belongs to user
-		var newStyle models.AssetStyle
-		err = assetStyleCollection.FindOne(ctx, bson.M{
-			"_id":     newStyleID,
-			"user_id": userID,
-		}).Decode(&newStyle)
-
-		if err == mongo.ErrNoDocuments {
+		_, err = s.repos.AssetStyles.FindByID(ctx, userID, newStyleID)
+		if errors.Is(err, repository.ErrNotFound) {
 			return fmt.Errorf("replacement asset style not found")
 		}
 		if err != nil {
@@ -204,50 +182,31 @@ func (s *AssetStyleService) DeleteAssetStyle(userID primitive.ObjectID, styleID
 		}
 
 		// Reassign all portfolios to new style
-		portfolioCollection := database.Database.Collection("portfolios")
-		_, err = portfolioCollection.UpdateMany(ctx, bson.M{
-			"user_id":        userID,
-			"asset_style_id": styleID,
-		}, bson.M{
-			"$set": bson.M{
-				"asset_style_id": newStyleID,
-				"updated_at":     time.Now(),
-			},
-		})
-
+		err = s.repos.Portfolios.ReassignAssetStyle(ctx, userID, styleID, newStyleID)
 		if err != nil {
 			return fmt.Errorf("failed to reassign portfolios: %w", err)
 		}
 	}
 
 	// Delete the asset style
-	result, err := assetStyleCollection.DeleteOne(ctx, bson.M{
-		"_id":     styleID,
-		"user_id": userID,
-	})
-
+	err = s.repos.AssetStyles.Delete(ctx, userID, styleID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrAssetStyleNotFound
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete asset style: %w", err)
 	}
 
-	if result.DeletedCount == 0 {
-		return ErrAssetStyleNotFound
-	}
-
+	dataVersions.bump(userID)
 	return nil
 }
 
-// GetAssetStyleUsageCount returns the number of portfolios using this style
-func (s *AssetStyleService) GetAssetStyleUsageCount(styleID primitive.ObjectID) (int64, error) {
+// GetAssetStyleUsageCount returns the number of the user's portfolios using this style
+func (s *AssetStyleService) GetAssetStyleUsageCount(userID primitive.ObjectID, styleID primitive.ObjectID) (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("portfolios")
-
-	count, err := collection.CountDocuments(ctx, bson.M{
-		"asset_style_id": styleID,
-	})
-
+	count, err := s.repos.Portfolios.CountByAssetStyle(ctx, userID, styleID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count portfolios: %w", err)
 	}
@@ -257,7 +216,7 @@ func (s *AssetStyleService) GetAssetStyleUsageCount(styleID primitive.ObjectID)
 
 // CreateDefaultAssetStyle creates the default asset style for a new user
 func (s *AssetStyleService) CreateDefaultAssetStyle(userID primitive.ObjectID) (*models.AssetStyle, error) {
-	return s.CreateAssetStyle(userID, "Default")
+	return s.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Default"})
 }
 
 // GetAssetStyleByID returns an asset style by ID
@@ -265,20 +224,27 @@ func (s *AssetStyleService) GetAssetStyleByID(userID primitive.ObjectID, styleID
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("asset_styles")
-
-	var assetStyle models.AssetStyle
-	err := collection.FindOne(ctx, bson.M{
-		"_id":     styleID,
-		"user_id": userID,
-	}).Decode(&assetStyle)
-
-	if err == mongo.ErrNoDocuments {
+	assetStyle, err := s.repos.AssetStyles.FindByID(ctx, userID, styleID)
+	if errors.Is(err, repository.ErrNotFound) {
 		return nil, ErrAssetStyleNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to find asset style: %w", err)
 	}
 
-	return &assetStyle, nil
+	return assetStyle, nil
+}
+
+// normalizeStyleAppearance validates an asset style's color and icon, both
+// optional, and lower-cases them
+func normalizeStyleAppearance(color, icon string) (string, string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color != "" && !styleColorPattern.MatchString(color) {
+		return "", "", ErrInvalidStyleColor
+	}
+	icon = strings.ToLower(strings.TrimSpace(icon))
+	if icon != "" && !styleIconPattern.MatchString(icon) {
+		return "", "", ErrInvalidStyleIcon
+	}
+	return color, icon, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"stock-portfolio-tracker/database"
 	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
 	"testing"
 	"time"
 
@@ -41,7 +42,7 @@ func TestCreateAssetStyle(t *testing.T) {
 	defer cleanup()
 
 	// Test creating a new asset style
-	assetStyle, err := service.CreateAssetStyle(userID, "Growth Stocks")
+	assetStyle, err := service.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Growth Stocks"})
 	if err != nil {
 		t.Fatalf("Failed to create asset style: %v", err)
 	}
@@ -60,13 +61,13 @@ func TestCreateDuplicateAssetStyle(t *testing.T) {
 	defer cleanup()
 
 	// Create first asset style
-	_, err := service.CreateAssetStyle(userID, "Tech Stocks")
+	_, err := service.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Tech Stocks"})
 	if err != nil {
 		t.Fatalf("Failed to create first asset style: %v", err)
 	}
 
 	// Try to create duplicate
-	_, err = service.CreateAssetStyle(userID, "Tech Stocks")
+	_, err = service.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Tech Stocks"})
 	if err != ErrDuplicateAssetStyle {
 		t.Errorf("Expected ErrDuplicateAssetStyle, got %v", err)
 	}
@@ -79,7 +80,7 @@ func TestGetUserAssetStyles(t *testing.T) {
 	// Create multiple asset styles
 	names := []string{"Growth", "Value", "Dividend"}
 	for _, name := range names {
-		_, err := service.CreateAssetStyle(userID, name)
+		_, err := service.CreateAssetStyle(userID, models.AssetStyleRequest{Name: name})
 		if err != nil {
 			t.Fatalf("Failed to create asset style '%s': %v", name, err)
 		}
@@ -101,13 +102,13 @@ func TestUpdateAssetStyle(t *testing.T) {
 	defer cleanup()
 
 	// Create asset style
-	assetStyle, err := service.CreateAssetStyle(userID, "Old Name")
+	assetStyle, err := service.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Old Name"})
 	if err != nil {
 		t.Fatalf("Failed to create asset style: %v", err)
 	}
 
 	// Update asset style
-	err = service.UpdateAssetStyle(userID, assetStyle.ID, "New Name")
+	err = service.UpdateAssetStyle(userID, assetStyle.ID, models.AssetStyleRequest{Name: "New Name"})
 	if err != nil {
 		t.Fatalf("Failed to update asset style: %v", err)
 	}
@@ -128,12 +129,12 @@ func TestDeleteAssetStyleWithReassignment(t *testing.T) {
 	defer cleanup()
 
 	// Create two asset styles
-	style1, err := service.CreateAssetStyle(userID, "Style 1")
+	style1, err := service.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Style 1"})
 	if err != nil {
 		t.Fatalf("Failed to create style 1: %v", err)
 	}
 
-	style2, err := service.CreateAssetStyle(userID, "Style 2")
+	style2, err := service.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Style 2"})
 	if err != nil {
 		t.Fatalf("Failed to create style 2: %v", err)
 	}
@@ -201,13 +202,13 @@ func TestGetAssetStyleUsageCount(t *testing.T) {
 	defer cleanup()
 
 	// Create asset style
-	assetStyle, err := service.CreateAssetStyle(userID, "Test Style")
+	assetStyle, err := service.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Test Style"})
 	if err != nil {
 		t.Fatalf("Failed to create asset style: %v", err)
 	}
 
 	// Initially should have 0 usage
-	count, err := service.GetAssetStyleUsageCount(assetStyle.ID)
+	count, err := service.GetAssetStyleUsageCount(userID, assetStyle.ID)
 	if err != nil {
 		t.Fatalf("Failed to get usage count: %v", err)
 	}
@@ -238,7 +239,7 @@ func TestGetAssetStyleUsageCount(t *testing.T) {
 	}
 
 	// Should now have 3 usages
-	count, err = service.GetAssetStyleUsageCount(assetStyle.ID)
+	count, err = service.GetAssetStyleUsageCount(userID, assetStyle.ID)
 	if err != nil {
 		t.Fatalf("Failed to get usage count: %v", err)
 	}
@@ -247,3 +248,78 @@ func TestGetAssetStyleUsageCount(t *testing.T) {
 		t.Errorf("Expected usage count 3, got %d", count)
 	}
 }
+
+func TestDeleteAssetStyleWithMemoryRepos(t *testing.T) {
+	repos := repository.NewMemory()
+	service := NewAssetStyleServiceWithRepos(repos)
+	userID := primitive.NewObjectID()
+
+	defaultStyle, err := service.CreateDefaultAssetStyle(userID)
+	if err != nil {
+		t.Fatalf("Failed to create default style: %v", err)
+	}
+	growth, err := service.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Growth"})
+	if err != nil {
+		t.Fatalf("Failed to create style: %v", err)
+	}
+	if _, err := service.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Growth"}); err != ErrDuplicateAssetStyle {
+		t.Errorf("Expected ErrDuplicateAssetStyle, got %v", err)
+	}
+	if err := service.UpdateAssetStyle(userID, growth.ID, models.AssetStyleRequest{Name: "Default"}); err != ErrDuplicateAssetStyle {
+		t.Errorf("Expected ErrDuplicateAssetStyle renaming onto another style, got %v", err)
+	}
+	if err := service.UpdateAssetStyle(userID, growth.ID, models.AssetStyleRequest{Name: "Growth"}); err != nil {
+		t.Errorf("Expected renaming a style to its own name to succeed, got %v", err)
+	}
+
+	ctx := context.Background()
+	portfolio := &models.Portfolio{ID: primitive.NewObjectID(), UserID: userID, Symbol: "AAPL", AssetStyleID: &growth.ID}
+	repos.Portfolios.Insert(ctx, portfolio)
+
+	if err := service.DeleteAssetStyle(userID, defaultStyle.ID, primitive.NilObjectID); err != ErrDefaultAssetStyle {
+		t.Errorf("Expected ErrDefaultAssetStyle, got %v", err)
+	}
+	if err := service.DeleteAssetStyle(userID, growth.ID, primitive.NilObjectID); err != ErrAssetStyleInUse {
+		t.Errorf("Expected ErrAssetStyleInUse, got %v", err)
+	}
+	if err := service.DeleteAssetStyle(userID, growth.ID, defaultStyle.ID); err != nil {
+		t.Fatalf("Failed to delete style: %v", err)
+	}
+
+	updated, _ := repos.Portfolios.FindByID(ctx, userID, portfolio.ID)
+	if updated.AssetStyleID == nil || *updated.AssetStyleID != defaultStyle.ID {
+		t.Errorf("Expected portfolio to be reassigned to the default style")
+	}
+	if _, err := service.GetAssetStyleByID(userID, growth.ID); err != ErrAssetStyleNotFound {
+		t.Errorf("Expected ErrAssetStyleNotFound, got %v", err)
+	}
+}
+
+func TestAssetStyleAppearance(t *testing.T) {
+	service := NewAssetStyleServiceWithRepos(repository.NewMemory())
+	userID := primitive.NewObjectID()
+
+	style, err := service.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Growth", Color: "#4F46E5", Icon: "Trending-Up"})
+	if err != nil {
+		t.Fatalf("Failed to create style: %v", err)
+	}
+	if style.Color != "#4f46e5" || style.Icon != "trending-up" {
+		t.Errorf("Expected lower-cased color and icon, got %q and %q", style.Color, style.Icon)
+	}
+
+	if _, err := service.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Value", Color: "blue"}); err != ErrInvalidStyleColor {
+		t.Errorf("Expected ErrInvalidStyleColor, got %v", err)
+	}
+	if err := service.UpdateAssetStyle(userID, style.ID, models.AssetStyleRequest{Name: "Growth", Icon: "chart up"}); err != ErrInvalidStyleIcon {
+		t.Errorf("Expected ErrInvalidStyleIcon, got %v", err)
+	}
+
+	// Updates replace the appearance
+	if err := service.UpdateAssetStyle(userID, style.ID, models.AssetStyleRequest{Name: "Growth", Color: "#0f0"}); err != nil {
+		t.Fatalf("Failed to update style: %v", err)
+	}
+	updated, _ := service.GetAssetStyleByID(userID, style.ID)
+	if updated.Color != "#0f0" || updated.Icon != "" {
+		t.Errorf("Expected the new color and no icon, got %q and %q", updated.Color, updated.Icon)
+	}
+}
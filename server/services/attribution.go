@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/currencycode"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HoldingAttribution describes one symbol's share of the portfolio's period return
+type HoldingAttribution struct {
+	Symbol        string  `json:"symbol"`
+	Name          string  `json:"name"`
+	AverageWeight float64 `json:"averageWeight"` // Percentage of the portfolio, averaged over days held
+	Return        float64 `json:"return"`        // Time-weighted price return while held, percentage
+	Contribution  float64 `json:"contribution"`  // Percentage points of the portfolio return
+	Gain          float64 `json:"gain"`          // Price gain in the requested currency
+}
+
+// AttributionResponse decomposes the portfolio's period return into per-symbol
+// contributions that sum to the total return
+type AttributionResponse struct {
+	Currency    string               `json:"currency"`
+	Period      string               `json:"period"`
+	StartDate   time.Time            `json:"startDate"`
+	EndDate     time.Time            `json:"endDate"`
+	TotalReturn float64              `json:"totalReturn"` // Time-weighted, percentage
+	TotalGain   float64              `json:"totalGain"`
+	Holdings    []HoldingAttribution `json:"holdings"`
+}
+
+// attributionDay holds each symbol's value at the start of a day and its price
+// gain over the day, indexed by symbol
+type attributionDay struct {
+	startValues []float64
+	gains       []float64
+}
+
+// GetAttribution decomposes the portfolio's return over a period into each
+// symbol's contribution (weight × return), following the same transaction
+// history as the performance series. Trades are treated as happening at the
+// close, so a day's gain belongs to the shares held at the start of the day
+// and contributions are unaffected by deposits and withdrawals. Prices are
+// fetched under ctx.
+func (s *AnalyticsService) GetAttribution(ctx context.Context, userID primitive.ObjectID, period string, currency string) (*AttributionResponse, error) {
+	currency = currencycode.Normalize(currency)
+
+	dates, cursors, err := s.loadSeriesCursors(ctx, userID, period, currency, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AttributionResponse{
+		Currency: currency,
+		Period:   period,
+		Holdings: []HoldingAttribution{},
+	}
+	if len(dates) == 0 || len(cursors) == 0 {
+		return response, nil
+	}
+	response.StartDate = dates[0]
+	response.EndDate = dates[len(dates)-1]
+
+	// The first date only establishes opening positions and prices
+	days := make([]attributionDay, 0, len(dates)-1)
+	for i, date := range dates {
+		day := attributionDay{
+			startValues: make([]float64, len(cursors)),
+			gains:       make([]float64, len(cursors)),
+		}
+		for j, cursor := range cursors {
+			shares, price := cursor.shares, cursor.price
+			cursor.valueAt(date)
+			if shares > 0 && price > 0 && cursor.price > 0 {
+				day.startValues[j] = shares * price * cursor.rate
+				day.gains[j] = shares * (cursor.price - price) * cursor.rate
+			}
+		}
+		if i > 0 {
+			days = append(days, day)
+		}
+	}
+
+	contributions, weights, returns, total := linkAttribution(days, len(cursors))
+	response.TotalReturn = total * 100
+
+	// Names are only known for symbols still held
+	names := make(map[string]string)
+	if holdings, err := s.portfolioService.GetUserHoldingsContext(ctx, userID, currency); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		fmt.Printf("[Analytics] Warning: Could not get holdings for attribution names: %v\n", err)
+	} else {
+		for _, holding := range holdings {
+			names[holding.Symbol] = holding.Name
+		}
+	}
+
+	for j, cursor := range cursors {
+		gain := 0.0
+		for _, day := range days {
+			gain += day.gains[j]
+		}
+		if weights[j] == 0 && gain == 0 {
+			continue
+		}
+		response.TotalGain += gain
+		response.Holdings = append(response.Holdings, HoldingAttribution{
+			Symbol:        cursor.symbol,
+			Name:          names[cursor.symbol],
+			AverageWeight: weights[j] * 100,
+			Return:        returns[j] * 100,
+			Contribution:  contributions[j] * 100,
+			Gain:          gain,
+		})
+	}
+
+	sort.Slice(response.Holdings, func(i, j int) bool {
+		if response.Holdings[i].Contribution != response.Holdings[j].Contribution {
+			return response.Holdings[i].Contribution > response.Holdings[j].Contribution
+		}
+		return response.Holdings[i].Symbol < response.Holdings[j].Symbol
+	})
+
+	return response, nil
+}
+
+// linkAttribution links daily weight × return contributions over a period.
+// Each day's contribution is scaled by the portfolio's growth before that day,
+// so the symbol contributions sum exactly to the compounded total return. It
+// also returns each symbol's average weight and compounded return over the
+// days it was held. All results are fractions.
+func linkAttribution(days []attributionDay, symbols int) (contributions []float64, weights []float64, returns []float64, total float64) {
+	contributions = make([]float64, symbols)
+	weights = make([]float64, symbols)
+	returns = make([]float64, symbols)
+
+	growth := make([]float64, symbols)
+	heldDays := make([]int, symbols)
+	for j := range growth {
+		growth[j] = 1
+	}
+
+	cumulative := 1.0
+	for _, day := range days {
+		startValue, gain := 0.0, 0.0
+		for j := 0; j < symbols; j++ {
+			startValue += day.startValues[j]
+			gain += day.gains[j]
+		}
+		if startValue <= 0 {
+			continue
+		}
+
+		for j := 0; j < symbols; j++ {
+			if day.startValues[j] <= 0 {
+				continue
+			}
+			contributions[j] += cumulative * day.gains[j] / startValue
+			weights[j] += day.startValues[j] / startValue
+			growth[j] *= 1 + day.gains[j]/day.startValues[j]
+			heldDays[j]++
+		}
+		cumulative *= 1 + gain/startValue
+	}
+
+	for j := 0; j < symbols; j++ {
+		if heldDays[j] > 0 {
+			weights[j] /= float64(heldDays[j])
+			returns[j] = growth[j] - 1
+		}
+	}
+	return contributions, weights, returns, cumulative - 1
+}
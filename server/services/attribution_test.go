@@ -0,0 +1,38 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLinkAttribution(t *testing.T) {
+	days := []attributionDay{
+		// A: 100 -> 110, B: 100 -> 90
+		{startValues: []float64{100, 100}, gains: []float64{10, -10}},
+		// B was sold; A: 110 -> 132 with a 50 deposit sitting in it as new shares
+		{startValues: []float64{160, 0}, gains: []float64{32, 0}},
+	}
+
+	contributions, weights, returns, total := linkAttribution(days, 2)
+
+	// Day one is flat, day two returns 20%
+	if math.Abs(total-0.2) > 1e-9 {
+		t.Errorf("Expected total return 0.2, got %v", total)
+	}
+	if math.Abs(contributions[0]+contributions[1]-total) > 1e-9 {
+		t.Errorf("Expected contributions %v to sum to %v", contributions, total)
+	}
+	if math.Abs(contributions[0]-0.25) > 1e-9 || math.Abs(contributions[1]+0.05) > 1e-9 {
+		t.Errorf("Unexpected contributions %v", contributions)
+	}
+	if math.Abs(weights[0]-0.75) > 1e-9 || math.Abs(weights[1]-0.5) > 1e-9 {
+		t.Errorf("Unexpected weights %v", weights)
+	}
+	if math.Abs(returns[0]-0.32) > 1e-9 || math.Abs(returns[1]+0.1) > 1e-9 {
+		t.Errorf("Unexpected returns %v", returns)
+	}
+
+	if _, _, _, total := linkAttribution(nil, 0); total != 0 {
+		t.Errorf("Expected no return without days, got %v", total)
+	}
+}
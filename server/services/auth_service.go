@@ -4,43 +4,55 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
-	"stock-portfolio-tracker/database"
 	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
 	"time"
+	"unicode/utf8"
 
 	"github.com/golang-jwt/jwt/v5"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
 const (
 	bcryptCost       = 10
 	tokenExpiration  = 24 * time.Hour
-	usersCollection  = "users"
+	// sessionTouchInterval limits how often a session's last-seen time is
+	// written as requests come in
+	sessionTouchInterval = time.Minute
+	// maxUserAgentLength caps the user agent stored with a session
+	maxUserAgentLength = 256
 )
 
 var (
 	ErrUserExists       = errors.New("user with this email already exists")
 	ErrInvalidCredentials = errors.New("invalid email or password")
 	ErrInvalidToken     = errors.New("invalid or expired token")
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrWeakPassword     = errors.New("password must be at least 8 characters long")
 )
 
 // AuthService handles authentication operations
 type AuthService struct {
 	jwtSecret []byte
+	repos     repository.Repositories
 }
 
-// NewAuthService creates a new AuthService instance
-func NewAuthService() *AuthService {
-	secret := os.Getenv("JWT_SECRET")
+// NewAuthService creates a new AuthService instance signing tokens with the
+// secret and storing users in MongoDB
+func NewAuthService(secret string) *AuthService {
+	return NewAuthServiceWithRepos(secret, repository.NewMongo())
+}
+
+// NewAuthServiceWithRepos creates an AuthService over the given repositories
+func NewAuthServiceWithRepos(secret string, repos repository.Repositories) *AuthService {
 	if secret == "" {
-		panic("JWT_SECRET environment variable is required")
+		panic("JWT secret is required")
 	}
 	return &AuthService{
 		jwtSecret: []byte(secret),
+		repos:     repos,
 	}
 }
 
@@ -49,15 +61,12 @@ func (s *AuthService) Register(email, password string) (*models.User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection(usersCollection)
-
 	// Check if user already exists
-	var existingUser models.User
-	err := collection.FindOne(ctx, bson.M{"email": email}).Decode(&existingUser)
+	_, err := s.repos.Users.FindByEmail(ctx, email)
 	if err == nil {
 		return nil, ErrUserExists
 	}
-	if err != mongo.ErrNoDocuments {
+	if !errors.Is(err, repository.ErrNotFound) {
 		return nil, fmt.Errorf("failed to check existing user: %w", err)
 	}
 
@@ -76,13 +85,13 @@ func (s *AuthService) Register(email, password string) (*models.User, error) {
 		UpdatedAt: time.Now(),
 	}
 
-	_, err = collection.InsertOne(ctx, user)
+	err = s.repos.Users.Insert(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
 	// Create default asset style for new user
-	assetStyleService := NewAssetStyleService()
+	assetStyleService := NewAssetStyleServiceWithRepos(s.repos)
 	_, err = assetStyleService.CreateDefaultAssetStyle(user.ID)
 	if err != nil {
 		// Log error but don't fail user creation
@@ -92,18 +101,16 @@ func (s *AuthService) Register(email, password string) (*models.User, error) {
 	return user, nil
 }
 
-// Login validates credentials and returns a JWT token
-func (s *AuthService) Login(email, password string) (string, error) {
+// Login validates credentials and returns a JWT token for a new session on
+// the device
+func (s *AuthService) Login(email, password string, device models.SessionDevice) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection(usersCollection)
-
 	// Find user by email
-	var user models.User
-	err := collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	user, err := s.repos.Users.FindByEmail(ctx, email)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if errors.Is(err, repository.ErrNotFound) {
 			return "", ErrInvalidCredentials
 		}
 		return "", fmt.Errorf("failed to find user: %w", err)
@@ -114,23 +121,53 @@ func (s *AuthService) Login(email, password string) (string, error) {
 		return "", ErrInvalidCredentials
 	}
 
-	// Generate JWT token
-	token, err := s.GenerateToken(user.ID)
+	return s.StartSession(user.ID, device)
+}
+
+// StartSession records a new session for the user on the device and returns
+// a JWT token bound to it
+func (s *AuthService) StartSession(userID primitive.ObjectID, device models.SessionDevice) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	session := &models.Session{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		UserAgent:  truncateUserAgent(device.UserAgent),
+		IP:         device.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(tokenExpiration),
+	}
+	if err := s.repos.Sessions.Insert(ctx, session); err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	token, err := s.signToken(jwt.MapClaims{
+		"user_id": userID.Hex(),
+		"sid":     session.ID.Hex(),
+		"exp":     session.ExpiresAt.Unix(),
+		"iat":     now.Unix(),
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to generate token: %w", err)
 	}
-
 	return token, nil
 }
 
-// GenerateToken creates a JWT token for the given user ID
+// GenerateToken creates a JWT token for the given user ID that is not bound
+// to a session and so can't be revoked before it expires
 func (s *AuthService) GenerateToken(userID primitive.ObjectID) (string, error) {
-	claims := jwt.MapClaims{
+	return s.signToken(jwt.MapClaims{
 		"user_id": userID.Hex(),
 		"exp":     time.Now().Add(tokenExpiration).Unix(),
 		"iat":     time.Now().Unix(),
-	}
+	})
+}
 
+// signToken signs the claims with the service's secret
+func (s *AuthService) signToken(claims jwt.MapClaims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString(s.jwtSecret)
 	if err != nil {
@@ -140,8 +177,64 @@ func (s *AuthService) GenerateToken(userID primitive.ObjectID) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken validates a JWT token and returns the user
+// ValidateToken validates a JWT token and returns the user. Tokens of a
+// revoked session are rejected.
 func (s *AuthService) ValidateToken(tokenString string) (*models.User, error) {
+	user, _, err := s.authenticate(tokenString, nil)
+	return user, err
+}
+
+// Authenticate validates a JWT token used from the device and returns the
+// user and the token's session ID, which is zero for tokens issued without a
+// session. The session's last-seen time is updated at most once per
+// sessionTouchInterval, or sooner when the device's IP changes.
+func (s *AuthService) Authenticate(tokenString string, device models.SessionDevice) (*models.User, primitive.ObjectID, error) {
+	return s.authenticate(tokenString, &device)
+}
+
+func (s *AuthService) authenticate(tokenString string, device *models.SessionDevice) (*models.User, primitive.ObjectID, error) {
+	userID, sessionID, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !sessionID.IsZero() {
+		session, err := s.repos.Sessions.FindByID(ctx, userID, sessionID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, primitive.NilObjectID, ErrInvalidToken
+			}
+			return nil, primitive.NilObjectID, fmt.Errorf("failed to find session: %w", err)
+		}
+		now := time.Now()
+		if session.RevokedAt != nil || !session.ExpiresAt.After(now) {
+			return nil, primitive.NilObjectID, ErrInvalidToken
+		}
+		if device != nil && (now.Sub(session.LastSeenAt) >= sessionTouchInterval || session.IP != device.IP) {
+			// A failed touch only leaves the session looking older
+			if err := s.repos.Sessions.Touch(ctx, userID, sessionID, device.IP, now); err != nil {
+				fmt.Printf("Warning: Failed to update session %s: %v\n", sessionID.Hex(), err)
+			}
+		}
+	}
+
+	user, err := s.repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, primitive.NilObjectID, ErrInvalidToken
+		}
+		return nil, primitive.NilObjectID, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return user, sessionID, nil
+}
+
+// parseToken verifies a JWT token and returns the user and session IDs it
+// carries
+func (s *AuthService) parseToken(tokenString string) (primitive.ObjectID, primitive.ObjectID, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// Verify signing method
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -150,45 +243,127 @@ func (s *AuthService) ValidateToken(tokenString string) (*models.User, error) {
 		return s.jwtSecret, nil
 	})
 
-	if err != nil {
-		return nil, ErrInvalidToken
-	}
-
-	if !token.Valid {
-		return nil, ErrInvalidToken
+	if err != nil || !token.Valid {
+		return primitive.NilObjectID, primitive.NilObjectID, ErrInvalidToken
 	}
 
 	// Extract user ID from claims
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return nil, ErrInvalidToken
+		return primitive.NilObjectID, primitive.NilObjectID, ErrInvalidToken
 	}
 
 	userIDStr, ok := claims["user_id"].(string)
 	if !ok {
-		return nil, ErrInvalidToken
+		return primitive.NilObjectID, primitive.NilObjectID, ErrInvalidToken
 	}
 
 	userID, err := primitive.ObjectIDFromHex(userIDStr)
 	if err != nil {
-		return nil, ErrInvalidToken
+		return primitive.NilObjectID, primitive.NilObjectID, ErrInvalidToken
+	}
+
+	// Tokens issued before sessions were tracked carry no session ID
+	sessionID := primitive.NilObjectID
+	if sid, ok := claims["sid"].(string); ok {
+		sessionID, err = primitive.ObjectIDFromHex(sid)
+		if err != nil {
+			return primitive.NilObjectID, primitive.NilObjectID, ErrInvalidToken
+		}
 	}
 
-	// Fetch user from database
+	return userID, sessionID, nil
+}
+
+// ListSessions returns the user's active sessions, most recently used first,
+// marking the one with the current session ID
+func (s *AuthService) ListSessions(userID, currentID primitive.ObjectID) ([]models.Session, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection(usersCollection)
-	var user models.User
-	err = collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	sessions, err := s.repos.Sessions.FindActive(ctx, userID, time.Now())
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, ErrInvalidToken
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions, so its token stops working
+func (s *AuthService) RevokeSession(userID, sessionID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.repos.Sessions.Revoke(ctx, userID, sessionID, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrSessionNotFound
 		}
-		return nil, fmt.Errorf("failed to find user: %w", err)
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// RevokeOtherSessions ends every session of the user except the current one
+// and returns how many were revoked
+func (s *AuthService) RevokeOtherSessions(userID, currentID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	revoked, err := s.repos.Sessions.RevokeAllExcept(ctx, userID, currentID, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
 	}
+	return revoked, nil
+}
 
-	return &user, nil
+// ResetPassword replaces the password of the user with the email and revokes
+// all of their sessions, so tokens issued under the old password stop
+// working. It returns how many sessions were revoked.
+func (s *AuthService) ResetPassword(email, password string) (int64, error) {
+	if len(password) < 8 {
+		return 0, ErrWeakPassword
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := s.repos.Users.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return 0, ErrUserNotFound
+		}
+		return 0, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	hashedPassword, err := s.HashPassword(password)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hash password: %w", err)
+	}
+	now := time.Now()
+	if err := s.repos.Users.SetPassword(ctx, user.ID, hashedPassword, now); err != nil {
+		return 0, fmt.Errorf("failed to update password: %w", err)
+	}
+
+	revoked, err := s.repos.Sessions.RevokeAllExcept(ctx, user.ID, primitive.NilObjectID, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return revoked, nil
+}
+
+// truncateUserAgent shortens a user agent to maxUserAgentLength bytes
+// without splitting a UTF-8 character
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) <= maxUserAgentLength {
+		return userAgent
+	}
+	cut := maxUserAgentLength
+	for cut > 0 && !utf8.RuneStart(userAgent[cut]) {
+		cut--
+	}
+	return userAgent[:cut]
 }
 
 // HashPassword hashes a password using bcrypt
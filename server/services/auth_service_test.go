@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"testing"
+)
+
+func TestSessionsCanBeListedAndRevoked(t *testing.T) {
+	service := NewAuthServiceWithRepos("test-secret", repository.NewMemory())
+	user, err := service.Register("sessions@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Failed to register user: %v", err)
+	}
+
+	laptop := models.SessionDevice{UserAgent: "Laptop", IP: "10.0.0.1"}
+	phone := models.SessionDevice{UserAgent: strings.Repeat("p", 300), IP: "10.0.0.2"}
+	laptopToken, err := service.Login("sessions@example.com", "password123", laptop)
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	phoneToken, err := service.Login("sessions@example.com", "password123", phone)
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+
+	_, laptopSession, err := service.Authenticate(laptopToken, laptop)
+	if err != nil {
+		t.Fatalf("Expected laptop token to be valid: %v", err)
+	}
+	_, phoneSession, err := service.Authenticate(phoneToken, phone)
+	if err != nil {
+		t.Fatalf("Expected phone token to be valid: %v", err)
+	}
+
+	sessions, err := service.ListSessions(user.ID, laptopSession)
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+	}
+	for _, session := range sessions {
+		if session.Current != (session.ID == laptopSession) {
+			t.Errorf("Expected only the laptop session to be current, got %+v", session)
+		}
+		if len(session.UserAgent) > maxUserAgentLength {
+			t.Errorf("Expected user agent to be truncated, got %d bytes", len(session.UserAgent))
+		}
+	}
+
+	if err := service.RevokeSession(user.ID, phoneSession); err != nil {
+		t.Fatalf("Failed to revoke session: %v", err)
+	}
+	if _, err := service.ValidateToken(phoneToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected revoked token to be rejected, got %v", err)
+	}
+	if err := service.RevokeSession(user.ID, phoneSession); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected revoking twice to fail, got %v", err)
+	}
+	if _, err := service.ValidateToken(laptopToken); err != nil {
+		t.Errorf("Expected other sessions to stay valid, got %v", err)
+	}
+}
+
+func TestRevokeOtherSessionsKeepsCurrent(t *testing.T) {
+	service := NewAuthServiceWithRepos("test-secret", repository.NewMemory())
+	user, err := service.Register("others@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Failed to register user: %v", err)
+	}
+
+	device := models.SessionDevice{UserAgent: "Browser", IP: "10.0.0.1"}
+	tokens := make([]string, 3)
+	for i := range tokens {
+		if tokens[i], err = service.StartSession(user.ID, device); err != nil {
+			t.Fatalf("Failed to start session: %v", err)
+		}
+	}
+	// Tokens issued without a session stay valid until they expire
+	legacyToken, err := service.GenerateToken(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	_, current, err := service.Authenticate(tokens[0], device)
+	if err != nil {
+		t.Fatalf("Expected token to be valid: %v", err)
+	}
+	revoked, err := service.RevokeOtherSessions(user.ID, current)
+	if err != nil {
+		t.Fatalf("Failed to revoke sessions: %v", err)
+	}
+	if revoked != 2 {
+		t.Errorf("Expected 2 sessions revoked, got %d", revoked)
+	}
+
+	if _, err := service.ValidateToken(tokens[0]); err != nil {
+		t.Errorf("Expected current session to stay valid, got %v", err)
+	}
+	for _, token := range tokens[1:] {
+		if _, err := service.ValidateToken(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Expected other session to be rejected, got %v", err)
+		}
+	}
+	if _, err := service.ValidateToken(legacyToken); err != nil {
+		t.Errorf("Expected token without session to stay valid, got %v", err)
+	}
+}
+
+func TestResetPasswordRevokesSessions(t *testing.T) {
+	service := NewAuthServiceWithRepos("test-secret", repository.NewMemory())
+	if _, err := service.Register("reset@example.com", "password123"); err != nil {
+		t.Fatalf("Failed to register user: %v", err)
+	}
+	device := models.SessionDevice{UserAgent: "Laptop", IP: "10.0.0.1"}
+	token, err := service.Login("reset@example.com", "password123", device)
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+
+	if _, err := service.ResetPassword("reset@example.com", "short"); !errors.Is(err, ErrWeakPassword) {
+		t.Errorf("Expected ErrWeakPassword, got %v", err)
+	}
+	if _, err := service.ResetPassword("nobody@example.com", "new-password"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+
+	revoked, err := service.ResetPassword("reset@example.com", "new-password")
+	if err != nil {
+		t.Fatalf("Failed to reset password: %v", err)
+	}
+	if revoked != 1 {
+		t.Errorf("Expected 1 session revoked, got %d", revoked)
+	}
+	if _, _, err := service.Authenticate(token, device); err == nil {
+		t.Errorf("Expected the old session's token to stop working")
+	}
+	if _, err := service.Login("reset@example.com", "password123", device); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected the old password to be rejected, got %v", err)
+	}
+	if _, err := service.Login("reset@example.com", "new-password", device); err != nil {
+		t.Errorf("Expected the new password to work, got %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrBacktestJobNotFound    = errors.New("backtest job not found")
+	ErrBacktestJobNotComplete = errors.New("backtest job has not completed")
+	ErrBacktestJobFailed      = errors.New("backtest job failed")
+	ErrTooManyBacktestJobs    = errors.New("too many active backtest jobs")
+)
+
+// BacktestJobStatus represents the lifecycle state of a backtest job
+type BacktestJobStatus string
+
+const (
+	BacktestJobPending   BacktestJobStatus = "pending"
+	BacktestJobRunning   BacktestJobStatus = "running"
+	BacktestJobCompleted BacktestJobStatus = "completed"
+	BacktestJobFailed    BacktestJobStatus = "failed"
+)
+
+const (
+	// maxActiveBacktestJobsPerUser bounds the pending and running jobs of a single user
+	maxActiveBacktestJobsPerUser = 3
+	// backtestJobRetention is how long finished jobs and their results are kept
+	backtestJobRetention = time.Hour
+)
+
+// BacktestJob represents an asynchronous backtest and its current status
+type BacktestJob struct {
+	ID          string             `json:"id"`
+	UserID      primitive.ObjectID `json:"-"`
+	Status      BacktestJobStatus  `json:"status"`
+	StartDate   time.Time          `json:"startDate"`
+	EndDate     time.Time          `json:"endDate"`
+	Currency    string             `json:"currency"`
+	Benchmark   string             `json:"benchmark,omitempty"`
+	Costs       BacktestCosts      `json:"costs"`
+	Error       string             `json:"error,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	StartedAt   *time.Time         `json:"startedAt,omitempty"`
+	CompletedAt *time.Time         `json:"completedAt,omitempty"`
+
+	result *BacktestResponse
+}
+
+// active reports whether the job is still waiting or running
+func (j *BacktestJob) active() bool {
+	return j.Status == BacktestJobPending || j.Status == BacktestJobRunning
+}
+
+// BacktestJobService runs backtests in the background and tracks their results
+type BacktestJobService struct {
+	backtestService *BacktestService
+	jobs            map[string]*BacktestJob
+	jobsMutex       sync.RWMutex
+	slots           chan struct{}
+}
+
+// NewBacktestJobService creates a new BacktestJobService that runs at most
+// maxConcurrent backtests at a time
+func NewBacktestJobService(backtestService *BacktestService, maxConcurrent int) *BacktestJobService {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &BacktestJobService{
+		backtestService: backtestService,
+		jobs:            make(map[string]*BacktestJob),
+		slots:           make(chan struct{}, maxConcurrent),
+	}
+}
+
+// SubmitJob validates the backtest parameters and queues the backtest for background execution
+func (s *BacktestJobService) SubmitJob(
+	userID primitive.ObjectID,
+	startDate time.Time,
+	endDate time.Time,
+	currency string,
+	benchmark string,
+	costs BacktestCosts,
+) (*BacktestJob, error) {
+	if err := s.backtestService.validateBacktestParams(startDate, endDate, currency); err != nil {
+		return nil, err
+	}
+	if err := costs.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.jobsMutex.Lock()
+	activeJobs := 0
+	for _, job := range s.jobs {
+		if job.UserID == userID && job.active() {
+			activeJobs++
+		}
+	}
+	if activeJobs >= maxActiveBacktestJobsPerUser {
+		s.jobsMutex.Unlock()
+		return nil, ErrTooManyBacktestJobs
+	}
+
+	job := &BacktestJob{
+		ID:        primitive.NewObjectID().Hex(),
+		UserID:    userID,
+		Status:    BacktestJobPending,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Currency:  currency,
+		Benchmark: benchmark,
+		Costs:     costs,
+		CreatedAt: time.Now(),
+	}
+	s.jobs[job.ID] = job
+	snapshot := *job
+	s.jobsMutex.Unlock()
+
+	fmt.Printf("[BacktestJob] Queued job %s for user %s\n", job.ID, userID.Hex())
+	go s.runJob(job)
+
+	return &snapshot, nil
+}
+
+// runJob waits for a free slot and executes the backtest
+func (s *BacktestJobService) runJob(job *BacktestJob) {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	s.jobsMutex.Lock()
+	startedAt := time.Now()
+	job.Status = BacktestJobRunning
+	job.StartedAt = &startedAt
+	s.jobsMutex.Unlock()
+
+	result, err := s.executeBacktest(job)
+
+	s.jobsMutex.Lock()
+	defer s.jobsMutex.Unlock()
+
+	completedAt := time.Now()
+	job.CompletedAt = &completedAt
+	if err != nil {
+		fmt.Printf("[BacktestJob] Job %s failed: %v\n", job.ID, err)
+		job.Status = BacktestJobFailed
+		job.Error = err.Error()
+		return
+	}
+
+	fmt.Printf("[BacktestJob] Job %s completed in %s\n", job.ID, completedAt.Sub(startedAt))
+	job.Status = BacktestJobCompleted
+	job.result = result
+}
+
+// executeBacktest runs the backtest, converting a panic into a job failure
+func (s *BacktestJobService) executeBacktest(job *BacktestJob) (result *BacktestResponse, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("backtest panicked: %v", r)
+		}
+	}()
+
+	return s.backtestService.RunBacktest(context.Background(), job.UserID, job.StartDate, job.EndDate, job.Currency, job.Benchmark, job.Costs)
+}
+
+// GetJob returns a snapshot of the user's job status
+func (s *BacktestJobService) GetJob(userID primitive.ObjectID, jobID string) (*BacktestJob, error) {
+	s.jobsMutex.RLock()
+	defer s.jobsMutex.RUnlock()
+
+	job, exists := s.jobs[jobID]
+	if !exists || job.UserID != userID {
+		return nil, ErrBacktestJobNotFound
+	}
+
+	snapshot := *job
+	snapshot.result = nil
+	return &snapshot, nil
+}
+
+// GetJobResult returns the result of a completed job.
+// The returned value is shared with the job and must not be modified.
+func (s *BacktestJobService) GetJobResult(userID primitive.ObjectID, jobID string) (*BacktestResponse, error) {
+	s.jobsMutex.RLock()
+	defer s.jobsMutex.RUnlock()
+
+	job, exists := s.jobs[jobID]
+	if !exists || job.UserID != userID {
+		return nil, ErrBacktestJobNotFound
+	}
+
+	switch job.Status {
+	case BacktestJobCompleted:
+		return job.result, nil
+	case BacktestJobFailed:
+		return nil, fmt.Errorf("%w: %s", ErrBacktestJobFailed, job.Error)
+	default:
+		return nil, ErrBacktestJobNotComplete
+	}
+}
+
+// cleanupFinishedJobs removes finished jobs past the retention period
+func (s *BacktestJobService) cleanupFinishedJobs() {
+	s.jobsMutex.Lock()
+	defer s.jobsMutex.Unlock()
+
+	cutoff := time.Now().Add(-backtestJobRetention)
+	for id, job := range s.jobs {
+		if job.CompletedAt != nil && job.CompletedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// StartCleanup starts a background goroutine to periodically remove expired jobs
+func (s *BacktestJobService) StartCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			s.cleanupFinishedJobs()
+		}
+	}()
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSubmitBacktestJobValidatesParams(t *testing.T) {
+	service := NewBacktestJobService(&BacktestService{}, 1)
+	userID := primitive.NewObjectID()
+
+	endDate := time.Now().AddDate(0, -1, 0)
+	startDate := endDate.AddDate(-1, 0, 0)
+
+	if _, err := service.SubmitJob(userID, startDate, endDate, "XYZ", "", BacktestCosts{}); err == nil {
+		t.Error("Expected error for invalid currency")
+	}
+
+	if _, err := service.SubmitJob(userID, endDate, startDate, "USD", "", BacktestCosts{}); err == nil {
+		t.Error("Expected error for start date after end date")
+	}
+
+	if len(service.jobs) != 0 {
+		t.Errorf("Expected no jobs to be queued, got %d", len(service.jobs))
+	}
+}
+
+func TestBacktestJobLookupIsScopedToUser(t *testing.T) {
+	service := NewBacktestJobService(&BacktestService{}, 1)
+	owner := primitive.NewObjectID()
+
+	completedAt := time.Now()
+	service.jobs["job1"] = &BacktestJob{
+		ID:          "job1",
+		UserID:      owner,
+		Status:      BacktestJobCompleted,
+		CompletedAt: &completedAt,
+		result:      &BacktestResponse{Currency: "USD"},
+	}
+	service.jobs["job2"] = &BacktestJob{ID: "job2", UserID: owner, Status: BacktestJobRunning}
+
+	if _, err := service.GetJob(primitive.NewObjectID(), "job1"); !errors.Is(err, ErrBacktestJobNotFound) {
+		t.Errorf("Expected ErrBacktestJobNotFound for another user, got %v", err)
+	}
+
+	result, err := service.GetJobResult(owner, "job1")
+	if err != nil || result.Currency != "USD" {
+		t.Errorf("Expected completed result, got %v, %v", result, err)
+	}
+
+	if _, err := service.GetJobResult(owner, "job2"); !errors.Is(err, ErrBacktestJobNotComplete) {
+		t.Errorf("Expected ErrBacktestJobNotComplete, got %v", err)
+	}
+}
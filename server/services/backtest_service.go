@@ -1,9 +1,14 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
+	"stock-portfolio-tracker/internal/analytics/engine"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/money"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -42,6 +47,47 @@ type BacktestMetrics struct {
 	Volatility         float64 `json:"volatility"`
 	SharpeRatio        float64 `json:"sharpeRatio"`
 	ExcessReturn       float64 `json:"excessReturn,omitempty"`
+	GrossReturnPercent float64 `json:"grossReturnPercent,omitempty"` // Return before trading costs
+	CostDrag           float64 `json:"costDrag,omitempty"`           // Gross minus net return, in percentage points
+	TotalCosts         float64 `json:"totalCosts,omitempty"`         // Commission and slippage paid
+}
+
+// maxSlippageBps bounds the slippage parameter to a plausible range
+const maxSlippageBps = 1000
+
+// BacktestCosts represents the trading costs applied to simulated trades
+type BacktestCosts struct {
+	CommissionPerTrade float64 `json:"commissionPerTrade,omitempty"` // Flat fee per trade in the backtest currency
+	SlippageBps        float64 `json:"slippageBps,omitempty"`        // Price impact in basis points of the traded amount
+}
+
+// Validate checks that the cost parameters are within range
+func (c BacktestCosts) Validate() error {
+	if c.CommissionPerTrade < 0 {
+		return fmt.Errorf("commission cannot be negative")
+	}
+	if c.SlippageBps < 0 || c.SlippageBps > maxSlippageBps {
+		return fmt.Errorf("slippage must be between 0 and %d basis points", maxSlippageBps)
+	}
+	return nil
+}
+
+// enabled reports whether any trading cost is configured
+func (c BacktestCosts) enabled() bool {
+	return c.CommissionPerTrade > 0 || c.SlippageBps > 0
+}
+
+// tradeCost returns the cost of trading the given amount, capped at the amount itself
+func (c BacktestCosts) tradeCost(amount float64) float64 {
+	cost := c.CommissionPerTrade + amount*c.SlippageBps/10000
+	return math.Min(cost, amount)
+}
+
+// backtestValueSummary carries the values needed to report gross vs net returns
+type backtestValueSummary struct {
+	InitialValue    float64 // Value invested before trading costs
+	GrossFinalValue float64 // Final value had no trading costs been paid
+	TotalCosts      float64
 }
 
 // AssetContribution represents an asset's contribution to portfolio return
@@ -57,41 +103,46 @@ type AssetContribution struct {
 
 // BenchmarkInfo represents benchmark information
 type BenchmarkInfo struct {
-	Symbol      string  `json:"symbol"`
-	Name        string  `json:"name"`
-	TotalReturn float64 `json:"totalReturn"`
+	Symbol      string                      `json:"symbol"`
+	Name        string                      `json:"name"`
+	TotalReturn float64                     `json:"totalReturn"`
+	Components  []models.BenchmarkComponent `json:"components,omitempty"` // Set for blended benchmarks
 }
 
 // BacktestService handles portfolio backtest calculations
 type BacktestService struct {
 	portfolioService *PortfolioService
 	analyticsService *AnalyticsService
-	currencyService  *CurrencyService
-	stockService     *StockAPIService
+	currencyService  CurrencyProvider
+	stockService     StockDataProvider
+	blendService     *BenchmarkBlendService
 }
 
 // NewBacktestService creates a new BacktestService instance
 func NewBacktestService(
 	portfolioService *PortfolioService,
 	analyticsService *AnalyticsService,
-	currencyService *CurrencyService,
-	stockService *StockAPIService,
+	currencyService CurrencyProvider,
+	stockService StockDataProvider,
 ) *BacktestService {
 	return &BacktestService{
 		portfolioService: portfolioService,
 		analyticsService: analyticsService,
 		currencyService:  currencyService,
 		stockService:     stockService,
+		blendService:     NewBenchmarkBlendService(),
 	}
 }
 
-// RunBacktest performs portfolio backtest
+// RunBacktest performs portfolio backtest, fetching prices under ctx
 func (s *BacktestService) RunBacktest(
+	ctx context.Context,
 	userID primitive.ObjectID,
 	startDate time.Time,
 	endDate time.Time,
 	currency string,
 	benchmark string,
+	costs BacktestCosts,
 ) (*BacktestResponse, error) {
 	fmt.Printf("[Backtest] Starting backtest for user %s from %s to %s in %s\n",
 		userID.Hex(), startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), currency)
@@ -100,9 +151,12 @@ func (s *BacktestService) RunBacktest(
 	if err := s.validateBacktestParams(startDate, endDate, currency); err != nil {
 		return nil, err
 	}
+	if err := costs.Validate(); err != nil {
+		return nil, err
+	}
 
 	// Get current holdings
-	holdings, err := s.portfolioService.GetUserHoldings(userID, currency)
+	holdings, err := s.portfolioService.GetUserHoldingsContext(ctx, userID, currency)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user holdings: %w", err)
 	}
@@ -115,13 +169,13 @@ func (s *BacktestService) RunBacktest(
 	weights := s.calculatePortfolioWeights(holdings)
 
 	// Get historical prices for all assets
-	historicalPrices, err := s.getHistoricalPrices(holdings, startDate, endDate)
+	historicalPrices, err := s.getHistoricalPrices(ctx, holdings, startDate, endDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get historical prices: %w", err)
 	}
 
 	// Calculate backtest performance
-	performance, err := s.calculateBacktestPerformance(weights, historicalPrices, startDate, endDate, currency, holdings)
+	performance, summary, err := s.calculateBacktestPerformance(weights, historicalPrices, startDate, endDate, currency, holdings, costs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate backtest performance: %w", err)
 	}
@@ -131,11 +185,18 @@ func (s *BacktestService) RunBacktest(
 	}
 
 	// Calculate metrics
-	metrics, err := s.calculateBacktestMetrics(performance, startDate, endDate)
+	metrics, err := s.calculateBacktestMetrics(performance, summary.InitialValue, startDate, endDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate metrics: %w", err)
 	}
 
+	// Report how much trading costs reduced the return
+	if costs.enabled() && summary.InitialValue > 0 {
+		metrics.GrossReturnPercent = ((summary.GrossFinalValue - summary.InitialValue) / summary.InitialValue) * 100
+		metrics.CostDrag = metrics.GrossReturnPercent - metrics.TotalReturnPercent
+		metrics.TotalCosts = summary.TotalCosts
+	}
+
 	// Calculate asset contributions
 	assetContributions, err := s.calculateAssetContributions(weights, historicalPrices, startDate, endDate, currency, holdings)
 	if err != nil {
@@ -145,8 +206,10 @@ func (s *BacktestService) RunBacktest(
 	// Get benchmark data if specified
 	var benchmarkInfo *BenchmarkInfo
 	if benchmark != "" {
-		benchmarkData, err := s.getBenchmarkData(benchmark, startDate, endDate)
-		if err != nil {
+		benchmarkData, info, err := s.resolveBenchmark(ctx, userID, benchmark, startDate, endDate)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		} else if err != nil {
 			fmt.Printf("[Backtest] Warning: failed to get benchmark data: %v\n", err)
 		} else if len(benchmarkData) > 0 {
 			// Add benchmark returns to performance data
@@ -156,11 +219,8 @@ func (s *BacktestService) RunBacktest(
 			benchmarkTotalReturn := benchmarkData[len(benchmarkData)-1].PortfolioReturn
 			metrics.ExcessReturn = metrics.TotalReturnPercent - benchmarkTotalReturn
 
-			benchmarkInfo = &BenchmarkInfo{
-				Symbol:      benchmark,
-				Name:        s.getBenchmarkName(benchmark),
-				TotalReturn: benchmarkTotalReturn,
-			}
+			info.TotalReturn = benchmarkTotalReturn
+			benchmarkInfo = info
 		}
 	}
 
@@ -183,8 +243,8 @@ func (s *BacktestService) RunBacktest(
 // validateBacktestParams validates backtest parameters
 func (s *BacktestService) validateBacktestParams(startDate, endDate time.Time, currency string) error {
 	// Validate currency
-	if currency != "USD" && currency != "RMB" && currency != "CNY" {
-		return fmt.Errorf("invalid currency: must be USD or RMB")
+	if _, err := NormalizeDisplayCurrency(currency); err != nil {
+		return err
 	}
 
 	// Validate dates
@@ -233,7 +293,7 @@ func (s *BacktestService) calculatePortfolioWeights(holdings []Holding) map[stri
 }
 
 // getHistoricalPrices fetches historical prices for all assets
-func (s *BacktestService) getHistoricalPrices(holdings []Holding, startDate, endDate time.Time) (map[string][]HistoricalPrice, error) {
+func (s *BacktestService) getHistoricalPrices(ctx context.Context, holdings []Holding, startDate, endDate time.Time) (map[string][]HistoricalPrice, error) {
 	historicalPrices := make(map[string][]HistoricalPrice)
 
 	// Determine period string based on date range
@@ -252,11 +312,16 @@ func (s *BacktestService) getHistoricalPrices(holdings []Holding, startDate, end
 	}
 
 	for _, holding := range holdings {
-		prices, err := s.stockService.GetHistoricalData(holding.Symbol, period)
+		prices, err := s.stockService.GetHistoricalDataContext(ctx, holding.Symbol, period)
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
 			fmt.Printf("[Backtest] Warning: failed to fetch historical data for %s: %v\n", holding.Symbol, err)
 			continue
 		}
+		// Total returns: dividends are reinvested through the adjusted closes
+		prices = AdjustedHistory(prices)
 
 		// Filter prices to the specified date range
 		var filteredPrices []HistoricalPrice
@@ -279,20 +344,70 @@ func (s *BacktestService) getHistoricalPrices(holdings []Holding, startDate, end
 	return historicalPrices, nil
 }
 
-// getBenchmarkName returns the display name for a benchmark symbol
-func (s *BacktestService) getBenchmarkName(symbol string) string {
-	benchmarkNames := map[string]string{
-		"^GSPC":     "S&P 500",
-		"^IXIC":     "NASDAQ",
-		"^DJI":      "Dow Jones",
-		"000001.SS": "Shanghai Composite",
-		"399001.SZ": "Shenzhen Component",
+// resolveBenchmark returns the benchmark series and its description for a
+// benchmark parameter, which may be a single symbol, a blend expression such
+// as "60% ^GSPC + 40% AGG", or a saved blend referenced as "blend:<id>"
+func (s *BacktestService) resolveBenchmark(
+	ctx context.Context,
+	userID primitive.ObjectID,
+	benchmark string,
+	startDate time.Time,
+	endDate time.Time,
+) ([]BacktestDataPoint, *BenchmarkInfo, error) {
+	var components []models.BenchmarkComponent
+	var name string
+
+	if strings.HasPrefix(benchmark, SavedBlendPrefix) {
+		blendID, err := primitive.ObjectIDFromHex(strings.TrimPrefix(benchmark, SavedBlendPrefix))
+		if err != nil {
+			return nil, nil, ErrBenchmarkBlendNotFound
+		}
+		blend, err := s.blendService.GetBlend(userID, blendID)
+		if err != nil {
+			return nil, nil, err
+		}
+		components = blend.Components
+		name = blend.Name
+	} else {
+		var err error
+		components, err = ParseBenchmarkBlend(benchmark)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Plain symbol
+	if components == nil {
+		benchmarkData, err := s.getBenchmarkData(ctx, benchmark, startDate, endDate)
+		if err != nil {
+			return nil, nil, err
+		}
+		return benchmarkData, &BenchmarkInfo{
+			Symbol: benchmark,
+			Name:   BenchmarkName(benchmark),
+		}, nil
+	}
+
+	series := make([][]BacktestDataPoint, len(components))
+	terms := make([]string, len(components))
+	for i, component := range components {
+		componentData, err := s.getBenchmarkData(ctx, component.Symbol, startDate, endDate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get data for %s: %w", component.Symbol, err)
+		}
+		series[i] = componentData
+		terms[i] = fmt.Sprintf("%g%% %s", component.Weight, BenchmarkName(component.Symbol))
 	}
 
-	if name, ok := benchmarkNames[symbol]; ok {
-		return name
+	if name == "" {
+		name = strings.Join(terms, " + ")
 	}
-	return symbol
+
+	return blendBenchmarkSeries(components, series), &BenchmarkInfo{
+		Symbol:     benchmark,
+		Name:       name,
+		Components: components,
+	}, nil
 }
 
 // mergeBenchmarkData merges benchmark returns into performance data
@@ -313,7 +428,9 @@ func (s *BacktestService) mergeBenchmarkData(performance []BacktestDataPoint, be
 	}
 }
 
-// calculateBacktestPerformance calculates daily portfolio values
+// calculateBacktestPerformance calculates daily portfolio values net of trading costs.
+// Costs are paid out of the initial purchase, which is the only trade of the
+// buy and hold simulation, so returns are measured against the pre-cost value.
 func (s *BacktestService) calculateBacktestPerformance(
 	weights map[string]float64,
 	historicalPrices map[string][]HistoricalPrice,
@@ -321,41 +438,27 @@ func (s *BacktestService) calculateBacktestPerformance(
 	endDate time.Time,
 	currency string,
 	holdings []Holding,
-) ([]BacktestDataPoint, error) {
-	// Build a map of all unique dates from historical prices
-	dateMap := make(map[string]time.Time)
-	for _, prices := range historicalPrices {
-		for _, price := range prices {
-			dateKey := price.Date.Format("2006-01-02")
-			if _, exists := dateMap[dateKey]; !exists {
-				dateMap[dateKey] = price.Date
-			}
-		}
-	}
+	costs BacktestCosts,
+) ([]BacktestDataPoint, backtestValueSummary, error) {
+	var summary backtestValueSummary
 
-	// Convert to sorted slice of dates
-	dates := make([]time.Time, 0, len(dateMap))
-	for _, date := range dateMap {
-		dates = append(dates, date)
-	}
-
-	sort.Slice(dates, func(i, j int) bool {
-		return dates[i].Before(dates[j])
-	})
+	// Value the portfolio on every trading day of any asset
+	dates := engine.TradingDates(historicalPrices, time.Time{})
 
 	if len(dates) == 0 {
-		return nil, fmt.Errorf("no historical dates available")
+		return nil, summary, fmt.Errorf("no historical dates available")
 	}
 
 	// Calculate total current portfolio value (this will be our initial investment)
 	totalCurrentValue := 0.0
 	for _, holding := range holdings {
-		totalCurrentValue += holding.CurrentValue
+		totalCurrentValue = money.Sum(currency, totalCurrentValue, holding.CurrentValue)
 	}
 
 	// Calculate the number of shares to hold for each asset based on start date prices
 	// This simulates a "buy and hold" strategy
 	shares := make(map[string]float64)
+	netFactors := make(map[string]float64) // Fraction of each position left after trading costs
 	for symbol, weight := range weights {
 		prices, ok := historicalPrices[symbol]
 		if !ok || len(prices) == 0 {
@@ -364,7 +467,7 @@ func (s *BacktestService) calculateBacktestPerformance(
 		}
 
 		// Find the price at start date (or closest available date)
-		startPrice := s.findPriceForDate(prices, startDate)
+		startPrice := engine.PriceOn(prices, startDate)
 		if startPrice <= 0 {
 			// Try to use the first available price if no price found at start date
 			if len(prices) > 0 {
@@ -380,12 +483,17 @@ func (s *BacktestService) calculateBacktestPerformance(
 		// Calculate initial investment amount for this asset
 		initialInvestment := weight * totalCurrentValue
 
-		// Handle currency conversion for initial investment if needed
-		symbolCurrency := "USD"
-		if s.stockService.IsChinaStock(symbol) {
-			symbolCurrency = "CNY"
+		// Commission and slippage reduce the amount actually invested
+		netFactors[symbol] = 1
+		if costs.enabled() && initialInvestment > 0 {
+			tradeCost := money.Round(costs.tradeCost(initialInvestment), currency)
+			netFactors[symbol] = (initialInvestment - tradeCost) / initialInvestment
+			summary.TotalCosts = money.Sum(currency, summary.TotalCosts, tradeCost)
 		}
 
+		// Handle currency conversion for initial investment if needed
+		symbolCurrency := s.stockService.SymbolCurrency(symbol)
+
 		// Convert initial investment to asset's currency
 		investmentInAssetCurrency := initialInvestment
 		if symbolCurrency != currency {
@@ -404,14 +512,16 @@ func (s *BacktestService) calculateBacktestPerformance(
 	}
 
 	if len(shares) == 0 {
-		return nil, fmt.Errorf("no valid shares calculated for any asset")
+		return nil, summary, fmt.Errorf("no valid shares calculated for any asset")
 	}
 
 	// Calculate portfolio value for each date using fixed share counts
 	performance := make([]BacktestDataPoint, 0, len(dates))
+	var grossValues []float64
 
 	for _, date := range dates {
-		portfolioValue := 0.0
+		portfolioValue := money.Zero(currency)
+		grossValue := money.Zero(currency)
 
 		// For each asset, calculate its value on this date: shares * price
 		for symbol, shareCount := range shares {
@@ -421,7 +531,7 @@ func (s *BacktestService) calculateBacktestPerformance(
 			}
 
 			// Find the price for this date (or closest previous date)
-			price := s.findPriceForDate(prices, date)
+			price := engine.PriceOn(prices, date)
 			if price <= 0 {
 				continue
 			}
@@ -430,10 +540,7 @@ func (s *BacktestService) calculateBacktestPerformance(
 			assetValue := shareCount * price
 
 			// Handle currency conversion if needed
-			symbolCurrency := "USD"
-			if s.stockService.IsChinaStock(symbol) {
-				symbolCurrency = "CNY"
-			}
+			symbolCurrency := s.stockService.SymbolCurrency(symbol)
 
 			if symbolCurrency != currency {
 				convertedValue, err := s.currencyService.ConvertAmount(assetValue, symbolCurrency, currency)
@@ -444,19 +551,23 @@ func (s *BacktestService) calculateBacktestPerformance(
 				}
 			}
 
-			portfolioValue += assetValue
+			portfolioValue = portfolioValue.Add(money.New(assetValue*netFactors[symbol], currency))
+			grossValue = grossValue.Add(money.New(assetValue, currency))
 		}
 
+		grossValues = append(grossValues, grossValue.Float64())
 		performance = append(performance, BacktestDataPoint{
 			Date:            date,
-			PortfolioValue:  portfolioValue,
+			PortfolioValue:  portfolioValue.Float64(),
 			PortfolioReturn: 0, // Will calculate after all points are collected
 		})
 	}
 
-	// Calculate returns based on initial portfolio value
+	// Calculate returns based on initial portfolio value before trading costs
 	if len(performance) > 0 {
-		initialValue := performance[0].PortfolioValue
+		initialValue := grossValues[0]
+		summary.InitialValue = initialValue
+		summary.GrossFinalValue = grossValues[len(grossValues)-1]
 		fmt.Printf("[Backtest] Initial portfolio value: %.2f %s\n", initialValue, currency)
 
 		for i := range performance {
@@ -471,59 +582,13 @@ func (s *BacktestService) calculateBacktestPerformance(
 			performance[len(performance)-1].PortfolioReturn)
 	}
 
-	return performance, nil
-}
-
-// findPriceForDate finds the price for a specific date or the closest previous date
-// If no previous date is found, it will use the closest future date within 30 days
-func (s *BacktestService) findPriceForDate(prices []HistoricalPrice, targetDate time.Time) float64 {
-	if len(prices) == 0 {
-		return 0
-	}
-
-	var closestPrice float64
-	var closestDate time.Time
-	var closestFuturePrice float64
-	var closestFutureDate time.Time
-
-	for _, price := range prices {
-		// If exact match, return immediately
-		if price.Date.Format("2006-01-02") == targetDate.Format("2006-01-02") {
-			return price.Price
-		}
-
-		// Track closest previous or equal date
-		if (price.Date.Before(targetDate) || price.Date.Equal(targetDate)) {
-			if closestDate.IsZero() || price.Date.After(closestDate) {
-				closestDate = price.Date
-				closestPrice = price.Price
-			}
-		}
-
-		// Track closest future date (within 30 days) as fallback
-		if price.Date.After(targetDate) {
-			daysDiff := price.Date.Sub(targetDate).Hours() / 24
-			if daysDiff <= 30 {
-				if closestFutureDate.IsZero() || price.Date.Before(closestFutureDate) {
-					closestFutureDate = price.Date
-					closestFuturePrice = price.Price
-				}
-			}
-		}
-	}
-
-	// Return closest previous price if found
-	if closestPrice > 0 {
-		return closestPrice
-	}
-
-	// Otherwise return closest future price as fallback
-	return closestFuturePrice
+	return performance, summary, nil
 }
 
-// calculateBacktestMetrics calculates performance metrics
+// calculateBacktestMetrics calculates performance metrics relative to the initial invested value
 func (s *BacktestService) calculateBacktestMetrics(
 	dataPoints []BacktestDataPoint,
+	initialValue float64,
 	startDate time.Time,
 	endDate time.Time,
 ) (*BacktestMetrics, error) {
@@ -542,7 +607,6 @@ func (s *BacktestService) calculateBacktestMetrics(
 		}, nil
 	}
 
-	initialValue := dataPoints[0].PortfolioValue
 	finalValue := dataPoints[len(dataPoints)-1].PortfolioValue
 
 	// Calculate total return
@@ -554,16 +618,18 @@ func (s *BacktestService) calculateBacktestMetrics(
 
 	// Calculate annualized return
 	days := endDate.Sub(startDate).Hours() / 24
-	annualizedReturn := 0.0
-	if days > 0 && initialValue > 0 {
-		annualizedReturn = (math.Pow(finalValue/initialValue, 365/days) - 1) * 100
+	annualizedReturn := engine.AnnualizedReturn(initialValue, finalValue, days)
+
+	values := make([]float64, len(dataPoints))
+	for i, point := range dataPoints {
+		values[i] = point.PortfolioValue
 	}
 
-	// Calculate maximum drawdown
-	maxDrawdown := s.calculateMaxDrawdownFromDataPoints(dataPoints)
+	// Calculate maximum drawdown, reported as a negative percentage
+	maxDrawdown := -engine.MaxDrawdown(values).Percent
 
 	// Calculate volatility (annualized standard deviation of daily returns)
-	volatility := s.calculateVolatility(dataPoints)
+	volatility := engine.AnnualizedVolatility(engine.Returns(values))
 
 	// Calculate Sharpe ratio (using 2% risk-free rate)
 	riskFreeRate := 2.0
@@ -582,79 +648,6 @@ func (s *BacktestService) calculateBacktestMetrics(
 	}, nil
 }
 
-// calculateMaxDrawdownFromDataPoints calculates maximum drawdown from backtest data points
-func (s *BacktestService) calculateMaxDrawdownFromDataPoints(dataPoints []BacktestDataPoint) float64 {
-	if len(dataPoints) <= 1 {
-		return 0
-	}
-
-	peak := dataPoints[0].PortfolioValue
-	maxDrawdown := 0.0
-
-	for _, point := range dataPoints {
-		// Update peak if current value is higher
-		if point.PortfolioValue > peak {
-			peak = point.PortfolioValue
-		}
-
-		// Calculate current drawdown from peak
-		if peak > 0 {
-			drawdown := ((peak - point.PortfolioValue) / peak) * 100
-			if drawdown > maxDrawdown {
-				maxDrawdown = drawdown
-			}
-		}
-	}
-
-	return -maxDrawdown // Return as negative value
-}
-
-// calculateVolatility calculates annualized volatility (standard deviation of returns)
-func (s *BacktestService) calculateVolatility(dataPoints []BacktestDataPoint) float64 {
-	if len(dataPoints) <= 1 {
-		return 0
-	}
-
-	// Calculate daily returns
-	dailyReturns := make([]float64, 0, len(dataPoints)-1)
-	for i := 1; i < len(dataPoints); i++ {
-		prevValue := dataPoints[i-1].PortfolioValue
-		currValue := dataPoints[i].PortfolioValue
-
-		if prevValue > 0 {
-			dailyReturn := (currValue - prevValue) / prevValue
-			dailyReturns = append(dailyReturns, dailyReturn)
-		}
-	}
-
-	if len(dailyReturns) == 0 {
-		return 0
-	}
-
-	// Calculate mean of daily returns
-	sum := 0.0
-	for _, ret := range dailyReturns {
-		sum += ret
-	}
-	mean := sum / float64(len(dailyReturns))
-
-	// Calculate variance
-	variance := 0.0
-	for _, ret := range dailyReturns {
-		diff := ret - mean
-		variance += diff * diff
-	}
-	variance /= float64(len(dailyReturns))
-
-	// Calculate standard deviation
-	stdDev := math.Sqrt(variance)
-
-	// Annualize volatility (assuming 252 trading days per year)
-	annualizedVolatility := stdDev * math.Sqrt(252) * 100
-
-	return annualizedVolatility
-}
-
 // calculateAssetContributions calculates each asset's contribution to portfolio return
 func (s *BacktestService) calculateAssetContributions(
 	weights map[string]float64,
@@ -689,8 +682,8 @@ func (s *BacktestService) calculateAssetContributions(
 		}
 
 		// Find start and end prices
-		startPrice := s.findPriceForDate(prices, startDate)
-		endPrice := s.findPriceForDate(prices, endDate)
+		startPrice := engine.PriceOn(prices, startDate)
+		endPrice := engine.PriceOn(prices, endDate)
 
 		if startPrice <= 0 || endPrice <= 0 {
 			continue
@@ -703,10 +696,7 @@ func (s *BacktestService) calculateAssetContributions(
 		initialInvestment := weight * totalCurrentValue
 
 		// Handle currency conversion
-		symbolCurrency := "USD"
-		if s.stockService.IsChinaStock(symbol) {
-			symbolCurrency = "CNY"
-		}
+		symbolCurrency := s.stockService.SymbolCurrency(symbol)
 
 		// Convert initial investment to asset's currency
 		investmentInAssetCurrency := initialInvestment
@@ -760,6 +750,7 @@ func (s *BacktestService) calculateAssetContributions(
 
 // getBenchmarkData fetches and processes benchmark data
 func (s *BacktestService) getBenchmarkData(
+	ctx context.Context,
 	benchmark string,
 	startDate time.Time,
 	endDate time.Time,
@@ -780,10 +771,11 @@ func (s *BacktestService) getBenchmarkData(
 	}
 
 	// Fetch historical data for benchmark
-	prices, err := s.stockService.GetHistoricalData(benchmark, period)
+	prices, err := s.stockService.GetHistoricalDataContext(ctx, benchmark, period)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch benchmark data: %w", err)
 	}
+	prices = AdjustedHistory(prices)
 
 	// Filter prices to the specified date range
 	var filteredPrices []HistoricalPrice
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBacktestPerformanceNetOfCosts(t *testing.T) {
+	service := NewBacktestService(nil, nil, nil, NewFixtureProvider())
+
+	startDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endDate := startDate.AddDate(0, 0, 1)
+	prices := map[string][]HistoricalPrice{
+		"AAPL": {
+			{Date: startDate, Price: 10},
+			{Date: endDate, Price: 11},
+		},
+	}
+	holdings := []Holding{{Symbol: "AAPL", CurrentValue: 1000}}
+	weights := map[string]float64{"AAPL": 1}
+
+	// 5 commission + 50 bps slippage on 1000 = 10 in costs
+	costs := BacktestCosts{CommissionPerTrade: 5, SlippageBps: 50}
+	performance, summary, err := service.calculateBacktestPerformance(weights, prices, startDate, endDate, "USD", holdings, costs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if math.Abs(summary.TotalCosts-10) > 1e-9 {
+		t.Errorf("TotalCosts = %.4f, want 10", summary.TotalCosts)
+	}
+	if math.Abs(summary.InitialValue-1000) > 1e-9 || math.Abs(summary.GrossFinalValue-1100) > 1e-9 {
+		t.Errorf("Unexpected gross values: %+v", summary)
+	}
+
+	final := performance[len(performance)-1]
+	if math.Abs(final.PortfolioValue-1089) > 1e-9 {
+		t.Errorf("Final net value = %.4f, want 1089", final.PortfolioValue)
+	}
+	if math.Abs(final.PortfolioReturn-8.9) > 1e-9 {
+		t.Errorf("Final net return = %.4f, want 8.9", final.PortfolioReturn)
+	}
+}
+
+func TestBacktestCostsValidate(t *testing.T) {
+	invalid := []BacktestCosts{
+		{CommissionPerTrade: -1},
+		{SlippageBps: -5},
+		{SlippageBps: maxSlippageBps + 1},
+	}
+	for _, costs := range invalid {
+		if err := costs.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", costs)
+		}
+	}
+
+	if err := (BacktestCosts{CommissionPerTrade: 4.95, SlippageBps: 10}).Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidBenchmarkBlend   = errors.New("invalid benchmark blend")
+	ErrDuplicateBenchmarkBlend = errors.New("benchmark blend name already exists")
+	ErrBenchmarkBlendNotFound  = errors.New("benchmark blend not found")
+)
+
+const (
+	// SavedBlendPrefix marks a benchmark parameter that references a saved blend by ID
+	SavedBlendPrefix = "blend:"
+	// maxBlendComponents bounds the number of symbols in a blend
+	maxBlendComponents = 10
+)
+
+// blendTermPattern matches one weighted term of a blend expression, e.g. "60% ^GSPC"
+var blendTermPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*%\s*([^\s+,%]+)`)
+
+// blendSeparatorPattern matches what may appear between blend terms. A literal
+// "+" sent unencoded in a query string arrives as a space, so both are accepted.
+var blendSeparatorPattern = regexp.MustCompile(`^[\s+,]*$`)
+
+// ParseBenchmarkBlend parses a composite benchmark expression such as
+// "60% ^GSPC + 40% AGG". It returns nil components when the expression is a
+// plain symbol.
+func ParseBenchmarkBlend(expression string) ([]models.BenchmarkComponent, error) {
+	if !strings.Contains(expression, "%") {
+		return nil, nil
+	}
+
+	matches := blendTermPattern.FindAllStringSubmatchIndex(expression, -1)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: expected terms like \"60%% ^GSPC\"", ErrInvalidBenchmarkBlend)
+	}
+
+	components := make([]models.BenchmarkComponent, 0, len(matches))
+	previousEnd := 0
+	for _, match := range matches {
+		if !blendSeparatorPattern.MatchString(expression[previousEnd:match[0]]) {
+			return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidBenchmarkBlend, strings.TrimSpace(expression[previousEnd:match[0]]))
+		}
+		previousEnd = match[1]
+
+		var weight float64
+		fmt.Sscanf(expression[match[2]:match[3]], "%g", &weight)
+		components = append(components, models.BenchmarkComponent{
+			Symbol: expression[match[4]:match[5]],
+			Weight: weight,
+		})
+	}
+	if !blendSeparatorPattern.MatchString(expression[previousEnd:]) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidBenchmarkBlend, strings.TrimSpace(expression[previousEnd:]))
+	}
+
+	return normalizeBenchmarkComponents(components)
+}
+
+// normalizeBenchmarkComponents validates blend components and normalizes their symbols.
+// Weights are percentages and must add up to 100.
+func normalizeBenchmarkComponents(components []models.BenchmarkComponent) ([]models.BenchmarkComponent, error) {
+	if len(components) == 0 || len(components) > maxBlendComponents {
+		return nil, fmt.Errorf("%w: must contain between 1 and %d symbols", ErrInvalidBenchmarkBlend, maxBlendComponents)
+	}
+
+	normalized := make([]models.BenchmarkComponent, 0, len(components))
+	seen := make(map[string]bool)
+	totalWeight := 0.0
+	for _, component := range components {
+		symbol := strings.ToUpper(strings.TrimSpace(component.Symbol))
+		if symbol == "" {
+			return nil, fmt.Errorf("%w: symbol is required", ErrInvalidBenchmarkBlend)
+		}
+		if seen[symbol] {
+			return nil, fmt.Errorf("%w: %s appears more than once", ErrInvalidBenchmarkBlend, symbol)
+		}
+		if component.Weight <= 0 {
+			return nil, fmt.Errorf("%w: weight for %s must be positive", ErrInvalidBenchmarkBlend, symbol)
+		}
+
+		seen[symbol] = true
+		totalWeight += component.Weight
+		normalized = append(normalized, models.BenchmarkComponent{Symbol: symbol, Weight: component.Weight})
+	}
+
+	if math.Abs(totalWeight-100) > 0.01 {
+		return nil, fmt.Errorf("%w: weights must add up to 100%%, got %.2f%%", ErrInvalidBenchmarkBlend, totalWeight)
+	}
+
+	return normalized, nil
+}
+
+// blendBenchmarkSeries combines component price series into a single benchmark
+// series. The blend holds the given weights at the first date on which every
+// component has a price and is not rebalanced afterwards. Each series must be
+// sorted by date.
+func blendBenchmarkSeries(components []models.BenchmarkComponent, series [][]BacktestDataPoint) []BacktestDataPoint {
+	if len(components) == 0 || len(components) != len(series) {
+		return nil
+	}
+
+	// The blend starts once every component has data
+	var start time.Time
+	for _, points := range series {
+		if len(points) == 0 {
+			return nil
+		}
+		if points[0].Date.After(start) {
+			start = points[0].Date
+		}
+	}
+
+	dateSet := make(map[time.Time]bool)
+	for _, points := range series {
+		for _, point := range points {
+			if !point.Date.Before(start) {
+				dateSet[point.Date] = true
+			}
+		}
+	}
+	dates := make([]time.Time, 0, len(dateSet))
+	for date := range dateSet {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+
+	positions := make([]int, len(series))
+	basePrices := make([]float64, len(series))
+	blended := make([]BacktestDataPoint, 0, len(dates))
+	for _, date := range dates {
+		value := 0.0
+		for i, points := range series {
+			// Carry forward the latest price on or before this date
+			for positions[i]+1 < len(points) && !points[positions[i]+1].Date.After(date) {
+				positions[i]++
+			}
+			price := points[positions[i]].PortfolioValue
+			if basePrices[i] == 0 {
+				basePrices[i] = price
+			}
+			if basePrices[i] > 0 {
+				value += components[i].Weight * price / basePrices[i]
+			}
+		}
+
+		blended = append(blended, BacktestDataPoint{
+			Date:            date,
+			PortfolioValue:  value,
+			PortfolioReturn: value - 100,
+		})
+	}
+
+	return blended
+}
+
+// BenchmarkBlendService handles saved benchmark blends
+type BenchmarkBlendService struct{}
+
+// NewBenchmarkBlendService creates a new BenchmarkBlendService instance
+func NewBenchmarkBlendService() *BenchmarkBlendService {
+	return &BenchmarkBlendService{}
+}
+
+// CreateBlend saves a new benchmark blend for a user
+func (s *BenchmarkBlendService) CreateBlend(userID primitive.ObjectID, name string, components []models.BenchmarkComponent) (*models.BenchmarkBlend, error) {
+	components, err := normalizeBenchmarkComponents(components)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("benchmark_blends")
+
+	blend := &models.BenchmarkBlend{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		Name:       strings.TrimSpace(name),
+		Components: components,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	_, err = collection.InsertOne(ctx, blend)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrDuplicateBenchmarkBlend
+		}
+		return nil, fmt.Errorf("failed to create benchmark blend: %w", err)
+	}
+
+	return blend, nil
+}
+
+// GetUserBlends returns all saved benchmark blends for a user
+func (s *BenchmarkBlendService) GetUserBlends(userID primitive.ObjectID) ([]models.BenchmarkBlend, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("benchmark_blends")
+
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch benchmark blends: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	blends := []models.BenchmarkBlend{}
+	if err := cursor.All(ctx, &blends); err != nil {
+		return nil, fmt.Errorf("failed to decode benchmark blends: %w", err)
+	}
+
+	return blends, nil
+}
+
+// GetBlend returns a saved benchmark blend owned by the user
+func (s *BenchmarkBlendService) GetBlend(userID primitive.ObjectID, blendID primitive.ObjectID) (*models.BenchmarkBlend, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("benchmark_blends")
+
+	var blend models.BenchmarkBlend
+	err := collection.FindOne(ctx, bson.M{"_id": blendID, "user_id": userID}).Decode(&blend)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrBenchmarkBlendNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch benchmark blend: %w", err)
+	}
+
+	return &blend, nil
+}
+
+// DeleteBlend deletes a saved benchmark blend
+func (s *BenchmarkBlendService) DeleteBlend(userID primitive.ObjectID, blendID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("benchmark_blends")
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": blendID, "user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete benchmark blend: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrBenchmarkBlendNotFound
+	}
+
+	return nil
+}
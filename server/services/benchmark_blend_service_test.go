@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"stock-portfolio-tracker/models"
+	"testing"
+	"time"
+)
+
+func TestParseBenchmarkBlend(t *testing.T) {
+	components, err := ParseBenchmarkBlend("60% ^GSPC + 40% agg")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(components) != 2 || components[0].Symbol != "^GSPC" || components[0].Weight != 60 ||
+		components[1].Symbol != "AGG" || components[1].Weight != 40 {
+		t.Errorf("Unexpected components: %+v", components)
+	}
+
+	// An unencoded "+" in a query string arrives as a space
+	if _, err := ParseBenchmarkBlend("60% ^GSPC   40% AGG"); err != nil {
+		t.Errorf("Expected space-separated blend to parse, got %v", err)
+	}
+
+	if components, err := ParseBenchmarkBlend("^GSPC"); err != nil || components != nil {
+		t.Errorf("Expected plain symbol to return no components, got %+v, %v", components, err)
+	}
+
+	invalid := []string{
+		"60% ^GSPC + 30% AGG",
+		"60% ^GSPC + 40% ^GSPC",
+		"60% ^GSPC and 40% AGG",
+		"100%",
+	}
+	for _, expression := range invalid {
+		if _, err := ParseBenchmarkBlend(expression); !errors.Is(err, ErrInvalidBenchmarkBlend) {
+			t.Errorf("ParseBenchmarkBlend(%q) error = %v, want ErrInvalidBenchmarkBlend", expression, err)
+		}
+	}
+}
+
+func TestBlendBenchmarkSeries(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	components := []models.BenchmarkComponent{
+		{Symbol: "A", Weight: 60},
+		{Symbol: "B", Weight: 40},
+	}
+	series := [][]BacktestDataPoint{
+		{
+			{Date: day(1), PortfolioValue: 10},
+			{Date: day(2), PortfolioValue: 11},
+			{Date: day(3), PortfolioValue: 12},
+		},
+		{
+			// Starts a day later and has no price on day 3
+			{Date: day(2), PortfolioValue: 50},
+			{Date: day(4), PortfolioValue: 55},
+		},
+	}
+
+	blended := blendBenchmarkSeries(components, series)
+	if len(blended) != 3 {
+		t.Fatalf("Expected 3 points from day 2 onward, got %d", len(blended))
+	}
+
+	want := []float64{
+		0,
+		60*12.0/11 + 40 - 100,
+		60*12.0/11 + 40*55.0/50 - 100,
+	}
+	for i, point := range blended {
+		if math.Abs(point.PortfolioReturn-want[i]) > 1e-9 {
+			t.Errorf("Point %d return = %.4f, want %.4f", i, point.PortfolioReturn, want[i])
+		}
+	}
+}
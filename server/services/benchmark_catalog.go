@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidCustomBenchmark   = errors.New("invalid custom benchmark")
+	ErrDuplicateCustomBenchmark = errors.New("benchmark already exists")
+	ErrCustomBenchmarkNotFound  = errors.New("custom benchmark not found")
+	ErrTooManyCustomBenchmarks  = errors.New("too many custom benchmarks")
+)
+
+// maxCustomBenchmarks bounds the number of custom benchmarks per user
+const maxCustomBenchmarks = 50
+
+// CatalogBenchmark describes a benchmark that can be compared against
+type CatalogBenchmark struct {
+	ID       string `json:"id,omitempty"` // Set for custom benchmarks
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Region   string `json:"region"`
+	Currency string `json:"currency"`
+	Custom   bool   `json:"custom"`
+}
+
+// BuiltInBenchmarks returns the supported benchmark indices, in market order
+func BuiltInBenchmarks() []CatalogBenchmark {
+	seen := make(map[string]bool)
+	catalog := make([]CatalogBenchmark, 0)
+	for _, market := range markets {
+		for _, benchmark := range market.Benchmarks {
+			if seen[benchmark.Symbol] {
+				continue
+			}
+			seen[benchmark.Symbol] = true
+			catalog = append(catalog, CatalogBenchmark{
+				Symbol:   benchmark.Symbol,
+				Name:     benchmark.Name,
+				Region:   market.Region,
+				Currency: market.Currency,
+			})
+		}
+	}
+	return catalog
+}
+
+// BenchmarkName returns the display name of a built-in benchmark, or the
+// symbol itself when it is not in the catalog
+func BenchmarkName(symbol string) string {
+	for _, market := range markets {
+		for _, benchmark := range market.Benchmarks {
+			if benchmark.Symbol == symbol {
+				return benchmark.Name
+			}
+		}
+	}
+	return symbol
+}
+
+// BenchmarkCatalogService serves the benchmark catalog and users' custom benchmarks
+type BenchmarkCatalogService struct {
+	stockService *StockAPIService
+}
+
+// NewBenchmarkCatalogService creates a new BenchmarkCatalogService instance
+func NewBenchmarkCatalogService(stockService *StockAPIService) *BenchmarkCatalogService {
+	return &BenchmarkCatalogService{
+		stockService: stockService,
+	}
+}
+
+// GetCatalog returns the built-in benchmarks followed by the user's custom ones
+func (s *BenchmarkCatalogService) GetCatalog(userID primitive.ObjectID) ([]CatalogBenchmark, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("custom_benchmarks")
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch custom benchmarks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var custom []models.CustomBenchmark
+	if err := cursor.All(ctx, &custom); err != nil {
+		return nil, fmt.Errorf("failed to decode custom benchmarks: %w", err)
+	}
+
+	catalog := BuiltInBenchmarks()
+	for _, benchmark := range custom {
+		catalog = append(catalog, CatalogBenchmark{
+			ID:       benchmark.ID.Hex(),
+			Symbol:   benchmark.Symbol,
+			Name:     benchmark.Name,
+			Region:   benchmark.Region,
+			Currency: benchmark.Currency,
+			Custom:   true,
+		})
+	}
+
+	return catalog, nil
+}
+
+// CreateCustomBenchmark saves a symbol as a custom benchmark for a user. The
+// symbol must have a quote; its name defaults to the quoted name and its
+// region and currency come from its exchange. The quote is fetched under ctx.
+func (s *BenchmarkCatalogService) CreateCustomBenchmark(ctx context.Context, userID primitive.ObjectID, req models.CustomBenchmarkRequest) (*models.CustomBenchmark, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if symbol == "" || s.stockService.IsCashSymbol(symbol) {
+		return nil, fmt.Errorf("%w: symbol is required", ErrInvalidCustomBenchmark)
+	}
+	for _, builtIn := range BuiltInBenchmarks() {
+		if builtIn.Symbol == symbol {
+			return nil, ErrDuplicateCustomBenchmark
+		}
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("custom_benchmarks")
+
+	count, err := collection.CountDocuments(queryCtx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count custom benchmarks: %w", err)
+	}
+	if count >= maxCustomBenchmarks {
+		return nil, fmt.Errorf("%w: at most %d allowed", ErrTooManyCustomBenchmarks, maxCustomBenchmarks)
+	}
+
+	info, err := s.stockService.GetStockInfoContext(ctx, symbol)
+	if err != nil {
+		if errors.Is(err, ErrStockNotFound) || errors.Is(err, ErrInvalidSymbol) {
+			return nil, fmt.Errorf("%w: no quote for %s", ErrInvalidCustomBenchmark, symbol)
+		}
+		return nil, fmt.Errorf("failed to look up %s: %w", symbol, err)
+	}
+
+	benchmark := &models.CustomBenchmark{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Symbol:    symbol,
+		Name:      strings.TrimSpace(req.Name),
+		Region:    "Other",
+		Currency:  info.Currency,
+		CreatedAt: time.Now(),
+	}
+	if benchmark.Name == "" {
+		benchmark.Name = info.Name
+	}
+	if market := MarketForSymbol(symbol); market != nil {
+		benchmark.Region = market.Region
+	}
+
+	_, err = collection.InsertOne(queryCtx, benchmark)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrDuplicateCustomBenchmark
+		}
+		return nil, fmt.Errorf("failed to create custom benchmark: %w", err)
+	}
+
+	return benchmark, nil
+}
+
+// DeleteCustomBenchmark deletes one of the user's custom benchmarks
+func (s *BenchmarkCatalogService) DeleteCustomBenchmark(userID primitive.ObjectID, benchmarkID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("custom_benchmarks")
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": benchmarkID, "user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete custom benchmark: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrCustomBenchmarkNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package services
+
+import "testing"
+
+func TestBuiltInBenchmarks(t *testing.T) {
+	catalog := BuiltInBenchmarks()
+
+	bySymbol := make(map[string]CatalogBenchmark)
+	for _, benchmark := range catalog {
+		if _, exists := bySymbol[benchmark.Symbol]; exists {
+			t.Errorf("Duplicate catalog entry for %s", benchmark.Symbol)
+		}
+		if benchmark.Name == "" || benchmark.Region == "" || benchmark.Currency == "" || benchmark.Custom {
+			t.Errorf("Incomplete catalog entry %+v", benchmark)
+		}
+		bySymbol[benchmark.Symbol] = benchmark
+	}
+
+	if catalog[0].Symbol != DefaultRiskBenchmark {
+		t.Errorf("Expected %s first, got %s", DefaultRiskBenchmark, catalog[0].Symbol)
+	}
+	if hsi := bySymbol["^HSI"]; hsi.Region != "Hong Kong" || hsi.Currency != "HKD" {
+		t.Errorf("Unexpected Hang Seng entry %+v", hsi)
+	}
+	if csi := bySymbol["000300.SS"]; csi.Region != "China" || csi.Currency != "RMB" {
+		t.Errorf("Unexpected CSI 300 entry %+v", csi)
+	}
+
+	if name := BenchmarkName("^GSPC"); name != "S&P 500" {
+		t.Errorf("Expected S&P 500, got %s", name)
+	}
+	if name := BenchmarkName("AGG"); name != "AGG" {
+		t.Errorf("Expected unknown symbols to keep their symbol, got %s", name)
+	}
+}
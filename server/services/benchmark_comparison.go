@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidBenchmark is returned when a saved default benchmark can't be parsed
+var ErrInvalidBenchmark = errors.New("invalid benchmark")
+
+// NoBenchmark turns off the default benchmark for a single request
+const NoBenchmark = "none"
+
+// maxBenchmarkSymbolLength bounds a plain benchmark symbol
+const maxBenchmarkSymbolLength = 20
+
+// benchmarkDayChangeWindow is how far back benchmark history is fetched to
+// find its last two closes
+const benchmarkDayChangeWindow = 10 * 24 * time.Hour
+
+// BenchmarkDayChange compares the portfolio's day change with a benchmark's
+type BenchmarkDayChange struct {
+	Symbol           string  `json:"symbol"`
+	Name             string  `json:"name"`
+	DayChangePercent float64 `json:"dayChangePercent"`
+	// ExcessDayChange is the portfolio's day change minus the benchmark's,
+	// in percentage points
+	ExcessDayChange float64 `json:"excessDayChange"`
+}
+
+// ValidateBenchmark checks that a benchmark is a plain symbol, a blend
+// expression or a saved blend reference. The empty benchmark is valid.
+func ValidateBenchmark(benchmark string) error {
+	switch {
+	case benchmark == "":
+		return nil
+	case strings.HasPrefix(benchmark, SavedBlendPrefix):
+		if !primitive.IsValidObjectID(strings.TrimPrefix(benchmark, SavedBlendPrefix)) {
+			return fmt.Errorf("%w: saved blend ID is malformed", ErrInvalidBenchmark)
+		}
+		return nil
+	case strings.Contains(benchmark, "%"):
+		if _, err := ParseBenchmarkBlend(benchmark); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBenchmark, err)
+		}
+		return nil
+	case len(benchmark) > maxBenchmarkSymbolLength || strings.ContainsAny(benchmark, " \t,"):
+		return fmt.Errorf("%w: %q is not a symbol", ErrInvalidBenchmark, benchmark)
+	default:
+		return nil
+	}
+}
+
+// BenchmarkComparisonService compares dashboard and performance responses with
+// a benchmark, defaulting to the one saved in the user's settings
+type BenchmarkComparisonService struct {
+	backtestService *BacktestService
+	settingsService *SettingsService
+}
+
+// NewBenchmarkComparisonService creates a new BenchmarkComparisonService instance
+func NewBenchmarkComparisonService(backtestService *BacktestService, settingsService *SettingsService) *BenchmarkComparisonService {
+	return &BenchmarkComparisonService{
+		backtestService: backtestService,
+		settingsService: settingsService,
+	}
+}
+
+// ResolveBenchmark returns the benchmark a request compares against: the
+// requested one, or the user's saved default when none was requested. It
+// returns "" when there is nothing to compare against.
+func (s *BenchmarkComparisonService) ResolveBenchmark(userID primitive.ObjectID, requested string) (string, error) {
+	switch requested {
+	case NoBenchmark:
+		return "", nil
+	case "":
+		settings, err := s.settingsService.GetSettings(userID)
+		if err != nil {
+			return "", err
+		}
+		return settings.Benchmarks.Default, nil
+	default:
+		return requested, nil
+	}
+}
+
+// DayChange returns the benchmark's change between its last two closes
+// compared with the portfolio's day change. Its history is fetched under ctx.
+func (s *BenchmarkComparisonService) DayChange(ctx context.Context, userID primitive.ObjectID, benchmark string, dayChangePercent float64) (*BenchmarkDayChange, error) {
+	endDate := time.Now()
+	series, info, err := s.backtestService.resolveBenchmark(ctx, userID, benchmark, endDate.Add(-benchmarkDayChangeWindow), endDate)
+	if err != nil {
+		return nil, err
+	}
+	if len(series) < 2 {
+		return nil, fmt.Errorf("not enough benchmark history for %s", benchmark)
+	}
+
+	// Returns are relative to the first close, so the day change is the ratio
+	// of the last two growth factors
+	last := 1 + series[len(series)-1].PortfolioReturn/100
+	previous := 1 + series[len(series)-2].PortfolioReturn/100
+	benchmarkDayChange := 0.0
+	if previous > 0 {
+		benchmarkDayChange = (last/previous - 1) * 100
+	}
+
+	return &BenchmarkDayChange{
+		Symbol:           info.Symbol,
+		Name:             info.Name,
+		DayChangePercent: benchmarkDayChange,
+		ExcessDayChange:  dayChangePercent - benchmarkDayChange,
+	}, nil
+}
+
+// Performance returns a copy of the performance series with the benchmark's
+// cumulative return over the same dates, and the benchmark's total return.
+// Dates the benchmark didn't trade on carry its previous return, and its
+// history is fetched under ctx.
+func (s *BenchmarkComparisonService) Performance(ctx context.Context, userID primitive.ObjectID, benchmark string, performance []PerformanceDataPoint) ([]PerformanceDataPoint, *BenchmarkInfo, error) {
+	if len(performance) == 0 {
+		return performance, nil, nil
+	}
+
+	startDate := performance[0].Date
+	endDate := performance[len(performance)-1].Date
+	series, info, err := s.backtestService.resolveBenchmark(ctx, userID, benchmark, startDate, endDate.Add(24*time.Hour))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged := make([]PerformanceDataPoint, len(performance))
+	copy(merged, performance)
+	next := 0
+	var current *float64
+	for i := range merged {
+		day := merged[i].Date.Format("2006-01-02")
+		for next < len(series) && series[next].Date.Format("2006-01-02") <= day {
+			value := series[next].PortfolioReturn
+			current = &value
+			next++
+		}
+		merged[i].BenchmarkReturn = current
+	}
+
+	if current != nil {
+		info.TotalReturn = *current
+	}
+	return merged, info, nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidateBenchmark(t *testing.T) {
+	valid := []string{"", "^GSPC", "60% ^GSPC + 40% AGG", SavedBlendPrefix + primitive.NewObjectID().Hex()}
+	for _, benchmark := range valid {
+		if err := ValidateBenchmark(benchmark); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", benchmark, err)
+		}
+	}
+
+	invalid := []string{"60% ^GSPC + 30% AGG", SavedBlendPrefix + "nope", "^GSPC AGG", "A-VERY-LONG-SYMBOL-NAME"}
+	for _, benchmark := range invalid {
+		if err := ValidateBenchmark(benchmark); !errors.Is(err, ErrInvalidBenchmark) {
+			t.Errorf("Expected %q to be rejected, got %v", benchmark, err)
+		}
+	}
+}
+
+func TestBenchmarkComparisonAlignsWithPerformance(t *testing.T) {
+	end := time.Now().Truncate(24 * time.Hour)
+	provider := NewFixtureProvider().SetHistory("^GSPC", end, 100, 102, 101, 105)
+	service := NewBenchmarkComparisonService(NewBacktestService(nil, nil, nil, provider), nil)
+	userID := primitive.NewObjectID()
+
+	// The portfolio has a point on a day after the benchmark's last close
+	performance := []PerformanceDataPoint{
+		{Date: end.AddDate(0, 0, -3), Value: 1000},
+		{Date: end.AddDate(0, 0, -1), Value: 1010},
+		{Date: end, Value: 1050},
+		{Date: end.AddDate(0, 0, 1), Value: 1040},
+	}
+	merged, info, err := service.Performance(context.Background(), userID, "^GSPC", performance)
+	if err != nil {
+		t.Fatalf("Failed to compare performance: %v", err)
+	}
+	if performance[0].BenchmarkReturn != nil {
+		t.Error("Expected the original series to be left unchanged")
+	}
+
+	expected := []float64{0, 1, 5, 5}
+	for i, want := range expected {
+		if merged[i].BenchmarkReturn == nil || math.Abs(*merged[i].BenchmarkReturn-want) > 1e-9 {
+			t.Errorf("Point %d: expected benchmark return %v, got %v", i, want, merged[i].BenchmarkReturn)
+		}
+	}
+	if info.Symbol != "^GSPC" || math.Abs(info.TotalReturn-5) > 1e-9 {
+		t.Errorf("Expected ^GSPC total return of 5%%, got %+v", info)
+	}
+
+	dayChange, err := service.DayChange(context.Background(), userID, "^GSPC", 1.5)
+	if err != nil {
+		t.Fatalf("Failed to compare day change: %v", err)
+	}
+	wantDayChange := (105.0/101.0 - 1) * 100
+	if math.Abs(dayChange.DayChangePercent-wantDayChange) > 1e-9 {
+		t.Errorf("Expected benchmark day change %.4f, got %.4f", wantDayChange, dayChange.DayChangePercent)
+	}
+	if math.Abs(dayChange.ExcessDayChange-(1.5-wantDayChange)) > 1e-9 {
+		t.Errorf("Expected excess day change %.4f, got %.4f", 1.5-wantDayChange, dayChange.ExcessDayChange)
+	}
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/telemetry"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// binanceAPIURL is the Binance spot API
+	binanceAPIURL = "https://api.binance.com"
+	// binancePageSize is the number of trades fetched per request
+	binancePageSize = 1000
+	// binanceUnknownMarket is Binance's error code for a market that doesn't exist
+	binanceUnknownMarket = -1121
+)
+
+// errBinanceUnknownMarket is returned for trades of a market that doesn't exist
+var errBinanceUnknownMarket = errors.New("unknown Binance market")
+
+// BinanceConnector syncs a Binance account with a read-only API key. Binance
+// only lists trades per market, so the dollar markets of every asset held, or
+// held at an earlier sync, are queried.
+type BinanceConnector struct {
+	baseURL string
+	client  *http.Client
+	cipher  *TokenCipher
+}
+
+// NewBinanceConnector creates a new BinanceConnector instance decrypting
+// API keys with cipher
+func NewBinanceConnector(cipher *TokenCipher) *BinanceConnector {
+	return &BinanceConnector{
+		baseURL: binanceAPIURL,
+		cipher:  cipher,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: telemetry.Transport(nil),
+		},
+	}
+}
+
+// Name returns the provider identifier
+func (c *BinanceConnector) Name() string {
+	return models.BrokerBinance
+}
+
+// CheckKey checks that the API key works and can neither trade nor withdraw
+func (c *BinanceConnector) CheckKey(ctx context.Context, link *models.BrokerageLink) error {
+	var restrictions struct {
+		EnableReading              bool `json:"enableReading"`
+		EnableWithdrawals          bool `json:"enableWithdrawals"`
+		EnableInternalTransfer     bool `json:"enableInternalTransfer"`
+		PermitsUniversalTransfer   bool `json:"permitsUniversalTransfer"`
+		EnableSpotAndMarginTrading bool `json:"enableSpotAndMarginTrading"`
+		EnableMargin               bool `json:"enableMargin"`
+		EnableFutures              bool `json:"enableFutures"`
+		EnableVanillaOptions       bool `json:"enableVanillaOptions"`
+	}
+	if err := c.get(ctx, link, "/sapi/v1/account/apiRestrictions", nil, &restrictions); err != nil {
+		return err
+	}
+	if !restrictions.EnableReading {
+		return fmt.Errorf("the API key can't read the account")
+	}
+	if restrictions.EnableWithdrawals || restrictions.EnableInternalTransfer || restrictions.PermitsUniversalTransfer ||
+		restrictions.EnableSpotAndMarginTrading || restrictions.EnableMargin || restrictions.EnableFutures || restrictions.EnableVanillaOptions {
+		return ErrExchangeKeyNotReadOnly
+	}
+	return nil
+}
+
+// binanceBalance is an asset balance of the account
+type binanceBalance struct {
+	Asset  string `json:"asset"`
+	Free   string `json:"free"`
+	Locked string `json:"locked"`
+}
+
+// balances returns the account's non-zero balances
+func (c *BinanceConnector) balances(ctx context.Context, link *models.BrokerageLink) ([]binanceBalance, error) {
+	var account struct {
+		Balances []binanceBalance `json:"balances"`
+	}
+	err := c.get(ctx, link, "/api/v3/account", url.Values{"omitZeroBalances": {"true"}}, &account)
+	return account.Balances, err
+}
+
+// Holdings returns the account's crypto balances as positions in their
+// dollar pairs
+func (c *BinanceConnector) Holdings(ctx context.Context, link *models.BrokerageLink) ([]models.BrokerPosition, error) {
+	balances, err := c.balances(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := newBrokerPositions()
+	for _, balance := range balances {
+		if isCryptoPosition(balance.Asset) {
+			merged.add(cryptoSymbol(balance.Asset), parseExchangeNumber(balance.Free)+parseExchangeNumber(balance.Locked), 0)
+		}
+	}
+	return merged.list(), nil
+}
+
+// Trades returns the account's trades in the dollar markets between start
+// and end, recording the assets queried on the link
+func (c *BinanceConnector) Trades(ctx context.Context, link *models.BrokerageLink, start, end time.Time) ([]ImportedTrade, error) {
+	balances, err := c.balances(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	assets := make(map[string]bool, len(link.Assets)+len(balances))
+	for _, asset := range link.Assets {
+		assets[asset] = true
+	}
+	for _, balance := range balances {
+		if isCryptoPosition(balance.Asset) {
+			assets[strings.ToUpper(balance.Asset)] = true
+		}
+	}
+	link.Assets = make([]string, 0, len(assets))
+	for asset := range assets {
+		link.Assets = append(link.Assets, asset)
+	}
+	sort.Strings(link.Assets)
+
+	trades := []ImportedTrade{}
+	for _, asset := range link.Assets {
+		for _, quote := range cryptoDollarQuotes {
+			market := asset + quote
+			fills, err := c.marketTrades(ctx, link, market, start, end)
+			if errors.Is(err, errBinanceUnknownMarket) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, fill := range fills {
+				trades = append(trades, fill.trade(len(trades)+1, asset, quote, market))
+			}
+		}
+	}
+	return trades, nil
+}
+
+// binanceTrade is a fill of one of the account's orders
+type binanceTrade struct {
+	ID              int64  `json:"id"`
+	Price           string `json:"price"`
+	Qty             string `json:"qty"`
+	Commission      string `json:"commission"`
+	CommissionAsset string `json:"commissionAsset"`
+	Time            int64  `json:"time"`
+	IsBuyer         bool   `json:"isBuyer"`
+}
+
+// trade converts a fill in a dollar market into an imported trade. A
+// commission paid in the asset bought reduces the shares received; one paid
+// in a third asset, such as BNB, can't be priced and is left out.
+func (t binanceTrade) trade(line int, asset, quote, market string) ImportedTrade {
+	shares := parseExchangeNumber(t.Qty)
+	price := parseExchangeNumber(t.Price)
+	commission := parseExchangeNumber(t.Commission)
+	action := "sell"
+	if t.IsBuyer {
+		action = "buy"
+	}
+
+	fees := 0.0
+	switch strings.ToUpper(t.CommissionAsset) {
+	case quote:
+		fees = commission
+	case asset:
+		fees = commission * price
+		if t.IsBuyer {
+			shares -= commission
+		}
+	}
+
+	date := time.UnixMilli(t.Time).UTC()
+	return ImportedTrade{
+		Line:        line,
+		Symbol:      cryptoSymbol(asset),
+		Action:      action,
+		Shares:      shares,
+		Price:       price,
+		Fees:        fees,
+		Currency:    "USD",
+		Date:        time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC),
+		Description: market,
+		ExternalID:  market + ":" + strconv.FormatInt(t.ID, 10),
+	}
+}
+
+// marketTrades returns the account's fills in a market between start and end
+func (c *BinanceConnector) marketTrades(ctx context.Context, link *models.BrokerageLink, market string, start, end time.Time) ([]binanceTrade, error) {
+	params := url.Values{
+		"symbol":    {market},
+		"startTime": {strconv.FormatInt(start.UnixMilli(), 10)},
+		"limit":     {strconv.Itoa(binancePageSize)},
+	}
+	var trades []binanceTrade
+	for {
+		var page []binanceTrade
+		if err := c.get(ctx, link, "/api/v3/myTrades", params, &page); err != nil {
+			return nil, err
+		}
+		for _, trade := range page {
+			if trade.Time > end.UnixMilli() {
+				return trades, nil
+			}
+			trades = append(trades, trade)
+		}
+		if len(page) < binancePageSize {
+			return trades, nil
+		}
+
+		// Later pages continue from the last trade ID instead of the start time
+		params.Del("startTime")
+		params.Set("fromId", strconv.FormatInt(page[len(page)-1].ID+1, 10))
+	}
+}
+
+// get sends a signed Binance request: the query string carries a timestamp
+// and its HMAC-SHA256 keyed with the secret key
+func (c *BinanceConnector) get(ctx context.Context, link *models.BrokerageLink, path string, params url.Values, out interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	apiKey, secret, err := exchangeKey(c.cipher, link)
+	if err != nil {
+		return err
+	}
+	params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	params.Set("recvWindow", "10000")
+	query := params.Encode()
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(query))
+	query += "&signature=" + hex.EncodeToString(mac.Sum(nil))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+query, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-MBX-APIKEY", apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Binance: %w", errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read Binance response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var binanceErr struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if json.Unmarshal(payload, &binanceErr) == nil && binanceErr.Msg != "" {
+			if binanceErr.Code == binanceUnknownMarket {
+				return errBinanceUnknownMarket
+			}
+			return fmt.Errorf("binance %d: %s", binanceErr.Code, binanceErr.Msg)
+		}
+		return fmt.Errorf("binance responded with status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("invalid Binance response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/telemetry"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// coinbaseAPIURL is the Coinbase Advanced Trade API
+	coinbaseAPIURL = "https://api.coinbase.com"
+	// coinbasePageSize is the number of accounts or fills fetched per request
+	coinbasePageSize = 250
+)
+
+// CoinbaseConnector syncs a Coinbase account with a read-only CDP API key,
+// whose name is the link's API key and whose EC private key is its secret
+type CoinbaseConnector struct {
+	baseURL string
+	client  *http.Client
+	cipher  *TokenCipher
+}
+
+// NewCoinbaseConnector creates a new CoinbaseConnector instance decrypting
+// API keys with cipher
+func NewCoinbaseConnector(cipher *TokenCipher) *CoinbaseConnector {
+	return &CoinbaseConnector{
+		baseURL: coinbaseAPIURL,
+		cipher:  cipher,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: telemetry.Transport(nil),
+		},
+	}
+}
+
+// Name returns the provider identifier
+func (c *CoinbaseConnector) Name() string {
+	return models.BrokerCoinbase
+}
+
+// CheckKey checks that the API key works and can neither trade nor transfer
+func (c *CoinbaseConnector) CheckKey(ctx context.Context, link *models.BrokerageLink) error {
+	var permissions struct {
+		CanView     bool `json:"can_view"`
+		CanTrade    bool `json:"can_trade"`
+		CanTransfer bool `json:"can_transfer"`
+	}
+	if err := c.get(ctx, link, "/api/v3/brokerage/key_permissions", nil, &permissions); err != nil {
+		return err
+	}
+	if !permissions.CanView {
+		return fmt.Errorf("the API key can't view the account")
+	}
+	if permissions.CanTrade || permissions.CanTransfer {
+		return ErrExchangeKeyNotReadOnly
+	}
+	return nil
+}
+
+// Holdings returns the account's crypto balances as positions in their
+// dollar pairs
+func (c *CoinbaseConnector) Holdings(ctx context.Context, link *models.BrokerageLink) ([]models.BrokerPosition, error) {
+	type amount struct {
+		Value string `json:"value"`
+	}
+	merged := newBrokerPositions()
+	params := url.Values{"limit": {fmt.Sprint(coinbasePageSize)}}
+	for {
+		var page struct {
+			Accounts []struct {
+				Currency         string `json:"currency"`
+				AvailableBalance amount `json:"available_balance"`
+				Hold             amount `json:"hold"`
+			} `json:"accounts"`
+			HasNext bool   `json:"has_next"`
+			Cursor  string `json:"cursor"`
+		}
+		if err := c.get(ctx, link, "/api/v3/brokerage/accounts", params, &page); err != nil {
+			return nil, err
+		}
+		for _, account := range page.Accounts {
+			balance := parseExchangeNumber(account.AvailableBalance.Value) + parseExchangeNumber(account.Hold.Value)
+			if isCryptoPosition(account.Currency) && balance != 0 {
+				merged.add(cryptoSymbol(account.Currency), balance, 0)
+			}
+		}
+		if !page.HasNext || page.Cursor == "" {
+			return merged.list(), nil
+		}
+		params.Set("cursor", page.Cursor)
+	}
+}
+
+// Trades returns the account's fills in dollar markets between start and end
+func (c *CoinbaseConnector) Trades(ctx context.Context, link *models.BrokerageLink, start, end time.Time) ([]ImportedTrade, error) {
+	params := url.Values{
+		"start_sequence_timestamp": {start.UTC().Format(time.RFC3339)},
+		"end_sequence_timestamp":   {end.UTC().Format(time.RFC3339)},
+		"limit":                    {fmt.Sprint(coinbasePageSize)},
+	}
+	trades := []ImportedTrade{}
+	for {
+		var page struct {
+			Fills []struct {
+				EntryID     string `json:"entry_id"`
+				TradeTime   string `json:"trade_time"`
+				Price       string `json:"price"`
+				Size        string `json:"size"`
+				Commission  string `json:"commission"`
+				ProductID   string `json:"product_id"`
+				Side        string `json:"side"`
+				SizeInQuote bool   `json:"size_in_quote"`
+			} `json:"fills"`
+			Cursor string `json:"cursor"`
+		}
+		if err := c.get(ctx, link, "/api/v3/brokerage/orders/historical/fills", params, &page); err != nil {
+			return nil, err
+		}
+
+		for _, fill := range page.Fills {
+			asset, quote, ok := strings.Cut(strings.ToUpper(fill.ProductID), "-")
+			action := strings.ToLower(fill.Side)
+			if !ok || !isCryptoDollarQuote(quote) || (action != "buy" && action != "sell") {
+				continue
+			}
+			date, err := time.Parse(time.RFC3339, fill.TradeTime)
+			if err != nil {
+				continue
+			}
+			price := parseExchangeNumber(fill.Price)
+			shares := parseExchangeNumber(fill.Size)
+			if fill.SizeInQuote {
+				if price <= 0 {
+					continue
+				}
+				shares /= price
+			}
+			date = date.UTC()
+			trades = append(trades, ImportedTrade{
+				Line:        len(trades) + 1,
+				Symbol:      cryptoSymbol(asset),
+				Action:      action,
+				Shares:      shares,
+				Price:       price,
+				Fees:        parseExchangeNumber(fill.Commission),
+				Currency:    "USD",
+				Date:        time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC),
+				Description: fill.ProductID,
+				ExternalID:  fill.EntryID,
+			})
+		}
+
+		if len(page.Fills) == 0 || page.Cursor == "" {
+			return trades, nil
+		}
+		params.Set("cursor", page.Cursor)
+	}
+}
+
+// token returns the JWT authenticating one request: signed with the key's EC
+// private key, naming the key and bound to the request's method and path
+func (c *CoinbaseConnector) token(link *models.BrokerageLink, method, path string) (string, error) {
+	apiKey, secret, err := exchangeKey(c.cipher, link)
+	if err != nil {
+		return "", err
+	}
+	// Keys pasted from the downloaded JSON file keep their escaped newlines
+	key, err := jwt.ParseECPrivateKeyFromPEM([]byte(strings.ReplaceAll(secret, `\n`, "\n")))
+	if err != nil {
+		return "", fmt.Errorf("invalid Coinbase private key: %w", err)
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	host := c.baseURL
+	if parsed, err := url.Parse(c.baseURL); err == nil {
+		host = parsed.Host
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"sub": apiKey,
+		"iss": "cdp",
+		"nbf": now.Unix(),
+		"exp": now.Add(2 * time.Minute).Unix(),
+		"uri": method + " " + host + path,
+	})
+	token.Header["kid"] = apiKey
+	token.Header["nonce"] = hex.EncodeToString(nonce)
+	return token.SignedString(key)
+}
+
+// get sends an authenticated Coinbase request
+func (c *CoinbaseConnector) get(ctx context.Context, link *models.BrokerageLink, path string, params url.Values, out interface{}) error {
+	token, err := c.token(link, http.MethodGet, path)
+	if err != nil {
+		return err
+	}
+
+	endpoint := c.baseURL + path
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Coinbase: %w", errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read Coinbase response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var coinbaseErr struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(payload, &coinbaseErr) == nil && coinbaseErr.Message != "" {
+			return fmt.Errorf("coinbase %s: %s", coinbaseErr.Error, coinbaseErr.Message)
+		}
+		return fmt.Errorf("coinbase responded with status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("invalid Coinbase response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/telemetry"
+	"strings"
+	"time"
+)
+
+// plaidPageSize is the number of investment transactions fetched per request
+const plaidPageSize = 500
+
+// plaidEnvironments maps Plaid environments to their API hosts
+var plaidEnvironments = map[string]string{
+	"sandbox":    "https://sandbox.plaid.com",
+	"production": "https://production.plaid.com",
+}
+
+// PlaidAggregator links brokerage accounts with Plaid Investments. The
+// client opens Plaid Link with the link token and completes the link with
+// the public token Link returns.
+type PlaidAggregator struct {
+	clientID string
+	secret   string
+	baseURL  string
+	client   *http.Client
+}
+
+// NewPlaidAggregator creates a new PlaidAggregator instance for the sandbox
+// or production environment
+func NewPlaidAggregator(clientID, secret, environment string) *PlaidAggregator {
+	baseURL, ok := plaidEnvironments[environment]
+	if !ok {
+		baseURL = plaidEnvironments["sandbox"]
+	}
+	return &PlaidAggregator{
+		clientID: clientID,
+		secret:   secret,
+		baseURL:  baseURL,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: telemetry.Transport(nil),
+		},
+	}
+}
+
+// Name returns the provider identifier
+func (p *PlaidAggregator) Name() string {
+	return models.BrokerPlaid
+}
+
+// Start creates a Link token for the user
+func (p *PlaidAggregator) Start(ctx context.Context, link *models.BrokerageLink) (string, error) {
+	var response struct {
+		LinkToken string `json:"link_token"`
+	}
+	err := p.post(ctx, "/link/token/create", map[string]interface{}{
+		"client_name":   "Stock Tracker",
+		"language":      "en",
+		"country_codes": []string{"US", "CA", "GB"},
+		"products":      []string{"investments"},
+		"user":          map[string]string{"client_user_id": link.UserID.Hex()},
+	}, &response)
+	return response.LinkToken, err
+}
+
+// Complete exchanges Link's public token for an access token
+func (p *PlaidAggregator) Complete(ctx context.Context, link *models.BrokerageLink, publicToken string) error {
+	if publicToken == "" {
+		return fmt.Errorf("a public token is required")
+	}
+	var response struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := p.post(ctx, "/item/public_token/exchange", map[string]interface{}{
+		"public_token": publicToken,
+	}, &response); err != nil {
+		return err
+	}
+	link.Credential = response.AccessToken
+	return nil
+}
+
+// plaidSecurity is a security referenced by holdings and transactions
+type plaidSecurity struct {
+	SecurityID   string `json:"security_id"`
+	TickerSymbol string `json:"ticker_symbol"`
+	Type         string `json:"type"`
+}
+
+// plaidSymbols maps security IDs to the ticker of every security except cash
+func plaidSymbols(securities []plaidSecurity) map[string]string {
+	symbols := make(map[string]string, len(securities))
+	for _, security := range securities {
+		if security.Type == "cash" || security.TickerSymbol == "" {
+			continue
+		}
+		symbols[security.SecurityID] = strings.ToUpper(security.TickerSymbol)
+	}
+	return symbols
+}
+
+// Holdings returns the positions across the item's accounts
+func (p *PlaidAggregator) Holdings(ctx context.Context, link *models.BrokerageLink) ([]models.BrokerPosition, error) {
+	var response struct {
+		Holdings []struct {
+			SecurityID       string  `json:"security_id"`
+			Quantity         float64 `json:"quantity"`
+			InstitutionPrice float64 `json:"institution_price"`
+		} `json:"holdings"`
+		Securities []plaidSecurity `json:"securities"`
+	}
+	if err := p.post(ctx, "/investments/holdings/get", map[string]interface{}{
+		"access_token": link.Credential,
+	}, &response); err != nil {
+		return nil, err
+	}
+
+	symbols := plaidSymbols(response.Securities)
+	merged := newBrokerPositions()
+	for _, holding := range response.Holdings {
+		if symbol, ok := symbols[holding.SecurityID]; ok {
+			merged.add(symbol, holding.Quantity, holding.InstitutionPrice)
+		}
+	}
+	return merged.list(), nil
+}
+
+// Trades returns the item's buys and sells between start and end
+func (p *PlaidAggregator) Trades(ctx context.Context, link *models.BrokerageLink, start, end time.Time) ([]ImportedTrade, error) {
+	trades := []ImportedTrade{}
+	for offset := 0; ; offset += plaidPageSize {
+		var response struct {
+			InvestmentTransactions []struct {
+				InvestmentTransactionID string  `json:"investment_transaction_id"`
+				SecurityID              string  `json:"security_id"`
+				Date                    string  `json:"date"`
+				Name                    string  `json:"name"`
+				Type                    string  `json:"type"`
+				Quantity                float64 `json:"quantity"`
+				Price                   float64 `json:"price"`
+				Fees                    float64 `json:"fees"`
+				IsoCurrencyCode         string  `json:"iso_currency_code"`
+			} `json:"investment_transactions"`
+			Securities []plaidSecurity `json:"securities"`
+			Total      int             `json:"total_investment_transactions"`
+		}
+		if err := p.post(ctx, "/investments/transactions/get", map[string]interface{}{
+			"access_token": link.Credential,
+			"start_date":   start.UTC().Format("2006-01-02"),
+			"end_date":     end.UTC().Format("2006-01-02"),
+			"options":      map[string]int{"count": plaidPageSize, "offset": offset},
+		}, &response); err != nil {
+			return nil, err
+		}
+
+		symbols := plaidSymbols(response.Securities)
+		for _, tx := range response.InvestmentTransactions {
+			symbol, ok := symbols[tx.SecurityID]
+			if !ok || (tx.Type != "buy" && tx.Type != "sell") {
+				continue
+			}
+			date, err := time.Parse("2006-01-02", tx.Date)
+			if err != nil {
+				continue
+			}
+			currency, ok := statementCurrency(tx.IsoCurrencyCode)
+			if !ok {
+				continue
+			}
+			trades = append(trades, ImportedTrade{
+				Line:        len(trades) + 1,
+				Symbol:      symbol,
+				Action:      tx.Type,
+				Shares:      math.Abs(tx.Quantity),
+				Price:       tx.Price,
+				Fees:        math.Abs(tx.Fees),
+				Currency:    currency,
+				Date:        date,
+				Description: tx.Name,
+				ExternalID:  tx.InvestmentTransactionID,
+			})
+		}
+
+		if len(response.InvestmentTransactions) < plaidPageSize || offset+plaidPageSize >= response.Total {
+			return trades, nil
+		}
+	}
+}
+
+// Remove deletes the Plaid item, revoking the access token
+func (p *PlaidAggregator) Remove(ctx context.Context, link *models.BrokerageLink) error {
+	return p.post(ctx, "/item/remove", map[string]interface{}{
+		"access_token": link.Credential,
+	}, nil)
+}
+
+// post calls a Plaid endpoint with the client credentials added to the body
+func (p *PlaidAggregator) post(ctx context.Context, path string, body map[string]interface{}, out interface{}) error {
+	body["client_id"] = p.clientID
+	body["secret"] = p.secret
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Plaid: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read Plaid response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var plaidErr struct {
+			ErrorCode    string `json:"error_code"`
+			ErrorMessage string `json:"error_message"`
+		}
+		if json.Unmarshal(payload, &plaidErr) == nil && plaidErr.ErrorCode != "" {
+			return fmt.Errorf("plaid %s: %s", plaidErr.ErrorCode, plaidErr.ErrorMessage)
+		}
+		return fmt.Errorf("plaid responded with status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("invalid Plaid response: %w", err)
+	}
+	return nil
+}
+
+// brokerPositions merges positions of a symbol held in several accounts
+type brokerPositions struct {
+	order  []string
+	merged map[string]*models.BrokerPosition
+}
+
+func newBrokerPositions() *brokerPositions {
+	return &brokerPositions{merged: make(map[string]*models.BrokerPosition)}
+}
+
+// add adds shares of a symbol, keeping the first positive price
+func (b *brokerPositions) add(symbol string, shares, price float64) {
+	position, ok := b.merged[symbol]
+	if !ok {
+		position = &models.BrokerPosition{Symbol: symbol}
+		b.merged[symbol] = position
+		b.order = append(b.order, symbol)
+	}
+	position.Shares += shares
+	if position.Price <= 0 && price > 0 {
+		position.Price = price
+	}
+}
+
+// list returns the merged positions in the order symbols were first added
+func (b *brokerPositions) list() []models.BrokerPosition {
+	positions := make([]models.BrokerPosition, 0, len(b.order))
+	for _, symbol := range b.order {
+		positions = append(positions, *b.merged[symbol])
+	}
+	return positions
+}
@@ -0,0 +1,605 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrBrokerageUnavailable    = errors.New("brokerage aggregator is not configured")
+	ErrBrokerageLinkNotFound   = errors.New("brokerage link not found")
+	ErrBrokerageLinkNotPending = errors.New("brokerage link is already complete")
+	ErrBrokerageLinkPending    = errors.New("brokerage link is not complete")
+	ErrTooManyBrokerageLinks   = errors.New("too many brokerage links")
+	ErrBrokerageSyncFailed     = errors.New("brokerage sync failed")
+	ErrExchangeKeyRejected     = errors.New("exchange rejected the API key")
+	ErrExchangeKeyNotReadOnly  = errors.New("exchange API key can trade or withdraw")
+	ErrCredentialKeyMissing    = errors.New("credential encryption key is not configured")
+)
+
+const (
+	// maxBrokerageLinks bounds the brokerage links of a single user
+	maxBrokerageLinks = 10
+	// brokerageInitialHistory is how far back the first sync of a link
+	// fetches transactions
+	brokerageInitialHistory = 2 * 365 * 24 * time.Hour
+	// brokerageSyncOverlap is refetched before the previous sync, as
+	// aggregators can report trades a few days late
+	brokerageSyncOverlap = 7 * 24 * time.Hour
+)
+
+// BrokerageAggregator links brokerage accounts through a third party and
+// reads their positions and trades
+type BrokerageAggregator interface {
+	// Name returns the provider identifier, such as models.BrokerPlaid
+	Name() string
+	// Start begins linking a new link. It returns what the client opens the
+	// aggregator's flow with, a link token or a URL, and may set the link's
+	// credential.
+	Start(ctx context.Context, link *models.BrokerageLink) (string, error)
+	// Complete finishes linking with what the client got back from the flow,
+	// setting the link's credential
+	Complete(ctx context.Context, link *models.BrokerageLink, publicToken string) error
+	// Holdings returns the positions across the link's accounts
+	Holdings(ctx context.Context, link *models.BrokerageLink) ([]models.BrokerPosition, error)
+	// Trades returns the buys and sells between start and end, with the
+	// aggregator's transaction IDs as external IDs
+	Trades(ctx context.Context, link *models.BrokerageLink, start, end time.Time) ([]ImportedTrade, error)
+	// Remove revokes the aggregator's access to the accounts
+	Remove(ctx context.Context, link *models.BrokerageLink) error
+}
+
+// ExchangeConnector syncs a crypto exchange account with an API key, which
+// is the link's API key and credential. The key reaches the connector
+// encrypted; it decrypts it with exchangeKey to sign each request.
+type ExchangeConnector interface {
+	// Name returns the provider identifier, such as models.BrokerBinance
+	Name() string
+	// CheckKey checks that the API key works and is read-only, returning
+	// ErrExchangeKeyNotReadOnly for keys that can trade or withdraw
+	CheckKey(ctx context.Context, link *models.BrokerageLink) error
+	// Holdings returns the crypto balances as positions in their dollar pairs
+	Holdings(ctx context.Context, link *models.BrokerageLink) ([]models.BrokerPosition, error)
+	// Trades returns the trades in dollar markets between start and end,
+	// with the exchange's trade IDs as external IDs
+	Trades(ctx context.Context, link *models.BrokerageLink, start, end time.Time) ([]ImportedTrade, error)
+}
+
+// brokerageSource reads a link's positions and trades, through an
+// aggregator or an exchange
+type brokerageSource interface {
+	Holdings(ctx context.Context, link *models.BrokerageLink) ([]models.BrokerPosition, error)
+	Trades(ctx context.Context, link *models.BrokerageLink, start, end time.Time) ([]ImportedTrade, error)
+}
+
+// BrokerageLinkStart is returned when linking starts
+type BrokerageLinkStart struct {
+	Link *models.BrokerageLink `json:"link"`
+	// Token is the Plaid link token, or the SnapTrade connection portal URL
+	Token string `json:"token"`
+}
+
+// BrokerageSyncResult details a sync of a brokerage link
+type BrokerageSyncResult struct {
+	models.BrokerageSyncCounts
+	Failed        []ImportFailure       `json:"failures"`
+	Discrepancies []PositionDiscrepancy `json:"positionDiscrepancies"`
+}
+
+// BrokerageService links brokerage accounts through Plaid or SnapTrade, and
+// crypto exchange accounts with API keys, and syncs their trades into the
+// user's transactions.
+//
+// Synced trades carry the aggregator's IDs, so later syncs skip them even if
+// they were edited. A synced trade matching a manually entered transaction
+// is a duplicate: the manual entry is kept and the trade skipped. Positions
+// that still differ from the broker's are reported, never overwritten, and
+// can be settled with the reconciliation suggestions.
+//
+// Links are stored with their credentials encrypted by cipher. Aggregator
+// credentials are decrypted to call the aggregator; exchange API keys are
+// sealed as soon as they are received, and only the exchange connector
+// decrypts them.
+type BrokerageService struct {
+	repos            repository.Repositories
+	portfolioService *PortfolioService
+	importService    *ImportService
+	cipher           *TokenCipher
+	aggregators      map[string]BrokerageAggregator
+	exchanges        map[string]ExchangeConnector
+}
+
+// NewBrokerageService creates a new BrokerageService instance offering the
+// given aggregators. Without a cipher no link can be stored.
+func NewBrokerageService(importService *ImportService, cipher *TokenCipher, aggregators ...BrokerageAggregator) *BrokerageService {
+	service := &BrokerageService{
+		repos:            importService.portfolioService.repos,
+		portfolioService: importService.portfolioService,
+		importService:    importService,
+		cipher:           cipher,
+		aggregators:      make(map[string]BrokerageAggregator, len(aggregators)),
+		exchanges:        make(map[string]ExchangeConnector),
+	}
+	for _, aggregator := range aggregators {
+		service.aggregators[aggregator.Name()] = aggregator
+	}
+	return service
+}
+
+// WithExchanges offers the given crypto exchanges and returns the service
+func (s *BrokerageService) WithExchanges(exchanges ...ExchangeConnector) *BrokerageService {
+	for _, exchange := range exchanges {
+		s.exchanges[exchange.Name()] = exchange
+	}
+	return s
+}
+
+// Providers returns the configured aggregators
+func (s *BrokerageService) Providers() []string {
+	providers := make([]string, 0, len(s.aggregators))
+	for name := range s.aggregators {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// Exchanges returns the crypto exchanges that can be linked with an API key
+func (s *BrokerageService) Exchanges() []string {
+	exchanges := make([]string, 0, len(s.exchanges))
+	for name := range s.exchanges {
+		exchanges = append(exchanges, name)
+	}
+	sort.Strings(exchanges)
+	return exchanges
+}
+
+// StartLink begins linking a brokerage through the provider. The link stays
+// pending until CompleteLink.
+func (s *BrokerageService) StartLink(userID primitive.ObjectID, provider string) (*BrokerageLinkStart, error) {
+	aggregator, ok := s.aggregators[provider]
+	if !ok {
+		return nil, ErrBrokerageUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := s.checkLinkLimit(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	link := &models.BrokerageLink{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Provider:  provider,
+		Status:    models.BrokerageLinkPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	token, err := aggregator.Start(ctx, link)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBrokerageSyncFailed, err)
+	}
+	if err := s.insertLink(ctx, link); err != nil {
+		return nil, err
+	}
+
+	return &BrokerageLinkStart{Link: link, Token: token}, nil
+}
+
+// LinkExchange links a crypto exchange account with a read-only API key. The
+// link is complete at once; its trades are synced on the next run of the
+// sync job, or with SyncLink.
+func (s *BrokerageService) LinkExchange(userID primitive.ObjectID, req models.ExchangeLinkRequest) (*models.BrokerageLink, error) {
+	exchange, ok := s.exchanges[req.Provider]
+	if !ok {
+		return nil, ErrBrokerageUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := s.checkLinkLimit(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	link, err := s.sealedLink(&models.BrokerageLink{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Provider:    req.Provider,
+		Institution: req.Label,
+		Credential:  strings.TrimSpace(req.APISecret),
+		APIKey:      strings.TrimSpace(req.APIKey),
+		Status:      models.BrokerageLinkActive,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := exchange.CheckKey(ctx, link); err != nil {
+		if errors.Is(err, ErrExchangeKeyNotReadOnly) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrExchangeKeyRejected, err)
+	}
+	if err := s.insertLink(ctx, link); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// checkLinkLimit fails once the user has as many links as allowed
+func (s *BrokerageService) checkLinkLimit(ctx context.Context, userID primitive.ObjectID) error {
+	existing, err := s.repos.BrokerageLinks.FindByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch brokerage links: %w", err)
+	}
+	if len(existing) >= maxBrokerageLinks {
+		return ErrTooManyBrokerageLinks
+	}
+	return nil
+}
+
+// CompleteLink finishes a pending link with what the aggregator's flow
+// returned. The accounts are synced on the next run of the sync job, or
+// with SyncLink.
+func (s *BrokerageService) CompleteLink(userID, linkID primitive.ObjectID, req models.BrokerageLinkCompleteRequest) (*models.BrokerageLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	link, err := s.findLink(ctx, userID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if link.Status != models.BrokerageLinkPending {
+		return nil, ErrBrokerageLinkNotPending
+	}
+	aggregator, ok := s.aggregators[link.Provider]
+	if !ok {
+		return nil, ErrBrokerageUnavailable
+	}
+
+	if req.Institution != "" {
+		link.Institution = req.Institution
+	}
+	if err := s.openCredential(link); err != nil {
+		return nil, err
+	}
+	if err := aggregator.Complete(ctx, link, req.PublicToken); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBrokerageSyncFailed, err)
+	}
+	link.Status = models.BrokerageLinkActive
+	link.UpdatedAt = time.Now()
+	if err := s.updateLink(ctx, link); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// ListLinks returns the user's brokerage links, oldest first
+func (s *BrokerageService) ListLinks(userID primitive.ObjectID) ([]models.BrokerageLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	links, err := s.repos.BrokerageLinks.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch brokerage links: %w", err)
+	}
+	return links, nil
+}
+
+// DeleteLink unlinks a brokerage. Transactions synced from it are kept. The
+// API key of an exchange link is forgotten; revoking it is up to the user.
+func (s *BrokerageService) DeleteLink(userID, linkID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	link, err := s.findLink(ctx, userID, linkID)
+	if err != nil {
+		return err
+	}
+	if aggregator, ok := s.aggregators[link.Provider]; ok && link.Credential != "" {
+		// The link is deleted even if the aggregator can't be reached; its
+		// access lapses when the user removes the app at the broker
+		if err := s.openCredential(link); err != nil {
+			fmt.Printf("[Brokerage] Failed to revoke %s link %s: %v\n", link.Provider, link.ID.Hex(), err)
+		} else if err := aggregator.Remove(ctx, link); err != nil {
+			fmt.Printf("[Brokerage] Failed to revoke %s link %s: %v\n", link.Provider, link.ID.Hex(), err)
+		}
+	}
+
+	err = s.repos.BrokerageLinks.Delete(ctx, userID, linkID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrBrokerageLinkNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete brokerage link: %w", err)
+	}
+	return nil
+}
+
+// SyncLink syncs a link now
+func (s *BrokerageService) SyncLink(userID, linkID primitive.ObjectID) (*BrokerageSyncResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	link, err := s.findLink(ctx, userID, linkID)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	if link.Status == models.BrokerageLinkPending {
+		return nil, ErrBrokerageLinkPending
+	}
+
+	result, err := s.sync(link)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBrokerageSyncFailed, err)
+	}
+	return result, nil
+}
+
+// SyncAll syncs every completed link. Each link is claimed with a
+// conditional update first, so with several instances only one syncs it.
+func (s *BrokerageService) SyncAll() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	links, err := s.repos.BrokerageLinks.FindSyncable(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to fetch brokerage links: %w", err)
+	}
+
+	synced := 0
+	var errs []error
+	for i := range links {
+		link := &links[i]
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		now := time.Now()
+		err := s.repos.BrokerageLinks.ClaimSync(ctx, link.UserID, link.ID, link.LastSyncedAt, now)
+		cancel()
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to claim brokerage link %s: %w", link.ID.Hex(), err))
+			continue
+		}
+		link.LastSyncedAt = &now
+
+		if _, err := s.sync(link); err != nil {
+			errs = append(errs, fmt.Errorf("failed to sync %s link %s: %w", link.Provider, link.ID.Hex(), err))
+			continue
+		}
+		synced++
+	}
+
+	if synced > 0 {
+		fmt.Printf("[Brokerage] Synced %d brokerage links\n", synced)
+	}
+	return errors.Join(errs...)
+}
+
+// sync imports the link's trades since the previous sync, compares its
+// positions with the tracked ones and records the outcome on the link
+func (s *BrokerageService) sync(link *models.BrokerageLink) (*BrokerageSyncResult, error) {
+	result, syncErr := s.syncTrades(link)
+
+	now := time.Now()
+	link.LastSyncedAt = &now
+	link.UpdatedAt = now
+	if syncErr != nil {
+		link.Status = models.BrokerageLinkError
+		link.LastError = syncErr.Error()
+	} else {
+		link.Status = models.BrokerageLinkActive
+		link.LastError = ""
+		link.SyncedThrough = &now
+		link.LastSync = &result.BrokerageSyncCounts
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.updateLink(ctx, link); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, errors.Join(syncErr, err)
+	}
+	if syncErr != nil {
+		return nil, syncErr
+	}
+	return result, nil
+}
+
+// syncTrades fetches and imports the link's trades and reports positions
+// that differ from the broker's
+func (s *BrokerageService) syncTrades(link *models.BrokerageLink) (*BrokerageSyncResult, error) {
+	source, ok := s.source(link.Provider)
+	if !ok {
+		return nil, ErrBrokerageUnavailable
+	}
+	// Exchange keys stay sealed for their connector to decrypt
+	if _, ok := s.aggregators[link.Provider]; ok {
+		if err := s.openCredential(link); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	end := time.Now()
+	start := end.Add(-brokerageInitialHistory)
+	if link.SyncedThrough != nil {
+		start = link.SyncedThrough.Add(-brokerageSyncOverlap)
+	}
+	trades, err := source.Trades(ctx, link, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trades: %w", err)
+	}
+	holdings, err := source.Holdings(ctx, link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch holdings: %w", err)
+	}
+
+	imported, err := s.importService.ImportTrades(link.UserID, link.Provider, trades)
+	if err != nil {
+		return nil, err
+	}
+	if imported.Imported > 0 {
+		dataVersions.bump(link.UserID)
+	}
+
+	discrepancies, err := s.positionDiscrepancies(ctx, link.UserID, holdings)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BrokerageSyncResult{
+		BrokerageSyncCounts: models.BrokerageSyncCounts{
+			Imported:      imported.Imported,
+			Duplicates:    imported.Duplicates,
+			Failed:        len(imported.Failed),
+			Discrepancies: len(discrepancies),
+		},
+		Failed:        imported.Failed,
+		Discrepancies: discrepancies,
+	}, nil
+}
+
+// positionDiscrepancies compares the broker's positions with the tracked
+// ones. Tracked positions the broker doesn't hold are left out, as they may
+// be held elsewhere.
+func (s *BrokerageService) positionDiscrepancies(ctx context.Context, userID primitive.ObjectID, holdings []models.BrokerPosition) ([]PositionDiscrepancy, error) {
+	broker, err := normalizeBrokerPositions(holdings)
+	if err != nil {
+		return nil, err
+	}
+	positions, err := s.portfolioService.getPositions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	discrepancies := []PositionDiscrepancy{}
+	for _, discrepancy := range reconcilePositions(positions, broker).Discrepancies {
+		if discrepancy.Kind != DiscrepancyMissingAtBroker {
+			discrepancies = append(discrepancies, discrepancy)
+		}
+	}
+	return discrepancies, nil
+}
+
+// source returns the aggregator or exchange a link's provider names
+func (s *BrokerageService) source(provider string) (brokerageSource, bool) {
+	if aggregator, ok := s.aggregators[provider]; ok {
+		return aggregator, true
+	}
+	exchange, ok := s.exchanges[provider]
+	return exchange, ok
+}
+
+// findLink loads a link of the user
+func (s *BrokerageService) findLink(ctx context.Context, userID, linkID primitive.ObjectID) (*models.BrokerageLink, error) {
+	link, err := s.repos.BrokerageLinks.FindByID(ctx, userID, linkID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrBrokerageLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find brokerage link: %w", err)
+	}
+	return link, nil
+}
+
+// insertLink stores a new link with its credential encrypted
+func (s *BrokerageService) insertLink(ctx context.Context, link *models.BrokerageLink) error {
+	stored, err := s.sealedLink(link)
+	if err != nil {
+		return err
+	}
+	if err := s.repos.BrokerageLinks.Insert(ctx, stored); err != nil {
+		return fmt.Errorf("failed to insert brokerage link: %w", err)
+	}
+	return nil
+}
+
+// updateLink stores a link with its credential encrypted
+func (s *BrokerageService) updateLink(ctx context.Context, link *models.BrokerageLink) error {
+	stored, err := s.sealedLink(link)
+	if err != nil {
+		return err
+	}
+	if err := s.repos.BrokerageLinks.Update(ctx, stored); err != nil {
+		return fmt.Errorf("failed to update brokerage link: %w", err)
+	}
+	return nil
+}
+
+// sealedLink returns a copy of the link with its credential and API key
+// encrypted. The link itself keeps the credential it is using.
+func (s *BrokerageService) sealedLink(link *models.BrokerageLink) (*models.BrokerageLink, error) {
+	stored := *link
+	if stored.CredentialSealed || (stored.Credential == "" && stored.APIKey == "") {
+		return &stored, nil
+	}
+	if s.cipher == nil {
+		return nil, ErrCredentialKeyMissing
+	}
+	for _, field := range []*string{&stored.Credential, &stored.APIKey} {
+		if *field == "" {
+			continue
+		}
+		sealed, err := s.cipher.Seal(*field)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt credential: %w", err)
+		}
+		*field = sealed
+	}
+	stored.CredentialSealed = true
+	return &stored, nil
+}
+
+// openCredential decrypts a stored aggregator link's credential in place,
+// for calling its aggregator
+func (s *BrokerageService) openCredential(link *models.BrokerageLink) error {
+	if !link.CredentialSealed {
+		return nil
+	}
+	if s.cipher == nil {
+		return ErrCredentialKeyMissing
+	}
+	credential, err := s.cipher.Open(link.Credential)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt credential: %w", err)
+	}
+	link.Credential = credential
+	link.CredentialSealed = false
+	return nil
+}
+
+// exchangeKey decrypts an exchange link's API key and secret, for its
+// connector to sign a request with. Links stored before keys were
+// encrypted hold them in plaintext until their next update.
+func exchangeKey(cipher *TokenCipher, link *models.BrokerageLink) (apiKey, secret string, err error) {
+	if !link.CredentialSealed {
+		return link.APIKey, link.Credential, nil
+	}
+	if cipher == nil {
+		return "", "", ErrCredentialKeyMissing
+	}
+	if apiKey, err = cipher.Open(link.APIKey); err != nil {
+		return "", "", fmt.Errorf("failed to decrypt API key: %w", err)
+	}
+	if secret, err = cipher.Open(link.Credential); err != nil {
+		return "", "", fmt.Errorf("failed to decrypt API secret: %w", err)
+	}
+	return apiKey, secret, nil
+}
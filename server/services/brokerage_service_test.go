@@ -0,0 +1,522 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeAggregator serves fixed trades and holdings
+type fakeAggregator struct {
+	trades   []ImportedTrade
+	holdings []models.BrokerPosition
+	calls    int
+	// credential is the one trades were last fetched with
+	credential string
+}
+
+func (a *fakeAggregator) Name() string { return models.BrokerPlaid }
+
+func (a *fakeAggregator) Start(ctx context.Context, link *models.BrokerageLink) (string, error) {
+	return "link-token", nil
+}
+
+func (a *fakeAggregator) Complete(ctx context.Context, link *models.BrokerageLink, publicToken string) error {
+	link.Credential = "access-" + publicToken
+	return nil
+}
+
+func (a *fakeAggregator) Holdings(ctx context.Context, link *models.BrokerageLink) ([]models.BrokerPosition, error) {
+	return a.holdings, nil
+}
+
+func (a *fakeAggregator) Trades(ctx context.Context, link *models.BrokerageLink, start, end time.Time) ([]ImportedTrade, error) {
+	a.calls++
+	a.credential = link.Credential
+	trades := make([]ImportedTrade, len(a.trades))
+	copy(trades, a.trades)
+	return trades, nil
+}
+
+func (a *fakeAggregator) Remove(ctx context.Context, link *models.BrokerageLink) error {
+	return nil
+}
+
+// newTestCipher returns a TokenCipher under a fixed key
+func newTestCipher(t *testing.T) *TokenCipher {
+	cipher, err := NewTokenCipher(base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	return cipher
+}
+
+// newTestBrokerageService creates a BrokerageService over in-memory
+// repositories with a completed link of a new user
+func newTestBrokerageService(t *testing.T, aggregator *fakeAggregator) (*BrokerageService, *PortfolioService, *models.BrokerageLink) {
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 190, "USD").
+		SetQuote("MSFT", "Microsoft Corporation", 410, "USD")
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	service := NewBrokerageService(NewImportService(portfolioService), newTestCipher(t), aggregator)
+	userID := primitive.NewObjectID()
+
+	start, err := service.StartLink(userID, models.BrokerPlaid)
+	if err != nil {
+		t.Fatalf("Failed to start link: %v", err)
+	}
+	if start.Token != "link-token" || start.Link.Status != models.BrokerageLinkPending {
+		t.Fatalf("Unexpected link start %+v", start)
+	}
+	if _, err := service.SyncLink(userID, start.Link.ID); !errors.Is(err, ErrBrokerageLinkPending) {
+		t.Errorf("Expected ErrBrokerageLinkPending, got %v", err)
+	}
+	link, err := service.CompleteLink(userID, start.Link.ID, models.BrokerageLinkCompleteRequest{PublicToken: "public", Institution: "Schwab"})
+	if err != nil {
+		t.Fatalf("Failed to complete link: %v", err)
+	}
+	return service, portfolioService, link
+}
+
+func TestBrokerageSyncLink(t *testing.T) {
+	aggregator := &fakeAggregator{
+		trades: []ImportedTrade{
+			{Line: 1, Symbol: "AAPL", Action: "buy", Shares: 10, Price: 180, Currency: "USD", Date: tradeDate(2024, 3, 1), ExternalID: "tx-1"},
+			{Line: 2, Symbol: "MSFT", Action: "buy", Shares: 5, Price: 400, Currency: "USD", Date: tradeDate(2024, 3, 4), ExternalID: "tx-2"},
+		},
+		holdings: []models.BrokerPosition{{Symbol: "AAPL", Shares: 10}, {Symbol: "MSFT", Shares: 8}},
+	}
+	service, portfolioService, link := newTestBrokerageService(t, aggregator)
+	userID := link.UserID
+
+	if link.Status != models.BrokerageLinkActive || link.Credential != "access-public" || link.Institution != "Schwab" {
+		t.Fatalf("Unexpected completed link %+v", link)
+	}
+	if _, err := service.CompleteLink(userID, link.ID, models.BrokerageLinkCompleteRequest{}); !errors.Is(err, ErrBrokerageLinkNotPending) {
+		t.Errorf("Expected ErrBrokerageLinkNotPending, got %v", err)
+	}
+
+	// The MSFT buy was entered by hand before linking, so it is kept and the
+	// synced copy skipped
+	if err := portfolioService.AddTransaction(userID, &models.Transaction{
+		Symbol: "MSFT", Action: "buy", Shares: 5, Price: 400, Currency: "USD", Date: tradeDate(2024, 3, 4),
+	}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+
+	result, err := service.SyncLink(userID, link.ID)
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	// The access token is only stored encrypted, and decrypted to sync
+	if aggregator.credential != "access-public" {
+		t.Errorf("Expected trades fetched with the access token, got %q", aggregator.credential)
+	}
+	stored, _ := service.repos.BrokerageLinks.FindByID(context.Background(), userID, link.ID)
+	if !stored.CredentialSealed || strings.Contains(stored.Credential, "access-public") {
+		t.Errorf("Expected the stored access token to be encrypted, got %+v", stored)
+	}
+	if result.Imported != 1 || result.Duplicates != 1 || len(result.Failed) != 0 {
+		t.Errorf("Expected 1 imported and 1 duplicate, got %+v", result.BrokerageSyncCounts)
+	}
+	// The broker holds 3 more MSFT shares than are tracked
+	if len(result.Discrepancies) != 1 || result.Discrepancies[0].Symbol != "MSFT" || result.Discrepancies[0].Difference != 3 {
+		t.Errorf("Unexpected discrepancies %+v", result.Discrepancies)
+	}
+
+	transactions, _ := portfolioService.GetTransactionsBySymbol(userID, "AAPL")
+	if len(transactions) != 1 || transactions[0].Broker != models.BrokerPlaid || transactions[0].ExternalID != "tx-1" {
+		t.Fatalf("Expected the AAPL buy to carry the Plaid ID, got %+v", transactions)
+	}
+
+	// A later sync recognizes the synced trade even after it was edited
+	edited := transactions[0]
+	edited.Price = 181
+	if err := portfolioService.UpdateTransaction(userID, edited.ID, &edited); err != nil {
+		t.Fatalf("Failed to edit transaction: %v", err)
+	}
+	result, err = service.SyncLink(userID, link.ID)
+	if err != nil {
+		t.Fatalf("Failed to resync: %v", err)
+	}
+	if result.Imported != 0 || result.Duplicates != 2 {
+		t.Errorf("Expected nothing new on resync, got %+v", result.BrokerageSyncCounts)
+	}
+
+	links, _ := service.ListLinks(userID)
+	if len(links) != 1 || links[0].LastSync == nil || links[0].LastSync.Duplicates != 2 || links[0].SyncedThrough == nil {
+		t.Errorf("Expected the link to record the last sync, got %+v", links)
+	}
+
+	if err := service.DeleteLink(userID, link.ID); err != nil {
+		t.Fatalf("Failed to delete link: %v", err)
+	}
+	if _, err := service.SyncLink(userID, link.ID); !errors.Is(err, ErrBrokerageLinkNotFound) {
+		t.Errorf("Expected ErrBrokerageLinkNotFound, got %v", err)
+	}
+	if transactions, _ := portfolioService.GetTransactionsBySymbol(userID, "AAPL"); len(transactions) != 1 {
+		t.Errorf("Expected synced transactions to outlive the link, got %d", len(transactions))
+	}
+}
+
+func TestBrokerageSyncAll(t *testing.T) {
+	aggregator := &fakeAggregator{
+		trades: []ImportedTrade{
+			{Line: 1, Symbol: "AAPL", Action: "buy", Shares: 2, Price: 180, Currency: "USD", Date: tradeDate(2024, 3, 1), ExternalID: "tx-1"},
+		},
+	}
+	service, portfolioService, link := newTestBrokerageService(t, aggregator)
+
+	if err := service.SyncAll(); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	if aggregator.calls != 1 {
+		t.Errorf("Expected one sync, got %d", aggregator.calls)
+	}
+
+	// A stale claim loses to the sync that already ran
+	ctx := context.Background()
+	if err := service.repos.BrokerageLinks.ClaimSync(ctx, link.UserID, link.ID, nil, time.Now()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected a stale claim to fail, got %v", err)
+	}
+	if transactions, _ := portfolioService.GetTransactionsBySymbol(link.UserID, "AAPL"); len(transactions) != 1 {
+		t.Errorf("Expected 1 synced transaction, got %d", len(transactions))
+	}
+
+	if _, err := service.StartLink(link.UserID, models.BrokerSnapTrade); !errors.Is(err, ErrBrokerageUnavailable) {
+		t.Errorf("Expected ErrBrokerageUnavailable, got %v", err)
+	}
+
+	// A link stored before credentials were encrypted syncs with its
+	// plaintext credential and is encrypted afterwards
+	legacy := &models.BrokerageLink{ID: primitive.NewObjectID(), UserID: link.UserID, Provider: models.BrokerPlaid, Credential: "access-legacy", Status: models.BrokerageLinkActive}
+	if err := service.repos.BrokerageLinks.Insert(ctx, legacy); err != nil {
+		t.Fatalf("Failed to insert link: %v", err)
+	}
+	if err := service.SyncAll(); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	if aggregator.credential != "access-legacy" {
+		t.Errorf("Expected trades fetched with the legacy access token, got %q", aggregator.credential)
+	}
+	stored, _ := service.repos.BrokerageLinks.FindByID(ctx, link.UserID, legacy.ID)
+	if credential, err := service.cipher.Open(stored.Credential); !stored.CredentialSealed || err != nil || credential != "access-legacy" {
+		t.Errorf("Expected the legacy access token to be encrypted, got %+v", stored)
+	}
+}
+
+func TestPlaidAggregatorTrades(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		if body["client_id"] != "client" || body["secret"] != "secret" || body["access_token"] != "access" {
+			t.Errorf("Unexpected request body %v", body)
+		}
+		if req.URL.Path != "/investments/transactions/get" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error_code":"INVALID_PRODUCT","error_message":"not supported"}`))
+			return
+		}
+		w.Write([]byte(`{
+			"investment_transactions": [
+				{"investment_transaction_id": "t1", "security_id": "s1", "date": "2024-03-01", "name": "BUY Apple", "type": "buy", "quantity": 10, "price": 180, "fees": 1, "iso_currency_code": "USD"},
+				{"investment_transaction_id": "t2", "security_id": "cash", "date": "2024-03-02", "type": "cash", "quantity": 100, "price": 1, "iso_currency_code": "USD"},
+				{"investment_transaction_id": "t3", "security_id": "s1", "date": "2024-03-05", "type": "sell", "quantity": -4, "price": 190, "fees": 0, "iso_currency_code": "USD"}
+			],
+			"securities": [
+				{"security_id": "s1", "ticker_symbol": "aapl", "type": "equity"},
+				{"security_id": "cash", "ticker_symbol": "CUR:USD", "type": "cash"}
+			],
+			"total_investment_transactions": 3
+		}`))
+	}))
+	defer server.Close()
+
+	aggregator := NewPlaidAggregator("client", "secret", "sandbox")
+	aggregator.baseURL = server.URL
+	link := &models.BrokerageLink{UserID: primitive.NewObjectID(), Credential: "access"}
+
+	trades, err := aggregator.Trades(context.Background(), link, tradeDate(2024, 1, 1), tradeDate(2024, 4, 1))
+	if err != nil {
+		t.Fatalf("Trades failed: %v", err)
+	}
+	if len(trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %d", len(trades))
+	}
+	assertTrade(t, trades[0], "AAPL", "buy", 10, 180, 1, "USD", tradeDate(2024, 3, 1))
+	assertTrade(t, trades[1], "AAPL", "sell", 4, 190, 0, "USD", tradeDate(2024, 3, 5))
+	if trades[1].ExternalID != "t3" {
+		t.Errorf("Expected the Plaid transaction ID, got %q", trades[1].ExternalID)
+	}
+
+	if _, err := aggregator.Holdings(context.Background(), link); err == nil || err.Error() != "plaid INVALID_PRODUCT: not supported" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestSnapTradeAggregatorTrades(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		expected, _ := signSnapTrade("consumer", req.URL.Path, req.URL.RawQuery, nil)
+		if req.Header.Get("Signature") != expected {
+			t.Errorf("Unexpected signature %q", req.Header.Get("Signature"))
+		}
+		if req.URL.Query().Get("userSecret") != "user-secret" || req.URL.Query().Get("clientId") != "client" {
+			t.Errorf("Unexpected query %s", req.URL.RawQuery)
+		}
+		w.Write([]byte(`[
+			{"id": "a1", "symbol": {"symbol": "MSFT"}, "type": "BUY", "units": 3, "price": 400, "fee": 0.5, "trade_date": "2024-03-04T00:00:00Z", "currency": {"code": "USD"}},
+			{"id": "a2", "symbol": {"symbol": "MSFT"}, "type": "DIVIDEND", "units": 0, "price": 0, "trade_date": "2024-03-10T00:00:00Z", "currency": {"code": "USD"}}
+		]`))
+	}))
+	defer server.Close()
+
+	aggregator := NewSnapTradeAggregator("client", "consumer")
+	aggregator.baseURL = server.URL
+	link := &models.BrokerageLink{ID: primitive.NewObjectID(), Credential: "user-secret"}
+
+	trades, err := aggregator.Trades(context.Background(), link, tradeDate(2024, 1, 1), tradeDate(2024, 4, 1))
+	if err != nil {
+		t.Fatalf("Trades failed: %v", err)
+	}
+	if len(trades) != 1 {
+		t.Fatalf("Expected 1 trade, got %d", len(trades))
+	}
+	assertTrade(t, trades[0], "MSFT", "buy", 3, 400, 0.5, "USD", tradeDate(2024, 3, 4))
+	if trades[0].ExternalID != "a1" {
+		t.Errorf("Expected the SnapTrade activity ID, got %q", trades[0].ExternalID)
+	}
+}
+
+// fakeExchange serves fixed trades and balances
+type fakeExchange struct {
+	fakeAggregator
+	cipher   *TokenCipher
+	canTrade bool
+}
+
+func (e *fakeExchange) Name() string { return models.BrokerBinance }
+
+func (e *fakeExchange) CheckKey(ctx context.Context, link *models.BrokerageLink) error {
+	apiKey, _, err := exchangeKey(e.cipher, link)
+	if err != nil {
+		return err
+	}
+	if apiKey != "key" {
+		return errors.New("invalid API key")
+	}
+	if e.canTrade {
+		return ErrExchangeKeyNotReadOnly
+	}
+	return nil
+}
+
+func TestBrokerageLinkExchange(t *testing.T) {
+	cipher := newTestCipher(t)
+	exchange := &fakeExchange{
+		cipher: cipher,
+		fakeAggregator: fakeAggregator{
+			trades: []ImportedTrade{
+				{Line: 1, Symbol: "BTC-USD", Action: "buy", Shares: 0.5, Price: 60000, Fees: 30, Currency: "USD", Date: tradeDate(2024, 3, 1), ExternalID: "BTCUSDT:1"},
+			},
+			holdings: []models.BrokerPosition{{Symbol: "BTC-USD", Shares: 0.5}},
+		},
+	}
+	provider := NewFixtureProvider().SetQuote("BTC-USD", "Bitcoin USD", 65000, "USD")
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	service := NewBrokerageService(NewImportService(portfolioService), cipher).WithExchanges(exchange)
+	userID := primitive.NewObjectID()
+
+	req := models.ExchangeLinkRequest{Provider: models.BrokerBinance, APIKey: "wrong", APISecret: "secret"}
+	if _, err := service.LinkExchange(userID, req); !errors.Is(err, ErrExchangeKeyRejected) {
+		t.Errorf("Expected ErrExchangeKeyRejected, got %v", err)
+	}
+	req.APIKey = "key"
+	exchange.canTrade = true
+	if _, err := service.LinkExchange(userID, req); !errors.Is(err, ErrExchangeKeyNotReadOnly) {
+		t.Errorf("Expected ErrExchangeKeyNotReadOnly, got %v", err)
+	}
+	req.Provider = models.BrokerCoinbase
+	if _, err := service.LinkExchange(userID, req); !errors.Is(err, ErrBrokerageUnavailable) {
+		t.Errorf("Expected ErrBrokerageUnavailable, got %v", err)
+	}
+
+	exchange.canTrade = false
+	req.Provider = models.BrokerBinance
+	link, err := service.LinkExchange(userID, req)
+	if err != nil {
+		t.Fatalf("Failed to link exchange: %v", err)
+	}
+	if link.Status != models.BrokerageLinkActive {
+		t.Fatalf("Unexpected exchange link %+v", link)
+	}
+	// The key is sealed on receipt and stays sealed until the connector
+	// signs a request with it
+	if apiKey, secret, err := exchangeKey(cipher, link); !link.CredentialSealed || link.APIKey == "key" || err != nil || apiKey != "key" || secret != "secret" {
+		t.Errorf("Expected the API key and secret to be encrypted, got %+v", link)
+	}
+
+	result, err := service.SyncLink(userID, link.ID)
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if result.Imported != 1 || len(result.Discrepancies) != 0 {
+		t.Errorf("Expected 1 imported trade and no discrepancies, got %+v", result)
+	}
+	if exchange.credential != link.Credential {
+		t.Errorf("Expected the connector to get the sealed secret, got %q", exchange.credential)
+	}
+
+	// Crypto holdings are filed under the crypto asset class
+	portfolio, err := portfolioService.repos.Portfolios.FindBySymbol(context.Background(), userID, "BTC-USD")
+	if err != nil {
+		t.Fatalf("Failed to find portfolio: %v", err)
+	}
+	if portfolio.AssetClass != "Crypto" {
+		t.Errorf("Expected the Crypto asset class, got %q", portfolio.AssetClass)
+	}
+}
+
+func TestBinanceConnectorTrades(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-MBX-APIKEY") != "key" {
+			t.Errorf("Missing API key header")
+		}
+		query := req.URL.RawQuery
+		signed, signature, _ := strings.Cut(query, "&signature=")
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte(signed))
+		if signature != hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("Unexpected signature for %s", query)
+		}
+
+		switch req.URL.Path {
+		case "/api/v3/account":
+			w.Write([]byte(`{"balances": [{"asset": "BTC", "free": "0.4", "locked": "0.1"}, {"asset": "USDT", "free": "250", "locked": "0"}]}`))
+		case "/api/v3/myTrades":
+			if req.URL.Query().Get("symbol") != "BTCUSDT" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"code": -1121, "msg": "Invalid symbol."}`))
+				return
+			}
+			w.Write([]byte(`[
+				{"id": 7, "price": "60000", "qty": "0.501", "commission": "0.001", "commissionAsset": "BTC", "time": 1709290800000, "isBuyer": true},
+				{"id": 8, "price": "62000", "qty": "0.1", "commission": "6.2", "commissionAsset": "USDT", "time": 1709636400000, "isBuyer": false}
+			]`))
+		default:
+			t.Errorf("Unexpected path %s", req.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	cipher := newTestCipher(t)
+	connector := NewBinanceConnector(cipher)
+	connector.baseURL = server.URL
+	link, err := (&BrokerageService{cipher: cipher}).sealedLink(&models.BrokerageLink{APIKey: "key", Credential: "secret", Assets: []string{"ETH"}})
+	if err != nil {
+		t.Fatalf("Failed to seal link: %v", err)
+	}
+
+	trades, err := connector.Trades(context.Background(), link, tradeDate(2024, 1, 1), tradeDate(2024, 4, 1))
+	if err != nil {
+		t.Fatalf("Trades failed: %v", err)
+	}
+	if len(trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %d", len(trades))
+	}
+	// The commission paid in BTC reduces the BTC received
+	assertTrade(t, trades[0], "BTC-USD", "buy", 0.5, 60000, 60, "USD", tradeDate(2024, 3, 1))
+	assertTrade(t, trades[1], "BTC-USD", "sell", 0.1, 62000, 6.2, "USD", tradeDate(2024, 3, 5))
+	if trades[0].ExternalID != "BTCUSDT:7" {
+		t.Errorf("Expected the market and trade ID, got %q", trades[0].ExternalID)
+	}
+	if strings.Join(link.Assets, ",") != "BTC,ETH" {
+		t.Errorf("Expected the held and earlier assets to be kept, got %v", link.Assets)
+	}
+
+	holdings, err := connector.Holdings(context.Background(), link)
+	if err != nil {
+		t.Fatalf("Holdings failed: %v", err)
+	}
+	if len(holdings) != 1 || holdings[0].Symbol != "BTC-USD" || holdings[0].Shares != 0.5 {
+		t.Errorf("Expected only the BTC balance, got %+v", holdings)
+	}
+}
+
+func TestCoinbaseConnectorTrades(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, _ := x509.MarshalECPrivateKey(key)
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, err := jwt.Parse(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "), func(token *jwt.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		}, jwt.WithValidMethods([]string{"ES256"}))
+		if err != nil {
+			t.Errorf("Invalid token: %v", err)
+		} else if claims := token.Claims.(jwt.MapClaims); claims["sub"] != "organizations/o/apiKeys/k" || claims["uri"] != "GET "+strings.TrimPrefix(server.URL, "http://")+req.URL.Path {
+			t.Errorf("Unexpected claims %v", claims)
+		}
+
+		switch req.URL.Path {
+		case "/api/v3/brokerage/key_permissions":
+			w.Write([]byte(`{"can_view": true, "can_trade": true, "can_transfer": false}`))
+		case "/api/v3/brokerage/orders/historical/fills":
+			if req.URL.Query().Get("cursor") != "" {
+				w.Write([]byte(`{"fills": [], "cursor": ""}`))
+				return
+			}
+			w.Write([]byte(`{"fills": [
+				{"entry_id": "e1", "trade_time": "2024-03-01T14:30:00Z", "price": "3000", "size": "600", "commission": "3.6", "product_id": "ETH-USD", "side": "BUY", "size_in_quote": true},
+				{"entry_id": "e2", "trade_time": "2024-03-02T09:00:00Z", "price": "0.05", "size": "1", "commission": "0", "product_id": "ETH-BTC", "side": "SELL", "size_in_quote": false}
+			], "cursor": "next"}`))
+		default:
+			t.Errorf("Unexpected path %s", req.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	connector := NewCoinbaseConnector(newTestCipher(t))
+	connector.baseURL = server.URL
+	// Keys pasted from the downloaded JSON keep their escaped newlines. The
+	// link predates encrypted keys, so they are used as stored.
+	link := &models.BrokerageLink{APIKey: "organizations/o/apiKeys/k", Credential: strings.ReplaceAll(privateKey, "\n", `\n`)}
+
+	if err := connector.CheckKey(context.Background(), link); !errors.Is(err, ErrExchangeKeyNotReadOnly) {
+		t.Errorf("Expected ErrExchangeKeyNotReadOnly, got %v", err)
+	}
+
+	trades, err := connector.Trades(context.Background(), link, tradeDate(2024, 1, 1), tradeDate(2024, 4, 1))
+	if err != nil {
+		t.Fatalf("Trades failed: %v", err)
+	}
+	if len(trades) != 1 {
+		t.Fatalf("Expected only the dollar market fill, got %d", len(trades))
+	}
+	assertTrade(t, trades[0], "ETH-USD", "buy", 0.2, 3000, 3.6, "USD", tradeDate(2024, 3, 1))
+	if trades[0].ExternalID != "e1" {
+		t.Errorf("Expected the fill's entry ID, got %q", trades[0].ExternalID)
+	}
+}
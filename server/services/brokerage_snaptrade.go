@@ -0,0 +1,275 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/telemetry"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// snapTradeAPIURL is the SnapTrade API
+const snapTradeAPIURL = "https://api.snaptrade.com"
+
+// SnapTradeAggregator links brokerage accounts with SnapTrade. Each link
+// registers its own SnapTrade user, whose secret is the link's credential;
+// the client opens the connection portal URL and completes the link once the
+// user has connected their brokerage.
+type SnapTradeAggregator struct {
+	clientID    string
+	consumerKey string
+	baseURL     string
+	client      *http.Client
+}
+
+// NewSnapTradeAggregator creates a new SnapTradeAggregator instance
+func NewSnapTradeAggregator(clientID, consumerKey string) *SnapTradeAggregator {
+	return &SnapTradeAggregator{
+		clientID:    clientID,
+		consumerKey: consumerKey,
+		baseURL:     snapTradeAPIURL,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: telemetry.Transport(nil),
+		},
+	}
+}
+
+// Name returns the provider identifier
+func (p *SnapTradeAggregator) Name() string {
+	return models.BrokerSnapTrade
+}
+
+// Start registers a SnapTrade user for the link and returns the connection
+// portal URL
+func (p *SnapTradeAggregator) Start(ctx context.Context, link *models.BrokerageLink) (string, error) {
+	var user struct {
+		UserSecret string `json:"userSecret"`
+	}
+	if err := p.call(ctx, http.MethodPost, "/api/v1/snapTrade/registerUser", nil, map[string]string{"userId": link.ID.Hex()}, &user); err != nil {
+		return "", err
+	}
+	link.Credential = user.UserSecret
+
+	var login struct {
+		RedirectURI string `json:"redirectURI"`
+	}
+	if err := p.call(ctx, http.MethodPost, "/api/v1/snapTrade/login", p.userQuery(link), map[string]string{"connectionType": "read"}, &login); err != nil {
+		return "", err
+	}
+	return login.RedirectURI, nil
+}
+
+// Complete checks that the user connected a brokerage in the portal
+func (p *SnapTradeAggregator) Complete(ctx context.Context, link *models.BrokerageLink, publicToken string) error {
+	accounts, err := p.accounts(ctx, link)
+	if err != nil {
+		return err
+	}
+	if len(accounts) == 0 {
+		return fmt.Errorf("no brokerage account was connected")
+	}
+	if link.Institution == "" {
+		link.Institution = accounts[0].InstitutionName
+	}
+	return nil
+}
+
+// snapTradeAccount is a brokerage account connected to a SnapTrade user
+type snapTradeAccount struct {
+	ID              string `json:"id"`
+	InstitutionName string `json:"institution_name"`
+}
+
+// accounts lists the link's connected accounts
+func (p *SnapTradeAggregator) accounts(ctx context.Context, link *models.BrokerageLink) ([]snapTradeAccount, error) {
+	var accounts []snapTradeAccount
+	err := p.call(ctx, http.MethodGet, "/api/v1/accounts", p.userQuery(link), nil, &accounts)
+	return accounts, err
+}
+
+// Holdings returns the positions across the link's accounts
+func (p *SnapTradeAggregator) Holdings(ctx context.Context, link *models.BrokerageLink) ([]models.BrokerPosition, error) {
+	accounts, err := p.accounts(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := newBrokerPositions()
+	for _, account := range accounts {
+		var positions []struct {
+			Symbol struct {
+				Symbol struct {
+					Symbol string `json:"symbol"`
+				} `json:"symbol"`
+			} `json:"symbol"`
+			Units float64 `json:"units"`
+			Price float64 `json:"price"`
+		}
+		path := "/api/v1/accounts/" + url.PathEscape(account.ID) + "/positions"
+		if err := p.call(ctx, http.MethodGet, path, p.userQuery(link), nil, &positions); err != nil {
+			return nil, err
+		}
+		for _, position := range positions {
+			if symbol := strings.ToUpper(position.Symbol.Symbol.Symbol); symbol != "" {
+				merged.add(symbol, position.Units, position.Price)
+			}
+		}
+	}
+	return merged.list(), nil
+}
+
+// Trades returns the buys and sells across the link's accounts between start and end
+func (p *SnapTradeAggregator) Trades(ctx context.Context, link *models.BrokerageLink, start, end time.Time) ([]ImportedTrade, error) {
+	query := p.userQuery(link)
+	query = append(query,
+		[2]string{"startDate", start.UTC().Format("2006-01-02")},
+		[2]string{"endDate", end.UTC().Format("2006-01-02")},
+		[2]string{"type", "BUY,SELL"},
+	)
+	var activities []struct {
+		ID     string `json:"id"`
+		Symbol struct {
+			Symbol string `json:"symbol"`
+		} `json:"symbol"`
+		Type        string  `json:"type"`
+		Description string  `json:"description"`
+		Units       float64 `json:"units"`
+		Price       float64 `json:"price"`
+		Fee         float64 `json:"fee"`
+		TradeDate   string  `json:"trade_date"`
+		Currency    struct {
+			Code string `json:"code"`
+		} `json:"currency"`
+	}
+	if err := p.call(ctx, http.MethodGet, "/api/v1/activities", query, nil, &activities); err != nil {
+		return nil, err
+	}
+
+	trades := []ImportedTrade{}
+	for _, activity := range activities {
+		action := strings.ToLower(activity.Type)
+		symbol := strings.ToUpper(activity.Symbol.Symbol)
+		if symbol == "" || (action != "buy" && action != "sell") {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, activity.TradeDate)
+		if err != nil {
+			continue
+		}
+		currency, ok := statementCurrency(activity.Currency.Code)
+		if !ok {
+			continue
+		}
+		trades = append(trades, ImportedTrade{
+			Line:        len(trades) + 1,
+			Symbol:      symbol,
+			Action:      action,
+			Shares:      math.Abs(activity.Units),
+			Price:       activity.Price,
+			Fees:        math.Abs(activity.Fee),
+			Currency:    currency,
+			Date:        time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC),
+			Description: activity.Description,
+			ExternalID:  activity.ID,
+		})
+	}
+	return trades, nil
+}
+
+// Remove deletes the link's SnapTrade user, disconnecting its brokerages
+func (p *SnapTradeAggregator) Remove(ctx context.Context, link *models.BrokerageLink) error {
+	return p.call(ctx, http.MethodDelete, "/api/v1/snapTrade/deleteUser", [][2]string{{"userId", link.ID.Hex()}}, nil, nil)
+}
+
+// userQuery returns the query parameters identifying the link's SnapTrade user
+func (p *SnapTradeAggregator) userQuery(link *models.BrokerageLink) [][2]string {
+	return [][2]string{{"userId", link.ID.Hex()}, {"userSecret", link.Credential}}
+}
+
+// signSnapTrade returns the Signature header of a SnapTrade request: the
+// base64 HMAC-SHA256, keyed with the consumer key, of the compact JSON object
+// with sorted keys holding the body, the path and the query string
+func signSnapTrade(consumerKey, path, query string, body interface{}) (string, error) {
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(map[string]interface{}{"content": body, "path": path, "query": query}); err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, []byte(consumerKey))
+	mac.Write(bytes.TrimRight(content.Bytes(), "\n"))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// call sends a signed SnapTrade request. Query parameters keep their order,
+// as the signature covers the query string.
+func (p *SnapTradeAggregator) call(ctx context.Context, method, path string, params [][2]string, body interface{}, out interface{}) error {
+	query := "clientId=" + url.QueryEscape(p.clientID) + "&timestamp=" + strconv.FormatInt(time.Now().Unix(), 10)
+	for _, param := range params {
+		query += "&" + url.QueryEscape(param[0]) + "=" + url.QueryEscape(param[1])
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	signature, err := signSnapTrade(p.consumerKey, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path+"?"+query, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature", signature)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		// The request URL holds the user secret, so don't let it reach the logs
+		return fmt.Errorf("failed to reach SnapTrade: %w", errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read SnapTrade response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var snapErr struct {
+			Detail string `json:"detail"`
+		}
+		if json.Unmarshal(payload, &snapErr) == nil && snapErr.Detail != "" {
+			return fmt.Errorf("snaptrade responded with status %d: %s", resp.StatusCode, snapErr.Detail)
+		}
+		return fmt.Errorf("snaptrade responded with status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("invalid SnapTrade response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/repository"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// cacheWarmConcurrency bounds the symbols a warming run fetches at once
+	cacheWarmConcurrency = 4
+
+	// cacheWarmTimeout bounds a whole warming run
+	cacheWarmTimeout = 2 * time.Minute
+)
+
+// CacheWarmer prefetches quotes and previous closes for the symbols held by
+// recently active users, so the first dashboards after a deploy or a cache
+// expiry are served from cache rather than a burst of external calls
+type CacheWarmer struct {
+	repos        repository.Repositories
+	stockService StockDataProvider
+	activeWindow time.Duration
+	running      atomic.Bool
+}
+
+// NewCacheWarmer creates a CacheWarmer for users seen within activeWindow
+func NewCacheWarmer(portfolioService *PortfolioService, stockService StockDataProvider, activeWindow time.Duration) *CacheWarmer {
+	return &CacheWarmer{
+		repos:        portfolioService.repos,
+		stockService: stockService,
+		activeWindow: activeWindow,
+	}
+}
+
+// WarmCaches fetches the quote of every symbol held by an active user. Quotes
+// still cached are not fetched again, so running it more often than the quote
+// cache TTL only costs external calls for entries that expired. Symbols that
+// fail are logged and skipped; a run that starts while another is in progress
+// does nothing.
+func (w *CacheWarmer) WarmCaches() error {
+	if !w.running.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer w.running.Store(false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheWarmTimeout)
+	defer cancel()
+
+	start := time.Now()
+	symbols, err := w.activeSymbols(ctx, start)
+	if err != nil {
+		return err
+	}
+
+	var failed atomic.Int64
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, cacheWarmConcurrency)
+	for _, symbol := range symbols {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(symbol string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := w.warmSymbol(ctx, symbol); err != nil {
+				failed.Add(1)
+				fmt.Printf("[CacheWarmer] Warning: Could not warm %s: %v\n", symbol, err)
+			}
+		}(symbol)
+	}
+	wg.Wait()
+
+	fmt.Printf("[CacheWarmer] Warmed %d of %d symbols in %s\n",
+		len(symbols)-int(failed.Load()), len(symbols), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// activeSymbols returns the sorted non-cash symbols of the open positions of
+// users with a session used within the active window
+func (w *CacheWarmer) activeSymbols(ctx context.Context, now time.Time) ([]string, error) {
+	userIDs, err := w.repos.Sessions.FindActiveUsers(ctx, now.Add(-w.activeWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find active users: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, userID := range userIDs {
+		positions, err := w.repos.Transactions.Positions(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch positions: %w", err)
+		}
+		for _, position := range positions {
+			if position.Shares != 0 && !w.stockService.IsCashSymbol(position.Symbol) {
+				seen[position.Symbol] = true
+			}
+		}
+	}
+
+	symbols := make([]string, 0, len(seen))
+	for symbol := range seen {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols, nil
+}
+
+// warmSymbol fetches a symbol's quote and, when the quote has no previous
+// close, the price history the day change falls back to
+func (w *CacheWarmer) warmSymbol(ctx context.Context, symbol string) error {
+	info, err := w.stockService.GetStockInfoContext(ctx, symbol)
+	if err != nil {
+		return err
+	}
+	if info.PreviousClose > 0 {
+		return nil
+	}
+	_, err = w.stockService.GetHistoricalDataContext(ctx, symbol, "1M")
+	return err
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCacheWarmerActiveSymbols(t *testing.T) {
+	now := time.Now()
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 110, "USD").
+		SetPreviousClose("AAPL", 105).
+		SetQuote("NVDA", "NVIDIA", 120, "USD").
+		SetHistory("NVDA", now, 118, 120).
+		SetQuote("MSFT", "Microsoft", 400, "USD")
+	repos := repository.NewMemory()
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repos)
+	warmer := NewCacheWarmer(portfolioService, provider, 7*24*time.Hour)
+
+	active, stale, revoked := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	revokedAt := now
+	ctx := context.Background()
+	for _, session := range []models.Session{
+		{ID: primitive.NewObjectID(), UserID: active, LastSeenAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+		{ID: primitive.NewObjectID(), UserID: stale, LastSeenAt: now.AddDate(0, 0, -30), ExpiresAt: now.Add(time.Hour)},
+		{ID: primitive.NewObjectID(), UserID: revoked, LastSeenAt: now, ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt},
+	} {
+		if err := repos.Sessions.Insert(ctx, &session); err != nil {
+			t.Fatalf("Failed to insert session: %v", err)
+		}
+	}
+
+	trades := map[primitive.ObjectID][]string{
+		active:  {"AAPL", "NVDA", "CASH_USD"},
+		stale:   {"MSFT"},
+		revoked: {"MSFT"},
+	}
+	for userID, symbols := range trades {
+		for _, symbol := range symbols {
+			tx := &models.Transaction{Symbol: symbol, Action: "buy", Shares: 10, Price: 1, Currency: "USD", Date: now.AddDate(0, 0, -1)}
+			if err := portfolioService.AddTransaction(userID, tx); err != nil {
+				t.Fatalf("Failed to add transaction: %v", err)
+			}
+		}
+	}
+
+	symbols, err := warmer.activeSymbols(ctx, now)
+	if err != nil {
+		t.Fatalf("Failed to find active symbols: %v", err)
+	}
+	if want := []string{"AAPL", "NVDA"}; !reflect.DeepEqual(symbols, want) {
+		t.Errorf("Expected active symbols %v, got %v", want, symbols)
+	}
+
+	if err := warmer.WarmCaches(); err != nil {
+		t.Errorf("Failed to warm caches: %v", err)
+	}
+}
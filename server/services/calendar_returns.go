@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CalendarYearReturn is the time-weighted return over one calendar year
+type CalendarYearReturn struct {
+	Year   int     `json:"year"`
+	Return float64 `json:"return"` // Percentage
+	// Partial is set for the current year and for a year the portfolio's
+	// history starts in
+	Partial bool `json:"partial"`
+}
+
+// CalendarReturns represents month-, quarter- and year-to-date returns with a
+// per-year breakdown, as fund factsheets report them. Returns are
+// time-weighted, so deposits and withdrawals do not count as performance.
+// A period-to-date return is omitted when the portfolio has no value in it.
+type CalendarReturns struct {
+	Currency string               `json:"currency"`
+	AsOf     time.Time            `json:"asOf"`
+	MTD      *float64             `json:"mtd"`
+	QTD      *float64             `json:"qtd"`
+	YTD      *float64             `json:"ytd"`
+	Years    []CalendarYearReturn `json:"years"`
+}
+
+// GetCalendarReturns returns the MTD, QTD and YTD returns and the return of
+// every calendar year in the portfolio's history. Prices are fetched under ctx.
+func (s *AnalyticsService) GetCalendarReturns(ctx context.Context, userID primitive.ObjectID, currency string) (*CalendarReturns, error) {
+	currency, err := NormalizeDisplayCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CalendarReturns{
+		Currency: currency,
+		AsOf:     time.Now(),
+		Years:    []CalendarYearReturn{},
+	}
+
+	performance, _, err := s.historicalPerformance(ctx, userID, "ALL", currency, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(performance) == 0 {
+		return response, nil
+	}
+
+	transactions, err := s.portfolioService.GetTransactionsSinceContext(ctx, userID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	index := timeWeightedIndex(performance, s.contributionTimeline(transactions, currency))
+
+	fillCalendarReturns(response, performance, index)
+	return response, nil
+}
+
+// timeWeightedIndex chains the daily returns of a value series into a growth
+// index starting at 1. Each day's change in net contributions is taken out of
+// its value before the return is computed, and a day that starts from no
+// value has no return.
+func timeWeightedIndex(points []PerformanceDataPoint, contributions []contributionChange) []float64 {
+	index := make([]float64, len(points))
+	contributionIndex := 0
+	netContributions, previousContributions := 0.0, 0.0
+	for i, point := range points {
+		for contributionIndex < len(contributions) && !contributions[contributionIndex].Date.After(point.Date) {
+			netContributions = contributions[contributionIndex].Total
+			contributionIndex++
+		}
+
+		if i == 0 {
+			index[i] = 1
+		} else if previous := points[i-1].Value; previous > 0 {
+			flow := netContributions - previousContributions
+			index[i] = index[i-1] * (point.Value - flow) / previous
+		} else {
+			index[i] = index[i-1]
+		}
+		previousContributions = netContributions
+	}
+	return index
+}
+
+// periodReturn returns the index's percentage change from the last point
+// before start to the last point, or from the first point when the series
+// starts inside the period. It reports false when no point falls in the
+// period or the portfolio held nothing in it.
+func periodReturn(points []PerformanceDataPoint, index []float64, start time.Time) (float64, bool, bool) {
+	base, partial := -1, false
+	held := false
+	for i, point := range points {
+		if point.Date.Before(start) {
+			base = i
+			continue
+		}
+		if base < 0 {
+			base, partial = i, true
+		}
+		if point.Value > 0 {
+			held = true
+		}
+	}
+	if base < 0 || !held || index[base] <= 0 {
+		return 0, false, false
+	}
+	return (index[len(index)-1]/index[base] - 1) * 100, partial, true
+}
+
+// fillCalendarReturns computes the period-to-date and per-year returns of a
+// series and its time-weighted index
+func fillCalendarReturns(response *CalendarReturns, points []PerformanceDataPoint, index []float64) {
+	last := points[len(points)-1].Date
+	year, month, _ := last.Date()
+	location := last.Location()
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, location)
+	quarterStart := time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, location)
+	yearStart := time.Date(year, time.January, 1, 0, 0, 0, 0, location)
+
+	for _, period := range []struct {
+		start  time.Time
+		target **float64
+	}{
+		{monthStart, &response.MTD},
+		{quarterStart, &response.QTD},
+		{yearStart, &response.YTD},
+	} {
+		if value, _, ok := periodReturn(points, index, period.start); ok {
+			*period.target = &value
+		}
+	}
+
+	for y := points[0].Date.Year(); y <= year; y++ {
+		start := time.Date(y, time.January, 1, 0, 0, 0, 0, location)
+		end := time.Date(y+1, time.January, 1, 0, 0, 0, 0, location)
+
+		count := 0
+		for count < len(points) && points[count].Date.Before(end) {
+			count++
+		}
+		value, partial, ok := periodReturn(points[:count], index[:count], start)
+		if !ok {
+			continue
+		}
+		response.Years = append(response.Years, CalendarYearReturn{
+			Year:    y,
+			Return:  value,
+			Partial: partial || y == year,
+		})
+	}
+}
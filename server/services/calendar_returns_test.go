@@ -0,0 +1,40 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCalendarReturnsExcludeContributions(t *testing.T) {
+	points := []PerformanceDataPoint{
+		{Date: tradeDate(2023, 12, 29), Value: 100},
+		{Date: tradeDate(2024, 1, 2), Value: 110},
+		{Date: tradeDate(2024, 2, 1), Value: 210}, // 100 deposited, flat day
+		{Date: tradeDate(2024, 3, 4), Value: 231},
+	}
+	contributions := []contributionChange{
+		{Date: tradeDate(2023, 12, 29), Total: 100},
+		{Date: tradeDate(2024, 2, 1), Total: 200},
+	}
+
+	index := timeWeightedIndex(points, contributions)
+	response := &CalendarReturns{Years: []CalendarYearReturn{}}
+	fillCalendarReturns(response, points, index)
+
+	near := func(got *float64, want float64) bool {
+		return got != nil && math.Abs(*got-want) < 1e-9
+	}
+	if !near(response.MTD, 10) || !near(response.QTD, 21) || !near(response.YTD, 21) {
+		t.Errorf("Expected MTD 10%%, QTD and YTD 21%%, got %v %v %v", response.MTD, response.QTD, response.YTD)
+	}
+
+	if len(response.Years) != 2 {
+		t.Fatalf("Expected 2 calendar years, got %+v", response.Years)
+	}
+	if first := response.Years[0]; first.Year != 2023 || first.Return != 0 || !first.Partial {
+		t.Errorf("Expected a flat partial 2023, got %+v", first)
+	}
+	if second := response.Years[1]; second.Year != 2024 || math.Abs(second.Return-21) > 1e-9 || !second.Partial {
+		t.Errorf("Expected 21%% in the current year 2024, got %+v", second)
+	}
+}
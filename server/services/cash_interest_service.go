@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/money"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrCashInterestNotFound = errors.New("cash interest rate not found")
+
+// CashInterestJobInterval is how often interest is accrued. Interest is
+// recorded per whole day, so only the first run after midnight UTC records
+// anything.
+const CashInterestJobInterval = time.Hour
+
+// CashInterestService records the interest earned on cash balances as
+// automatic buys of the cash symbol, so cash yield shows up in performance
+type CashInterestService struct {
+	repos            repository.Repositories
+	portfolioService *PortfolioService
+	stockService     StockDataProvider
+}
+
+// NewCashInterestService creates a new CashInterestService instance
+func NewCashInterestService(portfolioService *PortfolioService, stockService StockDataProvider) *CashInterestService {
+	return &CashInterestService{
+		repos:            portfolioService.repos,
+		portfolioService: portfolioService,
+		stockService:     stockService,
+	}
+}
+
+// accrualDay returns the UTC midnight starting t's day
+func accrualDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// cashSymbolCurrency returns the currency a cash symbol holds
+func cashSymbolCurrency(symbol string) string {
+	return strings.TrimPrefix(symbol, "CASH_")
+}
+
+// isCashInterest reports whether a transaction records accrued cash interest
+func isCashInterest(tx models.Transaction) bool {
+	for _, tag := range tx.Tags {
+		if tag == models.CashInterestTag {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRate sets the APY earned on a cash balance. Interest due at the old rate
+// is recorded first, so the new rate applies from today.
+func (s *CashInterestService) SetRate(userID primitive.ObjectID, symbol string, apy float64) (*models.CashInterestRate, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if !s.stockService.IsCashSymbol(symbol) {
+		return nil, fmt.Errorf("%w: %s is not a cash balance", ErrInvalidSymbol, symbol)
+	}
+	if apy < 0 || apy > 100 {
+		return nil, fmt.Errorf("%w: APY must be between 0 and 100", ErrInvalidTransaction)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	today := accrualDay(now)
+	rate, err := s.repos.CashInterest.FindBySymbol(ctx, userID, symbol)
+	if errors.Is(err, repository.ErrNotFound) {
+		rate = &models.CashInterestRate{
+			ID:             primitive.NewObjectID(),
+			UserID:         userID,
+			Symbol:         symbol,
+			APY:            apy,
+			AccruedThrough: today,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if err := s.repos.CashInterest.Insert(ctx, rate); err != nil {
+			return nil, fmt.Errorf("failed to insert cash interest rate: %w", err)
+		}
+		return rate, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cash interest rate: %w", err)
+	}
+
+	accrued, err := s.accrue(ctx, *rate, today)
+	if err != nil {
+		return nil, err
+	}
+
+	// Interest too small to record at the old rate is dropped
+	from := accrued.AccruedThrough
+	accrued.APY = apy
+	accrued.AccruedThrough = today
+	accrued.UpdatedAt = now
+	if err := s.repos.CashInterest.Update(ctx, &accrued, from); err != nil {
+		return nil, fmt.Errorf("failed to update cash interest rate: %w", err)
+	}
+
+	return &accrued, nil
+}
+
+// ListRates returns the rates set on the user's cash balances
+func (s *CashInterestService) ListRates(userID primitive.ObjectID) ([]models.CashInterestRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rates, err := s.repos.CashInterest.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cash interest rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// DeleteRate stops interest on a cash balance after recording what is due
+func (s *CashInterestService) DeleteRate(userID primitive.ObjectID, symbol string) error {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rate, err := s.repos.CashInterest.FindBySymbol(ctx, userID, symbol)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrCashInterestNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find cash interest rate: %w", err)
+	}
+
+	if _, err := s.accrue(ctx, *rate, accrualDay(time.Now())); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	err = s.repos.CashInterest.Delete(ctx, userID, symbol)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrCashInterestNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete cash interest rate: %w", err)
+	}
+
+	return nil
+}
+
+// AccrueInterest records the interest due on every cash balance with a rate
+// through the start of today
+func (s *CashInterestService) AccrueInterest() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	rates, err := s.repos.CashInterest.FindAccruing(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to fetch cash interest rates: %w", err)
+	}
+
+	today := accrualDay(time.Now())
+	var errs []error
+	for _, rate := range rates {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		_, err := s.accrue(ctx, rate, today)
+		cancel()
+		if errors.Is(err, repository.ErrNotFound) {
+			// Changed or accrued by another instance since it was loaded
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to accrue interest on %s for user %s: %w", rate.Symbol, rate.UserID.Hex(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// accrue records the interest on the balance from the rate's AccruedThrough
+// to through, compounding daily so that a year at the APY earns the APY. The
+// current balance is used for every day, which is exact when the job runs
+// daily. Interest below one minor unit is left to build up over the following
+// days rather than rounded away. The rate is advanced before the transaction
+// is recorded, so a day's interest is never recorded twice.
+func (s *CashInterestService) accrue(ctx context.Context, rate models.CashInterestRate, through time.Time) (models.CashInterestRate, error) {
+	days := int(through.Sub(rate.AccruedThrough).Hours() / 24)
+	if days < 1 {
+		return rate, nil
+	}
+
+	currency := cashSymbolCurrency(rate.Symbol)
+	interest := money.Zero(currency)
+	if rate.APY > 0 {
+		positions, err := s.portfolioService.getPositions(ctx, rate.UserID)
+		if err != nil {
+			return rate, err
+		}
+		balance := 0.0
+		for _, position := range positions {
+			if position.Symbol == rate.Symbol {
+				balance = position.Shares
+			}
+		}
+		if balance > 0 {
+			interest = money.New(balance*(math.Pow(1+rate.APY/100, float64(days)/365)-1), currency)
+			if interest.IsZero() {
+				return rate, nil
+			}
+		}
+	}
+
+	from := rate.AccruedThrough
+	rate.AccruedThrough = through
+	rate.UpdatedAt = time.Now()
+	if err := s.repos.CashInterest.Update(ctx, &rate, from); err != nil {
+		return rate, err
+	}
+	if interest.IsZero() {
+		return rate, nil
+	}
+
+	tx := &models.Transaction{
+		Symbol:   rate.Symbol,
+		Action:   "buy",
+		Shares:   interest.Float64(),
+		Price:    1,
+		Currency: currency,
+		Date:     through,
+		Note:     fmt.Sprintf("Interest at %g%% APY", rate.APY),
+		Tags:     []string{models.CashInterestTag},
+	}
+	if err := s.portfolioService.AddTransaction(rate.UserID, tx); err != nil {
+		return rate, fmt.Errorf("failed to record interest: %w", err)
+	}
+
+	fmt.Printf("[CashInterest] Recorded %s interest on %s for user %s\n", interest, rate.Symbol, rate.UserID.Hex())
+	return rate, nil
+}
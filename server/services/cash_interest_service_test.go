@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/money"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAccrueCashInterest(t *testing.T) {
+	provider := NewFixtureProvider()
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	service := NewCashInterestService(portfolioService, provider)
+	userID := primitive.NewObjectID()
+
+	if err := portfolioService.AddTransaction(userID, &models.Transaction{
+		Symbol: "CASH_USD", Action: "buy", Shares: 10000, Price: 1, Currency: "USD", Date: time.Now().AddDate(0, 0, -30),
+	}); err != nil {
+		t.Fatalf("Failed to add cash: %v", err)
+	}
+
+	if _, err := service.SetRate(userID, "AAPL", 4); !errors.Is(err, ErrInvalidSymbol) {
+		t.Errorf("Expected ErrInvalidSymbol for a stock, got %v", err)
+	}
+	rate, err := service.SetRate(userID, "cash_usd", 3.65)
+	if err != nil {
+		t.Fatalf("Failed to set rate: %v", err)
+	}
+
+	// Nothing is due on the day the rate is set
+	if err := service.AccrueInterest(); err != nil {
+		t.Fatalf("Failed to accrue interest: %v", err)
+	}
+	if transactions, _ := portfolioService.GetTransactionsBySymbol(userID, "CASH_USD"); len(transactions) != 1 {
+		t.Fatalf("Expected no interest on the first day, got %d transactions", len(transactions))
+	}
+
+	// Pretend the rate was set ten days ago
+	ctx := context.Background()
+	backdated := *rate
+	backdated.AccruedThrough = rate.AccruedThrough.AddDate(0, 0, -10)
+	if err := service.repos.CashInterest.Update(ctx, &backdated, rate.AccruedThrough); err != nil {
+		t.Fatalf("Failed to backdate rate: %v", err)
+	}
+
+	for run := 0; run < 2; run++ {
+		if err := service.AccrueInterest(); err != nil {
+			t.Fatalf("Failed to accrue interest: %v", err)
+		}
+	}
+
+	transactions, _ := portfolioService.GetTransactionsBySymbol(userID, "CASH_USD")
+	interest := FilterTransactionsByTag(transactions, models.CashInterestTag)
+	if len(interest) != 1 {
+		t.Fatalf("Expected one interest transaction, got %d", len(interest))
+	}
+	want := money.Round(10000*(math.Pow(1.0365, 10.0/365)-1), "USD")
+	if tx := interest[0]; tx.Shares != want || tx.Price != 1 || tx.Action != "buy" || !tx.Date.Equal(rate.AccruedThrough) {
+		t.Errorf("Expected %.2f of interest dated today, got %+v", want, tx)
+	}
+
+	// Interest is a gain, not a contribution
+	timeline := (&AnalyticsService{}).contributionTimeline(transactions, "USD")
+	if len(timeline) != 1 || timeline[0].Total != 10000 {
+		t.Errorf("Expected only the deposit as a contribution, got %+v", timeline)
+	}
+
+	if err := service.DeleteRate(userID, "CASH_USD"); err != nil {
+		t.Fatalf("Failed to delete rate: %v", err)
+	}
+	if err := service.DeleteRate(userID, "CASH_USD"); !errors.Is(err, ErrCashInterestNotFound) {
+		t.Errorf("Expected ErrCashInterestNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/telemetry"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidTelegramChat    = errors.New("invalid Telegram chat ID")
+	ErrInvalidSlackWebhook    = errors.New("invalid Slack incoming webhook URL")
+	ErrChatChannelUnavailable = errors.New("chat channel is not configured on this server")
+	ErrChatNotLinked          = errors.New("chat channel is not linked")
+	ErrChatDeliveryFailed     = errors.New("chat message delivery failed")
+)
+
+// Chat channel names, as used in the test message endpoint
+const (
+	ChatChannelTelegram = "telegram"
+	ChatChannelSlack    = "slack"
+)
+
+const (
+	// chatTimeout bounds a single chat message delivery
+	chatTimeout = 10 * time.Second
+	// telegramAPIURL is the Telegram Bot API
+	telegramAPIURL = "https://api.telegram.org"
+	// slackWebhookPrefix is where every Slack incoming webhook lives, so users
+	// can't point the server at arbitrary URLs
+	slackWebhookPrefix = "https://hooks.slack.com/"
+)
+
+// telegramChatPattern matches numeric chat IDs, negative for groups, and
+// public channel usernames
+var telegramChatPattern = regexp.MustCompile(`^(-?[0-9]{1,20}|@[A-Za-z][A-Za-z0-9_]{4,31})$`)
+
+// validateChatSettings checks the user's chat destinations. Empty ones are
+// valid and turn the channel off.
+func validateChatSettings(chat models.ChatSettings) error {
+	if chat.TelegramChatID != "" && !telegramChatPattern.MatchString(chat.TelegramChatID) {
+		return ErrInvalidTelegramChat
+	}
+	if chat.SlackWebhookURL != "" && !strings.HasPrefix(chat.SlackWebhookURL, slackWebhookPrefix) {
+		return ErrInvalidSlackWebhook
+	}
+	return nil
+}
+
+// ChatSettingsFunc loads a user's settings, for channels whose destination
+// is configured per user
+type ChatSettingsFunc func(userID primitive.ObjectID) (*models.UserSettings, error)
+
+// chatMessage formats a notification as a plain chat message
+func chatMessage(notification Notification) string {
+	if notification.Text == "" {
+		return notification.Subject
+	}
+	return notification.Subject + "\n\n" + notification.Text
+}
+
+// sendChat delivers a notification through a chat channel that is linked in
+// the user's settings. The outbox retries a failed channel on its own, so
+// failures are returned.
+func sendChat(name string, recipient *models.User, settings ChatSettingsFunc, destination func(models.ChatSettings) string, send func(string, Notification) error, notification Notification) error {
+	userSettings, err := settings(recipient.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch chat settings: %w", err)
+	}
+	to := destination(userSettings.Chat)
+	if to == "" {
+		return nil
+	}
+	return send(to, notification)
+}
+
+// TelegramChannel delivers notifications through a Telegram bot to the chat
+// each user links in their settings
+type TelegramChannel struct {
+	botToken string
+	apiURL   string
+	client   *http.Client
+	settings ChatSettingsFunc
+}
+
+// NewTelegramChannel creates a new TelegramChannel instance sending as the
+// bot with the given token
+func NewTelegramChannel(botToken string, settings ChatSettingsFunc) *TelegramChannel {
+	return &TelegramChannel{
+		botToken: botToken,
+		apiURL:   telegramAPIURL,
+		client: &http.Client{
+			Timeout:   chatTimeout,
+			Transport: telemetry.Transport(nil),
+		},
+		settings: settings,
+	}
+}
+
+// Name returns the channel name
+func (c *TelegramChannel) Name() string {
+	return ChatChannelTelegram
+}
+
+// Send messages the notification to the recipient's linked Telegram chat, if any
+func (c *TelegramChannel) Send(recipient *models.User, notification Notification) error {
+	return sendChat(c.Name(), recipient, c.settings, func(chat models.ChatSettings) string {
+		return chat.TelegramChatID
+	}, c.SendTo, notification)
+}
+
+// SendTo messages the notification to a Telegram chat
+func (c *TelegramChannel) SendTo(chatID string, notification Notification) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     chatMessage(notification),
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), chatTimeout)
+	defer cancel()
+
+	url := c.apiURL + "/bot" + c.botToken + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// The request URL holds the bot token, so don't let it reach the logs
+		return fmt.Errorf("failed to reach Telegram: %w", errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result); err != nil {
+		return fmt.Errorf("invalid Telegram response (status %d): %w", resp.StatusCode, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram rejected the message: %s", result.Description)
+	}
+	return nil
+}
+
+// SlackChannel delivers notifications to the Slack incoming webhook each user
+// links in their settings
+type SlackChannel struct {
+	client   *http.Client
+	settings ChatSettingsFunc
+}
+
+// NewSlackChannel creates a new SlackChannel instance
+func NewSlackChannel(settings ChatSettingsFunc) *SlackChannel {
+	return &SlackChannel{
+		client: &http.Client{
+			Timeout:   chatTimeout,
+			Transport: telemetry.Transport(nil),
+		},
+		settings: settings,
+	}
+}
+
+// Name returns the channel name
+func (c *SlackChannel) Name() string {
+	return ChatChannelSlack
+}
+
+// Send posts the notification to the recipient's linked Slack webhook, if any
+func (c *SlackChannel) Send(recipient *models.User, notification Notification) error {
+	return sendChat(c.Name(), recipient, c.settings, func(chat models.ChatSettings) string {
+		return chat.SlackWebhookURL
+	}, c.SendTo, notification)
+}
+
+// SendTo posts the notification to a Slack incoming webhook
+func (c *SlackChannel) SendTo(webhookURL string, notification Notification) error {
+	text := "*" + notification.Subject + "*"
+	if notification.Text != "" {
+		text += "\n" + notification.Text
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), chatTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// The webhook URL is a credential, so don't let it reach the logs
+		return fmt.Errorf("failed to reach Slack: %w", errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("slack rejected the message (status %d): %s", resp.StatusCode, strings.TrimSpace(string(reason)))
+	}
+	return nil
+}
+
+// ChatChannels holds the chat notification channels available on this
+// server and sends test messages through them
+type ChatChannels struct {
+	telegram *TelegramChannel // nil without a bot token
+	slack    *SlackChannel
+	settings ChatSettingsFunc
+}
+
+// NewChatChannels creates the chat channels. Telegram is only available with
+// a bot token.
+func NewChatChannels(telegramBotToken string, settings ChatSettingsFunc) *ChatChannels {
+	channels := &ChatChannels{
+		slack:    NewSlackChannel(settings),
+		settings: settings,
+	}
+	if telegramBotToken != "" {
+		channels.telegram = NewTelegramChannel(telegramBotToken, settings)
+	}
+	return channels
+}
+
+// List returns the available channels, for the NotificationService
+func (c *ChatChannels) List() []NotificationChannel {
+	list := []NotificationChannel{c.slack}
+	if c.telegram != nil {
+		list = append(list, c.telegram)
+	}
+	return list
+}
+
+// SendTest sends a test message to the user's linked chat on a channel, so
+// they can check the link before an alert depends on it
+func (c *ChatChannels) SendTest(userID primitive.ObjectID, channel string) error {
+	settings, err := c.settings(userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch chat settings: %w", err)
+	}
+
+	notification := Notification{
+		Subject: "Test notification",
+		Text:    "Portfolio alerts will be sent to this chat.",
+	}
+	switch channel {
+	case ChatChannelTelegram:
+		if c.telegram == nil {
+			return ErrChatChannelUnavailable
+		}
+		if settings.Chat.TelegramChatID == "" {
+			return ErrChatNotLinked
+		}
+		err = c.telegram.SendTo(settings.Chat.TelegramChatID, notification)
+	case ChatChannelSlack:
+		if settings.Chat.SlackWebhookURL == "" {
+			return ErrChatNotLinked
+		}
+		err = c.slack.SendTo(settings.Chat.SlackWebhookURL, notification)
+	default:
+		return ErrChatChannelUnavailable
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrChatDeliveryFailed, err)
+	}
+	return nil
+}
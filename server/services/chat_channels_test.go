@@ -0,0 +1,142 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"stock-portfolio-tracker/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// chatSettingsStub returns settings with the given chat destinations
+func chatSettingsStub(chat models.ChatSettings) ChatSettingsFunc {
+	return func(userID primitive.ObjectID) (*models.UserSettings, error) {
+		settings := defaultUserSettings(userID)
+		settings.Chat = chat
+		return settings, nil
+	}
+}
+
+func TestTelegramChannelSend(t *testing.T) {
+	var received []map[string]interface{}
+	ok := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/botTOKEN/sendMessage" {
+			t.Errorf("Unexpected path %s", req.URL.Path)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		received = append(received, body)
+		if ok {
+			w.Write([]byte(`{"ok":true,"result":{}}`))
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	channel := NewTelegramChannel("TOKEN", chatSettingsStub(models.ChatSettings{TelegramChatID: "-100123"}))
+	channel.apiURL = server.URL
+	user := &models.User{ID: primitive.NewObjectID()}
+
+	if err := channel.Send(user, Notification{Subject: "Stop triggered", Text: "AAPL fell to 150"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(received) != 1 || received[0]["chat_id"] != "-100123" || received[0]["text"] != "Stop triggered\n\nAAPL fell to 150" {
+		t.Fatalf("Unexpected messages %v", received)
+	}
+
+	// A rejected message fails Send, so the outbox retries this channel
+	ok = false
+	if err := channel.SendTo("-100123", Notification{Subject: "Hi"}); err == nil || err.Error() != "telegram rejected the message: Bad Request: chat not found" {
+		t.Errorf("Unexpected SendTo error %v", err)
+	}
+	if err := channel.Send(user, Notification{Subject: "Hi"}); err == nil {
+		t.Errorf("Expected Send to report the rejected message")
+	}
+
+	// Users without a linked chat get nothing
+	unlinked := NewTelegramChannel("TOKEN", chatSettingsStub(models.ChatSettings{}))
+	unlinked.apiURL = server.URL
+	unlinked.Send(user, Notification{Subject: "Hi"})
+	if len(received) != 3 {
+		t.Errorf("Expected no message for an unlinked user, got %d messages", len(received))
+	}
+}
+
+func TestSlackChannelSendTo(t *testing.T) {
+	var text string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]string
+		json.NewDecoder(req.Body).Decode(&body)
+		text = body["text"]
+		if req.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("no_service"))
+		}
+	}))
+	defer server.Close()
+
+	channel := NewSlackChannel(chatSettingsStub(models.ChatSettings{}))
+	if err := channel.SendTo(server.URL+"/hook", Notification{Subject: "Drift alert", Text: "Stock is 12 pp over"}); err != nil {
+		t.Fatalf("SendTo failed: %v", err)
+	}
+	if text != "*Drift alert*\nStock is 12 pp over" {
+		t.Errorf("Unexpected text %q", text)
+	}
+	if err := channel.SendTo(server.URL+"/gone", Notification{Subject: "Hi"}); err == nil || err.Error() != "slack rejected the message (status 404): no_service" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestValidateChatSettings(t *testing.T) {
+	valid := []models.ChatSettings{
+		{},
+		{TelegramChatID: "123456789"},
+		{TelegramChatID: "-1001234567890"},
+		{TelegramChatID: "@portfolio_alerts", SlackWebhookURL: "https://hooks.slack.com/services/T000/B000/XXXX"},
+	}
+	for _, chat := range valid {
+		if err := validateChatSettings(chat); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", chat, err)
+		}
+	}
+
+	if err := validateChatSettings(models.ChatSettings{TelegramChatID: "@abc"}); !errors.Is(err, ErrInvalidTelegramChat) {
+		t.Errorf("Expected ErrInvalidTelegramChat, got %v", err)
+	}
+	if err := validateChatSettings(models.ChatSettings{SlackWebhookURL: "https://hooks.slack.com.evil.example/x"}); !errors.Is(err, ErrInvalidSlackWebhook) {
+		t.Errorf("Expected ErrInvalidSlackWebhook, got %v", err)
+	}
+}
+
+func TestChatChannelsSendTest(t *testing.T) {
+	userID := primitive.NewObjectID()
+
+	channels := NewChatChannels("", chatSettingsStub(models.ChatSettings{TelegramChatID: "42"}))
+	if len(channels.List()) != 1 {
+		t.Errorf("Expected only Slack without a bot token, got %d channels", len(channels.List()))
+	}
+	if err := channels.SendTest(userID, ChatChannelTelegram); !errors.Is(err, ErrChatChannelUnavailable) {
+		t.Errorf("Expected ErrChatChannelUnavailable, got %v", err)
+	}
+	if err := channels.SendTest(userID, ChatChannelSlack); !errors.Is(err, ErrChatNotLinked) {
+		t.Errorf("Expected ErrChatNotLinked, got %v", err)
+	}
+	if err := channels.SendTest(userID, "sms"); !errors.Is(err, ErrChatChannelUnavailable) {
+		t.Errorf("Expected ErrChatChannelUnavailable for an unknown channel, got %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	channels = NewChatChannels("TOKEN", chatSettingsStub(models.ChatSettings{SlackWebhookURL: server.URL}))
+	if err := channels.SendTest(userID, ChatChannelSlack); !errors.Is(err, ErrChatDeliveryFailed) {
+		t.Errorf("Expected ErrChatDeliveryFailed, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+)
+
+// CrossAlertJobInterval is how often held symbols are checked for moving
+// average crosses
+const CrossAlertJobInterval = time.Hour
+
+// crossAlertWindow is how recent a cross must be to be alerted, so turning
+// alerts on doesn't report crosses from long ago
+const crossAlertWindow = 5 * 24 * time.Hour
+
+// CrossAlertService notifies users when the 50-day moving average of a symbol
+// they hold crosses its 200-day one
+type CrossAlertService struct {
+	repos               repository.Repositories
+	portfolioService    *PortfolioService
+	notificationService *NotificationService
+}
+
+// NewCrossAlertService creates a new CrossAlertService instance
+func NewCrossAlertService(portfolioService *PortfolioService, notificationService *NotificationService) *CrossAlertService {
+	return &CrossAlertService{
+		repos:               portfolioService.repos,
+		portfolioService:    portfolioService,
+		notificationService: notificationService,
+	}
+}
+
+// recentCrosses returns the latest cross of each symbol dated within the
+// alert window before now, ordered by symbol
+func recentCrosses(technicals map[string]*PriceTechnicals, now time.Time) []models.AlertedCross {
+	crosses := []models.AlertedCross{}
+	for symbol, symbolTechnicals := range technicals {
+		cross := symbolTechnicals.LastCross
+		if cross == nil || now.Sub(cross.Date) > crossAlertWindow {
+			continue
+		}
+		crosses = append(crosses, models.AlertedCross{Symbol: symbol, Type: cross.Type, Date: cross.Date})
+	}
+	sort.Slice(crosses, func(i, j int) bool { return crosses[i].Symbol < crosses[j].Symbol })
+	return crosses
+}
+
+// unalertedCrosses returns the crosses not among those already alerted
+func unalertedCrosses(crosses, alerted []models.AlertedCross) []models.AlertedCross {
+	fresh := []models.AlertedCross{}
+	for _, cross := range crosses {
+		seen := false
+		for _, previous := range alerted {
+			if previous.Symbol == cross.Symbol && previous.Type == cross.Type && previous.Date.Equal(cross.Date) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			fresh = append(fresh, cross)
+		}
+	}
+	return fresh
+}
+
+// CheckCrosses checks the held symbols of every user with cross alerts enabled
+// and notifies those with crosses not yet alerted. The recent crosses are
+// recorded with a conditional update in the transaction that queues the
+// alert, so several server instances running this job never send the same
+// alert twice.
+func (s *CrossAlertService) CheckCrosses() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	subscribers, err := s.repos.Settings.FindCrossAlertSubscribers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch cross alert subscribers: %w", err)
+	}
+
+	now := time.Now()
+	queued := 0
+	var errs []error
+	for _, settings := range subscribers {
+		positions, err := s.portfolioService.getPositions(context.Background(), settings.UserID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("crosses for user %s: %w", settings.UserID.Hex(), err))
+			continue
+		}
+
+		technicals := make(map[string]*PriceTechnicals, len(positions))
+		for _, position := range positions {
+			if s.portfolioService.stockService.IsCashSymbol(position.Symbol) {
+				continue
+			}
+			symbolCtx, symbolCancel := context.WithTimeout(context.Background(), 30*time.Second)
+			symbolTechnicals, err := s.portfolioService.SymbolTechnicals(symbolCtx, position.Symbol)
+			symbolCancel()
+			if err != nil {
+				fmt.Printf("[CrossAlert] Warning: %v\n", err)
+				continue
+			}
+			technicals[position.Symbol] = symbolTechnicals
+		}
+
+		alerts := settings.CrossAlerts
+		crosses := recentCrosses(technicals, now)
+		fresh := unalertedCrosses(crosses, alerts.Alerted)
+		if len(fresh) == 0 && len(crosses) == len(alerts.Alerted) {
+			continue
+		}
+
+		claim := func(ctx context.Context) error {
+			var alertedAt *time.Time
+			if len(fresh) > 0 {
+				alertedAt = &now
+			}
+			err := s.repos.Settings.ClaimCrossAlert(ctx, settings.UserID, settings.ID, alerts.Alerted, crosses, alertedAt)
+			if errors.Is(err, repository.ErrNotFound) {
+				return errClaimLost
+			}
+			if err != nil {
+				return fmt.Errorf("failed to update cross alert status: %w", err)
+			}
+			return nil
+		}
+		updateCtx, updateCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if len(fresh) == 0 {
+			// Crosses aged out of the window: nothing to report
+			err = claim(updateCtx)
+		} else {
+			err = s.notificationService.NotifyWith(updateCtx, "cross-alerts", settings.UserID, renderCrossNotification(fresh), claim)
+		}
+		updateCancel()
+		if errors.Is(err, errClaimLost) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cross alert for user %s: %w", settings.UserID.Hex(), err))
+			continue
+		}
+		if len(fresh) > 0 {
+			queued++
+		}
+	}
+
+	if queued > 0 {
+		fmt.Printf("[CrossAlert] Queued %d cross alerts\n", queued)
+	}
+	return errors.Join(errs...)
+}
+
+// renderCrossNotification renders new moving average crosses as a plain text email
+func renderCrossNotification(crosses []models.AlertedCross) Notification {
+	var text strings.Builder
+	text.WriteString("The 50-day moving average of these holdings crossed their 200-day average:\n\n")
+	for _, cross := range crosses {
+		direction := "above"
+		if cross.Type == CrossDeath {
+			direction = "below"
+		}
+		fmt.Fprintf(&text, "  %s  %s cross on %s, 50-day now %s the 200-day\n", cross.Symbol, cross.Type, cross.Date.Format("Jan 2, 2006"), direction)
+	}
+	text.WriteString("\nYou can turn off these alerts in your settings.\n")
+
+	subject := fmt.Sprintf("%s %s cross", crosses[0].Symbol, crosses[0].Type)
+	if len(crosses) > 1 {
+		subject = fmt.Sprintf("%d holdings crossed their 200-day average", len(crosses))
+	}
+	return Notification{
+		Subject: "Moving average alert: " + subject,
+		Text:    text.String(),
+	}
+}
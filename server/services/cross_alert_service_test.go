@@ -0,0 +1,92 @@
+package services
+
+import (
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRecentCrosses(t *testing.T) {
+	now := time.Date(2024, 6, 28, 12, 0, 0, 0, time.UTC)
+	technicals := map[string]*PriceTechnicals{
+		"MSFT": {LastCross: &MovingAverageCross{Type: CrossDeath, Date: now.AddDate(0, 0, -1)}},
+		"AAPL": {LastCross: &MovingAverageCross{Type: CrossGolden, Date: now.AddDate(0, 0, -2)}},
+		"KO":   {LastCross: &MovingAverageCross{Type: CrossGolden, Date: now.AddDate(0, -2, 0)}},
+		"VOO":  {},
+	}
+
+	crosses := recentCrosses(technicals, now)
+	if len(crosses) != 2 || crosses[0].Symbol != "AAPL" || crosses[1].Symbol != "MSFT" {
+		t.Fatalf("Expected the recent AAPL and MSFT crosses, got %+v", crosses)
+	}
+
+	fresh := unalertedCrosses(crosses, []models.AlertedCross{crosses[0]})
+	if len(fresh) != 1 || fresh[0].Symbol != "MSFT" {
+		t.Errorf("Expected only the MSFT cross to be new, got %+v", fresh)
+	}
+	// A later cross of an alerted symbol is new
+	later := models.AlertedCross{Symbol: "AAPL", Type: CrossDeath, Date: now}
+	if fresh := unalertedCrosses([]models.AlertedCross{later}, crosses); len(fresh) != 1 {
+		t.Errorf("Expected the later AAPL cross to be new, got %+v", fresh)
+	}
+
+	notification := renderCrossNotification(crosses)
+	if notification.Subject != "Moving average alert: 2 holdings crossed their 200-day average" {
+		t.Errorf("Unexpected subject %q", notification.Subject)
+	}
+	if !strings.Contains(notification.Text, "AAPL  golden cross on Jun 26, 2024, 50-day now above") ||
+		!strings.Contains(notification.Text, "MSFT  death cross on Jun 27, 2024, 50-day now below") {
+		t.Errorf("Unexpected text %q", notification.Text)
+	}
+}
+
+func TestCheckCrosses(t *testing.T) {
+	// A long decline ending in a spike that lifts the 50-day average over
+	// the 200-day one on the last day
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	closes := make([]float64, 250)
+	for i := range closes {
+		closes[i] = 300 - 0.5*float64(i)
+	}
+	closes[len(closes)-1] = 3000
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 3000, "USD").
+		SetHistory("AAPL", today, closes...)
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	repos := portfolioService.repos
+	service := NewCrossAlertService(portfolioService, NewNotificationServiceWithRepos(repos, &flakyChannel{}))
+	settingsRepo := repos.Settings.(*repository.MemorySettings)
+	outbox := repos.Outbox.(*repository.MemoryOutbox)
+
+	userID := primitive.NewObjectID()
+	tx := &models.Transaction{Symbol: "AAPL", Action: "buy", Shares: 1, Price: 200, Currency: "USD", Date: today.AddDate(0, 0, -30)}
+	if err := portfolioService.AddTransaction(userID, tx); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	settingsID := primitive.NewObjectID()
+	settingsRepo.Put(models.UserSettings{ID: settingsID, UserID: userID, CrossAlerts: models.CrossAlertSettings{Enabled: true}})
+
+	if err := service.CheckCrosses(); err != nil {
+		t.Fatalf("CheckCrosses failed: %v", err)
+	}
+	messages := outbox.Messages()
+	if len(messages) != 1 || messages[0].UserID != userID || messages[0].Source != "cross-alerts" {
+		t.Fatalf("Expected one cross alert queued for the user, got %+v", messages)
+	}
+	settings, _ := settingsRepo.Get(settingsID)
+	if alerted := settings.CrossAlerts.Alerted; len(alerted) != 1 || alerted[0].Symbol != "AAPL" || alerted[0].Type != CrossGolden || settings.CrossAlerts.LastAlertedAt == nil {
+		t.Errorf("Expected the AAPL golden cross recorded, got %+v", settings.CrossAlerts)
+	}
+
+	// The same cross isn't alerted again
+	if err := service.CheckCrosses(); err != nil {
+		t.Fatalf("CheckCrosses failed: %v", err)
+	}
+	if len(outbox.Messages()) != 1 {
+		t.Errorf("Expected no second alert for the same cross, got %d messages", len(outbox.Messages()))
+	}
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// cryptoSymbolPattern matches the dollar pairs crypto assets are tracked
+// under, such as BTC-USD
+var cryptoSymbolPattern = regexp.MustCompile(`^[A-Z0-9]{2,10}-USD$`)
+
+// isCryptoSymbol reports whether a symbol is a crypto asset's dollar pair
+func isCryptoSymbol(symbol string) bool {
+	return cryptoSymbolPattern.MatchString(symbol)
+}
+
+// cryptoSymbol returns the symbol a crypto asset is tracked under
+func cryptoSymbol(asset string) string {
+	return strings.ToUpper(asset) + "-USD"
+}
+
+// cryptoDollarQuotes are the quote assets of the exchange markets whose
+// trades are imported. Transactions are recorded in dollars, so trades
+// quoted in stablecoins count as dollar trades and trades in other markets,
+// such as ETH-BTC or BTC-EUR, are left out.
+var cryptoDollarQuotes = []string{"USD", "USDT", "USDC", "FDUSD"}
+
+// isCryptoDollarQuote reports whether trades quoted in an asset are imported
+func isCryptoDollarQuote(asset string) bool {
+	for _, quote := range cryptoDollarQuotes {
+		if strings.EqualFold(asset, quote) {
+			return true
+		}
+	}
+	return false
+}
+
+// cryptoCashAssets are exchange balances that are cash rather than crypto
+// positions, so they aren't compared with the tracked positions
+var cryptoCashAssets = map[string]bool{
+	"USD": true, "USDT": true, "USDC": true, "FDUSD": true, "BUSD": true, "DAI": true,
+	"EUR": true, "GBP": true, "JPY": true, "CAD": true, "AUD": true, "TRY": true, "BRL": true,
+}
+
+// isCryptoPosition reports whether an exchange balance of an asset is a
+// crypto position
+func isCryptoPosition(asset string) bool {
+	asset = strings.ToUpper(asset)
+	return asset != "" && !cryptoCashAssets[asset]
+}
+
+// parseExchangeNumber parses a decimal exchanges send as a string, reading
+// anything unparsable as zero
+func parseExchangeNumber(value string) float64 {
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0
+	}
+	return number
+}
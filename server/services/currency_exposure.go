@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/internal/analytics/engine"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HoldingCurrencyEffect splits a holding's period gain in the requested
+// currency into the asset's own return and the exchange rate move
+type HoldingCurrencyEffect struct {
+	Symbol        string  `json:"symbol"`
+	Name          string  `json:"name"`
+	AssetCurrency string  `json:"assetCurrency"`
+	Value         float64 `json:"value"`
+	LocalReturn   float64 `json:"localReturn"` // Return in the asset's own currency, i.e. fully hedged, percentage
+	FXReturn      float64 `json:"fxReturn"`    // Change of the asset currency against the requested currency, percentage
+	TotalReturn   float64 `json:"totalReturn"` // Unhedged return in the requested currency, percentage
+	LocalGain     float64 `json:"localGain"`   // Gain from the asset price at the starting exchange rate
+	FXGain        float64 `json:"fxGain"`      // Gain from translating the ending value at the moved rate
+	TotalGain     float64 `json:"totalGain"`
+}
+
+// CurrencyExposure summarizes holdings denominated in one currency
+type CurrencyExposure struct {
+	Currency  string  `json:"currency"`
+	Value     float64 `json:"value"`
+	Weight    float64 `json:"weight"` // Percentage
+	LocalGain float64 `json:"localGain"`
+	FXGain    float64 `json:"fxGain"`
+	FXReturn  float64 `json:"fxReturn"` // Percentage
+}
+
+// CurrencyEffectResponse separates asset returns from FX translation effects
+// over a period
+type CurrencyEffectResponse struct {
+	Currency     string                  `json:"currency"`
+	Period       string                  `json:"period"`
+	PeriodStart  time.Time               `json:"periodStart"`
+	TotalValue   float64                 `json:"totalValue"`
+	LocalGain    float64                 `json:"localGain"`
+	FXGain       float64                 `json:"fxGain"`
+	TotalGain    float64                 `json:"totalGain"`
+	HedgedReturn float64                 `json:"hedgedReturn"` // Return with the FX effect removed, percentage
+	TotalReturn  float64                 `json:"totalReturn"`  // Percentage
+	Exposures    []CurrencyExposure      `json:"exposures"`
+	Holdings     []HoldingCurrencyEffect `json:"holdings"`
+}
+
+// GetCurrencyEffect decomposes each current holding's period return in the
+// requested currency into its local-currency return and the FX translation
+// effect, e.g. how much of an A-share's USD gain is just the CNY/USD move.
+// Like the movers view, it measures the shares currently held. Both the start
+// and end rates come from the same daily currency pair history so the parts
+// add up. Prices are fetched under ctx, and manual prices replace the closes
+// from their effective dates.
+func (s *AnalyticsService) GetCurrencyEffect(ctx context.Context, userID primitive.ObjectID, period string, currency string) (*CurrencyEffectResponse, error) {
+	currency = currencycode.Normalize(currency)
+
+	holdings, err := s.portfolioService.GetUserHoldingsContext(ctx, userID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch holdings: %w", err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	overrides, err := s.portfolioService.priceOverrides(queryCtx, userID)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	start := PeriodStart(period, time.Now())
+	response := &CurrencyEffectResponse{
+		Currency:    currency,
+		Period:      period,
+		PeriodStart: start,
+		Exposures:   []CurrencyExposure{},
+		Holdings:    []HoldingCurrencyEffect{},
+	}
+
+	rates := make(map[string][]HistoricalPrice)
+	for _, holding := range holdings {
+		if s.stockService.IsCashSymbol(holding.Symbol) {
+			continue
+		}
+
+		assetCurrency := currencycode.Normalize(s.stockService.SymbolCurrency(holding.Symbol))
+
+		startRate, endRate := 1.0, 1.0
+		if assetCurrency != currency {
+			history, ok := rates[assetCurrency]
+			if !ok {
+				history, err = s.stockService.GetHistoricalDataContext(ctx, fxPairSymbol(assetCurrency, currency), period)
+				if err != nil {
+					return nil, fmt.Errorf("failed to fetch exchange rate history: %w", err)
+				}
+				history = engine.Sorted(history)
+				rates[assetCurrency] = history
+			}
+			startRate, endRate, ok = fxRates(history, start)
+			if !ok {
+				return nil, fmt.Errorf("failed to fetch exchange rate history: no rates for %s", period)
+			}
+		}
+
+		prices, err := s.stockService.GetHistoricalDataContext(ctx, holding.Symbol, period)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			fmt.Printf("[Analytics] Warning: Could not get price history for %s: %v\n", holding.Symbol, err)
+			continue
+		}
+		if override, ok := overrides[holding.Symbol]; ok {
+			prices = overrideHistory(prices, override)
+		}
+
+		effect, ok := currencyEffect(holding, engine.Sorted(prices), start, startRate, endRate)
+		if !ok {
+			continue
+		}
+		effect.AssetCurrency = assetCurrency
+		response.Holdings = append(response.Holdings, effect)
+	}
+
+	summarizeCurrencyEffects(response)
+	return response, nil
+}
+
+// fxPairSymbol returns the Yahoo Finance symbol quoting one currency in another
+func fxPairSymbol(from string, to string) string {
+	return currencycode.ISO(from) + currencycode.ISO(to) + "=X"
+}
+
+// fxRates returns the rate at the start of the period and at the latest close
+// from a date-sorted currency pair history
+func fxRates(rates []HistoricalPrice, start time.Time) (startRate float64, endRate float64, ok bool) {
+	startRate, ok = priceOnOrBefore(rates, start)
+	if !ok || rates[len(rates)-1].Price <= 0 {
+		return 0, 0, false
+	}
+	return startRate, rates[len(rates)-1].Price, true
+}
+
+// priceOnOrBefore returns the last positive price on or before the date, or
+// the first price when the date precedes the history
+func priceOnOrBefore(prices []HistoricalPrice, date time.Time) (float64, bool) {
+	price := 0.0
+	for _, p := range prices {
+		if p.Date.After(date) && price > 0 {
+			break
+		}
+		if p.Price > 0 {
+			price = p.Price
+		}
+	}
+	return price, price > 0
+}
+
+// currencyEffect splits a holding's gain since the period start. The local
+// gain is the price change at the starting rate and the FX gain is the ending
+// native value times the rate change, so together they equal the total gain.
+func currencyEffect(holding Holding, prices []HistoricalPrice, start time.Time, startRate float64, endRate float64) (HoldingCurrencyEffect, bool) {
+	if len(prices) == 0 || holding.Shares <= 0 {
+		return HoldingCurrencyEffect{}, false
+	}
+	startPrice, ok := priceOnOrBefore(prices, start)
+	endPrice := prices[len(prices)-1].Price
+	if !ok || endPrice <= 0 {
+		return HoldingCurrencyEffect{}, false
+	}
+
+	localGain := holding.Shares * (endPrice - startPrice) * startRate
+	fxGain := holding.Shares * endPrice * (endRate - startRate)
+	startValue := holding.Shares * startPrice * startRate
+
+	return HoldingCurrencyEffect{
+		Symbol:      holding.Symbol,
+		Name:        holding.Name,
+		Value:       holding.Shares * endPrice * endRate,
+		LocalReturn: (endPrice/startPrice - 1) * 100,
+		FXReturn:    (endRate/startRate - 1) * 100,
+		TotalReturn: (localGain + fxGain) / startValue * 100,
+		LocalGain:   localGain,
+		FXGain:      fxGain,
+		TotalGain:   localGain + fxGain,
+	}, true
+}
+
+// summarizeCurrencyEffects fills in the portfolio totals and per-currency
+// exposures from the holding effects
+func summarizeCurrencyEffects(response *CurrencyEffectResponse) {
+	byCurrency := make(map[string]*CurrencyExposure)
+	startValue := 0.0
+	for _, effect := range response.Holdings {
+		exposure, ok := byCurrency[effect.AssetCurrency]
+		if !ok {
+			exposure = &CurrencyExposure{Currency: effect.AssetCurrency}
+			byCurrency[effect.AssetCurrency] = exposure
+		}
+		exposure.Value += effect.Value
+		exposure.LocalGain += effect.LocalGain
+		exposure.FXGain += effect.FXGain
+		exposure.FXReturn = effect.FXReturn
+		startValue += effect.Value - effect.TotalGain
+
+		response.TotalValue += effect.Value
+		response.LocalGain += effect.LocalGain
+		response.FXGain += effect.FXGain
+	}
+	response.TotalGain = response.LocalGain + response.FXGain
+
+	if startValue > 0 {
+		response.HedgedReturn = response.LocalGain / startValue * 100
+		response.TotalReturn = response.TotalGain / startValue * 100
+	}
+
+	for _, exposure := range byCurrency {
+		if response.TotalValue > 0 {
+			exposure.Weight = exposure.Value / response.TotalValue * 100
+		}
+		response.Exposures = append(response.Exposures, *exposure)
+	}
+	sort.Slice(response.Exposures, func(i, j int) bool {
+		return response.Exposures[i].Value > response.Exposures[j].Value
+	})
+	sort.Slice(response.Holdings, func(i, j int) bool {
+		return response.Holdings[i].Value > response.Holdings[j].Value
+	})
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCurrencyEffect(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC)
+	}
+	// USD/CNY moves from 7.0 to 7.2, so CNY loses value against USD
+	if symbol := fxPairSymbol("RMB", "USD"); symbol != "CNYUSD=X" {
+		t.Errorf("Unexpected pair symbol %s", symbol)
+	}
+	rates := []HistoricalPrice{
+		{Date: day(1), Price: 1 / 7.0},
+		{Date: day(10), Price: 1 / 7.2},
+	}
+	startRate, endRate, ok := fxRates(rates, day(2))
+	if !ok || math.Abs(startRate-1/7.0) > 1e-12 || math.Abs(endRate-1/7.2) > 1e-12 {
+		t.Fatalf("Unexpected RMB to USD rates %v, %v", startRate, endRate)
+	}
+
+	// 100 A-shares rise from 70 to 79.2 RMB
+	prices := []HistoricalPrice{
+		{Date: day(1), Price: 70},
+		{Date: day(10), Price: 79.2},
+	}
+	holding := Holding{Symbol: "600519.SS", Shares: 100}
+	effect, ok := currencyEffect(holding, prices, day(2), startRate, endRate)
+	if !ok {
+		t.Fatalf("Expected an effect to be computed")
+	}
+
+	// Start 1000 USD, end 1100 USD; the hedged gain is 920 RMB at 7.0
+	if math.Abs(effect.LocalGain-920.0/7) > 1e-9 {
+		t.Errorf("Unexpected local gain %v", effect.LocalGain)
+	}
+	if math.Abs(effect.TotalGain-100) > 1e-9 || math.Abs(effect.LocalGain+effect.FXGain-effect.TotalGain) > 1e-9 {
+		t.Errorf("Unexpected gains %+v", effect)
+	}
+	if math.Abs(effect.LocalReturn-100.0*9.2/70) > 1e-9 || math.Abs(effect.TotalReturn-10) > 1e-9 {
+		t.Errorf("Unexpected returns %+v", effect)
+	}
+	if effect.FXReturn >= 0 {
+		t.Errorf("Expected a negative FX return, got %v", effect.FXReturn)
+	}
+
+	response := &CurrencyEffectResponse{Holdings: []HoldingCurrencyEffect{effect}}
+	response.Holdings[0].AssetCurrency = "RMB"
+	summarizeCurrencyEffects(response)
+	if math.Abs(response.TotalReturn-10) > 1e-9 || len(response.Exposures) != 1 || response.Exposures[0].Weight != 100 {
+		t.Errorf("Unexpected summary %+v", response)
+	}
+}
@@ -1,13 +1,14 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/telemetry"
+	"strings"
 	"sync"
 	"time"
 )
@@ -21,13 +22,36 @@ var (
 // CachedExchangeRate represents a cached exchange rate with expiration
 type CachedExchangeRate struct {
 	Rate      float64
+	UpdatedAt time.Time
 	ExpiresAt time.Time
+	Fallback  bool // Taken from the built-in fallback rates
+}
+
+// RateFreshness reports how current the exchange rates behind a converted
+// response are. Stale is set when any rate is past its cache lifetime or is
+// one of the built-in fallback rates; LastUpdated is when the oldest live
+// rate was fetched.
+type RateFreshness struct {
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+	Stale       bool       `json:"stale"`
+}
+
+// CurrencyConfig configures the exchange rate providers. Zero durations use
+// the defaults.
+type CurrencyConfig struct {
+	APIKey   string        // ExchangeRate-API key; the free Frankfurter API is used alone without one
+	Timeout  time.Duration // Default 30s
+	Rate     float64       // Requests per second to each provider, default 2
+	CacheTTL time.Duration // Default 1h
+
+	// Providers replaces the live providers, tried in order. The built-in
+	// fallback rates are always tried last.
+	Providers []FXProvider
 }
 
 // CurrencyService handles currency conversion operations
 type CurrencyService struct {
-	httpClient         *http.Client
-	apiKey             string
+	providers          []FXProvider
 	rateCache          map[string]*CachedExchangeRate
 	cacheMutex         sync.RWMutex
 	rateCacheDuration  time.Duration
@@ -41,20 +65,54 @@ type exchangeRateAPIResponse struct {
 	TimeLastUpdateUnix int64            `json:"time_last_update_unix"`
 }
 
-// NewCurrencyService creates a new CurrencyService instance
-func NewCurrencyService() *CurrencyService {
-	apiKey := os.Getenv("EXCHANGE_RATE_API_KEY")
-	
+// NewCurrencyService creates a new CurrencyService instance. Unless providers
+// are given, rates come from ExchangeRate-API when an API key is configured,
+// then from Frankfurter.
+func NewCurrencyService(config CurrencyConfig) *CurrencyService {
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.Rate <= 0 {
+		config.Rate = 2
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = time.Hour
+	}
+
+	providers := config.Providers
+	if providers == nil {
+		// Each provider has its own allowance, so falling back is not held up
+		// by the provider that failed
+		client := func() *http.Client {
+			return &http.Client{
+				Timeout:   config.Timeout,
+				Transport: rateLimited(telemetry.Transport(nil), config.Rate),
+			}
+		}
+		if config.APIKey != "" {
+			providers = append(providers, NewExchangeRateAPIProvider(client(), config.APIKey))
+		}
+		providers = append(providers, NewFrankfurterProvider(client()))
+	}
+
 	return &CurrencyService{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		apiKey:            apiKey,
+		providers:         providers,
 		rateCache:         make(map[string]*CachedExchangeRate),
-		rateCacheDuration: 1 * time.Hour,
+		rateCacheDuration: config.CacheTTL,
 	}
 }
 
+// FlushCache drops every cached exchange rate and returns how many it
+// dropped. Rates are refetched on next use.
+func (s *CurrencyService) FlushCache() int {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+
+	removed := len(s.rateCache)
+	s.rateCache = make(map[string]*CachedExchangeRate)
+	return removed
+}
+
 // getCachedRate retrieves exchange rate from cache if available and not expired
 func (s *CurrencyService) getCachedRate(cacheKey string) (float64, bool) {
 	s.cacheMutex.RLock()
@@ -73,13 +131,16 @@ func (s *CurrencyService) getCachedRate(cacheKey string) (float64, bool) {
 }
 
 // setCachedRate stores exchange rate in cache with expiration
-func (s *CurrencyService) setCachedRate(cacheKey string, rate float64) {
+func (s *CurrencyService) setCachedRate(cacheKey string, rate float64, fallback bool) {
 	s.cacheMutex.Lock()
 	defer s.cacheMutex.Unlock()
 	
+	now := time.Now()
 	s.rateCache[cacheKey] = &CachedExchangeRate{
 		Rate:      rate,
-		ExpiresAt: time.Now().Add(s.rateCacheDuration),
+		UpdatedAt: now,
+		ExpiresAt: now.Add(s.rateCacheDuration),
+		Fallback:  fallback,
 	}
 }
 
@@ -96,76 +157,79 @@ func (s *CurrencyService) getLastCachedRate(cacheKey string) (float64, bool) {
 	return cached.Rate, true
 }
 
-// getFallbackRate returns a hardcoded fallback exchange rate
-// These rates are approximate and should only be used when API is unavailable
-func (s *CurrencyService) getFallbackRate(from, to string) float64 {
-	// Fallback rates (approximate, as of Nov 2025)
-	fallbackRates := map[string]map[string]float64{
-		"USD": {
-			"RMB": 7.2,
-			"CNY": 7.2,
-			"EUR": 0.92,
-			"GBP": 0.79,
-			"JPY": 149.0,
-		},
-		"RMB": {
-			"USD": 0.139,
-			"EUR": 0.128,
-			"GBP": 0.110,
-			"JPY": 20.7,
-		},
-		"CNY": {
-			"USD": 0.139,
-			"EUR": 0.128,
-			"GBP": 0.110,
-			"JPY": 20.7,
-		},
-		"EUR": {
-			"USD": 1.09,
-			"RMB": 7.83,
-			"CNY": 7.83,
-			"GBP": 0.86,
-			"JPY": 162.0,
-		},
-		"GBP": {
-			"USD": 1.27,
-			"RMB": 9.14,
-			"CNY": 9.14,
-			"EUR": 1.16,
-			"JPY": 189.0,
-		},
-		"JPY": {
-			"USD": 0.0067,
-			"RMB": 0.048,
-			"CNY": 0.048,
-			"EUR": 0.0062,
-			"GBP": 0.0053,
-		},
-	}
-	
-	if rates, ok := fallbackRates[from]; ok {
-		if rate, ok := rates[to]; ok {
-			return rate
-		}
-	}
-	
-	return 0
+// fallbackRates are approximate exchange rates (as of Nov 2025), used only
+// when no provider is available
+var fallbackRates = StaticFXProvider{
+	"USD": {
+		"RMB": 7.2,
+		"CNY": 7.2,
+		"EUR": 0.92,
+		"GBP": 0.79,
+		"JPY": 149.0,
+		"HKD": 7.8,
+		"CAD": 1.36,
+	},
+	"RMB": {
+		"USD": 0.139,
+		"EUR": 0.128,
+		"GBP": 0.110,
+		"JPY": 20.7,
+		"HKD": 1.08,
+		"CAD": 0.19,
+	},
+	"CNY": {
+		"USD": 0.139,
+		"EUR": 0.128,
+		"GBP": 0.110,
+		"JPY": 20.7,
+		"HKD": 1.08,
+		"CAD": 0.19,
+	},
+	"EUR": {
+		"USD": 1.09,
+		"RMB": 7.83,
+		"CNY": 7.83,
+		"GBP": 0.86,
+		"JPY": 162.0,
+	},
+	"GBP": {
+		"USD": 1.27,
+		"RMB": 9.14,
+		"CNY": 9.14,
+		"EUR": 1.16,
+		"JPY": 189.0,
+	},
+	"JPY": {
+		"USD": 0.0067,
+		"RMB": 0.048,
+		"CNY": 0.048,
+		"EUR": 0.0062,
+		"GBP": 0.0053,
+	},
+	"HKD": {
+		"USD": 0.128,
+		"RMB": 0.92,
+		"CNY": 0.92,
+	},
+	"CAD": {
+		"USD": 0.735,
+		"RMB": 5.29,
+		"CNY": 5.29,
+	},
 }
 
-// GetExchangeRate fetches the exchange rate from one currency to another
+// GetExchangeRate fetches the exchange rate from one currency to another.
+// Live providers are tried in order; when none answers, the last cached rate
+// is reused, and failing that the built-in fallback rates.
 func (s *CurrencyService) GetExchangeRate(from, to string) (float64, error) {
 	// Validate currency codes
 	if from == "" || to == "" {
 		return 0, ErrInvalidCurrencyCode
 	}
 	
-	// Normalize CNY to RMB
-	if from == "CNY" {
-		from = "RMB"
-	}
-	if to == "CNY" {
-		to = "RMB"
-	}
+	// Accept CNY for RMB
+	from = currencycode.Normalize(from)
+	to = currencycode.Normalize(to)
 	
 	// If same currency, return 1
 	if from == to {
@@ -180,90 +244,172 @@ func (s *CurrencyService) GetExchangeRate(from, to string) (float64, error) {
 		return rate, nil
 	}
 	
-	// If API key is not configured, use fallback rates
-	if s.apiKey == "" {
-		rate := s.getFallbackRate(from, to)
-		if rate > 0 {
-			log.Printf("WARNING: ExchangeRate-API key not configured, using fallback rate for %s -> %s: %.4f", from, to, rate)
-			// Cache the fallback rate
-			s.setCachedRate(cacheKey, rate)
-			return rate, nil
-		}
-		// Try to use last cached rate
-		if rate, found := s.getLastCachedRate(cacheKey); found {
-			log.Printf("WARNING: ExchangeRate-API key not configured, using stale cached rate for %s", cacheKey)
-			return rate, nil
-		}
-		return 0, fmt.Errorf("%w: API key not configured and no fallback rate available", ErrCurrencyAPIError)
+	// Fetch the base currency's whole rate table, so later lookups from the
+	// same currency are served from cache
+	rates, err := s.fetchRates(from, to)
+	if err == nil {
+		s.setCachedRates(from, rates, false)
+		return rates[to], nil
 	}
 	
-	// Fetch from ExchangeRate-API
-	url := fmt.Sprintf("https://v6.exchangerate-api.com/v6/%s/latest/%s", s.apiKey, from)
+	// Prefer a stale live rate over the approximate fallback rates
+	if rate, found := s.getLastCachedRate(cacheKey); found {
+		log.Printf("WARNING: Exchange rate providers failed, using stale cached rate for %s: %v", cacheKey, err)
+		return rate, nil
+	}
 	
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create request: %w", err)
+	if fallback, fallbackErr := fallbackRates.FetchRates(context.Background(), from); fallbackErr == nil && fallback[to] > 0 {
+		log.Printf("WARNING: Exchange rate providers failed, using fallback rate for %s -> %s: %.4f (%v)", from, to, fallback[to], err)
+		s.setCachedRates(from, fallback, true)
+		return fallback[to], nil
 	}
 	
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		// If API call fails, try to use last cached rate
-		if rate, found := s.getLastCachedRate(cacheKey); found {
-			log.Printf("WARNING: ExchangeRate-API request failed, using stale cached rate for %s: %v", cacheKey, err)
-			return rate, nil
+	return 0, err
+}
+
+// fetchRates asks each provider in turn for the rates from base, returning
+// the first table that quotes the want currency, or any table when want is
+// empty. The error reports why the last provider failed.
+func (s *CurrencyService) fetchRates(base, want string) (map[string]float64, error) {
+	err := fmt.Errorf("%w: no exchange rate provider configured", ErrExchangeRateNotFound)
+	for _, provider := range s.providers {
+		rates, fetchErr := provider.FetchRates(context.Background(), base)
+		if fetchErr == nil && want != "" && rates[want] <= 0 {
+			fetchErr = fmt.Errorf("%w: %s does not quote %s", ErrExchangeRateNotFound, provider.Name(), want)
+		}
+		if fetchErr == nil {
+			return rates, nil
 		}
-		return 0, fmt.Errorf("%w: %v", ErrCurrencyAPIError, err)
+		
+		log.Printf("WARNING: %s rates for %s unavailable, trying next provider: %v", provider.Name(), base, fetchErr)
+		err = fetchErr
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		// If API call fails, try to use last cached rate
-		if rate, found := s.getLastCachedRate(cacheKey); found {
-			log.Printf("WARNING: ExchangeRate-API returned status %d, using stale cached rate for %s", resp.StatusCode, cacheKey)
-			return rate, nil
+	return nil, err
+}
+
+// setCachedRates stores every rate from a base currency in cache
+func (s *CurrencyService) setCachedRates(base string, rates map[string]float64, fallback bool) {
+	for currency, rate := range rates {
+		if currency != base && rate > 0 {
+			s.setCachedRate(fmt.Sprintf("%s_%s", base, currency), rate, fallback)
 		}
-		return 0, fmt.Errorf("%w: status code %d", ErrCurrencyAPIError, resp.StatusCode)
 	}
+}
+
+// getCachedRates returns the unexpired cached rates from a base currency
+func (s *CurrencyService) getCachedRates(base string) map[string]float64 {
+	s.cacheMutex.RLock()
+	defer s.cacheMutex.RUnlock()
 	
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		// If reading fails, try to use last cached rate
-		if rate, found := s.getLastCachedRate(cacheKey); found {
-			log.Printf("WARNING: Failed to read ExchangeRate-API response, using stale cached rate for %s: %v", cacheKey, err)
-			return rate, nil
+	now := time.Now()
+	prefix := base + "_"
+	rates := make(map[string]float64)
+	for key, cached := range s.rateCache {
+		if strings.HasPrefix(key, prefix) && !now.After(cached.ExpiresAt) {
+			rates[strings.TrimPrefix(key, prefix)] = cached.Rate
 		}
-		return 0, fmt.Errorf("failed to read response: %w", err)
+	}
+	return rates
+}
+
+// GetRateTable returns the exchange rates from base to every currency its
+// provider quotes, falling back to the built-in rates when no provider
+// answers. The table includes base itself at 1.
+func (s *CurrencyService) GetRateTable(base string) (map[string]float64, error) {
+	base = currencycode.Normalize(base)
+	if base == "" {
+		return nil, ErrInvalidCurrencyCode
 	}
 	
-	var apiResp exchangeRateAPIResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		// If parsing fails, try to use last cached rate
-		if rate, found := s.getLastCachedRate(cacheKey); found {
-			log.Printf("WARNING: Failed to parse ExchangeRate-API response, using stale cached rate for %s: %v", cacheKey, err)
-			return rate, nil
+	// Rates are cached a whole table at a time
+	rates := s.getCachedRates(base)
+	if len(rates) == 0 {
+		fetched, err := s.fetchRates(base, "")
+		fallback := err != nil
+		if err != nil {
+			fallback, fallbackErr := fallbackRates.FetchRates(context.Background(), base)
+			if fallbackErr != nil {
+				if errors.Is(err, ErrCurrencyAPIError) {
+					return nil, err
+				}
+				return nil, fallbackErr
+			}
+			log.Printf("WARNING: Exchange rate providers failed, using fallback rates for %s: %v", base, err)
+			fetched = fallback
+		}
+		s.setCachedRates(base, fetched, fallback)
+		rates = make(map[string]float64, len(fetched)+1)
+		for currency, rate := range fetched {
+			rates[currency] = rate
 		}
-		return 0, fmt.Errorf("failed to parse response: %w", err)
 	}
 	
-	if apiResp.Result != "success" {
-		// If API returns error, try to use last cached rate
-		if rate, found := s.getLastCachedRate(cacheKey); found {
-			log.Printf("WARNING: ExchangeRate-API returned error result, using stale cached rate for %s", cacheKey)
-			return rate, nil
+	rates[base] = 1
+	return rates, nil
+}
+
+// GetRateFreshness reports the freshness of the cached rates used to convert
+// each of the from currencies to to. It returns nil when no conversion was
+// needed or none of the rates has been looked up.
+func (s *CurrencyService) GetRateFreshness(to string, from ...string) *RateFreshness {
+	s.cacheMutex.RLock()
+	defer s.cacheMutex.RUnlock()
+	
+	now := time.Now()
+	var freshness *RateFreshness
+	for _, currency := range from {
+		cached, exists := s.rateCache[fmt.Sprintf("%s_%s", currencycode.Normalize(currency), currencycode.Normalize(to))]
+		if !exists {
+			continue
+		}
+		if freshness == nil {
+			freshness = &RateFreshness{}
+		}
+		if cached.Fallback || now.After(cached.ExpiresAt) {
+			freshness.Stale = true
+		}
+		if !cached.Fallback && (freshness.LastUpdated == nil || cached.UpdatedAt.Before(*freshness.LastUpdated)) {
+			updatedAt := cached.UpdatedAt
+			freshness.LastUpdated = &updatedAt
 		}
-		return 0, fmt.Errorf("%w: API returned error result", ErrCurrencyAPIError)
 	}
-	
-	// Get the conversion rate for the target currency
-	rate, exists := apiResp.ConversionRates[to]
-	if !exists {
-		return 0, ErrExchangeRateNotFound
+	return freshness
+}
+
+// CurrencyConversion is one amount converted between two currencies
+type CurrencyConversion struct {
+	Amount    float64 `json:"amount"`
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Rate      float64 `json:"rate"`
+	Converted float64 `json:"converted"`
+}
+
+// ConvertBatch converts each amount between its pair of currencies, looking
+// up every distinct pair once. It fails on the first pair without a rate.
+func (s *CurrencyService) ConvertBatch(conversions []CurrencyConversion) ([]CurrencyConversion, error) {
+	rates := make(map[string]float64)
+	results := make([]CurrencyConversion, len(conversions))
+	for i, conversion := range conversions {
+		conversion.From = currencycode.Normalize(conversion.From)
+		conversion.To = currencycode.Normalize(conversion.To)
+		
+		pair := conversion.From + "_" + conversion.To
+		rate, ok := rates[pair]
+		if !ok {
+			var err error
+			rate, err = s.GetExchangeRate(conversion.From, conversion.To)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert %s to %s: %w", conversion.From, conversion.To, err)
+			}
+			rates[pair] = rate
+		}
+		
+		conversion.Rate = rate
+		conversion.Converted = conversion.Amount * rate
+		results[i] = conversion
 	}
-	
-	// Cache the result
-	s.setCachedRate(cacheKey, rate)
-	
-	return rate, nil
+	return results, nil
 }
 
 // ConvertAmount converts an amount from one currency to another
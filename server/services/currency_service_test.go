@@ -1,13 +1,14 @@
 package services
 
 import (
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestCurrencyServiceFallbackRates(t *testing.T) {
-	// Create service without API key
+	// Create service without live providers
 	service := &CurrencyService{
-		apiKey:    "",
 		rateCache: make(map[string]*CachedExchangeRate),
 	}
 
@@ -69,7 +70,6 @@ func TestCurrencyServiceFallbackRates(t *testing.T) {
 
 func TestCurrencyServiceConvertAmount(t *testing.T) {
 	service := &CurrencyService{
-		apiKey:    "",
 		rateCache: make(map[string]*CachedExchangeRate),
 	}
 
@@ -120,3 +120,112 @@ func TestCurrencyServiceConvertAmount(t *testing.T) {
 		})
 	}
 }
+
+func TestCurrencyServiceRateTable(t *testing.T) {
+	service := &CurrencyService{
+		rateCache: make(map[string]*CachedExchangeRate),
+	}
+
+	rates, err := service.GetRateTable("cny")
+	if err != nil {
+		t.Fatalf("GetRateTable() error = %v", err)
+	}
+	if rates["RMB"] != 1 || rates["USD"] != 0.139 || rates["EUR"] != 0.128 {
+		t.Errorf("Unexpected RMB rate table %v", rates)
+	}
+	if _, ok := rates["CNY"]; ok {
+		t.Errorf("Expected CNY to be reported as RMB")
+	}
+
+	if _, err := service.GetRateTable("XYZ"); !errors.Is(err, ErrExchangeRateNotFound) {
+		t.Errorf("Expected ErrExchangeRateNotFound for an unknown base, got %v", err)
+	}
+}
+
+func TestCurrencyServiceConvertBatch(t *testing.T) {
+	service := &CurrencyService{
+		rateCache: make(map[string]*CachedExchangeRate),
+	}
+
+	results, err := service.ConvertBatch([]CurrencyConversion{
+		{Amount: 100, From: "usd", To: "CNY"},
+		{Amount: 10, From: "EUR", To: "USD"},
+		{Amount: 5, From: "USD", To: "USD"},
+	})
+	if err != nil {
+		t.Fatalf("ConvertBatch() error = %v", err)
+	}
+	if len(results) != 3 || results[0].From != "USD" || results[0].To != "RMB" || results[0].Converted != 720 || results[1].Converted != 10.9 || results[2].Converted != 5 {
+		t.Errorf("Unexpected conversions %+v", results)
+	}
+
+	if _, err := service.ConvertBatch([]CurrencyConversion{{Amount: 1, From: "USD", To: "XYZ"}}); err == nil {
+		t.Errorf("Expected an error for a pair without a rate")
+	}
+}
+
+func TestCurrencyServiceRateFreshness(t *testing.T) {
+	live := &stubFXProvider{name: "live", rates: map[string]map[string]float64{"HKD": {"USD": 0.128}}}
+	service := NewCurrencyService(CurrencyConfig{Providers: []FXProvider{live}})
+
+	if freshness := service.GetRateFreshness("USD", "USD"); freshness != nil {
+		t.Errorf("Expected no freshness without a conversion, got %+v", freshness)
+	}
+
+	before := time.Now()
+	if _, err := service.GetExchangeRate("HKD", "USD"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	freshness := service.GetRateFreshness("USD", "USD", "HKD")
+	if freshness == nil || freshness.Stale || freshness.LastUpdated == nil || freshness.LastUpdated.Before(before) {
+		t.Fatalf("Expected a fresh live rate, got %+v", freshness)
+	}
+
+	// A rate past its cache lifetime is stale but keeps its update time
+	service.rateCache["HKD_USD"].ExpiresAt = time.Now().Add(-time.Minute)
+	if freshness := service.GetRateFreshness("USD", "HKD"); freshness == nil || !freshness.Stale || freshness.LastUpdated == nil {
+		t.Errorf("Expected an expired rate to be stale, got %+v", freshness)
+	}
+
+	// Fallback rates are stale and have no update time of their own
+	live.err = ErrCurrencyAPIError
+	if _, err := service.GetExchangeRate("CNY", "USD"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if freshness := service.GetRateFreshness("USD", "RMB"); freshness == nil || !freshness.Stale || freshness.LastUpdated != nil {
+		t.Errorf("Expected a stale fallback rate, got %+v", freshness)
+	}
+}
+
+func TestNormalizeDisplayCurrency(t *testing.T) {
+	for input, want := range map[string]string{"usd": "USD", "CNY": "RMB", " eur ": "EUR", "JPY": "JPY", "hkd": "HKD"} {
+		if currency, err := NormalizeDisplayCurrency(input); err != nil || currency != want {
+			t.Errorf("Expected %q to normalize to %s, got %q, %v", input, want, currency, err)
+		}
+	}
+	for _, input := range []string{"", "XYZ", "CAD"} {
+		if _, err := NormalizeDisplayCurrency(input); !errors.Is(err, ErrInvalidCurrencyCode) {
+			t.Errorf("Expected %q to be rejected, got %v", input, err)
+		}
+	}
+}
+
+func TestDisplayCurrencyFormats(t *testing.T) {
+	formats := DisplayCurrencyFormats()
+	if len(formats) != len(DisplayCurrencies) {
+		t.Fatalf("Expected a format for each of %v, got %d", DisplayCurrencies, len(formats))
+	}
+	for i, format := range formats {
+		if format.Code != DisplayCurrencies[i] || format.Symbol == "" || format.Locale == "" {
+			t.Errorf("Unexpected format %+v for %s", format, DisplayCurrencies[i])
+		}
+		for _, alias := range format.Aliases {
+			if code, err := NormalizeDisplayCurrency(alias); err != nil || code != format.Code {
+				t.Errorf("Expected alias %s to normalize to %s, got %q, %v", alias, format.Code, code, err)
+			}
+		}
+	}
+	if formats[1].ISOCode != "CNY" || formats[len(formats)-1].Decimals != 0 {
+		t.Errorf("Expected RMB formatted as CNY and JPY without decimals, got %+v", formats)
+	}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// userDataVersions tracks a per-user counter that is incremented whenever the
+// user's transactions, portfolios, or asset styles change. Caches of derived
+// data record the version they were computed at and treat any newer version
+// as an invalidation.
+type userDataVersions struct {
+	mu       sync.RWMutex
+	versions map[primitive.ObjectID]uint64
+}
+
+// dataVersions is shared by every service that mutates or caches user data
+var dataVersions = &userDataVersions{
+	versions: make(map[primitive.ObjectID]uint64),
+}
+
+// bump records a mutation of the user's data
+func (v *userDataVersions) bump(userID primitive.ObjectID) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.versions[userID]++
+}
+
+// current returns the user's data version
+func (v *userDataVersions) current(userID primitive.ObjectID) uint64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.versions[userID]
+}
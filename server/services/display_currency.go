@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"stock-portfolio-tracker/currencycode"
+	"strings"
+)
+
+// CurrencyFormat describes how values in a display currency are rendered
+type CurrencyFormat struct {
+	Code string `json:"code"`
+	// ISOCode is the ISO 4217 code formatting libraries expect, CNY for RMB
+	ISOCode  string `json:"isoCode"`
+	Name     string `json:"name"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+	// Locale is the BCP 47 locale whose grouping and symbol placement the
+	// currency is conventionally written with
+	Locale string `json:"locale"`
+	// Aliases are other codes accepted for the currency
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// displayCurrencyFormats are the display currencies and their formatting, in
+// the order they are offered
+var displayCurrencyFormats = []CurrencyFormat{
+	{Code: "USD", ISOCode: "USD", Name: "US Dollar", Symbol: "$", Decimals: 2, Locale: "en-US"},
+	{Code: currencycode.RMB, ISOCode: currencycode.CNY, Name: "Chinese Yuan", Symbol: "¥", Decimals: 2, Locale: "zh-CN", Aliases: []string{currencycode.CNY}},
+	{Code: "EUR", ISOCode: "EUR", Name: "Euro", Symbol: "€", Decimals: 2, Locale: "de-DE"},
+	{Code: "GBP", ISOCode: "GBP", Name: "British Pound", Symbol: "£", Decimals: 2, Locale: "en-GB"},
+	{Code: "HKD", ISOCode: "HKD", Name: "Hong Kong Dollar", Symbol: "HK$", Decimals: 2, Locale: "zh-HK"},
+	{Code: "JPY", ISOCode: "JPY", Name: "Japanese Yen", Symbol: "¥", Decimals: 0, Locale: "ja-JP"},
+}
+
+// DisplayCurrencies are the currencies portfolio values can be reported in.
+// The Chinese yuan is RMB; CNY is accepted as an alias.
+var DisplayCurrencies = displayCurrencyCodes()
+
+// displayCurrencyCodes lists the codes of the display currencies
+func displayCurrencyCodes() []string {
+	codes := make([]string, len(displayCurrencyFormats))
+	for i, format := range displayCurrencyFormats {
+		codes[i] = format.Code
+	}
+	return codes
+}
+
+// DisplayCurrencyFormats returns the display currencies with their formatting
+func DisplayCurrencyFormats() []CurrencyFormat {
+	formats := make([]CurrencyFormat, len(displayCurrencyFormats))
+	copy(formats, displayCurrencyFormats)
+	return formats
+}
+
+// NormalizeDisplayCurrency validates a requested display currency and returns
+// its canonical code, upper-cased with CNY reported as RMB
+func NormalizeDisplayCurrency(currency string) (string, error) {
+	code := currencycode.Normalize(currency)
+	for _, supported := range DisplayCurrencies {
+		if code == supported {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q, must be one of %s", ErrInvalidCurrencyCode, currency, strings.Join(DisplayCurrencies, ", "))
+}
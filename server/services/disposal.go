@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DisposePosition sells every remaining share of a position at a manual
+// price, closing out holdings the providers no longer quote such as delisted
+// symbols. Unlike a regular sell, the price may be zero, for shares that
+// became worthless.
+func (s *PortfolioService) DisposePosition(userID primitive.ObjectID, symbol string, req models.DisposalRequest) (*models.Transaction, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if req.Date.After(time.Now()) {
+		return nil, ErrFutureDate
+	}
+	if req.Price < 0 || req.Fees < 0 {
+		return nil, fmt.Errorf("%w: price and fees cannot be negative", ErrInvalidTransaction)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transactions, err := s.repos.Transactions.FindBySymbol(ctx, userID, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+	if len(transactions) == 0 {
+		return nil, ErrPositionNotFound
+	}
+
+	shares := 0.0
+	for _, tx := range transactions {
+		switch tx.Action {
+		case "buy":
+			shares += tx.Shares
+		case "sell":
+			shares -= tx.Shares
+		}
+	}
+	if shares <= 1e-9 {
+		return nil, ErrPositionClosed
+	}
+
+	note := strings.TrimSpace(req.Note)
+	if note == "" {
+		note = "Final disposal"
+	}
+	tx := &models.Transaction{
+		Symbol:         symbol,
+		Action:         "sell",
+		Shares:         shares,
+		Price:          req.Price,
+		Currency:       transactions[0].Currency,
+		Fees:           req.Fees,
+		Date:           req.Date,
+		Note:           note,
+		InstrumentType: transactions[0].InstrumentType,
+		Option:         transactions[0].Option,
+	}
+	if tx.Option != nil {
+		tx.Contracts = shares / tx.Option.Multiplier
+	}
+
+	portfolioID, err := s.getOrCreatePortfolio(userID, symbol, tx.Option)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get or create portfolio: %w", err)
+	}
+	now := time.Now()
+	tx.ID = primitive.NewObjectID()
+	tx.PortfolioID = portfolioID
+	tx.UserID = userID
+	tx.CreatedAt = now
+	tx.UpdatedAt = now
+
+	if err := s.repos.Transactions.Insert(ctx, tx); err != nil {
+		return nil, fmt.Errorf("failed to insert transaction: %w", err)
+	}
+
+	dataVersions.bump(userID)
+	return tx, nil
+}
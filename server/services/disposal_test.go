@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDisposePosition(t *testing.T) {
+	provider := NewFixtureProvider().SetQuote("AAPL", "Apple", 150, "USD")
+	service := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	userID := primitive.NewObjectID()
+	day := time.Now().AddDate(0, 0, -10)
+
+	for _, tx := range []*models.Transaction{
+		{Symbol: "LEHMQ", Action: "buy", Shares: 10, Price: 40, Currency: "USD", Date: day},
+		{Symbol: "LEHMQ", Action: "sell", Shares: 4, Price: 20, Currency: "USD", Date: day.AddDate(0, 0, 1)},
+	} {
+		if err := service.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+
+	// Worthless shares are disposed of at zero
+	tx, err := service.DisposePosition(userID, "lehmq", models.DisposalRequest{Date: day.AddDate(0, 0, 2)})
+	if err != nil {
+		t.Fatalf("DisposePosition failed: %v", err)
+	}
+	if tx.Symbol != "LEHMQ" || tx.Action != "sell" || tx.Shares != 6 || tx.Price != 0 || tx.Currency != "USD" || tx.Note != "Final disposal" {
+		t.Errorf("Unexpected disposal %+v", tx)
+	}
+
+	if _, err := service.DisposePosition(userID, "LEHMQ", models.DisposalRequest{Date: day.AddDate(0, 0, 3)}); !errors.Is(err, ErrPositionClosed) {
+		t.Errorf("Expected ErrPositionClosed once disposed of, got %v", err)
+	}
+	if _, err := service.DisposePosition(userID, "AAPL", models.DisposalRequest{Date: day}); !errors.Is(err, ErrPositionNotFound) {
+		t.Errorf("Expected ErrPositionNotFound without transactions, got %v", err)
+	}
+	if _, err := service.DisposePosition(userID, "LEHMQ", models.DisposalRequest{Date: time.Now().Add(time.Hour)}); !errors.Is(err, ErrFutureDate) {
+		t.Errorf("Expected ErrFutureDate, got %v", err)
+	}
+}
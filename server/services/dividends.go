@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"time"
+)
+
+// dividendCacheDuration is how long a symbol's dividend schedule is reused.
+// Schedules change rarely, so they are kept much longer than quotes.
+const dividendCacheDuration = 12 * time.Hour
+
+// dividendHistoryYears is how far back dividend schedules are fetched
+const dividendHistoryYears = 2
+
+// DividendEvent represents a cash dividend per share. ExDate is the first
+// day the shares trade without the dividend; PayDate is nil when the provider
+// does not report it.
+type DividendEvent struct {
+	Symbol   string     `json:"symbol"`
+	ExDate   time.Time  `json:"exDate"`
+	PayDate  *time.Time `json:"payDate,omitempty"`
+	Amount   float64    `json:"amount"`
+	Currency string     `json:"currency"`
+}
+
+// GetDividendsContext returns the symbol's dividends over the past two years,
+// oldest first. Yahoo Finance reports ex-dates only, so PayDate is not set.
+// Cash and option holdings pay no dividends.
+func (s *StockAPIService) GetDividendsContext(ctx context.Context, symbol string) ([]DividendEvent, error) {
+	symbol = s.catalog.Resolve(symbol)
+	if symbol == "" {
+		return nil, ErrInvalidSymbol
+	}
+	if s.IsCashSymbol(symbol) {
+		return nil, nil
+	}
+	if _, ok := models.ParseOptionSymbol(symbol); ok {
+		return nil, nil
+	}
+
+	if cached, found := s.getCachedDividends(symbol); found {
+		return cached, nil
+	}
+
+	endTime := time.Now()
+	startTime := endTime.AddDate(-dividendHistoryYears, 0, 0)
+	response, err := s.fetchFromYahooChart(ctx, symbol, startTime.Unix(), endTime.Unix())
+	if err != nil {
+		fmt.Printf("[StockAPI] ERROR: Failed to fetch dividends for %s: %v\n", symbol, err)
+		return nil, err
+	}
+
+	dividends := s.extractDividends(symbol, response)
+	s.setCachedDividends(symbol, dividends)
+	return dividends, nil
+}
+
+// extractDividends extracts the dividend events from a Yahoo Chart API
+// response, sorted by ex-date
+func (s *StockAPIService) extractDividends(symbol string, response *yahooChartResponse) []DividendEvent {
+	if len(response.Chart.Result) == 0 {
+		return nil
+	}
+	result := response.Chart.Result[0]
+
+	// Amounts quoted in minor units such as pence are converted to the major unit
+	scale := 1.0
+	currency := strings.ToUpper(result.Meta.Currency)
+	if major, ok := minorCurrencyUnits[result.Meta.Currency]; ok {
+		currency = major
+		scale = 100
+	}
+	if currency == "" {
+		currency = s.SymbolCurrency(symbol)
+	}
+
+	dividends := make([]DividendEvent, 0, len(result.Events.Dividends))
+	for _, dividend := range result.Events.Dividends {
+		if dividend.Amount <= 0 {
+			continue
+		}
+		dividends = append(dividends, DividendEvent{
+			Symbol:   symbol,
+			ExDate:   time.Unix(dividend.Date, 0).UTC(),
+			Amount:   dividend.Amount / scale,
+			Currency: currency,
+		})
+	}
+	sort.Slice(dividends, func(i, j int) bool {
+		return dividends[i].ExDate.Before(dividends[j].ExDate)
+	})
+
+	return dividends
+}
+
+// getCachedDividends retrieves a dividend schedule from cache if available and not expired
+func (s *StockAPIService) getCachedDividends(symbol string) ([]DividendEvent, bool) {
+	return s.dividendCache.Get(symbol, time.Now())
+}
+
+// setCachedDividends stores a dividend schedule in cache with expiration
+func (s *StockAPIService) setCachedDividends(symbol string, dividends []DividendEvent) {
+	s.dividendCache.Set(symbol, dividends, time.Now().Add(dividendCacheDuration))
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"math"
+)
+
+// MinDownsamplePoints is the smallest target size the LTTB algorithm supports
+// (the first and last points are always kept plus at least one bucket)
+const MinDownsamplePoints = 3
+
+// ErrInvalidDownsamplePoints is returned when a downsampling target is too small
+var ErrInvalidDownsamplePoints = errors.New("points must be at least 3")
+
+// downsample reduces points to at most maxPoints with the
+// Largest-Triangle-Three-Buckets algorithm, plotting each point at x and y.
+// The first and last points are always kept; every bucket in between
+// contributes the point forming the largest triangle with the previously
+// selected point and the next bucket's average. Series no longer than
+// maxPoints are returned unchanged.
+func downsample[T any](points []T, maxPoints int, x, y func(T) float64) ([]T, error) {
+	if maxPoints < MinDownsamplePoints {
+		return nil, ErrInvalidDownsamplePoints
+	}
+	n := len(points)
+	if maxPoints >= n {
+		return points, nil
+	}
+
+	sampled := make([]T, 0, maxPoints)
+	sampled = append(sampled, points[0])
+
+	// Bucket size for everything between the first and last point
+	every := float64(n-2) / float64(maxPoints-2)
+	selected := points[0]
+
+	for bucket := 0; bucket < maxPoints-2; bucket++ {
+		// Average of the next bucket is the third vertex of the triangle
+		nextStart := int(math.Floor(float64(bucket+1)*every)) + 1
+		nextEnd := int(math.Floor(float64(bucket+2)*every)) + 1
+		if nextEnd > n {
+			nextEnd = n
+		}
+		avgX, avgY := 0.0, 0.0
+		for _, point := range points[nextStart:nextEnd] {
+			avgX += x(point)
+			avgY += y(point)
+		}
+		if count := nextEnd - nextStart; count > 0 {
+			avgX /= float64(count)
+			avgY /= float64(count)
+		}
+
+		// Pick the point in the current bucket with the largest triangle area
+		start := int(math.Floor(float64(bucket)*every)) + 1
+		end := int(math.Floor(float64(bucket+1)*every)) + 1
+		ax, ay := x(selected), y(selected)
+		maxArea := -1.0
+		maxPoint := points[start]
+		for _, point := range points[start:end] {
+			area := math.Abs((ax-avgX)*(y(point)-ay) - (ax-x(point))*(avgY-ay))
+			if area > maxArea {
+				maxArea = area
+				maxPoint = point
+			}
+		}
+
+		sampled = append(sampled, maxPoint)
+		selected = maxPoint
+	}
+
+	return append(sampled, points[n-1]), nil
+}
+
+// DownsamplePerformance reduces a performance series to at most maxPoints points
+// using LTTB on the portfolio value. Day-over-day fields keep their original
+// daily values; percentage returns are relative to the start and stay valid.
+func DownsamplePerformance(points []PerformanceDataPoint, maxPoints int) ([]PerformanceDataPoint, error) {
+	return downsample(points, maxPoints, func(p PerformanceDataPoint) float64 { return float64(p.Date.Unix()) }, func(p PerformanceDataPoint) float64 { return p.Value })
+}
+
+// DownsampleBacktest reduces a backtest series to at most maxPoints points
+// using LTTB on the portfolio value
+func DownsampleBacktest(points []BacktestDataPoint, maxPoints int) ([]BacktestDataPoint, error) {
+	return downsample(points, maxPoints, func(p BacktestDataPoint) float64 { return float64(p.Date.Unix()) }, func(p BacktestDataPoint) float64 { return p.PortfolioValue })
+}
+
+// DownsampleNetWorth reduces a net worth series to at most maxPoints points
+// using LTTB on the total net worth
+func DownsampleNetWorth(points []NetWorthDataPoint, maxPoints int) ([]NetWorthDataPoint, error) {
+	return downsample(points, maxPoints, func(p NetWorthDataPoint) float64 { return float64(p.Date.Unix()) }, func(p NetWorthDataPoint) float64 { return p.Value })
+}
+
+// DownsampleRollingReturns reduces a rolling return series to at most
+// maxPoints points using LTTB on the return
+func DownsampleRollingReturns(points []RollingReturnPoint, maxPoints int) ([]RollingReturnPoint, error) {
+	return downsample(points, maxPoints, func(p RollingReturnPoint) float64 { return float64(p.Date.Unix()) }, func(p RollingReturnPoint) float64 { return p.Return })
+}
+
+// DownsampleDrawdowns reduces a drawdown curve to at most maxPoints points
+// using LTTB on the drawdown
+func DownsampleDrawdowns(points []DrawdownPoint, maxPoints int) ([]DrawdownPoint, error) {
+	return downsample(points, maxPoints, func(p DrawdownPoint) float64 { return float64(p.Date.Unix()) }, func(p DrawdownPoint) float64 { return p.Drawdown })
+}
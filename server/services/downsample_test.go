@@ -0,0 +1,64 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDownsamplePerformance(t *testing.T) {
+	start := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := make([]PerformanceDataPoint, 2500)
+	for i := range points {
+		points[i] = PerformanceDataPoint{
+			Date:  start.AddDate(0, 0, i),
+			Value: 1000 + 100*math.Sin(float64(i)/50),
+		}
+	}
+	// Inject a spike that LTTB must preserve
+	points[1234].Value = 5000
+
+	sampled, err := DownsamplePerformance(points, 500)
+	if err != nil {
+		t.Fatalf("DownsamplePerformance failed: %v", err)
+	}
+
+	if len(sampled) != 500 {
+		t.Fatalf("Expected 500 points, got %d", len(sampled))
+	}
+	if !sampled[0].Date.Equal(points[0].Date) || !sampled[len(sampled)-1].Date.Equal(points[len(points)-1].Date) {
+		t.Error("Expected first and last points to be kept")
+	}
+
+	foundSpike := false
+	for i, point := range sampled {
+		if i > 0 && !point.Date.After(sampled[i-1].Date) {
+			t.Fatalf("Expected strictly increasing dates at index %d", i)
+		}
+		if point.Value == 5000 {
+			foundSpike = true
+		}
+	}
+	if !foundSpike {
+		t.Error("Expected the spike to survive downsampling")
+	}
+}
+
+func TestDownsampleShortSeriesUnchanged(t *testing.T) {
+	points := []BacktestDataPoint{
+		{Date: time.Now().AddDate(0, 0, -2), PortfolioValue: 1},
+		{Date: time.Now().AddDate(0, 0, -1), PortfolioValue: 2},
+	}
+
+	sampled, err := DownsampleBacktest(points, 500)
+	if err != nil {
+		t.Fatalf("DownsampleBacktest failed: %v", err)
+	}
+	if len(sampled) != len(points) {
+		t.Errorf("Expected %d points, got %d", len(points), len(sampled))
+	}
+
+	if _, err := DownsampleBacktest(points, 2); err != ErrInvalidDownsamplePoints {
+		t.Errorf("Expected ErrInvalidDownsamplePoints, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+)
+
+// DriftAlertJobInterval is how often allocations are checked against their targets
+const DriftAlertJobInterval = time.Hour
+
+// TargetDrift represents how far a group's weight is from its target
+type TargetDrift struct {
+	Group         string  `json:"group"`
+	CurrentWeight float64 `json:"currentWeight"` // Percentage
+	TargetWeight  float64 `json:"targetWeight"`  // Percentage
+	Drift         float64 `json:"drift"`         // Percentage points, positive when overweight
+}
+
+// DriftAlertService notifies users whose allocation has drifted from their
+// target weights by more than their threshold
+type DriftAlertService struct {
+	repos               repository.Repositories
+	analyticsService    *AnalyticsService
+	notificationService *NotificationService
+}
+
+// NewDriftAlertService creates a new DriftAlertService instance
+func NewDriftAlertService(analyticsService *AnalyticsService, notificationService *NotificationService) *DriftAlertService {
+	return &DriftAlertService{
+		repos:               analyticsService.portfolioService.repos,
+		analyticsService:    analyticsService,
+		notificationService: notificationService,
+	}
+}
+
+// targetDrift compares group weights with target weights and returns the
+// groups out of band, largest drift first. Groups without a target are held
+// against a target of 0, and targeted groups the portfolio lacks against a
+// weight of 0.
+func targetDrift(groups []GroupedHolding, targets map[string]float64, threshold float64) []TargetDrift {
+	weights := make(map[string]float64, len(groups))
+	for _, group := range groups {
+		weights[group.GroupName] += group.Percentage
+	}
+	names := make(map[string]bool, len(weights)+len(targets))
+	for name := range weights {
+		names[name] = true
+	}
+	for name := range targets {
+		names[name] = true
+	}
+
+	drifts := []TargetDrift{}
+	for name := range names {
+		drift := weights[name] - targets[name]
+		if math.Abs(drift) <= threshold {
+			continue
+		}
+		drifts = append(drifts, TargetDrift{
+			Group:         name,
+			CurrentWeight: weights[name],
+			TargetWeight:  targets[name],
+			Drift:         drift,
+		})
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		if math.Abs(drifts[i].Drift) != math.Abs(drifts[j].Drift) {
+			return math.Abs(drifts[i].Drift) > math.Abs(drifts[j].Drift)
+		}
+		return drifts[i].Group < drifts[j].Group
+	})
+	return drifts
+}
+
+// breachedGroups returns the sorted names of drifted groups
+func breachedGroups(drifts []TargetDrift) []string {
+	groups := make([]string, len(drifts))
+	for i, drift := range drifts {
+		groups[i] = drift.Group
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// sameGroups reports whether two sorted group lists are equal
+func sameGroups(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckDrift checks every user with drift alerts enabled and notifies those
+// whose set of out-of-band groups changed since the last alert, so a
+// persistent drift is reported once rather than every hour. The new set is
+// claimed with a conditional update in the transaction that queues the
+// alert, so several server instances running this job never send the same
+// alert twice.
+func (s *DriftAlertService) CheckDrift() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	subscribers, err := s.repos.Settings.FindDriftAlertSubscribers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch drift alert subscribers: %w", err)
+	}
+
+	queued := 0
+	var errs []error
+	for _, settings := range subscribers {
+		alerts := settings.DriftAlerts
+		if len(alerts.Targets) == 0 {
+			continue
+		}
+
+		metrics, err := s.analyticsService.GetGroupedDashboardMetrics(context.Background(), settings.UserID, alerts.Currency, alerts.GroupBy)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("drift for user %s: %w", settings.UserID.Hex(), err))
+			continue
+		}
+		if metrics.TotalValue <= 0 {
+			continue
+		}
+
+		drifts := targetDrift(metrics.Groups, alerts.Targets, alerts.Threshold)
+		breached := breachedGroups(drifts)
+		if sameGroups(breached, alerts.Breached) {
+			continue
+		}
+
+		claim := func(ctx context.Context) error {
+			var alertedAt *time.Time
+			if len(drifts) > 0 {
+				now := time.Now()
+				alertedAt = &now
+			}
+			err := s.repos.Settings.ClaimDriftAlert(ctx, settings.UserID, settings.ID, alerts.Breached, breached, alertedAt)
+			if errors.Is(err, repository.ErrNotFound) {
+				return errClaimLost
+			}
+			if err != nil {
+				return fmt.Errorf("failed to update drift alert status: %w", err)
+			}
+			return nil
+		}
+		updateCtx, updateCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if len(drifts) == 0 {
+			// Back within band: nothing to report, but the next drift alerts again
+			err = claim(updateCtx)
+		} else {
+			err = s.notificationService.NotifyWith(updateCtx, "drift-alerts", settings.UserID, renderDriftNotification(alerts, drifts), claim)
+		}
+		updateCancel()
+		if errors.Is(err, errClaimLost) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("drift alert for user %s: %w", settings.UserID.Hex(), err))
+			continue
+		}
+		if len(drifts) > 0 {
+			queued++
+		}
+	}
+
+	if queued > 0 {
+		fmt.Printf("[DriftAlert] Queued %d drift alerts\n", queued)
+	}
+	return errors.Join(errs...)
+}
+
+// renderDriftNotification renders the out-of-band groups as a plain text email
+func renderDriftNotification(alerts models.DriftAlertSettings, drifts []TargetDrift) Notification {
+	dimension := "asset class"
+	if alerts.GroupBy == "assetStyle" {
+		dimension = "asset style"
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Your allocation has drifted more than %.1f percentage points from its targets by %s:\n\n", alerts.Threshold, dimension)
+	for _, drift := range drifts {
+		fmt.Fprintf(&text, "  %s  %.1f%% vs %.1f%% target (%+.1f pp)\n", drift.Group, drift.CurrentWeight, drift.TargetWeight, drift.Drift)
+	}
+	text.WriteString("\nYou can change your targets or turn off these alerts in your settings.\n")
+
+	subject := fmt.Sprintf("%s is %.1f pp off target", drifts[0].Group, math.Abs(drifts[0].Drift))
+	if len(drifts) > 1 {
+		subject = fmt.Sprintf("%d %s groups are off target", len(drifts), dimension)
+	}
+	return Notification{
+		Subject: "Allocation drift: " + subject,
+		Text:    text.String(),
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTargetDrift(t *testing.T) {
+	groups := []GroupedHolding{
+		{GroupName: "Stock", Percentage: 72},
+		{GroupName: "Bond", Percentage: 20},
+		{GroupName: "Crypto", Percentage: 8},
+	}
+	targets := map[string]float64{"Stock": 60, "Bond": 30, "Cash": 10}
+
+	drifts := targetDrift(groups, targets, 5)
+	if len(drifts) != 4 {
+		t.Fatalf("Expected 4 groups out of band, got %+v", drifts)
+	}
+
+	// Overweight stocks first, then the untargeted and missing groups
+	if drifts[0].Group != "Stock" || drifts[0].Drift != 12 {
+		t.Errorf("Expected Stock 12 pp over, got %+v", drifts[0])
+	}
+	if drifts[1].Group != "Bond" || drifts[1].Drift != -10 || drifts[2].Group != "Cash" || drifts[2].Drift != -10 {
+		t.Errorf("Expected Bond and Cash 10 pp under, got %+v and %+v", drifts[1], drifts[2])
+	}
+	if drifts[3].Group != "Crypto" || drifts[3].TargetWeight != 0 {
+		t.Errorf("Expected untargeted Crypto against 0, got %+v", drifts[3])
+	}
+
+	if got := breachedGroups(drifts); strings.Join(got, ",") != "Bond,Cash,Crypto,Stock" {
+		t.Errorf("Unexpected breached groups %v", got)
+	}
+	if drifts := targetDrift(groups, targets, 15); len(drifts) != 0 {
+		t.Errorf("Expected no drift beyond 15 pp, got %+v", drifts)
+	}
+}
+
+func TestValidateDriftTargets(t *testing.T) {
+	if err := validateDriftTargets(map[string]float64{"Stock": 60.005, "Bond": 39.995}); err != nil {
+		t.Errorf("Expected targets adding up to 100 to be valid, got %v", err)
+	}
+	if err := validateDriftTargets(map[string]float64{"Stock": 60, "Bond": 30}); !errors.Is(err, ErrInvalidDriftTargets) {
+		t.Errorf("Expected ErrInvalidDriftTargets, got %v", err)
+	}
+}
+
+func TestRenderDriftNotification(t *testing.T) {
+	alerts := models.DriftAlertSettings{GroupBy: "assetStyle", Threshold: 5}
+	notification := renderDriftNotification(alerts, []TargetDrift{
+		{Group: "Growth", CurrentWeight: 70, TargetWeight: 50, Drift: 20},
+	})
+	if notification.Subject != "Allocation drift: Growth is 20.0 pp off target" {
+		t.Errorf("Unexpected subject %q", notification.Subject)
+	}
+	if !strings.Contains(notification.Text, "Growth  70.0% vs 50.0% target (+20.0 pp)") {
+		t.Errorf("Unexpected text %q", notification.Text)
+	}
+}
+
+func TestCheckDrift(t *testing.T) {
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 100, "USD").
+		SetQuote("SPY", "SPDR S&P 500", 100, "USD")
+	analyticsService, portfolioService := newFixtureAnalyticsService(provider)
+	repos := portfolioService.repos
+	service := NewDriftAlertService(analyticsService, NewNotificationServiceWithRepos(repos, &flakyChannel{}))
+	settingsRepo := repos.Settings.(*repository.MemorySettings)
+	outbox := repos.Outbox.(*repository.MemoryOutbox)
+
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -5)
+	for _, tx := range []*models.Transaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 5, Price: 100, Currency: "USD", Date: date},
+		{Symbol: "SPY", Action: "buy", Shares: 5, Price: 100, Currency: "USD", Date: date},
+	} {
+		if err := portfolioService.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+	for symbol, class := range map[string]string{"AAPL": "Stock", "SPY": "ETF"} {
+		if _, err := portfolioService.CreatePortfolioWithMetadata(userID, symbol, primitive.NilObjectID, class); err != nil {
+			t.Fatalf("Failed to classify %s: %v", symbol, err)
+		}
+	}
+
+	// Stock and ETF are each 30 pp off their targets
+	settingsID := primitive.NewObjectID()
+	settingsRepo.Put(models.UserSettings{ID: settingsID, UserID: userID, DriftAlerts: models.DriftAlertSettings{
+		Enabled:   true,
+		GroupBy:   "assetClass",
+		Currency:  "USD",
+		Threshold: 5,
+		Targets:   map[string]float64{"Stock": 80, "ETF": 20},
+	}})
+	settingsRepo.Put(models.UserSettings{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID()})
+
+	if err := service.CheckDrift(); err != nil {
+		t.Fatalf("CheckDrift failed: %v", err)
+	}
+	messages := outbox.Messages()
+	if len(messages) != 1 || messages[0].UserID != userID || messages[0].Source != "drift-alerts" {
+		t.Fatalf("Expected one drift alert queued for the user, got %+v", messages)
+	}
+	settings, _ := settingsRepo.Get(settingsID)
+	if strings.Join(settings.DriftAlerts.Breached, ",") != "ETF,Stock" || settings.DriftAlerts.LastAlertedAt == nil {
+		t.Errorf("Expected the breached groups recorded, got %+v", settings.DriftAlerts)
+	}
+
+	// The same drift isn't alerted again
+	if err := service.CheckDrift(); err != nil {
+		t.Fatalf("CheckDrift failed: %v", err)
+	}
+	if len(outbox.Messages()) != 1 {
+		t.Errorf("Expected no second alert for the same drift, got %d messages", len(outbox.Messages()))
+	}
+}
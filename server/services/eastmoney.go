@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"stock-portfolio-tracker/currencycode"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	eastmoneyQuoteURL = "http://push2.eastmoney.com/api/qt/stock/get"
+	eastmoneyKlineURL = "http://push2his.eastmoney.com/api/qt/stock/kline/get"
+
+	// eastmoneyPreferenceDuration is how long a Chinese or Hong Kong symbol is
+	// served from Eastmoney alone after Yahoo Finance failed for it
+	eastmoneyPreferenceDuration = 30 * time.Minute
+)
+
+// chinaMarketZone is the exchange time zone of Shanghai, Shenzhen and Hong Kong
+var chinaMarketZone = time.FixedZone("CST", 8*60*60)
+
+// Eastmoney API response structures
+type eastmoneyResponse struct {
+	Data *struct {
+		F43  interface{} `json:"f43"`  // 最新价，按 f59 位小数放大；停牌时为 "-"
+		F58  string      `json:"f58"`  // 股票名称
+		F59  int         `json:"f59"`  // 价格小数位数
+		F60  interface{} `json:"f60"`  // 昨收价，与 f43 同样放大
+		F127 string      `json:"f127"` // 所属行业；无行业时为 "-"
+	} `json:"data"`
+	RC  int    `json:"rc"`  // 返回码，0 表示成功
+	RT  int    `json:"rt"`  // 响应类型
+	Msg string `json:"msg"` // 消息
+}
+
+type eastmoneyKlineResponse struct {
+	Data *struct {
+		Klines []string `json:"klines"` // "日期,收盘价"
+	} `json:"data"`
+	RC  int    `json:"rc"`
+	Msg string `json:"msg"`
+}
+
+// fetchFromEastmoney performs a GET against an Eastmoney endpoint and returns the body
+func (s *StockAPIService) fetchFromEastmoney(ctx context.Context, url string) ([]byte, error) {
+	fmt.Printf("[StockAPI] Eastmoney HTTP GET: %s\n", url)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+
+	startTime := time.Now()
+	resp, err := s.eastmoneyClient.Do(req)
+	duration := time.Since(startTime)
+	if err != nil {
+		fmt.Printf("[StockAPI] ERROR: Eastmoney HTTP request failed after %v: %v\n", duration, err)
+		return nil, fmt.Errorf("%w: %v", ErrExternalAPI, err)
+	}
+	defer resp.Body.Close()
+
+	fmt.Printf("[StockAPI] Eastmoney HTTP response received in %v, status: %d\n", duration, resp.StatusCode)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status code %d", ErrExternalAPI, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
+
+// fetchQuoteFromEastmoney fetches the name and latest price of a Chinese or
+// Hong Kong stock. The price is zero while the stock is suspended.
+func (s *StockAPIService) fetchQuoteFromEastmoney(ctx context.Context, symbol string) (*StockInfo, error) {
+	secid, err := s.convertToEastmoneySecID(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := s.fetchFromEastmoney(ctx, fmt.Sprintf("%s?secid=%s&fields=f43,f58,f59,f60,f127", eastmoneyQuoteURL, secid))
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := parseEastmoneyQuote(symbol, body)
+	if err != nil {
+		fmt.Printf("[StockAPI] ERROR: Failed to parse Eastmoney quote for %s: %v\n", symbol, err)
+		return nil, err
+	}
+
+	fmt.Printf("[StockAPI] Successfully fetched quote from Eastmoney: %s -> %s %.2f\n", symbol, info.Name, info.CurrentPrice)
+	return info, nil
+}
+
+// fetchHistoryFromEastmoney fetches daily closes of a Chinese or Hong Kong
+// stock since the start time. Prices are forward-adjusted so the latest close
+// matches the quote.
+func (s *StockAPIService) fetchHistoryFromEastmoney(ctx context.Context, symbol string, startTime time.Time) ([]HistoricalPrice, error) {
+	secid, err := s.convertToEastmoneySecID(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf(
+		"%s?secid=%s&fields1=f1,f2,f3&fields2=f51,f53&klt=101&fqt=1&beg=%s&end=20500101",
+		eastmoneyKlineURL, secid, startTime.In(chinaMarketZone).Format("20060102"),
+	)
+	body, err := s.fetchFromEastmoney(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := parseEastmoneyKlines(body)
+	if err != nil {
+		fmt.Printf("[StockAPI] ERROR: Failed to parse Eastmoney klines for %s: %v\n", symbol, err)
+		return nil, err
+	}
+
+	fmt.Printf("[StockAPI] Successfully fetched %d daily prices from Eastmoney for %s\n", len(data), symbol)
+	return data, nil
+}
+
+// parseEastmoneyQuote extracts StockInfo from an Eastmoney quote response
+func parseEastmoneyQuote(symbol string, body []byte) (*StockInfo, error) {
+	var resp eastmoneyResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if resp.RC != 0 {
+		return nil, fmt.Errorf("eastmoney API error: %s", resp.Msg)
+	}
+	if resp.Data == nil {
+		return nil, ErrStockNotFound
+	}
+
+	name := strings.TrimSpace(resp.Data.F58)
+	if name == "" {
+		return nil, fmt.Errorf("empty stock name returned")
+	}
+
+	// Prices are integers scaled by 10^f59; suspended stocks report "-"
+	price := 0.0
+	if scaled, ok := resp.Data.F43.(float64); ok && scaled > 0 {
+		price = scaled / math.Pow10(resp.Data.F59)
+	}
+	previousClose := 0.0
+	if scaled, ok := resp.Data.F60.(float64); ok && scaled > 0 {
+		previousClose = scaled / math.Pow10(resp.Data.F59)
+	}
+
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	currency := currencycode.RMB
+	if market := MarketForSymbol(symbol); market != nil {
+		currency = market.Currency
+	}
+
+	sector := strings.TrimSpace(resp.Data.F127)
+	if sector == "-" {
+		sector = ""
+	}
+
+	now := time.Now()
+	return &StockInfo{
+		Symbol:        symbol,
+		Name:          name,
+		CurrentPrice:  price,
+		Currency:      currency,
+		Sector:        sector,
+		PreviousClose: previousClose,
+		PriceSource:   PriceSource{Source: PriceSourceEastmoney, AsOf: &now},
+	}, nil
+}
+
+// parseEastmoneyKlines extracts daily closes from an Eastmoney kline response.
+// Each day is stamped at the 09:30 open in Shanghai and Hong Kong time, as
+// Yahoo Finance does.
+func parseEastmoneyKlines(body []byte) ([]HistoricalPrice, error) {
+	var resp eastmoneyKlineResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if resp.RC != 0 {
+		return nil, fmt.Errorf("eastmoney API error: %s", resp.Msg)
+	}
+	if resp.Data == nil || len(resp.Data.Klines) == 0 {
+		return nil, ErrStockNotFound
+	}
+
+	data := make([]HistoricalPrice, 0, len(resp.Data.Klines))
+	for _, line := range resp.Data.Klines {
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("malformed kline: %q", line)
+		}
+		day, err := time.ParseInLocation("2006-01-02", fields[0], chinaMarketZone)
+		if err != nil {
+			return nil, fmt.Errorf("malformed kline date: %q", fields[0])
+		}
+		closePrice, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed kline close: %q", fields[1])
+		}
+		if closePrice <= 0 {
+			continue
+		}
+		data = append(data, HistoricalPrice{
+			Date:  day.Add(9*time.Hour + 30*time.Minute),
+			Price: closePrice,
+		})
+	}
+	return data, nil
+}
+
+// prefersEastmoney reports whether a symbol is currently served from
+// Eastmoney because Yahoo Finance recently failed for it
+func (s *StockAPIService) prefersEastmoney(symbol string) bool {
+	s.cacheMutex.RLock()
+	defer s.cacheMutex.RUnlock()
+	until, ok := s.eastmoneyPreferred[symbol]
+	return ok && time.Now().Before(until)
+}
+
+// setEastmoneyPreferred switches a symbol to or back from Eastmoney
+func (s *StockAPIService) setEastmoneyPreferred(symbol string, preferred bool) {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+	if !preferred {
+		delete(s.eastmoneyPreferred, symbol)
+		return
+	}
+	if s.eastmoneyPreferred == nil {
+		s.eastmoneyPreferred = make(map[string]time.Time)
+	}
+	s.eastmoneyPreferred[symbol] = time.Now().Add(eastmoneyPreferenceDuration)
+	fmt.Printf("[StockAPI] Serving %s from Eastmoney for the next %v\n", symbol, eastmoneyPreferenceDuration)
+}
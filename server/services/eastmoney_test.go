@@ -0,0 +1,63 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseEastmoneyQuote(t *testing.T) {
+	info, err := parseEastmoneyQuote("600519.ss", []byte(`{"rc":0,"rt":4,"data":{"f43":168812,"f58":"贵州茅台","f59":2,"f60":167500,"f127":"酿酒行业"}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Symbol != "600519.SS" || info.Name != "贵州茅台" || info.Currency != "RMB" || math.Abs(info.CurrentPrice-1688.12) > 1e-9 || math.Abs(info.PreviousClose-1675) > 1e-9 || info.Sector != "酿酒行业" {
+		t.Errorf("Unexpected quote %+v", info)
+	}
+	if info.Source != PriceSourceEastmoney || info.AsOf == nil || info.Cached {
+		t.Errorf("Expected a fresh Eastmoney quote, got %+v", info.PriceSource)
+	}
+
+	// Suspended stocks report no price
+	info, err = parseEastmoneyQuote("000001.SZ", []byte(`{"rc":0,"data":{"f43":"-","f58":"平安银行","f59":2,"f127":"-"}}`))
+	if err != nil || info.CurrentPrice != 0 || info.Sector != "" {
+		t.Errorf("Expected a suspended quote without price, got %+v, %v", info, err)
+	}
+
+	if _, err := parseEastmoneyQuote("999999.SS", []byte(`{"rc":0,"data":null}`)); err != ErrStockNotFound {
+		t.Errorf("Expected ErrStockNotFound, got %v", err)
+	}
+}
+
+func TestParseEastmoneyKlines(t *testing.T) {
+	data, err := parseEastmoneyKlines([]byte(`{"rc":0,"data":{"code":"600519","klines":["2024-05-09,1700.50","2024-05-10,1712.00"]}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(data) != 2 || data[1].Price != 1712 {
+		t.Fatalf("Unexpected klines %+v", data)
+	}
+	// Stamped at the Shanghai open, matching Yahoo Finance timestamps
+	if want := time.Date(2024, 5, 10, 1, 30, 0, 0, time.UTC); !data[1].Date.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, data[1].Date)
+	}
+
+	if _, err := parseEastmoneyKlines([]byte(`{"rc":0,"data":{"klines":["2024-05-10"]}}`)); err == nil {
+		t.Errorf("Expected an error for a malformed kline")
+	}
+}
+
+func TestEastmoneyPreference(t *testing.T) {
+	service := &StockAPIService{}
+	if service.prefersEastmoney("600519.SS") {
+		t.Fatalf("Expected Yahoo Finance by default")
+	}
+	service.setEastmoneyPreferred("600519.SS", true)
+	if !service.prefersEastmoney("600519.SS") || service.prefersEastmoney("000001.SZ") {
+		t.Errorf("Expected only 600519.SS to prefer Eastmoney")
+	}
+	service.setEastmoneyPreferred("600519.SS", false)
+	if service.prefersEastmoney("600519.SS") {
+		t.Errorf("Expected the preference to be cleared")
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"stock-portfolio-tracker/models"
+	"time"
+)
+
+// SMTPConfig represents the outgoing mail server settings
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// EmailChannel delivers notifications by email over SMTP
+type EmailChannel struct {
+	config SMTPConfig
+}
+
+// NewEmailChannel creates a new EmailChannel instance
+func NewEmailChannel(config SMTPConfig) *EmailChannel {
+	if config.Port == "" {
+		config.Port = "587"
+	}
+	return &EmailChannel{
+		config: config,
+	}
+}
+
+// NewMailChannel creates an email channel for the SMTP settings, falling back
+// to a LogChannel when no SMTP host is configured
+func NewMailChannel(config SMTPConfig) NotificationChannel {
+	if config.Host == "" {
+		fmt.Println("[Notification] SMTP host not configured, notifications will be logged instead of emailed")
+		return &LogChannel{}
+	}
+
+	return NewEmailChannel(config)
+}
+
+// Name returns the channel name
+func (c *EmailChannel) Name() string {
+	return "email"
+}
+
+// Send emails the notification to the recipient
+func (c *EmailChannel) Send(recipient *models.User, notification Notification) error {
+	message, err := buildEmailMessage(c.config.From, recipient.Email, notification)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if c.config.Username != "" {
+		auth = smtp.PlainAuth("", c.config.Username, c.config.Password, c.config.Host)
+	}
+
+	// The envelope sender must be a bare address even if From has a display name
+	sender := c.config.From
+	if address, err := mail.ParseAddress(c.config.From); err == nil {
+		sender = address.Address
+	}
+
+	addr := c.config.Host + ":" + c.config.Port
+	if err := smtp.SendMail(addr, auth, sender, []string{recipient.Email}, message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// buildEmailMessage builds a MIME message with a plain text body and, when
+// present, an HTML alternative
+func buildEmailMessage(from string, to string, notification Notification) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if notification.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		buf.WriteString(notification.Text)
+		return buf.Bytes(), nil
+	}
+
+	writer := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", notification.Text},
+		{"text/html; charset=utf-8", notification.HTML},
+	}
+	for _, part := range parts {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to build email: %w", err)
+		}
+		if _, err := partWriter.Write([]byte(part.body)); err != nil {
+			return nil, fmt.Errorf("failed to build email: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build email: %w", err)
+	}
+	return buf.Bytes(), nil
+}
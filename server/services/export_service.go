@@ -0,0 +1,321 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"stock-portfolio-tracker/repository"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrExportNotFound    = errors.New("data export not found")
+	ErrExportNotComplete = errors.New("data export has not completed")
+	ErrExportFailed      = errors.New("data export failed")
+)
+
+// ExportStatus represents the lifecycle state of a data export
+type ExportStatus string
+
+const (
+	ExportPending   ExportStatus = "pending"
+	ExportRunning   ExportStatus = "running"
+	ExportCompleted ExportStatus = "completed"
+	ExportFailed    ExportStatus = "failed"
+)
+
+// exportRetention is how long a finished export and its archive can be
+// downloaded
+const exportRetention = 24 * time.Hour
+
+// ExportJob represents an asynchronous export of all of a user's data
+type ExportJob struct {
+	ID          string             `json:"id"`
+	UserID      primitive.ObjectID `json:"-"`
+	Status      ExportStatus       `json:"status"`
+	Size        int                `json:"size,omitempty"`
+	Error       string             `json:"error,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	CompletedAt *time.Time         `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time         `json:"expiresAt,omitempty"`
+	// DownloadURL is set by the handler once the archive is ready
+	DownloadURL string `json:"downloadUrl,omitempty"`
+
+	// DownloadToken authorizes downloading the archive without the owner's
+	// credentials, so the link can be opened directly in a browser
+	DownloadToken string `json:"-"`
+
+	archive []byte
+}
+
+// active reports whether the export is still waiting or running
+func (j *ExportJob) active() bool {
+	return j.Status == ExportPending || j.Status == ExportRunning
+}
+
+// snapshot copies the job without its archive
+func (j *ExportJob) snapshot() *ExportJob {
+	snapshot := *j
+	snapshot.archive = nil
+	return &snapshot
+}
+
+// expired reports whether the job finished longer ago than the retention
+func (j *ExportJob) expired(now time.Time) bool {
+	return j.ExpiresAt != nil && !now.Before(*j.ExpiresAt)
+}
+
+// exportSection is one file of the export archive
+type exportSection struct {
+	name  string
+	fetch func(ctx context.Context, userID primitive.ObjectID) (interface{}, error)
+}
+
+// ExportService builds downloadable archives of everything stored for a
+// user. Exports run in the background and are kept in memory, one per user,
+// until they expire.
+type ExportService struct {
+	sections  []exportSection
+	jobs      map[primitive.ObjectID]*ExportJob
+	jobsMutex sync.RWMutex
+}
+
+// NewExportService creates a new ExportService exporting the user's stored
+// data, including the settings kept by settingsService
+func NewExportService(settingsService *SettingsService) *ExportService {
+	service := NewExportServiceWithRepos(repository.NewMongo())
+	service.sections = append(service.sections, exportSection{"settings", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+		return settingsService.GetSettings(userID)
+	}})
+	return service
+}
+
+// NewExportServiceWithRepos creates an ExportService exporting the data held
+// in the given repositories
+func NewExportServiceWithRepos(repos repository.Repositories) *ExportService {
+	return &ExportService{
+		sections: []exportSection{
+			{"profile", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+				user, err := repos.Users.FindByID(ctx, userID)
+				if err != nil {
+					return nil, err
+				}
+				return newProfile(user), nil
+			}},
+			{"transactions", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+				return repos.Transactions.FindSince(ctx, userID, time.Time{})
+			}},
+			{"positions", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+				return repos.Transactions.Positions(ctx, userID)
+			}},
+			{"portfolios", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+				return repos.Portfolios.FindByUser(ctx, userID)
+			}},
+			{"asset_styles", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+				return repos.AssetStyles.FindByUser(ctx, userID)
+			}},
+			{"pending_orders", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+				return repos.PendingOrders.FindByUser(ctx, userID)
+			}},
+			{"brokerage_links", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+				return repos.BrokerageLinks.FindByUser(ctx, userID)
+			}},
+			{"sheets_link", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+				link, err := repos.SheetsLinks.FindByUser(ctx, userID)
+				if errors.Is(err, repository.ErrNotFound) {
+					return nil, nil
+				}
+				return link, err
+			}},
+			{"webhooks", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+				return repos.Webhooks.FindByUser(ctx, userID)
+			}},
+			{"cash_interest_rates", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+				return repos.CashInterest.FindByUser(ctx, userID)
+			}},
+			{"price_overrides", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+				return repos.PriceOverrides.FindByUser(ctx, userID)
+			}},
+			{"sessions", func(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+				return repos.Sessions.FindActive(ctx, userID, time.Now())
+			}},
+		},
+		jobs: make(map[primitive.ObjectID]*ExportJob),
+	}
+}
+
+// StartExport queues an export of the user's data. A user has at most one
+// export running, so asking again while one is in progress returns it.
+func (s *ExportService) StartExport(userID primitive.ObjectID) (*ExportJob, error) {
+	token, err := generateShareToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate download token: %w", err)
+	}
+
+	s.jobsMutex.Lock()
+	if existing, ok := s.jobs[userID]; ok && existing.active() {
+		snapshot := existing.snapshot()
+		s.jobsMutex.Unlock()
+		return snapshot, nil
+	}
+
+	job := &ExportJob{
+		ID:            primitive.NewObjectID().Hex(),
+		UserID:        userID,
+		Status:        ExportPending,
+		CreatedAt:     time.Now(),
+		DownloadToken: token,
+	}
+	s.jobs[userID] = job
+	snapshot := job.snapshot()
+	s.jobsMutex.Unlock()
+
+	fmt.Printf("[Export] Queued export %s for user %s\n", job.ID, userID.Hex())
+	go s.runExport(job)
+
+	return snapshot, nil
+}
+
+// runExport builds the job's archive
+func (s *ExportService) runExport(job *ExportJob) {
+	s.jobsMutex.Lock()
+	job.Status = ExportRunning
+	s.jobsMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	archive, err := s.buildArchive(ctx, job.UserID)
+
+	s.jobsMutex.Lock()
+	defer s.jobsMutex.Unlock()
+
+	completedAt := time.Now()
+	expiresAt := completedAt.Add(exportRetention)
+	job.CompletedAt = &completedAt
+	job.ExpiresAt = &expiresAt
+	if err != nil {
+		fmt.Printf("[Export] Export %s failed: %v\n", job.ID, err)
+		job.Status = ExportFailed
+		job.Error = err.Error()
+		return
+	}
+
+	fmt.Printf("[Export] Export %s completed in %s\n", job.ID, completedAt.Sub(job.CreatedAt))
+	job.Status = ExportCompleted
+	job.Size = len(archive)
+	job.archive = archive
+}
+
+// buildArchive writes each section of the user's data as a JSON file of a
+// zip archive, preceded by a manifest listing them
+func (s *ExportService) buildArchive(ctx context.Context, userID primitive.ObjectID) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	files := make([]string, 0, len(s.sections))
+	for _, section := range s.sections {
+		files = append(files, section.name+".json")
+	}
+	manifest := map[string]interface{}{
+		"userId":     userID.Hex(),
+		"exportedAt": time.Now().UTC(),
+		"files":      files,
+	}
+	if err := writeArchiveJSON(archive, "manifest.json", manifest); err != nil {
+		return nil, err
+	}
+
+	for _, section := range s.sections {
+		data, err := section.fetch(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", section.name, err)
+		}
+		if err := writeArchiveJSON(archive, section.name+".json", data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeArchiveJSON adds value to the archive as an indented JSON file
+func writeArchiveJSON(archive *zip.Writer, name string, value interface{}) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetExport returns the status of the user's latest export
+func (s *ExportService) GetExport(userID primitive.ObjectID) (*ExportJob, error) {
+	s.jobsMutex.RLock()
+	defer s.jobsMutex.RUnlock()
+
+	job, exists := s.jobs[userID]
+	if !exists || job.expired(time.Now()) {
+		return nil, ErrExportNotFound
+	}
+	return job.snapshot(), nil
+}
+
+// GetArchive returns the archive of the completed export with the download
+// token. The returned bytes are shared with the job and must not be modified.
+func (s *ExportService) GetArchive(token string) ([]byte, *ExportJob, error) {
+	s.jobsMutex.RLock()
+	defer s.jobsMutex.RUnlock()
+
+	now := time.Now()
+	for _, job := range s.jobs {
+		if subtle.ConstantTimeCompare([]byte(job.DownloadToken), []byte(token)) != 1 || job.expired(now) {
+			continue
+		}
+		switch job.Status {
+		case ExportCompleted:
+			return job.archive, job.snapshot(), nil
+		case ExportFailed:
+			return nil, nil, fmt.Errorf("%w: %s", ErrExportFailed, job.Error)
+		default:
+			return nil, nil, ErrExportNotComplete
+		}
+	}
+	return nil, nil, ErrExportNotFound
+}
+
+// cleanupExpiredExports removes exports past their retention, freeing their archives
+func (s *ExportService) cleanupExpiredExports() {
+	s.jobsMutex.Lock()
+	defer s.jobsMutex.Unlock()
+
+	now := time.Now()
+	for userID, job := range s.jobs {
+		if job.expired(now) {
+			delete(s.jobs, userID)
+		}
+	}
+}
+
+// StartCleanup starts a background goroutine to periodically remove expired exports
+func (s *ExportService) StartCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			s.cleanupExpiredExports()
+		}
+	}()
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// waitForExport polls the user's export until it finishes
+func waitForExport(t *testing.T, service *ExportService, userID primitive.ObjectID) *ExportJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := service.GetExport(userID)
+		if err != nil {
+			t.Fatalf("Failed to get export: %v", err)
+		}
+		if !job.active() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Export did not finish in time")
+	return nil
+}
+
+func TestExportArchiveContainsUserData(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemory()
+	service := NewExportServiceWithRepos(repos)
+
+	user := &models.User{ID: primitive.NewObjectID(), Email: "export@example.com", Password: "secret-hash"}
+	if err := repos.Users.Insert(ctx, user); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	for _, symbol := range []string{"AAPL", "MSFT"} {
+		tx := &models.Transaction{ID: primitive.NewObjectID(), UserID: user.ID, Symbol: symbol, Action: "buy", Shares: 1, Price: 100, Currency: "USD", Date: time.Now()}
+		if err := repos.Transactions.Insert(ctx, tx); err != nil {
+			t.Fatalf("Failed to insert transaction: %v", err)
+		}
+	}
+	other := &models.Transaction{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Symbol: "TSLA", Action: "buy", Shares: 1, Price: 100, Currency: "USD", Date: time.Now()}
+	if err := repos.Transactions.Insert(ctx, other); err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+
+	if _, err := service.GetExport(user.ID); !errors.Is(err, ErrExportNotFound) {
+		t.Errorf("Expected no export yet, got %v", err)
+	}
+	if _, err := service.StartExport(user.ID); err != nil {
+		t.Fatalf("Failed to start export: %v", err)
+	}
+	job := waitForExport(t, service, user.ID)
+	if job.Status != ExportCompleted {
+		t.Fatalf("Expected export to complete, got %s: %s", job.Status, job.Error)
+	}
+
+	data, _, err := service.GetArchive(job.DownloadToken)
+	if err != nil {
+		t.Fatalf("Failed to get archive: %v", err)
+	}
+	if _, _, err := service.GetArchive("not-a-token"); !errors.Is(err, ErrExportNotFound) {
+		t.Errorf("Expected unknown token to be rejected, got %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", file.Name, err)
+		}
+		files[file.Name], _ = io.ReadAll(reader)
+		reader.Close()
+	}
+
+	for _, name := range []string{"manifest.json", "profile.json", "transactions.json", "portfolios.json", "asset_styles.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Expected archive to contain %s", name)
+		}
+	}
+	if bytes.Contains(files["profile.json"], []byte("secret-hash")) {
+		t.Error("Expected the password hash to be left out of the export")
+	}
+
+	var transactions []models.Transaction
+	if err := json.Unmarshal(files["transactions.json"], &transactions); err != nil {
+		t.Fatalf("Failed to decode transactions: %v", err)
+	}
+	if len(transactions) != 2 {
+		t.Errorf("Expected only the user's 2 transactions, got %d", len(transactions))
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import "time"
+
+// Trading sessions a quote can be taken in
+const (
+	SessionPre     = "pre"
+	SessionRegular = "regular"
+	SessionPost    = "post"
+	SessionClosed  = "closed"
+)
+
+// ExtendedHoursQuote is the latest pre-market or after-hours trade of a
+// symbol, with its move from the regular session's price
+type ExtendedHoursQuote struct {
+	Session       string    `json:"session"` // SessionPre or SessionPost
+	Price         float64   `json:"price"`
+	Change        float64   `json:"change"` // Per share, from the regular market price
+	ChangePercent float64   `json:"changePercent"`
+	Time          time.Time `json:"time"`
+
+	// Change in the holding's value, in its display currency. Set on holdings.
+	ValueChange float64 `json:"valueChange,omitempty"`
+}
+
+// yahooTradingPeriod is one session of the current trading day, in Unix seconds
+type yahooTradingPeriod struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// yahooTradingPeriods is the current trading day's sessions as Yahoo reports
+// them in the chart meta
+type yahooTradingPeriods struct {
+	Pre     yahooTradingPeriod `json:"pre"`
+	Regular yahooTradingPeriod `json:"regular"`
+	Post    yahooTradingPeriod `json:"post"`
+}
+
+// session returns the session in progress at a time, empty when Yahoo
+// reported no trading periods
+func (p yahooTradingPeriods) session(at time.Time) string {
+	if p.Regular.Start == 0 {
+		return ""
+	}
+	unix := at.Unix()
+	switch {
+	case p.Pre.Start > 0 && unix >= p.Pre.Start && unix < p.Pre.End:
+		return SessionPre
+	case unix >= p.Regular.Start && unix < p.Regular.End:
+		return SessionRegular
+	case p.Post.Start > 0 && unix >= p.Post.Start && unix < p.Post.End:
+		return SessionPost
+	default:
+		return SessionClosed
+	}
+}
+
+// dayStart returns when the current trading day starts in Unix seconds: at
+// the pre-market if there is one, else at the regular session
+func (p yahooTradingPeriods) dayStart() int64 {
+	if p.Pre.Start > 0 {
+		return p.Pre.Start
+	}
+	return p.Regular.Start
+}
+
+// newExtendedHoursQuote returns the later of the pre-market and after-hours
+// trades measured against the regular market price. It is nil during the
+// regular session, when the session isn't known, and when neither trade was
+// made since dayStart, the Unix time the current trading day started, as an
+// older trade is a previous day's. Prices are in the same unit as
+// regularPrice.
+func newExtendedHoursQuote(session string, dayStart int64, regularPrice, prePrice float64, preTime int64, postPrice float64, postTime int64) *ExtendedHoursQuote {
+	if session != SessionPre && session != SessionPost && session != SessionClosed {
+		return nil
+	}
+	if preTime < dayStart {
+		prePrice = 0
+	}
+	if postTime < dayStart {
+		postPrice = 0
+	}
+
+	tradeSession, price, at := SessionPre, prePrice, preTime
+	if postPrice > 0 && (prePrice <= 0 || postTime >= preTime) {
+		tradeSession, price, at = SessionPost, postPrice, postTime
+	}
+	if price <= 0 || regularPrice <= 0 {
+		return nil
+	}
+
+	change := price - regularPrice
+	return &ExtendedHoursQuote{
+		Session:       tradeSession,
+		Price:         price,
+		Change:        change,
+		ChangePercent: change / regularPrice * 100,
+		Time:          time.Unix(at, 0),
+	}
+}
+
+// convertedExtendedHours returns a copy of a symbol's extended-hours quote
+// converted at rate, with the move in value of shares
+func convertedExtendedHours(quote *ExtendedHoursQuote, rate, shares float64) *ExtendedHoursQuote {
+	if quote == nil {
+		return nil
+	}
+	converted := *quote
+	converted.Price *= rate
+	converted.Change *= rate
+	converted.ValueChange = converted.Change * shares
+	return &converted
+}
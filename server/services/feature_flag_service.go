@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"slices"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidFeatureFlag  = errors.New("feature flag names must be lowercase letters, digits and dashes")
+	ErrInvalidRollout      = errors.New("rollout percentage must be between 0 and 100")
+	ErrFeatureFlagNotFound = errors.New("feature flag not found")
+)
+
+// Flags gating features that are being rolled out
+const (
+	// FeatureExchangeLinks gates linking crypto exchange accounts with API
+	// keys, which are synced with the user's own credentials
+	FeatureExchangeLinks = "exchange-links"
+)
+
+// featureFlagNamePattern keeps flag names usable in FEATURE_FLAGS overrides
+var featureFlagNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// FeatureFlagService decides which users get features that are being rolled
+// out. Flags are stored in the database and cached, so checking one costs
+// nothing per request; configured overrides take precedence over them.
+type FeatureFlagService struct {
+	repos     repository.Repositories
+	overrides map[string]bool
+
+	mutex sync.RWMutex
+	flags map[string]models.FeatureFlag
+}
+
+// NewFeatureFlagService creates a new FeatureFlagService instance storing
+// flags in MongoDB
+func NewFeatureFlagService(overrides map[string]bool) *FeatureFlagService {
+	return NewFeatureFlagServiceWithRepos(repository.NewMongo(), overrides)
+}
+
+// NewFeatureFlagServiceWithRepos creates a FeatureFlagService over the given repositories
+func NewFeatureFlagServiceWithRepos(repos repository.Repositories, overrides map[string]bool) *FeatureFlagService {
+	return &FeatureFlagService{
+		repos:     repos,
+		overrides: overrides,
+		flags:     make(map[string]models.FeatureFlag),
+	}
+}
+
+// Refresh reloads the stored flags. On failure the last loaded flags stay
+// in effect.
+func (s *FeatureFlagService) Refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stored, err := s.repos.FeatureFlags.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load feature flags: %w", err)
+	}
+
+	flags := make(map[string]models.FeatureFlag, len(stored))
+	for _, flag := range stored {
+		flags[flag.Key] = flag
+	}
+	s.mutex.Lock()
+	s.flags = flags
+	s.mutex.Unlock()
+	return nil
+}
+
+// Enabled reports whether the user gets the feature. Unknown flags are off.
+func (s *FeatureFlagService) Enabled(key string, userID primitive.ObjectID) bool {
+	if enabled, ok := s.overrides[key]; ok {
+		return enabled
+	}
+
+	s.mutex.RLock()
+	flag, ok := s.flags[key]
+	s.mutex.RUnlock()
+	return ok && flagEnabledFor(flag, userID)
+}
+
+// EnabledForAnyone reports whether some user may get the feature, so what
+// the feature needs can be checked at startup
+func (s *FeatureFlagService) EnabledForAnyone(key string) bool {
+	if enabled, ok := s.overrides[key]; ok {
+		return enabled
+	}
+
+	s.mutex.RLock()
+	flag, ok := s.flags[key]
+	s.mutex.RUnlock()
+	return ok && flag.Enabled && (flag.Percentage > 0 || len(flag.Users) > 0)
+}
+
+// ForUser evaluates every known flag for the user, so clients can hide the
+// features they don't get
+func (s *FeatureFlagService) ForUser(userID primitive.ObjectID) map[string]bool {
+	s.mutex.RLock()
+	features := make(map[string]bool, len(s.flags)+len(s.overrides))
+	for key, flag := range s.flags {
+		features[key] = flagEnabledFor(flag, userID)
+	}
+	s.mutex.RUnlock()
+
+	for key, enabled := range s.overrides {
+		features[key] = enabled
+	}
+	return features
+}
+
+// List returns the stored flags, read from the database rather than the cache
+func (s *FeatureFlagService) List() ([]models.FeatureFlag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	flags, err := s.repos.FeatureFlags.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load feature flags: %w", err)
+	}
+	return flags, nil
+}
+
+// Save creates or replaces a flag. Running instances pick it up on their
+// next refresh.
+func (s *FeatureFlagService) Save(flag models.FeatureFlag) (*models.FeatureFlag, error) {
+	if !featureFlagNamePattern.MatchString(flag.Key) {
+		return nil, ErrInvalidFeatureFlag
+	}
+	if flag.Percentage < 0 || flag.Percentage > 100 {
+		return nil, ErrInvalidRollout
+	}
+	flag.UpdatedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.repos.FeatureFlags.Save(ctx, &flag); err != nil {
+		return nil, fmt.Errorf("failed to save feature flag: %w", err)
+	}
+
+	s.mutex.Lock()
+	s.flags[flag.Key] = flag
+	s.mutex.Unlock()
+	return &flag, nil
+}
+
+// Delete removes a flag, turning its feature off for everyone not covered
+// by an override
+func (s *FeatureFlagService) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.repos.FeatureFlags.Delete(ctx, key)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrFeatureFlagNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+
+	s.mutex.Lock()
+	delete(s.flags, key)
+	s.mutex.Unlock()
+	return nil
+}
+
+// flagEnabledFor applies a flag to a user. Users are bucketed by a hash of
+// the flag and user, so raising the percentage only adds users, and each
+// flag reaches a different sample.
+func flagEnabledFor(flag models.FeatureFlag, userID primitive.ObjectID) bool {
+	if !flag.Enabled {
+		return false
+	}
+	if slices.Contains(flag.Users, userID) {
+		return true
+	}
+	return rolloutBucket(flag.Key, userID) < flag.Percentage
+}
+
+// rolloutBucket places the user in one of 100 buckets for the flag
+func rolloutBucket(key string, userID primitive.ObjectID) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	hash.Write(userID[:])
+	return int(hash.Sum32() % 100)
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFeatureFlagRollout(t *testing.T) {
+	repos := repository.NewMemory()
+	service := NewFeatureFlagServiceWithRepos(repos, map[string]bool{"forced-off": false})
+
+	users := make([]primitive.ObjectID, 1000)
+	for i := range users {
+		users[i] = primitive.NewObjectID()
+	}
+	countEnabled := func(key string) int {
+		count := 0
+		for _, userID := range users {
+			if service.Enabled(key, userID) {
+				count++
+			}
+		}
+		return count
+	}
+
+	if _, err := service.Save(models.FeatureFlag{Key: "new-engine", Enabled: true, Percentage: 20}); err != nil {
+		t.Fatalf("Failed to save flag: %v", err)
+	}
+	twenty := countEnabled("new-engine")
+	if twenty < 150 || twenty > 250 {
+		t.Errorf("Expected about 20%% of users, got %d of %d", twenty, len(users))
+	}
+
+	// Raising the percentage keeps every user who already had the feature
+	before := map[primitive.ObjectID]bool{}
+	for _, userID := range users {
+		before[userID] = service.Enabled("new-engine", userID)
+	}
+	if _, err := service.Save(models.FeatureFlag{Key: "new-engine", Enabled: true, Percentage: 50}); err != nil {
+		t.Fatalf("Failed to save flag: %v", err)
+	}
+	for userID, enabled := range before {
+		if enabled && !service.Enabled("new-engine", userID) {
+			t.Fatalf("Expected user %s to keep the feature after raising the rollout", userID.Hex())
+		}
+	}
+
+	// Listed users get the feature at 0%, but not with the flag switched off
+	listed := users[0]
+	if _, err := service.Save(models.FeatureFlag{Key: "beta", Enabled: true, Users: []primitive.ObjectID{listed}}); err != nil {
+		t.Fatalf("Failed to save flag: %v", err)
+	}
+	if !service.Enabled("beta", listed) || countEnabled("beta") != 1 {
+		t.Errorf("Expected only the listed user to get beta")
+	}
+	if _, err := service.Save(models.FeatureFlag{Key: "beta", Enabled: false, Percentage: 100, Users: []primitive.ObjectID{listed}}); err != nil {
+		t.Fatalf("Failed to save flag: %v", err)
+	}
+	if countEnabled("beta") != 0 {
+		t.Errorf("Expected a disabled flag to be off for everyone")
+	}
+
+	// Overrides win over stored flags; unknown flags are off
+	if _, err := service.Save(models.FeatureFlag{Key: "forced-off", Enabled: true, Percentage: 100}); err != nil {
+		t.Fatalf("Failed to save flag: %v", err)
+	}
+	if countEnabled("forced-off") != 0 || countEnabled("unknown") != 0 {
+		t.Errorf("Expected overridden and unknown flags to be off")
+	}
+	if !service.EnabledForAnyone("new-engine") || service.EnabledForAnyone("beta") || service.EnabledForAnyone("forced-off") || service.EnabledForAnyone("unknown") {
+		t.Errorf("Expected only new-engine to be enabled for anyone")
+	}
+
+	// Another instance sees the stored flags after refreshing
+	other := NewFeatureFlagServiceWithRepos(repos, nil)
+	if err := other.Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	features := other.ForUser(listed)
+	if len(features) != 3 || !features["forced-off"] || features["beta"] {
+		t.Errorf("Unexpected features %v", features)
+	}
+
+	if err := service.Delete("beta"); err != nil || service.Delete("beta") != ErrFeatureFlagNotFound {
+		t.Errorf("Expected beta to be deleted once, got %v", err)
+	}
+}
+
+func TestSaveFeatureFlagValidation(t *testing.T) {
+	service := NewFeatureFlagServiceWithRepos(repository.NewMemory(), nil)
+	if _, err := service.Save(models.FeatureFlag{Key: "New Engine"}); err != ErrInvalidFeatureFlag {
+		t.Errorf("Expected ErrInvalidFeatureFlag, got %v", err)
+	}
+	if _, err := service.Save(models.FeatureFlag{Key: "new-engine", Percentage: 101}); err != ErrInvalidRollout {
+		t.Errorf("Expected ErrInvalidRollout, got %v", err)
+	}
+}
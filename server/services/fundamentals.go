@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"time"
+)
+
+const yahooQuoteURL = "https://query1.finance.yahoo.com/v7/finance/quote"
+
+// fundamentalsCacheDuration is how long a symbol's key statistics are reused.
+// They move with the price, but far less than quotes matter to a holder.
+const fundamentalsCacheDuration = time.Hour
+
+// FiftyTwoWeekRange represents a price's 52-week trading range
+type FiftyTwoWeekRange struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+	// Position is where the price sits in the range, from 0 at the low to
+	// 100 at the high
+	Position float64 `json:"position"`
+}
+
+// newFiftyTwoWeekRange returns the price's position in its 52-week range, nil
+// when the range is unknown
+func newFiftyTwoWeekRange(price, low, high float64) *FiftyTwoWeekRange {
+	if price <= 0 || low <= 0 || high < low {
+		return nil
+	}
+
+	position := 100.0
+	if high > low {
+		position = math.Max(0, math.Min(100, (price-low)/(high-low)*100))
+	}
+	return &FiftyTwoWeekRange{Low: low, High: high, Position: position}
+}
+
+// Fundamentals represents a symbol's key statistics. Statistics the provider
+// does not report are omitted; DividendYield is a percentage of the price.
+type Fundamentals struct {
+	Symbol        string             `json:"symbol"`
+	Name          string             `json:"name"`
+	Currency      string             `json:"currency"`
+	Price         float64            `json:"price"`
+	MarketCap     float64            `json:"marketCap,omitempty"`
+	PERatio       float64            `json:"peRatio,omitempty"`
+	EPS           float64            `json:"eps,omitempty"`
+	DividendYield float64            `json:"dividendYield"`
+	FiftyTwoWeek  *FiftyTwoWeekRange `json:"fiftyTwoWeek,omitempty"`
+	UpdatedAt     time.Time          `json:"updatedAt"`
+}
+
+// Yahoo Finance quote API response structure
+type yahooQuoteResponse struct {
+	QuoteResponse struct {
+		Result []struct {
+			Symbol                      string  `json:"symbol"`
+			LongName                    string  `json:"longName"`
+			ShortName                   string  `json:"shortName"`
+			Currency                    string  `json:"currency"`
+			RegularMarketPrice          float64 `json:"regularMarketPrice"`
+			MarketCap                   float64 `json:"marketCap"`
+			TrailingPE                  float64 `json:"trailingPE"`
+			EpsTrailingTwelveMonths     float64 `json:"epsTrailingTwelveMonths"`
+			FiftyTwoWeekHigh            float64 `json:"fiftyTwoWeekHigh"`
+			FiftyTwoWeekLow             float64 `json:"fiftyTwoWeekLow"`
+			TrailingAnnualDividendYield float64 `json:"trailingAnnualDividendYield"`
+		} `json:"result"`
+	} `json:"quoteResponse"`
+}
+
+// GetFundamentalsContext returns a symbol's market cap, P/E, EPS, dividend
+// yield and 52-week range. They come from the Yahoo Finance quote API; when
+// it is unavailable the price, range and dividend yield are derived from the
+// chart API and the valuation statistics are omitted.
+func (s *StockAPIService) GetFundamentalsContext(ctx context.Context, symbol string) (*Fundamentals, error) {
+	symbol = s.catalog.Resolve(symbol)
+	if symbol == "" || s.IsCashSymbol(symbol) {
+		return nil, ErrInvalidSymbol
+	}
+	if _, ok := models.ParseOptionSymbol(symbol); ok {
+		return nil, ErrInvalidSymbol
+	}
+
+	if cached, found := s.getCachedFundamentals(symbol); found {
+		return cached, nil
+	}
+
+	fundamentals, err := s.fetchFundamentals(ctx, symbol)
+	if err != nil {
+		fmt.Printf("[StockAPI] WARNING: Yahoo Finance quote failed for %s, deriving fundamentals from price history: %v\n", symbol, err)
+		fundamentals, err = s.deriveFundamentals(ctx, symbol)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.setCachedFundamentals(symbol, fundamentals)
+	return fundamentals, nil
+}
+
+// fetchFundamentals fetches key statistics from the Yahoo Finance quote API
+func (s *StockAPIService) fetchFundamentals(ctx context.Context, symbol string) (*Fundamentals, error) {
+	quoteURL := yahooQuoteURL + "?symbols=" + url.QueryEscape(symbol)
+	fmt.Printf("[StockAPI] HTTP GET: %s\n", quoteURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", quoteURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExternalAPI, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status code %d", ErrExternalAPI, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return parseYahooFundamentals(body)
+}
+
+// parseYahooFundamentals extracts key statistics from a Yahoo Finance quote
+// response. Prices quoted in minor units such as pence are converted to the
+// major unit.
+func parseYahooFundamentals(body []byte) (*Fundamentals, error) {
+	var response yahooQuoteResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(response.QuoteResponse.Result) == 0 || response.QuoteResponse.Result[0].RegularMarketPrice <= 0 {
+		return nil, ErrStockNotFound
+	}
+	quote := response.QuoteResponse.Result[0]
+
+	scale := 1.0
+	currency := strings.ToUpper(quote.Currency)
+	if major, ok := minorCurrencyUnits[quote.Currency]; ok {
+		currency = major
+		scale = 100
+	}
+
+	name := quote.LongName
+	if name == "" {
+		name = quote.ShortName
+	}
+	if name == "" {
+		name = quote.Symbol
+	}
+
+	price := quote.RegularMarketPrice / scale
+	return &Fundamentals{
+		Symbol:        quote.Symbol,
+		Name:          name,
+		Currency:      currency,
+		Price:         price,
+		MarketCap:     quote.MarketCap,
+		PERatio:       quote.TrailingPE,
+		EPS:           quote.EpsTrailingTwelveMonths / scale,
+		DividendYield: quote.TrailingAnnualDividendYield * 100,
+		FiftyTwoWeek:  newFiftyTwoWeekRange(price, quote.FiftyTwoWeekLow/scale, quote.FiftyTwoWeekHigh/scale),
+		UpdatedAt:     time.Now(),
+	}, nil
+}
+
+// deriveFundamentals builds the statistics available from the chart API: the
+// price, its 52-week range, from the quote or else the past year of closes,
+// and the trailing dividend yield
+func (s *StockAPIService) deriveFundamentals(ctx context.Context, symbol string) (*Fundamentals, error) {
+	info, err := s.GetStockInfoContext(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	fundamentals := &Fundamentals{
+		Symbol:    info.Symbol,
+		Name:      info.Name,
+		Currency:  info.Currency,
+		Price:     info.CurrentPrice,
+		UpdatedAt: time.Now(),
+	}
+
+	fundamentals.FiftyTwoWeek = newFiftyTwoWeekRange(info.CurrentPrice, info.FiftyTwoWeekLow, info.FiftyTwoWeekHigh)
+	if fundamentals.FiftyTwoWeek == nil {
+		if prices, err := s.GetHistoricalDataContext(ctx, symbol, "1Y"); err == nil && len(prices) > 0 {
+			low, high := prices[0].Price, prices[0].Price
+			for _, price := range prices {
+				low = math.Min(low, price.Price)
+				high = math.Max(high, price.Price)
+			}
+			fundamentals.FiftyTwoWeek = newFiftyTwoWeekRange(info.CurrentPrice, low, high)
+		}
+	}
+
+	if dividends, err := s.GetDividendsContext(ctx, symbol); err == nil && info.CurrentPrice > 0 {
+		perShare, _, _ := projectDividends(dividends, time.Now())
+		fundamentals.DividendYield = perShare / info.CurrentPrice * 100
+	}
+
+	return fundamentals, nil
+}
+
+// getCachedFundamentals retrieves key statistics from cache if available and not expired
+func (s *StockAPIService) getCachedFundamentals(symbol string) (*Fundamentals, bool) {
+	return s.fundamentalsCache.Get(symbol, time.Now())
+}
+
+// setCachedFundamentals stores key statistics in cache with expiration
+func (s *StockAPIService) setCachedFundamentals(symbol string, fundamentals *Fundamentals) {
+	s.fundamentalsCache.Set(symbol, fundamentals, time.Now().Add(fundamentalsCacheDuration))
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewFiftyTwoWeekRange(t *testing.T) {
+	if r := newFiftyTwoWeekRange(150, 100, 200); r == nil || r.Position != 50 {
+		t.Errorf("Expected the midpoint of the range, got %+v", r)
+	}
+	// A price outside a stale range is clamped to its ends
+	if r := newFiftyTwoWeekRange(210, 100, 200); r == nil || r.Position != 100 {
+		t.Errorf("Expected a position of 100, got %+v", r)
+	}
+	if r := newFiftyTwoWeekRange(150, 0, 0); r != nil {
+		t.Errorf("Expected no range when the provider reports none, got %+v", r)
+	}
+}
+
+func TestParseYahooFundamentals(t *testing.T) {
+	fundamentals, err := parseYahooFundamentals([]byte(`{"quoteResponse":{"result":[{
+		"symbol":"VOD.L","longName":"Vodafone Group Plc","currency":"GBp","regularMarketPrice":75,
+		"marketCap":19000000000,"trailingPE":12.5,"epsTrailingTwelveMonths":6,
+		"fiftyTwoWeekHigh":80,"fiftyTwoWeekLow":60,"trailingAnnualDividendYield":0.1
+	}]}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fundamentals.Currency != "GBP" || fundamentals.Price != 0.75 || fundamentals.EPS != 0.06 || fundamentals.PERatio != 12.5 {
+		t.Errorf("Expected prices in pounds, got %+v", fundamentals)
+	}
+	if math.Abs(fundamentals.DividendYield-10) > 1e-9 {
+		t.Errorf("Expected a 10%% dividend yield, got %.4f", fundamentals.DividendYield)
+	}
+	if r := fundamentals.FiftyTwoWeek; r == nil || r.Low != 0.6 || r.High != 0.8 || math.Abs(r.Position-75) > 1e-9 {
+		t.Errorf("Unexpected 52-week range %+v", r)
+	}
+
+	if _, err := parseYahooFundamentals([]byte(`{"quoteResponse":{"result":[]}}`)); err != ErrStockNotFound {
+		t.Errorf("Expected ErrStockNotFound, got %v", err)
+	}
+}
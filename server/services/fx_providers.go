@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"stock-portfolio-tracker/currencycode"
+)
+
+// FXProvider supplies exchange rates. CurrencyService tries its providers in
+// order and falls back to the next one when a provider fails or does not
+// quote the requested currency.
+type FXProvider interface {
+	// Name identifies the provider in logs
+	Name() string
+	// FetchRates returns the rates from base to every currency the provider
+	// quotes. RMB is used for the Chinese yuan in both base and result.
+	FetchRates(ctx context.Context, base string) (map[string]float64, error)
+}
+
+var (
+	_ FXProvider = (*ExchangeRateAPIProvider)(nil)
+	_ FXProvider = (*FrankfurterProvider)(nil)
+	_ FXProvider = StaticFXProvider(nil)
+)
+
+// normalizeProviderRates reports CNY rates as RMB
+func normalizeProviderRates(rates map[string]float64) map[string]float64 {
+	normalized := make(map[string]float64, len(rates))
+	for currency, rate := range rates {
+		if rate > 0 {
+			normalized[currencycode.Normalize(currency)] = rate
+		}
+	}
+	return normalized
+}
+
+// getJSON performs a GET request and decodes the JSON response into target
+func getJSON(ctx context.Context, client *http.Client, req *http.Request, target interface{}) error {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCurrencyAPIError, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status code %d", ErrCurrencyAPIError, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// ExchangeRateAPIProvider fetches rates from ExchangeRate-API, which requires
+// an API key
+type ExchangeRateAPIProvider struct {
+	client *http.Client
+	apiKey string
+}
+
+// NewExchangeRateAPIProvider creates an ExchangeRate-API provider
+func NewExchangeRateAPIProvider(client *http.Client, apiKey string) *ExchangeRateAPIProvider {
+	return &ExchangeRateAPIProvider{client: client, apiKey: apiKey}
+}
+
+func (p *ExchangeRateAPIProvider) Name() string {
+	return "ExchangeRate-API"
+}
+
+// FetchRates fetches the base currency's rate table. The key is sent as a
+// bearer token rather than in the path so it does not end up in traced URLs.
+func (p *ExchangeRateAPIProvider) FetchRates(ctx context.Context, base string) (map[string]float64, error) {
+	req, err := http.NewRequest("GET", "https://v6.exchangerate-api.com/v6/latest/"+url.PathEscape(currencycode.ISO(base)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	var apiResp exchangeRateAPIResponse
+	if err := getJSON(ctx, p.client, req, &apiResp); err != nil {
+		return nil, err
+	}
+	if apiResp.Result != "success" {
+		return nil, fmt.Errorf("%w: API returned error result", ErrCurrencyAPIError)
+	}
+	return normalizeProviderRates(apiResp.ConversionRates), nil
+}
+
+// frankfurterResponse represents the response from the Frankfurter API
+type frankfurterResponse struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// FrankfurterProvider fetches the European Central Bank reference rates
+// published by frankfurter.app. It needs no API key and covers the major
+// currencies, including CNY, HKD and JPY.
+type FrankfurterProvider struct {
+	client *http.Client
+}
+
+// NewFrankfurterProvider creates a Frankfurter provider
+func NewFrankfurterProvider(client *http.Client) *FrankfurterProvider {
+	return &FrankfurterProvider{client: client}
+}
+
+func (p *FrankfurterProvider) Name() string {
+	return "Frankfurter"
+}
+
+func (p *FrankfurterProvider) FetchRates(ctx context.Context, base string) (map[string]float64, error) {
+	req, err := http.NewRequest("GET", "https://api.frankfurter.app/latest?from="+url.QueryEscape(currencycode.ISO(base)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	var apiResp frankfurterResponse
+	if err := getJSON(ctx, p.client, req, &apiResp); err != nil {
+		return nil, err
+	}
+	if len(apiResp.Rates) == 0 {
+		return nil, fmt.Errorf("%w: no rates for %s", ErrCurrencyAPIError, base)
+	}
+	return normalizeProviderRates(apiResp.Rates), nil
+}
+
+// StaticFXProvider serves fixed rates keyed by base then quote currency. It is
+// the emergency fallback when no live provider answers.
+type StaticFXProvider map[string]map[string]float64
+
+func (p StaticFXProvider) Name() string {
+	return "fallback rates"
+}
+
+func (p StaticFXProvider) FetchRates(ctx context.Context, base string) (map[string]float64, error) {
+	rates, ok := p[base]
+	if !ok {
+		return nil, fmt.Errorf("%w: no fallback rates for %s", ErrExchangeRateNotFound, base)
+	}
+	return normalizeProviderRates(rates), nil
+}
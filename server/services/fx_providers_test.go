@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stock-portfolio-tracker/currencycode"
+	"testing"
+)
+
+// stubFXProvider serves fixed rates, or fails when err is set
+type stubFXProvider struct {
+	name  string
+	rates map[string]map[string]float64
+	err   error
+	calls int
+}
+
+func (p *stubFXProvider) Name() string {
+	return p.name
+}
+
+func (p *stubFXProvider) FetchRates(ctx context.Context, base string) (map[string]float64, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.rates[base], nil
+}
+
+func TestCurrencyServiceProviderFallback(t *testing.T) {
+	down := &stubFXProvider{name: "down", err: ErrCurrencyAPIError}
+	partial := &stubFXProvider{name: "partial", rates: map[string]map[string]float64{"USD": {"EUR": 0.9}}}
+	full := &stubFXProvider{name: "full", rates: map[string]map[string]float64{"USD": {"EUR": 0.91, "HKD": 7.81}}}
+	service := NewCurrencyService(CurrencyConfig{Providers: []FXProvider{down, partial, full}})
+
+	// The partial provider answers EUR; HKD falls through to the full provider
+	if rate, err := service.GetExchangeRate("USD", "EUR"); err != nil || rate != 0.9 {
+		t.Errorf("Expected 0.9 from the first provider quoting EUR, got %v, %v", rate, err)
+	}
+	if rate, err := service.GetExchangeRate("USD", "HKD"); err != nil || rate != 7.81 {
+		t.Errorf("Expected 7.81 from the full provider, got %v, %v", rate, err)
+	}
+
+	// Fetched tables are cached, so repeated lookups make no further calls
+	calls := full.calls
+	if _, err := service.GetExchangeRate("USD", "HKD"); err != nil || full.calls != calls {
+		t.Errorf("Expected a cached rate, got %v after %d calls", err, full.calls-calls)
+	}
+}
+
+func TestCurrencyServiceEmergencyRates(t *testing.T) {
+	down := &stubFXProvider{name: "down", err: ErrCurrencyAPIError}
+	service := NewCurrencyService(CurrencyConfig{Providers: []FXProvider{down}})
+
+	// With every provider down, USD/CNY still converts at the fallback rate
+	if rate, err := service.GetExchangeRate("USD", "CNY"); err != nil || rate != 7.2 {
+		t.Errorf("Expected the 7.2 fallback rate, got %v, %v", rate, err)
+	}
+	if _, err := service.GetExchangeRate("USD", "CHF"); !errors.Is(err, ErrCurrencyAPIError) {
+		t.Errorf("Expected the provider error without a fallback rate, got %v", err)
+	}
+
+	rates, err := service.GetRateTable("USD")
+	if err != nil || rates["RMB"] != 7.2 || rates["USD"] != 1 {
+		t.Errorf("Expected the fallback rate table, got %v, %v", rates, err)
+	}
+}
+
+func TestStaticFXProviderNormalizesCNY(t *testing.T) {
+	rates, err := fallbackRates.FetchRates(context.Background(), "EUR")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := rates["CNY"]; ok || rates["RMB"] != 7.83 {
+		t.Errorf("Expected CNY reported as RMB, got %v", rates)
+	}
+	if currencycode.ISO("RMB") != "CNY" {
+		t.Errorf("Expected RMB to be requested as CNY")
+	}
+}
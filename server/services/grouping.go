@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/money"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidGroupBy = errors.New("invalid groupBy parameter: must be assetStyle, assetClass, assetSubclass, currency, tag:<name>, or none, or up to three of them joined by commas")
+
+// maxGroupByDimensions bounds how deeply grouped dashboards nest
+const maxGroupByDimensions = 3
+
+// groupByDimensions are the fixed dimensions holdings group by, besides tags
+var groupByDimensions = map[string]bool{
+	"assetStyle":    true,
+	"assetClass":    true,
+	"assetSubclass": true,
+	"currency":      true,
+}
+
+// ParseGroupBy splits a groupBy parameter such as assetClass,currency into its
+// dimensions, outermost first, normalizing tag dimensions. none stands alone.
+func ParseGroupBy(groupBy string) ([]string, error) {
+	if groupBy == "none" {
+		return []string{"none"}, nil
+	}
+
+	parts := strings.Split(groupBy, ",")
+	if len(parts) > maxGroupByDimensions {
+		return nil, ErrInvalidGroupBy
+	}
+	dimensions := make([]string, 0, len(parts))
+	for _, dimension := range parts {
+		dimension = strings.TrimSpace(dimension)
+		if name, ok := TagGroupBy(dimension); ok {
+			dimension = tagGroupByPrefix + name
+		} else if !groupByDimensions[dimension] {
+			return nil, ErrInvalidGroupBy
+		}
+		if slices.Contains(dimensions, dimension) {
+			return nil, ErrInvalidGroupBy
+		}
+		dimensions = append(dimensions, dimension)
+	}
+	return dimensions, nil
+}
+
+// holdingGrouping holds the user's classifications that holdings are grouped by
+type holdingGrouping struct {
+	portfolioMap  map[string]*models.Portfolio
+	assetStyleMap map[primitive.ObjectID]string
+	stylesByName  map[string]models.AssetStyle
+	subclasses    []models.AssetSubclass
+}
+
+// groupHoldings groups holdings by one dimension. labels is only set for
+// asset subclasses, whose group keys differ from their names.
+func (s *AnalyticsService) groupHoldings(dimension string, holdings []Holding, grouping *holdingGrouping) (map[string][]Holding, map[string]subclassGroup) {
+	switch dimension {
+	case "assetStyle":
+		return s.groupByAssetStyle(holdings, grouping.portfolioMap, grouping.assetStyleMap), nil
+	case "assetClass":
+		return s.groupByAssetClass(holdings, grouping.portfolioMap), nil
+	case "assetSubclass":
+		return s.groupByAssetSubclass(holdings, grouping.portfolioMap, grouping.subclasses)
+	case "currency":
+		return s.groupByCurrency(holdings, grouping.portfolioMap), nil
+	}
+	if name, ok := strings.CutPrefix(dimension, tagGroupByPrefix); ok {
+		return s.groupByTag(holdings, grouping.portfolioMap, name), nil
+	}
+	// No grouping, return all holdings in a single group
+	return map[string][]Holding{"All Holdings": holdings}, nil
+}
+
+// describe names the group under key and adds what the dimension knows about
+// it: an asset style's appearance or an asset subclass's class
+func (g *holdingGrouping) describe(group *GroupedHolding, dimension, key string, labels map[string]subclassGroup) {
+	group.GroupName = key
+	// Style names are unique per user, so the group name finds the style
+	if style, ok := g.stylesByName[key]; ok && dimension == "assetStyle" {
+		group.Color, group.Icon = style.Color, style.Icon
+	}
+	if label, ok := labels[key]; ok {
+		group.GroupName, group.Parent = label.name, label.parent
+	}
+}
+
+// changePercent returns change as a percentage of previous, or 0 without a
+// previous value
+func changePercent(change, previous float64) float64 {
+	if previous <= 0 {
+		return 0
+	}
+	return change / previous * 100
+}
+
+// nestGroups groups holdings by the first of dimensions and each group in turn
+// by the rest, largest first at every level. Percentages are of totalValue,
+// the whole portfolio, so a group's children add up to its own percentage.
+func (s *AnalyticsService) nestGroups(holdings []Holding, dimensions []string, grouping *holdingGrouping, currency string, totalValue money.Amount) []GroupedHolding {
+	groups, labels := s.groupHoldings(dimensions[0], holdings, grouping)
+
+	nested := make([]GroupedHolding, 0, len(groups))
+	for key, groupHoldings := range groups {
+		value := money.Zero(currency)
+		costBasis := money.Zero(currency)
+		dayChange := money.Zero(currency)
+		for _, holding := range groupHoldings {
+			value = value.Add(money.New(holding.CurrentValue, currency))
+			costBasis = costBasis.Add(money.New(holding.CostBasis, currency))
+			dayChange = dayChange.Add(money.New(holding.DayChange, currency))
+		}
+
+		gain := value.Sub(costBasis)
+		group := GroupedHolding{
+			GroupValue:       value.Float64(),
+			CostBasis:        costBasis.Float64(),
+			GainLoss:         gain.Float64(),
+			GainLossPercent:  changePercent(gain.Float64(), costBasis.Float64()),
+			DayChange:        dayChange.Float64(),
+			DayChangePercent: changePercent(dayChange.Float64(), value.Sub(dayChange).Float64()),
+			Holdings:         groupHoldings,
+		}
+		grouping.describe(&group, dimensions[0], key, labels)
+		if totalValue.Minor > 0 {
+			group.Weight = group.GroupValue / totalValue.Float64()
+			group.Percentage = group.Weight * 100
+		}
+		if len(dimensions) > 1 {
+			group.Groups = s.nestGroups(groupHoldings, dimensions[1:], grouping, currency, totalValue)
+		}
+		nested = append(nested, group)
+	}
+
+	sort.Slice(nested, func(i, j int) bool {
+		return nested[i].GroupValue > nested[j].GroupValue
+	})
+	return nested
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"math"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	for groupBy, want := range map[string]string{
+		"assetClass":              "assetClass",
+		"assetClass, currency":    "assetClass,currency",
+		"tag:Horizon,assetStyle":  "tag:horizon,assetStyle",
+		"none":                    "none",
+		"assetClass,none":         "",
+		"currency,currency":       "",
+		"assetClass,,currency":    "",
+		"sector":                  "",
+		"a,b,c,d":                 "",
+		"assetClass,currency,tag": "",
+	} {
+		dimensions, err := ParseGroupBy(groupBy)
+		if want == "" {
+			if err != ErrInvalidGroupBy {
+				t.Errorf("ParseGroupBy(%q): expected ErrInvalidGroupBy, got %v", groupBy, err)
+			}
+			continue
+		}
+		if err != nil || strings.Join(dimensions, ",") != want {
+			t.Errorf("ParseGroupBy(%q) = %v, %v, want %q", groupBy, dimensions, err, want)
+		}
+	}
+}
+
+func TestNestedGroupingByClassAndCurrency(t *testing.T) {
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 100, "USD").
+		SetQuote("SPY", "SPDR S&P 500", 100, "USD").
+		SetQuote("600519.SS", "Kweichow Moutai", 70, "RMB").
+		SetRate("RMB", "USD", 1.0/7)
+	service, portfolioService := newFixtureAnalyticsService(provider)
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -5)
+
+	for _, tx := range []*models.Transaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 5, Price: 100, Currency: "USD", Date: date},
+		{Symbol: "SPY", Action: "buy", Shares: 2, Price: 100, Currency: "USD", Date: date},
+		{Symbol: "600519.SS", Action: "buy", Shares: 30, Price: 70, Currency: "RMB", Date: date},
+	} {
+		if err := portfolioService.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+	for symbol, class := range map[string]string{"AAPL": "Stock", "600519.SS": "Stock", "SPY": "ETF"} {
+		if _, err := portfolioService.CreatePortfolioWithMetadata(userID, symbol, primitive.NilObjectID, class); err != nil {
+			t.Fatalf("Failed to classify %s: %v", symbol, err)
+		}
+	}
+
+	metrics, err := service.GetGroupedDashboardMetrics(context.Background(), userID, "USD", "assetClass,currency")
+	if err != nil {
+		t.Fatalf("Failed to get grouped metrics: %v", err)
+	}
+	if len(metrics.Groups) != 2 {
+		t.Fatalf("Expected 2 asset class groups, got %d", len(metrics.Groups))
+	}
+
+	stock := metrics.Groups[0]
+	if stock.GroupName != "Stock" || stock.GroupValue != 800 || stock.Percentage != 80 {
+		t.Errorf("Expected Stock worth 800 (80%%) first, got %s worth %v (%v%%)", stock.GroupName, stock.GroupValue, stock.Percentage)
+	}
+	if len(stock.Groups) != 2 {
+		t.Fatalf("Expected Stock split into 2 currencies, got %d", len(stock.Groups))
+	}
+	sum := 0.0
+	for _, child := range stock.Groups {
+		sum += child.Percentage
+		if len(child.Groups) != 0 {
+			t.Errorf("Expected no deeper nesting under %s", child.GroupName)
+		}
+	}
+	if stock.Groups[0].GroupName != "USD" || stock.Groups[0].GroupValue != 500 || stock.Groups[1].GroupName != "RMB" {
+		t.Errorf("Expected USD (500) then RMB under Stock, got %+v", stock.Groups)
+	}
+	if sum != stock.Percentage {
+		t.Errorf("Expected currency percentages to add up to %v, got %v", stock.Percentage, sum)
+	}
+	if etf := metrics.Groups[1]; len(etf.Groups) != 1 || etf.Groups[0].GroupName != "USD" {
+		t.Errorf("Expected ETF to hold a single USD group, got %+v", etf.Groups)
+	}
+}
+
+func TestGroupAndHoldingDayChange(t *testing.T) {
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 110, "USD").
+		SetPreviousClose("AAPL", 100).
+		SetQuote("KO", "Coca-Cola", 45, "USD").
+		SetPreviousClose("KO", 50)
+	service, portfolioService := newFixtureAnalyticsService(provider)
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -5)
+
+	for _, tx := range []*models.Transaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 90, Currency: "USD", Date: date},
+		{Symbol: "KO", Action: "buy", Shares: 10, Price: 40, Currency: "USD", Date: date},
+	} {
+		if err := portfolioService.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+	for symbol, class := range map[string]string{"AAPL": "Stock", "KO": "Stock"} {
+		if _, err := portfolioService.CreatePortfolioWithMetadata(userID, symbol, primitive.NilObjectID, class); err != nil {
+			t.Fatalf("Failed to classify %s: %v", symbol, err)
+		}
+	}
+
+	metrics, err := service.GetGroupedDashboardMetrics(context.Background(), userID, "USD", "assetClass,currency")
+	if err != nil {
+		t.Fatalf("Failed to get grouped metrics: %v", err)
+	}
+	if len(metrics.Groups) != 1 {
+		t.Fatalf("Expected a single Stock group, got %d", len(metrics.Groups))
+	}
+
+	// AAPL gained 100 from 1000 and KO lost 50 from 500
+	stock := metrics.Groups[0]
+	if stock.DayChange != 50 || stock.DayChangePercent != 50.0/1500*100 {
+		t.Errorf("Expected Stock to change by 50 (%v%%), got %v (%v%%)", 50.0/1500*100, stock.DayChange, stock.DayChangePercent)
+	}
+	if stock.DayChange != metrics.DayChange {
+		t.Errorf("Expected the group day change to match the total %v, got %v", metrics.DayChange, stock.DayChange)
+	}
+	if usd := stock.Groups[0]; usd.DayChange != 50 || usd.DayChangePercent != stock.DayChangePercent {
+		t.Errorf("Expected the nested USD group to match Stock, got %v (%v%%)", usd.DayChange, usd.DayChangePercent)
+	}
+	for _, holding := range stock.Holdings {
+		want := map[string][2]float64{"AAPL": {100, 10}, "KO": {-50, -10}}[holding.Symbol]
+		if holding.DayChange != want[0] || holding.DayChangePercent != want[1] {
+			t.Errorf("Expected %s to change by %v (%v%%), got %v (%v%%)", holding.Symbol, want[0], want[1], holding.DayChange, holding.DayChangePercent)
+		}
+	}
+}
+
+func TestGroupGainAndWeight(t *testing.T) {
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 110, "USD").
+		SetQuote("MSFT", "Microsoft", 60, "USD").
+		SetQuote("SPY", "SPDR S&P 500", 45, "USD")
+	service, portfolioService := newFixtureAnalyticsService(provider)
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -5)
+
+	for _, tx := range []*models.Transaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 90, Currency: "USD", Date: date},
+		{Symbol: "MSFT", Action: "buy", Shares: 10, Price: 40, Currency: "USD", Date: date},
+		{Symbol: "SPY", Action: "buy", Shares: 10, Price: 50, Currency: "USD", Date: date},
+	} {
+		if err := portfolioService.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+	for symbol, class := range map[string]string{"AAPL": "Stock", "MSFT": "Stock", "SPY": "ETF"} {
+		if _, err := portfolioService.CreatePortfolioWithMetadata(userID, symbol, primitive.NilObjectID, class); err != nil {
+			t.Fatalf("Failed to classify %s: %v", symbol, err)
+		}
+	}
+
+	metrics, err := service.GetGroupedDashboardMetrics(context.Background(), userID, "USD", "assetClass,currency")
+	if err != nil {
+		t.Fatalf("Failed to get grouped metrics: %v", err)
+	}
+	if len(metrics.Groups) != 2 {
+		t.Fatalf("Expected Stock and ETF groups, got %d", len(metrics.Groups))
+	}
+
+	// Stock cost 1300 and is worth 1700 of the total 2150; ETF cost 500 and is
+	// worth 450
+	stock, etf := metrics.Groups[0], metrics.Groups[1]
+	if stock.CostBasis != 1300 || stock.GainLoss != 400 || math.Abs(stock.GainLossPercent-400.0/1300*100) > 1e-9 {
+		t.Errorf("Expected Stock to cost 1300 and gain 400, got %v and %v (%v%%)", stock.CostBasis, stock.GainLoss, stock.GainLossPercent)
+	}
+	if etf.CostBasis != 500 || etf.GainLoss != -50 || etf.GainLossPercent != -10 {
+		t.Errorf("Expected ETF to cost 500 and lose 50, got %v and %v (%v%%)", etf.CostBasis, etf.GainLoss, etf.GainLossPercent)
+	}
+	if math.Abs(stock.Weight-1700.0/2150) > 1e-9 || math.Abs(stock.Weight+etf.Weight-1) > 1e-9 {
+		t.Errorf("Expected weights of %v and %v, got %v and %v", 1700.0/2150, 450.0/2150, stock.Weight, etf.Weight)
+	}
+	if usd := stock.Groups[0]; usd.CostBasis != stock.CostBasis || usd.GainLoss != stock.GainLoss || usd.Weight != stock.Weight {
+		t.Errorf("Expected the nested USD group's cost, gain and weight to match Stock, got %+v", usd)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// historySeries is a cached daily price history stored column-wise, in 16
+// bytes a day rather than the 40 of a HistoricalPrice. Prices are kept as
+// float32, about seven significant digits, so cents are exact below 100,000.
+// A series is never modified once built, so one series per symbol serves
+// every period it covers and concurrent readers need no lock.
+type historySeries struct {
+	start    time.Time      // Start of the window the series was fetched for
+	location *time.Location // Zone the provider stamped the dates in
+	dates    []int64        // Unix seconds, ascending
+	closes   []float32
+	adjusted []float32 // Nil when the provider reports no adjusted closes
+}
+
+// newHistorySeries builds a series fetched for the window starting at start
+func newHistorySeries(start time.Time, prices []HistoricalPrice) *historySeries {
+	sorted := make([]HistoricalPrice, len(prices))
+	copy(sorted, prices)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	series := &historySeries{
+		start:    start,
+		location: time.Local,
+		dates:    make([]int64, len(sorted)),
+		closes:   make([]float32, len(sorted)),
+	}
+	if len(sorted) > 0 {
+		series.location = sorted[0].Date.Location()
+	}
+	for i, price := range sorted {
+		series.dates[i] = price.Date.Unix()
+		series.closes[i] = float32(price.Price)
+		if price.AdjustedPrice != 0 {
+			if series.adjusted == nil {
+				series.adjusted = make([]float32, len(sorted))
+			}
+			series.adjusted[i] = float32(price.AdjustedPrice)
+		}
+	}
+	return series
+}
+
+// covers reports whether the series was fetched for a window starting no
+// later than start, so it holds every price from start on
+func (p *historySeries) covers(start time.Time) bool {
+	return !p.start.After(start)
+}
+
+// since returns a new slice of the prices dated at or after start
+func (p *historySeries) since(start time.Time) []HistoricalPrice {
+	first := sort.Search(len(p.dates), func(i int) bool { return p.dates[i] >= start.Unix() })
+
+	prices := make([]HistoricalPrice, len(p.dates)-first)
+	for i := range prices {
+		index := first + i
+		prices[i] = HistoricalPrice{
+			Date:  time.Unix(p.dates[index], 0).In(p.location),
+			Price: expandPrice(p.closes[index]),
+		}
+		if p.adjusted != nil {
+			prices[i].AdjustedPrice = expandPrice(p.adjusted[index])
+		}
+	}
+	return prices
+}
+
+// expandPrice converts a stored price back to the shortest decimal that rounds
+// to it, so a close quoted as 187.43 is read back as 187.43 rather than
+// 187.42999267578125
+func expandPrice(price float32) float64 {
+	value, err := strconv.ParseFloat(strconv.FormatFloat(float64(price), 'g', -1, 32), 64)
+	if err != nil {
+		return float64(price)
+	}
+	return value
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistorySeriesRoundTrip(t *testing.T) {
+	location := time.FixedZone("CST", 8*60*60)
+	day := func(d int) time.Time {
+		return time.Date(2024, 5, d, 9, 30, 0, 0, location)
+	}
+	// Out of order, as a provider may return them
+	series := newHistorySeries(day(1), []HistoricalPrice{
+		{Date: day(3), Price: 1688.12, AdjustedPrice: 1650.5},
+		{Date: day(1), Price: 187.43},
+		{Date: day(2), Price: 0.725, AdjustedPrice: 0.7},
+	})
+
+	prices := series.since(day(1))
+	if len(prices) != 3 {
+		t.Fatalf("Expected 3 prices, got %d", len(prices))
+	}
+	want := []HistoricalPrice{
+		{Date: day(1), Price: 187.43},
+		{Date: day(2), Price: 0.725, AdjustedPrice: 0.7},
+		{Date: day(3), Price: 1688.12, AdjustedPrice: 1650.5},
+	}
+	for i, price := range prices {
+		if !price.Date.Equal(want[i].Date) || price.Date.Location() != location {
+			t.Errorf("Price %d: expected date %v, got %v", i, want[i].Date, price.Date)
+		}
+		if price.Price != want[i].Price || price.AdjustedPrice != want[i].AdjustedPrice {
+			t.Errorf("Price %d: expected %v/%v, got %v/%v", i, want[i].Price, want[i].AdjustedPrice, price.Price, price.AdjustedPrice)
+		}
+	}
+
+	// Shorter windows are sliced from the series; longer ones are not covered
+	if !series.covers(day(2)) || series.covers(day(1).Add(-time.Hour)) {
+		t.Errorf("Unexpected coverage of series starting %v", series.start)
+	}
+	if shorter := series.since(day(2).Add(-time.Hour)); len(shorter) != 2 || shorter[0].Price != 0.725 {
+		t.Errorf("Expected the last 2 prices, got %+v", shorter)
+	}
+
+	// Callers may modify what they are given without affecting the cache
+	prices[0].Price = 0
+	if again := series.since(day(1)); again[0].Price != 187.43 {
+		t.Errorf("Expected cached series to be unchanged, got %v", again[0].Price)
+	}
+}
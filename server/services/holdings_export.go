@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HoldingsExportRow is one row of the holdings table exported for
+// spreadsheets. Amounts are in Currency.
+type HoldingsExportRow struct {
+	Symbol          string  `json:"symbol"`
+	Name            string  `json:"name"`
+	Shares          float64 `json:"shares"`
+	CostBasis       float64 `json:"cost"`
+	CurrentValue    float64 `json:"value"`
+	GainLoss        float64 `json:"gain"`
+	GainLossPercent float64 `json:"gainPercent"`
+	Currency        string  `json:"currency"`
+	AssetStyle      string  `json:"style"`
+	AssetClass      string  `json:"class"`
+}
+
+// holdingsExportHeader is the CSV header, matching the JSON field names
+var holdingsExportHeader = []string{"symbol", "name", "shares", "cost", "value", "gain", "gainPercent", "currency", "style", "class"}
+
+// GetHoldingsExport returns the user's current holdings in currency with the
+// asset style and class they are grouped by on the dashboard, ordered by
+// symbol
+func (s *PortfolioService) GetHoldingsExport(ctx context.Context, userID primitive.ObjectID, currency string) ([]HoldingsExportRow, error) {
+	currency, err := NormalizeDisplayCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	holdings, err := s.GetUserHoldingsContext(ctx, userID, currency)
+	if err != nil {
+		return nil, err
+	}
+	portfolios, err := s.repos.Portfolios.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch portfolios: %w", err)
+	}
+	styles, err := s.repos.AssetStyles.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch asset styles: %w", err)
+	}
+
+	styleNames := make(map[primitive.ObjectID]string, len(styles))
+	for _, style := range styles {
+		styleNames[style.ID] = style.Name
+	}
+	// Unclassified holdings are labelled as the dashboard groups them
+	styleBySymbol := make(map[string]string, len(portfolios))
+	classBySymbol := make(map[string]string, len(portfolios))
+	for _, portfolio := range portfolios {
+		if portfolio.AssetStyleID != nil {
+			if name, ok := styleNames[*portfolio.AssetStyleID]; ok {
+				styleBySymbol[portfolio.Symbol] = name
+			} else {
+				styleBySymbol[portfolio.Symbol] = "Unknown"
+			}
+		}
+		if portfolio.AssetClass != "" {
+			classBySymbol[portfolio.Symbol] = portfolio.AssetClass
+		}
+	}
+
+	rows := make([]HoldingsExportRow, 0, len(holdings))
+	for _, holding := range holdings {
+		row := HoldingsExportRow{
+			Symbol:          holding.Symbol,
+			Name:            holding.Name,
+			Shares:          holding.Shares,
+			CostBasis:       holding.CostBasis,
+			CurrentValue:    holding.CurrentValue,
+			GainLoss:        holding.GainLoss,
+			GainLossPercent: math.Round(holding.GainLossPercent*100) / 100,
+			Currency:        holding.Currency,
+			AssetStyle:      styleBySymbol[holding.Symbol],
+			AssetClass:      classBySymbol[holding.Symbol],
+		}
+		if row.AssetStyle == "" {
+			row.AssetStyle = "Uncategorized"
+		}
+		if row.AssetClass == "" {
+			row.AssetClass = "Uncategorized"
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Symbol < rows[j].Symbol })
+	return rows, nil
+}
+
+// spreadsheetText quotes text a spreadsheet would evaluate as a formula
+func spreadsheetText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// WriteHoldingsCSV writes the holdings table as CSV with a header row. It
+// starts with a byte order mark so Excel reads names such as Chinese company
+// names as UTF-8.
+func WriteHoldingsCSV(w io.Writer, rows []HoldingsExportRow) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(holdingsExportHeader); err != nil {
+		return err
+	}
+	format := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	for _, row := range rows {
+		record := []string{
+			spreadsheetText(row.Symbol),
+			spreadsheetText(row.Name),
+			format(row.Shares),
+			format(row.CostBasis),
+			format(row.CurrentValue),
+			format(row.GainLoss),
+			format(row.GainLossPercent),
+			row.Currency,
+			spreadsheetText(row.AssetStyle),
+			spreadsheetText(row.AssetClass),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHoldingsExport(t *testing.T) {
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 110, "USD").
+		SetQuote("600519.SS", "贵州茅台", 1500, "RMB").
+		SetRate("RMB", "USD", 0.125)
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	styles := NewAssetStyleServiceWithRepos(portfolioService.repos)
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -5)
+
+	growth, err := styles.CreateAssetStyle(userID, models.AssetStyleRequest{Name: "Growth"})
+	if err != nil {
+		t.Fatalf("Failed to create style: %v", err)
+	}
+	for _, tx := range []*models.Transaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 100, Currency: "USD", Date: date},
+		{Symbol: "600519.SS", Action: "buy", Shares: 20, Price: 1600, Currency: "RMB", Date: date},
+	} {
+		if err := portfolioService.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+	if _, err := portfolioService.CreatePortfolioWithMetadata(userID, "AAPL", growth.ID, "Stock"); err != nil {
+		t.Fatalf("Failed to classify AAPL: %v", err)
+	}
+
+	rows, err := portfolioService.GetHoldingsExport(context.Background(), userID, "USD")
+	if err != nil {
+		t.Fatalf("Failed to export holdings: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Symbol != "600519.SS" || rows[1].Symbol != "AAPL" {
+		t.Fatalf("Expected holdings ordered by symbol, got %+v", rows)
+	}
+	if rows[0].CurrentValue != 3750 || rows[0].GainLoss != -250 || rows[0].GainLossPercent != -6.25 || rows[0].AssetStyle != "Uncategorized" {
+		t.Errorf("Unexpected Moutai row %+v", rows[0])
+	}
+	if rows[1].AssetStyle != "Growth" || rows[1].AssetClass != "Stock" || rows[1].Currency != "USD" {
+		t.Errorf("Expected AAPL classified, got %+v", rows[1])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHoldingsCSV(&buf, append(rows, HoldingsExportRow{Symbol: "=CMD()"})); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[0] != "\ufeffsymbol,name,shares,cost,value,gain,gainPercent,currency,style,class" {
+		t.Fatalf("Unexpected CSV %q", buf.String())
+	}
+	if lines[1] != "600519.SS,贵州茅台,20,4000,3750,-250,-6.25,USD,Uncategorized,Uncategorized" {
+		t.Errorf("Unexpected row %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[3], "'=CMD()") {
+		t.Errorf("Expected formulas quoted, got %q", lines[3])
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"sort"
+	"strings"
+)
+
+// Orders holdings can be sorted in
+const (
+	HoldingsSortValue       = "value"
+	HoldingsSortGainPercent = "gainPercent"
+	HoldingsSortSymbol      = "symbol"
+)
+
+// HoldingsQuery narrows and orders a holdings list, so clients of large
+// portfolios can fetch only the rows they render
+type HoldingsQuery struct {
+	// Sort is one of the HoldingsSort orders, or empty to keep the holdings
+	// in the order they were priced
+	Sort       string
+	Descending bool
+	// MinValue drops holdings worth less, in the display currency
+	MinValue float64
+	// Symbol keeps only the holdings whose symbol contains it, ignoring case
+	Symbol string
+}
+
+// ValidHoldingsSort reports whether holdings can be sorted by sort
+func ValidHoldingsSort(sort string) bool {
+	switch sort {
+	case HoldingsSortValue, HoldingsSortGainPercent, HoldingsSortSymbol:
+		return true
+	default:
+		return false
+	}
+}
+
+// Apply returns the holdings the query selects, in its order. Holdings that
+// tie are ordered by symbol.
+func (q HoldingsQuery) Apply(holdings []Holding) []Holding {
+	symbol := strings.ToUpper(strings.TrimSpace(q.Symbol))
+	selected := make([]Holding, 0, len(holdings))
+	for _, holding := range holdings {
+		if holding.CurrentValue < q.MinValue {
+			continue
+		}
+		if symbol != "" && !strings.Contains(strings.ToUpper(holding.Symbol), symbol) {
+			continue
+		}
+		selected = append(selected, holding)
+	}
+
+	if !ValidHoldingsSort(q.Sort) {
+		return selected
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		a, b := selected[i], selected[j]
+		if q.Descending {
+			a, b = b, a
+		}
+		switch {
+		case q.Sort == HoldingsSortSymbol:
+			return a.Symbol < b.Symbol
+		case q.Sort == HoldingsSortValue && a.CurrentValue != b.CurrentValue:
+			return a.CurrentValue < b.CurrentValue
+		case q.Sort == HoldingsSortGainPercent && a.GainLossPercent != b.GainLossPercent:
+			return a.GainLossPercent < b.GainLossPercent
+		}
+		return selected[i].Symbol < selected[j].Symbol
+	})
+	return selected
+}
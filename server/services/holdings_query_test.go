@@ -0,0 +1,43 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHoldingsQuery(t *testing.T) {
+	holdings := []Holding{
+		{Symbol: "MSFT", CurrentValue: 300, GainLossPercent: 5},
+		{Symbol: "AAPL", CurrentValue: 500, GainLossPercent: -2},
+		{Symbol: "AMZN", CurrentValue: 300, GainLossPercent: 12},
+		{Symbol: "KO", CurrentValue: 50, GainLossPercent: 1},
+	}
+	symbols := func(holdings []Holding) string {
+		var symbols []string
+		for _, holding := range holdings {
+			symbols = append(symbols, holding.Symbol)
+		}
+		return strings.Join(symbols, ",")
+	}
+
+	for _, tc := range []struct {
+		name  string
+		query HoldingsQuery
+		want  string
+	}{
+		{"unsorted", HoldingsQuery{}, "MSFT,AAPL,AMZN,KO"},
+		{"value desc, ties by symbol", HoldingsQuery{Sort: HoldingsSortValue, Descending: true}, "AAPL,AMZN,MSFT,KO"},
+		{"value asc", HoldingsQuery{Sort: HoldingsSortValue}, "KO,AMZN,MSFT,AAPL"},
+		{"gain desc", HoldingsQuery{Sort: HoldingsSortGainPercent, Descending: true}, "AMZN,MSFT,KO,AAPL"},
+		{"symbol desc", HoldingsQuery{Sort: HoldingsSortSymbol, Descending: true}, "MSFT,KO,AMZN,AAPL"},
+		{"min value", HoldingsQuery{Sort: HoldingsSortSymbol, MinValue: 300}, "AAPL,AMZN,MSFT"},
+		{"symbol filter", HoldingsQuery{Symbol: " a "}, "AAPL,AMZN"},
+	} {
+		if got := symbols(tc.query.Apply(holdings)); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+	if symbols(holdings) != "MSFT,AAPL,AMZN,KO" {
+		t.Error("Expected Apply to leave the holdings untouched")
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/internal/analytics/engine"
+	"stock-portfolio-tracker/money"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrFutureSnapshot = errors.New("snapshot date cannot be in the future")
+
+// HoldingsSnapshot represents the positions held at the end of a past day,
+// valued at that day's closes and exchange rates, e.g. for a year-end
+// statement. CurrentPrice and CurrentValue of each holding are as of AsOf.
+type HoldingsSnapshot struct {
+	AsOf           time.Time `json:"asOf"`
+	Currency       string    `json:"currency"`
+	TotalValue     float64   `json:"totalValue"`
+	TotalCostBasis float64   `json:"totalCostBasis"`
+	Holdings       []Holding `json:"holdings"`
+	// Missing lists symbols that were held but have no close on or before
+	// AsOf, so their value is left out of the totals
+	Missing []string `json:"missing,omitempty"`
+}
+
+// GetHoldingsSnapshot reconstructs the user's holdings after every
+// transaction dated on or before asOf's day. Each position is priced at its
+// last close on or before that day and converted at that day's exchange
+// rate, as is its cost basis, so value and cost are in the same money.
+func (s *PortfolioService) GetHoldingsSnapshot(ctx context.Context, userID primitive.ObjectID, asOf time.Time, currency string) (*HoldingsSnapshot, error) {
+	currency, err := NormalizeDisplayCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	day := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+	end := day.AddDate(0, 0, 1)
+	if day.After(time.Now()) {
+		return nil, ErrFutureSnapshot
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	positions, err := s.repos.Transactions.PositionsBefore(queryCtx, userID, end)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate transactions: %w", err)
+	}
+
+	snapshot := &HoldingsSnapshot{
+		AsOf:     day,
+		Currency: currency,
+		Holdings: []Holding{},
+	}
+
+	// Exchange rates on the day, per currency converted from
+	rates := make(map[string]float64)
+	rateOn := func(from string) (float64, error) {
+		from = currencycode.Normalize(from)
+		if from == currency {
+			return 1, nil
+		}
+		if rate, ok := rates[from]; ok {
+			return rate, nil
+		}
+		history, err := s.stockService.GetHistoricalDataContext(ctx, fxPairSymbol(from, currency), "ALL")
+		if err != nil {
+			return 0, fmt.Errorf("failed to fetch %s/%s rate history: %w", from, currency, err)
+		}
+		rate, ok := engine.CloseBefore(engine.Sorted(history), end)
+		if !ok {
+			return 0, fmt.Errorf("no %s/%s rate on or before %s", from, currency, day.Format("2006-01-02"))
+		}
+		rates[from] = rate
+		return rate, nil
+	}
+
+	totalValue := money.Zero(currency)
+	totalCost := money.Zero(currency)
+	for _, position := range positions {
+		price := 1.0
+		if !s.stockService.IsCashSymbol(position.Symbol) {
+			prices, err := s.stockService.GetHistoricalDataContext(ctx, position.Symbol, "ALL")
+			if err != nil {
+				fmt.Printf("[Portfolio] Warning: failed to fetch price history for %s: %v\n", position.Symbol, err)
+				snapshot.Missing = append(snapshot.Missing, position.Symbol)
+				continue
+			}
+			var ok bool
+			if price, ok = engine.CloseBefore(engine.Sorted(prices), end); !ok {
+				snapshot.Missing = append(snapshot.Missing, position.Symbol)
+				continue
+			}
+		}
+
+		priceRate, err := rateOn(s.stockService.SymbolCurrency(position.Symbol))
+		if err != nil {
+			return nil, err
+		}
+		costRate, err := rateOn(position.Currency)
+		if err != nil {
+			return nil, err
+		}
+
+		holding := Holding{
+			Symbol:       position.Symbol,
+			Shares:       position.Shares,
+			CurrentPrice: price * priceRate,
+			Currency:     currency,
+		}
+		if info, err := s.stockService.GetStockInfoContext(ctx, position.Symbol); err == nil {
+			holding.Name = info.Name
+		}
+
+		costBasis := money.New(position.Cost*costRate, currency)
+		value := money.New(holding.CurrentPrice*position.Shares, currency)
+		gainLoss := value.Sub(costBasis)
+		if s.stockService.IsCashSymbol(position.Symbol) {
+			gainLoss = money.Zero(currency)
+		} else if costBasis.Minor > 0 {
+			holding.GainLossPercent = gainLoss.Float64() / costBasis.Float64() * 100
+		}
+		holding.CostBasis = costBasis.Float64()
+		holding.CurrentValue = value.Float64()
+		holding.GainLoss = gainLoss.Float64()
+
+		totalValue = totalValue.Add(value)
+		totalCost = totalCost.Add(costBasis)
+		snapshot.Holdings = append(snapshot.Holdings, holding)
+	}
+
+	sort.SliceStable(snapshot.Holdings, func(i, j int) bool {
+		return snapshot.Holdings[i].CurrentValue > snapshot.Holdings[j].CurrentValue
+	})
+	snapshot.TotalValue = totalValue.Float64()
+	snapshot.TotalCostBasis = totalCost.Float64()
+	return snapshot, nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGetHoldingsSnapshot(t *testing.T) {
+	end := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple", 200, "USD").
+		SetHistory("AAPL", end, 100, 110, 120, 130, 140).
+		SetQuote("600519.SS", "Moutai", 1800, "CNY").
+		SetHistory("600519.SS", end, 1500, 1600, 1700, 1750, 1800).
+		SetHistory("CNYUSD=X", end, 0.15, 0.15, 0.14, 0.14, 0.14).
+		SetRate("CNY", "USD", 0.1)
+	service := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	userID := primitive.NewObjectID()
+
+	for _, tx := range []*models.Transaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 100, Currency: "USD", Date: end.AddDate(0, 0, -4)},
+		{Symbol: "600519.SS", Action: "buy", Shares: 1, Price: 1500, Currency: "RMB", Date: end.AddDate(0, 0, -4)},
+		{Symbol: "AAPL", Action: "sell", Shares: 4, Price: 120, Currency: "USD", Date: end.AddDate(0, 0, -2)},
+		// After the snapshot
+		{Symbol: "AAPL", Action: "buy", Shares: 5, Price: 140, Currency: "USD", Date: end},
+	} {
+		if err := service.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+
+	// The middle of the day still sees that day's trades and close
+	snapshot, err := service.GetHoldingsSnapshot(context.Background(), userID, end.AddDate(0, 0, -2).Add(15*time.Hour), "USD")
+	if err != nil {
+		t.Fatalf("Failed to get snapshot: %v", err)
+	}
+	if len(snapshot.Holdings) != 2 {
+		t.Fatalf("Expected 2 holdings, got %+v", snapshot.Holdings)
+	}
+
+	// 6 shares at 120, then 1 share at 1700 CNY converted at that day's 0.14
+	apple, moutai := snapshot.Holdings[0], snapshot.Holdings[1]
+	if apple.Symbol != "AAPL" || apple.Shares != 6 || apple.CurrentValue != 720 || apple.CostBasis != 600 {
+		t.Errorf("Unexpected AAPL holding %+v", apple)
+	}
+	if moutai.CurrentValue != 238 || moutai.CostBasis != 210 {
+		t.Errorf("Expected Moutai at 238 with 210 cost, got %+v", moutai)
+	}
+	if snapshot.TotalValue != 958 || snapshot.TotalCostBasis != 810 {
+		t.Errorf("Expected totals 958 and 810, got %.2f and %.2f", snapshot.TotalValue, snapshot.TotalCostBasis)
+	}
+
+	// Before any trade there is nothing to report
+	empty, err := service.GetHoldingsSnapshot(context.Background(), userID, end.AddDate(0, 0, -10), "USD")
+	if err != nil || len(empty.Holdings) != 0 || empty.TotalValue != 0 {
+		t.Errorf("Expected an empty snapshot, got %+v, %v", empty, err)
+	}
+
+	if _, err := service.GetHoldingsSnapshot(context.Background(), userID, time.Now().AddDate(0, 0, 2), "USD"); !errors.Is(err, ErrFutureSnapshot) {
+		t.Errorf("Expected ErrFutureSnapshot, got %v", err)
+	}
+}
@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInviteeNotFound       = errors.New("no account exists for this email")
+	ErrCannotInviteSelf      = errors.New("cannot invite your own account")
+	ErrDuplicateAccountLink  = errors.New("account link already exists")
+	ErrAccountLinkNotFound   = errors.New("account link not found")
+	ErrAccountLinkNotPending = errors.New("account link is not pending")
+)
+
+// HouseholdMember summarizes one account included in a household view
+type HouseholdMember struct {
+	UserID     primitive.ObjectID `json:"userId"`
+	Email      string             `json:"email"`
+	Self       bool               `json:"self"`
+	TotalValue float64            `json:"totalValue"`
+	Percentage float64            `json:"percentage"`
+}
+
+// HouseholdDashboard represents dashboard metrics merged across linked accounts
+type HouseholdDashboard struct {
+	DashboardMetrics
+	Members []HouseholdMember `json:"members"`
+}
+
+// HouseholdPerformance represents a value series merged across linked accounts
+type HouseholdPerformance struct {
+	Data     []PerformanceDataPoint `json:"data"`
+	Members  []HouseholdMember      `json:"members"`
+	Period   string                 `json:"period"`
+	Currency string                 `json:"currency"`
+}
+
+// householdAccount identifies an account whose data a user may read
+type householdAccount struct {
+	UserID primitive.ObjectID
+	Email  string
+	Self   bool
+}
+
+// HouseholdService handles account links and the aggregated household view
+type HouseholdService struct {
+	analyticsService *AnalyticsService
+}
+
+// NewHouseholdService creates a new HouseholdService instance
+func NewHouseholdService(analyticsService *AnalyticsService) *HouseholdService {
+	return &HouseholdService{
+		analyticsService: analyticsService,
+	}
+}
+
+// Invite grants the account with the given email read-only access to the
+// owner's data, pending acceptance by that account
+func (s *HouseholdService) Invite(ownerID primitive.ObjectID, email string) (*models.AccountLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	users := database.Database.Collection("users")
+
+	var owner models.User
+	if err := users.FindOne(ctx, bson.M{"_id": ownerID}).Decode(&owner); err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	var viewer models.User
+	err := users.FindOne(ctx, bson.M{"email": strings.TrimSpace(email)}).Decode(&viewer)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInviteeNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if viewer.ID == ownerID {
+		return nil, ErrCannotInviteSelf
+	}
+
+	link := &models.AccountLink{
+		ID:          primitive.NewObjectID(),
+		OwnerID:     ownerID,
+		OwnerEmail:  owner.Email,
+		ViewerID:    viewer.ID,
+		ViewerEmail: viewer.Email,
+		Status:      models.AccountLinkPending,
+		CreatedAt:   time.Now(),
+	}
+
+	_, err = database.Database.Collection("account_links").InsertOne(ctx, link)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrDuplicateAccountLink
+		}
+		return nil, fmt.Errorf("failed to create account link: %w", err)
+	}
+
+	return link, nil
+}
+
+// AcceptInvite accepts a pending invite addressed to the viewer
+func (s *HouseholdService) AcceptInvite(viewerID primitive.ObjectID, linkID primitive.ObjectID) (*models.AccountLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("account_links")
+
+	var link models.AccountLink
+	err := collection.FindOne(ctx, bson.M{"_id": linkID, "viewer_id": viewerID}).Decode(&link)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAccountLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch account link: %w", err)
+	}
+	if link.Status != models.AccountLinkPending {
+		return nil, ErrAccountLinkNotPending
+	}
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"status": models.AccountLinkAccepted, "accepted_at": now}}
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": linkID}, update); err != nil {
+		return nil, fmt.Errorf("failed to accept account link: %w", err)
+	}
+
+	link.Status = models.AccountLinkAccepted
+	link.AcceptedAt = &now
+	return &link, nil
+}
+
+// GetLinks returns the links a user has granted to others and those granted to the user
+func (s *HouseholdService) GetLinks(userID primitive.ObjectID) (granted []models.AccountLink, received []models.AccountLink, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("account_links")
+
+	filter := bson.M{"$or": bson.A{bson.M{"owner_id": userID}, bson.M{"viewer_id": userID}}}
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch account links: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var links []models.AccountLink
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode account links: %w", err)
+	}
+
+	granted = []models.AccountLink{}
+	received = []models.AccountLink{}
+	for _, link := range links {
+		if link.OwnerID == userID {
+			granted = append(granted, link)
+		} else {
+			received = append(received, link)
+		}
+	}
+
+	return granted, received, nil
+}
+
+// RemoveLink deletes an account link. Either the owner or the viewer may remove it.
+func (s *HouseholdService) RemoveLink(userID primitive.ObjectID, linkID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("account_links")
+
+	filter := bson.M{
+		"_id": linkID,
+		"$or": bson.A{bson.M{"owner_id": userID}, bson.M{"viewer_id": userID}},
+	}
+	result, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to delete account link: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrAccountLinkNotFound
+	}
+
+	return nil
+}
+
+// householdAccounts returns the user's own account followed by every account
+// that has granted the user accepted read-only access
+func (s *HouseholdService) householdAccounts(userID primitive.ObjectID) ([]householdAccount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var self models.User
+	if err := database.Database.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&self); err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	accounts := []householdAccount{{UserID: userID, Email: self.Email, Self: true}}
+
+	filter := bson.M{"viewer_id": userID, "status": models.AccountLinkAccepted}
+	cursor, err := database.Database.Collection("account_links").Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch account links: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var links []models.AccountLink
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, fmt.Errorf("failed to decode account links: %w", err)
+	}
+	for _, link := range links {
+		accounts = append(accounts, householdAccount{UserID: link.OwnerID, Email: link.OwnerEmail})
+	}
+
+	return accounts, nil
+}
+
+// GetHouseholdDashboard returns dashboard metrics merged across the user's
+// account and every account linked to it
+func (s *HouseholdService) GetHouseholdDashboard(userID primitive.ObjectID, currency string) (*HouseholdDashboard, error) {
+	accounts, err := s.householdAccounts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make([]*DashboardMetrics, 0, len(accounts))
+	for _, account := range accounts {
+		m, err := s.analyticsService.GetDashboardMetrics(account.UserID, currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate dashboard for %s: %w", account.Email, err)
+		}
+		metrics = append(metrics, m)
+	}
+
+	return mergeHouseholdDashboards(accounts, metrics), nil
+}
+
+// mergeHouseholdDashboards combines per-account dashboards. Allocation entries
+// for the same symbol are summed and percentages are recomputed on the total.
+func mergeHouseholdDashboards(accounts []householdAccount, metrics []*DashboardMetrics) *HouseholdDashboard {
+	household := &HouseholdDashboard{
+		DashboardMetrics: DashboardMetrics{Allocation: []AllocationItem{}},
+		Members:          make([]HouseholdMember, 0, len(accounts)),
+	}
+
+	totalCostBasis := 0.0
+	bySymbol := make(map[string]int)
+	for i, m := range metrics {
+		household.Currency = m.Currency
+		household.TotalValue += m.TotalValue
+		household.TotalGain += m.TotalGain
+		household.DayChange += m.DayChange
+		totalCostBasis += m.TotalValue - m.TotalGain
+
+		for _, item := range m.Allocation {
+			if index, exists := bySymbol[item.Symbol]; exists {
+				household.Allocation[index].Value += item.Value
+				continue
+			}
+			bySymbol[item.Symbol] = len(household.Allocation)
+			household.Allocation = append(household.Allocation, AllocationItem{
+				Symbol: item.Symbol,
+				Name:   item.Name,
+				Value:  item.Value,
+			})
+		}
+
+		household.Members = append(household.Members, HouseholdMember{
+			UserID:     accounts[i].UserID,
+			Email:      accounts[i].Email,
+			Self:       accounts[i].Self,
+			TotalValue: m.TotalValue,
+		})
+	}
+
+	if household.TotalValue > 0 {
+		for i := range household.Allocation {
+			household.Allocation[i].Percentage = household.Allocation[i].Value / household.TotalValue * 100
+		}
+		for i := range household.Members {
+			household.Members[i].Percentage = household.Members[i].TotalValue / household.TotalValue * 100
+		}
+	}
+	sort.Slice(household.Allocation, func(i, j int) bool {
+		return household.Allocation[i].Value > household.Allocation[j].Value
+	})
+
+	if totalCostBasis > 0 {
+		household.PercentageReturn = household.TotalGain / totalCostBasis * 100
+	}
+	if previousValue := household.TotalValue - household.DayChange; previousValue > 0 {
+		household.DayChangePercent = household.DayChange / previousValue * 100
+	}
+
+	return household
+}
+
+// GetHouseholdPerformance returns the historical value series summed across
+// the user's account and every account linked to it
+func (s *HouseholdService) GetHouseholdPerformance(userID primitive.ObjectID, period string, currency string) (*HouseholdPerformance, error) {
+	accounts, err := s.householdAccounts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	series := make([][]PerformanceDataPoint, 0, len(accounts))
+	members := make([]HouseholdMember, 0, len(accounts))
+	for _, account := range accounts {
+		points, err := s.analyticsService.GetHistoricalPerformance(account.UserID, period, currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate performance for %s: %w", account.Email, err)
+		}
+		series = append(series, points)
+
+		member := HouseholdMember{UserID: account.UserID, Email: account.Email, Self: account.Self}
+		if len(points) > 0 {
+			member.TotalValue = points[len(points)-1].Value
+		}
+		members = append(members, member)
+	}
+
+	data := mergePerformanceSeries(series)
+	if len(data) > 0 {
+		total := data[len(data)-1].Value
+		for i := range members {
+			if total > 0 {
+				members[i].Percentage = members[i].TotalValue / total * 100
+			}
+		}
+	}
+
+	currency = currencycode.Normalize(currency)
+
+	return &HouseholdPerformance{
+		Data:     data,
+		Members:  members,
+		Period:   period,
+		Currency: currency,
+	}, nil
+}
+
+// mergePerformanceSeries sums value series by calendar day. A series without
+// a point on a given day contributes its latest earlier value.
+func mergePerformanceSeries(series [][]PerformanceDataPoint) []PerformanceDataPoint {
+	dateMap := make(map[string]time.Time)
+	for _, points := range series {
+		for _, point := range points {
+			dateKey := point.Date.Format("2006-01-02")
+			if existing, exists := dateMap[dateKey]; !exists || point.Date.Before(existing) {
+				dateMap[dateKey] = point.Date
+			}
+		}
+	}
+
+	dateKeys := make([]string, 0, len(dateMap))
+	for dateKey := range dateMap {
+		dateKeys = append(dateKeys, dateKey)
+	}
+	sort.Strings(dateKeys)
+
+	positions := make([]int, len(series))
+	merged := make([]PerformanceDataPoint, 0, len(dateKeys))
+	for _, dateKey := range dateKeys {
+		value := 0.0
+		for i, points := range series {
+			// Advance to the latest point on or before this day
+			for positions[i] < len(points) && points[positions[i]].Date.Format("2006-01-02") <= dateKey {
+				positions[i]++
+			}
+			if positions[i] > 0 {
+				value += points[positions[i]-1].Value
+			}
+		}
+		merged = append(merged, PerformanceDataPoint{
+			Date:  dateMap[dateKey],
+			Value: value,
+		})
+	}
+
+	applyPerformanceReturns(merged)
+	return merged
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMergeHouseholdDashboards(t *testing.T) {
+	accounts := []householdAccount{
+		{UserID: primitive.NewObjectID(), Email: "me@example.com", Self: true},
+		{UserID: primitive.NewObjectID(), Email: "partner@example.com"},
+	}
+	metrics := []*DashboardMetrics{
+		{
+			TotalValue: 3000, TotalGain: 1000, DayChange: 30, Currency: "USD",
+			Allocation: []AllocationItem{
+				{Symbol: "AAPL", Name: "Apple Inc.", Value: 2000},
+				{Symbol: "MSFT", Name: "Microsoft", Value: 1000},
+			},
+		},
+		{
+			TotalValue: 1000, TotalGain: 0, DayChange: -10, Currency: "USD",
+			Allocation: []AllocationItem{
+				{Symbol: "MSFT", Name: "Microsoft", Value: 1000},
+			},
+		},
+	}
+
+	household := mergeHouseholdDashboards(accounts, metrics)
+
+	if household.TotalValue != 4000 || household.TotalGain != 1000 {
+		t.Errorf("Unexpected totals: value %.2f gain %.2f", household.TotalValue, household.TotalGain)
+	}
+	if math.Abs(household.PercentageReturn-100.0/3) > 1e-9 {
+		t.Errorf("Expected return on combined cost basis, got %.4f", household.PercentageReturn)
+	}
+	if math.Abs(household.DayChangePercent-20.0/3980*100) > 1e-9 {
+		t.Errorf("Unexpected day change percent %.4f", household.DayChangePercent)
+	}
+	if len(household.Allocation) != 2 {
+		t.Fatalf("Expected MSFT to be merged, got %+v", household.Allocation)
+	}
+	for _, item := range household.Allocation {
+		if item.Value != 2000 || item.Percentage != 50 {
+			t.Errorf("Unexpected allocation %+v", item)
+		}
+	}
+	if household.Members[0].Percentage != 75 || household.Members[1].Percentage != 25 {
+		t.Errorf("Unexpected member weights %+v", household.Members)
+	}
+}
+
+func TestMergePerformanceSeries(t *testing.T) {
+	day := func(d int, hour int) time.Time {
+		return time.Date(2024, 3, d, hour, 0, 0, 0, time.UTC)
+	}
+	us := []PerformanceDataPoint{
+		{Date: day(1, 21), Value: 100},
+		{Date: day(2, 21), Value: 110},
+		{Date: day(4, 21), Value: 120},
+	}
+	china := []PerformanceDataPoint{
+		{Date: day(2, 7), Value: 50},
+		{Date: day(3, 7), Value: 60},
+	}
+
+	merged := mergePerformanceSeries([][]PerformanceDataPoint{us, china})
+
+	want := []float64{100, 160, 170, 180}
+	if len(merged) != len(want) {
+		t.Fatalf("Expected %d points, got %d", len(want), len(merged))
+	}
+	for i, value := range want {
+		if merged[i].Value != value {
+			t.Errorf("Point %d: expected %.0f, got %.0f", i, value, merged[i].Value)
+		}
+	}
+	if merged[1].Date != day(2, 7) {
+		t.Errorf("Expected the earliest timestamp of the day, got %v", merged[1].Date)
+	}
+	if merged[3].PercentageReturn != 80 || merged[2].DayChange != 10 {
+		t.Errorf("Expected returns to be recomputed, got %+v", merged[3])
+	}
+}
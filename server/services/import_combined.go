@@ -0,0 +1,204 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/money"
+	"strings"
+	"time"
+)
+
+// combinedParser reads the generic multi-leg CSV format, where one file mixes
+// trades with dividends, fees, interest, deposits and withdrawals:
+//
+//	Date,Type,Symbol,Quantity,Price,Fees,Amount,Currency,Description,ID
+//
+// Cash movements become buys and sells of the currency's cash symbol at a
+// price of 1. Every trade also gets an offsetting cash leg, so the imported
+// cash balance follows the statement. Amount is the signed cash effect of the
+// row; for trades it may be left empty and is then derived from quantity,
+// price and fees. The optional ID column is the row's unique ID, which lets
+// the file be imported again without duplicating rows.
+type combinedParser struct{}
+
+func (combinedParser) Broker() string {
+	return models.BrokerCombined
+}
+
+func (combinedParser) Detect(data []byte) bool {
+	records, err := readCSVRecords(data)
+	return err == nil && findCSVHeader(records, "date", "type", "symbol", "quantity", "price", "amount", "currency") >= 0
+}
+
+func (combinedParser) Parse(data []byte) (*ParsedStatement, error) {
+	records, err := readCSVRecords(data)
+	if err != nil {
+		return nil, err
+	}
+	headerIndex := findCSVHeader(records, "date", "type", "amount")
+	if headerIndex < 0 {
+		return nil, ErrUnrecognizedFormat
+	}
+	columns := newCSVColumns(records[headerIndex])
+
+	statement := &ParsedStatement{Broker: models.BrokerCombined, Trades: []ImportedTrade{}, Skipped: []SkippedStatementRow{}}
+	for i := headerIndex + 1; i < len(records); i++ {
+		record := records[i]
+		line := i + 1
+
+		dateValue := columns.get(record, "date")
+		if dateValue == "" {
+			continue
+		}
+		date, err := parseStatementDate(dateValue, "2006-01-02", "01/02/2006", "1/2/2006")
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+		currency, ok := statementCurrency(columns.get(record, "currency"))
+		if !ok {
+			statement.skip(line, "unsupported currency %q", columns.get(record, "currency"))
+			continue
+		}
+		amount, err := parseStatementNumber(columns.get(record, "amount"))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+
+		row := combinedRow{
+			line:        line,
+			date:        date,
+			currency:    currency,
+			symbol:      strings.ToUpper(columns.get(record, "symbol")),
+			amount:      amount,
+			description: columns.get(record, "description"),
+			id:          columns.get(record, "id"),
+		}
+
+		kind := strings.ToLower(columns.get(record, "type"))
+		switch kind {
+		case "buy", "sell":
+			shares, err := parseStatementNumber(columns.get(record, "quantity"))
+			if err != nil {
+				statement.skip(line, "%v", err)
+				continue
+			}
+			price, err := parseStatementNumber(columns.get(record, "price"))
+			if err != nil {
+				statement.skip(line, "%v", err)
+				continue
+			}
+			fees, err := parseStatementNumber(columns.get(record, "fees"))
+			if err != nil {
+				statement.skip(line, "%v", err)
+				continue
+			}
+			row.addTrade(statement, kind, math.Abs(shares), price, math.Abs(fees))
+		case "dividend":
+			row.addCash(statement, "buy", models.CashDividendTag, "Dividend")
+		case "interest":
+			row.addCash(statement, "buy", models.CashInterestTag, "Interest")
+		case "deposit":
+			row.addCash(statement, "buy", models.CashDepositTag, "Deposit")
+		case "withdrawal":
+			row.addCash(statement, "sell", models.CashWithdrawalTag, "Withdrawal")
+		case "fee", "tax":
+			row.addCash(statement, "sell", models.CashFeeTag, strings.ToUpper(kind[:1])+kind[1:])
+		default:
+			statement.skip(line, "unknown row type %q", columns.get(record, "type"))
+		}
+	}
+
+	return statement, nil
+}
+
+// combinedRow holds the fields shared by every row type of a combined statement
+type combinedRow struct {
+	line        int
+	date        time.Time
+	currency    string
+	symbol      string
+	amount      float64
+	description string
+	id          string
+}
+
+// cashSymbol returns the cash symbol the row settles in
+func (r combinedRow) cashSymbol() string {
+	return "CASH_" + r.currency
+}
+
+// addTrade records a trade and the cash leg that pays for it or receives its
+// proceeds. The cash leg is only added when the trade itself is valid.
+func (r combinedRow) addTrade(statement *ParsedStatement, action string, shares, price, fees float64) {
+	before := len(statement.Trades)
+	statement.addTrade(ImportedTrade{
+		Line:        r.line,
+		Symbol:      r.symbol,
+		Action:      action,
+		Shares:      shares,
+		Price:       price,
+		Fees:        fees,
+		Currency:    r.currency,
+		Date:        r.date,
+		Description: r.description,
+		ExternalID:  r.id,
+	})
+	if len(statement.Trades) == before || strings.HasPrefix(r.symbol, "CASH_") {
+		return
+	}
+
+	cash := math.Abs(r.amount)
+	if cash == 0 {
+		cash = shares * price
+		if action == "buy" {
+			cash += fees
+		} else {
+			cash -= fees
+		}
+	}
+	cashAction := "sell"
+	if action == "sell" {
+		cashAction = "buy"
+	}
+	leg := r.cashLeg(cashAction, cash, models.CashTradeTag,
+		fmt.Sprintf("%s %g %s", strings.ToUpper(action[:1])+action[1:], shares, r.symbol))
+	if r.id != "" {
+		leg.ExternalID = r.id + "/cash"
+	}
+	statement.addTrade(leg)
+}
+
+// addCash records a cash movement such as a dividend or withdrawal. The note
+// names the paying or charging symbol when the row has one.
+func (r combinedRow) addCash(statement *ParsedStatement, action, tag, label string) {
+	if r.amount == 0 {
+		statement.skip(r.line, "amount is missing")
+		return
+	}
+	note := label
+	if r.symbol != "" {
+		note = fmt.Sprintf("%s: %s", label, r.symbol)
+	}
+	statement.addTrade(r.cashLeg(action, math.Abs(r.amount), tag, note))
+}
+
+// cashLeg builds a buy or sell of the row's cash symbol, rounded to the
+// currency's minor unit
+func (r combinedRow) cashLeg(action string, amount float64, tag, note string) ImportedTrade {
+	return ImportedTrade{
+		Line:        r.line,
+		Symbol:      r.cashSymbol(),
+		Action:      action,
+		Shares:      money.Round(amount, r.currency),
+		Price:       1,
+		Currency:    r.currency,
+		Date:        r.date,
+		Description: r.description,
+		Note:        note,
+		Tags:        []string{tag},
+		ExternalID:  r.id,
+	}
+}
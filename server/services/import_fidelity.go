@@ -0,0 +1,90 @@
+package services
+
+import (
+	"math"
+	"stock-portfolio-tracker/models"
+	"strings"
+)
+
+// fidelityParser reads Fidelity account history CSV exports
+type fidelityParser struct{}
+
+func (fidelityParser) Broker() string {
+	return models.BrokerFidelity
+}
+
+func (fidelityParser) Detect(data []byte) bool {
+	records, err := readCSVRecords(data)
+	return err == nil && findCSVHeader(records, "run date", "action", "symbol", "quantity", "price ($)") >= 0
+}
+
+func (fidelityParser) Parse(data []byte) (*ParsedStatement, error) {
+	records, err := readCSVRecords(data)
+	if err != nil {
+		return nil, err
+	}
+	headerIndex := findCSVHeader(records, "run date", "action", "symbol", "quantity", "price ($)")
+	if headerIndex < 0 {
+		return nil, ErrUnrecognizedFormat
+	}
+	columns := newCSVColumns(records[headerIndex])
+
+	statement := &ParsedStatement{Broker: models.BrokerFidelity, Trades: []ImportedTrade{}, Skipped: []SkippedStatementRow{}}
+	for i := headerIndex + 1; i < len(records); i++ {
+		record := records[i]
+		line := i + 1
+
+		// The export ends with free-text disclaimers that have no date
+		date, err := parseStatementDate(columns.get(record, "run date"), "01/02/2006", "1/2/2006")
+		if err != nil {
+			continue
+		}
+
+		description := columns.get(record, "action")
+		var action string
+		switch upper := strings.ToUpper(description); {
+		case strings.HasPrefix(upper, "YOU BOUGHT"), strings.HasPrefix(upper, "REINVESTMENT"):
+			action = "buy"
+		case strings.HasPrefix(upper, "YOU SOLD"):
+			action = "sell"
+		default:
+			statement.skip(line, "%q is not a trade", description)
+			continue
+		}
+
+		shares, err := parseStatementNumber(columns.get(record, "quantity"))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+		price, err := parseStatementNumber(columns.get(record, "price ($)"))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+		commission, err := parseStatementNumber(columns.get(record, "commission ($)"))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+		fees, err := parseStatementNumber(columns.get(record, "fees ($)"))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+
+		statement.addTrade(ImportedTrade{
+			Line:        line,
+			Symbol:      columns.get(record, "symbol"),
+			Action:      action,
+			Shares:      math.Abs(shares), // Sells are reported as negative quantities
+			Price:       price,
+			Fees:        math.Abs(commission) + math.Abs(fees),
+			Currency:    "USD",
+			Date:        date,
+			Description: description,
+		})
+	}
+
+	return statement, nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"stock-portfolio-tracker/models"
+	"strings"
+	"unicode"
+)
+
+// futuParser reads Futu / moomoo order history CSV exports in English or Chinese
+type futuParser struct{}
+
+// Futu column names in English and Chinese exports
+var (
+	futuSideColumns     = []string{"side", "方向"}
+	futuSymbolColumns   = []string{"symbol", "代码"}
+	futuNameColumns     = []string{"name", "名称"}
+	futuQuantityColumns = []string{"fill qty", "filled qty", "成交数量"}
+	futuPriceColumns    = []string{"fill price", "avg fill price", "成交价格", "成交均价"}
+	futuTimeColumns     = []string{"fill time", "filled time", "成交时间"}
+	futuMarketColumns   = []string{"market", "市场"}
+	futuCurrencyColumns = []string{"currency", "币种"}
+	futuFeeColumns      = []string{"total fees", "fees", "合计费用", "合计"}
+)
+
+// futuHeaderIndex finds the header row of an English or Chinese export
+func futuHeaderIndex(records [][]string) int {
+	if index := findCSVHeader(records, "side", "symbol", "fill qty"); index >= 0 {
+		return index
+	}
+	if index := findCSVHeader(records, "side", "symbol", "filled qty"); index >= 0 {
+		return index
+	}
+	return findCSVHeader(records, "方向", "代码", "成交数量")
+}
+
+func (futuParser) Broker() string {
+	return models.BrokerFutu
+}
+
+func (futuParser) Detect(data []byte) bool {
+	records, err := readCSVRecords(data)
+	return err == nil && futuHeaderIndex(records) >= 0
+}
+
+func (futuParser) Parse(data []byte) (*ParsedStatement, error) {
+	records, err := readCSVRecords(data)
+	if err != nil {
+		return nil, err
+	}
+	headerIndex := futuHeaderIndex(records)
+	if headerIndex < 0 {
+		return nil, ErrUnrecognizedFormat
+	}
+	columns := newCSVColumns(records[headerIndex])
+
+	statement := &ParsedStatement{Broker: models.BrokerFutu, Trades: []ImportedTrade{}, Skipped: []SkippedStatementRow{}}
+	for i := headerIndex + 1; i < len(records); i++ {
+		record := records[i]
+		line := i + 1
+
+		var action string
+		switch side := columns.get(record, futuSideColumns...); strings.ToLower(side) {
+		case "buy", "买入":
+			action = "buy"
+		case "sell", "卖出":
+			action = "sell"
+		case "":
+			continue
+		default:
+			statement.skip(line, "unsupported side %q", side)
+			continue
+		}
+
+		quantity, err := parseStatementNumber(columns.get(record, futuQuantityColumns...))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+		if quantity == 0 {
+			statement.skip(line, "order was not filled")
+			continue
+		}
+
+		symbol, ok := futuSymbol(columns.get(record, futuSymbolColumns...), columns.get(record, futuMarketColumns...))
+		if !ok {
+			statement.skip(line, "unsupported market for %q", columns.get(record, futuSymbolColumns...))
+			continue
+		}
+
+		currency := "USD"
+		if strings.HasSuffix(symbol, ".SS") || strings.HasSuffix(symbol, ".SZ") {
+			currency = "RMB"
+		}
+		if code := columns.get(record, futuCurrencyColumns...); code != "" {
+			if currency, ok = statementCurrency(code); !ok {
+				statement.skip(line, "unsupported currency %q", code)
+				continue
+			}
+		}
+
+		date, err := parseStatementDate(stripTimeZone(columns.get(record, futuTimeColumns...)),
+			"2006/01/02 15:04:05", "2006-01-02 15:04:05", "2006/01/02 15:04", "2006-01-02 15:04",
+			"2006/01/02", "2006-01-02", "Jan 2, 2006 15:04:05", "Jan 2, 2006")
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+
+		price, err := parseStatementNumber(columns.get(record, futuPriceColumns...))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+		fees, err := parseStatementNumber(columns.get(record, futuFeeColumns...))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+
+		statement.addTrade(ImportedTrade{
+			Line:        line,
+			Symbol:      symbol,
+			Action:      action,
+			Shares:      quantity,
+			Price:       price,
+			Fees:        fees,
+			Currency:    currency,
+			Date:        date,
+			Description: columns.get(record, futuNameColumns...),
+		})
+	}
+
+	return statement, nil
+}
+
+// futuSymbol maps a Futu code and market to a supported symbol. Futu lists
+// A-shares by their six-digit code; Hong Kong listings are not supported.
+func futuSymbol(code string, market string) (string, bool) {
+	code = strings.TrimSpace(code)
+	// Some exports prefix the market, e.g. "US.AAPL" or "SH.600519"
+	if index := strings.Index(code, "."); index > 0 && market == "" {
+		market, code = code[:index], code[index+1:]
+	}
+
+	switch strings.ToUpper(market) {
+	case "HK", "港股":
+		return "", false
+	case "SH", "SZ", "A股", "沪深":
+		return chinaSymbol(code, market)
+	}
+	if symbol, ok := chinaSymbol(code, market); ok {
+		return symbol, true
+	}
+	// Five-digit numeric codes are Hong Kong listings
+	if strings.IndexFunc(code, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+		return "", false
+	}
+	return code, code != ""
+}
+
+// stripTimeZone removes a trailing zone abbreviation such as "ET" or "HKT"
+func stripTimeZone(value string) string {
+	value = strings.TrimSpace(value)
+	if index := strings.LastIndex(value, " "); index > 0 {
+		zone := value[index+1:]
+		if strings.IndexFunc(zone, func(r rune) bool { return !unicode.IsUpper(r) }) < 0 {
+			return value[:index]
+		}
+	}
+	return value
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"math"
+	"stock-portfolio-tracker/models"
+	"strings"
+)
+
+// ibkrParser reads Interactive Brokers activity statement CSV exports. The
+// file is a sequence of sections, each row starting with the section name and
+// a Header or Data marker.
+type ibkrParser struct{}
+
+func (ibkrParser) Broker() string {
+	return models.BrokerIBKR
+}
+
+func (ibkrParser) Detect(data []byte) bool {
+	records, err := readCSVRecords(data)
+	if err != nil {
+		return false
+	}
+	for _, record := range records {
+		if len(record) > 2 && (record[0] == "Statement" || record[0] == "Trades") && record[1] == "Header" {
+			return true
+		}
+	}
+	return false
+}
+
+func (ibkrParser) Parse(data []byte) (*ParsedStatement, error) {
+	records, err := readCSVRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &ParsedStatement{Broker: models.BrokerIBKR, Trades: []ImportedTrade{}, Skipped: []SkippedStatementRow{}}
+	var columns csvColumns
+	for i, record := range records {
+		line := i + 1
+		if len(record) < 2 || record[0] != "Trades" {
+			continue
+		}
+		if record[1] == "Header" {
+			columns = newCSVColumns(record[2:])
+			continue
+		}
+		if record[1] != "Data" || columns == nil {
+			continue
+		}
+		fields := record[2:]
+
+		// Only individual stock orders; subtotal rows and other asset classes are ignored
+		if discriminator := columns.get(fields, "datadiscriminator"); discriminator != "" && discriminator != "Order" {
+			continue
+		}
+		if category := columns.get(fields, "asset category"); category != "Stocks" {
+			statement.skip(line, "unsupported asset category %q", category)
+			continue
+		}
+
+		currency, ok := statementCurrency(columns.get(fields, "currency"))
+		if !ok {
+			statement.skip(line, "unsupported currency %q", columns.get(fields, "currency"))
+			continue
+		}
+
+		// Date/Time reads "2024-01-05, 10:30:00" or "2024-01-05;103000"
+		dateValue := columns.get(fields, "date/time")
+		if index := strings.IndexAny(dateValue, ",;"); index >= 0 {
+			dateValue = dateValue[:index]
+		}
+		date, err := parseStatementDate(dateValue, "2006-01-02", "20060102")
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+
+		quantity, err := parseStatementNumber(columns.get(fields, "quantity"))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+		price, err := parseStatementNumber(columns.get(fields, "t. price"))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+		fees, err := parseStatementNumber(columns.get(fields, "comm/fee", "comm in usd"))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+
+		action := "buy"
+		if quantity < 0 {
+			action = "sell"
+		}
+
+		symbol := columns.get(fields, "symbol")
+		if code, ok := chinaSymbol(symbol, ""); ok && currency == "RMB" {
+			symbol = code
+		}
+
+		statement.addTrade(ImportedTrade{
+			Line:     line,
+			Symbol:   strings.ReplaceAll(symbol, " ", "-"), // Share classes such as "BRK B"
+			Action:   action,
+			Shares:   math.Abs(quantity),
+			Price:    price,
+			Fees:     fees,
+			Currency: currency,
+			Date:     date,
+			// Only Flex Query exports carry a trade ID
+			ExternalID: columns.get(fields, "tradeid", "trade id", "transactionid"),
+		})
+	}
+
+	if columns == nil {
+		return nil, ErrUnrecognizedFormat
+	}
+	return statement, nil
+}
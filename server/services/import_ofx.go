@@ -0,0 +1,146 @@
+package services
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"stock-portfolio-tracker/models"
+	"strings"
+)
+
+// ofxParser reads OFX investment statements, which Schwab, Fidelity and most
+// US brokers offer as a Quicken / Money download. Both the SGML (v1) and XML
+// (v2) variants are accepted: aggregates are always closed, while leaf
+// elements may not be.
+type ofxParser struct{}
+
+// ofxTradeAggregates maps OFX investment transaction aggregates to actions
+var ofxTradeAggregates = map[string]string{
+	"BUYSTOCK":  "buy",
+	"BUYMF":     "buy",
+	"BUYOTHER":  "buy",
+	"REINVEST":  "buy",
+	"SELLSTOCK": "sell",
+	"SELLMF":    "sell",
+	"SELLOTHER": "sell",
+}
+
+// ofxAggregatePatterns matches each trade aggregate and its contents
+var ofxAggregatePatterns = func() map[string]*regexp.Regexp {
+	patterns := make(map[string]*regexp.Regexp, len(ofxTradeAggregates))
+	for name := range ofxTradeAggregates {
+		patterns[name] = regexp.MustCompile(`(?is)<` + name + `>(.*?)</` + name + `>`)
+	}
+	return patterns
+}()
+
+// ofxSecInfoPattern matches security definitions in the SECLIST
+var ofxSecInfoPattern = regexp.MustCompile(`(?is)<SECINFO>(.*?)</SECINFO>`)
+
+// ofxElementPatterns matches the leaf elements read from an OFX statement
+var ofxElementPatterns = func() map[string]*regexp.Regexp {
+	names := []string{"CURDEF", "CURSYM", "FITID", "UNIQUEID", "TICKER", "DTTRADE", "UNITS", "UNITPRICE", "COMMISSION", "FEES", "MEMO"}
+	patterns := make(map[string]*regexp.Regexp, len(names))
+	for _, name := range names {
+		patterns[name] = regexp.MustCompile(`(?i)<` + name + `>\s*([^<\r\n]*)`)
+	}
+	return patterns
+}()
+
+// ofxElement returns the value of the first leaf element with the given name
+func ofxElement(aggregate string, name string) string {
+	if match := ofxElementPatterns[name].FindStringSubmatch(aggregate); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+	return ""
+}
+
+func (ofxParser) Broker() string {
+	return models.BrokerOFX
+}
+
+func (ofxParser) Detect(data []byte) bool {
+	head := bytes.ToUpper(data[:min(len(data), 4096)])
+	return bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>"))
+}
+
+func (ofxParser) Parse(data []byte) (*ParsedStatement, error) {
+	content := string(data)
+	if !strings.Contains(strings.ToUpper(content), "<INVSTMTRS>") {
+		return nil, ErrUnrecognizedFormat
+	}
+
+	// Trades reference securities by CUSIP; the SECLIST maps them to tickers
+	tickers := make(map[string]string)
+	for _, match := range ofxSecInfoPattern.FindAllStringSubmatch(content, -1) {
+		if id, ticker := ofxElement(match[1], "UNIQUEID"), ofxElement(match[1], "TICKER"); id != "" && ticker != "" {
+			tickers[id] = ticker
+		}
+	}
+
+	defaultCurrency := ofxElement(content, "CURDEF")
+
+	statement := &ParsedStatement{Broker: models.BrokerOFX, Trades: []ImportedTrade{}, Skipped: []SkippedStatementRow{}}
+	for name, pattern := range ofxAggregatePatterns {
+		for _, match := range pattern.FindAllStringSubmatchIndex(content, -1) {
+			aggregate := content[match[2]:match[3]]
+			line := strings.Count(content[:match[0]], "\n") + 1
+
+			currency := ofxElement(aggregate, "CURSYM")
+			if currency == "" {
+				currency = defaultCurrency
+			}
+			transactionCurrency, ok := statementCurrency(currency)
+			if !ok {
+				statement.skip(line, "unsupported currency %q", currency)
+				continue
+			}
+
+			uniqueID := ofxElement(aggregate, "UNIQUEID")
+			symbol, ok := tickers[uniqueID]
+			if !ok {
+				statement.skip(line, "no ticker found for security %q", uniqueID)
+				continue
+			}
+
+			// DTTRADE reads YYYYMMDD, optionally followed by a time and zone
+			dateValue := ofxElement(aggregate, "DTTRADE")
+			if len(dateValue) > 8 {
+				dateValue = dateValue[:8]
+			}
+			date, err := parseStatementDate(dateValue, "20060102")
+			if err != nil {
+				statement.skip(line, "%v", err)
+				continue
+			}
+
+			units, err := parseStatementNumber(ofxElement(aggregate, "UNITS"))
+			if err != nil {
+				statement.skip(line, "%v", err)
+				continue
+			}
+			price, err := parseStatementNumber(ofxElement(aggregate, "UNITPRICE"))
+			if err != nil {
+				statement.skip(line, "%v", err)
+				continue
+			}
+			commission, _ := parseStatementNumber(ofxElement(aggregate, "COMMISSION"))
+			fees, _ := parseStatementNumber(ofxElement(aggregate, "FEES"))
+
+			statement.addTrade(ImportedTrade{
+				Line:        line,
+				Symbol:      symbol,
+				Action:      ofxTradeAggregates[strings.ToUpper(name)],
+				Shares:      math.Abs(units), // Sells are reported as negative units
+				Price:       price,
+				Fees:        math.Abs(commission) + math.Abs(fees),
+				Currency:    transactionCurrency,
+				Date:        date,
+				Description: ofxElement(aggregate, "MEMO"),
+				ExternalID:  ofxElement(aggregate, "FITID"),
+			})
+		}
+	}
+
+	return statement, nil
+}
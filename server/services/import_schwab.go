@@ -0,0 +1,93 @@
+package services
+
+import (
+	"stock-portfolio-tracker/models"
+	"strings"
+)
+
+// schwabParser reads Charles Schwab transaction history CSV exports
+type schwabParser struct{}
+
+func (schwabParser) Broker() string {
+	return models.BrokerSchwab
+}
+
+func (schwabParser) Detect(data []byte) bool {
+	records, err := readCSVRecords(data)
+	return err == nil && findCSVHeader(records, "date", "action", "symbol", "quantity", "price", "fees & comm") >= 0
+}
+
+func (schwabParser) Parse(data []byte) (*ParsedStatement, error) {
+	records, err := readCSVRecords(data)
+	if err != nil {
+		return nil, err
+	}
+	headerIndex := findCSVHeader(records, "date", "action", "symbol", "quantity", "price")
+	if headerIndex < 0 {
+		return nil, ErrUnrecognizedFormat
+	}
+	columns := newCSVColumns(records[headerIndex])
+
+	statement := &ParsedStatement{Broker: models.BrokerSchwab, Trades: []ImportedTrade{}, Skipped: []SkippedStatementRow{}}
+	for i := headerIndex + 1; i < len(records); i++ {
+		record := records[i]
+		line := i + 1
+
+		// The export ends with a "Transactions Total" row
+		dateValue := columns.get(record, "date")
+		if dateValue == "" || strings.HasPrefix(dateValue, "Transactions Total") {
+			continue
+		}
+
+		var action string
+		switch strings.ToLower(columns.get(record, "action")) {
+		case "buy", "reinvest shares":
+			action = "buy"
+		case "sell":
+			action = "sell"
+		default:
+			statement.skip(line, "%q is not a trade", columns.get(record, "action"))
+			continue
+		}
+
+		// Corrected trades read "01/05/2024 as of 01/04/2024"; the as-of date is the trade date
+		if index := strings.Index(dateValue, " as of "); index >= 0 {
+			dateValue = dateValue[index+len(" as of "):]
+		}
+		date, err := parseStatementDate(dateValue, "01/02/2006", "1/2/2006")
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+
+		shares, err := parseStatementNumber(columns.get(record, "quantity"))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+		price, err := parseStatementNumber(columns.get(record, "price"))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+		fees, err := parseStatementNumber(columns.get(record, "fees & comm"))
+		if err != nil {
+			statement.skip(line, "%v", err)
+			continue
+		}
+
+		statement.addTrade(ImportedTrade{
+			Line:        line,
+			Symbol:      columns.get(record, "symbol"),
+			Action:      action,
+			Shares:      shares,
+			Price:       price,
+			Fees:        fees,
+			Currency:    "USD",
+			Date:        date,
+			Description: columns.get(record, "description"),
+		})
+	}
+
+	return statement, nil
+}
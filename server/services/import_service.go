@@ -0,0 +1,549 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrUnsupportedBroker   = errors.New("unsupported broker")
+	ErrUnrecognizedFormat  = errors.New("statement format not recognized")
+	ErrEmptyStatement      = errors.New("statement contains no trades")
+	ErrStatementTooLarge   = errors.New("statement is too large")
+	ErrBrokerNotDetectable = errors.New("could not detect the broker of this statement")
+)
+
+// MaxStatementSize bounds the size of an uploaded broker statement. Uploads
+// are also subject to the global 1MB request body limit.
+const MaxStatementSize = 1 << 20
+
+// ImportedTrade is a trade read from a broker statement
+type ImportedTrade struct {
+	Line        int       `json:"line"`
+	Symbol      string    `json:"symbol"`
+	Action      string    `json:"action"`
+	Shares      float64   `json:"shares"`
+	Price       float64   `json:"price"`
+	Fees        float64   `json:"fees"`
+	Currency    string    `json:"currency"`
+	Date        time.Time `json:"date"`
+	Description string    `json:"description,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	// ExternalID is the broker's ID for the row, when the format has one
+	ExternalID string `json:"externalId,omitempty"`
+}
+
+// SkippedStatementRow records a statement row that did not produce a trade
+type SkippedStatementRow struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// ParsedStatement is the result of parsing a broker statement
+type ParsedStatement struct {
+	Broker  string                `json:"broker"`
+	Trades  []ImportedTrade       `json:"trades"`
+	Skipped []SkippedStatementRow `json:"skipped"`
+}
+
+// skip records a row that was not imported
+func (p *ParsedStatement) skip(line int, format string, args ...interface{}) {
+	p.Skipped = append(p.Skipped, SkippedStatementRow{Line: line, Reason: fmt.Sprintf(format, args...)})
+}
+
+// addTrade records a trade, or skips it when it cannot become a valid transaction
+func (p *ParsedStatement) addTrade(trade ImportedTrade) {
+	switch {
+	case trade.Symbol == "":
+		p.skip(trade.Line, "missing symbol")
+	case trade.Shares <= 0:
+		p.skip(trade.Line, "quantity must be positive")
+	case trade.Price <= 0:
+		p.skip(trade.Line, "price must be positive")
+	default:
+		trade.Symbol = strings.ToUpper(trade.Symbol)
+		trade.Fees = math.Abs(trade.Fees)
+		p.Trades = append(p.Trades, trade)
+	}
+}
+
+// StatementParser parses one broker's statement export into trades
+type StatementParser interface {
+	// Broker returns the identifier used in the broker request parameter
+	Broker() string
+	// Detect reports whether the statement looks like this broker's format
+	Detect(data []byte) bool
+	// Parse reads trades from the statement
+	Parse(data []byte) (*ParsedStatement, error)
+}
+
+// statementParsers lists the supported formats in detection order
+var statementParsers = []StatementParser{
+	ofxParser{},
+	ibkrParser{},
+	schwabParser{},
+	fidelityParser{},
+	futuParser{},
+	combinedParser{},
+}
+
+// SupportedBrokers returns the identifiers of the supported statement formats
+func SupportedBrokers() []string {
+	brokers := make([]string, 0, len(statementParsers))
+	for _, parser := range statementParsers {
+		brokers = append(brokers, parser.Broker())
+	}
+	return brokers
+}
+
+// ParseStatement parses a broker statement. An empty broker detects the format.
+func ParseStatement(broker string, data []byte) (*ParsedStatement, error) {
+	if len(data) > MaxStatementSize {
+		return nil, ErrStatementTooLarge
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var parser StatementParser
+	broker = strings.ToLower(strings.TrimSpace(broker))
+	for _, candidate := range statementParsers {
+		if broker == "" && candidate.Detect(data) || broker == candidate.Broker() {
+			parser = candidate
+			break
+		}
+	}
+	if parser == nil {
+		if broker == "" {
+			return nil, ErrBrokerNotDetectable
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedBroker, broker)
+	}
+
+	statement, err := parser.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(statement.Trades) == 0 {
+		return nil, ErrEmptyStatement
+	}
+
+	sortImportedTrades(statement.Trades)
+	sort.Slice(statement.Skipped, func(i, j int) bool {
+		return statement.Skipped[i].Line < statement.Skipped[j].Line
+	})
+
+	return statement, nil
+}
+
+// sortImportedTrades puts trades in import order: by date, buys before
+// sells on the same day, so sells can be validated against earlier buys
+func sortImportedTrades(trades []ImportedTrade) {
+	sort.Slice(trades, func(i, j int) bool {
+		a, b := trades[i], trades[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if a.Action != b.Action {
+			return a.Action == "buy"
+		}
+		return a.Line < b.Line
+	})
+}
+
+// ImportPreviewRow is a parsed trade annotated with duplicate detection
+type ImportPreviewRow struct {
+	ImportedTrade
+	Duplicate   bool                `json:"duplicate"`
+	DuplicateOf *primitive.ObjectID `json:"duplicateOf,omitempty"`
+}
+
+// ImportPreview describes what committing a statement would do
+type ImportPreview struct {
+	Broker     string                `json:"broker"`
+	Trades     []ImportPreviewRow    `json:"trades"`
+	Skipped    []SkippedStatementRow `json:"skipped"`
+	NewCount   int                   `json:"newCount"`
+	Duplicates int                   `json:"duplicates"`
+}
+
+// ImportFailure records a trade that could not be saved
+type ImportFailure struct {
+	Line   int    `json:"line"`
+	Symbol string `json:"symbol"`
+	Reason string `json:"reason"`
+}
+
+// ImportResult summarizes a committed statement import
+type ImportResult struct {
+	Broker     string          `json:"broker"`
+	Imported   int             `json:"imported"`
+	Duplicates int             `json:"duplicates"`
+	Failed     []ImportFailure `json:"failed"`
+}
+
+// ImportService imports trades from broker statements
+type ImportService struct {
+	portfolioService *PortfolioService
+}
+
+// NewImportService creates a new ImportService instance
+func NewImportService(portfolioService *PortfolioService) *ImportService {
+	return &ImportService{
+		portfolioService: portfolioService,
+	}
+}
+
+// Preview parses a statement and flags trades that already exist, without saving anything
+func (s *ImportService) Preview(userID primitive.ObjectID, broker string, data []byte) (*ImportPreview, error) {
+	statement, err := ParseStatement(broker, data)
+	if err != nil {
+		return nil, err
+	}
+	return s.previewStatement(userID, statement)
+}
+
+// previewStatement flags the parsed trades that already exist
+func (s *ImportService) previewStatement(userID primitive.ObjectID, statement *ParsedStatement) (*ImportPreview, error) {
+	existing, err := s.existingTransactions(userID, statement.Broker, statement.Trades)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &ImportPreview{
+		Broker:  statement.Broker,
+		Trades:  markDuplicateTrades(statement.Broker, statement.Trades, existing),
+		Skipped: statement.Skipped,
+	}
+	for _, row := range preview.Trades {
+		if row.Duplicate {
+			preview.Duplicates++
+		} else {
+			preview.NewCount++
+		}
+	}
+
+	return preview, nil
+}
+
+// Commit imports every non-duplicate trade of a statement. Trades that fail
+// validation, such as sells exceeding the position, are reported and skipped.
+// Trades with a broker ID keep it, and a trade whose ID was imported
+// concurrently counts as a duplicate.
+func (s *ImportService) Commit(userID primitive.ObjectID, broker string, data []byte) (*ImportResult, error) {
+	preview, err := s.Preview(userID, broker, data)
+	if err != nil {
+		return nil, err
+	}
+	return s.commitPreview(userID, preview), nil
+}
+
+// ImportTrades imports trades fetched from a linked brokerage account like
+// the trades of a statement from broker. Trades must carry the broker's IDs,
+// so trades imported by an earlier sync are recognized even if edited since.
+func (s *ImportService) ImportTrades(userID primitive.ObjectID, broker string, trades []ImportedTrade) (*ImportResult, error) {
+	if len(trades) == 0 {
+		return &ImportResult{Broker: broker, Failed: []ImportFailure{}}, nil
+	}
+
+	sortImportedTrades(trades)
+	preview, err := s.previewStatement(userID, &ParsedStatement{Broker: broker, Trades: trades, Skipped: []SkippedStatementRow{}})
+	if err != nil {
+		return nil, err
+	}
+	return s.commitPreview(userID, preview), nil
+}
+
+// commitPreview saves the trades of a preview that are not duplicates
+func (s *ImportService) commitPreview(userID primitive.ObjectID, preview *ImportPreview) *ImportResult {
+	result := &ImportResult{
+		Broker:     preview.Broker,
+		Duplicates: preview.Duplicates,
+		Failed:     []ImportFailure{},
+	}
+	for _, row := range preview.Trades {
+		if row.Duplicate {
+			continue
+		}
+
+		tx := &models.Transaction{
+			Symbol:   row.Symbol,
+			Action:   row.Action,
+			Shares:   row.Shares,
+			Price:    row.Price,
+			Currency: row.Currency,
+			Fees:     row.Fees,
+			Date:     row.Date,
+			Note:     row.Note,
+			Tags:     row.Tags,
+		}
+		if row.ExternalID != "" {
+			tx.Broker = preview.Broker
+			tx.ExternalID = row.ExternalID
+		}
+		err := s.portfolioService.AddTransaction(userID, tx)
+		if errors.Is(err, repository.ErrDuplicate) {
+			result.Duplicates++
+			continue
+		}
+		if err != nil {
+			result.Failed = append(result.Failed, ImportFailure{Line: row.Line, Symbol: row.Symbol, Reason: err.Error()})
+			continue
+		}
+		result.Imported++
+	}
+
+	return result
+}
+
+// existingTransactions fetches the user's transactions that could duplicate
+// the given trades: those around the trades' dates and those imported from
+// the broker with the trades' IDs
+func (s *ImportService) existingTransactions(userID primitive.ObjectID, broker string, trades []ImportedTrade) ([]models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	symbols := make([]string, 0, len(trades))
+	seen := make(map[string]bool)
+	var ids []string
+	start, end := trades[0].Date, trades[0].Date
+	for _, trade := range trades {
+		if trade.ExternalID != "" {
+			ids = append(ids, trade.ExternalID)
+		}
+		if !seen[trade.Symbol] {
+			seen[trade.Symbol] = true
+			symbols = append(symbols, trade.Symbol)
+		}
+		if trade.Date.Before(start) {
+			start = trade.Date
+		}
+		if trade.Date.After(end) {
+			end = trade.Date
+		}
+	}
+
+	transactions, err := s.portfolioService.repos.Transactions.FindBySymbolsBetween(ctx, userID, symbols, start.AddDate(0, 0, -1), end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+	if len(ids) == 0 {
+		return transactions, nil
+	}
+
+	imported, err := s.portfolioService.repos.Transactions.FindByExternalIDs(ctx, userID, broker, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch imported transactions: %w", err)
+	}
+	fetched := make(map[primitive.ObjectID]bool, len(transactions))
+	for _, tx := range transactions {
+		fetched[tx.ID] = true
+	}
+	for _, tx := range imported {
+		if !fetched[tx.ID] {
+			transactions = append(transactions, tx)
+		}
+	}
+	return transactions, nil
+}
+
+// markDuplicateTrades flags trades matching an existing transaction. A trade
+// with a broker ID matches the transaction imported from the broker with that
+// ID; otherwise, and for trades without one, it matches a transaction with
+// the same details that has no broker ID. Each existing transaction matches
+// at most one trade, so identical fills within a statement are still
+// imported separately.
+func markDuplicateTrades(broker string, trades []ImportedTrade, existing []models.Transaction) []ImportPreviewRow {
+	used := make([]bool, len(existing))
+	find := func(match func(models.Transaction) bool) int {
+		for i, tx := range existing {
+			if !used[i] && match(tx) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	rows := make([]ImportPreviewRow, 0, len(trades))
+	for _, trade := range trades {
+		row := ImportPreviewRow{ImportedTrade: trade}
+		i := -1
+		if trade.ExternalID != "" {
+			i = find(func(tx models.Transaction) bool {
+				return tx.Broker == broker && tx.ExternalID == trade.ExternalID
+			})
+		}
+		if i < 0 {
+			i = find(func(tx models.Transaction) bool {
+				return tx.ExternalID == "" && sameTrade(trade, tx)
+			})
+		}
+		if i >= 0 {
+			used[i] = true
+			id := existing[i].ID
+			row.Duplicate = true
+			row.DuplicateOf = &id
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// sameTrade reports whether an imported trade matches an existing transaction
+func sameTrade(trade ImportedTrade, tx models.Transaction) bool {
+	return trade.Symbol == tx.Symbol &&
+		trade.Action == tx.Action &&
+		trade.Date.Format("2006-01-02") == tx.Date.UTC().Format("2006-01-02") &&
+		math.Abs(trade.Shares-tx.Shares) < 1e-6 &&
+		math.Abs(trade.Price-tx.Price) < 1e-4
+}
+
+// readCSVRecords reads every record of a CSV statement, tolerating rows with
+// differing field counts as broker exports mix sections and footers
+func readCSVRecords(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnrecognizedFormat, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// csvColumns maps normalized header names to column indexes
+type csvColumns map[string]int
+
+// newCSVColumns indexes a header row
+func newCSVColumns(header []string) csvColumns {
+	columns := make(csvColumns)
+	for i, name := range header {
+		columns[normalizeHeader(name)] = i
+	}
+	return columns
+}
+
+// normalizeHeader lowercases a header and strips whitespace and quotes
+func normalizeHeader(name string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(name), "\"\ufeff"))
+}
+
+// has reports whether every named column is present
+func (c csvColumns) has(names ...string) bool {
+	for _, name := range names {
+		if _, ok := c[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// get returns the value of the first present column among the names
+func (c csvColumns) get(record []string, names ...string) string {
+	for _, name := range names {
+		if i, ok := c[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+	}
+	return ""
+}
+
+// findCSVHeader returns the index of the first record that contains all of
+// the required columns, skipping title lines some brokers put first
+func findCSVHeader(records [][]string, required ...string) int {
+	for i, record := range records {
+		if newCSVColumns(record).has(required...) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseStatementNumber parses an amount such as "$1,234.50", "(12.00)" or "--"
+func parseStatementNumber(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "--" || value == "-" {
+		return 0, nil
+	}
+
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = value[1 : len(value)-1]
+	}
+	value = strings.NewReplacer("$", "", ",", "", " ", "", "+", "").Replace(value)
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	if negative {
+		number = -number
+	}
+	return number, nil
+}
+
+// parseStatementDate parses a trade date using the first matching layout.
+// Dates are normalized to midnight UTC since statements report trade days.
+func parseStatementDate(value string, layouts ...string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range layouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// statementCurrency maps a statement currency code to a transaction currency
+func statementCurrency(code string) (string, bool) {
+	code = currencycode.Normalize(code)
+	if code == "" {
+		return currencycode.USD, true
+	}
+	return code, currencycode.IsTransactionCurrency(code)
+}
+
+// chinaSymbol converts a six-digit A-share code into its exchange-suffixed symbol
+func chinaSymbol(code string, market string) (string, bool) {
+	if len(code) != 6 {
+		return "", false
+	}
+	if _, err := strconv.Atoi(code); err != nil {
+		return "", false
+	}
+
+	switch strings.ToUpper(market) {
+	case "SH", "SSE":
+		return code + ".SS", true
+	case "SZ", "SZSE":
+		return code + ".SZ", true
+	}
+	// Shanghai codes start with 6 (main board) or 9 (B shares)
+	if code[0] == '6' || code[0] == '9' {
+		return code + ".SS", true
+	}
+	return code + ".SZ", true
+}
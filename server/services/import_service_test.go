@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func tradeDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func assertTrade(t *testing.T, trade ImportedTrade, symbol string, action string, shares float64, price float64, fees float64, currency string, date time.Time) {
+	t.Helper()
+	if trade.Symbol != symbol || trade.Action != action || trade.Shares != shares || trade.Price != price ||
+		trade.Fees != fees || trade.Currency != currency || !trade.Date.Equal(date) {
+		t.Errorf("Unexpected trade %+v, want %s %s %.4f @ %.4f fees %.2f %s on %s",
+			trade, action, symbol, shares, price, fees, currency, date.Format("2006-01-02"))
+	}
+}
+
+func TestParseSchwabStatement(t *testing.T) {
+	data := []byte(`"Transactions  for account XXXX-1234 as of 01/31/2024"
+"Date","Action","Symbol","Description","Quantity","Price","Fees & Comm","Amount"
+"01/10/2024","Sell","AAPL","APPLE INC","5","$190.00","$0.65","$949.35"
+"01/08/2024 as of 01/05/2024","Buy","AAPL","APPLE INC","10","$185.50","","-$1,855.00"
+"01/09/2024","Qualified Dividend","MSFT","MICROSOFT CORP","","","","$7.50"
+"01/12/2024","Reinvest Shares","VTI","VANGUARD TOTAL STOCK","0.123","$240.10","","-$29.53"
+"Transactions Total","","","","","","","-$927.68"
+`)
+
+	statement, err := ParseStatement("", data)
+	if err != nil {
+		t.Fatalf("Failed to parse statement: %v", err)
+	}
+	if statement.Broker != models.BrokerSchwab {
+		t.Fatalf("Expected schwab to be detected, got %s", statement.Broker)
+	}
+	if len(statement.Trades) != 3 || len(statement.Skipped) != 1 {
+		t.Fatalf("Expected 3 trades and 1 skipped row, got %+v", statement)
+	}
+	assertTrade(t, statement.Trades[0], "AAPL", "buy", 10, 185.5, 0, "USD", tradeDate(2024, 1, 5))
+	assertTrade(t, statement.Trades[1], "AAPL", "sell", 5, 190, 0.65, "USD", tradeDate(2024, 1, 10))
+	assertTrade(t, statement.Trades[2], "VTI", "buy", 0.123, 240.1, 0, "USD", tradeDate(2024, 1, 12))
+}
+
+func TestParseFidelityStatement(t *testing.T) {
+	data := []byte(`
+Run Date,Action,Symbol,Security Description,Security Type,Quantity,Price ($),Commission ($),Fees ($),Accrued Interest ($),Amount ($),Settlement Date
+02/01/2024,YOU BOUGHT NVIDIA CORPORATION (NVDA) (Cash), NVDA,NVIDIA CORPORATION,Cash,4,630.25,,0.02,,-2521.02,02/05/2024
+02/02/2024,YOU SOLD NVIDIA CORPORATION (NVDA) (Cash), NVDA,NVIDIA CORPORATION,Cash,-1,661.60,,0.03,,661.57,02/06/2024
+02/03/2024,DIVIDEND RECEIVED FIDELITY GOVERNMENT MONEY MARKET (SPAXX) (Cash), SPAXX,FIDELITY GOVERNMENT MONEY MARKET,Cash,0.000,,,,,1.23,
+
+"The data and information in this spreadsheet is provided to you solely for your use"
+`)
+
+	statement, err := ParseStatement("", data)
+	if err != nil {
+		t.Fatalf("Failed to parse statement: %v", err)
+	}
+	if statement.Broker != models.BrokerFidelity {
+		t.Fatalf("Expected fidelity to be detected, got %s", statement.Broker)
+	}
+	if len(statement.Trades) != 2 || len(statement.Skipped) != 1 {
+		t.Fatalf("Expected 2 trades and 1 skipped row, got %+v", statement)
+	}
+	assertTrade(t, statement.Trades[0], "NVDA", "buy", 4, 630.25, 0.02, "USD", tradeDate(2024, 2, 1))
+	assertTrade(t, statement.Trades[1], "NVDA", "sell", 1, 661.6, 0.03, "USD", tradeDate(2024, 2, 2))
+}
+
+func TestParseIBKRStatement(t *testing.T) {
+	data := []byte(`Statement,Header,Field Name,Field Value
+Statement,Data,Title,Activity Statement
+Trades,Header,DataDiscriminator,Asset Category,Currency,Symbol,Date/Time,Quantity,T. Price,C. Price,Proceeds,Comm/Fee,Basis,Realized P/L,MTM P/L,Code
+Trades,Data,Order,Stocks,USD,BRK B,"2024-03-04, 10:15:02",10,410.5,411,-4105,-1,4106,0,5,O
+Trades,Data,Order,Stocks,USD,AAPL,"2024-03-05, 15:59:59",-3,170.12,170,510.36,-0.35,-500,10,0,C
+Trades,SubTotal,,Stocks,USD,AAPL,,-3,,,510.36,-0.35,-500,10,0,
+Trades,Data,Order,Stocks,HKD,700,"2024-03-05, 10:00:00",100,290,290,-29000,-50,29050,0,0,O
+Trades,Data,Order,Forex,USD,EUR.USD,"2024-03-05, 10:00:00",100,1.08,1.08,-108,-2,0,0,0,O
+`)
+
+	statement, err := ParseStatement("", data)
+	if err != nil {
+		t.Fatalf("Failed to parse statement: %v", err)
+	}
+	if statement.Broker != models.BrokerIBKR {
+		t.Fatalf("Expected ibkr to be detected, got %s", statement.Broker)
+	}
+	if len(statement.Trades) != 2 || len(statement.Skipped) != 2 {
+		t.Fatalf("Expected 2 trades and 2 skipped rows, got %+v", statement)
+	}
+	assertTrade(t, statement.Trades[0], "BRK-B", "buy", 10, 410.5, 1, "USD", tradeDate(2024, 3, 4))
+	assertTrade(t, statement.Trades[1], "AAPL", "sell", 3, 170.12, 0.35, "USD", tradeDate(2024, 3, 5))
+}
+
+func TestParseFutuStatement(t *testing.T) {
+	data := []byte(`方向,代码,名称,订单价格,订单数量,成交数量,成交价格,成交时间,市场,币种,合计费用
+买入,600519,贵州茅台,1700.00,100,100,1699.50,2024/04/01 10:00:00,沪深,CNY,5.10
+卖出,00700,腾讯控股,300.00,100,100,301.00,2024/04/01 10:00:00,港股,HKD,20.00
+买入,TSLA,特斯拉,170.00,10,0,0,,美股,USD,0
+买入,TSLA,特斯拉,170.00,10,10,169.80,2024/04/02 09:31:00 ET,美股,USD,1.99
+`)
+
+	statement, err := ParseStatement(models.BrokerFutu, data)
+	if err != nil {
+		t.Fatalf("Failed to parse statement: %v", err)
+	}
+	if len(statement.Trades) != 2 || len(statement.Skipped) != 2 {
+		t.Fatalf("Expected 2 trades and 2 skipped rows, got %+v", statement)
+	}
+	assertTrade(t, statement.Trades[0], "600519.SS", "buy", 100, 1699.5, 5.1, "RMB", tradeDate(2024, 4, 1))
+	assertTrade(t, statement.Trades[1], "TSLA", "buy", 10, 169.8, 1.99, "USD", tradeDate(2024, 4, 2))
+}
+
+func TestParseOFXStatement(t *testing.T) {
+	data := []byte(`OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<INVSTMTMSGSRSV1><INVSTMTTRNRS><INVSTMTRS>
+<CURDEF>USD
+<INVTRANLIST>
+<SELLSTOCK><INVSELL><INVTRAN><FITID>2<DTTRADE>20240510160000.000[-4:EDT]<MEMO>SOLD</INVTRAN>
+<SECID><UNIQUEID>037833100<UNIQUEIDTYPE>CUSIP</SECID>
+<UNITS>-2<UNITPRICE>183.05<COMMISSION>0<FEES>0.01<TOTAL>366.09</INVSELL><SELLTYPE>SELL</SELLSTOCK>
+<BUYSTOCK><INVBUY><INVTRAN><FITID>1<DTTRADE>20240509</INVTRAN>
+<SECID><UNIQUEID>037833100<UNIQUEIDTYPE>CUSIP</SECID>
+<UNITS>5<UNITPRICE>182.40<COMMISSION>4.95<TOTAL>-916.95</INVBUY><BUYTYPE>BUY</BUYSTOCK>
+<BUYSTOCK><INVBUY><INVTRAN><FITID>3<DTTRADE>20240509</INVTRAN>
+<SECID><UNIQUEID>000000000<UNIQUEIDTYPE>CUSIP</SECID>
+<UNITS>1<UNITPRICE>10<TOTAL>-10</INVBUY><BUYTYPE>BUY</BUYSTOCK>
+</INVTRANLIST>
+</INVSTMTRS></INVSTMTTRNRS></INVSTMTMSGSRSV1>
+<SECLISTMSGSRSV1><SECLIST>
+<STOCKINFO><SECINFO><SECID><UNIQUEID>037833100<UNIQUEIDTYPE>CUSIP</SECID><SECNAME>APPLE INC<TICKER>AAPL</SECINFO></STOCKINFO>
+</SECLIST></SECLISTMSGSRSV1>
+</OFX>
+`)
+
+	statement, err := ParseStatement("", data)
+	if err != nil {
+		t.Fatalf("Failed to parse statement: %v", err)
+	}
+	if statement.Broker != models.BrokerOFX {
+		t.Fatalf("Expected ofx to be detected, got %s", statement.Broker)
+	}
+	if len(statement.Trades) != 2 || len(statement.Skipped) != 1 {
+		t.Fatalf("Expected 2 trades and 1 skipped row, got %+v", statement)
+	}
+	assertTrade(t, statement.Trades[0], "AAPL", "buy", 5, 182.4, 4.95, "USD", tradeDate(2024, 5, 9))
+	assertTrade(t, statement.Trades[1], "AAPL", "sell", 2, 183.05, 0.01, "USD", tradeDate(2024, 5, 10))
+	if statement.Trades[0].ExternalID != "1" || statement.Trades[1].ExternalID != "2" {
+		t.Errorf("Expected trades to keep their FITIDs, got %q and %q", statement.Trades[0].ExternalID, statement.Trades[1].ExternalID)
+	}
+}
+
+func TestParseStatementErrors(t *testing.T) {
+	if _, err := ParseStatement("", []byte("a,b,c\n1,2,3\n")); !errors.Is(err, ErrBrokerNotDetectable) {
+		t.Errorf("Expected ErrBrokerNotDetectable, got %v", err)
+	}
+	if _, err := ParseStatement("robinhood", []byte("a,b,c\n")); !errors.Is(err, ErrUnsupportedBroker) {
+		t.Errorf("Expected ErrUnsupportedBroker, got %v", err)
+	}
+	if _, err := ParseStatement(models.BrokerSchwab, []byte("a,b,c\n")); !errors.Is(err, ErrUnrecognizedFormat) {
+		t.Errorf("Expected ErrUnrecognizedFormat, got %v", err)
+	}
+}
+
+func TestMarkDuplicateTrades(t *testing.T) {
+	existingID := primitive.NewObjectID()
+	existing := []models.Transaction{
+		{ID: existingID, Symbol: "AAPL", Action: "buy", Shares: 10, Price: 185.5, Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+	}
+	trades := []ImportedTrade{
+		{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 185.5, Date: tradeDate(2024, 1, 5)},
+		{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 185.5, Date: tradeDate(2024, 1, 5)},
+		{Symbol: "AAPL", Action: "sell", Shares: 10, Price: 185.5, Date: tradeDate(2024, 1, 5)},
+	}
+
+	rows := markDuplicateTrades(models.BrokerSchwab, trades, existing)
+
+	if !rows[0].Duplicate || *rows[0].DuplicateOf != existingID {
+		t.Errorf("Expected the first trade to match the existing transaction")
+	}
+	if rows[1].Duplicate || rows[2].Duplicate {
+		t.Errorf("Expected each existing transaction to match only once")
+	}
+}
+
+func TestParseCombinedStatement(t *testing.T) {
+	data := []byte(`Date,Type,Symbol,Quantity,Price,Fees,Amount,Currency,Description
+2024-03-01,Deposit,,,,,5000,USD,Transfer in
+2024-03-04,Buy,KO,10,60,1,,USD,
+2024-03-15,Dividend,KO,,,,4.85,USD,Cash dividend
+2024-03-20,Sell,KO,5,62,1,309,USD,
+2024-03-25,Fee,,,,,-2.5,USD,ADR fee
+2024-03-28,Withdrawal,,,,,-1000,USD,
+2024-03-29,Transfer,,,,,10,USD,
+`)
+
+	statement, err := ParseStatement("", data)
+	if err != nil {
+		t.Fatalf("Failed to parse statement: %v", err)
+	}
+	if statement.Broker != models.BrokerCombined {
+		t.Fatalf("Expected combined to be detected, got %s", statement.Broker)
+	}
+	if len(statement.Trades) != 8 || len(statement.Skipped) != 1 {
+		t.Fatalf("Expected 8 trades and 1 skipped row, got %+v", statement)
+	}
+
+	assertTrade(t, statement.Trades[0], "CASH_USD", "buy", 5000, 1, 0, "USD", tradeDate(2024, 3, 1))
+	assertTrade(t, statement.Trades[1], "KO", "buy", 10, 60, 1, "USD", tradeDate(2024, 3, 4))
+	assertTrade(t, statement.Trades[2], "CASH_USD", "sell", 601, 1, 0, "USD", tradeDate(2024, 3, 4))
+	assertTrade(t, statement.Trades[3], "CASH_USD", "buy", 4.85, 1, 0, "USD", tradeDate(2024, 3, 15))
+	assertTrade(t, statement.Trades[4], "CASH_USD", "buy", 309, 1, 0, "USD", tradeDate(2024, 3, 20))
+	assertTrade(t, statement.Trades[5], "KO", "sell", 5, 62, 1, "USD", tradeDate(2024, 3, 20))
+	assertTrade(t, statement.Trades[6], "CASH_USD", "sell", 2.5, 1, 0, "USD", tradeDate(2024, 3, 25))
+	assertTrade(t, statement.Trades[7], "CASH_USD", "sell", 1000, 1, 0, "USD", tradeDate(2024, 3, 28))
+
+	if tags := statement.Trades[3].Tags; len(tags) != 1 || tags[0] != models.CashDividendTag {
+		t.Errorf("Expected the dividend to be tagged, got %v", tags)
+	}
+	if note := statement.Trades[3].Note; note != "Dividend: KO" {
+		t.Errorf("Expected the dividend note to name the payer, got %q", note)
+	}
+	if tags := statement.Trades[2].Tags; len(tags) != 1 || tags[0] != models.CashTradeTag {
+		t.Errorf("Expected the cash leg to be tagged, got %v", tags)
+	}
+}
+
+func TestImportSkipsRowsByExternalID(t *testing.T) {
+	repos := repository.NewMemory()
+	provider := NewFixtureProvider()
+	importService := NewImportService(NewPortfolioServiceWithRepos(provider, provider, repos))
+	userID := primitive.NewObjectID()
+
+	data := []byte(`Date,Type,Symbol,Quantity,Price,Fees,Amount,Currency,Description,ID
+2024-03-01,Deposit,,,,,1000,USD,,D1
+2024-03-04,Buy,KO,5,60,0,,USD,,T1
+2024-03-04,Buy,KO,5,60,0,,USD,,T2
+`)
+
+	first, err := importService.Commit(userID, "", data)
+	if err != nil {
+		t.Fatalf("Failed to import statement: %v", err)
+	}
+	if first.Imported != 5 || first.Duplicates != 0 || len(first.Failed) != 0 {
+		t.Fatalf("Expected 5 rows imported, got %+v", first)
+	}
+
+	transactions, err := repos.Transactions.FindByExternalIDs(context.Background(), userID, models.BrokerCombined, []string{"T1", "T1/cash"})
+	if err != nil || len(transactions) != 2 {
+		t.Fatalf("Expected the trade and its cash leg to keep their IDs, got %d (%v)", len(transactions), err)
+	}
+
+	// The identical T2 fill is not mistaken for T1, and a new row still imports
+	again := append(data, []byte("2024-03-05,Sell,KO,2,61,0,,USD,,T3\n")...)
+	second, err := importService.Commit(userID, "", again)
+	if err != nil {
+		t.Fatalf("Failed to import statement again: %v", err)
+	}
+	if second.Imported != 2 || second.Duplicates != 5 || len(second.Failed) != 0 {
+		t.Errorf("Expected only the new sell and its cash leg to import, got %+v", second)
+	}
+
+	// Rows without an ID only match transactions that have none either
+	preview, err := importService.Preview(userID, models.BrokerCombined, []byte(`Date,Type,Symbol,Quantity,Price,Fees,Amount,Currency
+2024-03-04,Buy,KO,5,60,0,,USD
+`))
+	if err != nil {
+		t.Fatalf("Failed to preview statement: %v", err)
+	}
+	if preview.Duplicates != 0 {
+		t.Errorf("Expected imported transactions with IDs not to match rows without one, got %+v", preview)
+	}
+}
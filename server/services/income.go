@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"stock-portfolio-tracker/currencycode"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UpcomingDividend represents a dividend expected on a holding in the next
+// year. Projected dividends are extrapolated from the holding's past schedule
+// rather than announced by the provider.
+type UpcomingDividend struct {
+	Symbol           string     `json:"symbol"`
+	Name             string     `json:"name"`
+	ExDate           time.Time  `json:"exDate"`
+	PayDate          *time.Time `json:"payDate,omitempty"`
+	AmountPerShare   float64    `json:"amountPerShare"` // In the dividend's currency
+	DividendCurrency string     `json:"dividendCurrency"`
+	Shares           float64    `json:"shares"`
+	Amount           float64    `json:"amount"`
+	Projected        bool       `json:"projected"`
+}
+
+// HoldingIncome represents a holding's projected annual dividend income.
+// Yields are percentages of the holding's cost basis and current value.
+type HoldingIncome struct {
+	Symbol            string  `json:"symbol"`
+	Name              string  `json:"name"`
+	Shares            float64 `json:"shares"`
+	DividendsPerShare float64 `json:"dividendsPerShare"` // Annual, in the dividend's currency
+	DividendCurrency  string  `json:"dividendCurrency,omitempty"`
+	PaymentsPerYear   int     `json:"paymentsPerYear"`
+	AnnualIncome      float64 `json:"annualIncome"`
+	CostBasis         float64 `json:"costBasis"`
+	CurrentValue      float64 `json:"currentValue"`
+	YieldOnCost       float64 `json:"yieldOnCost"`
+	CurrentYield      float64 `json:"currentYield"`
+}
+
+// IncomeResponse represents the dividend calendar and projected income of a
+// user's holdings
+type IncomeResponse struct {
+	Currency      string             `json:"currency"`
+	AnnualIncome  float64            `json:"annualIncome"`
+	MonthlyIncome float64            `json:"monthlyIncome"`
+	YieldOnCost   float64            `json:"yieldOnCost"`
+	CurrentYield  float64            `json:"currentYield"`
+	Holdings      []HoldingIncome    `json:"holdings"`
+	Upcoming      []UpcomingDividend `json:"upcoming"`
+	GeneratedAt   time.Time          `json:"generatedAt"`
+}
+
+// GetIncome returns the user's upcoming ex-dividend and pay dates over the
+// next year and the projected annual income and yield of each holding.
+// Annual income is the trailing twelve months of dividends per share times
+// the shares held today. Cash and option holdings are left out.
+func (s *AnalyticsService) GetIncome(ctx context.Context, userID primitive.ObjectID, currency string) (*IncomeResponse, error) {
+	currency = currencycode.Normalize(currency)
+
+	holdings, err := s.portfolioService.GetUserHoldingsContext(ctx, userID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch holdings: %w", err)
+	}
+
+	now := time.Now()
+	rates := map[string]float64{currency: 1}
+	response := &IncomeResponse{
+		Currency:    currency,
+		Holdings:    []HoldingIncome{},
+		Upcoming:    []UpcomingDividend{},
+		GeneratedAt: now,
+	}
+
+	var totalCost, totalValue float64
+	for _, holding := range holdings {
+		if s.stockService.IsCashSymbol(holding.Symbol) || holding.Option != nil || holding.Shares <= 0 {
+			continue
+		}
+
+		dividends, err := s.stockService.GetDividendsContext(ctx, holding.Symbol)
+		if err != nil {
+			fmt.Printf("[Analytics] Warning: Could not get dividends for %s: %v\n", holding.Symbol, err)
+			continue
+		}
+
+		perShare, payments, upcoming := projectDividends(dividends, now)
+		income := HoldingIncome{
+			Symbol:            holding.Symbol,
+			Name:              holding.Name,
+			Shares:            holding.Shares,
+			DividendsPerShare: perShare,
+			PaymentsPerYear:   payments,
+			CostBasis:         holding.CostBasis,
+			CurrentValue:      holding.CurrentValue,
+		}
+
+		if len(dividends) > 0 {
+			dividendCurrency := dividends[len(dividends)-1].Currency
+			rate, ok := rates[dividendCurrency]
+			if !ok {
+				rate, err = s.currencyService.GetExchangeRate(dividendCurrency, currency)
+				if err != nil {
+					fmt.Printf("[Analytics] Warning: Could not convert dividends for %s: %v\n", holding.Symbol, err)
+					continue
+				}
+				rates[dividendCurrency] = rate
+			}
+
+			income.DividendCurrency = dividendCurrency
+			income.AnnualIncome = perShare * holding.Shares * rate
+			for _, dividend := range upcoming {
+				dividend.Name = holding.Name
+				dividend.Shares = holding.Shares
+				dividend.Amount = dividend.AmountPerShare * holding.Shares * rate
+				response.Upcoming = append(response.Upcoming, dividend)
+			}
+		}
+
+		if holding.CostBasis > 0 {
+			income.YieldOnCost = income.AnnualIncome / holding.CostBasis * 100
+		}
+		if holding.CurrentValue > 0 {
+			income.CurrentYield = income.AnnualIncome / holding.CurrentValue * 100
+		}
+
+		response.Holdings = append(response.Holdings, income)
+		response.AnnualIncome += income.AnnualIncome
+		totalCost += holding.CostBasis
+		totalValue += holding.CurrentValue
+	}
+
+	response.MonthlyIncome = response.AnnualIncome / 12
+	if totalCost > 0 {
+		response.YieldOnCost = response.AnnualIncome / totalCost * 100
+	}
+	if totalValue > 0 {
+		response.CurrentYield = response.AnnualIncome / totalValue * 100
+	}
+
+	sort.Slice(response.Holdings, func(i, j int) bool {
+		return response.Holdings[i].AnnualIncome > response.Holdings[j].AnnualIncome
+	})
+	sort.SliceStable(response.Upcoming, func(i, j int) bool {
+		return response.Upcoming[i].ExDate.Before(response.Upcoming[j].ExDate)
+	})
+
+	return response, nil
+}
+
+// projectDividends derives a symbol's annual dividends per share and payment
+// frequency from its dividends over the year to now, and lists its dividends
+// in the year ahead. Announced dividends are listed as reported; the
+// remaining ones are projected at the latest amount and frequency, with pay
+// dates keeping the latest known gap between ex-date and pay date.
+func projectDividends(dividends []DividendEvent, now time.Time) (perShare float64, payments int, upcoming []UpcomingDividend) {
+	yearAgo := now.AddDate(-1, 0, 0)
+	yearAhead := now.AddDate(1, 0, 0)
+
+	var latest *DividendEvent
+	var payLag time.Duration
+	hasPayLag := false
+	for i := range dividends {
+		dividend := dividends[i]
+		if dividend.ExDate.After(yearAhead) {
+			break
+		}
+		latest = &dividends[i]
+		if dividend.PayDate != nil {
+			payLag = dividend.PayDate.Sub(dividend.ExDate)
+			hasPayLag = true
+		}
+
+		if dividend.ExDate.After(now) {
+			upcoming = append(upcoming, UpcomingDividend{
+				Symbol:           dividend.Symbol,
+				ExDate:           dividend.ExDate,
+				PayDate:          dividend.PayDate,
+				AmountPerShare:   dividend.Amount,
+				DividendCurrency: dividend.Currency,
+			})
+		} else if dividend.ExDate.After(yearAgo) {
+			perShare += dividend.Amount
+			payments++
+		}
+	}
+
+	// Without a dividend in the past year the payments are taken as suspended
+	if latest == nil || payments == 0 {
+		return perShare, payments, upcoming
+	}
+
+	months := int(math.Round(12 / float64(payments)))
+	if months < 1 {
+		months = 1
+	}
+	for exDate := latest.ExDate.AddDate(0, months, 0); !exDate.After(yearAhead); exDate = exDate.AddDate(0, months, 0) {
+		if !exDate.After(now) {
+			continue
+		}
+		projected := UpcomingDividend{
+			Symbol:           latest.Symbol,
+			ExDate:           exDate,
+			AmountPerShare:   latest.Amount,
+			DividendCurrency: latest.Currency,
+			Projected:        true,
+		}
+		if hasPayLag {
+			payDate := exDate.Add(payLag)
+			projected.PayDate = &payDate
+		}
+		upcoming = append(upcoming, projected)
+	}
+
+	return perShare, payments, upcoming
+}
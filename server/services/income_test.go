@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"math"
+	"stock-portfolio-tracker/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestProjectDividends(t *testing.T) {
+	now := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	payDate := time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC)
+	dividends := []DividendEvent{
+		{Symbol: "AAPL", ExDate: time.Date(2023, 5, 12, 0, 0, 0, 0, time.UTC), Amount: 0.24},
+		{Symbol: "AAPL", ExDate: time.Date(2023, 8, 11, 0, 0, 0, 0, time.UTC), Amount: 0.24},
+		{Symbol: "AAPL", ExDate: time.Date(2023, 11, 10, 0, 0, 0, 0, time.UTC), Amount: 0.24},
+		{Symbol: "AAPL", ExDate: time.Date(2024, 2, 9, 0, 0, 0, 0, time.UTC), Amount: 0.24},
+		{Symbol: "AAPL", ExDate: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), PayDate: &payDate, Amount: 0.25},
+	}
+
+	perShare, payments, upcoming := projectDividends(dividends, now)
+	if math.Abs(perShare-0.97) > 1e-9 || payments != 4 {
+		t.Errorf("Expected 0.97 over 4 payments, got %.4f over %d", perShare, payments)
+	}
+	if len(upcoming) != 4 {
+		t.Fatalf("Expected 4 projected dividends, got %d", len(upcoming))
+	}
+	first := upcoming[0]
+	if !first.Projected || !first.ExDate.Equal(time.Date(2024, 8, 10, 0, 0, 0, 0, time.UTC)) || first.AmountPerShare != 0.25 {
+		t.Errorf("Unexpected first projection %+v", first)
+	}
+	if first.PayDate == nil || !first.PayDate.Equal(time.Date(2024, 8, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the pay date to keep the six day lag, got %v", first.PayDate)
+	}
+
+	// A dividend not paid within the past year is taken as suspended
+	if _, payments, upcoming := projectDividends(dividends[:1], now); payments != 0 || len(upcoming) != 0 {
+		t.Errorf("Expected no projection for a suspended dividend, got %d payments and %d upcoming", payments, len(upcoming))
+	}
+}
+
+func TestGetIncome(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	announced := today.AddDate(0, 0, 20)
+	provider := NewFixtureProvider().
+		SetQuote("KO", "Coca-Cola", 60, "USD").
+		SetQuote("TSLA", "Tesla", 200, "USD").
+		SetDividends("KO",
+			DividendEvent{ExDate: today.AddDate(0, -10, 0), Amount: 0.5},
+			DividendEvent{ExDate: today.AddDate(0, -7, 0), Amount: 0.5},
+			DividendEvent{ExDate: today.AddDate(0, -4, 0), Amount: 0.5},
+			DividendEvent{ExDate: today.AddDate(0, -1, 0), Amount: 0.5},
+			DividendEvent{ExDate: announced, Amount: 0.5},
+		).
+		SetRate("USD", "RMB", 7)
+	service, portfolioService := newFixtureAnalyticsService(provider)
+	userID := primitive.NewObjectID()
+
+	for _, tx := range []*models.Transaction{
+		{Symbol: "KO", Action: "buy", Shares: 100, Price: 50, Currency: "USD", Date: today.AddDate(-2, 0, 0)},
+		{Symbol: "TSLA", Action: "buy", Shares: 10, Price: 200, Currency: "USD", Date: today.AddDate(-1, 0, 0)},
+	} {
+		if err := portfolioService.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+
+	income, err := service.GetIncome(context.Background(), userID, "CNY")
+	if err != nil {
+		t.Fatalf("Failed to get income: %v", err)
+	}
+	if income.Currency != "RMB" || math.Abs(income.AnnualIncome-1400) > 1e-9 {
+		t.Errorf("Expected 1400 RMB of annual income, got %.2f %s", income.AnnualIncome, income.Currency)
+	}
+	if len(income.Holdings) != 2 || income.Holdings[0].Symbol != "KO" || income.Holdings[1].AnnualIncome != 0 {
+		t.Fatalf("Unexpected holdings %+v", income.Holdings)
+	}
+	if ko := income.Holdings[0]; math.Abs(ko.YieldOnCost-4) > 1e-9 || math.Abs(ko.CurrentYield-10.0/3) > 1e-9 {
+		t.Errorf("Expected 4%% yield on cost and 3.33%% current yield, got %.4f and %.4f", ko.YieldOnCost, ko.CurrentYield)
+	}
+
+	// The announced dividend comes first, followed by the quarterly projections
+	if len(income.Upcoming) == 0 || income.Upcoming[0].Projected || !income.Upcoming[0].ExDate.Equal(announced) {
+		t.Fatalf("Expected the announced dividend first, got %+v", income.Upcoming)
+	}
+	if income.Upcoming[0].Amount != 350 {
+		t.Errorf("Expected 350 RMB for the announced dividend, got %.2f", income.Upcoming[0].Amount)
+	}
+	for _, dividend := range income.Upcoming[1:] {
+		if !dividend.Projected || dividend.ExDate.After(today.AddDate(1, 0, 0)) {
+			t.Errorf("Unexpected upcoming dividend %+v", dividend)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-portfolio-tracker/internal/analytics/engine"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidIndicator is returned for an indicator name that isn't supported
+var ErrInvalidIndicator = errors.New("invalid indicator")
+
+// DefaultIndicators is the indicator set charted when a request names none
+var DefaultIndicators = []string{"sma50", "rsi", "macd"}
+
+const (
+	maxIndicators      = 10
+	maxIndicatorWindow = 400
+	defaultRSIWindow   = 14
+	macdFastWindow     = 12
+	macdSlowWindow     = 26
+	macdSignalWindow   = 9
+)
+
+// IndicatorLines maps the lines of one indicator, such as "macd" and "signal",
+// to values aligned with the chart's dates. A value is nil until enough
+// closes have been seen to compute it.
+type IndicatorLines map[string][]*float64
+
+// IndicatorChart is a symbol's daily closes over a period with technical
+// indicators computed from them. Indicators are computed over the symbol's
+// full history, so they are warmed up at the start of the period.
+type IndicatorChart struct {
+	Symbol     string                    `json:"symbol"`
+	Period     string                    `json:"period"`
+	Dates      []time.Time               `json:"dates"`
+	Closes     []float64                 `json:"closes"`
+	Indicators map[string]IndicatorLines `json:"indicators"` // By requested name
+}
+
+// indicatorSpec is a parsed indicator name
+type indicatorSpec struct {
+	name   string // As requested, lowercased
+	kind   string // sma, ema, rsi or macd
+	window int
+}
+
+// parseIndicators parses indicator names such as sma50, ema20, rsi, rsi7 and
+// macd, dropping duplicates. An empty list returns the default set.
+func parseIndicators(names []string) ([]indicatorSpec, error) {
+	if len(names) == 0 {
+		names = DefaultIndicators
+	}
+
+	seen := make(map[string]bool, len(names))
+	specs := make([]indicatorSpec, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		spec := indicatorSpec{name: name}
+		switch {
+		case name == "macd":
+			spec.kind = "macd"
+		case name == "rsi":
+			spec.kind, spec.window = "rsi", defaultRSIWindow
+		default:
+			for _, kind := range []string{"sma", "ema", "rsi"} {
+				if window, err := strconv.Atoi(strings.TrimPrefix(name, kind)); strings.HasPrefix(name, kind) && err == nil {
+					spec.kind, spec.window = kind, window
+					break
+				}
+			}
+			if spec.kind == "" || spec.window < 2 || spec.window > maxIndicatorWindow {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidIndicator, name)
+			}
+		}
+		specs = append(specs, spec)
+	}
+
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("%w: no indicators requested", ErrInvalidIndicator)
+	}
+	if len(specs) > maxIndicators {
+		return nil, fmt.Errorf("%w: at most %d indicators per request", ErrInvalidIndicator, maxIndicators)
+	}
+	return specs, nil
+}
+
+// simpleMovingAverage returns the mean of each close and the window-1 before it
+func simpleMovingAverage(closes []float64, window int) []*float64 {
+	values := make([]*float64, len(closes))
+	sum := 0.0
+	for i, price := range closes {
+		sum += price
+		if i >= window {
+			sum -= closes[i-window]
+		}
+		if i >= window-1 {
+			average := sum / float64(window)
+			values[i] = &average
+		}
+	}
+	return values
+}
+
+// exponentialMovingAverage weights the closes by 2/(window+1), seeded with the
+// simple average of the first window closes. Leading nil values are skipped.
+func exponentialMovingAverage(closes []*float64, window int) []*float64 {
+	values := make([]*float64, len(closes))
+	weight := 2 / float64(window+1)
+	seen, sum := 0, 0.0
+	var average float64
+	for i, price := range closes {
+		if price == nil {
+			continue
+		}
+		seen++
+		switch {
+		case seen < window:
+			sum += *price
+			continue
+		case seen == window:
+			average = (sum + *price) / float64(window)
+		default:
+			average += (*price - average) * weight
+		}
+		value := average
+		values[i] = &value
+	}
+	return values
+}
+
+// relativeStrengthIndex returns Wilder's RSI, from 0 to 100
+func relativeStrengthIndex(closes []float64, window int) []*float64 {
+	values := make([]*float64, len(closes))
+	gain, loss := 0.0, 0.0
+	for i := 1; i < len(closes); i++ {
+		change := closes[i] - closes[i-1]
+		up, down := max(change, 0), max(-change, 0)
+		if i <= window {
+			gain += up / float64(window)
+			loss += down / float64(window)
+			if i < window {
+				continue
+			}
+		} else {
+			gain = (gain*float64(window-1) + up) / float64(window)
+			loss = (loss*float64(window-1) + down) / float64(window)
+		}
+
+		rsi := 100.0
+		if loss > 0 {
+			rsi = 100 - 100/(1+gain/loss)
+		}
+		values[i] = &rsi
+	}
+	return values
+}
+
+// movingAverageConvergenceDivergence returns the 12-26 MACD line, its 9-day
+// signal line and their difference
+func movingAverageConvergenceDivergence(closes []float64) IndicatorLines {
+	prices := make([]*float64, len(closes))
+	for i := range closes {
+		prices[i] = &closes[i]
+	}
+	fast := exponentialMovingAverage(prices, macdFastWindow)
+	slow := exponentialMovingAverage(prices, macdSlowWindow)
+
+	macd := make([]*float64, len(closes))
+	for i := range closes {
+		if fast[i] != nil && slow[i] != nil {
+			value := *fast[i] - *slow[i]
+			macd[i] = &value
+		}
+	}
+	signal := exponentialMovingAverage(macd, macdSignalWindow)
+	histogram := make([]*float64, len(closes))
+	for i := range closes {
+		if macd[i] != nil && signal[i] != nil {
+			value := *macd[i] - *signal[i]
+			histogram[i] = &value
+		}
+	}
+	return IndicatorLines{"macd": macd, "signal": signal, "histogram": histogram}
+}
+
+// computeIndicators computes the indicators over the closes
+func computeIndicators(closes []float64, specs []indicatorSpec) map[string]IndicatorLines {
+	indicators := make(map[string]IndicatorLines, len(specs))
+	for _, spec := range specs {
+		switch spec.kind {
+		case "sma":
+			indicators[spec.name] = IndicatorLines{spec.name: simpleMovingAverage(closes, spec.window)}
+		case "ema":
+			prices := make([]*float64, len(closes))
+			for i := range closes {
+				prices[i] = &closes[i]
+			}
+			indicators[spec.name] = IndicatorLines{spec.name: exponentialMovingAverage(prices, spec.window)}
+		case "rsi":
+			indicators[spec.name] = IndicatorLines{spec.name: relativeStrengthIndex(closes, spec.window)}
+		case "macd":
+			indicators[spec.name] = movingAverageConvergenceDivergence(closes)
+		}
+	}
+	return indicators
+}
+
+// GetIndicatorsContext charts a symbol's daily closes over period with the
+// named indicators, such as sma50, ema20, rsi or macd. They are computed from
+// the cached full price history, which is limited to daily closes.
+func (s *StockAPIService) GetIndicatorsContext(ctx context.Context, symbol, period string, names []string) (*IndicatorChart, error) {
+	return indicatorChart(ctx, s, symbol, period, names)
+}
+
+// indicatorChart fetches the full history of a symbol from the provider and
+// charts the part within period
+func indicatorChart(ctx context.Context, provider StockDataProvider, symbol, period string, names []string) (*IndicatorChart, error) {
+	specs, err := parseIndicators(names)
+	if err != nil {
+		return nil, err
+	}
+
+	prices, err := provider.GetHistoricalDataContext(ctx, symbol, "ALL")
+	if err != nil {
+		return nil, err
+	}
+
+	var dates []time.Time
+	var closes []float64
+	for _, price := range engine.Sorted(prices) {
+		if price.Price > 0 {
+			dates = append(dates, price.Date)
+			closes = append(closes, price.Price)
+		}
+	}
+	if len(closes) == 0 {
+		return nil, ErrStockNotFound
+	}
+
+	// Indicators warm up over the history before the period
+	indicators := computeIndicators(closes, specs)
+	start := PeriodStart(period, dates[len(dates)-1])
+	from := 0
+	for from < len(dates) && dates[from].Before(start) {
+		from++
+	}
+	for _, lines := range indicators {
+		for line, values := range lines {
+			lines[line] = values[from:]
+		}
+	}
+
+	return &IndicatorChart{
+		Symbol:     symbol,
+		Period:     period,
+		Dates:      dates[from:],
+		Closes:     closes[from:],
+		Indicators: indicators,
+	}, nil
+}
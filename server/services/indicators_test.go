@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseIndicators(t *testing.T) {
+	specs, err := parseIndicators([]string{" SMA50", "rsi", "rsi7", "ema20", "macd", "sma50"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(specs) != 5 || specs[0] != (indicatorSpec{name: "sma50", kind: "sma", window: 50}) ||
+		specs[1].window != defaultRSIWindow || specs[2].window != 7 || specs[3].kind != "ema" {
+		t.Errorf("Unexpected specs %+v", specs)
+	}
+	if specs, _ := parseIndicators(nil); len(specs) != len(DefaultIndicators) {
+		t.Errorf("Expected the default set, got %+v", specs)
+	}
+	for _, names := range [][]string{{"vwap"}, {"sma"}, {"sma1"}, {"ema1000"}, {" "}} {
+		if _, err := parseIndicators(names); !errors.Is(err, ErrInvalidIndicator) {
+			t.Errorf("Expected ErrInvalidIndicator for %v, got %v", names, err)
+		}
+	}
+}
+
+func TestComputeIndicators(t *testing.T) {
+	closes := []float64{1, 2, 3, 4, 5, 6}
+	specs, _ := parseIndicators([]string{"sma3", "ema3", "rsi3"})
+	indicators := computeIndicators(closes, specs)
+
+	sma := indicators["sma3"]["sma3"]
+	if sma[1] != nil || *sma[2] != 2 || *sma[5] != 5 {
+		t.Errorf("Unexpected SMA %v", sma)
+	}
+	// Seeded at 2, then halfway to each close
+	ema := indicators["ema3"]["ema3"]
+	if ema[1] != nil || *ema[2] != 2 || *ema[3] != 3 || *ema[5] != 5 {
+		t.Errorf("Unexpected EMA %v", ema)
+	}
+	// Closes that only rise are maximally overbought
+	rsi := indicators["rsi3"]["rsi3"]
+	if rsi[2] != nil || *rsi[3] != 100 {
+		t.Errorf("Unexpected RSI %v", rsi)
+	}
+
+	falling := relativeStrengthIndex([]float64{10, 9, 10, 9, 10}, 2)
+	if math.Abs(*falling[2]-50) > 1e-9 || math.Abs(*falling[3]-25) > 1e-9 {
+		t.Errorf("Expected Wilder's smoothing, got %v and %v", *falling[2], *falling[3])
+	}
+}
+
+func TestIndicatorChart(t *testing.T) {
+	end := time.Date(2024, 6, 28, 0, 0, 0, 0, time.UTC)
+	closes := make([]float64, 120)
+	for i := range closes {
+		closes[i] = 100 + float64(i)
+	}
+	provider := NewFixtureProvider().SetHistory("AAPL", end, closes...)
+
+	chart, err := indicatorChart(context.Background(), provider, "AAPL", "1M", []string{"macd", "sma50"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(chart.Dates) != 32 || len(chart.Closes) != 32 || !chart.Dates[31].Equal(end) {
+		t.Fatalf("Expected a month of closes, got %d", len(chart.Dates))
+	}
+
+	// Warmed up over the history before the month
+	macd := chart.Indicators["macd"]
+	if len(macd["macd"]) != 32 || macd["macd"][0] == nil || macd["signal"][0] == nil || macd["histogram"][0] == nil {
+		t.Errorf("Expected MACD lines warmed up, got %+v", macd)
+	}
+	if sma := chart.Indicators["sma50"]["sma50"]; *sma[31] != 194.5 {
+		t.Errorf("Expected the 50-day average of the last closes, got %v", *sma[31])
+	}
+
+	if _, err := indicatorChart(context.Background(), provider, "AAPL", "1M", []string{"vwap"}); !errors.Is(err, ErrInvalidIndicator) {
+		t.Errorf("Expected ErrInvalidIndicator, got %v", err)
+	}
+	if _, err := indicatorChart(context.Background(), provider, "MISSING", "1M", nil); !errors.Is(err, ErrStockNotFound) {
+		t.Errorf("Expected ErrStockNotFound, got %v", err)
+	}
+}
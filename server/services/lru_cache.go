@@ -0,0 +1,141 @@
+package services
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// CacheStats reports the size and effectiveness of an in-memory cache
+type CacheStats struct {
+	Entries    int     `json:"entries"`
+	MaxEntries int     `json:"maxEntries"`
+	Hits       uint64  `json:"hits"`
+	Misses     uint64  `json:"misses"`
+	Evictions  uint64  `json:"evictions"` // Live entries dropped to make room
+	HitRate    float64 `json:"hitRate"`   // Hits over lookups, 0 before the first lookup
+}
+
+// lruCache maps keys to values that expire, holding at most maxEntries. When
+// full, storing a new key evicts the least recently used entry. It is safe for
+// concurrent use.
+type lruCache[V any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List // Most recently used first
+	hits       uint64
+	misses     uint64
+	evictions  uint64
+}
+
+// lruEntry is the list element value of a cached key
+type lruEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// newLRUCache creates an empty cache holding at most maxEntries, which must be
+// positive
+func newLRUCache[V any](maxEntries int) *lruCache[V] {
+	return &lruCache[V]{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Get returns the value cached under key if it has not expired at now, marking
+// it as recently used. Expired entries are removed.
+func (c *lruCache[V]) Get(key string, now time.Time) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if ok && now.After(element.Value.(*lruEntry[V]).expiresAt) {
+		c.remove(element)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		var zero V
+		return zero, false
+	}
+
+	c.hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry[V]).value, true
+}
+
+// Set caches value under key until expiresAt, evicting the least recently used
+// entry when the cache is full
+func (c *lruCache[V]) Set(key string, value V, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry[V])
+		entry.value, entry.expiresAt = value, expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	if c.order.Len() >= c.maxEntries {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value, expiresAt: expiresAt})
+}
+
+// RemoveExpired drops every entry expired at now and returns how many it removed
+func (c *lruCache[V]) RemoveExpired(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		if now.After(element.Value.(*lruEntry[V]).expiresAt) {
+			c.remove(element)
+			removed++
+		}
+		element = next
+	}
+	return removed
+}
+
+// Clear drops every entry and returns how many it removed. Hit counts are kept.
+func (c *lruCache[V]) Clear() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := c.order.Len()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	return removed
+}
+
+// Stats returns the cache's current size and lifetime hit counts
+func (c *lruCache[V]) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := CacheStats{
+		Entries:    c.order.Len(),
+		MaxEntries: c.maxEntries,
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Evictions:  c.evictions,
+	}
+	if lookups := c.hits + c.misses; lookups > 0 {
+		stats.HitRate = float64(c.hits) / float64(lookups)
+	}
+	return stats
+}
+
+// remove drops an element; the caller holds the lock
+func (c *lruCache[V]) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry[V]).key)
+}
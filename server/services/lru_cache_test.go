@@ -0,0 +1,81 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Now()
+	expires := now.Add(time.Minute)
+	cache := newLRUCache[int](2)
+
+	cache.Set("AAPL", 1, expires)
+	cache.Set("MSFT", 2, expires)
+	if _, ok := cache.Get("AAPL", now); !ok {
+		t.Fatalf("Expected AAPL to be cached")
+	}
+
+	// MSFT is now the least recently used entry
+	cache.Set("NVDA", 3, expires)
+	if _, ok := cache.Get("MSFT", now); ok {
+		t.Errorf("Expected MSFT to be evicted")
+	}
+	if value, ok := cache.Get("AAPL", now); !ok || value != 1 {
+		t.Errorf("Expected AAPL to survive eviction, got %d, %v", value, ok)
+	}
+
+	// Replacing a cached key does not evict anything
+	cache.Set("NVDA", 4, expires)
+	if value, ok := cache.Get("NVDA", now); !ok || value != 4 {
+		t.Errorf("Expected NVDA to be updated, got %d, %v", value, ok)
+	}
+
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.MaxEntries != 2 || stats.Hits != 3 || stats.Misses != 1 || stats.Evictions != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if stats.HitRate != 0.75 {
+		t.Errorf("Expected hit rate 0.75, got %.2f", stats.HitRate)
+	}
+}
+
+func TestLRUCacheExpiry(t *testing.T) {
+	now := time.Now()
+	cache := newLRUCache[string](10)
+	cache.Set("AAPL", "fresh", now.Add(time.Minute))
+	cache.Set("MSFT", "stale", now.Add(-time.Second))
+
+	if _, ok := cache.Get("MSFT", now); ok {
+		t.Errorf("Expected expired entry to miss")
+	}
+	if stats := cache.Stats(); stats.Entries != 1 {
+		t.Errorf("Expected expired entry to be removed on lookup, got %d entries", stats.Entries)
+	}
+
+	cache.Set("NVDA", "stale", now.Add(-time.Second))
+	if removed := cache.RemoveExpired(now); removed != 1 {
+		t.Errorf("Expected 1 expired entry removed, got %d", removed)
+	}
+	if _, ok := cache.Get("AAPL", now); !ok {
+		t.Errorf("Expected live entry to remain")
+	}
+}
+
+func TestLRUCacheClear(t *testing.T) {
+	now := time.Now()
+	cache := newLRUCache[int](10)
+	cache.Set("AAPL", 1, now.Add(time.Minute))
+	cache.Set("MSFT", 2, now.Add(time.Minute))
+
+	if removed := cache.Clear(); removed != 2 {
+		t.Errorf("Expected 2 entries cleared, got %d", removed)
+	}
+	if _, ok := cache.Get("AAPL", now); ok {
+		t.Errorf("Expected AAPL to be gone after Clear")
+	}
+	cache.Set("NVDA", 3, now.Add(time.Minute))
+	if stats := cache.Stats(); stats.Entries != 1 {
+		t.Errorf("Expected the cache to be usable after Clear, got %d entries", stats.Entries)
+	}
+}
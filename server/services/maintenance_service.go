@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"sync"
+	"time"
+)
+
+var ErrInvalidRetryAfter = errors.New("retry after must not be negative")
+
+const (
+	// MaintenanceRefreshInterval is how often each instance reloads the
+	// maintenance switch, bounding how long a change takes to apply everywhere
+	MaintenanceRefreshInterval = 15 * time.Second
+	// DefaultMaintenanceRetryAfter is announced to clients when no retry
+	// delay is given
+	DefaultMaintenanceRetryAfter = 5 * time.Minute
+)
+
+// MaintenanceService holds the read-only maintenance switch. The switch is
+// stored in the database so every instance follows it, and cached so checking
+// it costs nothing per request.
+type MaintenanceService struct {
+	repos repository.Repositories
+
+	mutex sync.RWMutex
+	mode  models.MaintenanceMode
+}
+
+// NewMaintenanceService creates a new MaintenanceService instance storing the
+// switch in MongoDB
+func NewMaintenanceService() *MaintenanceService {
+	return NewMaintenanceServiceWithRepos(repository.NewMongo())
+}
+
+// NewMaintenanceServiceWithRepos creates a MaintenanceService over the given repositories
+func NewMaintenanceServiceWithRepos(repos repository.Repositories) *MaintenanceService {
+	return &MaintenanceService{repos: repos}
+}
+
+// Current returns the switch as last loaded or set by this instance
+func (s *MaintenanceService) Current() models.MaintenanceMode {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.mode
+}
+
+// Refresh reloads the switch from the database. A switch that was never set
+// is off.
+func (s *MaintenanceService) Refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	mode, err := s.repos.Maintenance.Get(ctx)
+	if errors.Is(err, repository.ErrNotFound) {
+		mode, err = &models.MaintenanceMode{}, nil
+	}
+	if err != nil {
+		// Keep the last known state rather than guessing
+		return fmt.Errorf("failed to load maintenance mode: %w", err)
+	}
+
+	s.mutex.Lock()
+	changed := s.mode.Enabled != mode.Enabled
+	s.mode = *mode
+	s.mutex.Unlock()
+
+	if changed {
+		fmt.Printf("[Maintenance] Maintenance mode is now %s\n", onOff(mode.Enabled))
+	}
+	return nil
+}
+
+// Set turns maintenance mode on or off for every instance. Other instances
+// pick the change up within MaintenanceRefreshInterval. The time it was
+// turned on is kept while it stays on.
+func (s *MaintenanceService) Set(enabled bool, message string, retryAfter time.Duration) (*models.MaintenanceMode, error) {
+	if retryAfter < 0 {
+		return nil, ErrInvalidRetryAfter
+	}
+	if retryAfter == 0 {
+		retryAfter = DefaultMaintenanceRetryAfter
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	previous, err := s.repos.Maintenance.Get(ctx)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load maintenance mode: %w", err)
+	}
+
+	now := time.Now()
+	mode := &models.MaintenanceMode{
+		Enabled:    enabled,
+		Message:    message,
+		RetryAfter: int(retryAfter.Round(time.Second).Seconds()),
+		UpdatedAt:  now,
+	}
+	if enabled {
+		mode.Since = &now
+		if previous != nil && previous.Enabled && previous.Since != nil {
+			mode.Since = previous.Since
+		}
+	}
+
+	if err := s.repos.Maintenance.Set(ctx, mode); err != nil {
+		return nil, fmt.Errorf("failed to save maintenance mode: %w", err)
+	}
+
+	s.mutex.Lock()
+	s.mode = *mode
+	s.mutex.Unlock()
+	return mode, nil
+}
+
+// onOff describes a switch state in log messages
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
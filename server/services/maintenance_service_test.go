@@ -0,0 +1,49 @@
+package services
+
+import (
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceService(t *testing.T) {
+	repos := repository.NewMemory()
+	service := NewMaintenanceServiceWithRepos(repos)
+
+	// A switch that was never set is off
+	if err := service.Refresh(); err != nil || service.Current().Enabled {
+		t.Fatalf("Expected maintenance mode off, got %+v, %v", service.Current(), err)
+	}
+
+	first, err := service.Set(true, "", 0)
+	if err != nil {
+		t.Fatalf("Failed to turn on maintenance mode: %v", err)
+	}
+	if !first.Enabled || first.Since == nil || first.RetryAfter != int(DefaultMaintenanceRetryAfter.Seconds()) {
+		t.Errorf("Expected maintenance mode on with the default retry, got %+v", first)
+	}
+
+	// Another instance sees the switch after refreshing
+	other := NewMaintenanceServiceWithRepos(repos)
+	if err := other.Refresh(); err != nil || !other.Current().Enabled {
+		t.Fatalf("Expected another instance to see maintenance mode, got %+v, %v", other.Current(), err)
+	}
+
+	// Changing the message keeps the start time
+	second, err := service.Set(true, "Migrating", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to update maintenance mode: %v", err)
+	}
+	if !second.Since.Equal(*first.Since) || second.RetryAfter != 60 || second.Message != "Migrating" {
+		t.Errorf("Expected the original start and new settings, got %+v", second)
+	}
+
+	off, err := service.Set(false, "", 0)
+	if err != nil || off.Enabled || off.Since != nil || service.Current().Enabled {
+		t.Errorf("Expected maintenance mode off, got %+v, %v", off, err)
+	}
+
+	if _, err := service.Set(true, "", -time.Second); err != ErrInvalidRetryAfter {
+		t.Errorf("Expected ErrInvalidRetryAfter, got %v", err)
+	}
+}
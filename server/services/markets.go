@@ -0,0 +1,314 @@
+package services
+
+import (
+	"fmt"
+	"stock-portfolio-tracker/currencycode"
+	"strings"
+	"time"
+
+	// Embed the zone database so market hours work on hosts without one
+	_ "time/tzdata"
+)
+
+// TradingSession is a continuous trading window in the market's local time
+type TradingSession struct {
+	Open  string `json:"open"`  // "15:04"
+	Close string `json:"close"` // "15:04"
+}
+
+// BenchmarkSuggestion is an index commonly used to compare holdings on a market
+type BenchmarkSuggestion struct {
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+}
+
+// Market describes an exchange whose symbols share a Yahoo Finance suffix
+type Market struct {
+	Code       string                `json:"code"`
+	Name       string                `json:"name"`
+	Region     string                `json:"region"`
+	Suffixes   []string              `json:"suffixes"` // Empty for US symbols
+	Currency   string                `json:"currency"`
+	TimeZone   string                `json:"timeZone"`
+	Sessions   []TradingSession      `json:"sessions"`
+	Benchmarks []BenchmarkSuggestion `json:"benchmarks"`
+}
+
+// MarketStatus reports whether a market is trading at a point in time
+type MarketStatus struct {
+	Market
+	LocalTime time.Time `json:"localTime"`
+	IsOpen    bool      `json:"isOpen"`
+	NextOpen  time.Time `json:"nextOpen"`
+}
+
+// minorCurrencyUnits maps the minor-unit currency codes Yahoo Finance quotes
+// some listings in, such as pence on the London Stock Exchange, to the major
+// currency worth 100 of them
+var minorCurrencyUnits = map[string]string{
+	"GBp": "GBP",
+	"GBX": "GBP",
+	"ZAc": "ZAR",
+	"ILA": "ILS",
+}
+
+// markets lists the supported exchanges. Trading days are weekdays; exchange
+// holidays are not modelled, which is why quotes' official previous closes are
+// preferred over closes read from price history.
+var markets = []Market{
+	{
+		Code:     "US",
+		Name:     "NYSE and NASDAQ",
+		Region:   "United States",
+		Currency: "USD",
+		TimeZone: "America/New_York",
+		Sessions: []TradingSession{{Open: "09:30", Close: "16:00"}},
+		Benchmarks: []BenchmarkSuggestion{
+			{Symbol: "^GSPC", Name: "S&P 500"},
+			{Symbol: "^IXIC", Name: "NASDAQ Composite"},
+			{Symbol: "^DJI", Name: "Dow Jones Industrial Average"},
+		},
+	},
+	{
+		Code:     "SSE",
+		Name:     "Shanghai Stock Exchange",
+		Region:   "China",
+		Suffixes: []string{".SS"},
+		Currency: currencycode.RMB,
+		TimeZone: "Asia/Shanghai",
+		Sessions: []TradingSession{{Open: "09:30", Close: "11:30"}, {Open: "13:00", Close: "15:00"}},
+		Benchmarks: []BenchmarkSuggestion{
+			{Symbol: "000001.SS", Name: "SSE Composite"},
+			{Symbol: "000300.SS", Name: "CSI 300"},
+		},
+	},
+	{
+		Code:     "SZSE",
+		Name:     "Shenzhen Stock Exchange",
+		Region:   "China",
+		Suffixes: []string{".SZ"},
+		Currency: currencycode.RMB,
+		TimeZone: "Asia/Shanghai",
+		Sessions: []TradingSession{{Open: "09:30", Close: "11:30"}, {Open: "13:00", Close: "15:00"}},
+		Benchmarks: []BenchmarkSuggestion{
+			{Symbol: "399001.SZ", Name: "SZSE Component"},
+			{Symbol: "000300.SS", Name: "CSI 300"},
+		},
+	},
+	{
+		Code:     "HKEX",
+		Name:     "Hong Kong Stock Exchange",
+		Region:   "Hong Kong",
+		Suffixes: []string{".HK"},
+		Currency: "HKD",
+		TimeZone: "Asia/Hong_Kong",
+		Sessions: []TradingSession{{Open: "09:30", Close: "12:00"}, {Open: "13:00", Close: "16:00"}},
+		Benchmarks: []BenchmarkSuggestion{
+			{Symbol: "^HSI", Name: "Hang Seng Index"},
+		},
+	},
+	{
+		Code:     "LSE",
+		Name:     "London Stock Exchange",
+		Region:   "United Kingdom",
+		Suffixes: []string{".L"},
+		Currency: "GBP",
+		TimeZone: "Europe/London",
+		Sessions: []TradingSession{{Open: "08:00", Close: "16:30"}},
+		Benchmarks: []BenchmarkSuggestion{
+			{Symbol: "^FTSE", Name: "FTSE 100"},
+		},
+	},
+	{
+		Code:     "TSE",
+		Name:     "Tokyo Stock Exchange",
+		Region:   "Japan",
+		Suffixes: []string{".T"},
+		Currency: "JPY",
+		TimeZone: "Asia/Tokyo",
+		Sessions: []TradingSession{{Open: "09:00", Close: "11:30"}, {Open: "12:30", Close: "15:30"}},
+		Benchmarks: []BenchmarkSuggestion{
+			{Symbol: "^N225", Name: "Nikkei 225"},
+		},
+	},
+	{
+		Code:     "TSX",
+		Name:     "Toronto Stock Exchange",
+		Region:   "Canada",
+		Suffixes: []string{".TO"},
+		Currency: "CAD",
+		TimeZone: "America/Toronto",
+		Sessions: []TradingSession{{Open: "09:30", Close: "16:00"}},
+		Benchmarks: []BenchmarkSuggestion{
+			{Symbol: "^GSPTSE", Name: "S&P/TSX Composite"},
+		},
+	},
+}
+
+// Markets returns the supported exchanges
+func Markets() []Market {
+	result := make([]Market, len(markets))
+	copy(result, markets)
+	return result
+}
+
+// MarketForSymbol returns the exchange a symbol trades on, or nil for an
+// unsupported suffix. Symbols without a suffix are US.
+func MarketForSymbol(symbol string) *Market {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+
+	// Indices are matched by the benchmarks suggested for each market
+	for i := range markets {
+		for _, benchmark := range markets[i].Benchmarks {
+			if symbol == benchmark.Symbol {
+				return &markets[i]
+			}
+		}
+	}
+
+	dot := strings.LastIndex(symbol, ".")
+	if dot < 0 {
+		return &markets[0]
+	}
+
+	suffix := symbol[dot:]
+	for i := range markets {
+		for _, candidate := range markets[i].Suffixes {
+			if suffix == candidate {
+				return &markets[i]
+			}
+		}
+	}
+
+	// Share classes such as BRK.B are US listings
+	if len(suffix) == 2 && !isNonUSSuffix(suffix) {
+		return &markets[0]
+	}
+	return nil
+}
+
+// isNonUSSuffix reports whether a one-letter suffix belongs to a foreign exchange
+func isNonUSSuffix(suffix string) bool {
+	for _, market := range markets[1:] {
+		for _, candidate := range market.Suffixes {
+			if suffix == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Location returns the market's time zone, falling back to UTC
+func (m *Market) Location() *time.Location {
+	location, err := time.LoadLocation(m.TimeZone)
+	if err != nil {
+		fmt.Printf("[Markets] Warning: unknown time zone %s: %v\n", m.TimeZone, err)
+		return time.UTC
+	}
+	return location
+}
+
+// IsOpen reports whether the market is in a trading session at the given time
+func (m *Market) IsOpen(at time.Time) bool {
+	local := at.In(m.Location())
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return false
+	}
+
+	clock := local.Format("15:04")
+	for _, session := range m.Sessions {
+		if clock >= session.Open && clock < session.Close {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns the start of the next trading session after the given time
+func (m *Market) NextOpen(at time.Time) time.Time {
+	location := m.Location()
+	local := at.In(location)
+
+	for day := 0; day < 8; day++ {
+		date := local.AddDate(0, 0, day)
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+		for _, session := range m.Sessions {
+			open, err := time.ParseInLocation("2006-01-02 15:04", date.Format("2006-01-02")+" "+session.Open, location)
+			if err == nil && open.After(at) {
+				return open
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// SessionDate returns local midnight of the trading day the market is on at
+// the given time: today once its first session has opened, otherwise the most
+// recent weekday before it
+func (m *Market) SessionDate(at time.Time) time.Time {
+	location := m.Location()
+	local := at.In(location)
+	date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+
+	opened := len(m.Sessions) > 0 && local.Format("15:04") >= m.Sessions[0].Open
+	if opened && isWeekday(date) {
+		return date
+	}
+	for {
+		date = date.AddDate(0, 0, -1)
+		if isWeekday(date) {
+			return date
+		}
+	}
+}
+
+// isWeekday reports whether a date falls on Monday to Friday
+func isWeekday(date time.Time) bool {
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+}
+
+// Status returns the market's trading status at the given time
+func (m *Market) Status(at time.Time) MarketStatus {
+	return MarketStatus{
+		Market:    *m,
+		LocalTime: at.In(m.Location()),
+		IsOpen:    m.IsOpen(at),
+		NextOpen:  m.NextOpen(at),
+	}
+}
+
+// IsHongKongStock checks if a symbol is listed in Hong Kong
+func (s *StockAPIService) IsHongKongStock(symbol string) bool {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	return strings.HasSuffix(symbol, ".HK")
+}
+
+// hasEastmoneyData reports whether Eastmoney provides quotes for a symbol
+func (s *StockAPIService) hasEastmoneyData(symbol string) bool {
+	return s.IsChinaStock(symbol) || s.IsHongKongStock(symbol)
+}
+
+// SymbolCurrency returns the currency a symbol is priced in. Cash symbols use
+// their own currency. Other symbols, renamed ones under their current ticker,
+// use the currency the providers reported for them when it has been
+// recorded, and otherwise the currency of their exchange; symbols on
+// unsupported exchanges are assumed USD.
+func (s *StockAPIService) SymbolCurrency(symbol string) string {
+	symbol = s.catalog.Resolve(symbol)
+	if symbol == "CASH_RMB" {
+		return currencycode.RMB
+	}
+	if s.IsCashSymbol(symbol) {
+		return "USD"
+	}
+	if metadata, ok := s.catalog.Lookup(symbol); ok && metadata.Currency != "" {
+		return currencycode.Normalize(metadata.Currency)
+	}
+	if market := MarketForSymbol(symbol); market != nil {
+		return market.Currency
+	}
+	return "USD"
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMarketsPerExchange(t *testing.T) {
+	service := NewStockAPIService(StockAPIConfig{})
+
+	tests := []struct {
+		symbol    string
+		market    string
+		currency  string
+		benchmark string
+		open      time.Time // A weekday moment inside a trading session
+		closed    time.Time // The same weekday outside any session
+	}{
+		{"AAPL", "US", "USD", "^GSPC", time.Date(2024, 7, 10, 14, 0, 0, 0, time.UTC), time.Date(2024, 7, 10, 21, 0, 0, 0, time.UTC)},
+		{"BRK.B", "US", "USD", "^GSPC", time.Date(2024, 1, 10, 15, 0, 0, 0, time.UTC), time.Date(2024, 1, 10, 14, 0, 0, 0, time.UTC)},
+		{"600519.SS", "SSE", "RMB", "000001.SS", time.Date(2024, 7, 10, 2, 0, 0, 0, time.UTC), time.Date(2024, 7, 10, 4, 0, 0, 0, time.UTC)},
+		{"000001.SZ", "SZSE", "RMB", "399001.SZ", time.Date(2024, 7, 10, 6, 0, 0, 0, time.UTC), time.Date(2024, 7, 10, 8, 0, 0, 0, time.UTC)},
+		{"0700.HK", "HKEX", "HKD", "^HSI", time.Date(2024, 7, 10, 2, 0, 0, 0, time.UTC), time.Date(2024, 7, 10, 4, 30, 0, 0, time.UTC)},
+		{"VOD.L", "LSE", "GBP", "^FTSE", time.Date(2024, 7, 10, 7, 30, 0, 0, time.UTC), time.Date(2024, 7, 10, 16, 0, 0, 0, time.UTC)},
+		{"7203.T", "TSE", "JPY", "^N225", time.Date(2024, 7, 10, 0, 30, 0, 0, time.UTC), time.Date(2024, 7, 10, 3, 0, 0, 0, time.UTC)},
+		{"RY.TO", "TSX", "CAD", "^GSPTSE", time.Date(2024, 7, 10, 19, 0, 0, 0, time.UTC), time.Date(2024, 7, 10, 13, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			market := MarketForSymbol(tt.symbol)
+			if market == nil || market.Code != tt.market {
+				t.Fatalf("Expected market %s, got %+v", tt.market, market)
+			}
+			if got := service.SymbolCurrency(tt.symbol); got != tt.currency {
+				t.Errorf("Expected currency %s, got %s", tt.currency, got)
+			}
+			if market.Benchmarks[0].Symbol != tt.benchmark {
+				t.Errorf("Expected benchmark %s, got %s", tt.benchmark, market.Benchmarks[0].Symbol)
+			}
+			if MarketForSymbol(tt.benchmark) != market {
+				t.Errorf("Expected benchmark %s to map back to %s", tt.benchmark, tt.market)
+			}
+			if !market.IsOpen(tt.open) {
+				t.Errorf("Expected %s to be open at %v", tt.market, tt.open)
+			}
+			if market.IsOpen(tt.closed) {
+				t.Errorf("Expected %s to be closed at %v", tt.market, tt.closed)
+			}
+			if market.IsOpen(tt.open.AddDate(0, 0, 3)) {
+				t.Errorf("Expected %s to be closed on Saturday", tt.market)
+			}
+			if next := market.NextOpen(tt.closed); !next.After(tt.closed) || !market.IsOpen(next) {
+				t.Errorf("Expected next open after %v, got %v", tt.closed, next)
+			}
+		})
+	}
+
+	if MarketForSymbol("BHP.AX") != nil || service.IsUSStock("BHP.AX") {
+		t.Errorf("Expected unsupported exchanges to have no market")
+	}
+	if !service.IsUSStock("BRK.B") || service.IsUSStock("0700.HK") {
+		t.Errorf("Unexpected IsUSStock classification")
+	}
+	if service.SymbolCurrency("CASH_RMB") != "RMB" || service.SymbolCurrency("CASH_USD") != "USD" {
+		t.Errorf("Unexpected cash currencies")
+	}
+}
+
+func TestEastmoneySecIDHongKong(t *testing.T) {
+	service := NewStockAPIService(StockAPIConfig{})
+	if secid, err := service.convertToEastmoneySecID("0700.HK"); err != nil || secid != "116.00700" {
+		t.Errorf("Expected 116.00700, got %s, %v", secid, err)
+	}
+	if !service.hasEastmoneyData("0700.HK") || service.hasEastmoneyData("VOD.L") {
+		t.Errorf("Unexpected Eastmoney coverage")
+	}
+}
+
+func TestExtractPenceQuotes(t *testing.T) {
+	service := NewStockAPIService(StockAPIConfig{})
+	var response yahooChartResponse
+	body := `{"chart":{"result":[{"meta":{"symbol":"VOD.L","currency":"GBp","regularMarketPrice":72.5,"regularMarketPreviousClose":71,"longName":"Vodafone Group Plc"},
+		"timestamp":[1720598400,1720684800],"indicators":{"quote":[{"close":[70,72.5]}],"adjclose":[{"adjclose":[69,72.5]}]}}]}}`
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatalf("Failed to parse fixture: %v", err)
+	}
+
+	info, err := service.extractStockInfo(&response)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Currency != "GBP" || math.Abs(info.CurrentPrice-0.725) > 1e-9 {
+		t.Errorf("Expected 0.725 GBP, got %.4f %s", info.CurrentPrice, info.Currency)
+	}
+	if math.Abs(info.PreviousClose-0.71) > 1e-9 {
+		t.Errorf("Expected previous close 0.71 GBP, got %.4f", info.PreviousClose)
+	}
+
+	data, err := service.extractHistoricalData(&response)
+	if err != nil || len(data) != 2 || math.Abs(data[0].Price-0.70) > 1e-9 || math.Abs(data[0].AdjustedPrice-0.69) > 1e-9 {
+		t.Errorf("Expected prices in pounds, got %+v, %v", data, err)
+	}
+}
+
+func TestExtractExtendedHoursQuotes(t *testing.T) {
+	service := NewStockAPIService(StockAPIConfig{})
+	now := time.Now().Unix()
+	var response yahooChartResponse
+	body := fmt.Sprintf(`{"chart":{"result":[{"meta":{"symbol":"AAPL","currency":"USD","regularMarketPrice":200,
+		"currentTradingPeriod":{"pre":{"start":%d,"end":%d},"regular":{"start":%d,"end":%d},"post":{"start":%d,"end":%d}},
+		"preMarketPrice":198,"preMarketTime":%d,"postMarketPrice":205,"postMarketTime":%d}}]}}`,
+		now-7200, now-3600, now-3600, now-600, now-600, now+3600, now-3700, now-60)
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatalf("Failed to parse fixture: %v", err)
+	}
+
+	info, err := service.extractStockInfo(&response)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Session != SessionPost {
+		t.Errorf("Expected the post session, got %q", info.Session)
+	}
+	quote := info.ExtendedHours
+	if quote == nil || quote.Session != SessionPost || quote.Price != 205 || quote.Change != 5 || math.Abs(quote.ChangePercent-2.5) > 1e-9 {
+		t.Fatalf("Expected the later after-hours trade, got %+v", quote)
+	}
+
+	periods := response.Chart.Result[0].Meta.CurrentTradingPeriod
+	if periods.session(time.Unix(now-5000, 0)) != SessionPre || periods.session(time.Unix(now-1800, 0)) != SessionRegular ||
+		periods.session(time.Unix(now+7200, 0)) != SessionClosed || (yahooTradingPeriods{}).session(time.Now()) != "" {
+		t.Errorf("Unexpected session labels")
+	}
+
+	converted := convertedExtendedHours(quote, 2, 10)
+	if converted.Price != 410 || converted.ValueChange != 100 || quote.ValueChange != 0 {
+		t.Errorf("Expected the quote converted and valued, got %+v", converted)
+	}
+}
+
+func TestNewExtendedHoursQuote(t *testing.T) {
+	// The trading day starts at 1000; trades before it are the previous day's
+	const dayStart = 1000
+	tests := []struct {
+		name      string
+		session   string
+		preTime   int64
+		postTime  int64
+		noTrades  bool
+		wantQuote string // Session of the expected trade, empty for none
+	}{
+		{name: "pre-market trade in the pre session", session: SessionPre, preTime: 1100, postTime: 900, wantQuote: SessionPre},
+		{name: "regular session", session: SessionRegular, preTime: 1100, postTime: 900},
+		{name: "unknown session", session: "", preTime: 1100, postTime: 900},
+		{name: "later after-hours trade in the post session", session: SessionPost, preTime: 1100, postTime: 1500, wantQuote: SessionPost},
+		{name: "after-hours trade once closed", session: SessionClosed, preTime: 1100, postTime: 1500, wantQuote: SessionPost},
+		{name: "only the previous day's after-hours trade", session: SessionPre, preTime: 500, postTime: 900},
+		{name: "no trades", session: SessionClosed, noTrades: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prePrice, postPrice := 198.0, 205.0
+			if tt.noTrades {
+				prePrice, postPrice = 0, 0
+			}
+			quote := newExtendedHoursQuote(tt.session, dayStart, 200, prePrice, tt.preTime, postPrice, tt.postTime)
+			if tt.wantQuote == "" {
+				if quote != nil {
+					t.Errorf("Expected no extended-hours quote, got %+v", quote)
+				}
+				return
+			}
+			if quote == nil || quote.Session != tt.wantQuote {
+				t.Fatalf("Expected the %s trade, got %+v", tt.wantQuote, quote)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/internal/analytics/engine"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bounds on the number of movers returned per side
+const (
+	DefaultMoverCount = 5
+	MaxMoverCount     = 20
+)
+
+// MoverSet lists the holdings that rose and fell the most
+type MoverSet struct {
+	Gainers []HoldingMover `json:"gainers"`
+	Losers  []HoldingMover `json:"losers"`
+}
+
+// MoversResponse represents the best and worst performing holdings today and
+// over a period
+type MoversResponse struct {
+	Currency     string    `json:"currency"`
+	Period       string    `json:"period"`
+	PeriodStart  time.Time `json:"periodStart"`
+	Today        MoverSet  `json:"today"`
+	PeriodChange MoverSet  `json:"periodChange"`
+	GeneratedAt  time.Time `json:"generatedAt"`
+}
+
+// GetMovers returns the user's best and worst holdings since the previous
+// close and over the period. Both are computed in a single pass over the
+// holdings from one cached price history per symbol. Period changes measure
+// the price movement of the shares currently held. Holdings at a manual
+// price have no market movement and are left out. Prices are fetched under
+// ctx.
+func (s *AnalyticsService) GetMovers(ctx context.Context, userID primitive.ObjectID, period string, currency string, count int) (*MoversResponse, error) {
+	currency = currencycode.Normalize(currency)
+
+	holdings, err := s.portfolioService.GetUserHoldingsContext(ctx, userID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch holdings: %w", err)
+	}
+
+	now := time.Now()
+	start := PeriodStart(period, now)
+	rates := map[string]float64{currency: 1}
+
+	dayMovers := make([]HoldingMover, 0, len(holdings))
+	periodMovers := make([]HoldingMover, 0, len(holdings))
+	for _, holding := range holdings {
+		if s.stockService.IsCashSymbol(holding.Symbol) || holding.PriceOverride != nil {
+			continue
+		}
+
+		// The period history always covers at least a month, so it also holds the previous close
+		prices, err := s.stockService.GetHistoricalDataContext(ctx, holding.Symbol, period)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			fmt.Printf("[Analytics] Warning: Could not get price history for %s: %v\n", holding.Symbol, err)
+			continue
+		}
+
+		symbolCurrency := s.stockService.SymbolCurrency(holding.Symbol)
+		rate, ok := rates[symbolCurrency]
+		if !ok {
+			rate, err = s.currencyService.GetExchangeRate(symbolCurrency, currency)
+			if err != nil {
+				fmt.Printf("[Analytics] Warning: Could not convert currency for %s: %v\n", holding.Symbol, err)
+				continue
+			}
+			rates[symbolCurrency] = rate
+		}
+
+		prices = engine.Sorted(prices)
+		previousClose, ok := s.quotePreviousClose(ctx, holding.Symbol)
+		if !ok {
+			previousClose, ok = previousCloseFromHistory(holding.Symbol, prices, now)
+		}
+		if !ok {
+			continue
+		}
+
+		day, period, ok := holdingMoves(holding, prices, previousClose, rate, start)
+		if !ok {
+			continue
+		}
+		dayMovers = append(dayMovers, day)
+		periodMovers = append(periodMovers, period)
+	}
+
+	response := &MoversResponse{
+		Currency:    currency,
+		Period:      period,
+		PeriodStart: start,
+		GeneratedAt: now,
+	}
+	response.Today.Gainers, response.Today.Losers = splitMovers(sortMovers(dayMovers), count)
+	response.PeriodChange.Gainers, response.PeriodChange.Losers = splitMovers(sortMovers(periodMovers), count)
+
+	return response, nil
+}
+
+// holdingMoves computes a holding's change since the previous close and since
+// the start of the period from its date-sorted native-currency prices and
+// previous close
+func holdingMoves(holding Holding, prices []HistoricalPrice, previousClose, rate float64, start time.Time) (day HoldingMover, period HoldingMover, ok bool) {
+	if len(prices) == 0 || holding.Shares <= 0 {
+		return day, period, false
+	}
+
+	previousValue := holding.Shares * previousClose * rate
+
+	// The period starts at the last price on or before its start date, or the
+	// first available price for a shorter history
+	startPrice := prices[0].Price
+	for _, price := range prices {
+		if price.Date.After(start) {
+			break
+		}
+		startPrice = price.Price
+	}
+	startValue := holding.Shares * startPrice * rate
+
+	if previousValue <= 0 || startValue <= 0 {
+		return day, period, false
+	}
+
+	day = HoldingMover{
+		Symbol:        holding.Symbol,
+		Name:          holding.Name,
+		Value:         holding.CurrentValue,
+		Change:        holding.CurrentValue - previousValue,
+		ChangePercent: (holding.CurrentValue/previousValue - 1) * 100,
+	}
+	period = HoldingMover{
+		Symbol:        holding.Symbol,
+		Name:          holding.Name,
+		Value:         holding.CurrentValue,
+		Change:        holding.CurrentValue - startValue,
+		ChangePercent: (holding.CurrentValue/startValue - 1) * 100,
+	}
+	return day, period, true
+}
+
+// sortMovers orders movers from best to worst percentage change
+func sortMovers(movers []HoldingMover) []HoldingMover {
+	sort.Slice(movers, func(i, j int) bool {
+		return movers[i].ChangePercent > movers[j].ChangePercent
+	})
+	return movers
+}
+
+// splitMovers returns up to count holdings that rose the most and up to count
+// that fell the most from movers sorted best to worst
+func splitMovers(movers []HoldingMover, count int) (gainers []HoldingMover, losers []HoldingMover) {
+	gainers = []HoldingMover{}
+	losers = []HoldingMover{}
+
+	for _, mover := range movers {
+		if mover.ChangePercent <= 0 || len(gainers) == count {
+			break
+		}
+		gainers = append(gainers, mover)
+	}
+
+	for i := len(movers) - 1; i >= 0; i-- {
+		if movers[i].ChangePercent >= 0 || len(losers) == count {
+			break
+		}
+		losers = append(losers, movers[i])
+	}
+
+	return gainers, losers
+}
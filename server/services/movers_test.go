@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"math"
+	"stock-portfolio-tracker/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHoldingMoves(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC)
+	}
+	prices := []HistoricalPrice{
+		{Date: day(1), Price: 80},
+		{Date: day(2), Price: 90},
+		{Date: day(9), Price: 100},
+		{Date: day(10), Price: 110},
+	}
+	// 10 shares at 110 USD shown in RMB at a rate of 7
+	holding := Holding{Symbol: "AAPL", Shares: 10, CurrentValue: 7700}
+
+	today, period, ok := holdingMoves(holding, prices, 100, 7, day(2).Add(12*time.Hour))
+	if !ok {
+		t.Fatalf("Expected moves to be computed")
+	}
+	if math.Abs(today.Change-700) > 1e-9 || math.Abs(today.ChangePercent-10) > 1e-9 {
+		t.Errorf("Unexpected day move %+v", today)
+	}
+	if math.Abs(period.Change-1400) > 1e-9 || math.Abs(period.ChangePercent-100.0*20/90) > 1e-9 {
+		t.Errorf("Unexpected period move %+v", period)
+	}
+
+	if _, _, ok := holdingMoves(holding, prices, 0, 7, day(1)); ok {
+		t.Errorf("Expected a missing previous close to be insufficient")
+	}
+}
+
+func TestGetMoversSkipsPriceOverrides(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 110, "USD").
+		SetPreviousClose("AAPL", 105).
+		SetHistory("AAPL", today, 100, 102, 101, 105, 110).
+		SetQuote("FUND", "Illiquid Fund", 10, "USD").
+		SetPreviousClose("FUND", 9).
+		SetHistory("FUND", today, 9, 9, 9, 9, 10)
+	service, portfolioService := newFixtureAnalyticsService(provider)
+	userID := primitive.NewObjectID()
+
+	for _, symbol := range []string{"AAPL", "FUND"} {
+		if err := portfolioService.AddTransaction(userID, &models.Transaction{
+			Symbol: symbol, Action: "buy", Shares: 10, Price: 9, Currency: "USD", Date: today.AddDate(0, 0, -10),
+		}); err != nil {
+			t.Fatalf("Failed to add %s: %v", symbol, err)
+		}
+	}
+	// The manual price would look like a doubling since the provider's close
+	if _, err := NewPriceOverrideService(portfolioService).SetOverride(userID, "FUND", models.PriceOverrideRequest{Price: 20, EffectiveDate: today.AddDate(0, 0, -1)}); err != nil {
+		t.Fatalf("Failed to set override: %v", err)
+	}
+
+	movers, err := service.GetMovers(context.Background(), userID, "1M", "USD", DefaultMoverCount)
+	if err != nil {
+		t.Fatalf("GetMovers failed: %v", err)
+	}
+	for name, set := range map[string]MoverSet{"today": movers.Today, "period": movers.PeriodChange} {
+		if len(set.Gainers) != 1 || set.Gainers[0].Symbol != "AAPL" || len(set.Losers) != 0 {
+			t.Errorf("Expected only AAPL among the %s movers, got %+v", name, set)
+		}
+	}
+}
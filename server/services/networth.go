@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NetWorthDataPoint is one day of the net worth timeline. Value is the total
+// net worth, so the embedded fields match the performance series.
+type NetWorthDataPoint struct {
+	PerformanceDataPoint
+	Investments      float64 `json:"investments"`
+	Cash             float64 `json:"cash"`
+	NetContributions float64 `json:"netContributions"` // Cumulative money put in minus money taken out
+	Gain             float64 `json:"gain"`             // Net worth minus net contributions
+}
+
+// NetWorthResponse represents the net worth timeline with performance metrics
+type NetWorthResponse struct {
+	Period   string              `json:"period"`
+	Currency string              `json:"currency"`
+	NetWorth []NetWorthDataPoint `json:"netWorth"`
+	Metrics  *PerformanceMetrics `json:"metrics"`
+}
+
+// contributionChange records the cumulative net contribution after a transaction
+type contributionChange struct {
+	Date  time.Time
+	Total float64
+}
+
+// GetNetWorthTimeline returns the historical net worth split into investments
+// and cash, with cumulative net contributions overlaid. Prices are fetched
+// under ctx.
+func (s *AnalyticsService) GetNetWorthTimeline(ctx context.Context, userID primitive.ObjectID, period string, currency string) (*NetWorthResponse, error) {
+	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
+	if !validPeriods[period] {
+		return nil, fmt.Errorf("invalid period: must be 1M, 3M, 6M, 1Y, or ALL")
+	}
+	currency, err := NormalizeDisplayCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &NetWorthResponse{
+		Period:   period,
+		Currency: currency,
+		NetWorth: []NetWorthDataPoint{},
+		Metrics:  &PerformanceMetrics{},
+	}
+
+	dates, cursors, err := s.loadSeriesCursors(ctx, userID, period, currency, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(cursors) == 0 {
+		return response, nil
+	}
+
+	transactions, err := s.portfolioService.GetTransactionsSinceContext(ctx, userID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	contributions := s.contributionTimeline(transactions, currency)
+
+	performance := make([]PerformanceDataPoint, 0, len(dates))
+	response.NetWorth = make([]NetWorthDataPoint, 0, len(dates))
+	contributionIndex := 0
+	netContributions := 0.0
+	for _, date := range dates {
+		point := NetWorthDataPoint{PerformanceDataPoint: PerformanceDataPoint{Date: date}}
+		for _, c := range cursors {
+			value := c.valueAt(date)
+			if s.stockService.IsCashSymbol(c.symbol) {
+				point.Cash += value
+			} else {
+				point.Investments += value
+			}
+		}
+		point.Value = point.Investments + point.Cash
+
+		for contributionIndex < len(contributions) && !contributions[contributionIndex].Date.After(date) {
+			netContributions = contributions[contributionIndex].Total
+			contributionIndex++
+		}
+		point.NetContributions = netContributions
+		point.Gain = point.Value - netContributions
+
+		performance = append(performance, point.PerformanceDataPoint)
+		response.NetWorth = append(response.NetWorth, point)
+	}
+
+	applyPerformanceReturns(performance)
+	for i := range response.NetWorth {
+		response.NetWorth[i].PerformanceDataPoint = performance[i]
+	}
+
+	if metrics, err := s.CalculatePerformanceMetrics(performance); err == nil {
+		response.Metrics = metrics
+	} else {
+		fmt.Printf("Warning: failed to calculate net worth metrics: %v\n", err)
+	}
+
+	return response, nil
+}
+
+// contributionTimeline folds transactions into cumulative net contributions in
+// the target currency. Buys add their cost including fees and sells subtract
+// their proceeds net of fees. Recorded cash interest is a gain, not a
+// contribution, and is skipped. Amounts are converted at the current rate, as
+// the value series is.
+func (s *AnalyticsService) contributionTimeline(transactions []models.Transaction, currency string) []contributionChange {
+	sorted := make([]models.Transaction, len(transactions))
+	copy(sorted, transactions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	rates := map[string]float64{currency: 1}
+	timeline := make([]contributionChange, 0, len(sorted))
+	total := 0.0
+	for _, tx := range sorted {
+		if isCashInterest(tx) {
+			continue
+		}
+		rate, ok := rates[tx.Currency]
+		if !ok {
+			converted, err := s.currencyService.GetExchangeRate(tx.Currency, currency)
+			if err != nil {
+				fmt.Printf("Warning: failed to get exchange rate for %s: %v\n", tx.Currency, err)
+				converted = 1
+			}
+			rates[tx.Currency] = converted
+			rate = converted
+		}
+
+		amount := tx.Shares * tx.Price
+		if tx.Action == "sell" {
+			total -= (amount - tx.Fees) * rate
+		} else {
+			total += (amount + tx.Fees) * rate
+		}
+		timeline = append(timeline, contributionChange{Date: tx.Date, Total: total})
+	}
+
+	return timeline
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"stock-portfolio-tracker/models"
+	"testing"
+)
+
+func TestContributionTimeline(t *testing.T) {
+	service := &AnalyticsService{}
+	transactions := []models.Transaction{
+		{Symbol: "AAPL", Action: "sell", Shares: 5, Price: 120, Fees: 1, Currency: "USD", Date: tradeDate(2024, 3, 1)},
+		{Symbol: "CASH_USD", Action: "buy", Shares: 500, Price: 1, Currency: "USD", Date: tradeDate(2024, 2, 1)},
+		{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 100, Fees: 5, Currency: "USD", Date: tradeDate(2024, 1, 1)},
+	}
+
+	timeline := service.contributionTimeline(transactions, "USD")
+
+	want := []float64{1005, 1505, 906}
+	if len(timeline) != len(want) {
+		t.Fatalf("Expected %d changes, got %d", len(want), len(timeline))
+	}
+	for i, total := range want {
+		if timeline[i].Total != total {
+			t.Errorf("Change %d: expected %.2f, got %.2f", i, total, timeline[i].Total)
+		}
+	}
+	if !timeline[0].Date.Equal(tradeDate(2024, 1, 1)) {
+		t.Errorf("Expected changes in date order")
+	}
+}
@@ -0,0 +1,439 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/telemetry"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	yahooNewsSearchURL = "https://query1.finance.yahoo.com/v1/finance/search"
+	yahooNewsRSSURL    = "https://feeds.finance.yahoo.com/rss/2.0/headline"
+
+	// newsPerSymbol is the number of headlines requested for each symbol
+	newsPerSymbol = 20
+
+	// newsFetchConcurrency bounds the symbols fetched at once for a portfolio feed
+	newsFetchConcurrency = 4
+)
+
+// Bounds on the number of headlines returned
+const (
+	DefaultNewsLimit = 20
+	MaxNewsLimit     = 100
+)
+
+// NewsItem represents a headline about one or more symbols
+type NewsItem struct {
+	Title       string    `json:"title"`
+	Publisher   string    `json:"publisher,omitempty"`
+	Link        string    `json:"link"`
+	PublishedAt time.Time `json:"publishedAt"`
+	Symbols     []string  `json:"symbols"`
+}
+
+// CachedNews represents a symbol's cached headlines with expiration
+type CachedNews struct {
+	Data      []NewsItem
+	ExpiresAt time.Time
+}
+
+// NewsConfig configures the news provider. Zero fields use the defaults.
+type NewsConfig struct {
+	Timeout  time.Duration // Default 30s
+	CacheTTL time.Duration // Default 15m
+}
+
+// NewsService aggregates Yahoo Finance headlines per symbol and across a
+// user's holdings
+type NewsService struct {
+	httpClient        *http.Client
+	portfolioService  *PortfolioService
+	newsCache         map[string]*CachedNews
+	cacheMutex        sync.RWMutex
+	newsCacheDuration time.Duration
+}
+
+// NewNewsService creates a new NewsService instance
+func NewNewsService(portfolioService *PortfolioService, config NewsConfig) *NewsService {
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = 15 * time.Minute
+	}
+
+	return &NewsService{
+		httpClient: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: telemetry.Transport(nil),
+		},
+		portfolioService:  portfolioService,
+		newsCache:         make(map[string]*CachedNews),
+		newsCacheDuration: config.CacheTTL,
+	}
+}
+
+// Yahoo Finance search API response structure, keeping only the news
+type yahooNewsResponse struct {
+	News []struct {
+		Title               string `json:"title"`
+		Publisher           string `json:"publisher"`
+		Link                string `json:"link"`
+		ProviderPublishTime int64  `json:"providerPublishTime"`
+	} `json:"news"`
+}
+
+// Yahoo Finance headline RSS feed structure
+type yahooNewsRSS struct {
+	Channel struct {
+		Items []struct {
+			Title   string `xml:"title"`
+			Link    string `xml:"link"`
+			PubDate string `xml:"pubDate"`
+			Source  string `xml:"source"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// GetSymbolNews returns the latest headlines about a symbol, newest first.
+// Headlines come from the Yahoo Finance search API, falling back to its RSS
+// feed. Option contracts share their underlying's news.
+func (s *NewsService) GetSymbolNews(ctx context.Context, symbol string, limit int) ([]NewsItem, error) {
+	symbol = newsSymbol(symbol)
+	if symbol == "" {
+		return nil, ErrInvalidSymbol
+	}
+
+	items, err := s.fetchSymbolNews(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	return limitNews(items, limit), nil
+}
+
+// GetPortfolioNews returns the latest headlines about the user's open
+// positions merged into one feed, newest first. A headline about several
+// holdings is listed once with all of their symbols. Symbols whose news
+// cannot be fetched are left out.
+func (s *NewsService) GetPortfolioNews(ctx context.Context, userID primitive.ObjectID, limit int) ([]NewsItem, error) {
+	positions, err := s.portfolioService.getPositions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := make([]string, 0, len(positions))
+	seen := make(map[string]bool)
+	for _, position := range positions {
+		symbol := newsSymbol(position.Symbol)
+		if position.Shares <= 0 || symbol == "" || strings.HasPrefix(symbol, "CASH_") || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+	}
+
+	feeds := make([][]NewsItem, len(symbols))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, newsFetchConcurrency)
+	for i, symbol := range symbols {
+		wg.Add(1)
+		go func(i int, symbol string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			items, err := s.fetchSymbolNews(ctx, symbol)
+			if err != nil {
+				fmt.Printf("[News] Warning: Could not get news for %s: %v\n", symbol, err)
+				return
+			}
+			feeds[i] = items
+		}(i, symbol)
+	}
+	wg.Wait()
+
+	var merged []NewsItem
+	for _, items := range feeds {
+		merged = append(merged, items...)
+	}
+	return limitNews(dedupeNews(merged), limit), nil
+}
+
+// fetchSymbolNews returns a symbol's cached headlines, fetching them when
+// the cache has expired
+func (s *NewsService) fetchSymbolNews(ctx context.Context, symbol string) ([]NewsItem, error) {
+	if cached, found := s.getCachedNews(symbol); found {
+		return cached, nil
+	}
+
+	items, err := s.fetchYahooNews(ctx, symbol)
+	if err != nil || len(items) == 0 {
+		if err != nil {
+			fmt.Printf("[News] WARNING: Yahoo Finance news failed for %s, falling back to RSS: %v\n", symbol, err)
+		}
+		rssItems, rssErr := s.fetchRSSNews(ctx, symbol)
+		if rssErr != nil {
+			if err != nil {
+				return nil, err
+			}
+			return nil, rssErr
+		}
+		items = rssItems
+	}
+
+	items = dedupeNews(items)
+	s.setCachedNews(symbol, items)
+	return items, nil
+}
+
+// fetchYahooNews fetches a symbol's headlines from the Yahoo Finance search API
+func (s *NewsService) fetchYahooNews(ctx context.Context, symbol string) ([]NewsItem, error) {
+	params := url.Values{}
+	params.Set("q", symbol)
+	params.Set("quotesCount", "0")
+	params.Set("newsCount", fmt.Sprint(newsPerSymbol))
+
+	body, err := s.fetch(ctx, yahooNewsSearchURL+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	return parseYahooNews(symbol, body)
+}
+
+// fetchRSSNews fetches a symbol's headlines from the Yahoo Finance RSS feed
+func (s *NewsService) fetchRSSNews(ctx context.Context, symbol string) ([]NewsItem, error) {
+	params := url.Values{}
+	params.Set("s", symbol)
+	params.Set("region", "US")
+	params.Set("lang", "en-US")
+
+	body, err := s.fetch(ctx, yahooNewsRSSURL+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	return parseNewsRSS(symbol, body)
+}
+
+// fetch performs a GET against a news endpoint and returns the body
+func (s *NewsService) fetch(ctx context.Context, url string) ([]byte, error) {
+	fmt.Printf("[News] HTTP GET: %s\n", url)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExternalAPI, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status code %d", ErrExternalAPI, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
+
+// parseYahooNews extracts headlines from a Yahoo Finance search response,
+// tagged with the requested symbol
+func parseYahooNews(symbol string, body []byte) ([]NewsItem, error) {
+	var response yahooNewsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	items := make([]NewsItem, 0, len(response.News))
+	for _, news := range response.News {
+		if news.Title == "" || news.Link == "" {
+			continue
+		}
+		items = append(items, NewsItem{
+			Title:       news.Title,
+			Publisher:   news.Publisher,
+			Link:        news.Link,
+			PublishedAt: time.Unix(news.ProviderPublishTime, 0).UTC(),
+			Symbols:     []string{symbol},
+		})
+	}
+	return items, nil
+}
+
+// parseNewsRSS extracts headlines from a Yahoo Finance RSS feed
+func parseNewsRSS(symbol string, body []byte) ([]NewsItem, error) {
+	var feed yahooNewsRSS
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+
+	items := make([]NewsItem, 0, len(feed.Channel.Items))
+	for _, item := range feed.Channel.Items {
+		title := strings.TrimSpace(item.Title)
+		link := strings.TrimSpace(item.Link)
+		if title == "" || link == "" {
+			continue
+		}
+
+		var published time.Time
+		for _, layout := range []string{time.RFC1123Z, time.RFC1123} {
+			if parsed, err := time.Parse(layout, strings.TrimSpace(item.PubDate)); err == nil {
+				published = parsed.UTC()
+				break
+			}
+		}
+
+		items = append(items, NewsItem{
+			Title:       title,
+			Publisher:   strings.TrimSpace(item.Source),
+			Link:        link,
+			PublishedAt: published,
+			Symbols:     []string{symbol},
+		})
+	}
+	return items, nil
+}
+
+// dedupeNews merges headlines that share a link or title, keeping the
+// earliest publication time and the symbols of every copy, and sorts them
+// newest first
+func dedupeNews(items []NewsItem) []NewsItem {
+	merged := make([]NewsItem, 0, len(items))
+	byKey := make(map[string]int)
+	for _, item := range items {
+		linkKey := "link:" + newsLinkKey(item.Link)
+		titleKey := "title:" + strings.ToLower(strings.Join(strings.Fields(item.Title), " "))
+
+		i, found := byKey[linkKey]
+		if !found {
+			i, found = byKey[titleKey]
+		}
+		if !found {
+			item.Symbols = append([]string(nil), item.Symbols...)
+			merged = append(merged, item)
+			i = len(merged) - 1
+		} else {
+			existing := &merged[i]
+			if !item.PublishedAt.IsZero() && (existing.PublishedAt.IsZero() || item.PublishedAt.Before(existing.PublishedAt)) {
+				existing.PublishedAt = item.PublishedAt
+			}
+			for _, symbol := range item.Symbols {
+				if !containsString(existing.Symbols, symbol) {
+					existing.Symbols = append(existing.Symbols, symbol)
+				}
+			}
+		}
+		byKey[linkKey] = i
+		byKey[titleKey] = i
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].PublishedAt.After(merged[j].PublishedAt)
+	})
+	return merged
+}
+
+// newsLinkKey normalizes a headline link for deduplication, ignoring the
+// scheme, query string and trailing slash
+func newsLinkKey(link string) string {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || parsed.Host == "" {
+		return strings.ToLower(strings.TrimSpace(link))
+	}
+	return strings.ToLower(parsed.Host) + strings.TrimSuffix(parsed.Path, "/")
+}
+
+// newsSymbol returns the symbol whose news covers a holding: the symbol
+// itself, or an option contract's underlying
+func newsSymbol(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if contract, ok := models.ParseOptionSymbol(symbol); ok {
+		return contract.Underlying
+	}
+	return symbol
+}
+
+// limitNews returns at most limit headlines, all of them when limit is not positive
+func limitNews(items []NewsItem, limit int) []NewsItem {
+	if items == nil {
+		return []NewsItem{}
+	}
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// getCachedNews retrieves a symbol's headlines from cache if available and not expired
+func (s *NewsService) getCachedNews(symbol string) ([]NewsItem, bool) {
+	s.cacheMutex.RLock()
+	defer s.cacheMutex.RUnlock()
+
+	cached, exists := s.newsCache[symbol]
+	if !exists || time.Now().After(cached.ExpiresAt) {
+		return nil, false
+	}
+	return cached.Data, true
+}
+
+// setCachedNews stores a symbol's headlines in cache with expiration
+func (s *NewsService) setCachedNews(symbol string, items []NewsItem) {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+
+	s.newsCache[symbol] = &CachedNews{
+		Data:      items,
+		ExpiresAt: time.Now().Add(s.newsCacheDuration),
+	}
+}
+
+// cleanupExpiredCache removes expired headlines from cache
+func (s *NewsService) cleanupExpiredCache() {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+
+	now := time.Now()
+	for symbol, cached := range s.newsCache {
+		if now.After(cached.ExpiresAt) {
+			delete(s.newsCache, symbol)
+		}
+	}
+}
+
+// StartCacheCleanup starts a background goroutine to periodically clean expired cache entries
+func (s *NewsService) StartCacheCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			s.cleanupExpiredCache()
+		}
+	}()
+}
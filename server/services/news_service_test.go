@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseYahooNews(t *testing.T) {
+	items, err := parseYahooNews("AAPL", []byte(`{"news":[
+		{"title":"Apple unveils new iPhone","publisher":"Reuters","link":"https://finance.yahoo.com/news/apple-iphone.html","providerPublishTime":1718000000},
+		{"title":"","link":"https://finance.yahoo.com/news/empty.html","providerPublishTime":1718000100}
+	]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Expected headlines without a title to be skipped, got %d", len(items))
+	}
+	if item := items[0]; item.Publisher != "Reuters" || !item.PublishedAt.Equal(time.Unix(1718000000, 0)) || len(item.Symbols) != 1 || item.Symbols[0] != "AAPL" {
+		t.Errorf("Unexpected headline %+v", item)
+	}
+
+	if _, err := parseYahooNews("AAPL", []byte(`not json`)); err == nil {
+		t.Errorf("Expected an error for a malformed response")
+	}
+}
+
+func TestParseNewsRSS(t *testing.T) {
+	items, err := parseNewsRSS("MSFT", []byte(`<?xml version="1.0"?><rss><channel>
+		<item><title> Microsoft beats estimates </title><link>https://finance.yahoo.com/news/msft.html</link><pubDate>Mon, 10 Jun 2024 14:30:00 +0000</pubDate></item>
+	</channel></rss>`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Title != "Microsoft beats estimates" {
+		t.Fatalf("Unexpected headlines %+v", items)
+	}
+	if want := time.Date(2024, 6, 10, 14, 30, 0, 0, time.UTC); !items[0].PublishedAt.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, items[0].PublishedAt)
+	}
+}
+
+func TestDedupeNews(t *testing.T) {
+	early := time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)
+	late := early.Add(2 * time.Hour)
+	items := []NewsItem{
+		{Title: "Chip stocks rally", Link: "https://finance.yahoo.com/news/chips.html?src=aapl", PublishedAt: late, Symbols: []string{"AAPL"}},
+		{Title: "Fed holds rates", Link: "https://finance.yahoo.com/news/fed.html", PublishedAt: early, Symbols: []string{"AAPL"}},
+		{Title: "Chip stocks rally", Link: "http://finance.yahoo.com/news/chips.html/", PublishedAt: early, Symbols: []string{"NVDA"}},
+		{Title: "fed  holds rates", Link: "https://other.example.com/fed", PublishedAt: early, Symbols: []string{"MSFT"}},
+	}
+
+	merged := dedupeNews(items)
+	if len(merged) != 2 {
+		t.Fatalf("Expected 2 headlines after deduplication, got %d", len(merged))
+	}
+	for _, item := range merged {
+		if len(item.Symbols) != 2 || !item.PublishedAt.Equal(early) {
+			t.Errorf("Expected both symbols and the earliest time, got %+v", item)
+		}
+	}
+	if len(items[0].Symbols) != 1 {
+		t.Errorf("Expected the input symbols to be left unchanged")
+	}
+
+	if news := limitNews(nil, 5); news == nil || len(news) != 0 {
+		t.Errorf("Expected an empty feed, got %v", news)
+	}
+}
+
+func TestNewsSymbol(t *testing.T) {
+	if symbol := newsSymbol(" aapl250117c00150000 "); symbol != "AAPL" {
+		t.Errorf("Expected an option's underlying, got %s", symbol)
+	}
+	if symbol := newsSymbol("0700.hk"); symbol != "0700.HK" {
+		t.Errorf("Expected the symbol itself, got %s", symbol)
+	}
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrNoNotificationChannels = errors.New("no notification channels configured")
+	ErrRecipientNotFound      = errors.New("notification recipient not found")
+
+	// errClaimLost aborts a NotifyWith change whose conditional update found
+	// that another writer got there first
+	errClaimLost = errors.New("claimed by another writer")
+)
+
+const (
+	// OutboxJobInterval is how often queued notifications are delivered
+	OutboxJobInterval = 30 * time.Second
+	// outboxBatch bounds the messages delivered by one run
+	outboxBatch = 100
+	// outboxLease is how long a claimed message is left to one worker
+	// before another may retry it
+	outboxLease = 5 * time.Minute
+	// outboxMaxAttempts is how often delivery is tried before a message is
+	// marked failed
+	outboxMaxAttempts = 8
+)
+
+// Notification represents a message delivered to a user
+type Notification struct {
+	Subject string
+	Text    string // Plain text body
+	HTML    string // Optional HTML body
+	Source  string // The job that raised it, when delivered from the outbox
+}
+
+// NotificationChannel delivers notifications through one medium
+type NotificationChannel interface {
+	Name() string
+	Send(recipient *models.User, notification Notification) error
+}
+
+// NotificationService delivers notifications to users through the configured
+// channels, either directly or through the outbox
+type NotificationService struct {
+	repos    repository.Repositories
+	channels []NotificationChannel
+}
+
+// NewNotificationService creates a new NotificationService instance queueing
+// notifications in MongoDB
+func NewNotificationService(channels ...NotificationChannel) *NotificationService {
+	return NewNotificationServiceWithRepos(repository.NewMongo(), channels...)
+}
+
+// NewNotificationServiceWithRepos creates a NotificationService over the given repositories
+func NewNotificationServiceWithRepos(repos repository.Repositories, channels ...NotificationChannel) *NotificationService {
+	return &NotificationService{
+		repos:    repos,
+		channels: channels,
+	}
+}
+
+// NotifyWith runs change and queues the notification in the same
+// transaction, so the user hears about the change exactly when it is
+// committed, even if the server stops before delivery. DeliverOutbox sends
+// it later. On a nil service change runs alone.
+func (s *NotificationService) NotifyWith(ctx context.Context, source string, userID primitive.ObjectID, notification Notification, change func(ctx context.Context) error) error {
+	if s == nil {
+		return change(ctx)
+	}
+
+	return s.repos.Tx.Run(ctx, func(ctx context.Context) error {
+		if err := change(ctx); err != nil {
+			return err
+		}
+		now := time.Now()
+		return s.repos.Outbox.Insert(ctx, &models.OutboxMessage{
+			ID:            primitive.NewObjectID(),
+			UserID:        userID,
+			Source:        source,
+			Subject:       notification.Subject,
+			Text:          notification.Text,
+			HTML:          notification.HTML,
+			Status:        models.OutboxPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	})
+}
+
+// DeliverOutbox sends the queued notifications that are due. A failed
+// delivery is retried with exponential backoff until outboxMaxAttempts.
+func (s *NotificationService) DeliverOutbox() error {
+	now := time.Now()
+	delivered := 0
+	var errs []error
+	for i := 0; i < outboxBatch; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		msg, err := s.repos.Outbox.ClaimDue(ctx, now, now.Add(outboxLease))
+		cancel()
+		if errors.Is(err, repository.ErrNotFound) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to claim outbox message: %w", err))
+			break
+		}
+
+		if err := s.deliver(msg); err != nil {
+			errs = append(errs, err)
+		} else if msg.Status == models.OutboxDelivered {
+			delivered++
+		}
+	}
+
+	if delivered > 0 {
+		fmt.Printf("[Notification] Delivered %d queued notifications\n", delivered)
+	}
+	return errors.Join(errs...)
+}
+
+// deliver sends a claimed outbox message through the channels that have not
+// delivered it yet and records the outcome on each. It returns an error when
+// the message was given up on or could not be updated.
+func (s *NotificationService) deliver(msg *models.OutboxMessage) error {
+	sendErr := s.sendPending(msg)
+	now := time.Now()
+	switch {
+	case sendErr == nil:
+		msg.Status = models.OutboxDelivered
+		msg.DeliveredAt = &now
+		msg.LastError = ""
+	case errors.Is(sendErr, ErrRecipientNotFound) || msg.Attempts >= outboxMaxAttempts:
+		msg.Status = models.OutboxFailed
+		msg.LastError = sendErr.Error()
+	default:
+		msg.NextAttemptAt = now.Add(outboxBackoff(msg.Attempts))
+		msg.LastError = sendErr.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.repos.Outbox.Update(ctx, msg); err != nil {
+		return fmt.Errorf("failed to update outbox message %s: %w", msg.ID.Hex(), err)
+	}
+	if msg.Status == models.OutboxFailed {
+		return fmt.Errorf("gave up on %s notification %s after %d attempts: %w", msg.Source, msg.ID.Hex(), msg.Attempts, sendErr)
+	}
+	return nil
+}
+
+// sendPending sends an outbox message through every channel it has not been
+// delivered on, so a retry doesn't repeat the channels that already succeeded
+func (s *NotificationService) sendPending(msg *models.OutboxMessage) error {
+	if len(s.channels) == 0 {
+		return ErrNoNotificationChannels
+	}
+
+	user, err := s.getRecipient(msg.UserID)
+	if err != nil {
+		return err
+	}
+
+	notification := Notification{Subject: msg.Subject, Text: msg.Text, HTML: msg.HTML, Source: msg.Source}
+	var errs []error
+	for _, channel := range s.channels {
+		status := msg.ChannelStatus(channel.Name())
+		if status.DeliveredAt != nil {
+			continue
+		}
+		if err := channel.Send(user, notification); err != nil {
+			fmt.Printf("[Notification] Failed to send %q to user %s via %s: %v\n",
+				notification.Subject, msg.UserID.Hex(), channel.Name(), err)
+			status.LastError = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", channel.Name(), err))
+			continue
+		}
+		now := time.Now()
+		status.DeliveredAt = &now
+		status.LastError = ""
+	}
+
+	return errors.Join(errs...)
+}
+
+// outboxBackoff is the delay before the next delivery attempt: a minute,
+// doubling after each failure up to an hour
+func outboxBackoff(attempts int) time.Duration {
+	if attempts > 6 {
+		return time.Hour
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+	return time.Minute << (attempts - 1)
+}
+
+// Notify sends a notification to the user through every channel. Delivery is
+// attempted on all channels even if one fails.
+func (s *NotificationService) Notify(userID primitive.ObjectID, notification Notification) error {
+	if len(s.channels) == 0 {
+		return ErrNoNotificationChannels
+	}
+
+	user, err := s.getRecipient(userID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, channel := range s.channels {
+		if err := channel.Send(user, notification); err != nil {
+			fmt.Printf("[Notification] Failed to send %q to user %s via %s: %v\n",
+				notification.Subject, userID.Hex(), channel.Name(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", channel.Name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// getRecipient loads the user a notification is addressed to
+func (s *NotificationService) getRecipient(userID primitive.ObjectID) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := s.repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrRecipientNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch notification recipient: %w", err)
+	}
+
+	return user, nil
+}
+
+// LogChannel prints notifications instead of delivering them, for development
+// environments without an email server
+type LogChannel struct{}
+
+// Name returns the channel name
+func (c *LogChannel) Name() string {
+	return "log"
+}
+
+// Send prints the notification
+func (c *LogChannel) Send(recipient *models.User, notification Notification) error {
+	fmt.Printf("[Notification] To: %s\nSubject: %s\n\n%s\n", recipient.Email, notification.Subject, notification.Text)
+	return nil
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// flakyChannel fails the first failures sends and records the rest
+type flakyChannel struct {
+	name     string
+	failures int
+	sent     []Notification
+}
+
+func (c *flakyChannel) Name() string {
+	if c.name != "" {
+		return c.name
+	}
+	return "flaky"
+}
+
+func (c *flakyChannel) Send(recipient *models.User, notification Notification) error {
+	if c.failures > 0 {
+		c.failures--
+		return errors.New("connection refused")
+	}
+	c.sent = append(c.sent, notification)
+	return nil
+}
+
+func TestNotifyWithQueuesOnlyCommittedChanges(t *testing.T) {
+	repos := repository.NewMemory()
+	service := NewNotificationServiceWithRepos(repos, &flakyChannel{})
+	outbox := repos.Outbox.(*repository.MemoryOutbox)
+	userID := primitive.NewObjectID()
+	ctx := context.Background()
+
+	err := service.NotifyWith(ctx, "stops", userID, Notification{Subject: "Stop triggered"}, func(ctx context.Context) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NotifyWith failed: %v", err)
+	}
+	messages := outbox.Messages()
+	if len(messages) != 1 || messages[0].Status != models.OutboxPending || messages[0].Source != "stops" || messages[0].UserID != userID {
+		t.Fatalf("Expected one pending message, got %+v", messages)
+	}
+
+	// A failed change queues nothing and reports the change's error
+	err = service.NotifyWith(ctx, "stops", userID, Notification{Subject: "Stop triggered"}, func(ctx context.Context) error {
+		return repository.ErrNotFound
+	})
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected the change's error, got %v", err)
+	}
+	if len(outbox.Messages()) != 1 {
+		t.Errorf("Expected no message for a failed change")
+	}
+
+	// Without a service only the change runs
+	var none *NotificationService
+	ran := false
+	if err := none.NotifyWith(ctx, "stops", userID, Notification{}, func(ctx context.Context) error {
+		ran = true
+		return nil
+	}); err != nil || !ran {
+		t.Errorf("Expected the change to run on a nil service, got %v", err)
+	}
+}
+
+func TestDeliverOutbox(t *testing.T) {
+	repos := repository.NewMemory()
+	channel := &flakyChannel{failures: 1}
+	service := NewNotificationServiceWithRepos(repos, channel)
+	outbox := repos.Outbox.(*repository.MemoryOutbox)
+	ctx := context.Background()
+
+	user := &models.User{ID: primitive.NewObjectID(), Email: "investor@example.com"}
+	if err := repos.Users.Insert(ctx, user); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	noChange := func(ctx context.Context) error { return nil }
+	if err := service.NotifyWith(ctx, "drift-alerts", user.ID, Notification{Subject: "Drift"}, noChange); err != nil {
+		t.Fatalf("NotifyWith failed: %v", err)
+	}
+
+	// The first attempt fails and is scheduled for a retry
+	if err := service.DeliverOutbox(); err != nil {
+		t.Fatalf("Expected a retry rather than an error, got %v", err)
+	}
+	msg := outbox.Messages()[0]
+	if msg.Status != models.OutboxPending || msg.Attempts != 1 || msg.LastError == "" {
+		t.Fatalf("Expected a pending message after one failed attempt, got %+v", msg)
+	}
+	if wait := time.Until(msg.NextAttemptAt); wait < 50*time.Second || wait > time.Minute {
+		t.Errorf("Expected a retry in about a minute, got %s", wait)
+	}
+
+	// It is not retried before it is due
+	if err := service.DeliverOutbox(); err != nil || len(channel.sent) != 0 {
+		t.Fatalf("Expected no delivery before the retry is due, got %v, %d sent", err, len(channel.sent))
+	}
+
+	msg.NextAttemptAt = time.Now().Add(-time.Second)
+	if err := repos.Outbox.Update(ctx, &msg); err != nil {
+		t.Fatalf("Failed to update message: %v", err)
+	}
+	if err := service.DeliverOutbox(); err != nil {
+		t.Fatalf("DeliverOutbox failed: %v", err)
+	}
+	msg = outbox.Messages()[0]
+	if msg.Status != models.OutboxDelivered || msg.Attempts != 2 || msg.DeliveredAt == nil || msg.LastError != "" {
+		t.Errorf("Expected the message delivered on the second attempt, got %+v", msg)
+	}
+	if len(channel.sent) != 1 || channel.sent[0].Subject != "Drift" {
+		t.Errorf("Expected one notification sent, got %+v", channel.sent)
+	}
+
+	// A message for a deleted user is given up on at once
+	if err := service.NotifyWith(ctx, "stops", primitive.NewObjectID(), Notification{Subject: "Stop"}, noChange); err != nil {
+		t.Fatalf("NotifyWith failed: %v", err)
+	}
+	if err := service.DeliverOutbox(); !errors.Is(err, ErrRecipientNotFound) {
+		t.Errorf("Expected ErrRecipientNotFound, got %v", err)
+	}
+	if msg := outbox.Messages()[1]; msg.Status != models.OutboxFailed {
+		t.Errorf("Expected the message to fail, got %+v", msg)
+	}
+}
+
+func TestDeliverOutboxRetriesOnlyFailedChannels(t *testing.T) {
+	repos := repository.NewMemory()
+	email := &flakyChannel{name: "email", failures: 1}
+	chat := &flakyChannel{name: "chat"}
+	service := NewNotificationServiceWithRepos(repos, email, chat)
+	outbox := repos.Outbox.(*repository.MemoryOutbox)
+	ctx := context.Background()
+
+	user := &models.User{ID: primitive.NewObjectID(), Email: "investor@example.com"}
+	if err := repos.Users.Insert(ctx, user); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	noChange := func(ctx context.Context) error { return nil }
+	if err := service.NotifyWith(ctx, "stops", user.ID, Notification{Subject: "Stop"}, noChange); err != nil {
+		t.Fatalf("NotifyWith failed: %v", err)
+	}
+
+	// The email fails while the chat message goes out
+	if err := service.DeliverOutbox(); err != nil {
+		t.Fatalf("Expected a retry rather than an error, got %v", err)
+	}
+	msg := outbox.Messages()[0]
+	if msg.Status != models.OutboxPending || len(msg.Channels) != 2 {
+		t.Fatalf("Expected a pending message with two channel statuses, got %+v", msg)
+	}
+	if status := msg.ChannelStatus("email"); status.DeliveredAt != nil || status.LastError == "" {
+		t.Errorf("Expected the email failure recorded, got %+v", status)
+	}
+	if status := msg.ChannelStatus("chat"); status.DeliveredAt == nil {
+		t.Errorf("Expected the chat message recorded as delivered, got %+v", status)
+	}
+
+	// The retry only sends the email
+	msg.NextAttemptAt = time.Now().Add(-time.Second)
+	if err := repos.Outbox.Update(ctx, &msg); err != nil {
+		t.Fatalf("Failed to update message: %v", err)
+	}
+	if err := service.DeliverOutbox(); err != nil {
+		t.Fatalf("DeliverOutbox failed: %v", err)
+	}
+	if msg := outbox.Messages()[0]; msg.Status != models.OutboxDelivered {
+		t.Errorf("Expected the message delivered, got %+v", msg)
+	}
+	if len(email.sent) != 1 || len(chat.sent) != 1 {
+		t.Errorf("Expected each channel to send once, got %d emails and %d chat messages", len(email.sent), len(chat.sent))
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, time.Hour},
+		{40, time.Hour},
+	}
+	for _, test := range tests {
+		if got := outboxBackoff(test.attempts); got != test.want {
+			t.Errorf("outboxBackoff(%d) = %s, want %s", test.attempts, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"time"
+)
+
+// normalizeInstrument recognises option transactions, given either a
+// contract or an OCC symbol, and stores them under the contract's OCC symbol
+// with Shares scaled from contracts to underlying shares, so option positions
+// are valued and folded like any other holding
+func normalizeInstrument(tx *models.Transaction) error {
+	if tx.Option == nil {
+		contract, ok := models.ParseOptionSymbol(tx.Symbol)
+		if !ok {
+			tx.InstrumentType = ""
+			tx.Contracts = 0
+			return nil
+		}
+		tx.Option = &contract
+	}
+
+	tx.Option.Underlying = strings.ToUpper(strings.TrimSpace(tx.Option.Underlying))
+	if err := tx.Option.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+	}
+
+	tx.Symbol = tx.Option.Symbol()
+	tx.InstrumentType = models.InstrumentOption
+	if tx.Contracts == 0 {
+		tx.Contracts = tx.Shares
+	}
+	tx.Shares = tx.Contracts * tx.Option.Multiplier
+	return nil
+}
+
+// optionName describes a contract, e.g. "AAPL Jan 17 2025 150 Call"
+func optionName(contract models.OptionContract) string {
+	right := "Call"
+	if contract.Right == models.OptionPut {
+		right = "Put"
+	}
+	return fmt.Sprintf("%s %s %g %s", contract.Underlying, contract.Expiry.Format("Jan 2 2006"), contract.Strike, right)
+}
+
+// getOptionInfo quotes an option contract per underlying share. Yahoo Finance
+// quotes listed contracts by their OCC symbol; without a quote the contract is
+// valued at its intrinsic value from the underlying's price. Expired
+// contracts are worth nothing: exercise and assignment are recorded as
+// transactions in the underlying.
+func (s *StockAPIService) getOptionInfo(ctx context.Context, symbol string, contract models.OptionContract) (*StockInfo, error) {
+	if cached, found := s.getCachedStockInfo(symbol); found {
+		return cached, nil
+	}
+
+	info := &StockInfo{
+		Symbol:   symbol,
+		Name:     optionName(contract),
+		Currency: "USD",
+		Option:   &contract,
+	}
+
+	if contract.Expired(time.Now()) {
+		info.Name += " (expired)"
+		info.Source = PriceSourceFixed
+	} else {
+		endTime := time.Now()
+		response, err := s.fetchFromYahooChart(ctx, symbol, endTime.AddDate(0, 0, -1).Unix(), endTime.Unix())
+		var quote *StockInfo
+		if err == nil {
+			quote, err = s.extractStockInfo(response)
+		}
+
+		if err == nil && quote.CurrentPrice > 0 {
+			info.CurrentPrice = quote.CurrentPrice
+			info.PriceSource = quote.PriceSource
+			if quote.Currency != "" {
+				info.Currency = quote.Currency
+			}
+		} else {
+			fmt.Printf("[StockAPI] No quote for option %s, using intrinsic value (reason: %v)\n", symbol, err)
+			underlying, err := s.GetStockInfoContext(ctx, contract.Underlying)
+			if err != nil {
+				return nil, fmt.Errorf("failed to price option %s: %w", symbol, err)
+			}
+			info.CurrentPrice = contract.IntrinsicValue(underlying.CurrentPrice)
+			info.Currency = underlying.Currency
+			info.PriceSource = PriceSource{Source: PriceSourceIntrinsic, AsOf: underlying.AsOf}
+		}
+	}
+
+	s.setCachedStockInfo(symbol, info)
+	return info, nil
+}
+
+// getOptionHistory returns a contract's daily premiums, falling back to its
+// intrinsic value along the underlying's history, and zero after expiry
+func (s *StockAPIService) getOptionHistory(ctx context.Context, symbol, period string, contract models.OptionContract, startTime, endTime time.Time) ([]HistoricalPrice, error) {
+	data, err := s.fetchYahooHistory(ctx, symbol, startTime, endTime)
+	if err == nil && len(data) > 0 {
+		return data, nil
+	}
+	fmt.Printf("[StockAPI] No history for option %s, using intrinsic value (reason: %v)\n", symbol, err)
+
+	underlying, err := s.GetHistoricalDataContext(ctx, contract.Underlying, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch history for option %s: %w", symbol, err)
+	}
+
+	history := make([]HistoricalPrice, 0, len(underlying))
+	for _, point := range underlying {
+		price := 0.0
+		if !contract.Expired(point.Date) {
+			price = contract.IntrinsicValue(point.Price)
+		}
+		history = append(history, HistoricalPrice{Date: point.Date, Price: price})
+	}
+	return history, nil
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseOptionSymbol(t *testing.T) {
+	contract, ok := models.ParseOptionSymbol("aapl250117p00152500")
+	if !ok {
+		t.Fatal("Expected OCC symbol to parse")
+	}
+	if contract.Underlying != "AAPL" || contract.Strike != 152.5 || contract.Right != models.OptionPut ||
+		!contract.Expiry.Equal(time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)) || contract.Multiplier != 100 {
+		t.Errorf("Unexpected contract %+v", contract)
+	}
+	if symbol := contract.Symbol(); symbol != "AAPL250117P00152500" {
+		t.Errorf("Expected symbol to round-trip, got %s", symbol)
+	}
+
+	for _, symbol := range []string{"AAPL", "600519.SS", "CASH_USD", "AAPL250117X00152500", "AAPL251317C00152500"} {
+		if _, ok := models.ParseOptionSymbol(symbol); ok {
+			t.Errorf("Expected %s not to parse as an option", symbol)
+		}
+	}
+
+	if got := contract.IntrinsicValue(140); got != 12.5 {
+		t.Errorf("Expected put intrinsic value 12.5, got %v", got)
+	}
+	if got := contract.IntrinsicValue(160); got != 0 {
+		t.Errorf("Expected out-of-the-money put to be worth 0, got %v", got)
+	}
+}
+
+func TestOptionHoldingsWithMemoryRepos(t *testing.T) {
+	expiry := time.Now().AddDate(0, 3, 0)
+	option := models.OptionRequest{Expiry: expiry, Strike: 150, Right: "call"}
+	symbol := option.Contract("AAPL").Symbol()
+
+	provider := NewFixtureProvider().SetQuote(symbol, "AAPL Call", 7.5, "USD")
+	service := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -1)
+
+	buy := &models.Transaction{Symbol: "aapl", Action: "buy", Shares: 2, Price: 5, Currency: "USD", Date: date, Option: option.Contract("aapl")}
+	if err := service.AddTransaction(userID, buy); err != nil {
+		t.Fatalf("Failed to add option buy: %v", err)
+	}
+	if buy.Symbol != symbol || buy.Shares != 200 || buy.Contracts != 2 || buy.InstrumentType != models.InstrumentOption {
+		t.Errorf("Expected 2 contracts stored as 200 shares of %s, got %+v", symbol, buy)
+	}
+
+	// An OCC symbol alone is enough, with shares counted in contracts
+	sell := &models.Transaction{Symbol: symbol, Action: "sell", Shares: 1, Price: 6, Currency: "USD", Date: date}
+	if err := service.AddTransaction(userID, sell); err != nil {
+		t.Fatalf("Failed to add option sell: %v", err)
+	}
+	oversell := &models.Transaction{Symbol: symbol, Action: "sell", Shares: 2, Price: 6, Currency: "USD", Date: date}
+	if err := service.AddTransaction(userID, oversell); err != ErrInsufficientShares {
+		t.Errorf("Expected ErrInsufficientShares selling more contracts than held, got %v", err)
+	}
+
+	holdings, err := service.GetUserHoldings(userID, "USD")
+	if err != nil {
+		t.Fatalf("Failed to get holdings: %v", err)
+	}
+	if len(holdings) != 1 {
+		t.Fatalf("Expected one holding, got %d", len(holdings))
+	}
+	holding := holdings[0]
+	if holding.Contracts != 1 || holding.Option == nil || holding.Option.Strike != 150 {
+		t.Errorf("Expected 1 call contract, got %+v", holding)
+	}
+	if math.Abs(holding.CurrentValue-750) > 1e-9 || math.Abs(holding.CostBasis-500) > 1e-9 {
+		t.Errorf("Expected value 750 on cost 500, got %v on %v", holding.CurrentValue, holding.CostBasis)
+	}
+
+	portfolio, _ := service.GetPortfolioBySymbol(userID, symbol)
+	if portfolio == nil || portfolio.AssetClass != "Options" {
+		t.Errorf("Expected the option's portfolio entry in the Options asset class, got %+v", portfolio)
+	}
+
+	invalid := &models.Transaction{Symbol: "AAPL", Action: "buy", Shares: 1, Price: 5, Currency: "USD", Date: date,
+		Option: &models.OptionContract{Underlying: "AAPL", Expiry: expiry, Strike: 150, Right: "straddle", Multiplier: 100}}
+	if err := service.AddTransaction(userID, invalid); !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("Expected ErrInvalidTransaction for an invalid contract, got %v", err)
+	}
+}
+
+func TestExpiredOptionIsWorthless(t *testing.T) {
+	service := NewStockAPIService(StockAPIConfig{})
+	info, err := service.GetStockInfo("MSFT200117C00150000")
+	if err != nil {
+		t.Fatalf("Failed to price expired option: %v", err)
+	}
+	if info.CurrentPrice != 0 || info.Option == nil || info.Name != "MSFT Jan 17 2020 150 Call (expired)" {
+		t.Errorf("Expected a worthless expired call, got %+v", info)
+	}
+	if info.Source != PriceSourceFixed || info.Cached {
+		t.Errorf("Expected a fixed, uncached price, got %+v", info.PriceSource)
+	}
+
+	// Later quotes come from the cache without marking the cached entry
+	cached, err := service.GetStockInfo("MSFT200117C00150000")
+	if err != nil || !cached.Cached || cached.Source != PriceSourceFixed {
+		t.Errorf("Expected a cached quote, got %+v, %v", cached, err)
+	}
+	if info.Cached {
+		t.Errorf("Expected the first quote to stay uncached")
+	}
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrPendingOrderNotFound = errors.New("pending order not found")
+	ErrPendingOrderClosed   = errors.New("pending order is no longer open")
+	ErrTooManyPendingOrders = errors.New("too many open pending orders")
+	ErrPendingOrderCurrency = errors.New("pending order currency must match the symbol's trading currency")
+)
+
+const (
+	// PendingOrderJobInterval is how often open pending orders are checked
+	// against the latest quotes
+	PendingOrderJobInterval = time.Minute
+	// maxOpenPendingOrders bounds the open orders of a single user
+	maxOpenPendingOrders = 100
+)
+
+// PendingOrderService records intended trades and converts them to
+// transactions, or notifies the user, once the price reaches the trigger
+type PendingOrderService struct {
+	repos               repository.Repositories
+	portfolioService    *PortfolioService
+	stockService        StockDataProvider
+	notificationService *NotificationService
+}
+
+// NewPendingOrderService creates a new PendingOrderService instance. The
+// notification service may be nil, in which case triggered orders are only
+// recorded.
+func NewPendingOrderService(portfolioService *PortfolioService, stockService StockDataProvider, notificationService *NotificationService) *PendingOrderService {
+	return &PendingOrderService{
+		repos:               portfolioService.repos,
+		portfolioService:    portfolioService,
+		stockService:        stockService,
+		notificationService: notificationService,
+	}
+}
+
+// CreateOrder records a pending order. The trigger price is in the symbol's
+// trading currency, which must be the order currency.
+func (s *PendingOrderService) CreateOrder(userID primitive.ObjectID, req models.PendingOrderRequest) (*models.PendingOrder, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if symbol == "" {
+		return nil, fmt.Errorf("%w: symbol is required", ErrInvalidTransaction)
+	}
+
+	if !currencycode.Equal(s.stockService.SymbolCurrency(symbol), req.Currency) {
+		return nil, ErrPendingOrderCurrency
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	existing, err := s.repos.PendingOrders.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pending orders: %w", err)
+	}
+	open := 0
+	for _, order := range existing {
+		if order.Status == models.PendingOrderOpen {
+			open++
+		}
+	}
+	if open >= maxOpenPendingOrders {
+		return nil, ErrTooManyPendingOrders
+	}
+
+	onTrigger := req.OnTrigger
+	if onTrigger == "" {
+		onTrigger = models.PendingOrderExecute
+	}
+
+	now := time.Now()
+	order := &models.PendingOrder{
+		ID:           primitive.NewObjectID(),
+		UserID:       userID,
+		Symbol:       symbol,
+		Action:       req.Action,
+		OrderType:    req.OrderType,
+		TriggerPrice: req.TriggerPrice,
+		Shares:       req.Shares,
+		Currency:     currencycode.Normalize(req.Currency),
+		Fees:         req.Fees,
+		Note:         strings.TrimSpace(req.Note),
+		OnTrigger:    onTrigger,
+		Status:       models.PendingOrderOpen,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		order.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repos.PendingOrders.Insert(ctx, order); err != nil {
+		return nil, fmt.Errorf("failed to insert pending order: %w", err)
+	}
+
+	return order, nil
+}
+
+// ListOrders returns the user's pending orders in every status, newest first
+func (s *PendingOrderService) ListOrders(userID primitive.ObjectID) ([]models.PendingOrder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	orders, err := s.repos.PendingOrders.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pending orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// CancelOrder cancels an open pending order
+func (s *PendingOrderService) CancelOrder(userID, orderID primitive.ObjectID) (*models.PendingOrder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	order, err := s.repos.PendingOrders.FindByID(ctx, userID, orderID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrPendingOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending order: %w", err)
+	}
+	if order.Status != models.PendingOrderOpen {
+		return nil, ErrPendingOrderClosed
+	}
+
+	order.Status = models.PendingOrderCancelled
+	order.UpdatedAt = time.Now()
+	err = s.repos.PendingOrders.Transition(ctx, order, models.PendingOrderOpen)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Triggered between the lookup and the update
+		return nil, ErrPendingOrderClosed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel pending order: %w", err)
+	}
+
+	return order, nil
+}
+
+// pendingOrderTriggered reports whether the price reaches the order's trigger
+func pendingOrderTriggered(order models.PendingOrder, price float64) bool {
+	if price <= 0 {
+		return false
+	}
+	// A buy limit and a sell stop trigger when the price falls to the trigger
+	falling := (order.OrderType == models.PendingOrderLimit) == (order.Action == "buy")
+	if falling {
+		return price <= order.TriggerPrice
+	}
+	return price >= order.TriggerPrice
+}
+
+// CheckOrders expires stale orders and triggers the open orders whose trigger
+// price the latest quote reaches. Each symbol is quoted once per run.
+func (s *PendingOrderService) CheckOrders() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	orders, err := s.repos.PendingOrders.FindOpen(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to fetch open pending orders: %w", err)
+	}
+
+	now := time.Now()
+	bySymbol := make(map[string][]models.PendingOrder)
+	for _, order := range orders {
+		if order.ExpiresAt != nil && now.After(*order.ExpiresAt) {
+			order.Status = models.PendingOrderExpired
+			order.UpdatedAt = now
+			s.close(order, models.PendingOrderOpen, nil)
+			continue
+		}
+		bySymbol[order.Symbol] = append(bySymbol[order.Symbol], order)
+	}
+
+	var errs []error
+	triggered := 0
+	for symbol, symbolOrders := range bySymbol {
+		info, err := s.stockService.GetStockInfoContext(context.Background(), symbol)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to quote %s: %w", symbol, err))
+			continue
+		}
+		for _, order := range symbolOrders {
+			if pendingOrderTriggered(order, info.CurrentPrice) {
+				s.trigger(order, info.CurrentPrice, now)
+				triggered++
+			}
+		}
+	}
+
+	if triggered > 0 {
+		fmt.Printf("[PendingOrders] Triggered %d of %d open orders\n", triggered, len(orders))
+	}
+	return errors.Join(errs...)
+}
+
+// close moves the order out of the from status, reporting whether this run
+// won the update. A notification, when given, is queued in the same
+// transaction. Failures are logged: the order is retried on the next run.
+func (s *PendingOrderService) close(order models.PendingOrder, from string, notification *Notification) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transition := func(ctx context.Context) error {
+		return s.repos.PendingOrders.Transition(ctx, &order, from)
+	}
+	var err error
+	if notification != nil {
+		err = s.notificationService.NotifyWith(ctx, "pending-orders", order.UserID, *notification, transition)
+	} else {
+		err = transition(ctx)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		// Cancelled or handled by another instance since it was loaded
+		return false
+	}
+	if err != nil {
+		fmt.Printf("[PendingOrders] ERROR: Failed to update order %s: %v\n", order.ID.Hex(), err)
+		return false
+	}
+	return true
+}
+
+// trigger claims the order, then records its trade or notifies the user. The
+// claim comes first so an order is never converted twice.
+func (s *PendingOrderService) trigger(order models.PendingOrder, price float64, now time.Time) {
+	order.Status = models.PendingOrderTriggered
+	order.TriggeredAt = &now
+	order.TriggeredPrice = price
+	order.UpdatedAt = now
+
+	if order.OnTrigger != models.PendingOrderExecute {
+		s.close(order, models.PendingOrderOpen, orderNotification(order, fmt.Sprintf("%s reached %.2f, triggering your %s %s order for %g shares at %.2f.",
+			order.Symbol, price, order.OrderType, order.Action, order.Shares, order.TriggerPrice)))
+		return
+	}
+	if !s.close(order, models.PendingOrderOpen, nil) {
+		return
+	}
+
+	tx := &models.Transaction{
+		Symbol:   order.Symbol,
+		Action:   order.Action,
+		Shares:   order.Shares,
+		Price:    price,
+		Currency: order.Currency,
+		Fees:     order.Fees,
+		Date:     now,
+		Note:     order.Note,
+	}
+	var notification *Notification
+	if err := s.portfolioService.AddTransaction(order.UserID, tx); err != nil {
+		order.Status = models.PendingOrderFailed
+		order.Error = err.Error()
+		notification = orderNotification(order, fmt.Sprintf("Your %s %s order for %g shares of %s triggered at %.2f but could not be recorded: %v",
+			order.OrderType, order.Action, order.Shares, order.Symbol, price, err))
+	} else {
+		order.Status = models.PendingOrderExecuted
+		order.TransactionID = &tx.ID
+	}
+	order.UpdatedAt = time.Now()
+	s.close(order, models.PendingOrderTriggered, notification)
+}
+
+// orderNotification tells the user about a triggered order
+func orderNotification(order models.PendingOrder, text string) *Notification {
+	return &Notification{
+		Subject: fmt.Sprintf("Pending order triggered: %s %s", strings.ToUpper(order.Action), order.Symbol),
+		Text:    text,
+	}
+}
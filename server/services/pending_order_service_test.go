@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPendingOrderTriggered(t *testing.T) {
+	tests := []struct {
+		orderType string
+		action    string
+		price     float64
+		want      bool
+	}{
+		{models.PendingOrderLimit, "buy", 99, true},
+		{models.PendingOrderLimit, "buy", 101, false},
+		{models.PendingOrderLimit, "sell", 101, true},
+		{models.PendingOrderLimit, "sell", 99, false},
+		{models.PendingOrderStop, "buy", 101, true},
+		{models.PendingOrderStop, "buy", 99, false},
+		{models.PendingOrderStop, "sell", 99, true},
+		{models.PendingOrderStop, "sell", 101, false},
+		{models.PendingOrderLimit, "buy", 0, false},
+	}
+
+	for _, tt := range tests {
+		order := models.PendingOrder{OrderType: tt.orderType, Action: tt.action, TriggerPrice: 100}
+		if got := pendingOrderTriggered(order, tt.price); got != tt.want {
+			t.Errorf("%s %s at %.0f: expected %v, got %v", tt.orderType, tt.action, tt.price, tt.want, got)
+		}
+	}
+}
+
+func TestCheckPendingOrders(t *testing.T) {
+	provider := NewFixtureProvider().SetQuote("AAPL", "Apple Inc.", 95, "USD")
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	service := NewPendingOrderService(portfolioService, provider, nil)
+	userID := primitive.NewObjectID()
+
+	limitBuy, err := service.CreateOrder(userID, models.PendingOrderRequest{
+		Symbol: "aapl", Action: "buy", OrderType: "limit", TriggerPrice: 100, Shares: 10, Currency: "USD",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	stopSell, err := service.CreateOrder(userID, models.PendingOrderRequest{
+		Symbol: "AAPL", Action: "sell", OrderType: "stop", TriggerPrice: 90, Shares: 5, Currency: "USD", OnTrigger: "notify",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if _, err := service.CreateOrder(userID, models.PendingOrderRequest{
+		Symbol: "AAPL", Action: "buy", OrderType: "limit", TriggerPrice: 100, Shares: 1, Currency: "RMB",
+	}); !errors.Is(err, ErrPendingOrderCurrency) {
+		t.Errorf("Expected ErrPendingOrderCurrency, got %v", err)
+	}
+
+	if err := service.CheckOrders(); err != nil {
+		t.Fatalf("Failed to check orders: %v", err)
+	}
+
+	orders, _ := service.ListOrders(userID)
+	byID := make(map[primitive.ObjectID]models.PendingOrder)
+	for _, order := range orders {
+		byID[order.ID] = order
+	}
+
+	executed := byID[limitBuy.ID]
+	if executed.Status != models.PendingOrderExecuted || executed.TransactionID == nil || executed.TriggeredPrice != 95 {
+		t.Fatalf("Expected limit buy executed at 95, got %+v", executed)
+	}
+	transactions, _ := portfolioService.GetTransactionsBySymbol(userID, "AAPL")
+	if len(transactions) != 1 || transactions[0].ID != *executed.TransactionID || transactions[0].Price != 95 {
+		t.Errorf("Expected one transaction at the triggered price, got %+v", transactions)
+	}
+
+	if stopSell := byID[stopSell.ID]; stopSell.Status != models.PendingOrderOpen {
+		t.Errorf("Expected stop sell to stay open above its trigger, got %s", stopSell.Status)
+	}
+
+	// A second run must not execute the order again
+	if err := service.CheckOrders(); err != nil {
+		t.Fatalf("Failed to check orders: %v", err)
+	}
+	if transactions, _ := portfolioService.GetTransactionsBySymbol(userID, "AAPL"); len(transactions) != 1 {
+		t.Errorf("Expected the order to execute once, got %d transactions", len(transactions))
+	}
+
+	if _, err := service.CancelOrder(userID, limitBuy.ID); !errors.Is(err, ErrPendingOrderClosed) {
+		t.Errorf("Expected ErrPendingOrderClosed cancelling an executed order, got %v", err)
+	}
+	cancelled, err := service.CancelOrder(userID, stopSell.ID)
+	if err != nil || cancelled.Status != models.PendingOrderCancelled {
+		t.Errorf("Expected stop sell cancelled, got %+v, %v", cancelled, err)
+	}
+
+	provider.SetQuote("AAPL", "Apple Inc.", 80, "USD")
+	if err := service.CheckOrders(); err != nil {
+		t.Fatalf("Failed to check orders: %v", err)
+	}
+	if order, _ := service.repos.PendingOrders.FindByID(context.Background(), userID, stopSell.ID); order.Status != models.PendingOrderCancelled {
+		t.Errorf("Expected cancelled order not to trigger, got %s", order.Status)
+	}
+}
+
+func TestPendingOrderFailsWhenSellExceedsHoldings(t *testing.T) {
+	provider := NewFixtureProvider().SetQuote("MSFT", "Microsoft", 400, "USD")
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	service := NewPendingOrderService(portfolioService, provider, nil)
+	userID := primitive.NewObjectID()
+
+	order, err := service.CreateOrder(userID, models.PendingOrderRequest{
+		Symbol: "MSFT", Action: "sell", OrderType: "limit", TriggerPrice: 350, Shares: 1, Currency: "USD",
+		ExpiresInDays: 30,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if order.ExpiresAt == nil || order.ExpiresAt.Before(time.Now().AddDate(0, 0, 29)) {
+		t.Errorf("Expected expiry in 30 days, got %v", order.ExpiresAt)
+	}
+
+	if err := service.CheckOrders(); err != nil {
+		t.Fatalf("Failed to check orders: %v", err)
+	}
+	orders, _ := service.ListOrders(userID)
+	if len(orders) != 1 || orders[0].Status != models.PendingOrderFailed || orders[0].Error == "" {
+		t.Errorf("Expected the order to fail without holdings, got %+v", orders)
+	}
+}
@@ -0,0 +1,32 @@
+package services
+
+// Stages of a performance computation
+const (
+	// PerformanceStageSymbols counts the symbols whose price history has been
+	// fetched
+	PerformanceStageSymbols = "symbols"
+	// PerformanceStageDates counts the trading days valued
+	PerformanceStageDates = "dates"
+)
+
+// performanceProgressInterval is how many trading days are valued between
+// reports of the dates stage
+const performanceProgressInterval = 100
+
+// PerformanceProgress reports how far a performance computation has got
+type PerformanceProgress struct {
+	Stage string `json:"stage"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+}
+
+// PerformanceProgressFunc receives progress reports. It is called on the
+// computing goroutine, so it should return quickly.
+type PerformanceProgressFunc func(PerformanceProgress)
+
+// report calls f, if set, with the progress of stage
+func (f PerformanceProgressFunc) report(stage string, done, total int) {
+	if f != nil {
+		f(PerformanceProgress{Stage: stage, Done: done, Total: total})
+	}
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"stock-portfolio-tracker/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPerformanceProgress(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 110, "USD").
+		SetHistory("AAPL", today, 100, 102, 101, 105, 110).
+		SetQuote("MSFT", "Microsoft", 210, "USD").
+		SetHistory("MSFT", today, 200, 205, 210)
+	service, portfolioService := newFixtureAnalyticsService(provider)
+	userID := primitive.NewObjectID()
+
+	for _, tx := range []*models.Transaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 100, Currency: "USD", Date: today.AddDate(0, 0, -10)},
+		{Symbol: "MSFT", Action: "buy", Shares: 5, Price: 200, Currency: "USD", Date: today.AddDate(0, 0, -10)},
+	} {
+		if err := portfolioService.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+
+	var reports []PerformanceProgress
+	response, err := service.GetHistoricalPerformanceWithProgress(context.Background(), userID, "1M", "USD", func(p PerformanceProgress) {
+		reports = append(reports, p)
+	})
+	if err != nil {
+		t.Fatalf("Failed to get performance: %v", err)
+	}
+
+	want := []PerformanceProgress{
+		{Stage: PerformanceStageSymbols, Done: 1, Total: 2},
+		{Stage: PerformanceStageSymbols, Done: 2, Total: 2},
+		{Stage: PerformanceStageDates, Done: len(response.Performance), Total: len(response.Performance)},
+	}
+	if len(reports) != len(want) {
+		t.Fatalf("Expected %d progress reports, got %v", len(want), reports)
+	}
+	for i := range want {
+		if reports[i] != want[i] {
+			t.Errorf("Report %d: expected %+v, got %+v", i, want[i], reports[i])
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RebuildPortfolioEntries recreates the portfolio entry of every symbol the
+// user has transactions in but no entry for, such as after transactions were
+// restored or imported straight into the database, and returns the symbols
+// it created entries for. Existing entries and their metadata are kept.
+func (s *PortfolioService) RebuildPortfolioEntries(ctx context.Context, userID primitive.ObjectID) ([]string, error) {
+	symbols := make(map[string]bool)
+	err := s.repos.Transactions.Stream(ctx, userID, func(tx models.Transaction) error {
+		symbols[tx.Symbol] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transactions: %w", err)
+	}
+
+	portfolios, err := s.repos.Portfolios.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch portfolios: %w", err)
+	}
+	for _, portfolio := range portfolios {
+		delete(symbols, portfolio.Symbol)
+	}
+
+	created := make([]string, 0, len(symbols))
+	for symbol := range symbols {
+		created = append(created, symbol)
+	}
+	sort.Strings(created)
+	for _, symbol := range created {
+		var option *models.OptionContract
+		if contract, ok := models.ParseOptionSymbol(symbol); ok {
+			option = &contract
+		}
+		if _, err := s.getOrCreatePortfolio(userID, symbol, option); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(created) > 0 {
+		dataVersions.bump(userID)
+	}
+	return created, nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRebuildPortfolioEntries(t *testing.T) {
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 190, "USD").
+		SetQuote("MSFT", "Microsoft", 420, "USD")
+	repos := repository.NewMemory()
+	service := NewPortfolioServiceWithRepos(provider, provider, repos)
+	userID := primitive.NewObjectID()
+	ctx := context.Background()
+
+	// AAPL goes through the service and gets an entry; MSFT is written
+	// straight to the repository, as a restore would
+	if err := service.AddTransaction(userID, &models.Transaction{Symbol: "AAPL", Action: "buy", Shares: 1, Price: 180, Currency: "USD", Date: time.Now()}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	restored := &models.Transaction{ID: primitive.NewObjectID(), UserID: userID, Symbol: "MSFT", Action: "buy", Shares: 2, Price: 400, Currency: "USD", Date: time.Now()}
+	if err := repos.Transactions.Insert(ctx, restored); err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+
+	created, err := service.RebuildPortfolioEntries(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to rebuild entries: %v", err)
+	}
+	if !reflect.DeepEqual(created, []string{"MSFT"}) {
+		t.Errorf("Expected an entry created for MSFT only, got %v", created)
+	}
+	if _, err := repos.Portfolios.FindBySymbol(ctx, userID, "MSFT"); err != nil {
+		t.Errorf("Expected an MSFT entry, got %v", err)
+	}
+
+	if created, err := service.RebuildPortfolioEntries(ctx, userID); err != nil || len(created) != 0 {
+		t.Errorf("Expected nothing to rebuild the second time, got %v, %v", created, err)
+	}
+}
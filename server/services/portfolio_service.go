@@ -4,13 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"stock-portfolio-tracker/database"
+	"slices"
+	"stock-portfolio-tracker/currencycode"
 	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/money"
+	"stock-portfolio-tracker/repository"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var (
@@ -33,19 +34,57 @@ type Holding struct {
 	GainLoss        float64 `json:"gainLoss"`
 	GainLossPercent float64 `json:"gainLossPercent"`
 	Currency        string  `json:"currency"`
+
+	// Change in value since the previous close, in the holding's display
+	// currency. Set in grouped dashboards.
+	DayChange        float64 `json:"dayChange,omitempty"`
+	DayChangePercent float64 `json:"dayChangePercent,omitempty"`
+
+	// Where the price sits in its 52-week range, when the provider reports it
+	FiftyTwoWeek *FiftyTwoWeekRange `json:"fiftyTwoWeek,omitempty"`
+
+	// Latest pre-market or after-hours trade, in the display currency, when
+	// the provider reports one
+	ExtendedHours *ExtendedHoursQuote `json:"extendedHours,omitempty"`
+
+	// Set when the providers no longer quote the symbol and CurrentPrice is
+	// the last known price, as of PriceAsOf
+	PriceAsOf *time.Time `json:"priceAsOf,omitempty"`
+	Delisted  bool       `json:"delisted,omitempty"`
+
+	// Option holdings: Shares is the underlying share equivalent and
+	// CurrentPrice the premium per share
+	Option    *models.OptionContract `json:"option,omitempty"`
+	Contracts float64                `json:"contracts,omitempty"`
+
+	// Where CurrentPrice was quoted from and how fresh it is
+	PriceSource
+
+	// PriceOverride is set when CurrentPrice is the user's manual price
+	PriceOverride *models.PriceOverride `json:"priceOverride,omitempty"`
 }
 
 // PortfolioService handles portfolio and transaction operations
 type PortfolioService struct {
-	stockService    *StockAPIService
-	currencyService *CurrencyService
+	stockService    StockDataProvider
+	currencyService CurrencyProvider
+	repos           repository.Repositories
+	technicals      *lruCache[*PriceTechnicals] // By symbol
+}
+
+// NewPortfolioService creates a new PortfolioService instance backed by MongoDB
+func NewPortfolioService(stockService StockDataProvider, currencyService CurrencyProvider) *PortfolioService {
+	return NewPortfolioServiceWithRepos(stockService, currencyService, repository.NewMongo())
 }
 
-// NewPortfolioService creates a new PortfolioService instance
-func NewPortfolioService(stockService *StockAPIService, currencyService *CurrencyService) *PortfolioService {
+// NewPortfolioServiceWithRepos creates a PortfolioService over the given
+// repositories, e.g. in-memory ones in tests
+func NewPortfolioServiceWithRepos(stockService StockDataProvider, currencyService CurrencyProvider, repos repository.Repositories) *PortfolioService {
 	return &PortfolioService{
 		stockService:    stockService,
 		currencyService: currencyService,
+		repos:           repos,
+		technicals:      newLRUCache[*PriceTechnicals](technicalsCacheSize),
 	}
 }
 
@@ -64,7 +103,7 @@ func (s *PortfolioService) AddTransaction(userID primitive.ObjectID, tx *models.
 	}
 
 	// Get or create portfolio for this symbol
-	portfolioID, err := s.getOrCreatePortfolio(userID, tx.Symbol)
+	portfolioID, err := s.getOrCreatePortfolio(userID, tx.Symbol, tx.Option)
 	if err != nil {
 		return fmt.Errorf("failed to get or create portfolio: %w", err)
 	}
@@ -80,12 +119,12 @@ func (s *PortfolioService) AddTransaction(userID primitive.ObjectID, tx *models.
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("transactions")
-	_, err = collection.InsertOne(ctx, tx)
+	err = s.repos.Transactions.Insert(ctx, tx)
 	if err != nil {
 		return fmt.Errorf("failed to insert transaction: %w", err)
 	}
 
+	dataVersions.bump(userID)
 	return nil
 }
 
@@ -99,16 +138,9 @@ func (s *PortfolioService) UpdateTransaction(userID primitive.ObjectID, txID pri
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("transactions")
-
 	// First, check if transaction exists and belongs to user
-	var existingTx models.Transaction
-	err := collection.FindOne(ctx, bson.M{
-		"_id":     txID,
-		"user_id": userID,
-	}).Decode(&existingTx)
-
-	if err == mongo.ErrNoDocuments {
+	existingTx, err := s.repos.Transactions.FindByID(ctx, userID, txID)
+	if errors.Is(err, repository.ErrNotFound) {
 		return ErrTransactionNotFound
 	}
 	if err != nil {
@@ -129,17 +161,19 @@ func (s *PortfolioService) UpdateTransaction(userID primitive.ObjectID, txID pri
 	updatedTx.ID = txID
 	updatedTx.UserID = userID
 	updatedTx.PortfolioID = existingTx.PortfolioID
+	updatedTx.Broker = existingTx.Broker
+	updatedTx.ExternalID = existingTx.ExternalID
 
 	// Replace the transaction
-	_, err = collection.ReplaceOne(ctx, bson.M{
-		"_id":     txID,
-		"user_id": userID,
-	}, updatedTx)
-
+	err = s.repos.Transactions.Replace(ctx, updatedTx)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrTransactionNotFound
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update transaction: %w", err)
 	}
 
+	dataVersions.bump(userID)
 	return nil
 }
 
@@ -148,22 +182,16 @@ func (s *PortfolioService) DeleteTransaction(userID primitive.ObjectID, txID pri
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("transactions")
-
 	// Delete only if transaction belongs to user
-	result, err := collection.DeleteOne(ctx, bson.M{
-		"_id":     txID,
-		"user_id": userID,
-	})
-
+	err := s.repos.Transactions.Delete(ctx, userID, txID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrTransactionNotFound
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete transaction: %w", err)
 	}
 
-	if result.DeletedCount == 0 {
-		return ErrTransactionNotFound
-	}
-
+	dataVersions.bump(userID)
 	return nil
 }
 
@@ -194,12 +222,17 @@ func (s *PortfolioService) validateTransaction(tx *models.Transaction) error {
 		return fmt.Errorf("%w: action must be 'buy' or 'sell'", ErrInvalidTransaction)
 	}
 
-	// Check currency is valid
-	if tx.Currency != "USD" && tx.Currency != "RMB" {
+	// Check currency is valid, accepting CNY for RMB
+	if !currencycode.IsTransactionCurrency(tx.Currency) {
 		return fmt.Errorf("%w: currency must be 'USD' or 'RMB'", ErrInvalidTransaction)
 	}
+	tx.Currency = currencycode.Normalize(tx.Currency)
 
-	return nil
+	if err := normalizeInstrument(tx); err != nil {
+		return err
+	}
+
+	return normalizeTransactionLabels(tx)
 }
 
 // validateSellTransaction checks if user has sufficient shares for a sell transaction
@@ -212,32 +245,18 @@ func (s *PortfolioService) validateSellTransactionExcluding(userID primitive.Obj
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("transactions")
-
-	// Build filter to exclude the transaction being updated
-	filter := bson.M{
-		"user_id": userID,
-		"symbol":  tx.Symbol,
-	}
-	if !excludeTxID.IsZero() {
-		filter["_id"] = bson.M{"$ne": excludeTxID}
-	}
-
 	// Get all transactions for this symbol
-	cursor, err := collection.Find(ctx, filter)
+	transactions, err := s.repos.Transactions.FindBySymbol(ctx, userID, tx.Symbol)
 	if err != nil {
 		return fmt.Errorf("failed to fetch transactions: %w", err)
 	}
-	defer cursor.Close(ctx)
 
-	var transactions []models.Transaction
-	if err := cursor.All(ctx, &transactions); err != nil {
-		return fmt.Errorf("failed to decode transactions: %w", err)
-	}
-
-	// Calculate total shares
+	// Calculate total shares, excluding the transaction being updated
 	totalShares := 0.0
 	for _, t := range transactions {
+		if !excludeTxID.IsZero() && t.ID == excludeTxID {
+			continue
+		}
 		if t.Action == "buy" {
 			totalShares += t.Shares
 		} else if t.Action == "sell" {
@@ -253,31 +272,25 @@ func (s *PortfolioService) validateSellTransactionExcluding(userID primitive.Obj
 	return nil
 }
 
-// getOrCreatePortfolio gets an existing portfolio or creates a new one for the symbol
-func (s *PortfolioService) getOrCreatePortfolio(userID primitive.ObjectID, symbol string) (primitive.ObjectID, error) {
+// getOrCreatePortfolio gets an existing portfolio or creates a new one for the
+// symbol, recording the contract of an option
+func (s *PortfolioService) getOrCreatePortfolio(userID primitive.ObjectID, symbol string, option *models.OptionContract) (primitive.ObjectID, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("portfolios")
-
 	// Try to find existing portfolio
-	var portfolio models.Portfolio
-	err := collection.FindOne(ctx, bson.M{
-		"user_id": userID,
-		"symbol":  symbol,
-	}).Decode(&portfolio)
-
+	existing, err := s.repos.Portfolios.FindBySymbol(ctx, userID, symbol)
 	if err == nil {
 		// Portfolio exists
-		return portfolio.ID, nil
+		return existing.ID, nil
 	}
 
-	if err != mongo.ErrNoDocuments {
+	if !errors.Is(err, repository.ErrNotFound) {
 		return primitive.NilObjectID, fmt.Errorf("failed to query portfolio: %w", err)
 	}
 
 	// Create new portfolio
-	portfolio = models.Portfolio{
+	portfolio := models.Portfolio{
 		ID:        primitive.NewObjectID(),
 		UserID:    userID,
 		Symbol:    symbol,
@@ -285,12 +298,19 @@ func (s *PortfolioService) getOrCreatePortfolio(userID primitive.ObjectID, symbo
 		UpdatedAt: time.Now(),
 	}
 
-	// Automatically set Asset Class for cash holdings
+	// Automatically set Asset Class for cash holdings, crypto and options
 	if s.stockService.IsCashSymbol(symbol) {
 		portfolio.AssetClass = "Cash and Equivalents"
 	}
+	if isCryptoSymbol(symbol) {
+		portfolio.AssetClass = "Crypto"
+	}
+	if option != nil {
+		portfolio.AssetClass = "Options"
+		portfolio.Option = option
+	}
 
-	_, err = collection.InsertOne(ctx, portfolio)
+	err = s.repos.Portfolios.Insert(ctx, &portfolio)
 	if err != nil {
 		return primitive.NilObjectID, fmt.Errorf("failed to create portfolio: %w", err)
 	}
@@ -298,85 +318,91 @@ func (s *PortfolioService) getOrCreatePortfolio(userID primitive.ObjectID, symbo
 	return portfolio.ID, nil
 }
 
+// getPositions returns the user's open positions without pricing them
+func (s *PortfolioService) getPositions(ctx context.Context, userID primitive.ObjectID) ([]repository.Position, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	positions, err := s.repos.Transactions.Positions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate transactions: %w", err)
+	}
+
+	return positions, nil
+}
+
 // GetUserHoldings calculates and returns all holdings for a user in the specified currency
 func (s *PortfolioService) GetUserHoldings(userID primitive.ObjectID, targetCurrency string) ([]Holding, error) {
-	fmt.Printf("[Portfolio] GetUserHoldings called for user: %s, currency: %s\n", userID.Hex(), targetCurrency)
-	
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	return s.GetUserHoldingsContext(context.Background(), userID, targetCurrency)
+}
 
-	collection := database.Database.Collection("transactions")
+// GetUserHoldingsContext is GetUserHoldings with the queries and price lookups
+// made under ctx, so they are cancelled and traced with the request
+func (s *PortfolioService) GetUserHoldingsContext(ctx context.Context, userID primitive.ObjectID, targetCurrency string) ([]Holding, error) {
+	fmt.Printf("[Portfolio] GetUserHoldings called for user: %s, currency: %s\n", userID.Hex(), targetCurrency)
 
-	// Get all transactions for the user
-	cursor, err := collection.Find(ctx, bson.M{"user_id": userID})
+	// Fold transactions into per-symbol positions on the database side
+	positions, err := s.getPositions(ctx, userID)
 	if err != nil {
-		fmt.Printf("[Portfolio] ERROR: Failed to fetch transactions for user %s: %v\n", userID.Hex(), err)
-		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
+		fmt.Printf("[Portfolio] ERROR: Failed to aggregate positions for user %s: %v\n", userID.Hex(), err)
+		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	var transactions []models.Transaction
-	if err := cursor.All(ctx, &transactions); err != nil {
-		fmt.Printf("[Portfolio] ERROR: Failed to decode transactions for user %s: %v\n", userID.Hex(), err)
-		return nil, fmt.Errorf("failed to decode transactions: %w", err)
-	}
-	
-	fmt.Printf("[Portfolio] Found %d transactions for user %s\n", len(transactions), userID.Hex())
+	fmt.Printf("[Portfolio] Aggregated %d open positions for user %s\n", len(positions), userID.Hex())
+
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
 	// Fetch all portfolios for the user to get portfolio IDs
-	portfolioCollection := database.Database.Collection("portfolios")
-	portfolioCursor, err := portfolioCollection.Find(ctx, bson.M{"user_id": userID})
+	portfolios, err := s.repos.Portfolios.FindByUser(queryCtx, userID)
 	if err != nil {
 		fmt.Printf("[Portfolio] ERROR: Failed to fetch portfolios for user %s: %v\n", userID.Hex(), err)
 		return nil, fmt.Errorf("failed to fetch portfolios: %w", err)
 	}
-	defer portfolioCursor.Close(ctx)
 
-	var portfolios []models.Portfolio
-	if err := portfolioCursor.All(ctx, &portfolios); err != nil {
-		fmt.Printf("[Portfolio] ERROR: Failed to decode portfolios for user %s: %v\n", userID.Hex(), err)
-		return nil, fmt.Errorf("failed to decode portfolios: %w", err)
+	overrides, err := s.priceOverrides(queryCtx, userID)
+	if err != nil {
+		fmt.Printf("[Portfolio] ERROR: Failed to fetch price overrides for user %s: %v\n", userID.Hex(), err)
+		return nil, err
 	}
 
-	// Create a map of symbol to portfolio ID
-	symbolToPortfolioID := make(map[string]string)
+	// Create a map of symbol to portfolio entry
+	symbolToPortfolio := make(map[string]models.Portfolio)
 	for _, p := range portfolios {
-		symbolToPortfolioID[p.Symbol] = p.ID.Hex()
+		symbolToPortfolio[p.Symbol] = p
 	}
 
-	// Group transactions by symbol
-	symbolTransactions := make(map[string][]models.Transaction)
-	for _, tx := range transactions {
-		symbolTransactions[tx.Symbol] = append(symbolTransactions[tx.Symbol], tx)
-	}
-	
-	fmt.Printf("[Portfolio] Grouped into %d unique symbols\n", len(symbolTransactions))
-
-	// Calculate holdings for each symbol
-	holdings := make([]Holding, 0)
-	for symbol, txs := range symbolTransactions {
-		fmt.Printf("[Portfolio] Calculating holding for symbol: %s (%d transactions)\n", symbol, len(txs))
-		holding, err := s.calculateHolding(symbol, txs, targetCurrency)
+	// Price each open position
+	holdings := make([]Holding, 0, len(positions))
+	for _, position := range positions {
+		fmt.Printf("[Portfolio] Calculating holding for symbol: %s (%.2f shares)\n", position.Symbol, position.Shares)
+		var override *models.PriceOverride
+		if o, ok := overrides[position.Symbol]; ok {
+			override = &o
+		}
+		holding, err := s.calculateHolding(ctx, position, targetCurrency, override)
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
 			// Log error but continue with other holdings
-			fmt.Printf("[Portfolio] ERROR: Failed to calculate holding for %s: %v\n", symbol, err)
+			fmt.Printf("[Portfolio] ERROR: Failed to calculate holding for %s: %v\n", position.Symbol, err)
 			continue
 		}
 
-		// Add portfolio ID if available
-		if portfolioID, exists := symbolToPortfolioID[symbol]; exists {
-			holding.PortfolioID = portfolioID
+		// Add portfolio ID and option contract if available
+		if portfolio, exists := symbolToPortfolio[position.Symbol]; exists {
+			holding.PortfolioID = portfolio.ID.Hex()
+			if portfolio.Option != nil {
+				holding.Option = portfolio.Option
+				holding.Contracts = holding.Shares / portfolio.Option.Multiplier
+			}
 		}
 
-		// Filter out holdings with zero shares
-		if holding.Shares > 0 {
-			fmt.Printf("[Portfolio] Added holding: %s (%.2f shares, value: %.2f %s)\n", symbol, holding.Shares, holding.CurrentValue, targetCurrency)
-			holdings = append(holdings, *holding)
-		} else {
-			fmt.Printf("[Portfolio] Skipped holding %s (zero shares)\n", symbol)
-		}
+		fmt.Printf("[Portfolio] Added holding: %s (%.2f shares, value: %.2f %s)\n", position.Symbol, holding.Shares, holding.CurrentValue, targetCurrency)
+		holdings = append(holdings, *holding)
 	}
-	
+
 	fmt.Printf("[Portfolio] Returning %d holdings for user %s\n", len(holdings), userID.Hex())
 	return holdings, nil
 }
@@ -386,77 +412,73 @@ func (s *PortfolioService) GetTransactionsBySymbol(userID primitive.ObjectID, sy
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("transactions")
-
-	cursor, err := collection.Find(ctx, bson.M{
-		"user_id": userID,
-		"symbol":  symbol,
-	})
+	transactions, err := s.repos.Transactions.FindBySymbol(ctx, userID, symbol)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
 	}
-	defer cursor.Close(ctx)
 
-	var transactions []models.Transaction
-	if err := cursor.All(ctx, &transactions); err != nil {
-		return nil, fmt.Errorf("failed to decode transactions: %w", err)
+	return transactions, nil
+}
+
+// GetTransactionsByTag returns the user's transactions carrying the tag, newest first
+func (s *PortfolioService) GetTransactionsByTag(userID primitive.ObjectID, tag string) ([]models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transactions, err := s.repos.Transactions.FindByTag(ctx, userID, NormalizeTag(tag))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
 	}
 
 	return transactions, nil
 }
 
-// calculateHolding calculates holding details for a symbol based on its transactions
-func (s *PortfolioService) calculateHolding(symbol string, transactions []models.Transaction, targetCurrency string) (*Holding, error) {
-	if len(transactions) == 0 {
-		return nil, fmt.Errorf("no transactions for symbol")
-	}
-
-	var totalShares float64
-	var totalCost float64
-	var transactionCurrency string
-
-	// Calculate total shares and cost basis in original transaction currency
-	for _, tx := range transactions {
-		if tx.Action == "buy" {
-			totalShares += tx.Shares
-			// Cost basis includes price * shares + fees
-			totalCost += (tx.Price * tx.Shares) + tx.Fees
-		} else if tx.Action == "sell" {
-			// When selling, reduce shares and proportionally reduce cost basis
-			if totalShares > 0 {
-				// Calculate cost basis per share before the sell
-				costPerShare := totalCost / totalShares
-				// Reduce cost basis by the cost of shares sold
-				totalCost -= costPerShare * tx.Shares
-				// Reduce total shares
-				totalShares -= tx.Shares
-			}
-		}
+// GetTransactionsSince returns the user's transactions dated on or after since, newest first
+func (s *PortfolioService) GetTransactionsSince(userID primitive.ObjectID, since time.Time) ([]models.Transaction, error) {
+	return s.GetTransactionsSinceContext(context.Background(), userID, since)
+}
 
-		// Use currency from first transaction (all should be same currency per symbol)
-		if transactionCurrency == "" {
-			transactionCurrency = tx.Currency
-		}
+// GetTransactionsSinceContext is GetTransactionsSince with the query made
+// under ctx
+func (s *PortfolioService) GetTransactionsSinceContext(ctx context.Context, userID primitive.ObjectID, since time.Time) ([]models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	transactions, err := s.repos.Transactions.FindSince(ctx, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
 	}
 
+	return transactions, nil
+}
+
+// calculateHolding prices an aggregated position in the target currency, at
+// the user's manual price when override is set
+func (s *PortfolioService) calculateHolding(ctx context.Context, position repository.Position, targetCurrency string, override *models.PriceOverride) (*Holding, error) {
+	symbol := position.Symbol
+	totalShares := position.Shares
+	totalCost := position.Cost
+	transactionCurrency := position.Currency
+
 	// If no shares remaining, return zero holding
 	if totalShares <= 0 {
 		return &Holding{
-			Symbol:          symbol,
-			Name:            "",
-			Shares:          0,
-			CostBasis:       0,
-			CurrentPrice:    0,
-			CurrentValue:    0,
-			GainLoss:        0,
-			GainLossPercent: 0,
-			Currency:        targetCurrency,
+			Symbol:   symbol,
+			Currency: targetCurrency,
 		}, nil
 	}
 
 	// Fetch current price from stock service
 	fmt.Printf("[Portfolio] Fetching stock info for symbol: %s\n", symbol)
-	stockInfo, err := s.stockService.GetStockInfo(symbol)
+	stockInfo, err := s.stockService.GetStockInfoContext(ctx, symbol)
+	if override != nil {
+		// The manual price stands in for a quote the providers don't have
+		if err != nil {
+			fmt.Printf("[Portfolio] No quote for %s, using its manual price (reason: %v)\n", symbol, err)
+			stockInfo, err = nil, nil
+		}
+		stockInfo = overridePrice(symbol, stockInfo, *override)
+	}
 	if err != nil {
 		fmt.Printf("[Portfolio] ERROR: Failed to fetch stock info for symbol %s: %v\n", symbol, err)
 		return nil, fmt.Errorf("failed to fetch stock info for %s: %w", symbol, err)
@@ -485,72 +507,115 @@ func (s *PortfolioService) calculateHolding(symbol string, transactions []models
 		fmt.Printf("[Portfolio] Converted price from %.2f %s to %.2f %s\n", stockInfo.CurrentPrice, stockInfo.Currency, convertedCurrentPrice, targetCurrency)
 	}
 
-	currentValue := convertedCurrentPrice * totalShares
-	gainLoss := currentValue - convertedCostBasis
+	// Value and cost are rounded to the currency's minor unit so gain/loss
+	// is their exact difference
+	costBasis := money.New(convertedCostBasis, targetCurrency)
+	currentValue := money.New(convertedCurrentPrice*totalShares, targetCurrency)
+	gainLoss := currentValue.Sub(costBasis)
 	gainLossPercent := 0.0
 	
 	// For cash holdings, gain/loss is always 0
 	if s.stockService.IsCashSymbol(symbol) {
-		gainLoss = 0
+		gainLoss = money.Zero(targetCurrency)
 		gainLossPercent = 0
-	} else if convertedCostBasis > 0 {
-		gainLossPercent = (gainLoss / convertedCostBasis) * 100
+	} else if costBasis.Minor > 0 {
+		gainLossPercent = (gainLoss.Float64() / costBasis.Float64()) * 100
+	}
+
+	// The 52-week range and extended-hours trade convert at the same rate as
+	// the current price
+	rate := 1.0
+	if stockInfo.CurrentPrice > 0 {
+		rate = convertedCurrentPrice / stockInfo.CurrentPrice
+	}
+	fiftyTwoWeek := newFiftyTwoWeekRange(stockInfo.CurrentPrice, stockInfo.FiftyTwoWeekLow, stockInfo.FiftyTwoWeekHigh)
+	if fiftyTwoWeek != nil {
+		fiftyTwoWeek.Low *= rate
+		fiftyTwoWeek.High *= rate
 	}
 
 	return &Holding{
 		Symbol:          symbol,
 		Name:            stockInfo.Name,
 		Shares:          totalShares,
-		CostBasis:       convertedCostBasis,
+		CostBasis:       costBasis.Float64(),
 		CurrentPrice:    convertedCurrentPrice,
-		CurrentValue:    currentValue,
-		GainLoss:        gainLoss,
+		CurrentValue:    currentValue.Float64(),
+		GainLoss:        gainLoss.Float64(),
 		GainLossPercent: gainLossPercent,
 		Currency:        targetCurrency,
+		FiftyTwoWeek:    fiftyTwoWeek,
+		ExtendedHours:   convertedExtendedHours(stockInfo.ExtendedHours, rate, totalShares),
+		PriceAsOf:       stockInfo.PriceAsOf,
+		Delisted:        stockInfo.Delisted,
+		PriceSource:     stockInfo.PriceSource,
+		PriceOverride:   override,
 	}, nil
 }
 
-// UpdatePortfolioMetadata updates the asset style and asset class of a portfolio
+// HoldingsRateFreshness reports how current the exchange rates used to
+// convert the holdings to currency are, nil when none needed converting
+func (s *PortfolioService) HoldingsRateFreshness(holdings []Holding, currency string) *RateFreshness {
+	symbols := make([]string, 0, len(holdings))
+	for _, holding := range holdings {
+		symbols = append(symbols, holding.Symbol)
+	}
+	return s.symbolsRateFreshness(symbols, currency)
+}
+
+// symbolsRateFreshness reports the freshness of the rates from the symbols'
+// trading currencies to currency
+func (s *PortfolioService) symbolsRateFreshness(symbols []string, currency string) *RateFreshness {
+	currencies := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		currencies = append(currencies, s.stockService.SymbolCurrency(symbol))
+	}
+	return s.currencyService.GetRateFreshness(currency, currencies...)
+}
+
+// UpdatePortfolioMetadata updates the asset style and asset class of a
+// portfolio, removing it from any asset subclass
 func (s *PortfolioService) UpdatePortfolioMetadata(userID primitive.ObjectID, portfolioID primitive.ObjectID, assetStyleID primitive.ObjectID, assetClass string) error {
+	return s.UpdatePortfolioClassification(userID, portfolioID, assetStyleID, assetClass, primitive.NilObjectID)
+}
+
+// UpdatePortfolioClassification updates the asset style, asset class and
+// asset subclass of a portfolio. A zero subclass ID leaves it in none; a
+// subclass must belong to the asset class.
+func (s *PortfolioService) UpdatePortfolioClassification(userID primitive.ObjectID, portfolioID primitive.ObjectID, assetStyleID primitive.ObjectID, assetClass string, assetSubclassID primitive.ObjectID) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("portfolios")
-
 	// Validate asset class
-	validAssetClasses := map[string]bool{
-		"Stock":                 true,
-		"ETF":                   true,
-		"Bond":                  true,
-		"Cash and Equivalents": true,
+	if !slices.Contains(AssetClasses, assetClass) {
+		return fmt.Errorf("%w: invalid asset class", ErrInvalidTransaction)
 	}
 
-	if !validAssetClasses[assetClass] {
-		return fmt.Errorf("%w: invalid asset class", ErrInvalidTransaction)
+	var subclassID *primitive.ObjectID
+	if !assetSubclassID.IsZero() {
+		subclass, err := s.repos.AssetSubclasses.FindByID(ctx, userID, assetSubclassID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrAssetSubclassNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to find asset subclass: %w", err)
+		}
+		if subclass.AssetClass != assetClass {
+			return ErrAssetSubclassMismatch
+		}
+		subclassID = &assetSubclassID
 	}
 
 	// Update portfolio
-	update := bson.M{
-		"$set": bson.M{
-			"asset_style_id": assetStyleID,
-			"asset_class":    assetClass,
-			"updated_at":     time.Now(),
-		},
+	err := s.repos.Portfolios.UpdateMetadata(ctx, userID, portfolioID, assetStyleID, assetClass, subclassID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("portfolio not found")
 	}
-
-	result, err := collection.UpdateOne(ctx, bson.M{
-		"_id":     portfolioID,
-		"user_id": userID,
-	}, update)
-
 	if err != nil {
 		return fmt.Errorf("failed to update portfolio metadata: %w", err)
 	}
 
-	if result.MatchedCount == 0 {
-		return fmt.Errorf("portfolio not found")
-	}
-
+	dataVersions.bump(userID)
 	return nil
 }
 
@@ -559,22 +624,15 @@ func (s *PortfolioService) GetPortfolioWithMetadata(userID primitive.ObjectID, p
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("portfolios")
-
-	var portfolio models.Portfolio
-	err := collection.FindOne(ctx, bson.M{
-		"_id":     portfolioID,
-		"user_id": userID,
-	}).Decode(&portfolio)
-
-	if err == mongo.ErrNoDocuments {
+	portfolio, err := s.repos.Portfolios.FindByID(ctx, userID, portfolioID)
+	if errors.Is(err, repository.ErrNotFound) {
 		return nil, fmt.Errorf("portfolio not found")
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch portfolio: %w", err)
 	}
 
-	return &portfolio, nil
+	return portfolio, nil
 }
 
 // CheckPortfolioExists checks if a portfolio exists for a symbol
@@ -582,22 +640,15 @@ func (s *PortfolioService) CheckPortfolioExists(userID primitive.ObjectID, symbo
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("portfolios")
-
-	var portfolio models.Portfolio
-	err := collection.FindOne(ctx, bson.M{
-		"user_id": userID,
-		"symbol":  symbol,
-	}).Decode(&portfolio)
-
-	if err == mongo.ErrNoDocuments {
+	portfolio, err := s.repos.Portfolios.FindBySymbol(ctx, userID, symbol)
+	if errors.Is(err, repository.ErrNotFound) {
 		return false, nil, nil
 	}
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to check portfolio: %w", err)
 	}
 
-	return true, &portfolio, nil
+	return true, portfolio, nil
 }
 
 // GetPortfolioBySymbol returns a portfolio by symbol
@@ -605,22 +656,28 @@ func (s *PortfolioService) GetPortfolioBySymbol(userID primitive.ObjectID, symbo
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("portfolios")
-
-	var portfolio models.Portfolio
-	err := collection.FindOne(ctx, bson.M{
-		"user_id": userID,
-		"symbol":  symbol,
-	}).Decode(&portfolio)
-
-	if err == mongo.ErrNoDocuments {
+	portfolio, err := s.repos.Portfolios.FindBySymbol(ctx, userID, symbol)
+	if errors.Is(err, repository.ErrNotFound) {
 		return nil, fmt.Errorf("portfolio not found")
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch portfolio: %w", err)
 	}
 
-	return &portfolio, nil
+	return portfolio, nil
+}
+
+// GetUserPortfolios returns all of a user's portfolio entries with their metadata
+func (s *PortfolioService) GetUserPortfolios(ctx context.Context, userID primitive.ObjectID) ([]models.Portfolio, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	portfolios, err := s.repos.Portfolios.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch portfolios: %w", err)
+	}
+
+	return portfolios, nil
 }
 
 // CreatePortfolioWithMetadata creates a new portfolio with asset style and asset class
@@ -628,15 +685,8 @@ func (s *PortfolioService) CreatePortfolioWithMetadata(userID primitive.ObjectID
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := database.Database.Collection("portfolios")
-
 	// Check if portfolio already exists
-	var existing models.Portfolio
-	err := collection.FindOne(ctx, bson.M{
-		"user_id": userID,
-		"symbol":  symbol,
-	}).Decode(&existing)
-
+	existing, err := s.repos.Portfolios.FindBySymbol(ctx, userID, symbol)
 	if err == nil {
 		// Portfolio already exists, update its metadata
 		err = s.UpdatePortfolioMetadata(userID, existing.ID, assetStyleID, assetClass)
@@ -646,19 +696,12 @@ func (s *PortfolioService) CreatePortfolioWithMetadata(userID primitive.ObjectID
 		return existing.ID, nil
 	}
 
-	if err != mongo.ErrNoDocuments {
+	if !errors.Is(err, repository.ErrNotFound) {
 		return primitive.NilObjectID, fmt.Errorf("failed to query portfolio: %w", err)
 	}
 
 	// Validate asset class
-	validAssetClasses := map[string]bool{
-		"Stock":                 true,
-		"ETF":                   true,
-		"Bond":                  true,
-		"Cash and Equivalents": true,
-	}
-
-	if !validAssetClasses[assetClass] {
+	if !slices.Contains(AssetClasses, assetClass) {
 		return primitive.NilObjectID, fmt.Errorf("invalid asset class")
 	}
 
@@ -673,10 +716,42 @@ func (s *PortfolioService) CreatePortfolioWithMetadata(userID primitive.ObjectID
 		UpdatedAt:    time.Now(),
 	}
 
-	_, err = collection.InsertOne(ctx, portfolio)
+	err = s.repos.Portfolios.Insert(ctx, &portfolio)
 	if err != nil {
 		return primitive.NilObjectID, fmt.Errorf("failed to create portfolio: %w", err)
 	}
 
+	dataVersions.bump(userID)
 	return portfolio.ID, nil
 }
+
+// GetDataVersion returns a fingerprint of the user's portfolio data that changes
+// whenever a transaction, portfolio, asset style, asset subclass or price
+// override is created, updated, or deleted.
+// It is cheap to compute compared to the holdings themselves.
+func (s *PortfolioService) GetDataVersion(userID primitive.ObjectID) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collections := []struct {
+		name    string
+		version func(context.Context, primitive.ObjectID) (repository.Version, error)
+	}{
+		{"transactions", s.repos.Transactions.Version},
+		{"portfolios", s.repos.Portfolios.Version},
+		{"asset_styles", s.repos.AssetStyles.Version},
+		{"asset_subclasses", s.repos.AssetSubclasses.Version},
+		{"price_overrides", s.repos.PriceOverrides.Version},
+	}
+
+	version := ""
+	for _, collection := range collections {
+		v, err := collection.version(ctx, userID)
+		if err != nil {
+			return "", err
+		}
+		version += fmt.Sprintf("%s:%d:%d;", collection.name, v.Count, v.LatestUpdate.UnixNano())
+	}
+
+	return version, nil
+}
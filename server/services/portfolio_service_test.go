@@ -2,8 +2,11 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"stock-portfolio-tracker/database"
 	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
 	"testing"
 	"time"
 
@@ -19,8 +22,8 @@ func setupPortfolioTest(t *testing.T) (*PortfolioService, primitive.ObjectID, pr
 		t.Fatalf("Failed to connect to test database: %v", err)
 	}
 
-	stockService := NewStockAPIService()
-	currencyService := NewCurrencyService()
+	stockService := NewStockAPIService(StockAPIConfig{})
+	currencyService := NewCurrencyService(CurrencyConfig{})
 	service := NewPortfolioService(stockService, currencyService)
 	
 	userID := primitive.NewObjectID()
@@ -217,3 +220,193 @@ func TestUpdatePortfolioMetadataInvalidAssetClass(t *testing.T) {
 		t.Error("Expected error for invalid asset class")
 	}
 }
+
+// newMemoryPortfolioService creates a PortfolioService over in-memory
+// repositories, for tests that don't need MongoDB or price lookups
+func newMemoryPortfolioService() (*PortfolioService, repository.Repositories) {
+	repos := repository.NewMemory()
+	service := NewPortfolioServiceWithRepos(NewStockAPIService(StockAPIConfig{}), NewCurrencyService(CurrencyConfig{}), repos)
+	return service, repos
+}
+
+func TestTransactionLifecycleWithMemoryRepos(t *testing.T) {
+	service, repos := newMemoryPortfolioService()
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -1)
+
+	buy := &models.Transaction{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 100, Currency: "USD", Date: date}
+	if err := service.AddTransaction(userID, buy); err != nil {
+		t.Fatalf("Failed to add buy: %v", err)
+	}
+
+	// Selling more than is held is rejected
+	oversell := &models.Transaction{Symbol: "AAPL", Action: "sell", Shares: 11, Price: 120, Currency: "USD", Date: date}
+	if err := service.AddTransaction(userID, oversell); err != ErrInsufficientShares {
+		t.Errorf("Expected ErrInsufficientShares, got %v", err)
+	}
+
+	// The buy created the portfolio entry the transaction points at
+	portfolio, err := service.GetPortfolioBySymbol(userID, "AAPL")
+	if err != nil {
+		t.Fatalf("Failed to get portfolio: %v", err)
+	}
+	if buy.PortfolioID != portfolio.ID {
+		t.Errorf("Expected transaction in portfolio %s, got %s", portfolio.ID.Hex(), buy.PortfolioID.Hex())
+	}
+
+	// Shrinking the buy below an existing sell is rejected
+	sell := &models.Transaction{Symbol: "AAPL", Action: "sell", Shares: 4, Price: 120, Currency: "USD", Date: date}
+	if err := service.AddTransaction(userID, sell); err != nil {
+		t.Fatalf("Failed to add sell: %v", err)
+	}
+	smallerSell := *sell
+	smallerSell.Shares = 2
+	if err := service.UpdateTransaction(userID, sell.ID, &smallerSell); err != nil {
+		t.Fatalf("Failed to update sell: %v", err)
+	}
+
+	positions, err := service.getPositions(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("Failed to get positions: %v", err)
+	}
+	if len(positions) != 1 || positions[0].Shares != 8 {
+		t.Errorf("Expected 8 AAPL shares, got %+v", positions)
+	}
+
+	if err := service.DeleteTransaction(userID, sell.ID); err != nil {
+		t.Fatalf("Failed to delete sell: %v", err)
+	}
+	if err := service.DeleteTransaction(userID, sell.ID); err != ErrTransactionNotFound {
+		t.Errorf("Expected ErrTransactionNotFound, got %v", err)
+	}
+	if err := service.DeleteTransaction(primitive.NewObjectID(), buy.ID); err != ErrTransactionNotFound {
+		t.Errorf("Expected ErrTransactionNotFound for another user, got %v", err)
+	}
+
+	transactions, _ := repos.Transactions.FindBySymbol(context.Background(), userID, "AAPL")
+	if len(transactions) != 1 {
+		t.Errorf("Expected 1 remaining transaction, got %d", len(transactions))
+	}
+}
+
+func TestGetDataVersionWithMemoryRepos(t *testing.T) {
+	service, _ := newMemoryPortfolioService()
+	userID := primitive.NewObjectID()
+
+	before, err := service.GetDataVersion(userID)
+	if err != nil {
+		t.Fatalf("Failed to get data version: %v", err)
+	}
+
+	tx := &models.Transaction{Symbol: "AAPL", Action: "buy", Shares: 1, Price: 100, Currency: "USD", Date: time.Now().AddDate(0, 0, -1)}
+	if err := service.AddTransaction(userID, tx); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+
+	after, err := service.GetDataVersion(userID)
+	if err != nil {
+		t.Fatalf("Failed to get data version: %v", err)
+	}
+	if before == after {
+		t.Errorf("Expected data version to change after adding a transaction, still %q", after)
+	}
+}
+
+func TestTransactionNotesAndTags(t *testing.T) {
+	service, _ := newMemoryPortfolioService()
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -2)
+
+	harvest := &models.Transaction{
+		Symbol: "AAPL", Action: "buy", Shares: 10, Price: 100, Currency: "USD", Date: date,
+		Note: "  rebuy after harvest  ",
+		Tags: []string{" Tax-Loss  Harvest", "tax-loss harvest", "", "Earnings Play"},
+	}
+	if err := service.AddTransaction(userID, harvest); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	if harvest.Note != "rebuy after harvest" {
+		t.Errorf("Expected trimmed note, got %q", harvest.Note)
+	}
+	if len(harvest.Tags) != 2 || harvest.Tags[0] != "tax-loss harvest" || harvest.Tags[1] != "earnings play" {
+		t.Errorf("Expected normalized, de-duplicated tags, got %q", harvest.Tags)
+	}
+
+	untagged := &models.Transaction{Symbol: "MSFT", Action: "buy", Shares: 1, Price: 300, Currency: "USD", Date: date.AddDate(0, 0, 1)}
+	if err := service.AddTransaction(userID, untagged); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+
+	tagged, err := service.GetTransactionsByTag(userID, "Earnings Play")
+	if err != nil {
+		t.Fatalf("Failed to filter by tag: %v", err)
+	}
+	if len(tagged) != 1 || tagged[0].ID != harvest.ID {
+		t.Errorf("Expected only the tagged transaction, got %d", len(tagged))
+	}
+
+	bySymbol, _ := service.GetTransactionsBySymbol(userID, "MSFT")
+	if filtered := FilterTransactionsByTag(bySymbol, "earnings play"); len(filtered) != 0 {
+		t.Errorf("Expected no tagged MSFT transactions, got %d", len(filtered))
+	}
+
+	tooMany := &models.Transaction{Symbol: "AAPL", Action: "buy", Shares: 1, Price: 100, Currency: "USD", Date: date}
+	for i := 0; i <= maxTransactionTags; i++ {
+		tooMany.Tags = append(tooMany.Tags, fmt.Sprintf("tag %d", i))
+	}
+	if err := service.AddTransaction(userID, tooMany); !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("Expected ErrInvalidTransaction for too many tags, got %v", err)
+	}
+}
+
+func TestHoldingsRoundToMinorUnits(t *testing.T) {
+	fixture := NewFixtureProvider().
+		SetQuote("XYZ", "XYZ Corp", 0.1, "USD").
+		SetRate("USD", "JPY", 149.567)
+	service := NewPortfolioServiceWithRepos(fixture, fixture, repository.NewMemory())
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -1)
+
+	// Ten buys of a dime sum to 0.9999999999999999 in float64
+	for i := 0; i < 10; i++ {
+		tx := &models.Transaction{Symbol: "XYZ", Action: "buy", Shares: 1, Price: 0.1, Currency: "USD", Date: date}
+		if err := service.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+
+	holdings, err := service.GetUserHoldings(userID, "USD")
+	if err != nil || len(holdings) != 1 {
+		t.Fatalf("Expected 1 holding, got %+v, %v", holdings, err)
+	}
+	if holding := holdings[0]; holding.CostBasis != 1 || holding.CurrentValue != 1 || holding.GainLoss != 0 {
+		t.Errorf("Expected exact cost and value of 1 with no gain, got %+v", holding)
+	}
+
+	// Yen has no minor unit
+	holdings, err = service.GetUserHoldings(userID, "JPY")
+	if err != nil || len(holdings) != 1 {
+		t.Fatalf("Expected 1 holding, got %+v, %v", holdings, err)
+	}
+	if holding := holdings[0]; holding.CostBasis != 150 || holding.CurrentValue != 150 {
+		t.Errorf("Expected whole yen amounts, got %+v", holding)
+	}
+}
+
+func TestTransactionsAcceptCNY(t *testing.T) {
+	service, _ := newMemoryPortfolioService()
+	userID := primitive.NewObjectID()
+
+	tx := &models.Transaction{Symbol: "600519.SS", Action: "buy", Shares: 10, Price: 1500, Currency: "cny", Date: time.Now().AddDate(0, 0, -1)}
+	if err := service.AddTransaction(userID, tx); err != nil {
+		t.Fatalf("Expected CNY to be accepted, got %v", err)
+	}
+	if tx.Currency != "RMB" {
+		t.Errorf("Expected CNY stored as RMB, got %q", tx.Currency)
+	}
+
+	tx = &models.Transaction{Symbol: "0700.HK", Action: "buy", Shares: 10, Price: 300, Currency: "HKD", Date: time.Now().AddDate(0, 0, -1)}
+	if err := service.AddTransaction(userID, tx); !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("Expected ErrInvalidTransaction for HKD, got %v", err)
+	}
+}
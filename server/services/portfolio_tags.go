@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrPortfolioNotFound   = errors.New("portfolio not found")
+	ErrInvalidPortfolioTag = errors.New("invalid portfolio tag")
+)
+
+// Limits on the tags users attach to portfolios
+const (
+	maxPortfolioTags      = 20
+	maxPortfolioTagLength = 50
+)
+
+// tagGroupByPrefix introduces a tag dimension in a groupBy parameter, as in
+// tag:horizon
+const tagGroupByPrefix = "tag:"
+
+// untaggedGroup collects holdings without a tag in the requested dimension
+const untaggedGroup = "Untagged"
+
+// TagGroupBy returns the tag dimension a groupBy parameter such as
+// tag:horizon names, and whether it names one
+func TagGroupBy(groupBy string) (string, bool) {
+	name, ok := strings.CutPrefix(groupBy, tagGroupByPrefix)
+	if !ok {
+		return "", false
+	}
+	name = NormalizeTag(name)
+	if name == "" || strings.Contains(name, ":") {
+		return "", false
+	}
+	return name, true
+}
+
+// SetPortfolioTags replaces a portfolio's tags and returns them as stored:
+// normalized like transaction tags, de-duplicated and in their original order
+func (s *PortfolioService) SetPortfolioTags(userID, portfolioID primitive.ObjectID, tags []string) ([]string, error) {
+	tags, err := normalizePortfolioTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = s.repos.Portfolios.SetTags(ctx, userID, portfolioID, tags)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrPortfolioNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update portfolio tags: %w", err)
+	}
+
+	dataVersions.bump(userID)
+	return tags, nil
+}
+
+// normalizePortfolioTags normalizes each tag, trimming both sides of a
+// name:value tag, drops empty tags and duplicates, and checks the limits
+func normalizePortfolioTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if name, value, ok := strings.Cut(tag, ":"); ok {
+			name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+			if name == "" || value == "" || strings.Contains(value, ":") {
+				return nil, fmt.Errorf("%w: %q must be a name or name:value", ErrInvalidPortfolioTag, tag)
+			}
+			tag = name + ":" + value
+		}
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxPortfolioTagLength {
+			return nil, fmt.Errorf("%w: tags cannot exceed %d characters", ErrInvalidPortfolioTag, maxPortfolioTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxPortfolioTags {
+		return nil, fmt.Errorf("%w: a portfolio can have at most %d tags", ErrInvalidPortfolioTag, maxPortfolioTags)
+	}
+	return normalized, nil
+}
+
+// tagGroup returns the group tags file a holding under in the dimension name:
+// the value of its first name:value tag, or name itself for a plain name tag.
+// It returns "" when no tag is in the dimension.
+func tagGroup(tags []string, name string) string {
+	for _, tag := range tags {
+		if tag == name {
+			return name
+		}
+		if key, value, ok := strings.Cut(tag, ":"); ok && key == name {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stock-portfolio-tracker/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSetPortfolioTags(t *testing.T) {
+	service, _ := newMemoryPortfolioService()
+	userID := primitive.NewObjectID()
+
+	portfolioID, err := service.CreatePortfolioWithMetadata(userID, "AAPL", primitive.NilObjectID, "Stock")
+	if err != nil {
+		t.Fatalf("Failed to create portfolio: %v", err)
+	}
+
+	tags, err := service.SetPortfolioTags(userID, portfolioID, []string{" Horizon : Retirement", "speculative", "horizon:retirement", ""})
+	if err != nil {
+		t.Fatalf("Failed to set tags: %v", err)
+	}
+	if len(tags) != 2 || tags[0] != "horizon:retirement" || tags[1] != "speculative" {
+		t.Errorf("Expected normalized, de-duplicated tags, got %v", tags)
+	}
+
+	for _, invalid := range []string{"horizon:", ":retirement", "a:b:c"} {
+		if _, err := service.SetPortfolioTags(userID, portfolioID, []string{invalid}); !errors.Is(err, ErrInvalidPortfolioTag) {
+			t.Errorf("Expected ErrInvalidPortfolioTag for %q, got %v", invalid, err)
+		}
+	}
+	if _, err := service.SetPortfolioTags(userID, primitive.NewObjectID(), []string{"core"}); err != ErrPortfolioNotFound {
+		t.Errorf("Expected ErrPortfolioNotFound, got %v", err)
+	}
+
+	if _, err := service.SetPortfolioTags(userID, portfolioID, nil); err != nil {
+		t.Fatalf("Failed to clear tags: %v", err)
+	}
+	portfolio, _ := service.GetPortfolioWithMetadata(userID, portfolioID)
+	if len(portfolio.Tags) != 0 {
+		t.Errorf("Expected no tags, got %v", portfolio.Tags)
+	}
+}
+
+func TestTagGroupBy(t *testing.T) {
+	for groupBy, want := range map[string]string{"tag:horizon": "horizon", "tag: Risk Level ": "risk level", "tag:": "", "tag:a:b": "", "currency": ""} {
+		name, ok := TagGroupBy(groupBy)
+		if name != want || ok != (want != "") {
+			t.Errorf("TagGroupBy(%q) = %q, %v, want %q", groupBy, name, ok, want)
+		}
+	}
+}
+
+func TestTagGroupingOfHoldings(t *testing.T) {
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple Inc.", 100, "USD").
+		SetQuote("TSLA", "Tesla", 100, "USD").
+		SetQuote("KO", "Coca-Cola", 100, "USD")
+	service, portfolioService := newFixtureAnalyticsService(provider)
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -5)
+
+	for _, tx := range []*models.Transaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 5, Price: 100, Currency: "USD", Date: date},
+		{Symbol: "TSLA", Action: "buy", Shares: 3, Price: 100, Currency: "USD", Date: date},
+		{Symbol: "KO", Action: "buy", Shares: 2, Price: 100, Currency: "USD", Date: date},
+	} {
+		if err := portfolioService.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+	for symbol, tags := range map[string][]string{"AAPL": {"horizon:retirement"}, "TSLA": {"speculative", "horizon:speculative"}} {
+		portfolioID, err := portfolioService.CreatePortfolioWithMetadata(userID, symbol, primitive.NilObjectID, "Stock")
+		if err != nil {
+			t.Fatalf("Failed to create portfolio: %v", err)
+		}
+		if _, err := portfolioService.SetPortfolioTags(userID, portfolioID, tags); err != nil {
+			t.Fatalf("Failed to tag %s: %v", symbol, err)
+		}
+	}
+
+	metrics, err := service.GetGroupedDashboardMetrics(context.Background(), userID, "USD", "tag:Horizon")
+	if err != nil {
+		t.Fatalf("Failed to get grouped metrics: %v", err)
+	}
+	if metrics.GroupBy != "tag:horizon" {
+		t.Errorf("Expected the normalized groupBy, got %q", metrics.GroupBy)
+	}
+	values := make(map[string]float64)
+	for _, group := range metrics.Groups {
+		values[group.GroupName] = group.GroupValue
+	}
+	if len(values) != 3 || values["retirement"] != 500 || values["speculative"] != 300 || values["Untagged"] != 200 {
+		t.Errorf("Expected retirement, speculative and Untagged groups, got %v", values)
+	}
+
+	if _, err := service.GetGroupedDashboardMetrics(context.Background(), userID, "USD", "tag:"); err == nil {
+		t.Error("Expected an error for a tag groupBy without a name")
+	}
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/internal/analytics/engine"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/money"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrPositionNotFound = errors.New("no transactions for this symbol")
+
+// PositionLot is a purchase that is still held. Sells are matched against
+// the oldest lots first; the position's own cost basis uses the average cost
+// method, like holdings.
+type PositionLot struct {
+	TransactionID primitive.ObjectID `json:"transactionId"`
+	Date          time.Time          `json:"date"`
+	Shares        float64            `json:"shares"` // Still held
+	Price         float64            `json:"price"`  // Per share, in the transaction currency
+	CostBasis     float64            `json:"costBasis"`
+	CurrentValue  float64            `json:"currentValue"`
+	GainLoss      float64            `json:"gainLoss"`
+}
+
+// PositionDividend is a dividend that went ex while the position was held
+type PositionDividend struct {
+	ExDate         time.Time  `json:"exDate"`
+	PayDate        *time.Time `json:"payDate,omitempty"`
+	AmountPerShare float64    `json:"amountPerShare"` // In the dividend currency
+	Shares         float64    `json:"shares"`         // Held the day before the ex-date
+	Amount         float64    `json:"amount"`
+}
+
+// PositionDetail is everything about one symbol's position. Amounts are in
+// Currency, converted at the current exchange rate.
+type PositionDetail struct {
+	Symbol                    string               `json:"symbol"`
+	Name                      string               `json:"name"`
+	Currency                  string               `json:"currency"`
+	Shares                    float64              `json:"shares"`
+	AverageCost               float64              `json:"averageCost"` // Per share
+	CostBasis                 float64              `json:"costBasis"`
+	CurrentPrice              float64              `json:"currentPrice"`
+	CurrentValue              float64              `json:"currentValue"`
+	UnrealizedGainLoss        float64              `json:"unrealizedGainLoss"`
+	UnrealizedGainLossPercent float64              `json:"unrealizedGainLossPercent"`
+	RealizedGainLoss          float64              `json:"realizedGainLoss"` // Sell proceeds net of fees minus average cost
+	DividendIncome            float64              `json:"dividendIncome"`
+	Lots                      []PositionLot        `json:"lots"`
+	Dividends                 []PositionDividend   `json:"dividends"`
+	Transactions              []models.Transaction `json:"transactions"` // Newest first
+	Period                    string               `json:"period"`
+	Prices                    []HistoricalPrice    `json:"prices"` // Chart series in Currency
+	// Moving averages, 52-week range and latest moving average cross of the
+	// symbol's daily closes, in Currency
+	Technicals *PriceTechnicals `json:"technicals,omitempty"`
+	// PriceOverride is set when the user set a manual price for the symbol
+	PriceOverride *models.PriceOverride `json:"priceOverride,omitempty"`
+}
+
+// openLot is a purchase being folded, in the transaction currency
+type openLot struct {
+	tx     models.Transaction
+	shares float64
+	cost   money.Amount
+}
+
+// GetPositionDetail returns the position view of one symbol: its lots,
+// average cost, realized and unrealized gain, the dividends it earned, its
+// transactions and a price chart over period. Closed positions are reported
+// with no shares.
+func (s *PortfolioService) GetPositionDetail(ctx context.Context, userID primitive.ObjectID, symbol, currency, period string) (*PositionDetail, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	currency, err := NormalizeDisplayCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	transactions, err := s.repos.Transactions.FindBySymbol(queryCtx, userID, symbol)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+	if len(transactions) == 0 {
+		return nil, ErrPositionNotFound
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		if !transactions[i].Date.Equal(transactions[j].Date) {
+			return transactions[i].Date.Before(transactions[j].Date)
+		}
+		return transactions[i].CreatedAt.Before(transactions[j].CreatedAt)
+	})
+
+	// Fold the transactions with the average cost method, as the holdings
+	// aggregation does, tracking the lots still held alongside
+	txCurrency := transactions[0].Currency
+	shares := 0.0
+	cost := money.Zero(txCurrency)
+	realized := money.Zero(txCurrency)
+	var lots []openLot
+	for _, tx := range transactions {
+		switch {
+		case tx.Action == "buy":
+			lotCost := money.New(tx.Price*tx.Shares+tx.Fees, txCurrency)
+			shares += tx.Shares
+			cost = cost.Add(lotCost)
+			lots = append(lots, openLot{tx: tx, shares: tx.Shares, cost: lotCost})
+		case tx.Action == "sell" && shares > 0:
+			removed := cost.Mul(tx.Shares / shares)
+			realized = realized.Add(money.New(tx.Price*tx.Shares-tx.Fees, txCurrency).Sub(removed))
+			cost = cost.Sub(removed)
+			shares -= tx.Shares
+			lots = consumeLots(lots, tx.Shares)
+		}
+	}
+
+	queryCtx, cancel = context.WithTimeout(ctx, 5*time.Second)
+	override, err := s.repos.PriceOverrides.FindBySymbol(queryCtx, userID, symbol)
+	cancel()
+	if errors.Is(err, repository.ErrNotFound) {
+		override = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch price override: %w", err)
+	}
+
+	position := repository.Position{Symbol: symbol, Shares: shares, Cost: cost.Float64(), Currency: txCurrency}
+	holding, err := s.calculateHolding(ctx, position, currency, override)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := 1.0
+	if txCurrency != currency {
+		rate, err = s.currencyService.GetExchangeRate(txCurrency, currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert to %s: %w", currency, err)
+		}
+	}
+
+	detail := &PositionDetail{
+		Symbol:                    symbol,
+		Name:                      holding.Name,
+		Currency:                  currency,
+		Shares:                    holding.Shares,
+		CostBasis:                 holding.CostBasis,
+		CurrentPrice:              holding.CurrentPrice,
+		CurrentValue:              holding.CurrentValue,
+		UnrealizedGainLoss:        holding.GainLoss,
+		UnrealizedGainLossPercent: holding.GainLossPercent,
+		RealizedGainLoss:          money.Round(realized.Float64()*rate, currency),
+		Lots:                      []PositionLot{},
+		Dividends:                 []PositionDividend{},
+		Period:                    period,
+		Prices:                    []HistoricalPrice{},
+		PriceOverride:             override,
+	}
+	if holding.Shares > 0 {
+		detail.AverageCost = holding.CostBasis / holding.Shares
+	}
+	if detail.Name == "" {
+		if info, err := s.stockService.GetStockInfoContext(ctx, symbol); err == nil {
+			detail.Name = info.Name
+		}
+	}
+
+	for _, lot := range lots {
+		costBasis := money.New(lot.cost.Float64()*rate, currency)
+		currentValue := money.New(lot.shares*holding.CurrentPrice, currency)
+		detail.Lots = append(detail.Lots, PositionLot{
+			TransactionID: lot.tx.ID,
+			Date:          lot.tx.Date,
+			Shares:        lot.shares,
+			Price:         lot.tx.Price,
+			CostBasis:     costBasis.Float64(),
+			CurrentValue:  currentValue.Float64(),
+			GainLoss:      currentValue.Sub(costBasis).Float64(),
+		})
+	}
+
+	detail.Dividends, detail.DividendIncome = s.positionDividends(ctx, symbol, transactions, currency)
+
+	// The chart and technicals convert at the same rate as the current price
+	priceRate := 1.0
+	if tradingCurrency := s.stockService.SymbolCurrency(symbol); tradingCurrency != currency {
+		if converted, err := s.currencyService.GetExchangeRate(tradingCurrency, currency); err == nil {
+			priceRate = converted
+		}
+	}
+	if prices, err := s.stockService.GetHistoricalDataContext(ctx, symbol, period); err == nil {
+		if override != nil {
+			prices = overrideHistory(prices, *override)
+		}
+		for _, price := range engine.Sorted(prices) {
+			detail.Prices = append(detail.Prices, HistoricalPrice{Date: price.Date, Price: price.Price * priceRate})
+		}
+	} else {
+		fmt.Printf("Warning: failed to fetch price history for %s: %v\n", symbol, err)
+	}
+	// A manual price has no history to average
+	if override == nil && !s.stockService.IsCashSymbol(symbol) {
+		if technicals, err := s.SymbolTechnicals(ctx, symbol); err == nil {
+			detail.Technicals = technicals.converted(priceRate)
+		} else {
+			fmt.Printf("Warning: failed to compute technicals for %s: %v\n", symbol, err)
+		}
+	}
+
+	detail.Transactions = make([]models.Transaction, len(transactions))
+	for i, tx := range transactions {
+		detail.Transactions[len(transactions)-1-i] = tx
+	}
+
+	return detail, nil
+}
+
+// consumeLots removes sold shares from the oldest lots first, reducing each
+// lot's cost in proportion
+func consumeLots(lots []openLot, sold float64) []openLot {
+	for sold > 0 && len(lots) > 0 {
+		lot := &lots[0]
+		if sold < lot.shares {
+			lot.cost = lot.cost.Sub(lot.cost.Mul(sold / lot.shares))
+			lot.shares -= sold
+			return lots
+		}
+		sold -= lot.shares
+		lots = lots[1:]
+	}
+	return lots
+}
+
+// positionDividends returns the dividends that went ex while the position was
+// held, with the amount earned on the shares held the day before, and their
+// total in currency
+func (s *PortfolioService) positionDividends(ctx context.Context, symbol string, transactions []models.Transaction, currency string) ([]PositionDividend, float64) {
+	dividends := []PositionDividend{}
+	events, err := s.stockService.GetDividendsContext(ctx, symbol)
+	if err != nil {
+		fmt.Printf("Warning: failed to fetch dividends for %s: %v\n", symbol, err)
+		return dividends, 0
+	}
+
+	now := time.Now()
+	total := money.Zero(currency)
+	for _, event := range events {
+		if event.ExDate.After(now) {
+			continue
+		}
+
+		held := 0.0
+		for _, tx := range transactions {
+			if !tx.Date.Before(event.ExDate) {
+				break
+			}
+			if tx.Action == "buy" {
+				held += tx.Shares
+			} else if tx.Action == "sell" {
+				held -= tx.Shares
+			}
+		}
+		if held <= 0 {
+			continue
+		}
+
+		amount := event.Amount * held
+		if event.Currency != "" && event.Currency != currency {
+			converted, err := s.currencyService.ConvertAmount(amount, event.Currency, currency)
+			if err != nil {
+				fmt.Printf("Warning: failed to convert dividend for %s: %v\n", symbol, err)
+				continue
+			}
+			amount = converted
+		}
+		received := money.New(amount, currency)
+		total = total.Add(received)
+		dividends = append(dividends, PositionDividend{
+			ExDate:         event.ExDate,
+			PayDate:        event.PayDate,
+			AmountPerShare: event.Amount,
+			Shares:         held,
+			Amount:         received.Float64(),
+		})
+	}
+
+	return dividends, total.Float64()
+}
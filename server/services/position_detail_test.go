@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGetPositionDetail(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	provider := NewFixtureProvider().
+		SetQuote("KO", "Coca-Cola", 60, "USD").
+		SetHistory("KO", today, 55, 58, 60).
+		SetDividends("KO", DividendEvent{ExDate: today.AddDate(0, 0, -5), Amount: 0.5}).
+		SetRate("USD", "RMB", 7)
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	userID := primitive.NewObjectID()
+
+	for _, tx := range []*models.Transaction{
+		{Symbol: "KO", Action: "buy", Shares: 10, Price: 40, Currency: "USD", Date: today.AddDate(0, 0, -30)},
+		{Symbol: "KO", Action: "buy", Shares: 10, Price: 50, Currency: "USD", Date: today.AddDate(0, 0, -20)},
+		{Symbol: "KO", Action: "sell", Shares: 15, Price: 55, Currency: "USD", Fees: 5, Date: today.AddDate(0, 0, -3)},
+	} {
+		if err := portfolioService.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+
+	detail, err := portfolioService.GetPositionDetail(context.Background(), userID, "ko", "USD", "1M")
+	if err != nil {
+		t.Fatalf("Failed to get position: %v", err)
+	}
+
+	// Average cost 45: the sale removes 675 of cost against 820 of net proceeds
+	if detail.Shares != 5 || detail.CostBasis != 225 || detail.AverageCost != 45 || detail.RealizedGainLoss != 145 {
+		t.Errorf("Unexpected position %+v", detail)
+	}
+	if detail.CurrentValue != 300 || detail.UnrealizedGainLoss != 75 {
+		t.Errorf("Expected 300 value and 75 unrealized, got %.2f and %.2f", detail.CurrentValue, detail.UnrealizedGainLoss)
+	}
+
+	// The sale consumed the first lot and half of the second
+	if len(detail.Lots) != 1 || detail.Lots[0].Shares != 5 || detail.Lots[0].Price != 50 || detail.Lots[0].CostBasis != 250 {
+		t.Errorf("Expected 5 shares left of the 50 lot, got %+v", detail.Lots)
+	}
+
+	// 20 shares were held before the ex-date
+	if len(detail.Dividends) != 1 || detail.Dividends[0].Shares != 20 || detail.DividendIncome != 10 {
+		t.Errorf("Expected 10 of dividends on 20 shares, got %+v", detail.Dividends)
+	}
+	if len(detail.Transactions) != 3 || detail.Transactions[0].Action != "sell" {
+		t.Errorf("Expected transactions newest first, got %+v", detail.Transactions)
+	}
+	if len(detail.Prices) != 3 || detail.Prices[2].Price != 60 {
+		t.Errorf("Unexpected price series %+v", detail.Prices)
+	}
+
+	converted, err := portfolioService.GetPositionDetail(context.Background(), userID, "KO", "RMB", "1M")
+	if err != nil {
+		t.Fatalf("Failed to get position in RMB: %v", err)
+	}
+	if converted.RealizedGainLoss != 1015 || math.Abs(converted.Prices[2].Price-420) > 1e-9 {
+		t.Errorf("Expected amounts converted to RMB, got %+v", converted)
+	}
+	if converted.Technicals == nil || converted.Technicals.FiftyTwoWeekHigh != 420 || detail.Technicals.FiftyTwoWeekHigh != 60 {
+		t.Errorf("Expected the 52-week range converted to RMB, got %+v", converted.Technicals)
+	}
+
+	if _, err := portfolioService.GetPositionDetail(context.Background(), userID, "PEP", "USD", "1M"); !errors.Is(err, ErrPositionNotFound) {
+		t.Errorf("Expected ErrPositionNotFound, got %v", err)
+	}
+}
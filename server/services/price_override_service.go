@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrPriceOverrideNotFound = errors.New("price override not found")
+	ErrInvalidPriceOverride  = errors.New("invalid price override")
+)
+
+// PriceOverrideService manages the manual prices users set for symbols the
+// providers misprice or don't quote. Holdings and analytics use a symbol's
+// manual price from its effective date on.
+type PriceOverrideService struct {
+	repos        repository.Repositories
+	stockService StockDataProvider
+}
+
+// NewPriceOverrideService creates a new PriceOverrideService instance
+func NewPriceOverrideService(portfolioService *PortfolioService) *PriceOverrideService {
+	return &PriceOverrideService{
+		repos:        portfolioService.repos,
+		stockService: portfolioService.stockService,
+	}
+}
+
+// SetOverride sets the manual price of a symbol, replacing any earlier one
+func (s *PriceOverrideService) SetOverride(userID primitive.ObjectID, symbol string, req models.PriceOverrideRequest) (*models.PriceOverride, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return nil, ErrInvalidSymbol
+	}
+	if s.stockService.IsCashSymbol(symbol) {
+		return nil, fmt.Errorf("%w: cash is always priced at 1", ErrInvalidPriceOverride)
+	}
+	if req.Price <= 0 {
+		return nil, fmt.Errorf("%w: price must be positive", ErrInvalidPriceOverride)
+	}
+
+	now := time.Now()
+	effective := accrualDay(now)
+	if !req.EffectiveDate.IsZero() {
+		effective = accrualDay(req.EffectiveDate)
+	}
+	if effective.After(now) {
+		return nil, fmt.Errorf("%w: effective date cannot be in the future", ErrInvalidPriceOverride)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	override, err := s.repos.PriceOverrides.FindBySymbol(ctx, userID, symbol)
+	created := errors.Is(err, repository.ErrNotFound)
+	if created {
+		override = &models.PriceOverride{
+			ID:        primitive.NewObjectID(),
+			UserID:    userID,
+			Symbol:    symbol,
+			CreatedAt: now,
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find price override: %w", err)
+	}
+
+	override.Price = req.Price
+	override.Currency = s.stockService.SymbolCurrency(symbol)
+	override.EffectiveDate = effective
+	override.Note = strings.TrimSpace(req.Note)
+	override.UpdatedAt = now
+	if created {
+		err = s.repos.PriceOverrides.Insert(ctx, override)
+	} else {
+		err = s.repos.PriceOverrides.Update(ctx, override)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save price override: %w", err)
+	}
+
+	dataVersions.bump(userID)
+	return override, nil
+}
+
+// ListOverrides returns the user's manual prices
+func (s *PriceOverrideService) ListOverrides(userID primitive.ObjectID) ([]models.PriceOverride, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	overrides, err := s.repos.PriceOverrides.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price overrides: %w", err)
+	}
+
+	return overrides, nil
+}
+
+// DeleteOverride removes a symbol's manual price, so the providers' price is
+// used again
+func (s *PriceOverrideService) DeleteOverride(userID primitive.ObjectID, symbol string) error {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.repos.PriceOverrides.Delete(ctx, userID, symbol)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrPriceOverrideNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete price override: %w", err)
+	}
+
+	dataVersions.bump(userID)
+	return nil
+}
+
+// priceOverrides returns the user's manual prices by symbol
+func (s *PortfolioService) priceOverrides(ctx context.Context, userID primitive.ObjectID) (map[string]models.PriceOverride, error) {
+	overrides, err := s.repos.PriceOverrides.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price overrides: %w", err)
+	}
+
+	bySymbol := make(map[string]models.PriceOverride, len(overrides))
+	for _, override := range overrides {
+		bySymbol[override.Symbol] = override
+	}
+	return bySymbol, nil
+}
+
+// overridePrice returns a copy of a symbol's quote priced at its manual
+// price. The quote is nil when the providers have none. The providers' 52-week
+// range, previous close and extended-hours trade don't describe the manual
+// price and are dropped.
+func overridePrice(symbol string, info *StockInfo, override models.PriceOverride) *StockInfo {
+	overridden := StockInfo{Symbol: symbol, Name: symbol}
+	if info != nil {
+		overridden = *info
+	}
+	overridden.CurrentPrice = override.Price
+	overridden.Currency = override.Currency
+	overridden.FiftyTwoWeekHigh = 0
+	overridden.FiftyTwoWeekLow = 0
+	overridden.PreviousClose = 0
+	overridden.PriceAsOf = nil
+	overridden.Session = ""
+	overridden.ExtendedHours = nil
+	effective := override.EffectiveDate
+	overridden.PriceSource = PriceSource{Source: PriceSourceManual, AsOf: &effective}
+	return &overridden
+}
+
+// overrideHistory returns a copy of a price history with the closes from the
+// override's effective date on replaced by its manual price
+func overrideHistory(prices []HistoricalPrice, override models.PriceOverride) []HistoricalPrice {
+	overridden := make([]HistoricalPrice, len(prices))
+	for i, price := range prices {
+		if !price.Date.Before(override.EffectiveDate) {
+			price.Price = override.Price
+			price.AdjustedPrice = 0
+		}
+		overridden[i] = price
+	}
+	return overridden
+}
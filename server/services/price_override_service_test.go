@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPriceOverrideHoldings(t *testing.T) {
+	provider := NewFixtureProvider().SetQuote("FUND", "Illiquid Fund", 10, "USD")
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	service := NewPriceOverrideService(portfolioService)
+	userID := primitive.NewObjectID()
+	date := time.Now().AddDate(0, 0, -10)
+
+	for _, symbol := range []string{"FUND", "PRIVATE"} {
+		if err := portfolioService.AddTransaction(userID, &models.Transaction{
+			Symbol: symbol, Action: "buy", Shares: 100, Price: 9, Currency: "USD", Date: date,
+		}); err != nil {
+			t.Fatalf("Failed to add %s: %v", symbol, err)
+		}
+	}
+
+	if _, err := service.SetOverride(userID, "CASH_USD", models.PriceOverrideRequest{Price: 2}); !errors.Is(err, ErrInvalidPriceOverride) {
+		t.Errorf("Expected ErrInvalidPriceOverride for cash, got %v", err)
+	}
+	future := models.PriceOverrideRequest{Price: 12, EffectiveDate: time.Now().AddDate(0, 0, 2)}
+	if _, err := service.SetOverride(userID, "FUND", future); !errors.Is(err, ErrInvalidPriceOverride) {
+		t.Errorf("Expected ErrInvalidPriceOverride for a future date, got %v", err)
+	}
+
+	override, err := service.SetOverride(userID, "fund", models.PriceOverrideRequest{Price: 11, EffectiveDate: date, Note: "NAV statement"})
+	if err != nil {
+		t.Fatalf("Failed to set override: %v", err)
+	}
+	// Setting it again replaces it
+	if override, err = service.SetOverride(userID, "FUND", models.PriceOverrideRequest{Price: 12, EffectiveDate: date}); err != nil {
+		t.Fatalf("Failed to replace override: %v", err)
+	}
+	if override.Symbol != "FUND" || override.Currency != "USD" || !override.EffectiveDate.Equal(accrualDay(date)) {
+		t.Errorf("Unexpected override %+v", override)
+	}
+	// Symbols the providers don't quote can still be valued
+	if _, err := service.SetOverride(userID, "PRIVATE", models.PriceOverrideRequest{Price: 20}); err != nil {
+		t.Fatalf("Failed to set override: %v", err)
+	}
+	if overrides, _ := service.ListOverrides(userID); len(overrides) != 2 {
+		t.Fatalf("Expected 2 overrides, got %d", len(overrides))
+	}
+
+	holdings, err := portfolioService.GetUserHoldings(userID, "USD")
+	if err != nil {
+		t.Fatalf("Failed to get holdings: %v", err)
+	}
+	if len(holdings) != 2 {
+		t.Fatalf("Expected 2 holdings, got %d", len(holdings))
+	}
+	for _, holding := range holdings {
+		want := map[string]float64{"FUND": 1200, "PRIVATE": 2000}[holding.Symbol]
+		if math.Abs(holding.CurrentValue-want) > 1e-9 || holding.PriceOverride == nil || holding.Source != PriceSourceManual {
+			t.Errorf("Expected %s valued at its manual price, got %+v", holding.Symbol, holding)
+		}
+	}
+
+	if err := service.DeleteOverride(userID, "FUND"); err != nil {
+		t.Fatalf("Failed to delete override: %v", err)
+	}
+	if err := service.DeleteOverride(userID, "FUND"); !errors.Is(err, ErrPriceOverrideNotFound) {
+		t.Errorf("Expected ErrPriceOverrideNotFound, got %v", err)
+	}
+	holdings, _ = portfolioService.GetUserHoldings(userID, "USD")
+	for _, holding := range holdings {
+		if holding.Symbol == "FUND" && (holding.CurrentPrice != 10 || holding.PriceOverride != nil) {
+			t.Errorf("Expected the provider's price after deleting the override, got %+v", holding)
+		}
+	}
+}
+
+func TestOverrideHistory(t *testing.T) {
+	end := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	prices := []HistoricalPrice{
+		{Date: end.AddDate(0, 0, -2), Price: 10, AdjustedPrice: 9.5},
+		{Date: end.AddDate(0, 0, -1), Price: 11},
+		{Date: end, Price: 12},
+	}
+	overridden := overrideHistory(prices, models.PriceOverride{Price: 15, EffectiveDate: end.AddDate(0, 0, -1)})
+	if overridden[0].Price != 10 || overridden[0].AdjustedPrice != 9.5 || overridden[1].Price != 15 || overridden[2].Price != 15 {
+		t.Errorf("Expected closes from the effective date on replaced, got %+v", overridden)
+	}
+	if prices[2].Price != 12 {
+		t.Errorf("Expected the input history to be left alone")
+	}
+}
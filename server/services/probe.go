@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StockProviders are the market data providers StockAPIService.Probe can check
+var StockProviders = []string{PriceSourceYahoo, PriceSourceEastmoney}
+
+// ProbeCheck is the outcome of one request made directly to a data provider,
+// bypassing caches and fallbacks
+type ProbeCheck struct {
+	Provider  string             `json:"provider"`
+	Check     string             `json:"check"`  // "quote", "history" or "rates"
+	Target    string             `json:"target"` // Symbol, or base currency for rates
+	OK        bool               `json:"ok"`
+	LatencyMs int64              `json:"latencyMs"`
+	Error     string             `json:"error,omitempty"`
+	Quote     *StockInfo         `json:"quote,omitempty"`
+	History   *ProbeHistory      `json:"history,omitempty"`
+	Rates     map[string]float64 `json:"rates,omitempty"`
+}
+
+// ProbeHistory summarizes a fetched price history
+type ProbeHistory struct {
+	Points    int       `json:"points"`
+	First     time.Time `json:"first"`
+	Last      time.Time `json:"last"`
+	LastClose float64   `json:"lastClose"`
+}
+
+// Probe fetches a quote and a month of history for symbol from one provider,
+// without reading or filling the caches, so operators can tell which
+// provider is failing and how
+func (s *StockAPIService) Probe(ctx context.Context, provider, symbol string) ([]ProbeCheck, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return nil, ErrInvalidSymbol
+	}
+	end := time.Now()
+	start := end.AddDate(0, -1, 0)
+
+	var fetchQuote func() (*StockInfo, error)
+	var fetchHistory func() ([]HistoricalPrice, error)
+	switch provider {
+	case PriceSourceYahoo:
+		fetchQuote = func() (*StockInfo, error) {
+			response, err := s.fetchFromYahooChart(ctx, symbol, end.AddDate(0, 0, -1).Unix(), end.Unix())
+			if err != nil {
+				return nil, err
+			}
+			return s.extractStockInfo(response)
+		}
+		fetchHistory = func() ([]HistoricalPrice, error) {
+			return s.fetchYahooHistory(ctx, symbol, start, end)
+		}
+	case PriceSourceEastmoney:
+		fetchQuote = func() (*StockInfo, error) {
+			return s.fetchQuoteFromEastmoney(ctx, symbol)
+		}
+		fetchHistory = func() ([]HistoricalPrice, error) {
+			return s.fetchHistoryFromEastmoney(ctx, symbol, start)
+		}
+	default:
+		return nil, fmt.Errorf("unknown provider %q: must be one of %s", provider, strings.Join(StockProviders, ", "))
+	}
+
+	quote := ProbeCheck{Provider: provider, Check: "quote", Target: symbol}
+	began := time.Now()
+	info, err := fetchQuote()
+	quote.LatencyMs = time.Since(began).Milliseconds()
+	if err != nil {
+		quote.Error = err.Error()
+	} else {
+		quote.OK, quote.Quote = true, info
+	}
+
+	history := ProbeCheck{Provider: provider, Check: "history", Target: symbol}
+	began = time.Now()
+	prices, err := fetchHistory()
+	history.LatencyMs = time.Since(began).Milliseconds()
+	switch {
+	case err != nil:
+		history.Error = err.Error()
+	case len(prices) == 0:
+		history.Error = "no prices returned"
+	default:
+		history.OK = true
+		history.History = &ProbeHistory{
+			Points:    len(prices),
+			First:     prices[0].Date,
+			Last:      prices[len(prices)-1].Date,
+			LastClose: prices[len(prices)-1].Price,
+		}
+	}
+
+	return []ProbeCheck{quote, history}, nil
+}
+
+// ProbeFXProvider fetches the rate table for base from an exchange rate
+// provider. Only the rates for the display currencies are kept in the result.
+func ProbeFXProvider(ctx context.Context, provider FXProvider, base string) ProbeCheck {
+	check := ProbeCheck{Provider: provider.Name(), Check: "rates", Target: base}
+	began := time.Now()
+	rates, err := provider.FetchRates(ctx, base)
+	check.LatencyMs = time.Since(began).Milliseconds()
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	check.Rates = make(map[string]float64)
+	for _, currency := range []string{"USD", "RMB", "HKD", "EUR", "GBP", "JPY"} {
+		if rate, ok := rates[currency]; ok && currency != base {
+			check.Rates[currency] = rate
+		}
+	}
+	if len(rates) == 0 {
+		check.Error = "no rates returned"
+		return check
+	}
+	check.OK = true
+	return check
+}
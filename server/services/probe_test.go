@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestProbeRejectsUnknownProvider(t *testing.T) {
+	service := NewStockAPIService(StockAPIConfig{})
+	if _, err := service.Probe(context.Background(), "bloomberg", "AAPL"); err == nil {
+		t.Errorf("Expected an unknown provider to be rejected")
+	}
+	if _, err := service.Probe(context.Background(), "yahoo", " "); err != ErrInvalidSymbol {
+		t.Errorf("Expected ErrInvalidSymbol for a blank symbol, got %v", err)
+	}
+}
+
+func TestProbeFXProvider(t *testing.T) {
+	provider := StaticFXProvider{"USD": {"RMB": 7.2, "HKD": 7.8, "CHF": 0.9}}
+
+	check := ProbeFXProvider(context.Background(), provider, "USD")
+	if !check.OK || check.Provider != "fallback rates" || check.Check != "rates" {
+		t.Fatalf("Unexpected check %+v", check)
+	}
+	// Only display currencies are reported
+	if len(check.Rates) != 2 || check.Rates["RMB"] != 7.2 || check.Rates["HKD"] != 7.8 {
+		t.Errorf("Unexpected rates %v", check.Rates)
+	}
+
+	failed := ProbeFXProvider(context.Background(), provider, "EUR")
+	if failed.OK || failed.Error == "" {
+		t.Errorf("Expected a failed check for a missing base, got %+v", failed)
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrAvatarTooLarge    = errors.New("avatar is too large")
+	ErrUnsupportedAvatar = errors.New("avatar must be a PNG, JPEG, GIF or WebP image")
+	ErrAvatarNotFound    = errors.New("avatar not found")
+)
+
+// MaxAvatarSize is the largest avatar image accepted, in bytes
+const MaxAvatarSize = 512 << 10
+
+// avatarContentTypes lists the image types accepted as avatars
+var avatarContentTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Profile is the user's account as shown in the client header
+type Profile struct {
+	ID           string    `json:"id"`
+	Email        string    `json:"email"`
+	DisplayName  string    `json:"displayName"`
+	BaseCurrency string    `json:"baseCurrency"`
+	Country      string    `json:"country"`
+	HasAvatar    bool      `json:"hasAvatar"`
+	AvatarURL    string    `json:"avatarUrl,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+
+	// AvatarVersion changes whenever a new avatar is uploaded, so avatar
+	// URLs can be cached
+	AvatarVersion string `json:"-"`
+}
+
+// ProfileService manages users' profile fields and avatars
+type ProfileService struct {
+	repos repository.Repositories
+}
+
+// NewProfileService creates a new ProfileService instance storing profiles in
+// MongoDB
+func NewProfileService() *ProfileService {
+	return NewProfileServiceWithRepos(repository.NewMongo())
+}
+
+// NewProfileServiceWithRepos creates a ProfileService over the given repositories
+func NewProfileServiceWithRepos(repos repository.Repositories) *ProfileService {
+	return &ProfileService{repos: repos}
+}
+
+// newProfile builds the profile of user. Users who never chose a base
+// currency report in USD, like the rest of the API.
+func newProfile(user *models.User) *Profile {
+	profile := &Profile{
+		ID:           user.ID.Hex(),
+		Email:        user.Email,
+		DisplayName:  user.DisplayName,
+		BaseCurrency: user.BaseCurrency,
+		Country:      user.Country,
+		HasAvatar:    user.AvatarID != nil,
+		CreatedAt:    user.CreatedAt,
+	}
+	if profile.BaseCurrency == "" {
+		profile.BaseCurrency = "USD"
+	}
+	if user.AvatarID != nil {
+		profile.AvatarVersion = user.AvatarID.Hex()
+	}
+	return profile
+}
+
+// GetProfile returns the user's profile
+func (s *ProfileService) GetProfile(userID primitive.ObjectID) (*Profile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := s.repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	return newProfile(user), nil
+}
+
+// UpdateProfile replaces the user's editable profile fields
+func (s *ProfileService) UpdateProfile(userID primitive.ObjectID, profile models.UserProfile) (*Profile, error) {
+	profile.DisplayName = strings.TrimSpace(profile.DisplayName)
+	profile.Country = strings.ToUpper(profile.Country)
+	profile.BaseCurrency = currencycode.Normalize(profile.BaseCurrency)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.repos.Users.UpdateProfile(ctx, userID, profile, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	user, err := s.repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	return newProfile(user), nil
+}
+
+// SetAvatar stores data as the user's avatar, replacing any previous one. The
+// image type is sniffed from the data rather than trusted from the client.
+func (s *ProfileService) SetAvatar(userID primitive.ObjectID, data []byte) (*Profile, error) {
+	if len(data) > MaxAvatarSize {
+		return nil, ErrAvatarTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if !avatarContentTypes[contentType] {
+		return nil, ErrUnsupportedAvatar
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := s.repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	avatarID, err := s.repos.Avatars.Put(ctx, userID, contentType, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store avatar: %w", err)
+	}
+	if err := s.repos.Users.SetAvatar(ctx, userID, &avatarID, time.Now()); err != nil {
+		s.deleteAvatar(ctx, userID, avatarID)
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+	if user.AvatarID != nil {
+		s.deleteAvatar(ctx, userID, *user.AvatarID)
+	}
+
+	user.AvatarID = &avatarID
+	return newProfile(user), nil
+}
+
+// GetAvatar returns the user's avatar image and its content type
+func (s *ProfileService) GetAvatar(userID primitive.ObjectID) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := s.repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if user.AvatarID == nil {
+		return nil, "", ErrAvatarNotFound
+	}
+
+	data, contentType, err := s.repos.Avatars.Get(ctx, userID, *user.AvatarID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, "", ErrAvatarNotFound
+		}
+		return nil, "", fmt.Errorf("failed to fetch avatar: %w", err)
+	}
+	return data, contentType, nil
+}
+
+// DeleteAvatar removes the user's avatar
+func (s *ProfileService) DeleteAvatar(userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := s.repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if user.AvatarID == nil {
+		return ErrAvatarNotFound
+	}
+
+	if err := s.repos.Users.SetAvatar(ctx, userID, nil, time.Now()); err != nil {
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+	s.deleteAvatar(ctx, userID, *user.AvatarID)
+	return nil
+}
+
+// deleteAvatar removes a stored avatar no longer referenced by its user. A
+// failure only leaves an orphaned file behind, so it is logged rather than
+// returned.
+func (s *ProfileService) deleteAvatar(ctx context.Context, userID, avatarID primitive.ObjectID) {
+	if err := s.repos.Avatars.Delete(ctx, userID, avatarID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		fmt.Printf("Warning: Failed to delete avatar %s: %v\n", avatarID.Hex(), err)
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pngHeader is enough of a PNG file for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newProfileTestUser(t *testing.T, repos repository.Repositories) primitive.ObjectID {
+	t.Helper()
+	user := &models.User{ID: primitive.NewObjectID(), Email: "profile@example.com", CreatedAt: time.Now()}
+	if err := repos.Users.Insert(context.Background(), user); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	return user.ID
+}
+
+func TestUpdateProfile(t *testing.T) {
+	repos := repository.NewMemory()
+	service := NewProfileServiceWithRepos(repos)
+	userID := newProfileTestUser(t, repos)
+
+	profile, err := service.GetProfile(userID)
+	if err != nil {
+		t.Fatalf("Failed to get profile: %v", err)
+	}
+	if profile.BaseCurrency != "USD" || profile.HasAvatar {
+		t.Errorf("Expected a new profile in USD without avatar, got %+v", profile)
+	}
+
+	profile, err = service.UpdateProfile(userID, models.UserProfile{DisplayName: "  Ada  ", BaseCurrency: "RMB", Country: "cn"})
+	if err != nil {
+		t.Fatalf("Failed to update profile: %v", err)
+	}
+	if profile.DisplayName != "Ada" || profile.BaseCurrency != "RMB" || profile.Country != "CN" {
+		t.Errorf("Expected normalized profile fields, got %+v", profile)
+	}
+	if profile.Email != "profile@example.com" {
+		t.Errorf("Expected email to be kept, got %q", profile.Email)
+	}
+}
+
+func TestAvatarUploadReplacesPrevious(t *testing.T) {
+	repos := repository.NewMemory()
+	service := NewProfileServiceWithRepos(repos)
+	userID := newProfileTestUser(t, repos)
+
+	if _, _, err := service.GetAvatar(userID); !errors.Is(err, ErrAvatarNotFound) {
+		t.Errorf("Expected no avatar yet, got %v", err)
+	}
+
+	first, err := service.SetAvatar(userID, append(pngHeader, 1))
+	if err != nil {
+		t.Fatalf("Failed to set avatar: %v", err)
+	}
+	second, err := service.SetAvatar(userID, append(pngHeader, 2))
+	if err != nil {
+		t.Fatalf("Failed to replace avatar: %v", err)
+	}
+	if !second.HasAvatar || second.AvatarVersion == first.AvatarVersion {
+		t.Errorf("Expected a new avatar version, got %q after %q", second.AvatarVersion, first.AvatarVersion)
+	}
+
+	data, contentType, err := service.GetAvatar(userID)
+	if err != nil {
+		t.Fatalf("Failed to get avatar: %v", err)
+	}
+	if contentType != "image/png" || !bytes.Equal(data, append(pngHeader, 2)) {
+		t.Errorf("Expected the latest PNG avatar, got %s %v", contentType, data)
+	}
+
+	firstID, _ := primitive.ObjectIDFromHex(first.AvatarVersion)
+	if _, _, err := repos.Avatars.Get(context.Background(), userID, firstID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected the replaced avatar to be deleted, got %v", err)
+	}
+
+	if err := service.DeleteAvatar(userID); err != nil {
+		t.Fatalf("Failed to delete avatar: %v", err)
+	}
+	if _, _, err := service.GetAvatar(userID); !errors.Is(err, ErrAvatarNotFound) {
+		t.Errorf("Expected avatar to be gone, got %v", err)
+	}
+}
+
+func TestAvatarRejectsInvalidImages(t *testing.T) {
+	repos := repository.NewMemory()
+	service := NewProfileServiceWithRepos(repos)
+	userID := newProfileTestUser(t, repos)
+
+	if _, err := service.SetAvatar(userID, []byte("<svg></svg>")); !errors.Is(err, ErrUnsupportedAvatar) {
+		t.Errorf("Expected non-image to be rejected, got %v", err)
+	}
+	large := append(append([]byte(nil), pngHeader...), make([]byte, MaxAvatarSize)...)
+	if _, err := service.SetAvatar(userID, large); !errors.Is(err, ErrAvatarTooLarge) {
+		t.Errorf("Expected oversized image to be rejected, got %v", err)
+	}
+}
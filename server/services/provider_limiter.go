@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type providerCallerKey struct{}
+
+// WithProviderCaller tags ctx with the caller, usually a user ID, that
+// outbound provider requests made with it are scheduled for
+func WithProviderCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, providerCallerKey{}, caller)
+}
+
+// providerCaller returns the caller ctx was tagged with. Untagged requests,
+// such as those of scheduled jobs, share one lane.
+func providerCaller(ctx context.Context) string {
+	caller, _ := ctx.Value(providerCallerKey{}).(string)
+	return caller
+}
+
+// providerLimiter paces the requests sent to one provider with a token
+// bucket, so bursts of valuations stay under the provider's throttle.
+// Requests that find the bucket empty queue rather than fail, and the queue
+// is served round-robin across callers, so one user valuing a large
+// portfolio cannot starve everyone else.
+type providerLimiter struct {
+	rate  float64 // Tokens added per second
+	burst float64
+
+	mutex     sync.Mutex
+	tokens    float64
+	refilled  time.Time
+	queues    map[string][]chan struct{} // Waiting requests by caller
+	callers   []string                   // Callers with waiting requests, in serving order
+	scheduled bool                       // Whether a dispatch is pending
+}
+
+// newProviderLimiter allows rate requests per second on average and up to
+// burst at once
+func newProviderLimiter(rate float64, burst int) *providerLimiter {
+	return &providerLimiter{
+		rate:     rate,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		refilled: time.Now(),
+		queues:   make(map[string][]chan struct{}),
+	}
+}
+
+// Wait blocks until the request may be sent or ctx is done
+func (l *providerLimiter) Wait(ctx context.Context) error {
+	l.mutex.Lock()
+	l.refill(time.Now())
+	if len(l.callers) == 0 && l.tokens >= 1 {
+		l.tokens--
+		l.mutex.Unlock()
+		return nil
+	}
+
+	caller := providerCaller(ctx)
+	ready := make(chan struct{})
+	if len(l.queues[caller]) == 0 {
+		l.callers = append(l.callers, caller)
+	}
+	l.queues[caller] = append(l.queues[caller], ready)
+	l.schedule()
+	l.mutex.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		select {
+		case <-ready:
+			// Granted while giving up: hand the token back
+			l.tokens = math.Min(l.tokens+1, l.burst)
+		default:
+			l.remove(caller, ready)
+		}
+		return ctx.Err()
+	}
+}
+
+// refill adds the tokens earned since the last refill
+func (l *providerLimiter) refill(now time.Time) {
+	l.tokens = math.Min(l.tokens+now.Sub(l.refilled).Seconds()*l.rate, l.burst)
+	l.refilled = now
+}
+
+// schedule arranges a dispatch for when the next token is due, unless one
+// is already pending
+func (l *providerLimiter) schedule() {
+	if l.scheduled || len(l.callers) == 0 {
+		return
+	}
+	l.scheduled = true
+	wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	time.AfterFunc(max(wait, 0), l.dispatch)
+}
+
+// dispatch releases one waiting request per available token, taking turns
+// between callers
+func (l *providerLimiter) dispatch() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.scheduled = false
+	l.refill(time.Now())
+	for l.tokens >= 1 && len(l.callers) > 0 {
+		caller := l.callers[0]
+		queue := l.queues[caller]
+		close(queue[0])
+		l.tokens--
+
+		l.callers = l.callers[1:]
+		if len(queue) > 1 {
+			l.queues[caller] = queue[1:]
+			l.callers = append(l.callers, caller)
+		} else {
+			delete(l.queues, caller)
+		}
+	}
+	l.schedule()
+}
+
+// remove drops a waiting request whose context ended
+func (l *providerLimiter) remove(caller string, ready chan struct{}) {
+	queue := l.queues[caller]
+	for i, waiting := range queue {
+		if waiting == ready {
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) > 0 {
+		l.queues[caller] = queue
+		return
+	}
+	delete(l.queues, caller)
+	for i, waiting := range l.callers {
+		if waiting == caller {
+			l.callers = append(l.callers[:i], l.callers[i+1:]...)
+			break
+		}
+	}
+}
+
+// limitedTransport waits for the limiter before sending each request
+type limitedTransport struct {
+	limiter *providerLimiter
+	next    http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// rateLimited wraps transport so requests through it are paced at rate per
+// second, allowing bursts of two seconds' worth
+func rateLimited(transport http.RoundTripper, rate float64) http.RoundTripper {
+	burst := max(int(math.Ceil(rate*2)), 1)
+	return &limitedTransport{limiter: newProviderLimiter(rate, burst), next: transport}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// queued counts the requests waiting on the limiter
+func (l *providerLimiter) queued() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	count := 0
+	for _, queue := range l.queues {
+		count += len(queue)
+	}
+	return count
+}
+
+func TestProviderLimiterPacesRequests(t *testing.T) {
+	limiter := newProviderLimiter(20, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+	}
+	// The burst of 2 passes at once, the next two take 50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected about 100ms for 4 requests at 20/s with a burst of 2, took %s", elapsed)
+	}
+}
+
+func TestProviderLimiterTakesTurnsBetweenCallers(t *testing.T) {
+	limiter := newProviderLimiter(20, 1)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	var mutex sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	enqueue := func(caller string) {
+		wg.Add(1)
+		waiting := limiter.queued()
+		go func() {
+			defer wg.Done()
+			if err := limiter.Wait(WithProviderCaller(context.Background(), caller)); err != nil {
+				t.Errorf("Wait failed: %v", err)
+				return
+			}
+			mutex.Lock()
+			order = append(order, caller)
+			mutex.Unlock()
+		}()
+		for limiter.queued() == waiting {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// A large portfolio queues first, then a second user asks for one quote
+	enqueue("alice")
+	enqueue("alice")
+	enqueue("alice")
+	enqueue("bob")
+	wg.Wait()
+
+	want := []string{"alice", "bob", "alice", "alice"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("Expected callers served in turn %v, got %v", want, order)
+		}
+	}
+}
+
+func TestProviderLimiterCancelledWaitLeavesQueue(t *testing.T) {
+	limiter := newProviderLimiter(0.5, 1)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the wait to end with its context, got %v", err)
+	}
+	if waiting := limiter.queued(); waiting != 0 {
+		t.Errorf("Expected the cancelled request to leave the queue, %d still waiting", waiting)
+	}
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"stock-portfolio-tracker/currencycode"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StockDataProvider supplies quotes and price history. StockAPIService is the
+// live implementation; FixtureProvider serves fixed data for tests.
+type StockDataProvider interface {
+	GetStockInfo(symbol string) (*StockInfo, error)
+	GetStockInfoContext(ctx context.Context, symbol string) (*StockInfo, error)
+	GetHistoricalData(symbol string, period string) ([]HistoricalPrice, error)
+	GetHistoricalDataContext(ctx context.Context, symbol string, period string) ([]HistoricalPrice, error)
+	// GetDividendsContext returns the symbol's recent and announced dividends,
+	// oldest first
+	GetDividendsContext(ctx context.Context, symbol string) ([]DividendEvent, error)
+	SymbolCurrency(symbol string) string
+	IsCashSymbol(symbol string) bool
+	// CacheVersion changes whenever the provider's prices may have changed
+	CacheVersion() string
+}
+
+// CurrencyProvider supplies exchange rates. CurrencyService is the live
+// implementation; FixtureProvider serves fixed rates for tests.
+type CurrencyProvider interface {
+	GetExchangeRate(from, to string) (float64, error)
+	ConvertAmount(amount float64, from, to string) (float64, error)
+	// GetRateFreshness reports how current the rates from each of the from
+	// currencies to to are, nil when there is nothing to report
+	GetRateFreshness(to string, from ...string) *RateFreshness
+}
+
+var (
+	_ StockDataProvider = (*StockAPIService)(nil)
+	_ CurrencyProvider  = (*CurrencyService)(nil)
+	_ StockDataProvider = (*FixtureProvider)(nil)
+	_ CurrencyProvider  = (*FixtureProvider)(nil)
+)
+
+// FixtureProvider is a deterministic StockDataProvider and CurrencyProvider
+// backed by quotes, price histories and rates registered up front. Price
+// history periods are measured back from the latest registered price rather
+// than the wall clock, so results only depend on the fixture, except for the
+// flat price history of unregistered cash symbols.
+type FixtureProvider struct {
+	mu        sync.RWMutex
+	quotes    map[string]StockInfo
+	history   map[string][]HistoricalPrice
+	dividends map[string][]DividendEvent
+	rates     map[string]float64
+	version   int
+}
+
+// NewFixtureProvider creates an empty FixtureProvider
+func NewFixtureProvider() *FixtureProvider {
+	return &FixtureProvider{
+		quotes:    make(map[string]StockInfo),
+		history:   make(map[string][]HistoricalPrice),
+		dividends: make(map[string][]DividendEvent),
+		rates:     make(map[string]float64),
+	}
+}
+
+// SetQuote registers the current quote for a symbol
+func (p *FixtureProvider) SetQuote(symbol, name string, price float64, currency string) *FixtureProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	symbol = strings.ToUpper(symbol)
+	p.quotes[symbol] = StockInfo{
+		Symbol:       symbol,
+		Name:         name,
+		CurrentPrice: price,
+		Currency:     currencycode.Normalize(currency),
+		PriceSource:  PriceSource{Source: PriceSourceFixture},
+	}
+	p.version++
+	return p
+}
+
+// SetPreviousClose sets the official previous close of a quote registered
+// with SetQuote
+func (p *FixtureProvider) SetPreviousClose(symbol string, price float64) *FixtureProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	symbol = strings.ToUpper(symbol)
+	if info, ok := p.quotes[symbol]; ok {
+		info.PreviousClose = price
+		p.quotes[symbol] = info
+		p.version++
+	}
+	return p
+}
+
+// SetHistory registers a symbol's daily closes, one per day ending on end
+func (p *FixtureProvider) SetHistory(symbol string, end time.Time, closes ...float64) *FixtureProvider {
+	prices := make([]HistoricalPrice, len(closes))
+	for i, price := range closes {
+		prices[i] = HistoricalPrice{Date: end.AddDate(0, 0, i-len(closes)+1), Price: price}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.history[strings.ToUpper(symbol)] = prices
+	p.version++
+	return p
+}
+
+// SetAdjustedCloses registers dividend-adjusted closes for the history set
+// with SetHistory, one per registered close
+func (p *FixtureProvider) SetAdjustedCloses(symbol string, closes ...float64) *FixtureProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	prices := p.history[strings.ToUpper(symbol)]
+	for i := range prices {
+		if i < len(closes) {
+			prices[i].AdjustedPrice = closes[i]
+		}
+	}
+	p.version++
+	return p
+}
+
+// SetDividends registers a symbol's dividend schedule, past and announced.
+// Symbol and Currency default to the symbol and its quote currency.
+func (p *FixtureProvider) SetDividends(symbol string, dividends ...DividendEvent) *FixtureProvider {
+	symbol = strings.ToUpper(symbol)
+	currency := p.SymbolCurrency(symbol)
+	events := make([]DividendEvent, len(dividends))
+	for i, dividend := range dividends {
+		if dividend.Symbol == "" {
+			dividend.Symbol = symbol
+		}
+		if dividend.Currency == "" {
+			dividend.Currency = currency
+		}
+		events[i] = dividend
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ExDate.Before(events[j].ExDate) })
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dividends[symbol] = events
+	p.version++
+	return p
+}
+
+// SetRate registers the exchange rate from one currency to another and its
+// inverse
+func (p *FixtureProvider) SetRate(from, to string, rate float64) *FixtureProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	from, to = currencycode.Normalize(from), currencycode.Normalize(to)
+	p.rates[from+"/"+to] = rate
+	p.rates[to+"/"+from] = 1 / rate
+	p.version++
+	return p
+}
+
+func (p *FixtureProvider) GetStockInfo(symbol string) (*StockInfo, error) {
+	return p.GetStockInfoContext(context.Background(), symbol)
+}
+
+// GetStockInfoContext returns the registered quote. Cash symbols are always
+// priced at 1 in their own currency.
+func (p *FixtureProvider) GetStockInfoContext(ctx context.Context, symbol string) (*StockInfo, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if p.IsCashSymbol(symbol) {
+		return &StockInfo{Symbol: symbol, Name: symbol, CurrentPrice: 1, Currency: p.SymbolCurrency(symbol), PriceSource: PriceSource{Source: PriceSourceFixed}}, nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	info, ok := p.quotes[symbol]
+	if !ok {
+		return nil, ErrStockNotFound
+	}
+	return &info, nil
+}
+
+func (p *FixtureProvider) GetHistoricalData(symbol string, period string) ([]HistoricalPrice, error) {
+	return p.GetHistoricalDataContext(context.Background(), symbol, period)
+}
+
+// GetHistoricalDataContext returns the registered closes within the period.
+// Cash symbols without registered history are flat at 1 up to today, like
+// StockAPIService.
+func (p *FixtureProvider) GetHistoricalDataContext(ctx context.Context, symbol string, period string) ([]HistoricalPrice, error) {
+	validPeriods := map[string]bool{"1M": true, "3M": true, "6M": true, "1Y": true, "ALL": true}
+	if !validPeriods[period] {
+		return nil, ErrInvalidPeriod
+	}
+
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	prices, ok := p.history[symbol]
+	if !ok && p.IsCashSymbol(symbol) {
+		today := time.Now().Truncate(24 * time.Hour)
+		for date := PeriodStart(period, today); !date.After(today); date = date.AddDate(0, 0, 1) {
+			prices = append(prices, HistoricalPrice{Date: date, Price: 1})
+		}
+		return prices, nil
+	}
+	if len(prices) == 0 {
+		return nil, ErrStockNotFound
+	}
+
+	start := PeriodStart(period, prices[len(prices)-1].Date)
+	i := sort.Search(len(prices), func(i int) bool { return !prices[i].Date.Before(start) })
+	return append([]HistoricalPrice(nil), prices[i:]...), nil
+}
+
+// GetDividendsContext returns the registered dividends, none for symbols
+// without a schedule
+func (p *FixtureProvider) GetDividendsContext(ctx context.Context, symbol string) ([]DividendEvent, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]DividendEvent(nil), p.dividends[symbol]...), nil
+}
+
+// SymbolCurrency returns the currency of the symbol's quote, USD when none is
+// registered
+func (p *FixtureProvider) SymbolCurrency(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "CASH_RMB" {
+		return currencycode.RMB
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if info, ok := p.quotes[symbol]; ok && info.Currency != "" {
+		return info.Currency
+	}
+	return "USD"
+}
+
+func (p *FixtureProvider) IsCashSymbol(symbol string) bool {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	return symbol == "CASH_USD" || symbol == "CASH_RMB"
+}
+
+// CacheVersion changes whenever fixture data is registered
+func (p *FixtureProvider) CacheVersion() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return "fixture:" + strconv.Itoa(p.version)
+}
+
+// GetExchangeRate returns the registered rate, 1 between equal currencies
+func (p *FixtureProvider) GetExchangeRate(from, to string) (float64, error) {
+	if from == "" || to == "" {
+		return 0, ErrInvalidCurrencyCode
+	}
+	from, to = currencycode.Normalize(from), currencycode.Normalize(to)
+	if from == to {
+		return 1, nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	rate, ok := p.rates[from+"/"+to]
+	if !ok {
+		return 0, ErrCurrencyAPIError
+	}
+	return rate, nil
+}
+
+func (p *FixtureProvider) ConvertAmount(amount float64, from, to string) (float64, error) {
+	rate, err := p.GetExchangeRate(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// GetRateFreshness reports nothing, since registered rates never go stale
+func (p *FixtureProvider) GetRateFreshness(to string, from ...string) *RateFreshness {
+	return nil
+}
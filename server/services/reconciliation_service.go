@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Position discrepancy kinds
+const (
+	DiscrepancyMissingInTracker = "missing_in_tracker"
+	DiscrepancyMissingAtBroker  = "missing_at_broker"
+	DiscrepancyShareMismatch    = "share_mismatch"
+)
+
+// reconcileShareTolerance ignores rounding differences in fractional shares
+const reconcileShareTolerance = 1e-6
+
+// PositionDiscrepancy describes a symbol whose tracked shares differ from the broker
+type PositionDiscrepancy struct {
+	Symbol        string  `json:"symbol"`
+	Kind          string  `json:"kind"`
+	TrackedShares float64 `json:"trackedShares"`
+	BrokerShares  float64 `json:"brokerShares"`
+	Difference    float64 `json:"difference"` // Broker shares minus tracked shares
+}
+
+// SuggestedTransaction is an adjustment that brings a tracked position in line
+// with the broker. It can be submitted as-is to the transactions endpoint.
+type SuggestedTransaction struct {
+	Symbol   string    `json:"symbol"`
+	Action   string    `json:"action"`
+	Shares   float64   `json:"shares"`
+	Price    float64   `json:"price"`
+	Currency string    `json:"currency"`
+	Fees     float64   `json:"fees"`
+	Date     time.Time `json:"date"`
+	Note     string    `json:"note,omitempty"`
+}
+
+// ReconciliationResult compares tracked holdings with a broker snapshot
+type ReconciliationResult struct {
+	AsOf          time.Time              `json:"asOf"`
+	Matched       int                    `json:"matched"`
+	Discrepancies []PositionDiscrepancy  `json:"discrepancies"`
+	Suggested     []SuggestedTransaction `json:"suggested"`
+	InSync        bool                   `json:"inSync"`
+}
+
+// ReconciliationService compares tracked holdings with broker positions
+type ReconciliationService struct {
+	portfolioService *PortfolioService
+	stockService     *StockAPIService
+}
+
+// NewReconciliationService creates a new ReconciliationService instance
+func NewReconciliationService(portfolioService *PortfolioService, stockService *StockAPIService) *ReconciliationService {
+	return &ReconciliationService{
+		portfolioService: portfolioService,
+		stockService:     stockService,
+	}
+}
+
+// Reconcile compares the user's tracked positions with a broker snapshot and
+// suggests buy or sell adjustments for every discrepancy, quoting prices
+// under ctx
+func (s *ReconciliationService) Reconcile(ctx context.Context, userID primitive.ObjectID, brokerPositions []models.BrokerPosition, asOf time.Time) (*ReconciliationResult, error) {
+	if asOf.IsZero() || asOf.After(time.Now()) {
+		asOf = time.Now()
+	}
+
+	positions, err := s.portfolioService.getPositions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	broker, err := normalizeBrokerPositions(brokerPositions)
+	if err != nil {
+		return nil, err
+	}
+
+	result := reconcilePositions(positions, broker)
+	result.AsOf = asOf
+
+	for _, discrepancy := range result.Discrepancies {
+		result.Suggested = append(result.Suggested, s.suggestAdjustment(ctx, discrepancy, positions, broker, asOf))
+	}
+
+	return result, nil
+}
+
+// normalizeBrokerPositions upper-cases symbols and rejects duplicates
+func normalizeBrokerPositions(brokerPositions []models.BrokerPosition) (map[string]models.BrokerPosition, error) {
+	broker := make(map[string]models.BrokerPosition, len(brokerPositions))
+	for _, position := range brokerPositions {
+		position.Symbol = strings.ToUpper(strings.TrimSpace(position.Symbol))
+		if position.Symbol == "" {
+			return nil, fmt.Errorf("%w: symbol is required", ErrInvalidTransaction)
+		}
+		if _, exists := broker[position.Symbol]; exists {
+			return nil, fmt.Errorf("%w: %s appears more than once", ErrInvalidTransaction, position.Symbol)
+		}
+		broker[position.Symbol] = position
+	}
+	return broker, nil
+}
+
+// reconcilePositions lists the symbols whose tracked and broker shares differ
+func reconcilePositions(positions []repository.Position, broker map[string]models.BrokerPosition) *ReconciliationResult {
+	result := &ReconciliationResult{
+		Discrepancies: []PositionDiscrepancy{},
+		Suggested:     []SuggestedTransaction{},
+	}
+
+	tracked := make(map[string]float64, len(positions))
+	for _, position := range positions {
+		tracked[position.Symbol] = position.Shares
+	}
+
+	symbols := make([]string, 0, len(tracked)+len(broker))
+	for symbol := range tracked {
+		symbols = append(symbols, symbol)
+	}
+	for symbol := range broker {
+		if _, exists := tracked[symbol]; !exists {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		trackedShares := tracked[symbol]
+		brokerShares := broker[symbol].Shares
+		difference := brokerShares - trackedShares
+		if math.Abs(difference) <= reconcileShareTolerance {
+			result.Matched++
+			continue
+		}
+
+		kind := DiscrepancyShareMismatch
+		if trackedShares <= reconcileShareTolerance {
+			kind = DiscrepancyMissingInTracker
+		} else if brokerShares <= reconcileShareTolerance {
+			kind = DiscrepancyMissingAtBroker
+		}
+
+		result.Discrepancies = append(result.Discrepancies, PositionDiscrepancy{
+			Symbol:        symbol,
+			Kind:          kind,
+			TrackedShares: trackedShares,
+			BrokerShares:  brokerShares,
+			Difference:    difference,
+		})
+	}
+
+	result.InSync = len(result.Discrepancies) == 0
+	return result
+}
+
+// suggestAdjustment builds the transaction that resolves a discrepancy. The
+// price is the broker's price when given, then the current quote, then the
+// tracked average cost.
+func (s *ReconciliationService) suggestAdjustment(ctx context.Context, discrepancy PositionDiscrepancy, positions []repository.Position, broker map[string]models.BrokerPosition, asOf time.Time) SuggestedTransaction {
+	suggestion := SuggestedTransaction{
+		Symbol:   discrepancy.Symbol,
+		Action:   "buy",
+		Shares:   math.Abs(discrepancy.Difference),
+		Currency: "USD",
+		Date:     asOf,
+	}
+	if discrepancy.Difference < 0 {
+		suggestion.Action = "sell"
+	}
+	if s.stockService.IsChinaStock(discrepancy.Symbol) {
+		suggestion.Currency = "RMB"
+	}
+
+	averageCost := 0.0
+	for _, position := range positions {
+		if position.Symbol == discrepancy.Symbol {
+			if position.Shares > 0 {
+				averageCost = position.Cost / position.Shares
+			}
+			if position.Currency != "" {
+				suggestion.Currency = position.Currency
+			}
+			break
+		}
+	}
+
+	switch {
+	case broker[discrepancy.Symbol].Price > 0:
+		suggestion.Price = broker[discrepancy.Symbol].Price
+	case s.stockService.IsCashSymbol(discrepancy.Symbol):
+		suggestion.Price = 1
+	default:
+		if info, err := s.stockService.GetStockInfoContext(ctx, discrepancy.Symbol); err == nil && info.CurrentPrice > 0 {
+			suggestion.Price = info.CurrentPrice
+		} else if averageCost > 0 {
+			suggestion.Price = averageCost
+			suggestion.Note = "no quote available; priced at the tracked average cost"
+		} else {
+			suggestion.Note = "no price available; set the price before submitting"
+		}
+	}
+
+	return suggestion
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+)
+
+func TestReconcilePositions(t *testing.T) {
+	positions := []repository.Position{
+		{Symbol: "AAPL", Shares: 10, Cost: 1500, Currency: "USD"},
+		{Symbol: "MSFT", Shares: 5, Cost: 1000, Currency: "USD"},
+		{Symbol: "VTI", Shares: 2.5, Cost: 500, Currency: "USD"},
+	}
+	broker, err := normalizeBrokerPositions([]models.BrokerPosition{
+		{Symbol: "aapl", Shares: 10},
+		{Symbol: "MSFT", Shares: 7},
+		{Symbol: "NVDA", Shares: 3},
+		{Symbol: "VTI", Shares: 0},
+	})
+	if err != nil {
+		t.Fatalf("Failed to normalize positions: %v", err)
+	}
+
+	result := reconcilePositions(positions, broker)
+
+	if result.Matched != 1 || result.InSync {
+		t.Errorf("Expected one matched symbol, got %d", result.Matched)
+	}
+	want := []PositionDiscrepancy{
+		{Symbol: "MSFT", Kind: DiscrepancyShareMismatch, TrackedShares: 5, BrokerShares: 7, Difference: 2},
+		{Symbol: "NVDA", Kind: DiscrepancyMissingInTracker, TrackedShares: 0, BrokerShares: 3, Difference: 3},
+		{Symbol: "VTI", Kind: DiscrepancyMissingAtBroker, TrackedShares: 2.5, BrokerShares: 0, Difference: -2.5},
+	}
+	if len(result.Discrepancies) != len(want) {
+		t.Fatalf("Expected %d discrepancies, got %+v", len(want), result.Discrepancies)
+	}
+	for i := range want {
+		if result.Discrepancies[i] != want[i] {
+			t.Errorf("Discrepancy %d: expected %+v, got %+v", i, want[i], result.Discrepancies[i])
+		}
+	}
+}
+
+func TestSuggestAdjustmentUsesBrokerPrice(t *testing.T) {
+	service := NewReconciliationService(nil, NewStockAPIService(StockAPIConfig{}))
+	positions := []repository.Position{{Symbol: "600519.SS", Shares: 200, Cost: 340000, Currency: "RMB"}}
+	broker := map[string]models.BrokerPosition{"600519.SS": {Symbol: "600519.SS", Shares: 100, Price: 1700}}
+	discrepancy := PositionDiscrepancy{Symbol: "600519.SS", TrackedShares: 200, BrokerShares: 100, Difference: -100}
+
+	suggestion := service.suggestAdjustment(context.Background(), discrepancy, positions, broker, tradeDate(2024, 6, 1))
+
+	if suggestion.Action != "sell" || suggestion.Shares != 100 || suggestion.Price != 1700 || suggestion.Currency != "RMB" {
+		t.Errorf("Unexpected suggestion %+v", suggestion)
+	}
+}
+
+func TestNormalizeBrokerPositionsRejectsDuplicates(t *testing.T) {
+	_, err := normalizeBrokerPositions([]models.BrokerPosition{{Symbol: "AAPL", Shares: 1}, {Symbol: " aapl", Shares: 2}})
+	if !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("Expected ErrInvalidTransaction, got %v", err)
+	}
+}
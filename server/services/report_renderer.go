@@ -0,0 +1,301 @@
+package services
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"stock-portfolio-tracker/currencycode"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// reportChartWidth and reportChartHeight are the dimensions of the HTML performance chart
+const (
+	reportChartWidth  = 640.0
+	reportChartHeight = 200.0
+)
+
+// RenderPortfolioReport writes the report in the requested format
+func RenderPortfolioReport(report *PortfolioReport, format string, w io.Writer) error {
+	switch format {
+	case ReportFormatHTML:
+		return renderReportHTML(report, w)
+	case ReportFormatPDF:
+		return renderReportPDF(report, w)
+	default:
+		return ErrInvalidReportFormat
+	}
+}
+
+// currencySymbol returns the display symbol for a report currency
+func currencySymbol(currency string) string {
+	switch currencycode.Normalize(currency) {
+	case currencycode.RMB, "JPY":
+		return "¥"
+	case "EUR":
+		return "€"
+	case "GBP":
+		return "£"
+	case "HKD":
+		return "HK$"
+	}
+	return "$"
+}
+
+// formatMoney formats an amount with a currency symbol and thousands separators
+func formatMoney(amount float64, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	whole := fmt.Sprintf("%.2f", amount)
+	intPart, fracPart := whole[:len(whole)-3], whole[len(whole)-3:]
+
+	var grouped strings.Builder
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	return sign + currencySymbol(currency) + grouped.String() + fracPart
+}
+
+// formatPercent formats a percentage with an explicit sign
+func formatPercent(value float64) string {
+	return fmt.Sprintf("%+.2f%%", value)
+}
+
+// chartPoints scales the performance series into chart coordinates
+func chartPoints(points []PerformanceDataPoint, width, height float64) [][2]float64 {
+	if len(points) == 0 {
+		return nil
+	}
+
+	minValue, maxValue := math.Inf(1), math.Inf(-1)
+	for _, point := range points {
+		minValue = math.Min(minValue, point.Value)
+		maxValue = math.Max(maxValue, point.Value)
+	}
+	valueRange := maxValue - minValue
+	if valueRange == 0 {
+		valueRange = 1
+	}
+
+	scaled := make([][2]float64, len(points))
+	for i, point := range points {
+		x := 0.0
+		if len(points) > 1 {
+			x = float64(i) / float64(len(points)-1) * width
+		}
+		y := height - (point.Value-minValue)/valueRange*height
+		scaled[i] = [2]float64{x, y}
+	}
+	return scaled
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"money": func(amount float64, currency string) string {
+		return formatMoney(amount, currency)
+	},
+	"percent": formatPercent,
+	"date": func(t interface{ Format(string) string }) string {
+		return t.Format("2006-01-02")
+	},
+	"chart": func(points []PerformanceDataPoint) string {
+		coords := make([]string, 0, len(points))
+		for _, point := range chartPoints(points, reportChartWidth, reportChartHeight) {
+			coords = append(coords, fmt.Sprintf("%.1f,%.1f", point[0], point[1]))
+		}
+		return strings.Join(coords, " ")
+	},
+	"gainClass": func(value float64) string {
+		if value < 0 {
+			return "loss"
+		}
+		return "gain"
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Portfolio Report ({{.Period}})</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2933; max-width: 720px; margin: 24px auto; }
+h1 { font-size: 22px; margin-bottom: 4px; }
+h2 { font-size: 16px; margin-top: 28px; border-bottom: 1px solid #e4e7eb; padding-bottom: 4px; }
+.muted { color: #7b8794; font-size: 12px; }
+table { width: 100%; border-collapse: collapse; font-size: 13px; }
+th, td { text-align: left; padding: 4px 6px; border-bottom: 1px solid #f0f2f4; }
+td.num, th.num { text-align: right; }
+.gain { color: #1a7f37; }
+.loss { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>Portfolio Report</h1>
+<div class="muted">{{date .PeriodStart}} to {{date .GeneratedAt}} ({{.Period}}) &middot; {{.Currency}} &middot; generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}</div>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Total value</th><td class="num">{{money .Summary.TotalValue .Currency}}</td></tr>
+<tr><th>Total gain</th><td class="num {{gainClass .Summary.TotalGain}}">{{money .Summary.TotalGain .Currency}} ({{percent .Summary.PercentageReturn}})</td></tr>
+<tr><th>Day change</th><td class="num {{gainClass .Summary.DayChange}}">{{money .Summary.DayChange .Currency}} ({{percent .Summary.DayChangePercent}})</td></tr>
+{{- with .Metrics}}
+<tr><th>Period return</th><td class="num {{gainClass .PeriodReturn.Absolute}}">{{money .PeriodReturn.Absolute $.Currency}} ({{percent .PeriodReturn.Percentage}})</td></tr>
+<tr><th>Max drawdown</th><td class="num loss">{{percent .MaxDrawdown.Percentage}}</td></tr>
+{{- end}}
+</table>
+
+{{- if .Performance}}
+<h2>Performance</h2>
+<svg width="640" height="200" viewBox="0 0 640 200" role="img" aria-label="Portfolio value over the period">
+<polyline fill="none" stroke="#2563eb" stroke-width="2" points="{{chart .Performance}}"/>
+</svg>
+{{- end}}
+
+<h2>Allocation</h2>
+<table>
+<tr><th>Symbol</th><th>Name</th><th class="num">Value</th><th class="num">Weight</th></tr>
+{{- range .Summary.Allocation}}
+<tr><td>{{.Symbol}}</td><td>{{.Name}}</td><td class="num">{{money .Value $.Currency}}</td><td class="num">{{printf "%.2f%%" .Percentage}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Top movers today</h2>
+<table>
+<tr><th>Symbol</th><th>Name</th><th class="num">Change</th><th class="num">%</th></tr>
+{{- range .TopGainers}}
+<tr><td>{{.Symbol}}</td><td>{{.Name}}</td><td class="num gain">{{money .Change $.Currency}}</td><td class="num gain">{{percent .ChangePercent}}</td></tr>
+{{- end}}
+{{- range .TopLosers}}
+<tr><td>{{.Symbol}}</td><td>{{.Name}}</td><td class="num loss">{{money .Change $.Currency}}</td><td class="num loss">{{percent .ChangePercent}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Transactions</h2>
+{{- if .Transactions}}
+<table>
+<tr><th>Date</th><th>Symbol</th><th>Action</th><th class="num">Shares</th><th class="num">Price</th><th class="num">Fees</th></tr>
+{{- range .Transactions}}
+<tr><td>{{date .Date}}</td><td>{{.Symbol}}</td><td>{{.Action}}</td><td class="num">{{printf "%.4g" .Shares}}</td><td class="num">{{money .Price .Currency}}</td><td class="num">{{money .Fees .Currency}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="muted">No transactions in this period.</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// renderReportHTML renders the report as a self-contained HTML document
+func renderReportHTML(report *PortfolioReport, w io.Writer) error {
+	if err := reportTemplate.Execute(w, report); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return nil
+}
+
+// renderReportPDF renders the report as an A4 PDF document
+func renderReportPDF(report *PortfolioReport, w io.Writer) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Portfolio Report", true)
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AddPage()
+
+	// Core fonts are cp1252, which covers the currency symbols used here
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	heading := func(text string) {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 8, text, "B", 1, "L", false, 0, "")
+		pdf.Ln(2)
+	}
+	row := func(widths []float64, aligns string, cells ...string) {
+		for i, cell := range cells {
+			pdf.CellFormat(widths[i], 6, tr(cell), "", 0, string(aligns[i]), false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 10, "Portfolio Report", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.CellFormat(0, 5, fmt.Sprintf("%s to %s (%s) - %s - generated %s",
+		report.PeriodStart.Format("2006-01-02"), report.GeneratedAt.Format("2006-01-02"),
+		report.Period, report.Currency, report.GeneratedAt.Format("2006-01-02 15:04 MST")), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+
+	heading("Summary")
+	pdf.SetFont("Helvetica", "", 10)
+	summaryWidths := []float64{60, 120}
+	summary := report.Summary
+	row(summaryWidths, "LR", "Total value", formatMoney(summary.TotalValue, report.Currency))
+	row(summaryWidths, "LR", "Total gain", fmt.Sprintf("%s (%s)", formatMoney(summary.TotalGain, report.Currency), formatPercent(summary.PercentageReturn)))
+	row(summaryWidths, "LR", "Day change", fmt.Sprintf("%s (%s)", formatMoney(summary.DayChange, report.Currency), formatPercent(summary.DayChangePercent)))
+	if report.Metrics != nil {
+		row(summaryWidths, "LR", "Period return", fmt.Sprintf("%s (%s)", formatMoney(report.Metrics.PeriodReturn.Absolute, report.Currency), formatPercent(report.Metrics.PeriodReturn.Percentage)))
+		row(summaryWidths, "LR", "Max drawdown", formatPercent(report.Metrics.MaxDrawdown.Percentage))
+	}
+
+	if len(report.Performance) > 1 {
+		heading("Performance")
+		left, top := pdf.GetXY()
+		width, height := 180.0, 55.0
+		pdf.SetDrawColor(37, 99, 235)
+		pdf.SetLineWidth(0.4)
+		points := chartPoints(report.Performance, width, height)
+		for i := 1; i < len(points); i++ {
+			pdf.Line(left+points[i-1][0], top+points[i-1][1], left+points[i][0], top+points[i][1])
+		}
+		pdf.SetDrawColor(0, 0, 0)
+		pdf.SetLineWidth(0.2)
+		pdf.SetY(top + height + 4)
+	}
+
+	heading("Allocation")
+	allocationWidths := []float64{35, 85, 35, 25}
+	pdf.SetFont("Helvetica", "B", 9)
+	row(allocationWidths, "LLRR", "Symbol", "Name", "Value", "Weight")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, item := range summary.Allocation {
+		row(allocationWidths, "LLRR", item.Symbol, item.Name, formatMoney(item.Value, report.Currency), fmt.Sprintf("%.2f%%", item.Percentage))
+	}
+
+	heading("Top movers today")
+	moverWidths := []float64{35, 85, 35, 25}
+	pdf.SetFont("Helvetica", "B", 9)
+	row(moverWidths, "LLRR", "Symbol", "Name", "Change", "%")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, mover := range append(append([]HoldingMover{}, report.TopGainers...), report.TopLosers...) {
+		row(moverWidths, "LLRR", mover.Symbol, mover.Name, formatMoney(mover.Change, report.Currency), formatPercent(mover.ChangePercent))
+	}
+
+	heading("Transactions")
+	pdf.SetFont("Helvetica", "", 9)
+	if len(report.Transactions) == 0 {
+		pdf.CellFormat(0, 6, "No transactions in this period.", "", 1, "L", false, 0, "")
+	} else {
+		transactionWidths := []float64{28, 35, 20, 30, 37, 30}
+		pdf.SetFont("Helvetica", "B", 9)
+		row(transactionWidths, "LLLRRR", "Date", "Symbol", "Action", "Shares", "Price", "Fees")
+		pdf.SetFont("Helvetica", "", 9)
+		for _, tx := range report.Transactions {
+			row(transactionWidths, "LLLRRR", tx.Date.Format("2006-01-02"), tx.Symbol, tx.Action,
+				fmt.Sprintf("%.4g", tx.Shares), formatMoney(tx.Price, tx.Currency), formatMoney(tx.Fees, tx.Currency))
+		}
+	}
+
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("failed to render PDF report: %w", err)
+	}
+	return nil
+}
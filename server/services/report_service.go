@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"stock-portfolio-tracker/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidReportFormat = errors.New("invalid report format: must be pdf or html")
+
+const (
+	ReportFormatPDF  = "pdf"
+	ReportFormatHTML = "html"
+
+	// reportChartPoints bounds the performance series included in a report
+	reportChartPoints = 120
+	// reportMoverCount is the number of gainers and losers listed in a report
+	reportMoverCount = 5
+)
+
+// PortfolioReport represents the data rendered into a portfolio report
+type PortfolioReport struct {
+	GeneratedAt  time.Time              `json:"generatedAt"`
+	Period       string                 `json:"period"`
+	PeriodStart  time.Time              `json:"periodStart"`
+	Currency     string                 `json:"currency"`
+	Summary      *DashboardMetrics      `json:"summary"`
+	Metrics      *PerformanceMetrics    `json:"metrics,omitempty"`
+	Performance  []PerformanceDataPoint `json:"performance"`
+	TopGainers   []HoldingMover         `json:"topGainers"`
+	TopLosers    []HoldingMover         `json:"topLosers"`
+	Transactions []models.Transaction   `json:"transactions"`
+}
+
+// ReportService builds and renders portfolio reports
+type ReportService struct {
+	analyticsService *AnalyticsService
+	portfolioService *PortfolioService
+}
+
+// NewReportService creates a new ReportService instance
+func NewReportService(analyticsService *AnalyticsService, portfolioService *PortfolioService) *ReportService {
+	return &ReportService{
+		analyticsService: analyticsService,
+		portfolioService: portfolioService,
+	}
+}
+
+// BuildPortfolioReport collects the summary metrics, allocation, performance
+// series, top movers, and transaction log for a period
+func (s *ReportService) BuildPortfolioReport(userID primitive.ObjectID, period string, currency string) (*PortfolioReport, error) {
+	fmt.Printf("[Report] Building %s report for user %s in %s\n", period, userID.Hex(), currency)
+
+	summary, err := s.analyticsService.GetDashboardMetrics(userID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate summary: %w", err)
+	}
+	currency = summary.Currency
+
+	performance, err := s.analyticsService.GetHistoricalPerformanceWithMetrics(userID, period, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate performance: %w", err)
+	}
+
+	series := performance.Performance
+	if len(series) > reportChartPoints {
+		series, err = DownsamplePerformance(series, reportChartPoints)
+		if err != nil {
+			return nil, fmt.Errorf("failed to downsample performance: %w", err)
+		}
+	}
+
+	movers, err := s.analyticsService.GetDayMovers(userID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate movers: %w", err)
+	}
+	gainers, losers := splitMovers(movers, reportMoverCount)
+
+	now := time.Now()
+	periodStart := PeriodStart(period, now)
+	transactions, err := s.portfolioService.GetTransactionsSince(userID, periodStart)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PortfolioReport{
+		GeneratedAt:  now,
+		Period:       period,
+		PeriodStart:  periodStart,
+		Currency:     currency,
+		Summary:      summary,
+		Metrics:      performance.Metrics,
+		Performance:  series,
+		TopGainers:   gainers,
+		TopLosers:    losers,
+		Transactions: transactions,
+	}, nil
+}
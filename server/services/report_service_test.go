@@ -0,0 +1,75 @@
+package services
+
+import (
+	"bytes"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleReport() *PortfolioReport {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	return &PortfolioReport{
+		GeneratedAt: now,
+		Period:      "1M",
+		PeriodStart: now.AddDate(0, -1, 0),
+		Currency:    "RMB",
+		Summary: &DashboardMetrics{
+			TotalValue: 123456.78,
+			TotalGain:  -1000,
+			Allocation: []AllocationItem{{Symbol: "600519.SS", Name: "Kweichow <Moutai>", Value: 123456.78, Percentage: 100}},
+			Currency:   "RMB",
+		},
+		Metrics: &PerformanceMetrics{},
+		Performance: []PerformanceDataPoint{
+			{Date: now.AddDate(0, 0, -2), Value: 100},
+			{Date: now.AddDate(0, 0, -1), Value: 120},
+			{Date: now, Value: 110},
+		},
+		TopGainers:   []HoldingMover{{Symbol: "600519.SS", Change: 50, ChangePercent: 1.5}},
+		TopLosers:    []HoldingMover{},
+		Transactions: []models.Transaction{{Symbol: "600519.SS", Action: "buy", Shares: 10, Price: 1700, Currency: "RMB", Date: now}},
+	}
+}
+
+func TestRenderPortfolioReport(t *testing.T) {
+	var html bytes.Buffer
+	if err := RenderPortfolioReport(sampleReport(), ReportFormatHTML, &html); err != nil {
+		t.Fatalf("Unexpected HTML error: %v", err)
+	}
+	for _, want := range []string{"¥123,456.78", "-¥1,000.00", "Kweichow &lt;Moutai&gt;", "<polyline"} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("Expected HTML report to contain %q", want)
+		}
+	}
+
+	var pdf bytes.Buffer
+	if err := RenderPortfolioReport(sampleReport(), ReportFormatPDF, &pdf); err != nil {
+		t.Fatalf("Unexpected PDF error: %v", err)
+	}
+	if !bytes.HasPrefix(pdf.Bytes(), []byte("%PDF-")) {
+		t.Errorf("Expected PDF output")
+	}
+
+	if err := RenderPortfolioReport(sampleReport(), "docx", &pdf); err != ErrInvalidReportFormat {
+		t.Errorf("Expected ErrInvalidReportFormat, got %v", err)
+	}
+}
+
+func TestSplitMovers(t *testing.T) {
+	movers := []HoldingMover{
+		{Symbol: "A", ChangePercent: 3},
+		{Symbol: "B", ChangePercent: 1},
+		{Symbol: "C", ChangePercent: 0},
+		{Symbol: "D", ChangePercent: -2},
+	}
+
+	gainers, losers := splitMovers(movers, 1)
+	if len(gainers) != 1 || gainers[0].Symbol != "A" {
+		t.Errorf("Unexpected gainers: %+v", gainers)
+	}
+	if len(losers) != 1 || losers[0].Symbol != "D" {
+		t.Errorf("Unexpected losers: %+v", losers)
+	}
+}
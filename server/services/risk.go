@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/internal/analytics/engine"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// DefaultRiskBenchmark is the index beta is measured against by default
+	DefaultRiskBenchmark = "^GSPC"
+	// minRiskObservations is the fewest daily returns a statistic is computed from
+	minRiskObservations = 20
+)
+
+// ValueAtRisk is the one-day loss not exceeded with the given confidence
+type ValueAtRisk struct {
+	Confidence float64 `json:"confidence"` // e.g. 95
+	Percentage float64 `json:"percentage"` // Loss as a percentage of the portfolio
+	Absolute   float64 `json:"absolute"`   // Loss in the requested currency
+}
+
+// ConcentrationMetric measures how concentrated the portfolio is in single positions
+type ConcentrationMetric struct {
+	HHI               float64 `json:"hhi"`               // Herfindahl-Hirschman index of weights, 0 to 1
+	EffectiveHoldings float64 `json:"effectiveHoldings"` // 1 / HHI
+	LargestSymbol     string  `json:"largestSymbol"`
+	LargestWeight     float64 `json:"largestWeight"` // Percentage
+}
+
+// HoldingRisk describes one holding's contribution to portfolio risk
+type HoldingRisk struct {
+	Symbol           string   `json:"symbol"`
+	Name             string   `json:"name"`
+	Value            float64  `json:"value"`
+	Weight           float64  `json:"weight"` // Percentage
+	Beta             *float64 `json:"beta,omitempty"`
+	MaxDrawdown      float64  `json:"maxDrawdown"`      // Largest peak-to-trough decline over the period, percentage
+	DrawdownExposure float64  `json:"drawdownExposure"` // Loss if that decline repeated at the current value
+}
+
+// RiskMetrics represents portfolio risk statistics computed from historical prices
+type RiskMetrics struct {
+	Currency      string              `json:"currency"`
+	Period        string              `json:"period"`
+	Benchmark     string              `json:"benchmark"`
+	TotalValue    float64             `json:"totalValue"`
+	Observations  int                 `json:"observations"` // Daily returns used
+	Beta          *float64            `json:"beta"`
+	Correlation   *float64            `json:"correlation"`
+	Volatility    float64             `json:"volatility"` // Annualized, percentage
+	ValueAtRisk   []ValueAtRisk       `json:"valueAtRisk"`
+	Concentration ConcentrationMetric `json:"concentration"`
+	Holdings      []HoldingRisk       `json:"holdings"`
+}
+
+// GetRiskMetrics computes beta against a benchmark index, historical VaR,
+// concentration and per-holding drawdown exposure. Returns are those of the
+// current holdings at their current weights, measured in each holding's
+// native currency from the cached price histories, fetched under ctx, with
+// manual prices replacing the closes from their effective dates.
+func (s *AnalyticsService) GetRiskMetrics(ctx context.Context, userID primitive.ObjectID, period string, currency string, benchmark string) (*RiskMetrics, error) {
+	currency = currencycode.Normalize(currency)
+	benchmark = strings.ToUpper(strings.TrimSpace(benchmark))
+	if benchmark == "" {
+		benchmark = DefaultRiskBenchmark
+	}
+
+	holdings, err := s.portfolioService.GetUserHoldingsContext(ctx, userID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch holdings: %w", err)
+	}
+
+	metrics := &RiskMetrics{
+		Currency:    currency,
+		Period:      period,
+		Benchmark:   benchmark,
+		ValueAtRisk: []ValueAtRisk{},
+		Holdings:    []HoldingRisk{},
+	}
+	for _, holding := range holdings {
+		metrics.TotalValue += holding.CurrentValue
+	}
+	if metrics.TotalValue <= 0 {
+		return metrics, nil
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	overrides, err := s.portfolioService.priceOverrides(queryCtx, userID)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	benchmarkPrices, err := s.stockService.GetHistoricalDataContext(ctx, benchmark, period)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if err != nil {
+		fmt.Printf("[Analytics] Warning: Could not get benchmark history for %s: %v\n", benchmark, err)
+	}
+	benchmarkReturns := engine.DailyReturns(benchmarkPrices)
+
+	weights := make(map[string]float64, len(holdings))
+	histories := make(map[string][]HistoricalPrice, len(holdings))
+	holdingWeights := make([]float64, 0, len(holdings))
+	for _, holding := range holdings {
+		weight := holding.CurrentValue / metrics.TotalValue
+		holdingWeights = append(holdingWeights, weight)
+
+		risk := HoldingRisk{
+			Symbol: holding.Symbol,
+			Name:   holding.Name,
+			Value:  holding.CurrentValue,
+			Weight: weight * 100,
+		}
+
+		// Cash has no price risk and contributes zero returns
+		if !s.stockService.IsCashSymbol(holding.Symbol) {
+			prices, err := s.stockService.GetHistoricalDataContext(ctx, holding.Symbol, period)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			if err != nil {
+				fmt.Printf("[Analytics] Warning: Could not get price history for %s: %v\n", holding.Symbol, err)
+			} else {
+				if override, ok := overrides[holding.Symbol]; ok {
+					prices = overrideHistory(prices, override)
+				}
+				prices = engine.Sorted(prices)
+				weights[holding.Symbol] = weight
+				histories[holding.Symbol] = prices
+
+				risk.MaxDrawdown = engine.MaxDrawdown(closes(prices)).Percent
+				risk.DrawdownExposure = holding.CurrentValue * risk.MaxDrawdown / 100
+				if beta, _, ok := regressionBeta(engine.DailyReturns(prices), benchmarkReturns); ok {
+					risk.Beta = &beta
+				}
+			}
+		}
+
+		metrics.Holdings = append(metrics.Holdings, risk)
+	}
+	sort.Slice(metrics.Holdings, func(i, j int) bool {
+		return metrics.Holdings[i].DrawdownExposure > metrics.Holdings[j].DrawdownExposure
+	})
+
+	metrics.Concentration = concentration(holdings, holdingWeights)
+
+	returns := portfolioDailyReturns(weights, histories)
+	metrics.Observations = len(returns)
+	if len(returns) < minRiskObservations {
+		return metrics, nil
+	}
+
+	values := make([]float64, 0, len(returns))
+	for _, r := range returns {
+		values = append(values, r)
+	}
+	metrics.Volatility = engine.AnnualizedVolatility(values)
+	for _, confidence := range []float64{95, 99} {
+		loss := historicalVaR(values, confidence)
+		metrics.ValueAtRisk = append(metrics.ValueAtRisk, ValueAtRisk{
+			Confidence: confidence,
+			Percentage: loss * 100,
+			Absolute:   loss * metrics.TotalValue,
+		})
+	}
+
+	if beta, correlation, ok := regressionBeta(returns, benchmarkReturns); ok {
+		metrics.Beta = &beta
+		metrics.Correlation = &correlation
+	}
+
+	return metrics, nil
+}
+
+// portfolioDailyReturns combines per-symbol daily returns at fixed weights.
+// A symbol without a price on a day, such as on its market's holiday,
+// contributes no return that day.
+func portfolioDailyReturns(weights map[string]float64, histories map[string][]HistoricalPrice) map[string]float64 {
+	returns := make(map[string]float64)
+	for symbol, prices := range histories {
+		for day, r := range engine.DailyReturns(prices) {
+			returns[day] += weights[symbol] * r
+		}
+	}
+	return returns
+}
+
+// regressionBeta returns the beta and correlation of returns against market
+// returns over the days present in both, requiring minRiskObservations days
+func regressionBeta(returns map[string]float64, market map[string]float64) (beta float64, correlation float64, ok bool) {
+	var xs, ys []float64
+	for day, r := range returns {
+		if m, exists := market[day]; exists {
+			xs = append(xs, m)
+			ys = append(ys, r)
+		}
+	}
+	if len(xs) < minRiskObservations {
+		return 0, 0, false
+	}
+
+	meanX, meanY := engine.Mean(xs), engine.Mean(ys)
+	var covariance, varianceX, varianceY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 {
+		return 0, 0, false
+	}
+
+	beta = covariance / varianceX
+	if varianceY > 0 {
+		correlation = covariance / math.Sqrt(varianceX*varianceY)
+	}
+	return beta, correlation, true
+}
+
+// historicalVaR returns the one-day loss fraction at the confidence level,
+// read from the empirical distribution of daily returns
+func historicalVaR(returns []float64, confidence float64) float64 {
+	sorted := make([]float64, len(returns))
+	copy(sorted, returns)
+	sort.Float64s(sorted)
+	return math.Max(0, -percentile(sorted, 100-confidence))
+}
+
+// concentration computes the Herfindahl-Hirschman index of position weights
+func concentration(holdings []Holding, weights []float64) ConcentrationMetric {
+	metric := ConcentrationMetric{}
+	for i, weight := range weights {
+		metric.HHI += weight * weight
+		if weight*100 > metric.LargestWeight {
+			metric.LargestWeight = weight * 100
+			metric.LargestSymbol = holdings[i].Symbol
+		}
+	}
+	if metric.HHI > 0 {
+		metric.EffectiveHoldings = 1 / metric.HHI
+	}
+	return metric
+}
+
+// closes returns the closing prices of a price history
+func closes(prices []HistoricalPrice) []float64 {
+	values := make([]float64, len(prices))
+	for i, price := range prices {
+		values[i] = price.Price
+	}
+	return values
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"math"
+	"stock-portfolio-tracker/internal/analytics/engine"
+	"testing"
+	"time"
+)
+
+// priceSeries builds a daily price series from consecutive returns starting at 100
+func priceSeries(returns []float64) []HistoricalPrice {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prices := []HistoricalPrice{{Date: start, Price: 100}}
+	for i, r := range returns {
+		prices = append(prices, HistoricalPrice{
+			Date:  start.AddDate(0, 0, i+1),
+			Price: prices[i].Price * (1 + r),
+		})
+	}
+	return prices
+}
+
+func TestRegressionBeta(t *testing.T) {
+	marketReturns := make([]float64, 30)
+	for i := range marketReturns {
+		marketReturns[i] = 0.01 * math.Sin(float64(i))
+	}
+	doubled := make([]float64, len(marketReturns))
+	for i, r := range marketReturns {
+		doubled[i] = 2 * r
+	}
+
+	beta, correlation, ok := regressionBeta(engine.DailyReturns(priceSeries(doubled)), engine.DailyReturns(priceSeries(marketReturns)))
+	if !ok {
+		t.Fatalf("Expected beta to be computed")
+	}
+	if math.Abs(beta-2) > 1e-9 || math.Abs(correlation-1) > 1e-9 {
+		t.Errorf("Expected beta 2 and correlation 1, got %.4f and %.4f", beta, correlation)
+	}
+
+	if _, _, ok := regressionBeta(engine.DailyReturns(priceSeries(doubled[:5])), engine.DailyReturns(priceSeries(marketReturns))); ok {
+		t.Errorf("Expected too few observations to be rejected")
+	}
+}
+
+func TestHistoricalVaR(t *testing.T) {
+	returns := make([]float64, 101)
+	for i := range returns {
+		returns[i] = float64(i-50) / 1000 // -5% to +5%
+	}
+
+	if loss := historicalVaR(returns, 95); math.Abs(loss-0.045) > 1e-9 {
+		t.Errorf("Expected 95%% VaR of 4.5%%, got %.4f", loss)
+	}
+	if loss := historicalVaR(returns, 99); math.Abs(loss-0.049) > 1e-9 {
+		t.Errorf("Expected 99%% VaR of 4.9%%, got %.4f", loss)
+	}
+	if loss := historicalVaR([]float64{0.01, 0.02}, 95); loss != 0 {
+		t.Errorf("Expected no loss for positive returns, got %.4f", loss)
+	}
+}
+
+func TestConcentrationAndDrawdown(t *testing.T) {
+	holdings := []Holding{{Symbol: "AAPL"}, {Symbol: "MSFT"}}
+	metric := concentration(holdings, []float64{0.75, 0.25})
+	if metric.HHI != 0.625 || metric.EffectiveHoldings != 1.6 || metric.LargestSymbol != "AAPL" || metric.LargestWeight != 75 {
+		t.Errorf("Unexpected concentration %+v", metric)
+	}
+
+	drawdown := engine.MaxDrawdown(closes(priceSeries([]float64{0.1, -0.5, 0.5, 1}))).Percent
+	if math.Abs(drawdown-50) > 1e-9 {
+		t.Errorf("Expected 50%% drawdown, got %.4f", drawdown)
+	}
+}
+
+func TestPortfolioDailyReturns(t *testing.T) {
+	histories := map[string][]HistoricalPrice{
+		"AAPL": priceSeries([]float64{0.1, 0}),
+		"MSFT": priceSeries([]float64{-0.1, 0.2}),
+	}
+	returns := portfolioDailyReturns(map[string]float64{"AAPL": 0.5, "MSFT": 0.5}, histories)
+
+	if math.Abs(returns["2024-01-02"]) > 1e-12 || math.Abs(returns["2024-01-03"]-0.1) > 1e-12 {
+		t.Errorf("Unexpected portfolio returns %+v", returns)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"stock-portfolio-tracker/internal/analytics/engine"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Optional series a performance response can include
+const (
+	PerformanceSeriesRolling  = "rolling"
+	PerformanceSeriesDrawdown = "drawdown"
+)
+
+// RollingReturnWindows are the lengths, in calendar days, of the rolling
+// return series
+var RollingReturnWindows = []int{30, 90, 365}
+
+// RollingReturnPoint is the return over the window ending on a date
+type RollingReturnPoint struct {
+	Date   time.Time `json:"date"`
+	Return float64   `json:"return"` // Percentage
+}
+
+// RollingReturnSeries is the time-weighted return over a fixed window, rolled
+// forward one day at a time
+type RollingReturnSeries struct {
+	WindowDays int                  `json:"windowDays"`
+	Returns    []RollingReturnPoint `json:"returns"`
+}
+
+// DrawdownPoint is how far the portfolio value sits below its running peak
+type DrawdownPoint struct {
+	Date     time.Time `json:"date"`
+	Drawdown float64   `json:"drawdown"` // Percentage below the peak, 0 at a new high
+}
+
+// AddPerformanceSeries adds the requested optional series to a performance
+// response. The drawdown curve follows the value series, as the max drawdown
+// metric does. Rolling returns are time-weighted, and a period shorter than
+// ALL is extended with earlier history so its windows are full from the
+// first day. The history is fetched under ctx.
+func (s *AnalyticsService) AddPerformanceSeries(ctx context.Context, userID primitive.ObjectID, response *PerformanceResponse, rolling, drawdown bool) error {
+	if drawdown {
+		response.Drawdowns = drawdownSeries(response.Performance)
+	}
+	if !rolling || len(response.Performance) == 0 {
+		return nil
+	}
+
+	history := response.Performance
+	if response.Period != "ALL" {
+		var err error
+		history, _, err = s.historicalPerformance(ctx, userID, "ALL", response.Currency, nil)
+		if err != nil {
+			return fmt.Errorf("failed to load history for rolling returns: %w", err)
+		}
+	}
+
+	transactions, err := s.portfolioService.GetTransactionsSinceContext(ctx, userID, time.Time{})
+	if err != nil {
+		return err
+	}
+	index := timeWeightedIndex(history, s.contributionTimeline(transactions, response.Currency))
+
+	from := response.Performance[0].Date
+	response.RollingReturns = make([]RollingReturnSeries, 0, len(RollingReturnWindows))
+	for _, window := range RollingReturnWindows {
+		response.RollingReturns = append(response.RollingReturns, RollingReturnSeries{
+			WindowDays: window,
+			Returns:    rollingReturns(history, index, window, from),
+		})
+	}
+	return nil
+}
+
+// rollingReturns returns the index's change over the window ending at each
+// point from from onwards. The window starts at the last point at least
+// windowDays earlier; points without a full window of held history are
+// skipped.
+func rollingReturns(points []PerformanceDataPoint, index []float64, windowDays int, from time.Time) []RollingReturnPoint {
+	returns := []RollingReturnPoint{}
+	base := -1
+	for i, point := range points {
+		cutoff := point.Date.AddDate(0, 0, -windowDays)
+		for base+1 < i && !points[base+1].Date.After(cutoff) {
+			base++
+		}
+		if base < 0 || point.Date.Before(from) || points[base].Value <= 0 || index[base] <= 0 {
+			continue
+		}
+		returns = append(returns, RollingReturnPoint{
+			Date:   point.Date,
+			Return: (index[i]/index[base] - 1) * 100,
+		})
+	}
+	return returns
+}
+
+// drawdownSeries returns the percentage decline from the running peak value
+// at each point
+func drawdownSeries(points []PerformanceDataPoint) []DrawdownPoint {
+	values := make([]float64, len(points))
+	for i, point := range points {
+		values[i] = point.Value
+	}
+
+	drawdowns := make([]DrawdownPoint, len(points))
+	for i, drawdown := range engine.DrawdownSeries(values) {
+		drawdowns[i] = DrawdownPoint{Date: points[i].Date, Drawdown: drawdown}
+	}
+	return drawdowns
+}
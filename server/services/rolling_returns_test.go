@@ -0,0 +1,50 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRollingReturns(t *testing.T) {
+	points := []PerformanceDataPoint{
+		{Date: tradeDate(2024, 1, 1), Value: 0},
+		{Date: tradeDate(2024, 1, 10), Value: 100},
+		{Date: tradeDate(2024, 2, 10), Value: 110},
+		{Date: tradeDate(2024, 3, 10), Value: 121},
+	}
+	index := timeWeightedIndex(points, []contributionChange{{Date: tradeDate(2024, 1, 10), Total: 100}})
+
+	// Each window starts at the last point at least 30 days earlier
+	returns := rollingReturns(points, index, 30, tradeDate(2024, 2, 1))
+	want := []float64{10, 21}
+	if len(returns) != len(want) {
+		t.Fatalf("Expected 30-day returns on the last two dates, got %+v", returns)
+	}
+	for i, point := range returns {
+		if math.Abs(point.Return-want[i]) > 1e-9 {
+			t.Errorf("Expected a %.0f%% 30-day return on %s, got %.4f", want[i], point.Date.Format("2006-01-02"), point.Return)
+		}
+	}
+
+	// The window before the first holding is not a return
+	if returns := rollingReturns(points, index, 60, tradeDate(2024, 1, 1)); len(returns) != 1 || math.Abs(returns[0].Return-21) > 1e-9 {
+		t.Errorf("Expected only a 21%% 60-day return in March, got %+v", returns)
+	}
+}
+
+func TestDrawdownSeries(t *testing.T) {
+	points := []PerformanceDataPoint{
+		{Date: tradeDate(2024, 1, 1), Value: 100},
+		{Date: tradeDate(2024, 1, 2), Value: 80},
+		{Date: tradeDate(2024, 1, 3), Value: 90},
+		{Date: tradeDate(2024, 1, 4), Value: 120},
+	}
+
+	want := []float64{0, 20, 10, 0}
+	drawdowns := drawdownSeries(points)
+	for i, point := range drawdowns {
+		if point.Drawdown != want[i] || !point.Date.Equal(points[i].Date) {
+			t.Errorf("Point %d: expected %.0f%% drawdown, got %+v", i, want[i], point)
+		}
+	}
+}
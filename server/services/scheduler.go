@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// scheduledJob represents a recurring background job
+type scheduledJob struct {
+	name     string
+	interval time.Duration
+	run      func() error
+}
+
+// Scheduler runs recurring background jobs. Each job runs on its own ticker
+// and never overlaps with itself.
+type Scheduler struct {
+	jobs    []scheduledJob
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	started bool
+	mutex   sync.Mutex
+}
+
+// NewScheduler creates a new Scheduler instance
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		stop: make(chan struct{}),
+	}
+}
+
+// Every registers a job that runs once per interval after the scheduler starts.
+// Jobs must be registered before Start.
+func (s *Scheduler) Every(name string, interval time.Duration, run func() error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.jobs = append(s.jobs, scheduledJob{name: name, interval: interval, run: run})
+}
+
+// Start launches every registered job
+func (s *Scheduler) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(job)
+	}
+	fmt.Printf("[Scheduler] Started %d jobs\n", len(s.jobs))
+}
+
+// Stop signals every job to exit and waits for running jobs to finish
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	if !s.started {
+		s.mutex.Unlock()
+		return
+	}
+	s.started = false
+	close(s.stop)
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+}
+
+// loop runs a job on its interval until the scheduler stops
+func (s *Scheduler) loop(job scheduledJob) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.runJob(job)
+		}
+	}
+}
+
+// runJob runs a job once, logging failures and recovering from panics
+func (s *Scheduler) runJob(job scheduledJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("[Scheduler] Job %s panicked: %v\n", job.name, r)
+		}
+	}()
+
+	start := time.Now()
+	if err := job.run(); err != nil {
+		fmt.Printf("[Scheduler] Job %s failed after %s: %v\n", job.name, time.Since(start), err)
+	}
+}
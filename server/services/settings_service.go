@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidDriftTargets is returned when drift alert targets don't add up to 100%
+var ErrInvalidDriftTargets = errors.New("target weights must add up to 100")
+
+// DefaultDriftThreshold is the drift alert threshold, in percentage points,
+// of a user who has never set one
+const DefaultDriftThreshold = 5.0
+
+// SettingsService handles per-user settings
+type SettingsService struct{}
+
+// NewSettingsService creates a new SettingsService instance
+func NewSettingsService() *SettingsService {
+	return &SettingsService{}
+}
+
+// defaultUserSettings returns the settings of a user who has never saved any
+func defaultUserSettings(userID primitive.ObjectID) *models.UserSettings {
+	return &models.UserSettings{
+		UserID: userID,
+		SummaryEmail: models.SummaryEmailSettings{
+			Frequency: models.SummaryFrequencyOff,
+			Currency:  "USD",
+		},
+		DriftAlerts: models.DriftAlertSettings{
+			GroupBy:   "assetClass",
+			Currency:  "USD",
+			Threshold: DefaultDriftThreshold,
+		},
+	}
+}
+
+// GetSettings returns the user's settings, falling back to defaults
+func (s *SettingsService) GetSettings(userID primitive.ObjectID) (*models.UserSettings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("user_settings")
+
+	var settings models.UserSettings
+	err := collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&settings)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return defaultUserSettings(userID), nil
+		}
+		return nil, fmt.Errorf("failed to fetch settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// UpdateSummaryEmail saves the user's summary email preferences
+func (s *SettingsService) UpdateSummaryEmail(userID primitive.ObjectID, frequency string, currency string) (*models.UserSettings, error) {
+	if currency = currencycode.Normalize(currency); currency == "" {
+		currency = currencycode.USD
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("user_settings")
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"summary_email.frequency": frequency,
+			"summary_email.currency":  currency,
+			"updated_at":              now,
+		},
+		"$setOnInsert": bson.M{
+			"user_id":      userID,
+			"drift_alerts": defaultUserSettings(userID).DriftAlerts,
+			"created_at":   now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var settings models.UserSettings
+	err := collection.FindOneAndUpdate(ctx, bson.M{"user_id": userID}, update, opts).Decode(&settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// validateDriftTargets checks that target weights add up to 100%, allowing
+// for rounding in the inputs
+func validateDriftTargets(targets map[string]float64) error {
+	total := 0.0
+	for _, weight := range targets {
+		total += weight
+	}
+	if math.Abs(total-100) > 0.01 {
+		return fmt.Errorf("%w, got %.2f", ErrInvalidDriftTargets, total)
+	}
+	return nil
+}
+
+// UpdateDriftAlerts saves the user's target weights and drift alert
+// preferences. Changing them resets the alert state, so drift already out of
+// band under the new targets is reported on the next check.
+func (s *SettingsService) UpdateDriftAlerts(userID primitive.ObjectID, alerts models.DriftAlertSettings) (*models.UserSettings, error) {
+	if err := validateDriftTargets(alerts.Targets); err != nil {
+		return nil, err
+	}
+	if alerts.Currency = currencycode.Normalize(alerts.Currency); alerts.Currency == "" {
+		alerts.Currency = currencycode.USD
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("user_settings")
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"drift_alerts.enabled":   alerts.Enabled,
+			"drift_alerts.group_by":  alerts.GroupBy,
+			"drift_alerts.currency":  alerts.Currency,
+			"drift_alerts.threshold": alerts.Threshold,
+			"drift_alerts.targets":   alerts.Targets,
+			"updated_at":             now,
+		},
+		"$unset": bson.M{
+			"drift_alerts.breached": "",
+		},
+		"$setOnInsert": bson.M{
+			"user_id":       userID,
+			"summary_email": defaultUserSettings(userID).SummaryEmail,
+			"created_at":    now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var settings models.UserSettings
+	err := collection.FindOneAndUpdate(ctx, bson.M{"user_id": userID}, update, opts).Decode(&settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// UpdateCrossAlerts turns moving average cross alerts on or off. Changing
+// them resets the alert state, so recent crosses are reported on the next
+// check.
+func (s *SettingsService) UpdateCrossAlerts(userID primitive.ObjectID, enabled bool) (*models.UserSettings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("user_settings")
+
+	defaults := defaultUserSettings(userID)
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"cross_alerts.enabled": enabled,
+			"updated_at":           now,
+		},
+		"$unset": bson.M{
+			"cross_alerts.alerted": "",
+		},
+		"$setOnInsert": bson.M{
+			"user_id":       userID,
+			"summary_email": defaults.SummaryEmail,
+			"drift_alerts":  defaults.DriftAlerts,
+			"created_at":    now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var settings models.UserSettings
+	err := collection.FindOneAndUpdate(ctx, bson.M{"user_id": userID}, update, opts).Decode(&settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// UpdateChat saves where the user's notifications are sent on Telegram and
+// Slack. An empty destination turns that channel off.
+func (s *SettingsService) UpdateChat(userID primitive.ObjectID, chat models.ChatSettings) (*models.UserSettings, error) {
+	chat.TelegramChatID = strings.TrimSpace(chat.TelegramChatID)
+	chat.SlackWebhookURL = strings.TrimSpace(chat.SlackWebhookURL)
+	if err := validateChatSettings(chat); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("user_settings")
+
+	defaults := defaultUserSettings(userID)
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"chat":       chat,
+			"updated_at": now,
+		},
+		"$setOnInsert": bson.M{
+			"user_id":       userID,
+			"summary_email": defaults.SummaryEmail,
+			"drift_alerts":  defaults.DriftAlerts,
+			"created_at":    now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var settings models.UserSettings
+	err := collection.FindOneAndUpdate(ctx, bson.M{"user_id": userID}, update, opts).Decode(&settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// UpdateDefaultBenchmark saves the benchmark the user's dashboard and
+// performance are compared against by default. An empty benchmark turns the
+// comparison off.
+func (s *SettingsService) UpdateDefaultBenchmark(userID primitive.ObjectID, benchmark string) (*models.UserSettings, error) {
+	benchmark = strings.TrimSpace(benchmark)
+	if err := ValidateBenchmark(benchmark); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("user_settings")
+
+	defaults := defaultUserSettings(userID)
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"benchmarks.default": benchmark,
+			"updated_at":         now,
+		},
+		"$setOnInsert": bson.M{
+			"user_id":       userID,
+			"summary_email": defaults.SummaryEmail,
+			"drift_alerts":  defaults.DriftAlerts,
+			"created_at":    now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var settings models.UserSettings
+	err := collection.FindOneAndUpdate(ctx, bson.M{"user_id": userID}, update, opts).Decode(&settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+
+	return &settings, nil
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrShareLinkNotFound = errors.New("share link not found")
+	ErrTooManyShareLinks = errors.New("too many active share links")
+)
+
+const (
+	// shareTokenBytes is the amount of randomness in a share token
+	shareTokenBytes = 32
+	// maxActiveShareLinks bounds the number of unrevoked links per user
+	maxActiveShareLinks = 20
+)
+
+// SharedDashboard is the redacted dashboard exposed through a share link.
+// Monetary fields are omitted unless the owner chose to share values.
+type SharedDashboard struct {
+	Label            string                 `json:"label,omitempty"`
+	Currency         string                 `json:"currency,omitempty"`
+	ShowValues       bool                   `json:"showValues"`
+	TotalValue       *float64               `json:"totalValue,omitempty"`
+	TotalGain        *float64               `json:"totalGain,omitempty"`
+	DayChange        *float64               `json:"dayChange,omitempty"`
+	PercentageReturn float64                `json:"percentageReturn"`
+	DayChangePercent float64                `json:"dayChangePercent"`
+	Allocation       []SharedAllocationItem `json:"allocation"`
+	GeneratedAt      time.Time              `json:"generatedAt"`
+}
+
+// SharedAllocationItem is an allocation entry of a shared dashboard
+type SharedAllocationItem struct {
+	Symbol     string   `json:"symbol"`
+	Name       string   `json:"name"`
+	Percentage float64  `json:"percentage"`
+	Value      *float64 `json:"value,omitempty"`
+}
+
+// ShareService handles public read-only portfolio share links
+type ShareService struct {
+	analyticsService *AnalyticsService
+}
+
+// NewShareService creates a new ShareService instance
+func NewShareService(analyticsService *AnalyticsService) *ShareService {
+	return &ShareService{
+		analyticsService: analyticsService,
+	}
+}
+
+// generateShareToken returns a random URL-safe token
+func generateShareToken() (string, error) {
+	buf := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashShareToken returns the stored form of a share token
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// activeShareLinkFilter matches links that have not been revoked or expired
+func activeShareLinkFilter(now time.Time) bson.M {
+	return bson.M{
+		"revoked_at": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$exists": false}},
+			bson.M{"expires_at": bson.M{"$gt": now}},
+		},
+	}
+}
+
+// CreateLink creates a share link and returns it together with its token.
+// The token cannot be recovered later.
+func (s *ShareService) CreateLink(userID primitive.ObjectID, req models.CreateShareLinkRequest) (*models.ShareLink, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("share_links")
+
+	now := time.Now()
+	filter := activeShareLinkFilter(now)
+	filter["user_id"] = userID
+	active, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to count share links: %w", err)
+	}
+	if active >= maxActiveShareLinks {
+		return nil, "", ErrTooManyShareLinks
+	}
+
+	token, err := generateShareToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate share token: %w", err)
+	}
+
+	currency := currencycode.Normalize(req.Currency)
+	if currency == "" {
+		currency = currencycode.USD
+	}
+
+	link := &models.ShareLink{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		TokenHash:  hashShareToken(token),
+		Label:      strings.TrimSpace(req.Label),
+		ShowValues: req.ShowValues,
+		Currency:   currency,
+		CreatedAt:  now,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if _, err := collection.InsertOne(ctx, link); err != nil {
+		return nil, "", fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	return link, token, nil
+}
+
+// GetUserLinks returns all share links created by a user, newest first
+func (s *ShareService) GetUserLinks(userID primitive.ObjectID) ([]models.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("share_links")
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch share links: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	links := []models.ShareLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, fmt.Errorf("failed to decode share links: %w", err)
+	}
+
+	return links, nil
+}
+
+// RevokeLink revokes a share link owned by the user
+func (s *ShareService) RevokeLink(userID primitive.ObjectID, linkID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("share_links")
+
+	filter := bson.M{
+		"_id":        linkID,
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+	}
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrShareLinkNotFound
+	}
+
+	return nil
+}
+
+// GetSharedDashboard resolves a share token and returns the redacted dashboard.
+// Unknown, revoked and expired tokens all return ErrShareLinkNotFound.
+func (s *ShareService) GetSharedDashboard(token string) (*SharedDashboard, error) {
+	if token == "" {
+		return nil, ErrShareLinkNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("share_links")
+
+	now := time.Now()
+	filter := activeShareLinkFilter(now)
+	filter["token_hash"] = hashShareToken(token)
+	update := bson.M{"$set": bson.M{"last_accessed_at": now}}
+
+	var link models.ShareLink
+	err := collection.FindOneAndUpdate(ctx, filter, update).Decode(&link)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch share link: %w", err)
+	}
+
+	metrics, err := s.analyticsService.GetDashboardMetrics(link.UserID, link.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := redactDashboard(metrics, link.ShowValues)
+	dashboard.Label = link.Label
+	dashboard.GeneratedAt = now
+	return dashboard, nil
+}
+
+// redactDashboard converts dashboard metrics into their shareable form
+func redactDashboard(metrics *DashboardMetrics, showValues bool) *SharedDashboard {
+	dashboard := &SharedDashboard{
+		ShowValues:       showValues,
+		PercentageReturn: metrics.PercentageReturn,
+		DayChangePercent: metrics.DayChangePercent,
+		Allocation:       make([]SharedAllocationItem, 0, len(metrics.Allocation)),
+	}
+
+	if showValues {
+		totalValue := metrics.TotalValue
+		totalGain := metrics.TotalGain
+		dayChange := metrics.DayChange
+		dashboard.Currency = metrics.Currency
+		dashboard.TotalValue = &totalValue
+		dashboard.TotalGain = &totalGain
+		dashboard.DayChange = &dayChange
+	}
+
+	for _, item := range metrics.Allocation {
+		shared := SharedAllocationItem{
+			Symbol:     item.Symbol,
+			Name:       item.Name,
+			Percentage: item.Percentage,
+		}
+		if showValues {
+			value := item.Value
+			shared.Value = &value
+		}
+		dashboard.Allocation = append(dashboard.Allocation, shared)
+	}
+
+	return dashboard
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedactDashboardHidesValues(t *testing.T) {
+	metrics := &DashboardMetrics{
+		TotalValue:       10000,
+		TotalGain:        1500,
+		PercentageReturn: 17.6,
+		DayChange:        -50,
+		DayChangePercent: -0.5,
+		Currency:         "USD",
+		Allocation: []AllocationItem{
+			{Symbol: "AAPL", Name: "Apple Inc.", Value: 6000, Percentage: 60},
+			{Symbol: "MSFT", Name: "Microsoft", Value: 4000, Percentage: 40},
+		},
+	}
+
+	redacted := redactDashboard(metrics, false)
+	body, err := json.Marshal(redacted)
+	if err != nil {
+		t.Fatalf("Failed to marshal shared dashboard: %v", err)
+	}
+	for _, field := range []string{"totalValue", "totalGain", "dayChange\"", "\"value\"", "currency"} {
+		if strings.Contains(string(body), field) {
+			t.Errorf("Expected %s to be redacted, got %s", field, body)
+		}
+	}
+	if redacted.PercentageReturn != 17.6 || redacted.Allocation[0].Percentage != 60 {
+		t.Errorf("Expected percentages to be preserved, got %+v", redacted)
+	}
+
+	withValues := redactDashboard(metrics, true)
+	if withValues.TotalValue == nil || *withValues.TotalValue != 10000 {
+		t.Errorf("Expected total value to be shared, got %v", withValues.TotalValue)
+	}
+	if withValues.Allocation[1].Value == nil || *withValues.Allocation[1].Value != 4000 {
+		t.Errorf("Expected allocation values to be shared")
+	}
+}
+
+func TestShareTokens(t *testing.T) {
+	first, err := generateShareToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	second, _ := generateShareToken()
+
+	if first == second {
+		t.Errorf("Expected distinct tokens")
+	}
+	if len(first) != 43 {
+		t.Errorf("Expected 43 character token, got %d", len(first))
+	}
+	if hashShareToken(first) == first || hashShareToken(first) != hashShareToken(first) {
+		t.Errorf("Expected a stable hash distinct from the token")
+	}
+}
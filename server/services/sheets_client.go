@@ -0,0 +1,259 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"stock-portfolio-tracker/telemetry"
+	"strings"
+	"time"
+)
+
+// sheetsScope is the OAuth scope for reading and writing the user's sheets
+const sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+// GoogleToken is an OAuth token pair from Google. The refresh token is only
+// returned when the user consents.
+type GoogleToken struct {
+	AccessToken  string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+// GoogleSheetsClient authorizes with Google OAuth and writes values to the
+// user's spreadsheets through the Sheets API
+type GoogleSheetsClient struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	authURL      string
+	tokenURL     string
+	revokeURL    string
+	sheetsURL    string
+	client       *http.Client
+}
+
+// NewGoogleSheetsClient creates a new GoogleSheetsClient instance for the
+// OAuth client, which redirects back to redirectURL after consent
+func NewGoogleSheetsClient(clientID, clientSecret, redirectURL string) *GoogleSheetsClient {
+	return &GoogleSheetsClient{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		authURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		tokenURL:     "https://oauth2.googleapis.com/token",
+		revokeURL:    "https://oauth2.googleapis.com/revoke",
+		sheetsURL:    "https://sheets.googleapis.com/v4/spreadsheets",
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: telemetry.Transport(nil),
+		},
+	}
+}
+
+// AuthURL returns the consent screen URL, which redirects back with a code
+// and the state. Offline access with forced consent returns a refresh token
+// even if the user consented before.
+func (g *GoogleSheetsClient) AuthURL(state string) string {
+	query := url.Values{
+		"client_id":     {g.clientID},
+		"redirect_uri":  {g.redirectURL},
+		"response_type": {"code"},
+		"scope":         {sheetsScope},
+		"access_type":   {"offline"},
+		"prompt":        {"consent"},
+		"state":         {state},
+	}
+	return g.authURL + "?" + query.Encode()
+}
+
+// Exchange exchanges the consent screen's code for tokens
+func (g *GoogleSheetsClient) Exchange(ctx context.Context, code string) (*GoogleToken, error) {
+	return g.token(ctx, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {g.redirectURL},
+	})
+}
+
+// Refresh returns a new access token for the refresh token
+func (g *GoogleSheetsClient) Refresh(ctx context.Context, refreshToken string) (*GoogleToken, error) {
+	token, err := g.token(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// token requests tokens from the token endpoint
+func (g *GoogleSheetsClient) token(ctx context.Context, form url.Values) (*GoogleToken, error) {
+	form.Set("client_id", g.clientID)
+	form.Set("client_secret", g.clientSecret)
+
+	var response struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := g.do(ctx, http.MethodPost, g.tokenURL, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()), "", &response); err != nil {
+		return nil, err
+	}
+	if response.AccessToken == "" {
+		return nil, fmt.Errorf("google returned no access token")
+	}
+	return &GoogleToken{
+		AccessToken:  response.AccessToken,
+		RefreshToken: response.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(response.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// Revoke revokes a token, and with it the app's access to the user's sheets
+func (g *GoogleSheetsClient) Revoke(ctx context.Context, token string) error {
+	form := url.Values{"token": {token}}
+	return g.do(ctx, http.MethodPost, g.revokeURL, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()), "", nil)
+}
+
+// EnsureSheets adds the named tabs the spreadsheet lacks, returning those it
+// added
+func (g *GoogleSheetsClient) EnsureSheets(ctx context.Context, accessToken, spreadsheetID string, titles ...string) ([]string, error) {
+	var spreadsheet struct {
+		Sheets []struct {
+			Properties struct {
+				Title string `json:"title"`
+			} `json:"properties"`
+		} `json:"sheets"`
+	}
+	endpoint := g.spreadsheetURL(spreadsheetID) + "?fields=" + url.QueryEscape("sheets.properties.title")
+	if err := g.do(ctx, http.MethodGet, endpoint, "", nil, accessToken, &spreadsheet); err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool, len(spreadsheet.Sheets))
+	for _, sheet := range spreadsheet.Sheets {
+		existing[sheet.Properties.Title] = true
+	}
+	var added []string
+	var requests []interface{}
+	for _, title := range titles {
+		if !existing[title] {
+			added = append(added, title)
+			requests = append(requests, map[string]interface{}{
+				"addSheet": map[string]interface{}{"properties": map[string]string{"title": title}},
+			})
+		}
+	}
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	err := g.postJSON(ctx, g.spreadsheetURL(spreadsheetID)+":batchUpdate", accessToken, map[string]interface{}{"requests": requests})
+	return added, err
+}
+
+// ReplaceValues clears a tab and writes rows from its top left cell
+func (g *GoogleSheetsClient) ReplaceValues(ctx context.Context, accessToken, spreadsheetID, sheet string, rows [][]interface{}) error {
+	if err := g.postJSON(ctx, g.valuesURL(spreadsheetID, sheet)+":clear", accessToken, map[string]interface{}{}); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"values": rows})
+	if err != nil {
+		return err
+	}
+	endpoint := g.valuesURL(spreadsheetID, sheet+"!A1") + "?valueInputOption=RAW"
+	return g.do(ctx, http.MethodPut, endpoint, "application/json", bytes.NewReader(data), accessToken, nil)
+}
+
+// AppendValues adds rows below the last row of a tab
+func (g *GoogleSheetsClient) AppendValues(ctx context.Context, accessToken, spreadsheetID, sheet string, rows [][]interface{}) error {
+	endpoint := g.valuesURL(spreadsheetID, sheet+"!A1") + ":append?valueInputOption=RAW&insertDataOption=INSERT_ROWS"
+	return g.postJSON(ctx, endpoint, accessToken, map[string]interface{}{"values": rows})
+}
+
+// spreadsheetURL returns the Sheets API URL of a spreadsheet
+func (g *GoogleSheetsClient) spreadsheetURL(spreadsheetID string) string {
+	return g.sheetsURL + "/" + url.PathEscape(spreadsheetID)
+}
+
+// valuesURL returns the Sheets API URL of a range of a spreadsheet
+func (g *GoogleSheetsClient) valuesURL(spreadsheetID, valueRange string) string {
+	return g.spreadsheetURL(spreadsheetID) + "/values/" + url.PathEscape(valueRange)
+}
+
+// postJSON posts a JSON body to the Sheets API, ignoring the response
+func (g *GoogleSheetsClient) postJSON(ctx context.Context, endpoint, accessToken string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return g.do(ctx, http.MethodPost, endpoint, "application/json", bytes.NewReader(data), accessToken, nil)
+}
+
+// do sends a request to Google and decodes the JSON response into out,
+// unless out is nil
+func (g *GoogleSheetsClient) do(ctx context.Context, method, endpoint, contentType string, body io.Reader, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Google: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read Google response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return googleError(resp.StatusCode, payload)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("invalid Google response: %w", err)
+	}
+	return nil
+}
+
+// googleError describes a failed response. The OAuth endpoints report an
+// error code and description, the Sheets API an error object.
+func googleError(status int, payload []byte) error {
+	var oauthErr struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if json.Unmarshal(payload, &oauthErr) == nil && oauthErr.Error != "" {
+		return fmt.Errorf("google %s: %s", oauthErr.Error, oauthErr.ErrorDescription)
+	}
+	var apiErr struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(payload, &apiErr) == nil && apiErr.Error.Message != "" {
+		return fmt.Errorf("google %s: %s", apiErr.Error.Status, apiErr.Error.Message)
+	}
+	return fmt.Errorf("google responded with status %d", status)
+}
@@ -0,0 +1,435 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrSheetsUnavailable  = errors.New("google sheets export is not configured")
+	ErrSheetsLinkNotFound = errors.New("google sheet not linked")
+	ErrSheetsLinkPending  = errors.New("google sheet link is not complete")
+	ErrInvalidSheetsState = errors.New("google authorization does not match the pending link")
+	ErrInvalidSpreadsheet = errors.New("invalid spreadsheet")
+	ErrSheetsSyncFailed   = errors.New("google sheets sync failed")
+)
+
+// The tabs written in a linked spreadsheet
+const (
+	sheetsHoldingsTab    = "Holdings"
+	sheetsPerformanceTab = "Performance"
+)
+
+// sheetsPerformanceHeader heads the performance tab, matching the dashboard's
+// JSON field names
+var sheetsPerformanceHeader = []interface{}{"date", "totalValue", "dayChange", "dayChangePercent", "totalGain", "percentageReturn", "currency"}
+
+var (
+	// spreadsheetURLPattern finds the ID in a spreadsheet's URL
+	spreadsheetURLPattern = regexp.MustCompile(`/spreadsheets/d/([A-Za-z0-9_-]+)`)
+	spreadsheetIDPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]{10,200}$`)
+)
+
+// SheetsLinkStart is returned when linking starts
+type SheetsLinkStart struct {
+	Link *models.SheetsLink `json:"link"`
+	// AuthURL is Google's consent screen, which redirects back to the web
+	// app with the code and state to authorize the link with
+	AuthURL string `json:"authUrl"`
+}
+
+// SheetsService pushes users' holdings and daily performance to a Google
+// Sheet they link. Each sync rewrites the Holdings tab with the current
+// holdings table and appends the day's dashboard figures to the Performance
+// tab, once per day. The Google tokens are encrypted at rest.
+type SheetsService struct {
+	repos            repository.Repositories
+	analyticsService *AnalyticsService
+	portfolioService *PortfolioService
+	client           *GoogleSheetsClient
+	cipher           *TokenCipher
+}
+
+// NewSheetsService creates a new SheetsService instance. Without a client
+// the export isn't offered.
+func NewSheetsService(analyticsService *AnalyticsService, client *GoogleSheetsClient, cipher *TokenCipher) *SheetsService {
+	return &SheetsService{
+		repos:            analyticsService.portfolioService.repos,
+		analyticsService: analyticsService,
+		portfolioService: analyticsService.portfolioService,
+		client:           client,
+		cipher:           cipher,
+	}
+}
+
+// Available reports whether sheets can be linked on this server
+func (s *SheetsService) Available() bool {
+	return s.client != nil && s.cipher != nil
+}
+
+// parseSpreadsheetID accepts a spreadsheet's ID or URL and returns its ID
+func parseSpreadsheetID(spreadsheet string) (string, error) {
+	spreadsheet = strings.TrimSpace(spreadsheet)
+	if match := spreadsheetURLPattern.FindStringSubmatch(spreadsheet); match != nil {
+		spreadsheet = match[1]
+	}
+	if !spreadsheetIDPattern.MatchString(spreadsheet) {
+		return "", fmt.Errorf("%w: %q is not a Google Sheets URL or ID", ErrInvalidSpreadsheet, spreadsheet)
+	}
+	return spreadsheet, nil
+}
+
+// StartLink begins linking the spreadsheet, replacing any sheet the user
+// linked before. The link stays pending until the user consents on the
+// returned page and the web app calls CompleteLink.
+func (s *SheetsService) StartLink(userID primitive.ObjectID, req models.SheetsLinkRequest) (*SheetsLinkStart, error) {
+	if !s.Available() {
+		return nil, ErrSheetsUnavailable
+	}
+	spreadsheetID, err := parseSpreadsheetID(req.Spreadsheet)
+	if err != nil {
+		return nil, err
+	}
+	currency := "USD"
+	if req.Currency != "" {
+		currency = currencycode.Normalize(req.Currency)
+	}
+	state, err := generateShareToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate link state: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	existing, err := s.repos.SheetsLinks.FindByUser(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to fetch sheets link: %w", err)
+	}
+
+	now := time.Now()
+	link := &models.SheetsLink{
+		ID:            primitive.NewObjectID(),
+		UserID:        userID,
+		SpreadsheetID: spreadsheetID,
+		Currency:      currency,
+		State:         state,
+		Status:        models.SheetsLinkPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if existing != nil {
+		s.revoke(ctx, existing)
+		link.ID, link.CreatedAt = existing.ID, existing.CreatedAt
+		err = s.repos.SheetsLinks.Update(ctx, link)
+	} else {
+		err = s.repos.SheetsLinks.Insert(ctx, link)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save sheets link: %w", err)
+	}
+
+	return &SheetsLinkStart{Link: link, AuthURL: s.client.AuthURL(state)}, nil
+}
+
+// CompleteLink authorizes the pending link with the code Google's consent
+// screen returned, then syncs the sheet. A failed first sync is recorded on
+// the link rather than returned, like those of the sync job.
+func (s *SheetsService) CompleteLink(userID primitive.ObjectID, req models.SheetsAuthorizeRequest) (*models.SheetsLink, error) {
+	if !s.Available() {
+		return nil, ErrSheetsUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	link, err := s.findLink(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if link.Status != models.SheetsLinkPending || subtle.ConstantTimeCompare([]byte(link.State), []byte(req.State)) != 1 {
+		return nil, ErrInvalidSheetsState
+	}
+
+	token, err := s.client.Exchange(ctx, req.Code)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSheetsSyncFailed, err)
+	}
+	if err := s.setToken(link, token); err != nil {
+		return nil, err
+	}
+	link.State = ""
+	link.Status = models.SheetsLinkActive
+	link.UpdatedAt = time.Now()
+	if err := s.repos.SheetsLinks.Update(ctx, link); err != nil {
+		return nil, fmt.Errorf("failed to update sheets link: %w", err)
+	}
+
+	s.sync(link)
+	return link, nil
+}
+
+// GetLink returns the user's linked sheet, nil if they have none
+func (s *SheetsService) GetLink(userID primitive.ObjectID) (*models.SheetsLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	link, err := s.repos.SheetsLinks.FindByUser(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sheets link: %w", err)
+	}
+	return link, nil
+}
+
+// Unlink stops syncing the user's sheet and revokes the app's access to
+// their sheets. What was written to the sheet is left in place.
+func (s *SheetsService) Unlink(userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	link, err := s.findLink(ctx, userID)
+	if err != nil {
+		return err
+	}
+	s.revoke(ctx, link)
+
+	err = s.repos.SheetsLinks.Delete(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrSheetsLinkNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete sheets link: %w", err)
+	}
+	return nil
+}
+
+// SyncNow syncs the user's sheet now
+func (s *SheetsService) SyncNow(userID primitive.ObjectID) (*models.SheetsLink, error) {
+	if !s.Available() {
+		return nil, ErrSheetsUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	link, err := s.findLink(ctx, userID)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	if link.Status == models.SheetsLinkPending {
+		return nil, ErrSheetsLinkPending
+	}
+
+	if err := s.sync(link); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSheetsSyncFailed, err)
+	}
+	return link, nil
+}
+
+// SyncAll syncs every authorized link. Each link is claimed with a
+// conditional update first, so with several instances only one syncs it.
+func (s *SheetsService) SyncAll() error {
+	if !s.Available() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	links, err := s.repos.SheetsLinks.FindSyncable(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to fetch sheets links: %w", err)
+	}
+
+	synced := 0
+	var errs []error
+	for i := range links {
+		link := &links[i]
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		now := time.Now()
+		err := s.repos.SheetsLinks.ClaimSync(ctx, link.UserID, link.ID, link.LastSyncedAt, now)
+		cancel()
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to claim sheets link %s: %w", link.ID.Hex(), err))
+			continue
+		}
+		link.LastSyncedAt = &now
+
+		if err := s.sync(link); err != nil {
+			errs = append(errs, fmt.Errorf("failed to sync sheets link %s: %w", link.ID.Hex(), err))
+			continue
+		}
+		synced++
+	}
+
+	if synced > 0 {
+		fmt.Printf("[Sheets] Synced %d linked sheets\n", synced)
+	}
+	return errors.Join(errs...)
+}
+
+// sync writes the user's holdings and performance to the sheet and records
+// the outcome on the link
+func (s *SheetsService) sync(link *models.SheetsLink) error {
+	syncErr := s.push(link)
+
+	now := time.Now()
+	link.LastSyncedAt = &now
+	link.UpdatedAt = now
+	if syncErr != nil {
+		link.Status = models.SheetsLinkError
+		link.LastError = syncErr.Error()
+	} else {
+		link.Status = models.SheetsLinkActive
+		link.LastError = ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.repos.SheetsLinks.Update(ctx, link); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return errors.Join(syncErr, fmt.Errorf("failed to update sheets link: %w", err))
+	}
+	return syncErr
+}
+
+// push rewrites the Holdings tab and appends the day's performance row
+// unless the day was written already. Days are UTC dates.
+func (s *SheetsService) push(link *models.SheetsLink) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	accessToken, err := s.accessToken(ctx, link)
+	if err != nil {
+		return err
+	}
+	added, err := s.client.EnsureSheets(ctx, accessToken, link.SpreadsheetID, sheetsHoldingsTab, sheetsPerformanceTab)
+	if err != nil {
+		return fmt.Errorf("failed to prepare spreadsheet: %w", err)
+	}
+
+	holdings, err := s.portfolioService.GetHoldingsExport(ctx, link.UserID, link.Currency)
+	if err != nil {
+		return err
+	}
+	header := make([]interface{}, len(holdingsExportHeader))
+	for i, column := range holdingsExportHeader {
+		header[i] = column
+	}
+	rows := [][]interface{}{header}
+	for _, holding := range holdings {
+		rows = append(rows, []interface{}{
+			holding.Symbol, holding.Name, holding.Shares, holding.CostBasis, holding.CurrentValue,
+			holding.GainLoss, holding.GainLossPercent, holding.Currency, holding.AssetStyle, holding.AssetClass,
+		})
+	}
+	if err := s.client.ReplaceValues(ctx, accessToken, link.SpreadsheetID, sheetsHoldingsTab, rows); err != nil {
+		return fmt.Errorf("failed to write holdings: %w", err)
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	if link.LastPerformanceDate == today {
+		return nil
+	}
+	metrics, err := s.analyticsService.GetDashboardMetricsContext(ctx, link.UserID, link.Currency)
+	if err != nil {
+		return err
+	}
+	performance := [][]interface{}{}
+	if slices.Contains(added, sheetsPerformanceTab) {
+		performance = append(performance, sheetsPerformanceHeader)
+	}
+	performance = append(performance, []interface{}{
+		today, metrics.TotalValue, metrics.DayChange, metrics.DayChangePercent,
+		metrics.TotalGain, metrics.PercentageReturn, metrics.Currency,
+	})
+	if err := s.client.AppendValues(ctx, accessToken, link.SpreadsheetID, sheetsPerformanceTab, performance); err != nil {
+		return fmt.Errorf("failed to append performance: %w", err)
+	}
+	link.LastPerformanceDate = today
+	return nil
+}
+
+// accessToken returns the link's access token, refreshing it first when it
+// is about to expire
+func (s *SheetsService) accessToken(ctx context.Context, link *models.SheetsLink) (string, error) {
+	if time.Until(link.TokenExpiry) > time.Minute {
+		return s.cipher.Open(link.AccessToken)
+	}
+	if link.RefreshToken == "" {
+		return "", fmt.Errorf("google granted no refresh token; link the sheet again")
+	}
+
+	refreshToken, err := s.cipher.Open(link.RefreshToken)
+	if err != nil {
+		return "", err
+	}
+	token, err := s.client.Refresh(ctx, refreshToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh Google token: %w", err)
+	}
+	if err := s.setToken(link, token); err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// setToken stores the token on the link, encrypted
+func (s *SheetsService) setToken(link *models.SheetsLink, token *GoogleToken) error {
+	accessToken, err := s.cipher.Seal(token.AccessToken)
+	if err != nil {
+		return err
+	}
+	refreshToken := ""
+	if token.RefreshToken != "" {
+		if refreshToken, err = s.cipher.Seal(token.RefreshToken); err != nil {
+			return err
+		}
+	}
+	link.AccessToken = accessToken
+	link.RefreshToken = refreshToken
+	link.TokenExpiry = token.Expiry
+	return nil
+}
+
+// revoke revokes the link's Google grant. The link is replaced or deleted
+// even if Google can't be reached; the user can remove the app's access
+// from their Google account.
+func (s *SheetsService) revoke(ctx context.Context, link *models.SheetsLink) {
+	if !s.Available() || link.RefreshToken == "" {
+		return
+	}
+	token, err := s.cipher.Open(link.RefreshToken)
+	if err == nil {
+		err = s.client.Revoke(ctx, token)
+	}
+	if err != nil {
+		fmt.Printf("[Sheets] Failed to revoke Google access for link %s: %v\n", link.ID.Hex(), err)
+	}
+}
+
+// findLink loads the user's link
+func (s *SheetsService) findLink(ctx context.Context, userID primitive.ObjectID) (*models.SheetsLink, error) {
+	link, err := s.repos.SheetsLinks.FindByUser(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrSheetsLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sheets link: %w", err)
+	}
+	return link, nil
+}
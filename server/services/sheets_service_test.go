@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTokenCipher(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	cipher, err := NewTokenCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	first, err := cipher.Seal("ya29.token")
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	second, _ := cipher.Seal("ya29.token")
+	if first == second || strings.Contains(first, "ya29") {
+		t.Errorf("Expected distinct ciphertexts hiding the token, got %q and %q", first, second)
+	}
+	if token, err := cipher.Open(first); err != nil || token != "ya29.token" {
+		t.Errorf("Expected the token back, got %q, %v", token, err)
+	}
+
+	other, _ := NewTokenCipher(base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210")))
+	if _, err := other.Open(first); !errors.Is(err, ErrTokenDecrypt) {
+		t.Errorf("Expected ErrTokenDecrypt under another key, got %v", err)
+	}
+	if _, err := NewTokenCipher(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("Expected a short key to be rejected")
+	}
+}
+
+func TestParseSpreadsheetID(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1BxiMVs0XRA5nFMdKvBdBZjgmUUqptlbs74OgvE2upms", "1BxiMVs0XRA5nFMdKvBdBZjgmUUqptlbs74OgvE2upms"},
+		{" https://docs.google.com/spreadsheets/d/1BxiMVs0XRA5nFMd_-/edit#gid=0 ", "1BxiMVs0XRA5nFMd_-"},
+		{"https://example.com/not-a-sheet", ""},
+		{"short", ""},
+	}
+	for _, tt := range tests {
+		got, err := parseSpreadsheetID(tt.input)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidSpreadsheet) {
+				t.Errorf("parseSpreadsheetID(%q): expected ErrInvalidSpreadsheet, got %q, %v", tt.input, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseSpreadsheetID(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+// fakeGoogle serves the OAuth and Sheets endpoints the sheets client calls,
+// keeping the spreadsheet's tabs in memory
+type fakeGoogle struct {
+	mu          sync.Mutex
+	tabs        map[string][][]interface{}
+	accessToken string
+	refreshes   int
+	revoked     []string
+}
+
+func (g *fakeGoogle) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch {
+	case req.URL.Path == "/token":
+		req.ParseForm()
+		switch req.Form.Get("grant_type") {
+		case "authorization_code":
+			if req.Form.Get("code") != "good-code" || req.Form.Get("client_secret") != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_grant","error_description":"Bad Request"}`))
+				return
+			}
+			g.accessToken = "access-1"
+			w.Write([]byte(`{"access_token":"access-1","refresh_token":"refresh-1","expires_in":3600}`))
+		case "refresh_token":
+			g.refreshes++
+			g.accessToken = "access-2"
+			w.Write([]byte(`{"access_token":"access-2","expires_in":3600}`))
+		}
+		return
+	case req.URL.Path == "/revoke":
+		req.ParseForm()
+		g.revoked = append(g.revoked, req.Form.Get("token"))
+		return
+	}
+
+	if req.Header.Get("Authorization") != "Bearer "+g.accessToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"code":401,"message":"Invalid Credentials","status":"UNAUTHENTICATED"}}`))
+		return
+	}
+	var body struct {
+		Requests []struct {
+			AddSheet struct {
+				Properties struct {
+					Title string `json:"title"`
+				} `json:"properties"`
+			} `json:"addSheet"`
+		} `json:"requests"`
+		Values [][]interface{} `json:"values"`
+	}
+	json.NewDecoder(req.Body).Decode(&body)
+
+	path := strings.TrimPrefix(req.URL.Path, "/sheets/sheet-1234567890")
+	tab, _, _ := strings.Cut(strings.TrimPrefix(path, "/values/"), "!")
+	switch {
+	case path == "" && req.Method == http.MethodGet:
+		sheets := []interface{}{}
+		for title := range g.tabs {
+			sheets = append(sheets, map[string]interface{}{"properties": map[string]string{"title": title}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"sheets": sheets})
+	case path == ":batchUpdate":
+		for _, request := range body.Requests {
+			g.tabs[request.AddSheet.Properties.Title] = nil
+		}
+	case strings.HasSuffix(path, ":clear"):
+		g.tabs[strings.TrimSuffix(tab, ":clear")] = nil
+	case req.Method == http.MethodPut:
+		g.tabs[tab] = body.Values
+	case strings.HasSuffix(path, ":append"):
+		g.tabs[tab] = append(g.tabs[tab], body.Values...)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSheetsSync(t *testing.T) {
+	google := &fakeGoogle{tabs: map[string][][]interface{}{"Sheet1": nil}}
+	server := httptest.NewServer(google)
+	defer server.Close()
+
+	client := NewGoogleSheetsClient("client", "secret", "https://app.example.com/settings/sheets")
+	client.tokenURL = server.URL + "/token"
+	client.revokeURL = server.URL + "/revoke"
+	client.sheetsURL = server.URL + "/sheets"
+	cipher, err := NewTokenCipher(base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	provider := NewFixtureProvider().SetQuote("AAPL", "Apple Inc.", 110, "USD")
+	repos := repository.NewMemory()
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repos)
+	analyticsService := NewAnalyticsService(portfolioService, provider, provider)
+	service := NewSheetsService(analyticsService, client, cipher)
+	userID := primitive.NewObjectID()
+	if err := portfolioService.AddTransaction(userID, &models.Transaction{
+		Symbol: "AAPL", Action: "buy", Shares: 10, Price: 100, Currency: "USD", Date: time.Now().AddDate(0, 0, -5),
+	}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+
+	unavailable := NewSheetsService(analyticsService, nil, nil)
+	if _, err := unavailable.StartLink(userID, models.SheetsLinkRequest{Spreadsheet: "sheet-1234567890"}); !errors.Is(err, ErrSheetsUnavailable) {
+		t.Errorf("Expected ErrSheetsUnavailable, got %v", err)
+	}
+
+	start, err := service.StartLink(userID, models.SheetsLinkRequest{Spreadsheet: "https://docs.google.com/spreadsheets/d/sheet-1234567890/edit"})
+	if err != nil {
+		t.Fatalf("StartLink failed: %v", err)
+	}
+	if start.Link.SpreadsheetID != "sheet-1234567890" || start.Link.Status != models.SheetsLinkPending || start.Link.Currency != "USD" {
+		t.Errorf("Unexpected pending link %+v", start.Link)
+	}
+	if !strings.Contains(start.AuthURL, "access_type=offline") || !strings.Contains(start.AuthURL, "state="+start.Link.State) {
+		t.Errorf("Unexpected consent URL %s", start.AuthURL)
+	}
+	if _, err := service.SyncNow(userID); !errors.Is(err, ErrSheetsLinkPending) {
+		t.Errorf("Expected ErrSheetsLinkPending, got %v", err)
+	}
+	if _, err := service.CompleteLink(userID, models.SheetsAuthorizeRequest{Code: "good-code", State: "forged"}); !errors.Is(err, ErrInvalidSheetsState) {
+		t.Errorf("Expected ErrInvalidSheetsState, got %v", err)
+	}
+
+	link, err := service.CompleteLink(userID, models.SheetsAuthorizeRequest{Code: "good-code", State: start.Link.State})
+	if err != nil {
+		t.Fatalf("CompleteLink failed: %v", err)
+	}
+	if link.Status != models.SheetsLinkActive || link.LastError != "" {
+		t.Fatalf("Expected the link synced, got status %s: %s", link.Status, link.LastError)
+	}
+	stored, _ := repos.SheetsLinks.FindByUser(context.Background(), userID)
+	if stored.AccessToken == "access-1" || stored.RefreshToken == "refresh-1" {
+		t.Error("Expected the tokens stored encrypted")
+	}
+	if token, err := cipher.Open(stored.RefreshToken); err != nil || token != "refresh-1" {
+		t.Errorf("Expected the refresh token to decrypt, got %q, %v", token, err)
+	}
+
+	holdings := google.tabs["Holdings"]
+	if len(holdings) != 2 || holdings[0][0] != "symbol" || holdings[1][0] != "AAPL" || holdings[1][4] != 1100.0 {
+		t.Errorf("Unexpected holdings tab %v", holdings)
+	}
+	performance := google.tabs["Performance"]
+	today := time.Now().UTC().Format("2006-01-02")
+	if len(performance) != 2 || performance[0][0] != "date" || performance[1][0] != today || performance[1][1] != 1100.0 {
+		t.Errorf("Unexpected performance tab %v", performance)
+	}
+
+	// A second sync the same day refreshes the holdings only
+	if _, err := service.SyncNow(userID); err != nil {
+		t.Fatalf("SyncNow failed: %v", err)
+	}
+	if len(google.tabs["Holdings"]) != 2 || len(google.tabs["Performance"]) != 2 {
+		t.Errorf("Expected no new performance row, got %v", google.tabs["Performance"])
+	}
+
+	// An expired token is refreshed, and the next day gets its row
+	stored, _ = repos.SheetsLinks.FindByUser(context.Background(), userID)
+	stored.TokenExpiry = time.Now().Add(-time.Hour)
+	stored.LastPerformanceDate = "2000-01-01"
+	repos.SheetsLinks.Update(context.Background(), stored)
+	if err := service.SyncAll(); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	if google.refreshes != 1 || len(google.tabs["Performance"]) != 3 || google.tabs["Performance"][2][0] != today {
+		t.Errorf("Expected a refreshed token and a new row, got %d refreshes and %v", google.refreshes, google.tabs["Performance"])
+	}
+	stored, _ = repos.SheetsLinks.FindByUser(context.Background(), userID)
+	if token, err := cipher.Open(stored.AccessToken); err != nil || token != "access-2" {
+		t.Errorf("Expected the refreshed token stored, got %q, %v", token, err)
+	}
+
+	// A revoked grant is recorded on the link
+	google.accessToken = "revoked"
+	if _, err := service.SyncNow(userID); !errors.Is(err, ErrSheetsSyncFailed) {
+		t.Errorf("Expected ErrSheetsSyncFailed, got %v", err)
+	}
+	stored, _ = repos.SheetsLinks.FindByUser(context.Background(), userID)
+	if stored.Status != models.SheetsLinkError || !strings.Contains(stored.LastError, "UNAUTHENTICATED") {
+		t.Errorf("Expected the failure recorded, got status %s: %s", stored.Status, stored.LastError)
+	}
+
+	if err := service.Unlink(userID); err != nil {
+		t.Fatalf("Unlink failed: %v", err)
+	}
+	if len(google.revoked) != 1 || google.revoked[0] != "refresh-1" {
+		t.Errorf("Expected the refresh token revoked, got %v", google.revoked)
+	}
+	if link, err := service.GetLink(userID); err != nil || link != nil {
+		t.Errorf("Expected no link after unlinking, got %+v, %v", link, err)
+	}
+	if err := service.Unlink(userID); !errors.Is(err, ErrSheetsLinkNotFound) {
+		t.Errorf("Expected ErrSheetsLinkNotFound, got %v", err)
+	}
+}
@@ -1,13 +1,19 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/internal/analytics/engine"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/telemetry"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,6 +24,30 @@ var (
 	ErrInvalidPeriod    = errors.New("invalid period parameter")
 )
 
+// Price sources reported on quotes
+const (
+	PriceSourceYahoo     = "yahoo"
+	PriceSourceEastmoney = "eastmoney"
+	// PriceSourceLastKnown is the last price the providers reported for a
+	// symbol they no longer find
+	PriceSourceLastKnown = "last-known"
+	// PriceSourceIntrinsic is an option's intrinsic value from its
+	// underlying's price
+	PriceSourceIntrinsic = "intrinsic"
+	// PriceSourceFixed is a price that isn't quoted, such as cash at 1
+	PriceSourceFixed   = "fixed"
+	PriceSourceFixture = "fixture"
+	// PriceSourceManual is a price the user set with a price override
+	PriceSourceManual = "manual"
+)
+
+// PriceSource tells where a price came from and how fresh it is
+type PriceSource struct {
+	Source string     `json:"source,omitempty"` // Such as PriceSourceYahoo
+	AsOf   *time.Time `json:"asOf,omitempty"`   // When the price was fetched from Source
+	Cached bool       `json:"cached"`           // Served from the quote cache rather than fetched for this request
+}
+
 // StockInfo represents stock information
 type StockInfo struct {
 	Symbol       string  `json:"symbol"`
@@ -25,67 +55,136 @@ type StockInfo struct {
 	CurrentPrice float64 `json:"currentPrice"`
 	Currency     string  `json:"currency"`
 	Sector       string  `json:"sector,omitempty"`
+	Exchange     string  `json:"exchange,omitempty"`
+	Type         string  `json:"type,omitempty"` // Instrument type such as EQUITY or ETF
+
+	// 52-week trading range, when the provider reports it
+	FiftyTwoWeekHigh float64 `json:"fiftyTwoWeekHigh,omitempty"`
+	FiftyTwoWeekLow  float64 `json:"fiftyTwoWeekLow,omitempty"`
+
+	// Official close of the previous trading session, when the provider reports it
+	PreviousClose float64 `json:"previousClose,omitempty"`
+
+	// PriceAsOf is set when the providers no longer find the symbol and
+	// CurrentPrice is the last price they reported, at this time. Delisted
+	// is set once they have not found it for days.
+	PriceAsOf *time.Time `json:"priceAsOf,omitempty"`
+	Delisted  bool       `json:"delisted,omitempty"`
+
+	// Option is set for option contracts, whose price is the premium per share
+	Option *models.OptionContract `json:"option,omitempty"`
+
+	// Session is the trading session in progress when the quote was fetched,
+	// such as SessionPre, when the provider reports the trading day's
+	// sessions. ExtendedHours is the current trading day's latest pre-market
+	// or after-hours trade, which CurrentPrice leaves out, outside the
+	// regular session.
+	Session       string              `json:"session,omitempty"`
+	ExtendedHours *ExtendedHoursQuote `json:"extendedHours,omitempty"`
+
+	PriceSource
 }
 
 // HistoricalPrice represents a historical price data point
-type HistoricalPrice struct {
-	Date  time.Time `json:"date"`
-	Price float64   `json:"price"`
-}
-
-// CachedStockData represents cached stock information with expiration
-type CachedStockData struct {
-	Data      *StockInfo
-	ExpiresAt time.Time
+type HistoricalPrice = engine.Price
+
+// AdjustedHistory returns the prices with each close replaced by its
+// dividend-adjusted close where the provider reports one, so returns measured
+// over the series are total returns. Eastmoney history is already adjusted.
+func AdjustedHistory(prices []HistoricalPrice) []HistoricalPrice {
+	adjusted := make([]HistoricalPrice, len(prices))
+	for i, price := range prices {
+		if price.AdjustedPrice > 0 {
+			price.Price = price.AdjustedPrice
+		}
+		adjusted[i] = price
+	}
+	return adjusted
 }
 
-// CachedHistoricalData represents cached historical data with expiration
-type CachedHistoricalData struct {
-	Data      []HistoricalPrice
-	ExpiresAt time.Time
+// StockAPIConfig configures the market data providers. Zero fields use the
+// defaults.
+type StockAPIConfig struct {
+	YahooTimeout     time.Duration // Default 30s
+	EastmoneyTimeout time.Duration // Default 10s
+	YahooRate        float64       // Requests per second, default 5
+	EastmoneyRate    float64       // Requests per second, default 10
+	CacheTTL         time.Duration // Quotes and price history, default 5m
+
+	// Catalog records the metadata of quoted symbols and supplies their
+	// currency and sector; nil disables it
+	Catalog *SymbolCatalog
+
+	// Entries kept per cache before the least recently used is evicted.
+	// Quotes, dividends and fundamentals share QuoteCacheSize, default 5000;
+	// price histories are larger, default 1000.
+	QuoteCacheSize   int
+	HistoryCacheSize int
 }
 
 // StockAPIService handles stock data operations
 type StockAPIService struct {
 	httpClient           *http.Client
-	stockCache           map[string]*CachedStockData
-	historicalCache      map[string]*CachedHistoricalData
-	cacheMutex           sync.RWMutex
+	eastmoneyClient      *http.Client
+	stockCache           *lruCache[*StockInfo]
+	historicalCache      *lruCache[*historySeries] // One series per symbol, shared by every period
+	dividendCache        *lruCache[[]DividendEvent]
+	fundamentalsCache    *lruCache[*Fundamentals]
+	cacheMutex           sync.RWMutex // Guards eastmoneyPreferred
 	stockCacheDuration   time.Duration
+	cacheVersion         atomic.Uint64
+	eastmoneyPreferred   map[string]time.Time // Symbols served from Eastmoney until the given time
+	catalog              *SymbolCatalog
 }
 
 // NewStockAPIService creates a new StockAPIService instance
-func NewStockAPIService() *StockAPIService {
+func NewStockAPIService(config StockAPIConfig) *StockAPIService {
+	if config.YahooTimeout <= 0 {
+		config.YahooTimeout = 30 * time.Second
+	}
+	if config.EastmoneyTimeout <= 0 {
+		config.EastmoneyTimeout = 10 * time.Second
+	}
+	if config.YahooRate <= 0 {
+		config.YahooRate = 5
+	}
+	if config.EastmoneyRate <= 0 {
+		config.EastmoneyRate = 10
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = 5 * time.Minute
+	}
+	if config.QuoteCacheSize <= 0 {
+		config.QuoteCacheSize = 5000
+	}
+	if config.HistoryCacheSize <= 0 {
+		config.HistoryCacheSize = 1000
+	}
+
 	return &StockAPIService{
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   config.YahooTimeout,
+			Transport: rateLimited(telemetry.Transport(nil), config.YahooRate),
+		},
+		eastmoneyClient: &http.Client{
+			Timeout:   config.EastmoneyTimeout,
+			Transport: rateLimited(telemetry.Transport(nil), config.EastmoneyRate),
 		},
-		stockCache:         make(map[string]*CachedStockData),
-		historicalCache:    make(map[string]*CachedHistoricalData),
-		stockCacheDuration: 5 * time.Minute,
+		stockCache:         newLRUCache[*StockInfo](config.QuoteCacheSize),
+		historicalCache:    newLRUCache[*historySeries](config.HistoryCacheSize),
+		dividendCache:      newLRUCache[[]DividendEvent](config.QuoteCacheSize),
+		fundamentalsCache:  newLRUCache[*Fundamentals](config.QuoteCacheSize),
+		stockCacheDuration: config.CacheTTL,
+		eastmoneyPreferred: make(map[string]time.Time),
+		catalog:            config.Catalog,
 	}
 }
 
 // IsUSStock checks if a symbol is a US stock
-// US stocks have no suffix or common US patterns
+// US stocks have no exchange suffix, apart from share classes such as BRK.B
 func (s *StockAPIService) IsUSStock(symbol string) bool {
-	symbol = strings.ToUpper(strings.TrimSpace(symbol))
-	
-	// Check if it has Chinese exchange suffixes
-	if strings.HasSuffix(symbol, ".SS") || strings.HasSuffix(symbol, ".SZ") {
-		return false
-	}
-	
-	// Check if it has other common non-US suffixes
-	nonUSSuffixes := []string{".HK", ".L", ".T", ".TO", ".AX", ".PA", ".DE"}
-	for _, suffix := range nonUSSuffixes {
-		if strings.HasSuffix(symbol, suffix) {
-			return false
-		}
-	}
-	
-	// If no suffix or only contains letters (typical US pattern), consider it US
-	return true
+	market := MarketForSymbol(symbol)
+	return market != nil && market.Code == "US"
 }
 
 // IsChinaStock checks if a symbol is a Chinese stock
@@ -110,7 +209,7 @@ func (s *StockAPIService) getCashInfo(symbol string) *StockInfo {
 		currency = "USD"
 		name = "Cash - USD"
 	} else {
-		currency = "CNY" // RMB uses CNY currency code
+		currency = currencycode.RMB
 		name = "Cash - RMB"
 	}
 	
@@ -119,6 +218,7 @@ func (s *StockAPIService) getCashInfo(symbol string) *StockInfo {
 		Name:         name,
 		CurrentPrice: 1.0,
 		Currency:     currency,
+		PriceSource:  PriceSource{Source: PriceSourceFixed},
 	}
 }
 
@@ -132,40 +232,56 @@ type yahooChartResponse struct {
 				RegularMarketPrice float64 `json:"regularMarketPrice"`
 				LongName           string  `json:"longName"`
 				ShortName          string  `json:"shortName"`
+				FullExchangeName   string  `json:"fullExchangeName"`
+				InstrumentType     string  `json:"instrumentType"`
+				FiftyTwoWeekHigh   float64 `json:"fiftyTwoWeekHigh"`
+				FiftyTwoWeekLow    float64 `json:"fiftyTwoWeekLow"`
+
+				// Close of the session before the latest one. Unlike
+				// chartPreviousClose, neither depends on the requested range.
+				RegularMarketPreviousClose float64 `json:"regularMarketPreviousClose"`
+				PreviousClose              float64 `json:"previousClose"`
+
+				// Extended-hours trades of US listings, with their Unix times
+				CurrentTradingPeriod yahooTradingPeriods `json:"currentTradingPeriod"`
+				PreMarketPrice       float64             `json:"preMarketPrice"`
+				PreMarketTime        int64               `json:"preMarketTime"`
+				PostMarketPrice      float64             `json:"postMarketPrice"`
+				PostMarketTime       int64               `json:"postMarketTime"`
 			} `json:"meta"`
 			Timestamp  []int64 `json:"timestamp"`
 			Indicators struct {
 				Quote []struct {
 					Close []float64 `json:"close"`
 				} `json:"quote"`
+				AdjClose []struct {
+					AdjClose []float64 `json:"adjclose"`
+				} `json:"adjclose"`
 			} `json:"indicators"`
+			Events struct {
+				Dividends map[string]struct {
+					Amount float64 `json:"amount"`
+					Date   int64   `json:"date"`
+				} `json:"dividends"`
+			} `json:"events"`
 		} `json:"result"`
 		Error interface{} `json:"error"`
 	} `json:"chart"`
 }
 
-// Eastmoney API response structures
-type eastmoneyResponse struct {
-	Data struct {
-		F58 string `json:"f58"` // 股票名称
-	} `json:"data"`
-	RC  int    `json:"rc"`  // 返回码，0 表示成功
-	RT  int    `json:"rt"`  // 响应类型
-	Msg string `json:"msg"` // 消息
-}
 
 
 
 // fetchFromYahooChart calls Yahoo Finance Chart API with the specified parameters
-func (s *StockAPIService) fetchFromYahooChart(symbol string, period1, period2 int64) (*yahooChartResponse, error) {
+func (s *StockAPIService) fetchFromYahooChart(ctx context.Context, symbol string, period1, period2 int64) (*yahooChartResponse, error) {
 	url := fmt.Sprintf(
-		"https://query1.finance.yahoo.com/v8/finance/chart/%s?period1=%d&period2=%d&interval=1d",
+		"https://query1.finance.yahoo.com/v8/finance/chart/%s?period1=%d&period2=%d&interval=1d&events=div",
 		symbol, period1, period2,
 	)
 	
 	fmt.Printf("[StockAPI] HTTP GET: %s\n", url)
 	
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		fmt.Printf("[StockAPI] ERROR: Failed to create HTTP request: %v\n", err)
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -185,6 +301,12 @@ func (s *StockAPIService) fetchFromYahooChart(symbol string, period1, period2 in
 	
 	fmt.Printf("[StockAPI] HTTP response received in %v, status: %d\n", duration, resp.StatusCode)
 	
+	// Yahoo Finance answers 404 for symbols it does not know, including
+	// delisted ones
+	if resp.StatusCode == http.StatusNotFound {
+		fmt.Printf("[StockAPI] ERROR: Yahoo Finance does not know symbol %s\n", symbol)
+		return nil, ErrStockNotFound
+	}
 	if resp.StatusCode != http.StatusOK {
 		fmt.Printf("[StockAPI] ERROR: Non-OK status code: %d\n", resp.StatusCode)
 		return nil, fmt.Errorf("%w: status code %d", ErrExternalAPI, resp.StatusCode)
@@ -249,21 +371,45 @@ func (s *StockAPIService) extractStockInfo(response *yahooChartResponse) (*Stock
 		name = meta.Symbol
 	}
 	
-	// Get currency from meta, or infer from symbol suffix
-	currency := strings.ToUpper(meta.Currency)
+	// Get currency from meta, or infer from the symbol's exchange. Prices
+	// quoted in minor units such as pence are converted to the major unit.
+	price := meta.RegularMarketPrice
+	high, low := meta.FiftyTwoWeekHigh, meta.FiftyTwoWeekLow
+	previousClose := meta.RegularMarketPreviousClose
+	if previousClose <= 0 {
+		previousClose = meta.PreviousClose
+	}
+	prePrice, postPrice := meta.PreMarketPrice, meta.PostMarketPrice
+	currency := currencycode.Normalize(meta.Currency)
+	if major, ok := minorCurrencyUnits[meta.Currency]; ok {
+		currency = major
+		price /= 100
+		high /= 100
+		low /= 100
+		previousClose /= 100
+		prePrice /= 100
+		postPrice /= 100
+	}
 	if currency == "" {
-		if strings.HasSuffix(meta.Symbol, ".SS") || strings.HasSuffix(meta.Symbol, ".SZ") {
-			currency = "CNY"
-		} else {
-			currency = "USD"
-		}
+		currency = s.SymbolCurrency(meta.Symbol)
 	}
 	
+	now := time.Now()
+	periods := meta.CurrentTradingPeriod
+	session := periods.session(now)
 	return &StockInfo{
-		Symbol:       meta.Symbol,
-		Name:         name,
-		CurrentPrice: meta.RegularMarketPrice,
-		Currency:     currency,
+		Symbol:           meta.Symbol,
+		Name:             name,
+		CurrentPrice:     price,
+		Currency:         currency,
+		Exchange:         meta.FullExchangeName,
+		Type:             meta.InstrumentType,
+		FiftyTwoWeekHigh: high,
+		FiftyTwoWeekLow:  low,
+		PreviousClose:    previousClose,
+		Session:          session,
+		ExtendedHours:    newExtendedHoursQuote(session, periods.dayStart(), price, prePrice, meta.PreMarketTime, postPrice, meta.PostMarketTime),
+		PriceSource:      PriceSource{Source: PriceSourceYahoo, AsOf: &now},
 	}, nil
 }
 
@@ -281,6 +427,16 @@ func (s *StockAPIService) extractHistoricalData(response *yahooChartResponse) ([
 	
 	timestamps := result.Timestamp
 	closes := result.Indicators.Quote[0].Close
+	var adjCloses []float64
+	if len(result.Indicators.AdjClose) > 0 && len(result.Indicators.AdjClose[0].AdjClose) == len(closes) {
+		adjCloses = result.Indicators.AdjClose[0].AdjClose
+	}
+	
+	// Prices quoted in minor units such as pence are converted to the major unit
+	scale := 1.0
+	if _, ok := minorCurrencyUnits[result.Meta.Currency]; ok {
+		scale = 100
+	}
 	
 	// Verify arrays have matching lengths
 	if len(timestamps) != len(closes) {
@@ -294,10 +450,14 @@ func (s *StockAPIService) extractHistoricalData(response *yahooChartResponse) ([
 			continue
 		}
 		
-		historicalData = append(historicalData, HistoricalPrice{
+		price := HistoricalPrice{
 			Date:  time.Unix(timestamps[i], 0),
-			Price: closes[i],
-		})
+			Price: closes[i] / scale,
+		}
+		if adjCloses != nil {
+			price.AdjustedPrice = adjCloses[i] / scale
+		}
+		historicalData = append(historicalData, price)
 	}
 	
 	return historicalData, nil
@@ -326,6 +486,11 @@ func (s *StockAPIService) convertToEastmoneySecID(symbol string) (string, error)
 		marketCode = "1" // Shanghai Stock Exchange
 	case "SZ":
 		marketCode = "0" // Shenzhen Stock Exchange
+	case "HK":
+		marketCode = "116" // Hong Kong Stock Exchange, five-digit codes
+		if len(stockCode) < 5 {
+			stockCode = strings.Repeat("0", 5-len(stockCode)) + stockCode
+		}
 	default:
 		fmt.Printf("[StockAPI] ERROR: Unsupported exchange suffix for Eastmoney: %s\n", suffix)
 		return "", fmt.Errorf("unsupported exchange suffix: %s", suffix)
@@ -337,88 +502,6 @@ func (s *StockAPIService) convertToEastmoneySecID(symbol string) (string, error)
 	return secid, nil
 }
 
-// fetchStockNameFromEastmoney fetches stock name from Eastmoney API for Chinese stocks
-func (s *StockAPIService) fetchStockNameFromEastmoney(symbol string) (string, error) {
-	fmt.Printf("[StockAPI] Fetching stock name from Eastmoney for symbol: %s\n", symbol)
-	
-	// Convert symbol to Eastmoney secid format
-	secid, err := s.convertToEastmoneySecID(symbol)
-	if err != nil {
-		fmt.Printf("[StockAPI] ERROR: Failed to convert symbol to secid: %v\n", err)
-		return "", err
-	}
-	
-	// Build request URL
-	url := fmt.Sprintf("http://push2.eastmoney.com/api/qt/stock/get?secid=%s&fields=f58", secid)
-	fmt.Printf("[StockAPI] Eastmoney HTTP GET: %s\n", url)
-	
-	// Create HTTP request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		fmt.Printf("[StockAPI] ERROR: Failed to create Eastmoney HTTP request: %v\n", err)
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-	
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
-	
-	// Create a client with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	
-	// Execute request
-	startTime := time.Now()
-	resp, err := client.Do(req)
-	duration := time.Since(startTime)
-	
-	if err != nil {
-		fmt.Printf("[StockAPI] ERROR: Eastmoney HTTP request failed after %v: %v\n", duration, err)
-		return "", fmt.Errorf("%w: %v", ErrExternalAPI, err)
-	}
-	defer resp.Body.Close()
-	
-	fmt.Printf("[StockAPI] Eastmoney HTTP response received in %v, status: %d\n", duration, resp.StatusCode)
-	
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("[StockAPI] ERROR: Eastmoney non-OK status code: %d\n", resp.StatusCode)
-		return "", fmt.Errorf("%w: status code %d", ErrExternalAPI, resp.StatusCode)
-	}
-	
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("[StockAPI] ERROR: Failed to read Eastmoney response body: %v\n", err)
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-	
-	fmt.Printf("[StockAPI] Eastmoney response body size: %d bytes\n", len(body))
-	
-	// Parse JSON response
-	var eastmoneyResp eastmoneyResponse
-	if err := json.Unmarshal(body, &eastmoneyResp); err != nil {
-		fmt.Printf("[StockAPI] ERROR: Failed to parse Eastmoney JSON response: %v\n", err)
-		fmt.Printf("[StockAPI] Response body preview: %s\n", string(body[:min(len(body), 500)]))
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-	
-	// Check return code
-	if eastmoneyResp.RC != 0 {
-		fmt.Printf("[StockAPI] ERROR: Eastmoney API returned error code: %d, message: %s\n", 
-			eastmoneyResp.RC, eastmoneyResp.Msg)
-		return "", fmt.Errorf("eastmoney API error: %s", eastmoneyResp.Msg)
-	}
-	
-	// Extract stock name
-	stockName := strings.TrimSpace(eastmoneyResp.Data.F58)
-	if stockName == "" {
-		fmt.Printf("[StockAPI] WARNING: Eastmoney returned empty stock name for %s\n", symbol)
-		return "", fmt.Errorf("empty stock name returned")
-	}
-	
-	fmt.Printf("[StockAPI] Successfully fetched stock name from Eastmoney: %s -> %s\n", symbol, stockName)
-	
-	return stockName, nil
-}
 
 
 
@@ -434,87 +517,129 @@ func (s *StockAPIService) fetchStockNameFromEastmoney(symbol string) (string, er
 
 
 
-// getCachedStockInfo retrieves stock info from cache if available and not expired
+// getCachedStockInfo retrieves stock info from cache if available and not
+// expired, as a copy marked cached
 func (s *StockAPIService) getCachedStockInfo(symbol string) (*StockInfo, bool) {
-	s.cacheMutex.RLock()
-	defer s.cacheMutex.RUnlock()
-	
-	cached, exists := s.stockCache[symbol]
-	if !exists {
-		return nil, false
-	}
-	
-	if time.Now().After(cached.ExpiresAt) {
+	cached, found := s.stockCache.Get(symbol, time.Now())
+	if !found {
 		return nil, false
 	}
-	
-	return cached.Data, true
+	info := *cached
+	info.Cached = true
+	return &info, true
 }
 
 // setCachedStockInfo stores stock info in cache with expiration
 func (s *StockAPIService) setCachedStockInfo(symbol string, info *StockInfo) {
-	s.cacheMutex.Lock()
-	defer s.cacheMutex.Unlock()
-	
-	s.stockCache[symbol] = &CachedStockData{
-		Data:      info,
-		ExpiresAt: time.Now().Add(s.stockCacheDuration),
-	}
+	s.stockCache.Set(symbol, info, time.Now().Add(s.stockCacheDuration))
+	s.cacheVersion.Add(1)
 }
 
-// getCachedHistoricalData retrieves historical data from cache if available and not expired
-func (s *StockAPIService) getCachedHistoricalData(cacheKey string) ([]HistoricalPrice, bool) {
-	s.cacheMutex.RLock()
-	defer s.cacheMutex.RUnlock()
-	
-	cached, exists := s.historicalCache[cacheKey]
-	if !exists {
+// getCachedHistoricalData returns the cached prices of a symbol from start on,
+// if its series has not expired and was fetched for a window covering start
+func (s *StockAPIService) getCachedHistoricalData(symbol string, start time.Time) ([]HistoricalPrice, bool) {
+	series, found := s.historicalCache.Get(symbol, time.Now())
+	if !found || !series.covers(start) {
 		return nil, false
 	}
-	
-	if time.Now().After(cached.ExpiresAt) {
-		return nil, false
+	return series.since(start), true
+}
+
+// setCachedHistoricalData caches the prices fetched for a symbol's window
+// starting at start, replacing any shorter series, and returns them as they
+// will be read back from the cache
+func (s *StockAPIService) setCachedHistoricalData(symbol string, start time.Time, data []HistoricalPrice) []HistoricalPrice {
+	series := newHistorySeries(start, data)
+	s.historicalCache.Set(symbol, series, time.Now().Add(s.stockCacheDuration))
+	s.cacheVersion.Add(1)
+	return series.since(start)
+}
+
+// CacheStats reports the size and hit rate of each market data cache
+func (s *StockAPIService) CacheStats() map[string]CacheStats {
+	return map[string]CacheStats{
+		"quotes":       s.stockCache.Stats(),
+		"history":      s.historicalCache.Stats(),
+		"dividends":    s.dividendCache.Stats(),
+		"fundamentals": s.fundamentalsCache.Stats(),
 	}
-	
-	return cached.Data, true
 }
 
-// setCachedHistoricalData stores historical data in cache with expiration
-func (s *StockAPIService) setCachedHistoricalData(cacheKey string, data []HistoricalPrice) {
+// FlushCaches empties every market data cache, so the next request for any
+// symbol is fetched from the providers, and returns how many entries it
+// dropped. Eastmoney preferences are reset too.
+func (s *StockAPIService) FlushCaches() int {
+	removed := s.stockCache.Clear() + s.historicalCache.Clear() + s.dividendCache.Clear() + s.fundamentalsCache.Clear()
+
 	s.cacheMutex.Lock()
-	defer s.cacheMutex.Unlock()
-	
-	s.historicalCache[cacheKey] = &CachedHistoricalData{
-		Data:      data,
-		ExpiresAt: time.Now().Add(s.stockCacheDuration),
+	s.eastmoneyPreferred = make(map[string]time.Time)
+	s.cacheMutex.Unlock()
+
+	s.cacheVersion.Add(1)
+	return removed
+}
+
+// CacheVersion returns a value that changes whenever fresh price data is cached
+// or cached prices expire. It is used to fingerprint price-dependent responses.
+func (s *StockAPIService) CacheVersion() string {
+	// Include the current cache window so entries expiring without being
+	// refreshed still produce a new version
+	window := int64(0)
+	if s.stockCacheDuration > 0 {
+		window = time.Now().UnixNano() / int64(s.stockCacheDuration)
 	}
+	return fmt.Sprintf("%d-%d", s.cacheVersion.Load(), window)
 }
 
 // cleanupExpiredCache removes expired entries from cache
 func (s *StockAPIService) cleanupExpiredCache() {
-	s.cacheMutex.Lock()
-	defer s.cacheMutex.Unlock()
-	
 	now := time.Now()
-	
-	// Clean stock info cache
-	for symbol, cached := range s.stockCache {
-		if now.After(cached.ExpiresAt) {
-			delete(s.stockCache, symbol)
-		}
+	s.stockCache.RemoveExpired(now)
+	s.historicalCache.RemoveExpired(now)
+	s.dividendCache.RemoveExpired(now)
+	s.fundamentalsCache.RemoveExpired(now)
+}
+
+// GetStockInfo fetches stock information with caching
+func (s *StockAPIService) GetStockInfo(symbol string) (*StockInfo, error) {
+	return s.GetStockInfoContext(context.Background(), symbol)
+}
+
+// GetStockInfoContext is GetStockInfo with the external calls made under ctx,
+// so they are cancelled and traced with the request. When the providers no
+// longer find a symbol they quoted before, its last known price is returned
+// with PriceAsOf set.
+func (s *StockAPIService) GetStockInfoContext(ctx context.Context, symbol string) (*StockInfo, error) {
+	info, err := s.getStockInfo(ctx, symbol)
+	if !errors.Is(err, ErrStockNotFound) {
+		return info, err
 	}
 	
-	// Clean historical data cache
-	for key, cached := range s.historicalCache {
-		if now.After(cached.ExpiresAt) {
-			delete(s.historicalCache, key)
-		}
+	metadata, ok := s.catalog.RecordMissing(ctx, s.catalog.Resolve(symbol))
+	if !ok {
+		return nil, err
 	}
+	fmt.Printf("[StockAPI] %s not found, using last known price %.2f from %s\n", 
+		metadata.Symbol, metadata.LastPrice, metadata.LastPriceAt.Format(time.RFC3339))
+	info = &StockInfo{
+		Symbol:       metadata.Symbol,
+		Name:         metadata.Name,
+		CurrentPrice: metadata.LastPrice,
+		Currency:     metadata.Currency,
+		Sector:       metadata.Sector,
+		Exchange:     metadata.Exchange,
+		Type:         metadata.Type,
+		PriceAsOf:    metadata.LastPriceAt,
+		Delisted:     metadata.DelistedAt != nil,
+		PriceSource:  PriceSource{Source: PriceSourceLastKnown, AsOf: metadata.LastPriceAt},
+	}
+	s.setCachedStockInfo(metadata.Symbol, info)
+	return info, nil
 }
 
-// GetStockInfo fetches stock information with caching
-func (s *StockAPIService) GetStockInfo(symbol string) (*StockInfo, error) {
-	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+// getStockInfo quotes a symbol from the cache or the providers
+func (s *StockAPIService) getStockInfo(ctx context.Context, symbol string) (*StockInfo, error) {
+	symbol = s.catalog.Resolve(symbol)
 	
 	fmt.Printf("[StockAPI] GetStockInfo called for symbol: %s\n", symbol)
 	
@@ -529,6 +654,11 @@ func (s *StockAPIService) GetStockInfo(symbol string) (*StockInfo, error) {
 		return s.getCashInfo(symbol), nil
 	}
 	
+	// Options are priced from their own quote or the underlying's
+	if contract, ok := models.ParseOptionSymbol(symbol); ok {
+		return s.getOptionInfo(ctx, symbol, contract)
+	}
+	
 	// Check cache first
 	if cached, found := s.getCachedStockInfo(symbol); found {
 		fmt.Printf("[StockAPI] Cache HIT for %s (price: %.2f)\n", symbol, cached.CurrentPrice)
@@ -540,14 +670,14 @@ func (s *StockAPIService) GetStockInfo(symbol string) (*StockInfo, error) {
 	endTime := time.Now()
 	startTime := endTime.AddDate(0, 0, -1)
 	
-	// Check if it's a Chinese stock
-	isChinaStock := s.IsChinaStock(symbol)
+	// Check if Eastmoney covers the symbol (Chinese and Hong Kong stocks)
+	hasEastmoneyData := s.hasEastmoneyData(symbol)
 	
 	var info *StockInfo
 	
-	if isChinaStock {
-		// For Chinese stocks, fetch from both Yahoo Finance and Eastmoney concurrently
-		fmt.Printf("[StockAPI] Chinese stock detected: %s, fetching from both Yahoo Finance and Eastmoney\n", symbol)
+	if hasEastmoneyData {
+		// For Chinese and Hong Kong stocks, fetch from both Yahoo Finance and Eastmoney concurrently
+		fmt.Printf("[StockAPI] Chinese or Hong Kong stock detected: %s, fetching from both Yahoo Finance and Eastmoney\n", symbol)
 		
 		// Create channels for concurrent API calls
 		type yahooResult struct {
@@ -555,17 +685,24 @@ func (s *StockAPIService) GetStockInfo(symbol string) (*StockInfo, error) {
 			err  error
 		}
 		type eastmoneyResult struct {
-			name string
+			info *StockInfo
 			err  error
 		}
 		
 		yahooChan := make(chan yahooResult, 1)
 		eastmoneyChan := make(chan eastmoneyResult, 1)
 		
+		// Skip Yahoo Finance while it is failing for this symbol
+		preferEastmoney := s.prefersEastmoney(symbol)
+		
 		// Fetch from Yahoo Finance concurrently
 		go func() {
+			if preferEastmoney {
+				yahooChan <- yahooResult{nil, fmt.Errorf("%w: Yahoo Finance skipped for %s", ErrExternalAPI, symbol)}
+				return
+			}
 			fmt.Printf("[StockAPI] [Goroutine] Calling Yahoo Finance API for %s\n", symbol)
-			response, err := s.fetchFromYahooChart(symbol, startTime.Unix(), endTime.Unix())
+			response, err := s.fetchFromYahooChart(ctx, symbol, startTime.Unix(), endTime.Unix())
 			if err != nil {
 				fmt.Printf("[StockAPI] [Goroutine] Yahoo Finance API call failed: %v\n", err)
 				yahooChan <- yahooResult{nil, err}
@@ -586,46 +723,57 @@ func (s *StockAPIService) GetStockInfo(symbol string) (*StockInfo, error) {
 		// Fetch from Eastmoney concurrently
 		go func() {
 			fmt.Printf("[StockAPI] [Goroutine] Calling Eastmoney API for %s\n", symbol)
-			name, err := s.fetchStockNameFromEastmoney(symbol)
+			quote, err := s.fetchQuoteFromEastmoney(ctx, symbol)
 			if err != nil {
 				fmt.Printf("[StockAPI] [Goroutine] Eastmoney API call failed: %v\n", err)
-				eastmoneyChan <- eastmoneyResult{"", err}
+				eastmoneyChan <- eastmoneyResult{nil, err}
 				return
 			}
 			
-			fmt.Printf("[StockAPI] [Goroutine] Eastmoney fetch successful: %s\n", name)
-			eastmoneyChan <- eastmoneyResult{name, nil}
+			fmt.Printf("[StockAPI] [Goroutine] Eastmoney fetch successful: %s\n", quote.Name)
+			eastmoneyChan <- eastmoneyResult{quote, nil}
 		}()
 		
 		// Wait for both results
 		yahooRes := <-yahooChan
 		eastmoneyRes := <-eastmoneyChan
 		
-		// Yahoo Finance result is critical
+		// Fall back to the Eastmoney quote when Yahoo Finance fails, and keep
+		// using it for this symbol for a while
 		if yahooRes.err != nil {
 			fmt.Printf("[StockAPI] ERROR: Yahoo Finance API call failed for %s: %v\n", symbol, yahooRes.err)
-			return nil, yahooRes.err
-		}
-		
-		info = yahooRes.info
-		
-		// Use Eastmoney name if available, otherwise fallback to Yahoo Finance name
-		if eastmoneyRes.err == nil && eastmoneyRes.name != "" {
+			if eastmoneyRes.err != nil || eastmoneyRes.info.CurrentPrice <= 0 {
+				if preferEastmoney {
+					s.setEastmoneyPreferred(symbol, false)
+				}
+				return nil, yahooRes.err
+			}
+			
+			fmt.Printf("[StockAPI] Using Eastmoney quote for %s\n", symbol)
+			if !preferEastmoney {
+				s.setEastmoneyPreferred(symbol, true)
+			}
+			info = eastmoneyRes.info
+		} else if eastmoneyRes.err == nil {
+			// Use Eastmoney name if available, otherwise fallback to Yahoo Finance name
+			info = yahooRes.info
 			fmt.Printf("[StockAPI] Using Eastmoney name: %s (replacing Yahoo name: %s)\n", 
-				eastmoneyRes.name, info.Name)
-			info.Name = eastmoneyRes.name
+				eastmoneyRes.info.Name, info.Name)
+			info.Name = eastmoneyRes.info.Name
+			info.Sector = eastmoneyRes.info.Sector
 		} else {
+			info = yahooRes.info
 			fmt.Printf("[StockAPI] WARNING: Eastmoney name fetch failed, falling back to Yahoo Finance name: %s (reason: %v)\n", 
 				info.Name, eastmoneyRes.err)
 		}
 		
 	} else {
-		// For non-Chinese stocks, use Yahoo Finance only
-		fmt.Printf("[StockAPI] Non-Chinese stock: %s, fetching from Yahoo Finance only\n", symbol)
+		// For other stocks, use Yahoo Finance only
+		fmt.Printf("[StockAPI] Stock not covered by Eastmoney: %s, fetching from Yahoo Finance only\n", symbol)
 		fmt.Printf("[StockAPI] Calling Yahoo Finance API for %s (period: %s to %s)\n", 
 			symbol, startTime.Format("2006-01-02"), endTime.Format("2006-01-02"))
 		
-		response, err := s.fetchFromYahooChart(symbol, startTime.Unix(), endTime.Unix())
+		response, err := s.fetchFromYahooChart(ctx, symbol, startTime.Unix(), endTime.Unix())
 		if err != nil {
 			fmt.Printf("[StockAPI] ERROR: Yahoo Finance API call failed for %s: %v\n", symbol, err)
 			return nil, err
@@ -642,6 +790,21 @@ func (s *StockAPIService) GetStockInfo(symbol string) (*StockInfo, error) {
 	fmt.Printf("[StockAPI] Successfully fetched %s: price=%.2f, currency=%s, name=%s\n", 
 		symbol, info.CurrentPrice, info.Currency, info.Name)
 	
+	// Record what the providers reported, and fill in details this response
+	// lacked from earlier ones
+	s.catalog.Record(ctx, info)
+	if metadata, ok := s.catalog.Lookup(symbol); ok {
+		if info.Sector == "" {
+			info.Sector = metadata.Sector
+		}
+		if info.Exchange == "" {
+			info.Exchange = metadata.Exchange
+		}
+		if info.Type == "" {
+			info.Type = metadata.Type
+		}
+	}
+	
 	// Cache the result
 	s.setCachedStockInfo(symbol, info)
 	
@@ -650,7 +813,13 @@ func (s *StockAPIService) GetStockInfo(symbol string) (*StockInfo, error) {
 
 // GetHistoricalData fetches historical price data with caching
 func (s *StockAPIService) GetHistoricalData(symbol string, period string) ([]HistoricalPrice, error) {
-	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	return s.GetHistoricalDataContext(context.Background(), symbol, period)
+}
+
+// GetHistoricalDataContext is GetHistoricalData with the external calls made
+// under ctx
+func (s *StockAPIService) GetHistoricalDataContext(ctx context.Context, symbol string, period string) ([]HistoricalPrice, error) {
+	symbol = s.catalog.Resolve(symbol)
 	
 	if symbol == "" {
 		return nil, ErrInvalidSymbol
@@ -697,14 +866,6 @@ func (s *StockAPIService) GetHistoricalData(symbol string, period string) ([]His
 		return nil, ErrInvalidPeriod
 	}
 	
-	// Create cache key with symbol and period
-	cacheKey := fmt.Sprintf("%s_%s", symbol, period)
-	
-	// Check cache first
-	if cached, found := s.getCachedHistoricalData(cacheKey); found {
-		return cached, nil
-	}
-	
 	// Calculate time range based on period
 	endTime := time.Now()
 	var startTime time.Time
@@ -722,22 +883,59 @@ func (s *StockAPIService) GetHistoricalData(symbol string, period string) ([]His
 		startTime = endTime.AddDate(-10, 0, 0)
 	}
 	
+	// Check cache first. A series fetched for a longer period serves shorter ones.
+	if cached, found := s.getCachedHistoricalData(symbol, startTime); found {
+		return cached, nil
+	}
+	
+	// Options have their own history, falling back to their intrinsic value
+	if contract, ok := models.ParseOptionSymbol(symbol); ok {
+		data, err := s.getOptionHistory(ctx, symbol, period, contract, startTime, endTime)
+		if err != nil {
+			return nil, err
+		}
+		return s.setCachedHistoricalData(symbol, startTime, data), nil
+	}
+	
+	// Chinese and Hong Kong stocks come from Eastmoney while Yahoo Finance is failing for them
+	if s.hasEastmoneyData(symbol) && s.prefersEastmoney(symbol) {
+		data, err := s.fetchHistoryFromEastmoney(ctx, symbol, startTime)
+		if err == nil {
+			return s.setCachedHistoricalData(symbol, startTime, data), nil
+		}
+		fmt.Printf("[StockAPI] WARNING: Eastmoney history failed for %s, retrying Yahoo Finance: %v\n", symbol, err)
+		s.setEastmoneyPreferred(symbol, false)
+	}
+	
 	// Fetch from Yahoo Finance Chart API
-	response, err := s.fetchFromYahooChart(symbol, startTime.Unix(), endTime.Unix())
+	data, err := s.fetchYahooHistory(ctx, symbol, startTime, endTime)
 	if err != nil {
-		return nil, err
+		if !s.hasEastmoneyData(symbol) {
+			return nil, err
+		}
+		
+		// Fall back to Eastmoney for Chinese and Hong Kong stocks
+		fmt.Printf("[StockAPI] WARNING: Yahoo Finance history failed for %s, falling back to Eastmoney: %v\n", symbol, err)
+		fallback, fallbackErr := s.fetchHistoryFromEastmoney(ctx, symbol, startTime)
+		if fallbackErr != nil {
+			return nil, err
+		}
+		s.setEastmoneyPreferred(symbol, true)
+		data = fallback
 	}
 	
-	// Extract historical data from response
-	data, err := s.extractHistoricalData(response)
+	// Cache the result
+	return s.setCachedHistoricalData(symbol, startTime, data), nil
+}
+
+// fetchYahooHistory fetches daily closes from the Yahoo Finance Chart API
+func (s *StockAPIService) fetchYahooHistory(ctx context.Context, symbol string, startTime, endTime time.Time) ([]HistoricalPrice, error) {
+	response, err := s.fetchFromYahooChart(ctx, symbol, startTime.Unix(), endTime.Unix())
 	if err != nil {
 		return nil, err
 	}
 	
-	// Cache the result
-	s.setCachedHistoricalData(cacheKey, data)
-	
-	return data, nil
+	return s.extractHistoricalData(response)
 }
 
 // StartCacheCleanup starts a background goroutine to periodically clean expired cache entries
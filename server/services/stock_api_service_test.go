@@ -8,7 +8,7 @@ import (
 
 // Test 7.1: 测试美股数据获取
 func TestGetStockInfo_USStock(t *testing.T) {
-	service := NewStockAPIService()
+	service := NewStockAPIService(StockAPIConfig{})
 	
 	// Test with AAPL
 	info, err := service.GetStockInfo("AAPL")
@@ -41,7 +41,7 @@ func TestGetStockInfo_USStock(t *testing.T) {
 }
 
 func TestGetHistoricalData_USStock_AllPeriods(t *testing.T) {
-	service := NewStockAPIService()
+	service := NewStockAPIService(StockAPIConfig{})
 	
 	periods := []string{"1M", "3M", "6M", "1Y"}
 	
@@ -80,7 +80,7 @@ func TestGetHistoricalData_USStock_AllPeriods(t *testing.T) {
 
 // Test 7.2: 测试中国股票数据获取
 func TestGetStockInfo_ChinaStock(t *testing.T) {
-	service := NewStockAPIService()
+	service := NewStockAPIService(StockAPIConfig{})
 	
 	// Test with 600000.SS (Shanghai Pudong Development Bank)
 	info, err := service.GetStockInfo("600000.SS")
@@ -103,9 +103,9 @@ func TestGetStockInfo_ChinaStock(t *testing.T) {
 		t.Errorf("Expected positive currentPrice, got %f", info.CurrentPrice)
 	}
 	
-	// Verify currency is CNY
-	if info.Currency != "CNY" {
-		t.Errorf("Expected currency 'CNY', got '%s'", info.Currency)
+	// Verify currency is RMB
+	if info.Currency != "RMB" {
+		t.Errorf("Expected currency 'RMB', got '%s'", info.Currency)
 	}
 	
 	t.Logf("600000.SS Stock Info: Symbol=%s, Name=%s, Price=%f, Currency=%s", 
@@ -113,7 +113,7 @@ func TestGetStockInfo_ChinaStock(t *testing.T) {
 }
 
 func TestGetHistoricalData_ChinaStock_AllPeriods(t *testing.T) {
-	service := NewStockAPIService()
+	service := NewStockAPIService(StockAPIConfig{})
 	
 	periods := []string{"1M", "3M", "6M", "1Y"}
 	
@@ -159,7 +159,7 @@ func TestGetHistoricalData_ChinaStock_AllPeriods(t *testing.T) {
 
 // Test 7.3: 测试缓存机制
 func TestCacheMechanism(t *testing.T) {
-	service := NewStockAPIService()
+	service := NewStockAPIService(StockAPIConfig{})
 	
 	symbol := "AAPL"
 	
@@ -183,6 +183,9 @@ func TestCacheMechanism(t *testing.T) {
 	if info1.Symbol != info2.Symbol || info1.CurrentPrice != info2.CurrentPrice {
 		t.Error("Cached data should be identical to first call")
 	}
+	if info1.Cached || !info2.Cached || info2.Source != info1.Source {
+		t.Errorf("Expected only the second call to be served from cache, got %+v and %+v", info1.PriceSource, info2.PriceSource)
+	}
 	
 	// Cache hit should be very fast (< 10ms)
 	if duration > 10*time.Millisecond {
@@ -192,12 +195,7 @@ func TestCacheMechanism(t *testing.T) {
 	}
 	
 	// Test cache expiration by manually setting a very short cache duration
-	serviceShortCache := &StockAPIService{
-		httpClient:         service.httpClient,
-		stockCache:         make(map[string]*CachedStockData),
-		historicalCache:    make(map[string]*CachedHistoricalData),
-		stockCacheDuration: 1 * time.Second, // Very short cache
-	}
+	serviceShortCache := NewStockAPIService(StockAPIConfig{CacheTTL: 1 * time.Second}) // Very short cache
 	
 	// First call
 	t.Log("Testing cache expiration - first call")
@@ -225,7 +223,7 @@ func TestCacheMechanism(t *testing.T) {
 }
 
 func TestHistoricalDataCache(t *testing.T) {
-	service := NewStockAPIService()
+	service := NewStockAPIService(StockAPIConfig{})
 	
 	symbol := "AAPL"
 	period := "1M"
@@ -259,7 +257,7 @@ func TestHistoricalDataCache(t *testing.T) {
 
 // Test 7.4: 测试错误处理
 func TestErrorHandling_InvalidSymbol(t *testing.T) {
-	service := NewStockAPIService()
+	service := NewStockAPIService(StockAPIConfig{})
 	
 	// Test empty symbol
 	_, err := service.GetStockInfo("")
@@ -275,7 +273,7 @@ func TestErrorHandling_InvalidSymbol(t *testing.T) {
 }
 
 func TestErrorHandling_StockNotFound(t *testing.T) {
-	service := NewStockAPIService()
+	service := NewStockAPIService(StockAPIConfig{})
 	
 	// Test with invalid/non-existent symbol
 	_, err := service.GetStockInfo("INVALIDXYZ123")
@@ -295,7 +293,7 @@ func TestErrorHandling_StockNotFound(t *testing.T) {
 }
 
 func TestErrorHandling_InvalidSymbolWithZeroPrice(t *testing.T) {
-	service := NewStockAPIService()
+	service := NewStockAPIService(StockAPIConfig{})
 	
 	// Test with APPL (typo of AAPL) which may return zero price
 	_, err := service.GetStockInfo("APPL")
@@ -325,7 +323,7 @@ func findSubstring(s, substr string) bool {
 }
 
 func TestErrorHandling_InvalidPeriod(t *testing.T) {
-	service := NewStockAPIService()
+	service := NewStockAPIService(StockAPIConfig{})
 	
 	invalidPeriods := []string{"", "1D", "2M", "5Y", "invalid"}
 	
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidStop    = errors.New("a stop needs a stop price or a trailing percentage")
+	ErrPositionClosed = errors.New("no shares are held in this position")
+)
+
+// StopJobInterval is how often watched positions are checked against the
+// latest quotes
+const StopJobInterval = 5 * time.Minute
+
+// StopService watches positions for stop-loss and trailing stop levels and
+// notifies the user when one is hit. Stops only notify: no trade is recorded.
+type StopService struct {
+	repos               repository.Repositories
+	portfolioService    *PortfolioService
+	stockService        StockDataProvider
+	notificationService *NotificationService
+}
+
+// NewStopService creates a new StopService instance. The notification service
+// may be nil, in which case triggered stops are only recorded.
+func NewStopService(portfolioService *PortfolioService, stockService StockDataProvider, notificationService *NotificationService) *StopService {
+	return &StopService{
+		repos:               portfolioService.repos,
+		portfolioService:    portfolioService,
+		stockService:        stockService,
+		notificationService: notificationService,
+	}
+}
+
+// SetStop sets the stop levels on an open position, replacing any earlier
+// stop. The peak starts at the highest close since the position was opened.
+func (s *StopService) SetStop(userID primitive.ObjectID, symbol string, req models.PositionStopRequest) (*models.PositionStop, error) {
+	if req.StopPrice <= 0 && req.TrailingPercent <= 0 {
+		return nil, ErrInvalidStop
+	}
+	if req.StopPrice < 0 || req.TrailingPercent < 0 || req.TrailingPercent >= 100 {
+		return nil, fmt.Errorf("%w: levels must be positive and the trailing percentage below 100", ErrInvalidStop)
+	}
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	portfolio, err := s.repos.Portfolios.FindBySymbol(ctx, userID, symbol)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrPositionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find portfolio: %w", err)
+	}
+
+	transactions, err := s.repos.Transactions.FindBySymbol(ctx, userID, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+	opened, ok := positionOpenedAt(transactions)
+	if !ok {
+		return nil, ErrPositionClosed
+	}
+
+	peak, peakAt, err := s.peakSince(ctx, symbol, opened)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := &models.PositionStop{
+		StopPrice:       req.StopPrice,
+		TrailingPercent: req.TrailingPercent,
+		PeakPrice:       peak,
+		PeakAt:          peakAt,
+		UpdatedAt:       time.Now(),
+	}
+	if err := s.repos.Portfolios.SetStop(ctx, userID, portfolio.ID, stop); err != nil {
+		return nil, fmt.Errorf("failed to set stop: %w", err)
+	}
+
+	return stop, nil
+}
+
+// ListStops returns the user's portfolio entries that have a stop, ordered by symbol
+func (s *StopService) ListStops(userID primitive.ObjectID) ([]models.Portfolio, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	portfolios, err := s.repos.Portfolios.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch portfolios: %w", err)
+	}
+
+	watched := []models.Portfolio{}
+	for _, portfolio := range portfolios {
+		if portfolio.Stop != nil {
+			watched = append(watched, portfolio)
+		}
+	}
+	sort.Slice(watched, func(i, j int) bool { return watched[i].Symbol < watched[j].Symbol })
+	return watched, nil
+}
+
+// RemoveStop stops watching a position
+func (s *StopService) RemoveStop(userID primitive.ObjectID, symbol string) error {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	portfolio, err := s.repos.Portfolios.FindBySymbol(ctx, userID, symbol)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrPositionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find portfolio: %w", err)
+	}
+
+	if err := s.repos.Portfolios.SetStop(ctx, userID, portfolio.ID, nil); err != nil {
+		return fmt.Errorf("failed to remove stop: %w", err)
+	}
+	return nil
+}
+
+// positionOpenedAt returns when the current position was opened: the date of
+// the buy that last took it from no shares to some. It reports false when no
+// shares are held.
+func positionOpenedAt(transactions []models.Transaction) (time.Time, bool) {
+	sorted := append([]models.Transaction(nil), transactions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].Date.Equal(sorted[j].Date) {
+			return sorted[i].Date.Before(sorted[j].Date)
+		}
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	var opened time.Time
+	shares := 0.0
+	for _, tx := range sorted {
+		switch tx.Action {
+		case "buy":
+			if shares <= 0 {
+				opened = tx.Date
+			}
+			shares += tx.Shares
+		case "sell":
+			shares -= tx.Shares
+		}
+	}
+	return opened, shares > 0
+}
+
+// peakSince returns the highest close from the day opened onwards, or the
+// current quote if that is higher
+func (s *StopService) peakSince(ctx context.Context, symbol string, opened time.Time) (float64, time.Time, error) {
+	info, err := s.stockService.GetStockInfoContext(ctx, symbol)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to fetch stock info for %s: %w", symbol, err)
+	}
+	peak, peakAt := info.CurrentPrice, time.Now()
+
+	prices, err := s.stockService.GetHistoricalDataContext(ctx, symbol, "ALL")
+	if err != nil {
+		fmt.Printf("[Stops] Warning: failed to fetch price history for %s: %v\n", symbol, err)
+		return peak, peakAt, nil
+	}
+	openedDay := opened.UTC().Truncate(24 * time.Hour)
+	for _, price := range prices {
+		if !price.Date.Before(openedDay) && price.Price > peak {
+			peak, peakAt = price.Price, price.Date
+		}
+	}
+	return peak, peakAt, nil
+}
+
+// stopTriggered reports what, if anything, the price triggers. An absolute
+// stop takes precedence over a trailing one.
+func stopTriggered(stop models.PositionStop, price float64) string {
+	if price <= 0 {
+		return ""
+	}
+	if stop.StopPrice > 0 && price <= stop.StopPrice {
+		return models.StopTriggerPrice
+	}
+	if stop.TrailingPercent > 0 && price <= stop.PeakPrice*(1-stop.TrailingPercent/100) {
+		return models.StopTriggerTrailing
+	}
+	return ""
+}
+
+// CheckStops raises the peaks of watched positions to the latest quotes and
+// triggers the stops they hit. Stops on positions that have since been sold
+// are removed. Each symbol is quoted once per run.
+func (s *StopService) CheckStops() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	watched, err := s.repos.Portfolios.FindWatchedStops(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to fetch watched positions: %w", err)
+	}
+
+	// Only positions still held are watched
+	held := make(map[primitive.ObjectID]map[string]bool)
+	bySymbol := make(map[string][]models.Portfolio)
+	var errs []error
+	for _, portfolio := range watched {
+		symbols, ok := held[portfolio.UserID]
+		if !ok {
+			positions, err := s.portfolioService.getPositions(context.Background(), portfolio.UserID)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			symbols = make(map[string]bool, len(positions))
+			for _, position := range positions {
+				symbols[position.Symbol] = true
+			}
+			held[portfolio.UserID] = symbols
+		}
+		if !symbols[portfolio.Symbol] {
+			s.removeClosed(portfolio)
+			continue
+		}
+		bySymbol[portfolio.Symbol] = append(bySymbol[portfolio.Symbol], portfolio)
+	}
+
+	now := time.Now()
+	triggered := 0
+	for symbol, portfolios := range bySymbol {
+		info, err := s.stockService.GetStockInfoContext(context.Background(), symbol)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to quote %s: %w", symbol, err))
+			continue
+		}
+		for _, portfolio := range portfolios {
+			if s.check(portfolio, info.CurrentPrice, now) {
+				triggered++
+			}
+		}
+	}
+
+	if triggered > 0 {
+		fmt.Printf("[Stops] Triggered %d of %d watched positions\n", triggered, len(watched))
+	}
+	return errors.Join(errs...)
+}
+
+// check applies a quote to a watched position, reporting whether this run
+// triggered its stop. The trigger is claimed in the transaction that queues
+// the notification, so a stop notifies exactly once; other failures are
+// logged and retried next run.
+func (s *StopService) check(portfolio models.Portfolio, price float64, now time.Time) bool {
+	stop := *portfolio.Stop
+	from := stop.UpdatedAt
+	if price > stop.PeakPrice {
+		stop.PeakPrice, stop.PeakAt = price, now
+	}
+
+	reason := stopTriggered(stop, price)
+	if reason == "" && stop.PeakPrice == portfolio.Stop.PeakPrice {
+		return false
+	}
+	if reason != "" {
+		stop.TriggeredAt = &now
+		stop.TriggeredBy = reason
+		stop.TriggeredPrice = price
+	}
+	stop.UpdatedAt = now
+	portfolio.Stop = &stop
+
+	update := func(ctx context.Context) error {
+		return s.repos.Portfolios.UpdateStop(ctx, &portfolio, from)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	var err error
+	if reason != "" {
+		// The trigger and its notification are committed together
+		err = s.notificationService.NotifyWith(ctx, "stops", portfolio.UserID, stopNotification(portfolio.Symbol, stop), update)
+	} else {
+		err = update(ctx)
+	}
+	cancel()
+	if errors.Is(err, repository.ErrNotFound) {
+		// Changed by the user or handled by another instance since it was loaded
+		return false
+	}
+	if err != nil {
+		fmt.Printf("[Stops] ERROR: Failed to update stop on %s for user %s: %v\n", portfolio.Symbol, portfolio.UserID.Hex(), err)
+		return false
+	}
+	return reason != ""
+}
+
+// removeClosed drops the stop of a position that is no longer held, so a
+// later purchase starts from a fresh peak
+func (s *StopService) removeClosed(portfolio models.Portfolio) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.repos.Portfolios.SetStop(ctx, portfolio.UserID, portfolio.ID, nil); err != nil {
+		fmt.Printf("[Stops] ERROR: Failed to remove stop on closed %s for user %s: %v\n", portfolio.Symbol, portfolio.UserID.Hex(), err)
+	}
+}
+
+// renderStopText describes a triggered stop
+func renderStopText(symbol string, stop models.PositionStop) string {
+	if stop.TriggeredBy == models.StopTriggerTrailing {
+		return fmt.Sprintf("%s fell to %.2f, %.1f%% below its peak of %.2f on %s, hitting your %g%% trailing stop.",
+			symbol, stop.TriggeredPrice, (1-stop.TriggeredPrice/stop.PeakPrice)*100, stop.PeakPrice,
+			stop.PeakAt.Format("Jan 2, 2006"), stop.TrailingPercent)
+	}
+	return fmt.Sprintf("%s fell to %.2f, hitting your stop at %.2f.", symbol, stop.TriggeredPrice, stop.StopPrice)
+}
+
+// stopNotification tells the user about a triggered stop
+func stopNotification(symbol string, stop models.PositionStop) Notification {
+	return Notification{
+		Subject: fmt.Sprintf("Stop triggered: %s", symbol),
+		Text:    renderStopText(symbol, stop) + "\n\nThe stop stays triggered until you set it again.",
+	}
+}
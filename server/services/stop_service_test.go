@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStopService(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple", 110, "USD").
+		SetHistory("AAPL", today, 150, 100, 120, 110).
+		SetQuote("MSFT", "Microsoft", 50, "USD").
+		SetHistory("MSFT", today, 50, 50)
+	portfolioService := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	service := NewStopService(portfolioService, provider, nil)
+	userID := primitive.NewObjectID()
+
+	for _, tx := range []*models.Transaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 10, Price: 100, Currency: "USD", Date: today.AddDate(0, 0, -2)},
+		{Symbol: "MSFT", Action: "buy", Shares: 10, Price: 50, Currency: "USD", Date: today.AddDate(0, 0, -1)},
+	} {
+		if err := portfolioService.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+
+	// The 150 close predates the purchase, so the peak is 120
+	stop, err := service.SetStop(userID, "aapl", models.PositionStopRequest{TrailingPercent: 10})
+	if err != nil {
+		t.Fatalf("Failed to set stop: %v", err)
+	}
+	if stop.PeakPrice != 120 {
+		t.Errorf("Expected a peak of 120 since purchase, got %.2f", stop.PeakPrice)
+	}
+	if _, err := service.SetStop(userID, "MSFT", models.PositionStopRequest{StopPrice: 45}); err != nil {
+		t.Fatalf("Failed to set stop: %v", err)
+	}
+
+	stopOn := func(symbol string) *models.PositionStop {
+		portfolio, err := portfolioService.repos.Portfolios.FindBySymbol(context.Background(), userID, symbol)
+		if err != nil {
+			t.Fatalf("Failed to find %s: %v", symbol, err)
+		}
+		return portfolio.Stop
+	}
+
+	// A new high raises the peak
+	provider.SetQuote("AAPL", "Apple", 125, "USD")
+	if err := service.CheckStops(); err != nil {
+		t.Fatalf("Failed to check stops: %v", err)
+	}
+	if stop := stopOn("AAPL"); stop.PeakPrice != 125 || stop.TriggeredAt != nil {
+		t.Errorf("Expected an untriggered stop peaking at 125, got %+v", stop)
+	}
+
+	// 112 is more than 10% below 125
+	provider.SetQuote("AAPL", "Apple", 112, "USD")
+	provider.SetQuote("MSFT", "Microsoft", 44, "USD")
+	if err := service.CheckStops(); err != nil {
+		t.Fatalf("Failed to check stops: %v", err)
+	}
+	if stop := stopOn("AAPL"); stop.TriggeredAt == nil || stop.TriggeredBy != models.StopTriggerTrailing || stop.TriggeredPrice != 112 {
+		t.Errorf("Expected the trailing stop to trigger at 112, got %+v", stop)
+	}
+	if stop := stopOn("MSFT"); stop.TriggeredAt == nil || stop.TriggeredBy != models.StopTriggerPrice {
+		t.Errorf("Expected the MSFT stop to trigger, got %+v", stop)
+	}
+
+	// Triggered stops are no longer watched
+	watched, err := portfolioService.repos.Portfolios.FindWatchedStops(context.Background())
+	if err != nil || len(watched) != 0 {
+		t.Errorf("Expected no watched stops, got %+v, %v", watched, err)
+	}
+
+	// Setting a stop again re-arms it; selling out removes it on the next check
+	if _, err := service.SetStop(userID, "AAPL", models.PositionStopRequest{StopPrice: 90}); err != nil {
+		t.Fatalf("Failed to set stop: %v", err)
+	}
+	sell := &models.Transaction{Symbol: "AAPL", Action: "sell", Shares: 10, Price: 112, Currency: "USD", Date: today}
+	if err := portfolioService.AddTransaction(userID, sell); err != nil {
+		t.Fatalf("Failed to sell: %v", err)
+	}
+	if err := service.CheckStops(); err != nil {
+		t.Fatalf("Failed to check stops: %v", err)
+	}
+	if stop := stopOn("AAPL"); stop != nil {
+		t.Errorf("Expected the stop on the closed position to be removed, got %+v", stop)
+	}
+	if _, err := service.SetStop(userID, "AAPL", models.PositionStopRequest{StopPrice: 90}); !errors.Is(err, ErrPositionClosed) {
+		t.Errorf("Expected ErrPositionClosed, got %v", err)
+	}
+	if _, err := service.SetStop(userID, "MSFT", models.PositionStopRequest{}); !errors.Is(err, ErrInvalidStop) {
+		t.Errorf("Expected ErrInvalidStop, got %v", err)
+	}
+}
@@ -0,0 +1,381 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"math"
+	"sort"
+	"stock-portfolio-tracker/database"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// SummaryEmailJobInterval is how often the scheduler checks for due summaries
+	SummaryEmailJobInterval = time.Hour
+	// summaryMoverCount is the number of day-change leaders and laggards in a summary
+	summaryMoverCount = 3
+	// summaryDriftCount is the number of allocation changes listed in a summary
+	summaryDriftCount = 5
+	// minAllocationDrift ignores weight changes smaller than this many percentage points
+	minAllocationDrift = 0.5
+)
+
+// AllocationDrift represents how a holding's portfolio weight changed since the last summary
+type AllocationDrift struct {
+	Symbol         string  `json:"symbol"`
+	PreviousWeight float64 `json:"previousWeight"`
+	CurrentWeight  float64 `json:"currentWeight"`
+	Change         float64 `json:"change"` // Percentage points
+}
+
+// PortfolioSummary represents the contents of a recurring summary email
+type PortfolioSummary struct {
+	Frequency        string             `json:"frequency"`
+	Currency         string             `json:"currency"`
+	PeriodStart      time.Time          `json:"periodStart"`
+	PeriodEnd        time.Time          `json:"periodEnd"`
+	TotalValue       float64            `json:"totalValue"`
+	PeriodReturn     ReturnMetric       `json:"periodReturn"`
+	DayChange        float64            `json:"dayChange"`
+	DayChangePercent float64            `json:"dayChangePercent"`
+	Leaders          []HoldingMover     `json:"leaders"`
+	Laggards         []HoldingMover     `json:"laggards"`
+	Drift            []AllocationDrift  `json:"drift"`
+	Allocation       map[string]float64 `json:"-"`
+}
+
+// SummaryEmailService compiles and sends recurring portfolio summary emails
+type SummaryEmailService struct {
+	analyticsService    *AnalyticsService
+	settingsService     *SettingsService
+	notificationService *NotificationService
+}
+
+// NewSummaryEmailService creates a new SummaryEmailService instance
+func NewSummaryEmailService(
+	analyticsService *AnalyticsService,
+	settingsService *SettingsService,
+	notificationService *NotificationService,
+) *SummaryEmailService {
+	return &SummaryEmailService{
+		analyticsService:    analyticsService,
+		settingsService:     settingsService,
+		notificationService: notificationService,
+	}
+}
+
+// summaryPeriodStart returns the start of the period a summary covers
+func summaryPeriodStart(frequency string, end time.Time) time.Time {
+	if frequency == models.SummaryFrequencyMonthly {
+		return end.AddDate(0, -1, 0)
+	}
+	return end.AddDate(0, 0, -7)
+}
+
+// summaryDue reports whether a summary should be sent at now
+func summaryDue(frequency string, lastSentAt *time.Time, now time.Time) bool {
+	if frequency != models.SummaryFrequencyWeekly && frequency != models.SummaryFrequencyMonthly {
+		return false
+	}
+	if lastSentAt == nil {
+		return true
+	}
+	// Allow one job interval of slack so the send time doesn't creep later every period
+	return !lastSentAt.After(summaryPeriodStart(frequency, now).Add(SummaryEmailJobInterval))
+}
+
+// returnSince measures the change from the last point on or before since to the final point
+func returnSince(points []PerformanceDataPoint, since time.Time) ReturnMetric {
+	if len(points) == 0 {
+		return ReturnMetric{}
+	}
+
+	base := points[0]
+	for _, point := range points {
+		if point.Date.After(since) {
+			break
+		}
+		base = point
+	}
+
+	end := points[len(points)-1]
+	metric := ReturnMetric{Absolute: end.Value - base.Value}
+	if base.Value > 0 {
+		metric.Percentage = metric.Absolute / base.Value * 100
+	}
+	return metric
+}
+
+// allocationDrift lists the largest weight changes between two allocations
+func allocationDrift(previous map[string]float64, current map[string]float64) []AllocationDrift {
+	drift := []AllocationDrift{}
+	if previous == nil {
+		return drift
+	}
+
+	symbols := make(map[string]bool)
+	for symbol := range previous {
+		symbols[symbol] = true
+	}
+	for symbol := range current {
+		symbols[symbol] = true
+	}
+
+	for symbol := range symbols {
+		change := current[symbol] - previous[symbol]
+		if math.Abs(change) < minAllocationDrift {
+			continue
+		}
+		drift = append(drift, AllocationDrift{
+			Symbol:         symbol,
+			PreviousWeight: previous[symbol],
+			CurrentWeight:  current[symbol],
+			Change:         change,
+		})
+	}
+
+	sort.Slice(drift, func(i, j int) bool {
+		return math.Abs(drift[i].Change) > math.Abs(drift[j].Change)
+	})
+	if len(drift) > summaryDriftCount {
+		drift = drift[:summaryDriftCount]
+	}
+	return drift
+}
+
+// BuildSummary compiles the period return, day-change leaders and laggards,
+// and allocation drift since the previous summary
+func (s *SummaryEmailService) BuildSummary(userID primitive.ObjectID, settings models.SummaryEmailSettings, now time.Time) (*PortfolioSummary, error) {
+	dashboard, err := s.analyticsService.GetDashboardMetrics(userID, settings.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate dashboard metrics: %w", err)
+	}
+
+	// The performance series must reach back past the start of the summary period
+	period := "1M"
+	if settings.Frequency == models.SummaryFrequencyMonthly {
+		period = "3M"
+	}
+	performance, err := s.analyticsService.GetHistoricalPerformance(userID, period, dashboard.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate performance: %w", err)
+	}
+
+	movers, err := s.analyticsService.GetDayMovers(userID, dashboard.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate movers: %w", err)
+	}
+	leaders, laggards := splitMovers(movers, summaryMoverCount)
+
+	allocation := make(map[string]float64, len(dashboard.Allocation))
+	for _, item := range dashboard.Allocation {
+		allocation[item.Symbol] = item.Percentage
+	}
+
+	periodStart := summaryPeriodStart(settings.Frequency, now)
+	return &PortfolioSummary{
+		Frequency:        settings.Frequency,
+		Currency:         dashboard.Currency,
+		PeriodStart:      periodStart,
+		PeriodEnd:        now,
+		TotalValue:       dashboard.TotalValue,
+		PeriodReturn:     returnSince(performance, periodStart),
+		DayChange:        dashboard.DayChange,
+		DayChangePercent: dashboard.DayChangePercent,
+		Leaders:          leaders,
+		Laggards:         laggards,
+		Drift:            allocationDrift(settings.LastAllocation, allocation),
+		Allocation:       allocation,
+	}, nil
+}
+
+// SendSummaryNow compiles and sends the user's summary immediately without
+// affecting the regular schedule
+func (s *SummaryEmailService) SendSummaryNow(userID primitive.ObjectID) error {
+	settings, err := s.settingsService.GetSettings(userID)
+	if err != nil {
+		return err
+	}
+
+	summaryEmail := settings.SummaryEmail
+	if summaryEmail.Frequency == models.SummaryFrequencyOff {
+		summaryEmail.Frequency = models.SummaryFrequencyWeekly
+	}
+
+	summary, err := s.BuildSummary(userID, summaryEmail, time.Now())
+	if err != nil {
+		return err
+	}
+	return s.notificationService.Notify(userID, renderSummaryNotification(summary))
+}
+
+// SendDueSummaries queues every summary email that is due. Each user's send
+// is claimed by moving last_sent_at with a conditional update in the
+// transaction that queues the email, so several server instances running
+// this job never send the same summary twice.
+func (s *SummaryEmailService) SendDueSummaries() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.Database.Collection("user_settings")
+	cursor, err := collection.Find(ctx, bson.M{
+		"summary_email.frequency": bson.M{"$in": []string{models.SummaryFrequencyWeekly, models.SummaryFrequencyMonthly}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch summary subscribers: %w", err)
+	}
+
+	var subscribers []models.UserSettings
+	if err := cursor.All(ctx, &subscribers); err != nil {
+		return fmt.Errorf("failed to decode summary subscribers: %w", err)
+	}
+
+	now := time.Now()
+	queued := 0
+	var errs []error
+	for _, settings := range subscribers {
+		if !summaryDue(settings.SummaryEmail.Frequency, settings.SummaryEmail.LastSentAt, now) {
+			continue
+		}
+
+		summary, err := s.BuildSummary(settings.UserID, settings.SummaryEmail, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("summary for user %s: %w", settings.UserID.Hex(), err))
+			continue
+		}
+
+		claim := func(ctx context.Context) error {
+			claimed, err := s.setLastSent(ctx, settings.ID, settings.SummaryEmail.LastSentAt, now, summary.Allocation)
+			if err == nil && !claimed {
+				err = errClaimLost
+			}
+			return err
+		}
+		updateCtx, updateCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = s.notificationService.NotifyWith(updateCtx, "summary-emails", settings.UserID, renderSummaryNotification(summary), claim)
+		updateCancel()
+		if errors.Is(err, errClaimLost) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("summary for user %s: %w", settings.UserID.Hex(), err))
+			continue
+		}
+		queued++
+	}
+
+	if queued > 0 {
+		fmt.Printf("[SummaryEmail] Queued %d summary emails\n", queued)
+	}
+	return errors.Join(errs...)
+}
+
+// setLastSent moves last_sent_at from expected to value, recording the
+// allocation the summary reported. It returns false if another writer
+// changed last_sent_at first.
+func (s *SummaryEmailService) setLastSent(ctx context.Context, settingsID primitive.ObjectID, expected *time.Time, value time.Time, allocation map[string]float64) (bool, error) {
+	filter := bson.M{"_id": settingsID}
+	if expected == nil {
+		filter["summary_email.last_sent_at"] = bson.M{"$exists": false}
+	} else {
+		filter["summary_email.last_sent_at"] = *expected
+	}
+
+	set := bson.M{"summary_email.last_sent_at": value}
+	if allocation != nil {
+		set["summary_email.last_allocation"] = allocation
+	}
+	update := bson.M{"$set": set}
+
+	result, err := database.Database.Collection("user_settings").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to update summary email status: %w", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+var summaryTemplateFuncs = map[string]interface{}{
+	"money":   formatMoney,
+	"percent": formatPercent,
+	"date": func(t time.Time) string {
+		return t.Format("Jan 2, 2006")
+	},
+	"points": func(value float64) string {
+		return fmt.Sprintf("%+.1f pp", value)
+	},
+	"title": func(s string) string {
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+}
+
+var summaryTextTemplate = template.Must(template.New("summary").Funcs(summaryTemplateFuncs).Parse(
+	`{{title .Frequency}} portfolio summary, {{date .PeriodStart}} - {{date .PeriodEnd}}
+
+Portfolio value: {{money .TotalValue .Currency}}
+Period return:   {{money .PeriodReturn.Absolute .Currency}} ({{percent .PeriodReturn.Percentage}})
+Today:           {{money .DayChange .Currency}} ({{percent .DayChangePercent}})
+{{if .Leaders}}
+Today's leaders:
+{{range .Leaders}}  {{.Symbol}}  {{percent .ChangePercent}}  {{money .Change $.Currency}}
+{{end}}{{end}}{{if .Laggards}}
+Today's laggards:
+{{range .Laggards}}  {{.Symbol}}  {{percent .ChangePercent}}  {{money .Change $.Currency}}
+{{end}}{{end}}{{if .Drift}}
+Allocation drift since the last summary:
+{{range .Drift}}  {{.Symbol}}  {{printf "%.1f%%" .PreviousWeight}} -> {{printf "%.1f%%" .CurrentWeight}} ({{points .Change}})
+{{end}}{{end}}
+You can change or turn off these emails in your settings.
+`))
+
+var summaryHTMLTemplate = htmltemplate.Must(htmltemplate.New("summary").Funcs(summaryTemplateFuncs).Parse(`<!DOCTYPE html>
+<html><body style="font-family: Helvetica, Arial, sans-serif; color: #1f2933;">
+<h2 style="margin-bottom: 4px;">{{title .Frequency}} portfolio summary</h2>
+<div style="color: #7b8794; font-size: 12px;">{{date .PeriodStart}} - {{date .PeriodEnd}}</div>
+<table style="margin-top: 16px; font-size: 14px;">
+<tr><td>Portfolio value</td><td style="text-align: right; padding-left: 24px;"><b>{{money .TotalValue .Currency}}</b></td></tr>
+<tr><td>Period return</td><td style="text-align: right; padding-left: 24px;">{{money .PeriodReturn.Absolute .Currency}} ({{percent .PeriodReturn.Percentage}})</td></tr>
+<tr><td>Today</td><td style="text-align: right; padding-left: 24px;">{{money .DayChange .Currency}} ({{percent .DayChangePercent}})</td></tr>
+</table>
+{{- if or .Leaders .Laggards}}
+<h3>Today's movers</h3>
+<table style="font-size: 14px;">
+{{- range .Leaders}}<tr><td>{{.Symbol}}</td><td style="color: #1a7f37; padding-left: 24px;">{{percent .ChangePercent}}</td><td style="padding-left: 24px;">{{money .Change $.Currency}}</td></tr>{{end}}
+{{- range .Laggards}}<tr><td>{{.Symbol}}</td><td style="color: #cf222e; padding-left: 24px;">{{percent .ChangePercent}}</td><td style="padding-left: 24px;">{{money .Change $.Currency}}</td></tr>{{end}}
+</table>
+{{- end}}
+{{- if .Drift}}
+<h3>Allocation drift since the last summary</h3>
+<table style="font-size: 14px;">
+{{- range .Drift}}<tr><td>{{.Symbol}}</td><td style="padding-left: 24px;">{{printf "%.1f%%" .PreviousWeight}} &rarr; {{printf "%.1f%%" .CurrentWeight}}</td><td style="padding-left: 24px;">{{points .Change}}</td></tr>{{end}}
+</table>
+{{- end}}
+<p style="color: #7b8794; font-size: 12px;">You can change or turn off these emails in your settings.</p>
+</body></html>
+`))
+
+// renderSummaryNotification renders a summary as a plain text and HTML email
+func renderSummaryNotification(summary *PortfolioSummary) Notification {
+	var text, html bytes.Buffer
+	if err := summaryTextTemplate.Execute(&text, summary); err != nil {
+		fmt.Printf("[SummaryEmail] Failed to render text summary: %v\n", err)
+	}
+	if err := summaryHTMLTemplate.Execute(&html, summary); err != nil {
+		fmt.Printf("[SummaryEmail] Failed to render HTML summary: %v\n", err)
+		html.Reset()
+	}
+
+	return Notification{
+		Subject: fmt.Sprintf("Your %s portfolio summary: %s", summary.Frequency, formatPercent(summary.PeriodReturn.Percentage)),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"math"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummaryDue(t *testing.T) {
+	now := time.Date(2024, 6, 15, 9, 0, 0, 0, time.UTC)
+	sixDaysAgo := now.AddDate(0, 0, -6)
+	weekAgo := now.AddDate(0, 0, -7).Add(30 * time.Minute)
+
+	tests := []struct {
+		frequency  string
+		lastSentAt *time.Time
+		want       bool
+	}{
+		{models.SummaryFrequencyOff, nil, false},
+		{models.SummaryFrequencyWeekly, nil, true},
+		{models.SummaryFrequencyWeekly, &sixDaysAgo, false},
+		{models.SummaryFrequencyWeekly, &weekAgo, true},
+		{models.SummaryFrequencyMonthly, &weekAgo, false},
+	}
+
+	for _, tt := range tests {
+		if got := summaryDue(tt.frequency, tt.lastSentAt, now); got != tt.want {
+			t.Errorf("summaryDue(%s, %v) = %v, want %v", tt.frequency, tt.lastSentAt, got, tt.want)
+		}
+	}
+}
+
+func TestReturnSince(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	points := []PerformanceDataPoint{
+		{Date: day(1), Value: 80},
+		{Date: day(3), Value: 100},
+		{Date: day(5), Value: 110},
+	}
+
+	metric := returnSince(points, day(4))
+	if metric.Absolute != 10 || math.Abs(metric.Percentage-10) > 1e-9 {
+		t.Errorf("Unexpected return: %+v", metric)
+	}
+}
+
+func TestAllocationDrift(t *testing.T) {
+	previous := map[string]float64{"AAPL": 50, "MSFT": 50}
+	current := map[string]float64{"AAPL": 60.2, "MSFT": 39.6, "TSLA": 0.2}
+
+	drift := allocationDrift(previous, current)
+	if len(drift) != 2 || drift[0].Symbol != "MSFT" || drift[1].Symbol != "AAPL" {
+		t.Errorf("Unexpected drift: %+v", drift)
+	}
+
+	if len(allocationDrift(nil, current)) != 0 {
+		t.Errorf("Expected no drift without a previous allocation")
+	}
+}
+
+func TestRenderSummaryNotification(t *testing.T) {
+	summary := &PortfolioSummary{
+		Frequency:    models.SummaryFrequencyWeekly,
+		Currency:     "USD",
+		TotalValue:   1500,
+		PeriodReturn: ReturnMetric{Absolute: 30, Percentage: 2.04},
+		Leaders:      []HoldingMover{{Symbol: "AAPL", Change: 12, ChangePercent: 1.5}},
+		Drift:        []AllocationDrift{{Symbol: "AAPL", PreviousWeight: 50, CurrentWeight: 55, Change: 5}},
+	}
+
+	notification := renderSummaryNotification(summary)
+	if notification.Subject != "Your weekly portfolio summary: +2.04%" {
+		t.Errorf("Unexpected subject: %s", notification.Subject)
+	}
+	for _, want := range []string{"$1,500.00", "AAPL", "+5.0 pp"} {
+		if !strings.Contains(notification.Text, want) {
+			t.Errorf("Expected text body to contain %q", want)
+		}
+	}
+	if !strings.Contains(notification.HTML, "<h3>Allocation drift since the last summary</h3>") {
+		t.Errorf("Expected HTML body to list allocation drift")
+	}
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidRename = errors.New("a rename needs two different symbols")
+	ErrRenameCycle   = errors.New("the new symbol was itself renamed from the old one")
+)
+
+const (
+	// SymbolCatalogRefreshInterval is how often each instance reloads the
+	// catalog, bounding how long a rename takes to apply everywhere
+	SymbolCatalogRefreshInterval = 5 * time.Minute
+
+	// lastPriceInterval is how often a symbol's last known price is saved
+	lastPriceInterval = time.Hour
+	// delistAfter is how long the providers must not find a symbol before
+	// it is marked delisted, so a passing provider glitch doesn't delist it
+	delistAfter = 72 * time.Hour
+)
+
+// SymbolCatalog keeps the metadata of every symbol quoted so far. Entries are
+// recorded lazily from provider responses and stored in the database, so a
+// symbol's currency and sector survive restarts and come from what the
+// provider reported rather than from its exchange suffix.
+type SymbolCatalog struct {
+	repos repository.Repositories
+
+	mutex   sync.RWMutex
+	symbols map[string]models.SymbolMetadata
+}
+
+// NewSymbolCatalog creates a new SymbolCatalog storing symbols in MongoDB
+func NewSymbolCatalog() *SymbolCatalog {
+	return NewSymbolCatalogWithRepos(repository.NewMongo())
+}
+
+// NewSymbolCatalogWithRepos creates a SymbolCatalog over the given repositories
+func NewSymbolCatalogWithRepos(repos repository.Repositories) *SymbolCatalog {
+	return &SymbolCatalog{
+		repos:   repos,
+		symbols: make(map[string]models.SymbolMetadata),
+	}
+}
+
+// Load reads the stored catalog into memory
+func (c *SymbolCatalog) Load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stored, err := c.repos.Symbols.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load symbol catalog: %w", err)
+	}
+
+	symbols := make(map[string]models.SymbolMetadata, len(stored))
+	for _, symbol := range stored {
+		symbols[symbol.Symbol] = symbol
+	}
+	c.mutex.Lock()
+	c.symbols = symbols
+	c.mutex.Unlock()
+	return nil
+}
+
+// Lookup returns the symbol's recorded metadata. A nil catalog knows no
+// symbols.
+func (c *SymbolCatalog) Lookup(symbol string) (models.SymbolMetadata, bool) {
+	if c == nil {
+		return models.SymbolMetadata{}, false
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	metadata, ok := c.symbols[strings.ToUpper(strings.TrimSpace(symbol))]
+	return metadata, ok
+}
+
+// Resolve normalizes a symbol and returns its current ticker when it was
+// renamed. Renames are recorded one hop from the current ticker, so one
+// lookup suffices.
+func (c *SymbolCatalog) Resolve(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if metadata, ok := c.Lookup(symbol); ok && metadata.RenamedTo != "" {
+		return metadata.RenamedTo
+	}
+	return symbol
+}
+
+// Rename records a ticker change such as FB becoming META. Transactions in
+// the old symbol are then priced, and counted towards holdings, under the
+// new one. A new symbol that was itself renamed is followed to its current
+// ticker.
+func (c *SymbolCatalog) Rename(from, to string) (*models.SymbolMetadata, error) {
+	from = strings.ToUpper(strings.TrimSpace(from))
+	to = strings.ToUpper(strings.TrimSpace(to))
+	if from == "" || to == "" || from == to {
+		return nil, ErrInvalidRename
+	}
+	if to = c.Resolve(to); to == from {
+		return nil, ErrRenameCycle
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	if err := c.repos.Symbols.Rename(ctx, from, to, now); err != nil {
+		return nil, fmt.Errorf("failed to rename symbol: %w", err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, metadata := range c.symbols {
+		if metadata.RenamedTo == from {
+			metadata.RenamedTo, metadata.RenamedAt = to, &now
+			c.symbols[key] = metadata
+		}
+	}
+	metadata, ok := c.symbols[from]
+	if !ok {
+		metadata = models.SymbolMetadata{Symbol: from, FirstSeen: now}
+	}
+	metadata.RenamedTo, metadata.RenamedAt, metadata.UpdatedAt = to, &now, now
+	c.symbols[from] = metadata
+	return &metadata, nil
+}
+
+// Record merges a quote's metadata into the catalog, saving it when anything
+// changed. Details the quote lacks, such as the exchange when only Eastmoney
+// answered, keep their recorded values. Failing to save is logged rather than
+// returned, as the quote itself is still good.
+func (c *SymbolCatalog) Record(ctx context.Context, info *StockInfo) {
+	if c == nil || info == nil || info.Symbol == "" {
+		return
+	}
+
+	now := time.Now()
+	key := strings.ToUpper(info.Symbol)
+	c.mutex.RLock()
+	previous, known := c.symbols[key]
+	c.mutex.RUnlock()
+
+	metadata := previous
+	if !known {
+		metadata = models.SymbolMetadata{Symbol: key, FirstSeen: now}
+	}
+	mergeMetadata(&metadata.Name, info.Name)
+	mergeMetadata(&metadata.Exchange, info.Exchange)
+	mergeMetadata(&metadata.Currency, info.Currency)
+	mergeMetadata(&metadata.Sector, info.Sector)
+	mergeMetadata(&metadata.Type, info.Type)
+	if info.CurrentPrice > 0 && (metadata.LastPriceAt == nil || now.Sub(*metadata.LastPriceAt) >= lastPriceInterval) {
+		metadata.LastPrice, metadata.LastPriceAt = info.CurrentPrice, &now
+	}
+	if metadata.DelistedAt != nil {
+		log.Printf("[Symbols] %s is quoted again, no longer delisted", key)
+	}
+	metadata.MissingSince, metadata.DelistedAt = nil, nil
+	if known && metadata == previous {
+		return
+	}
+	metadata.UpdatedAt = now
+
+	if err := c.repos.Symbols.Save(ctx, &metadata); err != nil {
+		log.Printf("WARNING: Failed to record metadata for %s: %v", key, err)
+		return
+	}
+	c.mutex.Lock()
+	c.symbols[key] = metadata
+	c.mutex.Unlock()
+}
+
+// RecordMissing notes that the providers did not find a symbol they quoted
+// before, marking it delisted once they have not found it for delistAfter.
+// It returns the symbol's metadata, or false for symbols never quoted.
+func (c *SymbolCatalog) RecordMissing(ctx context.Context, symbol string) (models.SymbolMetadata, bool) {
+	metadata, ok := c.Lookup(symbol)
+	if !ok || metadata.LastPrice <= 0 {
+		return metadata, false
+	}
+
+	now := time.Now()
+	switch {
+	case metadata.MissingSince == nil:
+		metadata.MissingSince = &now
+	case metadata.DelistedAt == nil && now.Sub(*metadata.MissingSince) >= delistAfter:
+		metadata.DelistedAt = &now
+		log.Printf("[Symbols] %s not found since %s, marked delisted", metadata.Symbol, metadata.MissingSince.Format(time.RFC3339))
+	default:
+		return metadata, true
+	}
+	metadata.UpdatedAt = now
+
+	if err := c.repos.Symbols.Save(ctx, &metadata); err != nil {
+		log.Printf("WARNING: Failed to record %s as missing: %v", metadata.Symbol, err)
+		return metadata, true
+	}
+	c.mutex.Lock()
+	c.symbols[metadata.Symbol] = metadata
+	c.mutex.Unlock()
+	return metadata, true
+}
+
+// mergeMetadata replaces a recorded detail with a newly reported one
+func mergeMetadata(recorded *string, reported string) {
+	if reported = strings.TrimSpace(reported); reported != "" {
+		*recorded = reported
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+)
+
+func TestSymbolCatalogRecordsAndMerges(t *testing.T) {
+	repos := repository.NewMemory()
+	catalog := NewSymbolCatalogWithRepos(repos)
+	ctx := context.Background()
+
+	catalog.Record(ctx, &StockInfo{Symbol: "VOD.L", Name: "Vodafone Group", Currency: "GBP", Exchange: "LSE", Type: "EQUITY"})
+	metadata, ok := catalog.Lookup("vod.l")
+	if !ok || metadata.Currency != "GBP" || metadata.Exchange != "LSE" || metadata.FirstSeen.IsZero() {
+		t.Fatalf("Expected the quote to be recorded, got %+v", metadata)
+	}
+
+	// A quote lacking details keeps the recorded ones and the first-seen time
+	catalog.Record(ctx, &StockInfo{Symbol: "VOD.L", Name: "Vodafone", Currency: "GBP", Sector: "Telecom"})
+	updated, _ := catalog.Lookup("VOD.L")
+	if updated.Name != "Vodafone" || updated.Sector != "Telecom" || updated.Exchange != "LSE" || !updated.FirstSeen.Equal(metadata.FirstSeen) {
+		t.Errorf("Expected the details merged, got %+v", updated)
+	}
+
+	// The catalog is stored, so a fresh instance loads it
+	reloaded := NewSymbolCatalogWithRepos(repos)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if stored, ok := reloaded.Lookup("VOD.L"); !ok || stored.Sector != "Telecom" {
+		t.Errorf("Expected the stored metadata, got %+v", stored)
+	}
+
+	var none *SymbolCatalog
+	none.Record(ctx, &StockInfo{Symbol: "AAPL"})
+	if _, ok := none.Lookup("AAPL"); ok {
+		t.Errorf("Expected a nil catalog to know no symbols")
+	}
+}
+
+func TestSymbolCurrencyPrefersCatalog(t *testing.T) {
+	catalog := NewSymbolCatalogWithRepos(repository.NewMemory())
+	service := NewStockAPIService(StockAPIConfig{Catalog: catalog})
+
+	if got := service.SymbolCurrency("0700.HK"); got != "HKD" {
+		t.Fatalf("Expected the exchange currency before the symbol is recorded, got %s", got)
+	}
+	// The currency the provider reported wins over the exchange's
+	catalog.Record(context.Background(), &StockInfo{Symbol: "9988.HK", Name: "Dual counter", Currency: "USD"})
+	if got := service.SymbolCurrency("9988.hk"); got != "USD" {
+		t.Errorf("Expected the recorded currency, got %s", got)
+	}
+	if got := service.SymbolCurrency("CASH_RMB"); got != "RMB" {
+		t.Errorf("Expected cash symbols to keep their currency, got %s", got)
+	}
+}
+
+func TestSymbolCatalogRename(t *testing.T) {
+	repos := repository.NewMemory()
+	catalog := NewSymbolCatalogWithRepos(repos)
+	ctx := context.Background()
+	catalog.Record(ctx, &StockInfo{Symbol: "FB", Name: "Facebook", Currency: "USD"})
+
+	renamed, err := catalog.Rename("fb", "meta")
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if renamed.RenamedTo != "META" || renamed.Name != "Facebook" || renamed.RenamedAt == nil {
+		t.Errorf("Expected FB renamed to META, got %+v", renamed)
+	}
+	if got := catalog.Resolve(" fb "); got != "META" {
+		t.Errorf("Expected FB to resolve to META, got %s", got)
+	}
+	if got := catalog.Resolve("AAPL"); got != "AAPL" {
+		t.Errorf("Expected other symbols unchanged, got %s", got)
+	}
+
+	// Renaming back would loop, and a symbol can't be renamed to itself
+	if _, err := catalog.Rename("META", "FB"); !errors.Is(err, ErrRenameCycle) {
+		t.Errorf("Expected ErrRenameCycle, got %v", err)
+	}
+	if _, err := catalog.Rename("META", "meta"); !errors.Is(err, ErrInvalidRename) {
+		t.Errorf("Expected ErrInvalidRename, got %v", err)
+	}
+
+	// Renames survive a reload
+	reloaded := NewSymbolCatalogWithRepos(repos)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := reloaded.Resolve("FB"); got != "META" {
+		t.Errorf("Expected the stored rename, got %s", got)
+	}
+}
+
+func TestSymbolCatalogMarksDelisted(t *testing.T) {
+	repos := repository.NewMemory()
+	catalog := NewSymbolCatalogWithRepos(repos)
+	ctx := context.Background()
+
+	if _, ok := catalog.RecordMissing(ctx, "NOPE"); ok {
+		t.Fatalf("Expected symbols never quoted to be unknown")
+	}
+
+	catalog.Record(ctx, &StockInfo{Symbol: "TWTR", Name: "Twitter", Currency: "USD", CurrentPrice: 53.7})
+	metadata, ok := catalog.RecordMissing(ctx, "TWTR")
+	if !ok || metadata.LastPrice != 53.7 || metadata.MissingSince == nil || metadata.DelistedAt != nil {
+		t.Fatalf("Expected the symbol missing but not yet delisted, got %+v", metadata)
+	}
+
+	// Missing for longer than delistAfter, it is delisted
+	since := time.Now().Add(-delistAfter - time.Hour)
+	metadata.MissingSince = &since
+	if err := repos.Symbols.Save(ctx, &metadata); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := catalog.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if metadata, _ = catalog.RecordMissing(ctx, "TWTR"); metadata.DelistedAt == nil {
+		t.Fatalf("Expected the symbol delisted, got %+v", metadata)
+	}
+
+	// A new quote lists it again
+	catalog.Record(ctx, &StockInfo{Symbol: "TWTR", Name: "Twitter", Currency: "USD", CurrentPrice: 54})
+	if metadata, _ := catalog.Lookup("TWTR"); metadata.DelistedAt != nil || metadata.MissingSince != nil {
+		t.Errorf("Expected the symbol listed again, got %+v", metadata)
+	}
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"stock-portfolio-tracker/internal/analytics/engine"
+	"time"
+)
+
+// Moving average crosses
+const (
+	CrossGolden = "golden" // The 50-day average rose above the 200-day average
+	CrossDeath  = "death"  // The 50-day average fell below the 200-day average
+)
+
+const (
+	shortMovingAverageDays = 50
+	longMovingAverageDays  = 200
+
+	// technicalsCacheDuration bounds how long a symbol's technicals are
+	// reused. They only move with each day's close.
+	technicalsCacheDuration = time.Hour
+	technicalsCacheSize     = 2000
+)
+
+// MovingAverageCross is a close at which the 50-day moving average crossed
+// the 200-day one
+type MovingAverageCross struct {
+	Type string    `json:"type"` // CrossGolden or CrossDeath
+	Date time.Time `json:"date"`
+}
+
+// PriceTechnicals summarizes a symbol's daily closes. Averages and the range
+// are in the price history's currency and are 0 when the history is too short.
+type PriceTechnicals struct {
+	FiftyDayAverage      float64             `json:"fiftyDayAverage,omitempty"`
+	TwoHundredDayAverage float64             `json:"twoHundredDayAverage,omitempty"`
+	FiftyTwoWeekHigh     float64             `json:"fiftyTwoWeekHigh,omitempty"`
+	FiftyTwoWeekLow      float64             `json:"fiftyTwoWeekLow,omitempty"`
+	LastCross            *MovingAverageCross `json:"lastCross,omitempty"`
+	AsOf                 time.Time           `json:"asOf"` // Date of the latest close
+}
+
+// converted returns a copy of the technicals with the prices multiplied by rate
+func (t *PriceTechnicals) converted(rate float64) *PriceTechnicals {
+	converted := *t
+	converted.FiftyDayAverage *= rate
+	converted.TwoHundredDayAverage *= rate
+	converted.FiftyTwoWeekHigh *= rate
+	converted.FiftyTwoWeekLow *= rate
+	return &converted
+}
+
+// computeTechnicals returns the moving averages and 52-week range as of the
+// latest close, and the latest moving average cross. It returns nil for an
+// empty history.
+func computeTechnicals(prices []HistoricalPrice) *PriceTechnicals {
+	closes := make([]float64, 0, len(prices))
+	dates := make([]time.Time, 0, len(prices))
+	for _, price := range engine.Sorted(prices) {
+		if price.Price > 0 {
+			closes = append(closes, price.Price)
+			dates = append(dates, price.Date)
+		}
+	}
+	if len(closes) == 0 {
+		return nil
+	}
+
+	last := len(closes) - 1
+	technicals := &PriceTechnicals{AsOf: dates[last]}
+
+	yearAgo := dates[last].AddDate(-1, 0, 0)
+	for i, price := range closes {
+		if !dates[i].After(yearAgo) {
+			continue
+		}
+		if price > technicals.FiftyTwoWeekHigh {
+			technicals.FiftyTwoWeekHigh = price
+		}
+		if technicals.FiftyTwoWeekLow == 0 || price < technicals.FiftyTwoWeekLow {
+			technicals.FiftyTwoWeekLow = price
+		}
+	}
+
+	// Walk both averages along the series, noting each change of side
+	shortSum, longSum := 0.0, 0.0
+	side := 0
+	for i, price := range closes {
+		shortSum += price
+		longSum += price
+		if i >= shortMovingAverageDays {
+			shortSum -= closes[i-shortMovingAverageDays]
+		}
+		if i >= longMovingAverageDays {
+			longSum -= closes[i-longMovingAverageDays]
+		}
+		if i >= shortMovingAverageDays-1 {
+			technicals.FiftyDayAverage = shortSum / shortMovingAverageDays
+		}
+		if i < longMovingAverageDays-1 {
+			continue
+		}
+		technicals.TwoHundredDayAverage = longSum / longMovingAverageDays
+
+		current := 0
+		switch {
+		case technicals.FiftyDayAverage > technicals.TwoHundredDayAverage:
+			current = 1
+		case technicals.FiftyDayAverage < technicals.TwoHundredDayAverage:
+			current = -1
+		}
+		if current == 0 {
+			continue
+		}
+		if side != 0 && current != side {
+			cross := &MovingAverageCross{Type: CrossGolden, Date: dates[i]}
+			if current < 0 {
+				cross.Type = CrossDeath
+			}
+			technicals.LastCross = cross
+		}
+		side = current
+	}
+
+	return technicals
+}
+
+// SymbolTechnicals returns a symbol's moving averages, 52-week range and
+// latest moving average cross, in its trading currency. They are computed
+// from its daily closes and cached per symbol.
+func (s *PortfolioService) SymbolTechnicals(ctx context.Context, symbol string) (*PriceTechnicals, error) {
+	now := time.Now()
+	if technicals, ok := s.technicals.Get(symbol, now); ok {
+		return technicals, nil
+	}
+
+	prices, err := s.stockService.GetHistoricalDataContext(ctx, symbol, "ALL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price history for %s: %w", symbol, err)
+	}
+	technicals := computeTechnicals(prices)
+	if technicals == nil {
+		return nil, fmt.Errorf("%w: no price history for %s", ErrStockNotFound, symbol)
+	}
+
+	s.technicals.Set(symbol, technicals, now.Add(technicalsCacheDuration))
+	return technicals, nil
+}
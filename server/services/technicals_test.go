@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"math"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+)
+
+// crossingCloses returns a year of falling closes followed by rising ones,
+// so the 50-day average crosses below and then back above the 200-day one
+func crossingCloses() []float64 {
+	closes := make([]float64, 0, 400)
+	for i := 0; i < 150; i++ {
+		closes = append(closes, 100+float64(i)*0.1)
+	}
+	for i := 0; i < 150; i++ {
+		closes = append(closes, 115-float64(i)*0.2)
+	}
+	for i := 0; i < 100; i++ {
+		closes = append(closes, 85+float64(i)*0.5)
+	}
+	return closes
+}
+
+func TestComputeTechnicals(t *testing.T) {
+	end := time.Date(2024, 6, 28, 0, 0, 0, 0, time.UTC)
+	provider := NewFixtureProvider().SetHistory("AAPL", end, crossingCloses()...)
+	prices, _ := provider.GetHistoricalData("AAPL", "ALL")
+
+	technicals := computeTechnicals(prices)
+	if technicals == nil || !technicals.AsOf.Equal(end) {
+		t.Fatalf("Expected technicals as of %s, got %+v", end, technicals)
+	}
+
+	// The last 50 closes run from 110 to 134.5
+	if math.Abs(technicals.FiftyDayAverage-122.25) > 1e-9 {
+		t.Errorf("Expected 50-day average 122.25, got %.4f", technicals.FiftyDayAverage)
+	}
+	if technicals.TwoHundredDayAverage <= 0 || technicals.TwoHundredDayAverage >= technicals.FiftyDayAverage {
+		t.Errorf("Expected the 200-day average below the 50-day, got %.4f", technicals.TwoHundredDayAverage)
+	}
+	if technicals.FiftyTwoWeekHigh != 134.5 || math.Abs(technicals.FiftyTwoWeekLow-85) > 1e-9 {
+		t.Errorf("Expected 52-week range 85-134.5, got %.2f-%.2f", technicals.FiftyTwoWeekLow, technicals.FiftyTwoWeekHigh)
+	}
+	cross := technicals.LastCross
+	if cross == nil || cross.Type != CrossGolden || !cross.Date.After(end.AddDate(0, 0, -100)) {
+		t.Errorf("Expected a golden cross in the rally, got %+v", cross)
+	}
+
+	// Too short for the averages
+	short := computeTechnicals(prices[len(prices)-30:])
+	if short.FiftyDayAverage != 0 || short.TwoHundredDayAverage != 0 || short.LastCross != nil {
+		t.Errorf("Expected no averages over 30 closes, got %+v", short)
+	}
+	if computeTechnicals(nil) != nil {
+		t.Errorf("Expected no technicals without history")
+	}
+}
+
+func TestSymbolTechnicalsCached(t *testing.T) {
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+	provider := NewFixtureProvider().SetHistory("AAPL", end, crossingCloses()...)
+	service := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+
+	technicals, err := service.SymbolTechnicals(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	provider.SetHistory("AAPL", end, 1, 2, 3)
+	if cached, _ := service.SymbolTechnicals(context.Background(), "AAPL"); cached != technicals {
+		t.Errorf("Expected the cached technicals")
+	}
+	if _, err := service.SymbolTechnicals(context.Background(), "MISSING"); err == nil {
+		t.Errorf("Expected an error without price history")
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrTokenDecrypt is returned for a stored token that can't be decrypted,
+// such as one sealed with a different key
+var ErrTokenDecrypt = errors.New("failed to decrypt token")
+
+// TokenCipher encrypts third-party credentials before they are stored, such
+// as OAuth tokens and brokerage access tokens, with AES-256-GCM under the
+// credential key from the server configuration. One cipher is shared by
+// every service storing credentials.
+type TokenCipher struct {
+	aead cipher.AEAD
+}
+
+// NewTokenCipher creates a TokenCipher from a base64 encoded 32-byte key
+func NewTokenCipher(encodedKey string) (*TokenCipher, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid token key: %d bytes, want 32", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token key: %w", err)
+	}
+	return &TokenCipher{aead: aead}, nil
+}
+
+// Seal encrypts a token under a fresh nonce, returning the nonce and
+// ciphertext as base64
+func (c *TokenCipher) Seal(token string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(token), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a token sealed by Seal
+func (c *TokenCipher) Open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < c.aead.NonceSize() {
+		return "", ErrTokenDecrypt
+	}
+	nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	token, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrTokenDecrypt
+	}
+	return string(token), nil
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AllocationChange compares one symbol's position before and after simulated trades
+type AllocationChange struct {
+	Symbol       string  `json:"symbol"`
+	Name         string  `json:"name"`
+	ValueBefore  float64 `json:"valueBefore"`
+	ValueAfter   float64 `json:"valueAfter"`
+	WeightBefore float64 `json:"weightBefore"` // Percentage
+	WeightAfter  float64 `json:"weightAfter"`  // Percentage
+	WeightChange float64 `json:"weightChange"` // Percentage points
+}
+
+// TradeSimulation represents the portfolio before and after hypothetical
+// trades. Trades lists them as they were applied, with defaulted prices.
+type TradeSimulation struct {
+	Currency            string               `json:"currency"`
+	Trades              []models.Transaction `json:"trades"`
+	Before              *DashboardMetrics    `json:"before"`
+	After               *DashboardMetrics    `json:"after"`
+	ConcentrationBefore ConcentrationMetric  `json:"concentrationBefore"`
+	ConcentrationAfter  ConcentrationMetric  `json:"concentrationAfter"`
+	Changes             []AllocationChange   `json:"changes"` // Largest weight change first
+}
+
+// SimulateTrades applies hypothetical trades, dated now, to a copy of the
+// user's transactions and returns the dashboard and concentration before and
+// after. Trades are validated like real transactions, in order, so a sell may
+// close a simulated buy. Nothing is persisted.
+func (s *PortfolioService) SimulateTrades(ctx context.Context, userID primitive.ObjectID, trades []models.SimulatedTrade, currency string) (*TradeSimulation, error) {
+	currency, err := NormalizeDisplayCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	scratch, err := s.scratchCopy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	simulation := &TradeSimulation{Currency: currency, Trades: []models.Transaction{}}
+	simulation.Before, err = NewAnalyticsService(scratch, s.currencyService, s.stockService).calculateDashboardMetrics(ctx, userID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i, trade := range trades {
+		tx := models.Transaction{
+			ID:        primitive.NewObjectID(),
+			UserID:    userID,
+			Symbol:    strings.ToUpper(strings.TrimSpace(trade.Symbol)),
+			Action:    trade.Action,
+			Shares:    trade.Shares,
+			Price:     trade.Price,
+			Currency:  trade.Currency,
+			Fees:      trade.Fees,
+			Date:      now,
+			CreatedAt: now.Add(time.Duration(i)),
+		}
+		if tx.Price == 0 {
+			if tx.Price, err = s.quoteIn(ctx, tx.Symbol, tx.Currency); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := scratch.validateTransaction(&tx); err != nil {
+			return nil, fmt.Errorf("trade %d: %w", i+1, err)
+		}
+		if tx.Action == "sell" {
+			if err := scratch.validateSellTransaction(userID, &tx); err != nil {
+				return nil, fmt.Errorf("trade %d: %w", i+1, err)
+			}
+		}
+		if err := scratch.repos.Transactions.Insert(ctx, &tx); err != nil {
+			return nil, fmt.Errorf("failed to apply trade %d: %w", i+1, err)
+		}
+		simulation.Trades = append(simulation.Trades, tx)
+	}
+
+	simulation.After, err = NewAnalyticsService(scratch, s.currencyService, s.stockService).calculateDashboardMetrics(ctx, userID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	simulation.ConcentrationBefore = allocationConcentration(simulation.Before.Allocation)
+	simulation.ConcentrationAfter = allocationConcentration(simulation.After.Allocation)
+	simulation.Changes = allocationChanges(simulation.Before.Allocation, simulation.After.Allocation)
+	return simulation, nil
+}
+
+// scratchCopy returns a PortfolioService over in-memory copies of the user's
+// transactions and portfolio entries
+func (s *PortfolioService) scratchCopy(ctx context.Context, userID primitive.ObjectID) (*PortfolioService, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	transactions, err := s.repos.Transactions.FindSince(queryCtx, userID, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+	portfolios, err := s.repos.Portfolios.FindByUser(queryCtx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch portfolios: %w", err)
+	}
+
+	repos := repository.NewMemory()
+	for i := range transactions {
+		if err := repos.Transactions.Insert(ctx, &transactions[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range portfolios {
+		if err := repos.Portfolios.Insert(ctx, &portfolios[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewPortfolioServiceWithRepos(s.stockService, s.currencyService, repos), nil
+}
+
+// quoteIn returns the symbol's current price converted to currency
+func (s *PortfolioService) quoteIn(ctx context.Context, symbol, currency string) (float64, error) {
+	info, err := s.stockService.GetStockInfoContext(ctx, symbol)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch stock info for %s: %w", symbol, err)
+	}
+	if currencycode.Equal(info.Currency, currency) {
+		return info.CurrentPrice, nil
+	}
+	price, err := s.currencyService.ConvertAmount(info.CurrentPrice, info.Currency, currency)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert price of %s: %w", symbol, err)
+	}
+	return price, nil
+}
+
+// allocationConcentration computes the concentration of a dashboard allocation
+func allocationConcentration(allocation []AllocationItem) ConcentrationMetric {
+	holdings := make([]Holding, len(allocation))
+	weights := make([]float64, len(allocation))
+	for i, item := range allocation {
+		holdings[i] = Holding{Symbol: item.Symbol}
+		weights[i] = item.Percentage / 100
+	}
+	return concentration(holdings, weights)
+}
+
+// allocationChanges pairs the symbols of two allocations, largest weight
+// change first
+func allocationChanges(before, after []AllocationItem) []AllocationChange {
+	bySymbol := make(map[string]*AllocationChange)
+	changes := []*AllocationChange{}
+	entry := func(item AllocationItem) *AllocationChange {
+		change, ok := bySymbol[item.Symbol]
+		if !ok {
+			change = &AllocationChange{Symbol: item.Symbol, Name: item.Name}
+			bySymbol[item.Symbol] = change
+			changes = append(changes, change)
+		}
+		return change
+	}
+	for _, item := range before {
+		change := entry(item)
+		change.ValueBefore = item.Value
+		change.WeightBefore = item.Percentage
+	}
+	for _, item := range after {
+		change := entry(item)
+		change.ValueAfter = item.Value
+		change.WeightAfter = item.Percentage
+	}
+
+	result := make([]AllocationChange, 0, len(changes))
+	for _, change := range changes {
+		change.WeightChange = change.WeightAfter - change.WeightBefore
+		result = append(result, *change)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return math.Abs(result[i].WeightChange) > math.Abs(result[j].WeightChange)
+	})
+	return result
+}
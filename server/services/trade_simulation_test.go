@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSimulateTrades(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	provider := NewFixtureProvider().
+		SetQuote("AAPL", "Apple", 100, "USD").
+		SetHistory("AAPL", today, 100, 100).
+		SetQuote("MSFT", "Microsoft", 50, "USD").
+		SetHistory("MSFT", today, 50, 50)
+	service := NewPortfolioServiceWithRepos(provider, provider, repository.NewMemory())
+	userID := primitive.NewObjectID()
+
+	for _, tx := range []*models.Transaction{
+		{Symbol: "AAPL", Action: "buy", Shares: 30, Price: 80, Currency: "USD", Date: today.AddDate(0, 0, -10)},
+		{Symbol: "MSFT", Action: "buy", Shares: 20, Price: 50, Currency: "USD", Date: today.AddDate(0, 0, -10)},
+	} {
+		if err := service.AddTransaction(userID, tx); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+
+	// Trim AAPL at the quote and add to MSFT
+	simulation, err := service.SimulateTrades(context.Background(), userID, []models.SimulatedTrade{
+		{Symbol: "aapl", Action: "sell", Shares: 10, Currency: "USD"},
+		{Symbol: "MSFT", Action: "buy", Shares: 20, Price: 50, Currency: "USD"},
+	}, "USD")
+	if err != nil {
+		t.Fatalf("Failed to simulate: %v", err)
+	}
+
+	if simulation.Trades[0].Price != 100 {
+		t.Errorf("Expected the sell to default to the 100 quote, got %.2f", simulation.Trades[0].Price)
+	}
+	if simulation.Before.TotalValue != 4000 || simulation.After.TotalValue != 4000 {
+		t.Errorf("Expected 4000 before and after, got %.2f and %.2f", simulation.Before.TotalValue, simulation.After.TotalValue)
+	}
+
+	// 75/25 becomes 50/50
+	if math.Abs(simulation.ConcentrationBefore.HHI-0.625) > 1e-9 || math.Abs(simulation.ConcentrationAfter.HHI-0.5) > 1e-9 {
+		t.Errorf("Expected HHI 0.625 then 0.5, got %v then %v", simulation.ConcentrationBefore.HHI, simulation.ConcentrationAfter.HHI)
+	}
+	if len(simulation.Changes) != 2 || math.Abs(math.Abs(simulation.Changes[0].WeightChange)-25) > 1e-9 {
+		t.Errorf("Expected 25 point weight changes, got %+v", simulation.Changes)
+	}
+
+	// Nothing was recorded
+	holdings, err := service.GetUserHoldings(userID, "USD")
+	if err != nil {
+		t.Fatalf("Failed to get holdings: %v", err)
+	}
+	for _, holding := range holdings {
+		if holding.Symbol == "AAPL" && holding.Shares != 30 || holding.Symbol == "MSFT" && holding.Shares != 20 {
+			t.Errorf("Simulation changed holding %+v", holding)
+		}
+	}
+
+	// Selling more than held after the earlier simulated trades is rejected
+	_, err = service.SimulateTrades(context.Background(), userID, []models.SimulatedTrade{
+		{Symbol: "AAPL", Action: "sell", Shares: 20, Price: 100, Currency: "USD"},
+		{Symbol: "AAPL", Action: "sell", Shares: 20, Price: 100, Currency: "USD"},
+	}, "USD")
+	if !errors.Is(err, ErrInsufficientShares) {
+		t.Errorf("Expected ErrInsufficientShares, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+	"stock-portfolio-tracker/models"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits on the free-form labels users attach to transactions
+const (
+	maxTransactionNoteLength = 1000
+	maxTransactionTags       = 20
+	maxTransactionTagLength  = 50
+)
+
+// NormalizeTag returns the stored form of a tag: trimmed, lower case and with
+// inner whitespace collapsed, so "Earnings  Play" and "earnings play" match
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// normalizeTransactionLabels trims the note, normalizes and de-duplicates the
+// tags in their original order, and checks both against the limits
+func normalizeTransactionLabels(tx *models.Transaction) error {
+	tx.Note = strings.TrimSpace(tx.Note)
+	if utf8.RuneCountInString(tx.Note) > maxTransactionNoteLength {
+		return fmt.Errorf("%w: note cannot exceed %d characters", ErrInvalidTransaction, maxTransactionNoteLength)
+	}
+
+	var tags []string
+	seen := make(map[string]bool, len(tx.Tags))
+	for _, tag := range tx.Tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTransactionTagLength {
+			return fmt.Errorf("%w: tags cannot exceed %d characters", ErrInvalidTransaction, maxTransactionTagLength)
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTransactionTags {
+		return fmt.Errorf("%w: a transaction can have at most %d tags", ErrInvalidTransaction, maxTransactionTags)
+	}
+	tx.Tags = tags
+	return nil
+}
+
+// FilterTransactionsByTag returns the transactions carrying the tag
+func FilterTransactionsByTag(transactions []models.Transaction, tag string) []models.Transaction {
+	tag = NormalizeTag(tag)
+	filtered := []models.Transaction{}
+	for _, tx := range transactions {
+		for _, t := range tx.Tags {
+			if t == tag {
+				filtered = append(filtered, tx)
+				break
+			}
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"stock-portfolio-tracker/currencycode"
+	"stock-portfolio-tracker/models"
+	"stock-portfolio-tracker/repository"
+	"stock-portfolio-tracker/telemetry"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrWebhookNotFound       = errors.New("webhook not found")
+	ErrInvalidWebhookURL     = errors.New("webhook URLs must be http or https URLs")
+	ErrTooManyWebhooks       = errors.New("too many webhooks")
+	ErrWebhookDeliveryFailed = errors.New("webhook delivery failed")
+
+	// errWebhookAddressBlocked refuses connections to loopback, private and
+	// other non-public addresses, so webhooks can't reach internal services
+	errWebhookAddressBlocked = errors.New("webhook address is not public")
+)
+
+const (
+	// WebhookSummaryJobInterval is how often the scheduler checks for due
+	// daily summaries
+	WebhookSummaryJobInterval = time.Hour
+	// webhookSummaryPeriod is the time between two summaries of a webhook
+	webhookSummaryPeriod = 24 * time.Hour
+	// maxWebhooks bounds the webhooks of a single user
+	maxWebhooks = 5
+	// webhookTimeout bounds a single delivery
+	webhookTimeout = 10 * time.Second
+	// webhookSecretBytes is the amount of randomness in a signing secret
+	webhookSecretBytes = 32
+	// webhookEventTest is posted by SendTest
+	webhookEventTest = "test"
+)
+
+// webhookAlertSources are the notification sources posted to alert webhooks
+var webhookAlertSources = []string{"stops", "drift-alerts", "pending-orders"}
+
+// WebhookPayload is the JSON body posted to a webhook
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookAlert is the data of an alerts event
+type WebhookAlert struct {
+	Source  string `json:"source"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+}
+
+// WebhookService posts daily portfolio summaries and alerts to the URLs users
+// register. Every post is signed with the webhook's secret: the
+// X-Webhook-Signature header is "sha256=" and the hex HMAC-SHA256 of the
+// X-Webhook-Timestamp header, a dot and the body.
+type WebhookService struct {
+	repos            repository.Repositories
+	analyticsService *AnalyticsService
+	client           *http.Client
+}
+
+// NewWebhookService creates a new WebhookService instance
+func NewWebhookService(analyticsService *AnalyticsService) *WebhookService {
+	return &WebhookService{
+		repos:            analyticsService.portfolioService.repos,
+		analyticsService: analyticsService,
+		client:           newWebhookClient(),
+	}
+}
+
+// newWebhookClient returns an HTTP client that only connects to public
+// addresses and doesn't follow redirects. The address is checked after DNS
+// resolution, so a public name resolving to a private address is refused too.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("%w: %s", errWebhookAddressBlocked, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: telemetry.Transport(transport),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicIP reports whether ip is a routable public unicast address
+func publicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsUnspecified()
+}
+
+// validateWebhookURL checks that a webhook URL is an absolute http or https URL
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+// generateWebhookSecret returns a random signing secret
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// SignWebhook returns the X-Webhook-Signature header of a post
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateWebhook registers a webhook and returns it with its signing secret,
+// which is not shown again
+func (s *WebhookService) CreateWebhook(userID primitive.ObjectID, req models.WebhookRequest) (*models.Webhook, string, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	existing, err := s.repos.Webhooks.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch webhooks: %w", err)
+	}
+	if len(existing) >= maxWebhooks {
+		return nil, "", ErrTooManyWebhooks
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	events := []string{}
+	for _, event := range req.Events {
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	currency := "USD"
+	if req.Currency != "" {
+		currency = currencycode.Normalize(req.Currency)
+	}
+
+	webhook := &models.Webhook{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    events,
+		Currency:  currency,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repos.Webhooks.Insert(ctx, webhook); err != nil {
+		return nil, "", fmt.Errorf("failed to insert webhook: %w", err)
+	}
+
+	return webhook, secret, nil
+}
+
+// ListWebhooks returns the user's webhooks, oldest first
+func (s *WebhookService) ListWebhooks(userID primitive.ObjectID) ([]models.Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	webhooks, err := s.repos.Webhooks.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes a webhook
+func (s *WebhookService) DeleteWebhook(userID, webhookID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.repos.Webhooks.Delete(ctx, userID, webhookID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrWebhookNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// SendTest posts a test event to a webhook, so users can check their
+// endpoint and its signature verification
+func (s *WebhookService) SendTest(userID, webhookID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	webhook, err := s.repos.Webhooks.FindByID(ctx, userID, webhookID)
+	cancel()
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrWebhookNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find webhook: %w", err)
+	}
+
+	if err := s.deliver(webhook, webhookEventTest, map[string]string{"message": "Webhook test from Stock Tracker"}); err != nil {
+		return fmt.Errorf("%w: %v", ErrWebhookDeliveryFailed, err)
+	}
+	return nil
+}
+
+// SendDailySummaries posts the dashboard to every summary webhook that
+// hasn't had one in the last day. Each webhook is claimed with a conditional
+// update first, so with several instances only one of them posts.
+func (s *WebhookService) SendDailySummaries() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	webhooks, err := s.repos.Webhooks.FindByEvent(ctx, models.WebhookEventSummary)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to fetch summary webhooks: %w", err)
+	}
+
+	now := time.Now()
+	sent := 0
+	var errs []error
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		// Allow one job interval of slack so the send time doesn't creep later every day
+		if webhook.LastSummaryAt != nil && webhook.LastSummaryAt.After(now.Add(-webhookSummaryPeriod+WebhookSummaryJobInterval)) {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := s.repos.Webhooks.ClaimSummary(ctx, webhook.UserID, webhook.ID, webhook.LastSummaryAt, now)
+		cancel()
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to claim webhook %s: %w", webhook.ID.Hex(), err))
+			continue
+		}
+
+		dashboard, err := s.analyticsService.GetDashboardMetrics(webhook.UserID, webhook.Currency)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to calculate dashboard for webhook %s: %w", webhook.ID.Hex(), err))
+			continue
+		}
+		if err := s.deliver(webhook, models.WebhookEventSummary, dashboard); err != nil {
+			errs = append(errs, fmt.Errorf("failed to post summary to webhook %s: %w", webhook.ID.Hex(), err))
+			continue
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		fmt.Printf("[Webhook] Posted %d daily summaries\n", sent)
+	}
+	return errors.Join(errs...)
+}
+
+// Name returns the channel name
+func (s *WebhookService) Name() string {
+	return "webhook"
+}
+
+// Send posts stop, drift and pending order notifications to the recipient's
+// alert webhooks. Failures are recorded on the webhook rather than returned:
+// the outbox retries a channel as a whole, so a broken endpoint would
+// otherwise repost to the user's working ones.
+func (s *WebhookService) Send(recipient *models.User, notification Notification) error {
+	if !slices.Contains(webhookAlertSources, notification.Source) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	webhooks, err := s.repos.Webhooks.FindByUser(ctx, recipient.ID)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to fetch webhooks: %w", err)
+	}
+
+	alert := WebhookAlert{Source: notification.Source, Subject: notification.Subject, Text: notification.Text}
+	for i := range webhooks {
+		if !slices.Contains(webhooks[i].Events, models.WebhookEventAlerts) {
+			continue
+		}
+		if err := s.deliver(&webhooks[i], models.WebhookEventAlerts, alert); err != nil {
+			fmt.Printf("[Webhook] Failed to post alert to webhook %s: %v\n", webhooks[i].ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// deliver posts a signed event to a webhook and records the outcome on it
+func (s *WebhookService) deliver(webhook *models.Webhook, event string, data interface{}) error {
+	now := time.Now()
+	postErr := s.post(webhook, WebhookPayload{
+		ID:        primitive.NewObjectID().Hex(),
+		Event:     event,
+		CreatedAt: now,
+		Data:      data,
+	}, now)
+
+	lastError := ""
+	if postErr != nil {
+		lastError = postErr.Error()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.repos.Webhooks.RecordDelivery(ctx, webhook.UserID, webhook.ID, now, lastError); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return errors.Join(postErr, fmt.Errorf("failed to record webhook delivery: %w", err))
+	}
+	return postErr
+}
+
+// post sends a payload and checks for a 2xx response
+func (s *WebhookService) post(webhook *models.Webhook, payload WebhookPayload, now time.Time) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "stock-tracker-webhooks/1")
+	req.Header.Set("X-Webhook-Event", payload.Event)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", SignWebhook(webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInsufficientHistory = errors.New("not enough price history to simulate withdrawals")
+	ErrInvalidSimulation   = errors.New("invalid withdrawal simulation parameters")
+)
+
+const (
+	WithdrawalMethodHistorical = "historical"
+	WithdrawalMethodMonteCarlo = "montecarlo"
+
+	// minSimulationHistoryMonths is the shortest monthly return history accepted
+	minSimulationHistoryMonths = 12
+	// defaultMonteCarloPaths is the number of Monte Carlo paths when none is given
+	defaultMonteCarloPaths = 1000
+	// maxMonteCarloPaths bounds the cost of a single simulation request
+	maxMonteCarloPaths = 10000
+	// maxSimulationYears bounds the simulation horizon
+	maxSimulationYears = 60
+	// safeWithdrawalSuccessRate is the success rate a safe withdrawal rate must reach
+	safeWithdrawalSuccessRate = 95.0
+	// maxSafeWithdrawalRate is the upper bound of the safe withdrawal rate search
+	maxSafeWithdrawalRate = 30.0
+)
+
+// WithdrawalSimulationParams represents the inputs of a withdrawal simulation
+type WithdrawalSimulationParams struct {
+	Currency          string
+	InitialValue      float64 // Starting balance; 0 uses the current portfolio value
+	AnnualWithdrawal  float64 // First-year withdrawal amount
+	WithdrawalRate    float64 // First-year withdrawal as a percentage of the initial value, used when AnnualWithdrawal is 0
+	Years             int
+	InflationAdjusted bool
+	InflationRate     float64 // Annual inflation percentage applied when InflationAdjusted is set
+	Method            string  // historical or montecarlo
+	Simulations       int     // Number of Monte Carlo paths
+	Seed              int64   // Monte Carlo seed; 0 picks one
+}
+
+// WithdrawalPercentiles represents the distribution of simulated balances
+type WithdrawalPercentiles struct {
+	P10 float64 `json:"p10"`
+	P25 float64 `json:"p25"`
+	P50 float64 `json:"p50"`
+	P75 float64 `json:"p75"`
+	P90 float64 `json:"p90"`
+}
+
+// WithdrawalYearBalances represents the balance distribution at the end of a year
+type WithdrawalYearBalances struct {
+	Year int `json:"year"`
+	WithdrawalPercentiles
+}
+
+// WithdrawalSimulationResult represents the outcome of a withdrawal simulation
+type WithdrawalSimulationResult struct {
+	Currency             string                   `json:"currency"`
+	Method               string                   `json:"method"`
+	Seed                 int64                    `json:"seed,omitempty"`
+	Years                int                      `json:"years"`
+	Simulations          int                      `json:"simulations"`
+	HistoryMonths        int                      `json:"historyMonths"`
+	InitialValue         float64                  `json:"initialValue"`
+	InitialWithdrawal    float64                  `json:"initialWithdrawal"`
+	WithdrawalRate       float64                  `json:"withdrawalRate"`
+	InflationAdjusted    bool                     `json:"inflationAdjusted"`
+	InflationRate        float64                  `json:"inflationRate"`
+	DepletionProbability float64                  `json:"depletionProbability"`
+	SuccessRate          float64                  `json:"successRate"`
+	EndingBalance        WithdrawalPercentiles    `json:"endingBalance"`
+	YearlyBalances       []WithdrawalYearBalances `json:"yearlyBalances"`
+	SafeWithdrawalRate   float64                  `json:"safeWithdrawalRate"`   // Highest initial rate with at least 95% success
+	SafeWithdrawalAmount float64                  `json:"safeWithdrawalAmount"` // First-year amount at the safe rate
+}
+
+// WithdrawalService simulates withdrawals from the current portfolio
+type WithdrawalService struct {
+	portfolioService *PortfolioService
+	backtestService  *BacktestService
+}
+
+// NewWithdrawalService creates a new WithdrawalService instance
+func NewWithdrawalService(portfolioService *PortfolioService, backtestService *BacktestService) *WithdrawalService {
+	return &WithdrawalService{
+		portfolioService: portfolioService,
+		backtestService:  backtestService,
+	}
+}
+
+// SimulateWithdrawals models withdrawals from the user's current portfolio using
+// the monthly returns its holdings would have produced over the last ten years.
+// Prices are fetched under ctx.
+func (s *WithdrawalService) SimulateWithdrawals(ctx context.Context, userID primitive.ObjectID, params WithdrawalSimulationParams) (*WithdrawalSimulationResult, error) {
+	if err := validateWithdrawalParams(&params); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("[Withdrawal] Simulating %d years of withdrawals for user %s using %s returns\n",
+		params.Years, userID.Hex(), params.Method)
+
+	holdings, err := s.portfolioService.GetUserHoldingsContext(ctx, userID, params.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user holdings: %w", err)
+	}
+	if len(holdings) == 0 {
+		return nil, fmt.Errorf("no holdings found for user")
+	}
+
+	if params.InitialValue == 0 {
+		for _, holding := range holdings {
+			params.InitialValue += holding.CurrentValue
+		}
+	}
+
+	// Replay the current allocation over the available history
+	endDate := time.Now()
+	startDate := endDate.AddDate(-10, 0, 0)
+	weights := s.backtestService.calculatePortfolioWeights(holdings)
+	historicalPrices, err := s.backtestService.getHistoricalPrices(ctx, holdings, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get historical prices: %w", err)
+	}
+	performance, _, err := s.backtestService.calculateBacktestPerformance(weights, historicalPrices, startDate, endDate, params.Currency, holdings, BacktestCosts{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate historical performance: %w", err)
+	}
+
+	returns := monthlyReturns(performance)
+	if len(returns) < minSimulationHistoryMonths {
+		return nil, ErrInsufficientHistory
+	}
+
+	return runWithdrawalSimulation(params, returns), nil
+}
+
+// validateWithdrawalParams checks the simulation inputs and fills in defaults
+func validateWithdrawalParams(params *WithdrawalSimulationParams) error {
+	if params.Currency == "" {
+		params.Currency = "USD"
+	}
+	currency, err := NormalizeDisplayCurrency(params.Currency)
+	if err != nil {
+		return fmt.Errorf("%w: currency must be one of %s", ErrInvalidSimulation, strings.Join(DisplayCurrencies, ", "))
+	}
+	params.Currency = currency
+
+	if params.Years < 1 || params.Years > maxSimulationYears {
+		return fmt.Errorf("%w: years must be between 1 and %d", ErrInvalidSimulation, maxSimulationYears)
+	}
+	if params.InitialValue < 0 || params.AnnualWithdrawal < 0 || params.InflationRate < 0 {
+		return fmt.Errorf("%w: amounts cannot be negative", ErrInvalidSimulation)
+	}
+	if params.AnnualWithdrawal == 0 && (params.WithdrawalRate <= 0 || params.WithdrawalRate > 100) {
+		return fmt.Errorf("%w: provide annualWithdrawal or a withdrawalRate between 0 and 100", ErrInvalidSimulation)
+	}
+
+	switch params.Method {
+	case "":
+		params.Method = WithdrawalMethodHistorical
+	case WithdrawalMethodHistorical:
+	case WithdrawalMethodMonteCarlo:
+		if params.Simulations == 0 {
+			params.Simulations = defaultMonteCarloPaths
+		}
+		if params.Simulations < 1 || params.Simulations > maxMonteCarloPaths {
+			return fmt.Errorf("%w: simulations must be between 1 and %d", ErrInvalidSimulation, maxMonteCarloPaths)
+		}
+		if params.Seed == 0 {
+			params.Seed = time.Now().UnixNano()
+		}
+	default:
+		return fmt.Errorf("%w: method must be historical or montecarlo", ErrInvalidSimulation)
+	}
+
+	return nil
+}
+
+// monthlyReturns converts a daily value series into month-over-month returns,
+// using the last value of each calendar month
+func monthlyReturns(points []BacktestDataPoint) []float64 {
+	var monthEnds []float64
+	for i, point := range points {
+		last := i == len(points)-1
+		if last || points[i+1].Date.Month() != point.Date.Month() || points[i+1].Date.Year() != point.Date.Year() {
+			monthEnds = append(monthEnds, point.PortfolioValue)
+		}
+	}
+
+	returns := make([]float64, 0, len(monthEnds))
+	for i := 1; i < len(monthEnds); i++ {
+		if monthEnds[i-1] > 0 {
+			returns = append(returns, monthEnds[i]/monthEnds[i-1]-1)
+		}
+	}
+	return returns
+}
+
+// withdrawalScenario describes the monthly return paths a simulation runs over.
+// Historical paths start at every month of the history and wrap around at the
+// end; Monte Carlo paths draw months from the history at random.
+type withdrawalScenario struct {
+	returns []float64
+	method  string
+	paths   int
+	months  int
+	seed    uint64
+}
+
+// monthlyReturn returns the return of a path in a given month. Monte Carlo
+// draws are derived from the seed, path, and month so every run over the same
+// scenario sees identical paths.
+func (sc withdrawalScenario) monthlyReturn(path, month int) float64 {
+	n := len(sc.returns)
+	if sc.method == WithdrawalMethodHistorical {
+		return sc.returns[(path+month)%n]
+	}
+	draw := splitMix64(sc.seed ^ uint64(path)<<32 ^ uint64(month))
+	return sc.returns[draw%uint64(n)]
+}
+
+// splitMix64 is a fast stateless hash used to derive Monte Carlo draws
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// run simulates monthly withdrawals on every path and returns the number of
+// depleted paths. Withdrawals are taken at the start of each month. When
+// yearEnd is not nil it is called with each path's balance at every year end.
+func (sc withdrawalScenario) run(
+	initialValue float64,
+	annualWithdrawal float64,
+	annualInflation float64,
+	yearEnd func(path, year int, balance float64),
+) int {
+	depleted := 0
+	for path := 0; path < sc.paths; path++ {
+		balance := initialValue
+		withdrawal := annualWithdrawal / 12
+		for month := 0; month < sc.months; month++ {
+			if month > 0 && month%12 == 0 {
+				withdrawal *= 1 + annualInflation
+			}
+
+			if balance > 0 {
+				balance -= withdrawal
+				if balance <= 0 {
+					balance = 0
+					depleted++
+				} else {
+					balance *= 1 + sc.monthlyReturn(path, month)
+				}
+			}
+
+			if yearEnd != nil && (month+1)%12 == 0 {
+				yearEnd(path, (month+1)/12, balance)
+			}
+		}
+	}
+	return depleted
+}
+
+// runWithdrawalSimulation runs a validated simulation over monthly returns
+func runWithdrawalSimulation(params WithdrawalSimulationParams, returns []float64) *WithdrawalSimulationResult {
+	scenario := withdrawalScenario{
+		returns: returns,
+		method:  params.Method,
+		paths:   len(returns),
+		months:  params.Years * 12,
+	}
+	if params.Method == WithdrawalMethodMonteCarlo {
+		scenario.paths = params.Simulations
+		scenario.seed = uint64(params.Seed)
+	}
+
+	annualInflation := 0.0
+	if params.InflationAdjusted {
+		annualInflation = params.InflationRate / 100
+	}
+
+	initialWithdrawal := params.AnnualWithdrawal
+	if initialWithdrawal == 0 {
+		initialWithdrawal = params.InitialValue * params.WithdrawalRate / 100
+	}
+
+	yearly := make([][]float64, params.Years)
+	for year := range yearly {
+		yearly[year] = make([]float64, scenario.paths)
+	}
+	depleted := scenario.run(params.InitialValue, initialWithdrawal, annualInflation, func(path, year int, balance float64) {
+		yearly[year-1][path] = balance
+	})
+
+	result := &WithdrawalSimulationResult{
+		Currency:             params.Currency,
+		Method:               params.Method,
+		Years:                params.Years,
+		Simulations:          scenario.paths,
+		HistoryMonths:        len(returns),
+		InitialValue:         params.InitialValue,
+		InitialWithdrawal:    initialWithdrawal,
+		InflationAdjusted:    params.InflationAdjusted,
+		InflationRate:        params.InflationRate,
+		DepletionProbability: float64(depleted) / float64(scenario.paths) * 100,
+		YearlyBalances:       make([]WithdrawalYearBalances, params.Years),
+	}
+	if params.Method == WithdrawalMethodMonteCarlo {
+		result.Seed = params.Seed
+	}
+	result.SuccessRate = 100 - result.DepletionProbability
+	if params.InitialValue > 0 {
+		result.WithdrawalRate = initialWithdrawal / params.InitialValue * 100
+	}
+
+	for year, balances := range yearly {
+		result.YearlyBalances[year] = WithdrawalYearBalances{
+			Year:                  year + 1,
+			WithdrawalPercentiles: balancePercentiles(balances),
+		}
+	}
+	result.EndingBalance = result.YearlyBalances[params.Years-1].WithdrawalPercentiles
+
+	// Binary search the highest initial rate that keeps enough paths solvent.
+	// Every search step reuses the same paths, so success falls monotonically with the rate.
+	if params.InitialValue > 0 {
+		low, high := 0.0, maxSafeWithdrawalRate
+		for i := 0; i < 16; i++ {
+			rate := (low + high) / 2
+			failed := scenario.run(params.InitialValue, params.InitialValue*rate/100, annualInflation, nil)
+			if 100-float64(failed)/float64(scenario.paths)*100 >= safeWithdrawalSuccessRate {
+				low = rate
+			} else {
+				high = rate
+			}
+		}
+		result.SafeWithdrawalRate = math.Round(low*100) / 100
+		result.SafeWithdrawalAmount = params.InitialValue * result.SafeWithdrawalRate / 100
+	}
+
+	return result
+}
+
+// balancePercentiles returns the percentiles of a set of balances
+func balancePercentiles(balances []float64) WithdrawalPercentiles {
+	sorted := append([]float64(nil), balances...)
+	sort.Float64s(sorted)
+
+	return WithdrawalPercentiles{
+		P10: percentile(sorted, 10),
+		P25: percentile(sorted, 25),
+		P50: percentile(sorted, 50),
+		P75: percentile(sorted, 75),
+		P90: percentile(sorted, 90),
+	}
+}
+
+// percentile returns the p-th percentile of sorted values using linear interpolation
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMonthlyReturns(t *testing.T) {
+	points := []BacktestDataPoint{
+		{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), PortfolioValue: 90},
+		{Date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), PortfolioValue: 100},
+		{Date: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), PortfolioValue: 105},
+		{Date: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), PortfolioValue: 110},
+		{Date: time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC), PortfolioValue: 99},
+	}
+
+	returns := monthlyReturns(points)
+	want := []float64{0.1, -0.1}
+	if len(returns) != len(want) {
+		t.Fatalf("Expected %d returns, got %d", len(want), len(returns))
+	}
+	for i := range want {
+		if math.Abs(returns[i]-want[i]) > 1e-9 {
+			t.Errorf("Return %d = %.4f, want %.4f", i, returns[i], want[i])
+		}
+	}
+}
+
+func TestRunWithdrawalSimulationFlatReturns(t *testing.T) {
+	returns := make([]float64, 24)
+	params := WithdrawalSimulationParams{
+		Currency:       "USD",
+		InitialValue:   1000,
+		WithdrawalRate: 10,
+		Years:          5,
+		Method:         WithdrawalMethodHistorical,
+	}
+
+	result := runWithdrawalSimulation(params, returns)
+
+	if result.Simulations != 24 {
+		t.Errorf("Expected one historical path per month, got %d", result.Simulations)
+	}
+	if result.DepletionProbability != 0 {
+		t.Errorf("Expected no depletion, got %.2f%%", result.DepletionProbability)
+	}
+	if math.Abs(result.EndingBalance.P50-500) > 1e-6 {
+		t.Errorf("Expected ending balance of 500, got %.4f", result.EndingBalance.P50)
+	}
+
+	// Without growth, withdrawing 20% a year runs out after exactly five years
+	if math.Abs(result.SafeWithdrawalRate-20) > 0.01 {
+		t.Errorf("Expected safe withdrawal rate near 20%%, got %.4f", result.SafeWithdrawalRate)
+	}
+}
+
+func TestRunWithdrawalSimulationMonteCarloIsReproducible(t *testing.T) {
+	returns := []float64{0.05, -0.04, 0.02, -0.01, 0.03, -0.06, 0.01, 0.04, -0.02, 0.02, 0.0, -0.03}
+	params := WithdrawalSimulationParams{
+		Currency:          "USD",
+		InitialValue:      100000,
+		AnnualWithdrawal:  6000,
+		Years:             30,
+		InflationAdjusted: true,
+		InflationRate:     3,
+		Method:            WithdrawalMethodMonteCarlo,
+		Simulations:       200,
+		Seed:              42,
+	}
+
+	first := runWithdrawalSimulation(params, returns)
+	second := runWithdrawalSimulation(params, returns)
+
+	if first.DepletionProbability != second.DepletionProbability || first.EndingBalance != second.EndingBalance {
+		t.Errorf("Expected identical results for the same seed")
+	}
+	if first.SuccessRate+first.DepletionProbability != 100 {
+		t.Errorf("Success and depletion should add up to 100%%")
+	}
+}
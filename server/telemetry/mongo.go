@@ -0,0 +1,90 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// mongoMonitor records each MongoDB command as a client span. The driver
+// reports the start and end of a command in separate callbacks, so open spans
+// are kept by request ID until the command finishes.
+type mongoMonitor struct {
+	spans sync.Map // int64 request ID -> trace.Span
+}
+
+// NewMongoMonitor returns a command monitor that traces MongoDB commands as
+// children of the span in the operation's context
+func NewMongoMonitor() *event.CommandMonitor {
+	m := &mongoMonitor{}
+	return &event.CommandMonitor{
+		Started:   m.started,
+		Succeeded: m.succeeded,
+		Failed:    m.failed,
+	}
+}
+
+func (m *mongoMonitor) started(ctx context.Context, evt *event.CommandStartedEvent) {
+	collection := commandCollection(evt.Command, evt.CommandName)
+
+	name := evt.CommandName
+	if collection != "" {
+		name += " " + collection
+	}
+
+	attributes := []attribute.KeyValue{
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.name", evt.DatabaseName),
+		attribute.String("db.operation", evt.CommandName),
+	}
+	if collection != "" {
+		attributes = append(attributes, attribute.String("db.mongodb.collection", collection))
+	}
+
+	_, span := tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+	m.spans.Store(evt.RequestID, span)
+}
+
+func (m *mongoMonitor) succeeded(_ context.Context, evt *event.CommandSucceededEvent) {
+	if span, ok := m.finish(evt.RequestID); ok {
+		span.End()
+	}
+}
+
+func (m *mongoMonitor) failed(_ context.Context, evt *event.CommandFailedEvent) {
+	if span, ok := m.finish(evt.RequestID); ok {
+		span.SetStatus(codes.Error, evt.Failure)
+		span.End()
+	}
+}
+
+// finish removes and returns the open span of a command
+func (m *mongoMonitor) finish(requestID int64) (trace.Span, bool) {
+	value, ok := m.spans.LoadAndDelete(requestID)
+	if !ok {
+		return nil, false
+	}
+	return value.(trace.Span), true
+}
+
+// commandCollection returns the collection a command targets. Most commands
+// name it as the value of their first element, e.g. {find: "transactions"}.
+func commandCollection(command bson.Raw, commandName string) string {
+	value, err := command.LookupErr(commandName)
+	if err != nil {
+		return ""
+	}
+	collection, ok := value.StringValueOK()
+	if !ok {
+		return ""
+	}
+	return collection
+}
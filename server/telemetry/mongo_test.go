@@ -0,0 +1,83 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMongoMonitorRecordsCommandSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	// Commands should be children of the request span
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "GET /api/analytics/dashboard")
+
+	find, _ := bson.Marshal(bson.D{{Key: "find", Value: "transactions"}, {Key: "filter", Value: bson.D{}}})
+	aggregate, _ := bson.Marshal(bson.D{{Key: "aggregate", Value: "portfolios"}})
+
+	monitor := NewMongoMonitor()
+	monitor.Started(ctx, &event.CommandStartedEvent{Command: find, DatabaseName: "stock-portfolio", CommandName: "find", RequestID: 1})
+	monitor.Started(ctx, &event.CommandStartedEvent{Command: aggregate, DatabaseName: "stock-portfolio", CommandName: "aggregate", RequestID: 2})
+	monitor.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "aggregate", RequestID: 2}, Failure: "timeout"})
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1}})
+	// A finish without a start is ignored
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 3}})
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 ended spans, got %d", len(spans))
+	}
+
+	failed, succeeded := spans[0], spans[1]
+	if failed.Name() != "aggregate portfolios" || failed.Status().Code != codes.Error || failed.Status().Description != "timeout" {
+		t.Errorf("Unexpected failed span %q with status %+v", failed.Name(), failed.Status())
+	}
+	if succeeded.Name() != "find transactions" || succeeded.Status().Code == codes.Error {
+		t.Errorf("Unexpected succeeded span %q with status %+v", succeeded.Name(), succeeded.Status())
+	}
+	if succeeded.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Expected command span to be a child of the request span")
+	}
+
+	found := false
+	for _, kv := range succeeded.Attributes() {
+		if kv.Key == attribute.Key("db.mongodb.collection") && kv.Value.AsString() == "transactions" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected collection attribute, got %v", succeeded.Attributes())
+	}
+}
+
+func TestInitDisabledWithoutEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+
+	if Enabled() {
+		t.Fatal("Expected tracing to be disabled without an endpoint")
+	}
+	shutdown, err := Init(context.Background())
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("Expected no-op shutdown, got %v", err)
+	}
+	if ServiceName() != DefaultServiceName {
+		t.Errorf("Expected default service name, got %s", ServiceName())
+	}
+}
@@ -0,0 +1,90 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultServiceName names the service in traces unless OTEL_SERVICE_NAME is set
+const DefaultServiceName = "stock-portfolio-tracker"
+
+// instrumentationName identifies spans created by this package
+const instrumentationName = "stock-portfolio-tracker/telemetry"
+
+// Enabled reports whether an OTLP endpoint is configured. Tracing is off by
+// default so local runs do not try to reach a collector.
+func Enabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// ServiceName returns the service name reported in traces
+func ServiceName() string {
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		return name
+	}
+	return DefaultServiceName
+}
+
+// Init installs the global tracer provider exporting spans over OTLP/HTTP and
+// the W3C trace context propagator. The exporter reads the standard OTEL_*
+// environment variables for its endpoint, headers and timeout. When tracing is
+// disabled the global no-op provider is left in place. The returned function
+// flushes pending spans and must be called on shutdown.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", ServiceName())),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	log.Printf("Tracing enabled for service %s", ServiceName())
+	return provider.Shutdown, nil
+}
+
+// Transport wraps an HTTP transport so every outbound request is recorded as
+// a client span and carries the trace context. A nil base uses the default
+// transport.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
+
+// tracer returns the tracer of the current global provider
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
@@ -4,6 +4,7 @@ import { X, Search, Loader2, Plus } from 'lucide-react';
 import { useWatchlist } from '../contexts/WatchlistContext';
 import { useToast } from '../contexts/ToastContext';
 import { searchStock, StockInfo } from '../api/stocks';
+import { currencySymbol } from '../utils/formatters';
 
 interface AddToWatchlistDialogProps {
     open: boolean;
@@ -165,7 +166,7 @@ const AddToWatchlistDialog: React.FC<AddToWatchlistDialogProps> = ({ open, onClo
                                             </p>
                                             <div className="flex items-baseline gap-2">
                                                 <span className="text-lg font-bold text-gray-900 dark:text-white">
-                                                    {searchResult.currency === 'CNY' ? '¥' : '$'}
+                                                    {currencySymbol(searchResult.currency)}
                                                     {searchResult.currentPrice.toFixed(2)}
                                                 </span>
                                             </div>
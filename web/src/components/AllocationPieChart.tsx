@@ -2,6 +2,7 @@ import React from 'react';
 import { Chart as ChartJS, ArcElement, Tooltip, Legend } from 'chart.js';
 import { Pie } from 'react-chartjs-2';
 import { getGroupColors, getAssetClassIcon } from '../utils/assetClassColors';
+import { currencySymbol } from '../utils/formatters';
 
 // Register Chart.js components
 ChartJS.register(ArcElement, Tooltip, Legend);
@@ -115,7 +116,7 @@ const AllocationPieChart: React.FC<AllocationPieChartProps> = ({
               minimumFractionDigits: 2,
               maximumFractionDigits: 2,
             });
-            return `${icon}${label}: ${currency === 'RMB' ? '¥' : '$'}${formattedValue} (${percentage.toFixed(1)}%)`;
+            return `${icon}${label}: ${currencySymbol(currency)}${formattedValue} (${percentage.toFixed(1)}%)`;
           },
         },
       },
@@ -3,6 +3,7 @@ import { useWatchlist } from '../contexts/WatchlistContext';
 import { TrendingUp, TrendingDown, Plus } from 'lucide-react';
 import { useNavigate } from 'react-router-dom';
 import AddToWatchlistDialog from './AddToWatchlistDialog';
+import { currencySymbol } from '../utils/formatters';
 
 export const WatchlistWidget: React.FC = () => {
     const { watchlist } = useWatchlist();
@@ -63,7 +64,7 @@ export const WatchlistWidget: React.FC = () => {
                                     )}
                                     <div className="flex flex-col items-end min-w-[70px]">
                                         <span className="font-medium text-sm whitespace-nowrap">
-                                            {item.currency === 'CNY' ? '¥' : '$'}
+                                            {currencySymbol(item.currency)}
                                             {item.price.toFixed(2)}
                                         </span>
                                         <span
@@ -3,6 +3,7 @@ import { Plus, Search, TrendingUp, TrendingDown, X, RefreshCw } from 'lucide-rea
 import { DashboardLayout } from '../components/layout/DashboardLayout';
 import { useWatchlist } from '../contexts/WatchlistContext';
 import AddToWatchlistDialog from '../components/AddToWatchlistDialog';
+import { currencySymbol } from '../utils/formatters';
 
 const WatchlistPage: React.FC = () => {
     const { watchlist, removeFromWatchlist, refreshPrices, isRefreshing } = useWatchlist();
@@ -111,7 +112,7 @@ const WatchlistPage: React.FC = () => {
                             {/* Price */}
                             <div className="mb-3">
                                 <p className="text-2xl font-bold">
-                                    {item.currency === 'CNY' ? '¥' : '$'}
+                                    {currencySymbol(item.currency)}
                                     {item.price.toFixed(2)}
                                 </p>
                             </div>
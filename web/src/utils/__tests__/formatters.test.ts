@@ -0,0 +1,22 @@
+import { currencySymbol, formatCurrency, isRenminbi } from '../formatters';
+
+describe('Currency formatters', () => {
+  it('should treat RMB and CNY as the renminbi', () => {
+    expect(isRenminbi('RMB')).toBe(true);
+    expect(isRenminbi('CNY')).toBe(true);
+    expect(isRenminbi('cny')).toBe(true);
+    expect(isRenminbi('USD')).toBe(false);
+    expect(isRenminbi(undefined)).toBe(false);
+  });
+
+  it('should show renminbi prices with the yuan symbol', () => {
+    expect(currencySymbol('RMB')).toBe('¥');
+    expect(currencySymbol('CNY')).toBe('¥');
+    expect(currencySymbol('USD')).toBe('$');
+  });
+
+  it('should format either renminbi code the same way', () => {
+    expect(formatCurrency(12.5, 'RMB')).toBe(formatCurrency(12.5, 'CNY'));
+    expect(formatCurrency(12.5, 'RMB')).not.toBe(formatCurrency(12.5, 'USD'));
+  });
+});
@@ -1,9 +1,25 @@
+/**
+ * Whether a currency code names the renminbi, which the API reports as RMB
+ * and quote providers as its ISO code CNY
+ */
+export const isRenminbi = (currency: string | undefined): boolean => {
+  const code = (currency || '').trim().toUpperCase();
+  return code === 'RMB' || code === 'CNY';
+};
+
+/**
+ * The symbol prices in a currency are shown with
+ */
+export const currencySymbol = (currency: string | undefined): string => {
+  return isRenminbi(currency) ? '¥' : '$';
+};
+
 /**
  * Format a number as currency
  */
 export const formatCurrency = (value: number, currency: string): string => {
-  const locale = currency === 'RMB' ? 'zh-CN' : 'en-US';
-  const currencyCode = currency === 'RMB' ? 'CNY' : 'USD';
+  const locale = isRenminbi(currency) ? 'zh-CN' : 'en-US';
+  const currencyCode = isRenminbi(currency) ? 'CNY' : 'USD';
 
   return new Intl.NumberFormat(locale, {
     style: 'currency',